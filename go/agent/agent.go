@@ -16,7 +16,11 @@
 
 package agent
 
-import "github.com/outbrain/orchestrator/go/inst"
+import (
+	"time"
+
+	"github.com/outbrain/orchestrator/go/inst"
+)
 
 // LogicalVolume describes an LVM volume
 type LogicalVolume struct {
@@ -60,11 +64,74 @@ type Agent struct {
 type SeedOperation struct {
 	SeedId         int64
 	TargetHostname string
+	TargetPort     int // MySQL port; only recorded by seed methods operating on MySQL directly
 	SourceHostname string
+	SourcePort     int // MySQL port; only recorded by seed methods operating on MySQL directly
+	SeedMethod     string
 	StartTimestamp string
 	EndTimestamp   string
 	IsComplete     bool
 	IsSuccessful   bool
+	ResumeCount    uint
+	AttachReplica  bool
+	MasterKey      inst.InstanceKey
+	ChecksumTables []string
+	Progress       SeedProgress
+}
+
+// SeedOptions are the choices made by a seed request
+type SeedOptions struct {
+	Method         string           // Name of seed method
+	AttachReplica  bool             // Attach target into replication, wait for it to catch up and validate checksums
+	MasterKey      inst.InstanceKey // Master to attach target to; empty for the seed method's natural choice
+	ChecksumTables []string         // Tables (schema.table) to compare between target and source
+	Force          bool             // Seed even onto a target that is a writable cluster master or has replicas (clone only)
+}
+
+// GetSeedOptions returns the options a seed was submitted with
+func (this *SeedOperation) GetSeedOptions() *SeedOptions {
+	return &SeedOptions{
+		Method:         this.SeedMethod,
+		AttachReplica:  this.AttachReplica,
+		MasterKey:      this.MasterKey,
+		ChecksumTables: this.ChecksumTables,
+	}
+}
+
+// SeedProgress describes data transfer progress of a seed at a given point in time
+type SeedProgress struct {
+	BytesCopied    int64
+	BytesTotal     int64
+	BytesPerSecond int64
+	ETASeconds     int64
+}
+
+// NewSeedProgress computes throughput and estimated time to completion based on the bytes copied
+// since the previous sample. ETASeconds is -1 when it cannot be estimated.
+func NewSeedProgress(bytesCopied int64, bytesTotal int64, previousBytesCopied int64, elapsed time.Duration) SeedProgress {
+	progress := SeedProgress{BytesCopied: bytesCopied, BytesTotal: bytesTotal, ETASeconds: -1}
+	if elapsed > 0 && bytesCopied > previousBytesCopied {
+		progress.BytesPerSecond = int64(float64(bytesCopied-previousBytesCopied) / elapsed.Seconds())
+	}
+	if progress.BytesPerSecond > 0 {
+		remaining := bytesTotal - bytesCopied
+		if remaining < 0 {
+			remaining = 0
+		}
+		progress.ETASeconds = remaining / progress.BytesPerSecond
+	}
+	return progress
+}
+
+// PercentComplete returns the copied portion of the data, capped at 100
+func (this *SeedProgress) PercentComplete() int64 {
+	if this.BytesTotal <= 0 {
+		return 0
+	}
+	if this.BytesCopied >= this.BytesTotal {
+		return 100
+	}
+	return 100 * this.BytesCopied / this.BytesTotal
 }
 
 // SeedOperationState represents a single state (step) in a seed operation
@@ -74,6 +141,7 @@ type SeedOperationState struct {
 	StateTimestamp string
 	Action         string
 	ErrorMessage   string
+	Progress       SeedProgress
 }
 
 // Build an instance key for a given agent
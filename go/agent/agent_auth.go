@@ -0,0 +1,84 @@
+/*
+   Copyright 2014 Outbrain Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package agent
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/outbrain/orchestrator/go/config"
+)
+
+// AgentNewTokenHeader carries the new token on a token rotation request to an agent
+const AgentNewTokenHeader = "X-Orchestrator-Agent-New-Token"
+
+// AgentTokenAcknowledgement is what an agent responds (as JSON string) to a token rotation request, having
+// switched to the new token: hex encoded SHA-256 of the new token
+func AgentTokenAcknowledgement(newToken string) string {
+	digest := sha256.Sum256([]byte(newToken))
+	return hex.EncodeToString(digest[:])
+}
+
+// ErrAgentUnauthorized is returned when an agent rejects the token orchestrator holds for it
+var ErrAgentUnauthorized = errors.New("Agent rejected token")
+
+// ComputeAgentEnrollmentSignature returns the signature an agent is expected to present when registering,
+// given the pre-shared AgentEnrollmentSecret: hex encoded HMAC-SHA256 of "hostname:port:token"
+func ComputeAgentEnrollmentSignature(secret string, hostname string, port int, token string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(fmt.Sprintf("%s:%d:%s", hostname, port, token)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyAgentRegistration validates an agent's registration request. With mutual TLS, the agent's
+// verified client certificate (its OU having been validated against AgentSSLValidOUs) must be issued
+// for the registering host. With AgentEnrollmentSecret, the request must be signed with the secret.
+// When neither applies, registrations are accepted as they are.
+func VerifyAgentRegistration(hostname string, port int, token string, signature string, clientCertificate *x509.Certificate) error {
+	if token == "" {
+		return errors.New("Empty token")
+	}
+	if config.Config.AgentsUseMutualTLS {
+		if clientCertificate == nil {
+			return errors.New("No verified client certificate")
+		}
+		if err := clientCertificate.VerifyHostname(hostname); err != nil {
+			return fmt.Errorf("Client certificate does not match host: %+v", err)
+		}
+	}
+	if config.Config.AgentEnrollmentSecret != "" {
+		expectedSignature := ComputeAgentEnrollmentSignature(config.Config.AgentEnrollmentSecret, hostname, port, token)
+		if !hmac.Equal([]byte(signature), []byte(expectedSignature)) {
+			return errors.New("Invalid enrollment signature")
+		}
+	}
+	return nil
+}
+
+// generateAgentToken returns a new random agent token
+func generateAgentToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,80 @@
+/*
+   Copyright 2014 Outbrain Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package agent
+
+import (
+	"crypto/x509"
+	test "github.com/outbrain/golib/tests"
+	"github.com/outbrain/orchestrator/go/config"
+	"testing"
+)
+
+func TestComputeAgentEnrollmentSignature(t *testing.T) {
+	signature := ComputeAgentEnrollmentSignature("secret", "db1", 3002, "abc")
+	test.S(t).ExpectEquals(len(signature), 64)
+	test.S(t).ExpectEquals(ComputeAgentEnrollmentSignature("secret", "db1", 3002, "abc"), signature)
+	test.S(t).ExpectNotEquals(ComputeAgentEnrollmentSignature("other", "db1", 3002, "abc"), signature)
+	test.S(t).ExpectNotEquals(ComputeAgentEnrollmentSignature("secret", "db2", 3002, "abc"), signature)
+	test.S(t).ExpectNotEquals(ComputeAgentEnrollmentSignature("secret", "db1", 3002, "abd"), signature)
+}
+
+func TestVerifyAgentRegistrationOpen(t *testing.T) {
+	defer func(secret string, mutualTLS bool) {
+		config.Config.AgentEnrollmentSecret, config.Config.AgentsUseMutualTLS = secret, mutualTLS
+	}(config.Config.AgentEnrollmentSecret, config.Config.AgentsUseMutualTLS)
+	config.Config.AgentEnrollmentSecret = ""
+	config.Config.AgentsUseMutualTLS = false
+
+	test.S(t).ExpectNil(VerifyAgentRegistration("db1", 3002, "abc", "", nil))
+	test.S(t).ExpectNotNil(VerifyAgentRegistration("db1", 3002, "", "", nil))
+}
+
+func TestVerifyAgentRegistrationEnrollmentSecret(t *testing.T) {
+	defer func(secret string, mutualTLS bool) {
+		config.Config.AgentEnrollmentSecret, config.Config.AgentsUseMutualTLS = secret, mutualTLS
+	}(config.Config.AgentEnrollmentSecret, config.Config.AgentsUseMutualTLS)
+	config.Config.AgentEnrollmentSecret = "secret"
+	config.Config.AgentsUseMutualTLS = false
+
+	signature := ComputeAgentEnrollmentSignature("secret", "db1", 3002, "abc")
+	test.S(t).ExpectNil(VerifyAgentRegistration("db1", 3002, "abc", signature, nil))
+	test.S(t).ExpectNotNil(VerifyAgentRegistration("db1", 3002, "abc", "", nil))
+	test.S(t).ExpectNotNil(VerifyAgentRegistration("db2", 3002, "abc", signature, nil))
+	test.S(t).ExpectNotNil(VerifyAgentRegistration("db1", 3002, "abd", signature, nil))
+}
+
+func TestVerifyAgentRegistrationMutualTLS(t *testing.T) {
+	defer func(secret string, mutualTLS bool) {
+		config.Config.AgentEnrollmentSecret, config.Config.AgentsUseMutualTLS = secret, mutualTLS
+	}(config.Config.AgentEnrollmentSecret, config.Config.AgentsUseMutualTLS)
+	config.Config.AgentEnrollmentSecret = ""
+	config.Config.AgentsUseMutualTLS = true
+
+	test.S(t).ExpectNotNil(VerifyAgentRegistration("db1", 3002, "abc", "", nil))
+	certificate := &x509.Certificate{DNSNames: []string{"db1.example.com"}}
+	test.S(t).ExpectNil(VerifyAgentRegistration("db1.example.com", 3002, "abc", "", certificate))
+	test.S(t).ExpectNotNil(VerifyAgentRegistration("db2.example.com", 3002, "abc", "", certificate))
+}
+
+func TestGenerateAgentToken(t *testing.T) {
+	token, err := generateAgentToken()
+	test.S(t).ExpectNil(err)
+	test.S(t).ExpectEquals(len(token), 64)
+	other, err := generateAgentToken()
+	test.S(t).ExpectNil(err)
+	test.S(t).ExpectNotEquals(token, other)
+}
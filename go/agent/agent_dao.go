@@ -24,6 +24,7 @@ import (
 	"io/ioutil"
 	"net"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
 
@@ -64,7 +65,7 @@ func auditAgentOperation(auditType string, agent *Agent, message string) error {
 	return inst.AuditOperation(auditType, instanceKey, message)
 }
 
-// readResponse returns the body of an HTTP response
+// readResponse returns the body of a successful (2xx) HTTP response
 func readResponse(res *http.Response, err error) ([]byte, error) {
 	if err != nil {
 		return nil, err
@@ -76,21 +77,28 @@ func readResponse(res *http.Response, err error) ([]byte, error) {
 		return nil, err
 	}
 
-	if res.Status == "500" {
-		return body, errors.New("Response Status 500")
+	if res.StatusCode == http.StatusUnauthorized || res.StatusCode == http.StatusForbidden {
+		return body, ErrAgentUnauthorized
+	}
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return body, fmt.Errorf("Response status %s", res.Status)
 	}
 
 	return body, nil
 }
 
-// SubmitAgent submits a new agent for listing
+// SubmitAgent submits a new agent for listing. The registration is expected to have been verified
+// by VerifyAgentRegistration.
 func SubmitAgent(hostname string, port int, token string) (string, error) {
+	if _, _, err := readAgentBasicInfo(hostname); err != nil {
+		auditAgentOperation("agent-registration", &Agent{Hostname: hostname}, fmt.Sprintf("Registration of unknown host %s, agent port %d", hostname, port))
+	}
 	_, err := db.ExecOrchestrator(`
 			replace 
 				into host_agent (
-					hostname, port, token, last_submitted
+					hostname, port, token, last_submitted, token_rotated_at, token_validated_at
 				) VALUES (
-					?, ?, ?, NOW()
+					?, ?, ?, NOW(), NOW(), NOW()
 				)
 			`,
 		hostname,
@@ -109,6 +117,16 @@ func SubmitAgent(hostname string, port int, token string) (string, error) {
 	return hostname, err
 }
 
+// AuditRejectedAgentRegistration records a registration attempt which failed verification
+func AuditRejectedAgentRegistration(hostname string, port int, reason error) error {
+	message := fmt.Sprintf("Rejected registration of %s, agent port %d: %+v", hostname, port, reason)
+	if _, _, err := readAgentBasicInfo(hostname); err != nil {
+		message = fmt.Sprintf("%s (unknown host)", message)
+	}
+	log.Warning(message)
+	return auditAgentOperation("agent-registration-rejected", &Agent{Hostname: hostname}, message)
+}
+
 // If a mysql port is available, try to discover against it
 func DiscoverAgentInstance(hostname string, port int) error {
 	agent, err := GetAgent(hostname)
@@ -387,6 +405,66 @@ func GetAgent(hostname string) (Agent, error) {
 	return agent, err
 }
 
+// GetMySQLDatadirDiskFree asks the agent on given host for the free disk space on the MySQL datadir.
+// Unlike GetAgent, this involves a single HTTP request.
+func GetMySQLDatadirDiskFree(hostname string) (int64, error) {
+	agent, token, err := readAgentBasicInfo(hostname)
+	if err != nil {
+		return 0, err
+	}
+	var diskFree int64
+	uri := baseAgentUri(agent.Hostname, agent.Port)
+	body, err := readResponse(httpGet(fmt.Sprintf("%s/mysql-datadir-available-space?token=%s", uri, token)))
+	if err == nil {
+		err = json.Unmarshal(body, &diskFree)
+	}
+	return diskFree, err
+}
+
+// CheckHost asks the agent on given host, within AgentFailureVerificationTimeoutSeconds, whether the host
+// is reachable and MySQL is running, and for the tail of the MySQL error log.
+func CheckHost(hostname string) *inst.AgentHostCheck {
+	check := &inst.AgentHostCheck{ErrorLogTail: []string{}}
+	agent, token, err := agentTokens.read(hostname)
+	if err != nil {
+		check.Message = err.Error()
+		return check
+	}
+	check.HasAgent = true
+
+	client := &http.Client{
+		Transport: httpTransport,
+		Timeout:   time.Duration(config.Config.AgentFailureVerificationTimeoutSeconds) * time.Second,
+	}
+	uri := baseAgentUri(agent.Hostname, agent.Port)
+	res, err := client.Get(fmt.Sprintf("%s/mysql-status?token=%s", uri, token))
+	if err != nil {
+		check.Message = err.Error()
+		return check
+	}
+	// Any response at all means the host is up
+	check.HostReachable = true
+	body, err := readResponse(res, nil)
+	if err == ErrAgentUnauthorized {
+		check.Unauthorized = true
+		check.Message = err.Error()
+		return check
+	}
+	// "status" returns with non-zero exit code when MySQL is not running, making for an error response
+	if err != nil || json.Unmarshal(body, &check.MySQLRunning) != nil {
+		check.MySQLRunning = false
+	}
+
+	body, err = readResponse(client.Get(fmt.Sprintf("%s/mysql-error-log-tail?token=%s", uri, token)))
+	if err == nil {
+		err = json.Unmarshal(body, &check.ErrorLogTail)
+	}
+	if err != nil {
+		check.Message = fmt.Sprintf("Cannot read error log: %+v", err)
+	}
+	return check
+}
+
 // executeAgentCommand requests an agent to execute a command via HTTP api
 func executeAgentCommand(hostname string, command string, onResponse *func([]byte)) (Agent, error) {
 	agent, token, err := readAgentBasicInfo(hostname)
@@ -458,6 +536,29 @@ func ReceiveMySQLSeedData(hostname string, seedId int64) (Agent, error) {
 	return executeAgentCommand(hostname, fmt.Sprintf("receive-mysql-seed-data/%d", seedId), nil)
 }
 
+// ResumeReceiveMySQLSeedData requests an agent to start listening for seed data, keeping data already received
+func ResumeReceiveMySQLSeedData(hostname string, seedId int64) (Agent, error) {
+	return executeAgentCommand(hostname, fmt.Sprintf("receive-mysql-seed-data/%d?resume=true", seedId), nil)
+}
+
+// ResumeSendMySQLSeedData requests an agent to send seed data, skipping files up to and including given file
+func ResumeSendMySQLSeedData(hostname string, targetHostname string, seedId int64, lastCompletedFile string) (Agent, error) {
+	return executeAgentCommand(hostname, fmt.Sprintf("send-mysql-seed-data/%s/%d?resume-from=%s", targetHostname, seedId, url.QueryEscape(lastCompletedFile)), nil)
+}
+
+// seedResumePoint asks an agent for the last file it has completely received in a seed. An empty result
+// means the seed cannot be resumed, which is also the case for agents which do not support resuming.
+func seedResumePoint(hostname string, seedId int64) (string, error) {
+	lastCompletedFile := ""
+	onResponse := func(body []byte) {
+		if err := json.Unmarshal(body, &lastCompletedFile); err != nil {
+			lastCompletedFile = ""
+		}
+	}
+	_, err := executeAgentCommand(hostname, fmt.Sprintf("seed-resume-point/%d", seedId), &onResponse)
+	return lastCompletedFile, err
+}
+
 // ReceiveMySQLSeedData requests an agent to start sending seed data
 func SendMySQLSeedData(hostname string, targetHostname string, seedId int64) (Agent, error) {
 	return executeAgentCommand(hostname, fmt.Sprintf("send-mysql-seed-data/%s/%d", targetHostname, seedId), nil)
@@ -513,23 +614,78 @@ func AbortSeed(seedId int64) error {
 	return nil
 }
 
+// ReceiveXtrabackupSeedData requests an agent to start listening for an incoming xtrabackup stream
+func ReceiveXtrabackupSeedData(hostname string, seedId int64) (Agent, error) {
+	return executeAgentCommand(hostname, fmt.Sprintf("receive-xtrabackup-seed-data/%d", seedId), nil)
+}
+
+// SendXtrabackupSeedData requests an agent to stream an xtrabackup backup to the target host
+func SendXtrabackupSeedData(hostname string, targetHostname string, seedId int64) (Agent, error) {
+	return executeAgentCommand(hostname, fmt.Sprintf("send-xtrabackup-seed-data/%s/%d", targetHostname, seedId), nil)
+}
+
+// PrepareXtrabackupSeed requests an agent to run xtrabackup --prepare on received seed data, in background
+func PrepareXtrabackupSeed(hostname string, seedId int64) (Agent, error) {
+	return executeAgentCommand(hostname, fmt.Sprintf("prepare-xtrabackup-seed/%d", seedId), nil)
+}
+
+// xtrabackupPrepareCompleted checks an agent to see if xtrabackup --prepare of given seed has completed.
+// The prepare command is tracked apart from the receive command, which runs under the same seed id.
+func xtrabackupPrepareCompleted(hostname string, seedId int64) (Agent, bool, error) {
+	result := false
+	onResponse := func(body []byte) {
+		json.Unmarshal(body, &result)
+	}
+	agent, err := executeAgentCommand(hostname, fmt.Sprintf("prepare-xtrabackup-seed-completed/%d", seedId), &onResponse)
+	return agent, result, err
+}
+
+// xtrabackupPrepareSucceeded checks an agent to see if xtrabackup --prepare of given seed was successful.
+func xtrabackupPrepareSucceeded(hostname string, seedId int64) (Agent, bool, error) {
+	result := false
+	onResponse := func(body []byte) {
+		json.Unmarshal(body, &result)
+	}
+	agent, err := executeAgentCommand(hostname, fmt.Sprintf("prepare-xtrabackup-seed-succeeded/%d", seedId), &onResponse)
+	return agent, result, err
+}
+
+// ReadXtrabackupInfo requests an agent for the replication info files xtrabackup stored along with seed data
+func ReadXtrabackupInfo(hostname string, seedId int64) (*XtrabackupInfo, error) {
+	xtrabackupInfo := &XtrabackupInfo{}
+	var unmarshalErr error
+	onResponse := func(body []byte) {
+		unmarshalErr = json.Unmarshal(body, xtrabackupInfo)
+	}
+	_, err := executeAgentCommand(hostname, fmt.Sprintf("xtrabackup-info/%d", seedId), &onResponse)
+	if err != nil {
+		return nil, err
+	}
+	return xtrabackupInfo, unmarshalErr
+}
+
 // PostCopy will request an agent to invoke post-copy commands
 func PostCopy(hostname string) (Agent, error) {
 	return executeAgentCommand(hostname, "post-copy", nil)
 }
 
 // SubmitSeedEntry submits a new seed operation entry, returning its unique ID
-func SubmitSeedEntry(targetHostname string, sourceHostname string) (int64, error) {
+func SubmitSeedEntry(targetHostname string, sourceHostname string, seedOptions *SeedOptions) (int64, error) {
 	res, err := db.ExecOrchestrator(`
 			insert 
 				into agent_seed (
-					target_hostname, source_hostname, start_timestamp
+					target_hostname, source_hostname, seed_method, attach_replica, master_hostname, master_port, checksum_tables, start_timestamp
 				) VALUES (
-					?, ?, NOW()
+					?, ?, ?, ?, ?, ?, ?, NOW()
 				)
 			`,
 		targetHostname,
 		sourceHostname,
+		seedOptions.Method,
+		seedOptions.AttachReplica,
+		seedOptions.MasterKey.Hostname,
+		seedOptions.MasterKey.Port,
+		strings.Join(seedOptions.ChecksumTables, ","),
 	)
 	if err != nil {
 		return 0, log.Errore(err)
@@ -582,6 +738,33 @@ func submitSeedStateEntry(seedId int64, action string, errorMessage string) (int
 	return id, err
 }
 
+// submitSeedProgressEntry submits a seed state recording data transfer progress
+func submitSeedProgressEntry(seedId int64, action string, progress SeedProgress) (int64, error) {
+	res, err := db.ExecOrchestrator(`
+			insert 
+				into agent_seed_state (
+					agent_seed_id, state_timestamp, state_action, error_message,
+					bytes_copied, bytes_total, bytes_per_second, eta_seconds
+				) VALUES (
+					?, NOW(), ?, '',
+					?, ?, ?, ?
+				)
+			`,
+		seedId,
+		action,
+		progress.BytesCopied,
+		progress.BytesTotal,
+		progress.BytesPerSecond,
+		progress.ETASeconds,
+	)
+	if err != nil {
+		return 0, log.Errore(err)
+	}
+	id, err := res.LastInsertId()
+
+	return id, err
+}
+
 // updateSeedStateEntry updates seed step state
 func updateSeedStateEntry(seedStateId int64, reason error) error {
 	_, err := db.ExecOrchestrator(`
@@ -626,9 +809,13 @@ func FailStaleSeeds() error {
 
 // executeSeed is *the* function for taking a seed. It is a complex operation of testing, preparing, re-testing
 // agents on both sides, initiating data transfer, following up, awaiting completion, diagnosing errors, claning up.
-func executeSeed(seedId int64, targetHostname string, sourceHostname string) error {
+func executeSeed(seedId int64, targetHostname string, sourceHostname string, seedOptions *SeedOptions, resume bool) error {
 
 	var err error
+	seedMethod, err := GetSeedMethod(seedOptions.Method)
+	if err != nil {
+		return log.Errore(err)
+	}
 	var seedStateId int64
 
 	seedStateId, _ = submitSeedStateEntry(seedId, fmt.Sprintf("getting target agent info for %s", targetHostname), "")
@@ -649,169 +836,201 @@ func executeSeed(seedId int64, targetHostname string, sourceHostname string) err
 		return updateSeedStateEntry(seedStateId, errors.New("MySQL is running on target host. Cowardly refusing to proceeed. Please stop the MySQL service"))
 	}
 
-	seedStateId, _ = submitSeedStateEntry(seedId, fmt.Sprintf("Looking up available snapshots on source %s", sourceHostname), "")
-	if len(sourceAgent.LogicalVolumes) == 0 {
-		return updateSeedStateEntry(seedStateId, errors.New("No logical volumes found on source host"))
-	}
-
-	seedStateId, _ = submitSeedStateEntry(seedId, fmt.Sprintf("Checking mount point on source %s", sourceHostname), "")
-	if sourceAgent.MountPoint.IsMounted {
-		return updateSeedStateEntry(seedStateId, errors.New("Volume already mounted on source host; please unmount"))
-	}
-
-	seedFromLogicalVolume := sourceAgent.LogicalVolumes[0]
-	seedStateId, _ = submitSeedStateEntry(seedId, fmt.Sprintf("Mounting logical volume: %s", seedFromLogicalVolume.Path), "")
-	_, err = MountLV(sourceHostname, seedFromLogicalVolume.Path)
-	if err != nil {
-		return updateSeedStateEntry(seedStateId, err)
+	seedStateId, _ = submitSeedStateEntry(seedId, fmt.Sprintf("Copying data using %s seed method", seedOptions.Method), "")
+	if err = seedMethod.Copy(seedId, &targetAgent, &sourceAgent, resume); err != nil {
+		return err
 	}
-	sourceAgent, err = GetAgent(sourceHostname)
-	seedStateId, _ = submitSeedStateEntry(seedId, fmt.Sprintf("MySQL data volume on source host %s is %d bytes", sourceHostname, sourceAgent.MountPoint.MySQLDiskUsage), "")
 
-	seedStateId, _ = submitSeedStateEntry(seedId, fmt.Sprintf("Erasing MySQL data on %s", targetHostname), "")
-	_, err = deleteMySQLDatadir(targetHostname)
+	seedStateId, _ = submitSeedStateEntry(seedId, fmt.Sprintf("Starting MySQL on target: %s", targetHostname), "")
+	_, err = MySQLStart(targetHostname)
 	if err != nil {
 		return updateSeedStateEntry(seedStateId, err)
 	}
 
-	seedStateId, _ = submitSeedStateEntry(seedId, fmt.Sprintf("Aquiring target host datadir free space on %s", targetHostname), "")
-	targetAgent, err = GetAgent(targetHostname)
-	if err != nil {
-		return updateSeedStateEntry(seedStateId, err)
-	}
+	seedStateId, _ = submitSeedStateEntry(seedId, fmt.Sprintf("Submitting MySQL instance for discovery: %s", targetHostname), "")
+	SeededAgents <- &targetAgent
 
-	if sourceAgent.MountPoint.MySQLDiskUsage > targetAgent.MySQLDatadirDiskFree {
-		Unmount(sourceHostname)
-		return updateSeedStateEntry(seedStateId, fmt.Errorf("Not enough disk space on target host %s. Required: %d, available: %d. Bailing out.", targetHostname, sourceAgent.MountPoint.MySQLDiskUsage, targetAgent.MySQLDatadirDiskFree))
+	if err = seedMethod.Attach(seedId, &targetAgent, &sourceAgent, seedOptions); err != nil {
+		return err
 	}
 
-	// ...
-	seedStateId, _ = submitSeedStateEntry(seedId, fmt.Sprintf("%s will now receive data in background", targetHostname), "")
-	ReceiveMySQLSeedData(targetHostname, seedId)
-
-	seedStateId, _ = submitSeedStateEntry(seedId, fmt.Sprintf("Waiting some time for %s to start listening for incoming data", targetHostname), "")
-	time.Sleep(2 * time.Second)
-
-	seedStateId, _ = submitSeedStateEntry(seedId, fmt.Sprintf("%s will now send data to %s in background", sourceHostname, targetHostname), "")
-	SendMySQLSeedData(sourceHostname, targetHostname, seedId)
-
-	copyComplete := false
-	numStaleIterations := 0
-	var bytesCopied int64 = 0
-
-	for !copyComplete {
-		targetAgentPoll, err := GetAgent(targetHostname)
-		if err != nil {
-			return log.Errore(err)
-		}
-
-		if targetAgentPoll.MySQLDiskUsage == bytesCopied {
-			numStaleIterations++
-		}
-		bytesCopied = targetAgentPoll.MySQLDiskUsage
-
-		copyFailed := false
-		if _, commandCompleted, _ := seedCommandCompleted(targetHostname, seedId); commandCompleted {
-			copyComplete = true
-			if _, commandSucceeded, _ := seedCommandSucceeded(targetHostname, seedId); !commandSucceeded {
-				// failed.
-				copyFailed = true
-			}
-		}
-		if numStaleIterations > 10 {
-			copyFailed = true
-		}
-		if copyFailed {
-			AbortSeedCommand(sourceHostname, seedId)
-			AbortSeedCommand(targetHostname, seedId)
-			Unmount(sourceHostname)
-			return updateSeedStateEntry(seedStateId, errors.New("10 iterations have passed without progress. Bailing out."))
-		}
-
-		var copyPct int64 = 0
-		if sourceAgent.MountPoint.MySQLDiskUsage > 0 {
-			copyPct = 100 * bytesCopied / sourceAgent.MountPoint.MySQLDiskUsage
+	if seedOptions.AttachReplica {
+		if err = waitForSeededReplicaCatchUp(seedId, targetAgent.GetInstance()); err != nil {
+			return err
 		}
-		seedStateId, _ = submitSeedStateEntry(seedId, fmt.Sprintf("Copied %d/%d bytes (%d%%)", bytesCopied, sourceAgent.MountPoint.MySQLDiskUsage, copyPct), "")
-
-		if !copyComplete {
-			time.Sleep(30 * time.Second)
+		if err = compareSeedChecksums(seedId, targetAgent.GetInstance(), sourceAgent.GetInstance(), seedOptions.ChecksumTables); err != nil {
+			return err
 		}
 	}
 
-	// Cleanup:
-	seedStateId, _ = submitSeedStateEntry(seedId, fmt.Sprintf("Executing post-copy command on %s", targetHostname), "")
-	_, err = PostCopy(targetHostname)
-	if err != nil {
-		return updateSeedStateEntry(seedStateId, err)
-	}
-
-	seedStateId, _ = submitSeedStateEntry(seedId, fmt.Sprintf("Unmounting logical volume: %s", seedFromLogicalVolume.Path), "")
-	_, err = Unmount(sourceHostname)
-	if err != nil {
-		return updateSeedStateEntry(seedStateId, err)
-	}
-
-	seedStateId, _ = submitSeedStateEntry(seedId, fmt.Sprintf("Starting MySQL on target: %s", targetHostname), "")
-	_, err = MySQLStart(targetHostname)
-	if err != nil {
-		return updateSeedStateEntry(seedStateId, err)
-	}
-
-	seedStateId, _ = submitSeedStateEntry(seedId, fmt.Sprintf("Submitting MySQL instance for discovery: %s", targetHostname), "")
-	SeededAgents <- &targetAgent
-
 	seedStateId, _ = submitSeedStateEntry(seedId, "Done", "")
 
 	return nil
 }
 
-// Seed is the entry point for making a seed
-func Seed(targetHostname string, sourceHostname string) (int64, error) {
+// Seed is the entry point for making a seed. An empty seed method implies config.Config.DefaultSeedMethod
+func Seed(targetHostname string, sourceHostname string, seedOptions *SeedOptions) (int64, error) {
 	if targetHostname == sourceHostname {
 		return 0, log.Errorf("Cannot seed %s onto itself", targetHostname)
 	}
-	seedId, err := SubmitSeedEntry(targetHostname, sourceHostname)
+	if seedOptions.Method == "" {
+		seedOptions.Method = config.Config.DefaultSeedMethod
+	}
+	if _, err := GetSeedMethod(seedOptions.Method); err != nil {
+		return 0, log.Errore(err)
+	}
+	if err := validateSeedChecksumTables(seedOptions.ChecksumTables); err != nil {
+		return 0, log.Errore(err)
+	}
+	seedId, err := SubmitSeedEntry(targetHostname, sourceHostname, seedOptions)
 	if err != nil {
 		return 0, log.Errore(err)
 	}
 
 	go func() {
-		err := executeSeed(seedId, targetHostname, sourceHostname)
+		err := executeSeed(seedId, targetHostname, sourceHostname, seedOptions, false)
 		updateSeedComplete(seedId, err)
 	}()
 
 	return seedId, nil
 }
 
+// ResumeSeed restarts a failed seed. Where the seed method and agents support it, data transfer resumes
+// from the last file the target has completely received; otherwise data is copied anew.
+func ResumeSeed(seedId int64) error {
+	seedOperations, err := AgentSeedDetails(seedId)
+	if err != nil {
+		return log.Errore(err)
+	}
+	if len(seedOperations) == 0 {
+		return log.Errorf("Seed %d not found", seedId)
+	}
+	seedOperation := seedOperations[0]
+	if !seedOperation.IsComplete {
+		return log.Errorf("Seed %d is still active", seedId)
+	}
+	if seedOperation.IsSuccessful {
+		return log.Errorf("Seed %d has completed successfully; nothing to resume", seedId)
+	}
+	for _, hostname := range []string{seedOperation.TargetHostname, seedOperation.SourceHostname} {
+		activeSeeds, err := ReadActiveSeedsForHost(hostname)
+		if err != nil {
+			return log.Errore(err)
+		}
+		if len(activeSeeds) > 0 {
+			return log.Errorf("%s participates in active seed %d", hostname, activeSeeds[0].SeedId)
+		}
+	}
+
+	sqlResult, err := db.ExecOrchestrator(`
+			update 
+				agent_seed
+					set is_complete = 0,
+					is_successful = 0,
+					resume_count = resume_count + 1
+				where
+					agent_seed_id = ?
+					and is_complete = 1
+			`,
+		seedId,
+	)
+	if err != nil {
+		return log.Errore(err)
+	}
+	rows, err := sqlResult.RowsAffected()
+	if err != nil {
+		return log.Errore(err)
+	}
+	if rows != 1 {
+		// Someone else has just resumed this seed
+		return log.Errorf("Seed %d is no longer complete; it may have been resumed concurrently", seedId)
+	}
+	submitSeedStateEntry(seedId, fmt.Sprintf("Resuming seed (attempt %d)", seedOperation.ResumeCount+1), "")
+
+	if seedOperation.SeedMethod == SeedMethodClone {
+		targetKey := &inst.InstanceKey{Hostname: seedOperation.TargetHostname, Port: seedOperation.TargetPort}
+		donorKey := &inst.InstanceKey{Hostname: seedOperation.SourceHostname, Port: seedOperation.SourcePort}
+		go func() {
+			err := executeCloneSeed(seedId, targetKey, donorKey, seedOperation.GetSeedOptions())
+			updateSeedComplete(seedId, err)
+		}()
+		return nil
+	}
+	go func() {
+		err := executeSeed(seedId, seedOperation.TargetHostname, seedOperation.SourceHostname, seedOperation.GetSeedOptions(), true)
+		updateSeedComplete(seedId, err)
+	}()
+	return nil
+}
+
 // readSeeds reads seed from the backend table
 func readSeeds(whereCondition string, args []interface{}, limit string) ([]SeedOperation, error) {
 	res := []SeedOperation{}
+	// Progress is that of the most recent state recording data transfer
 	query := fmt.Sprintf(`
 		select 
-			agent_seed_id,
-			target_hostname,
-			source_hostname,
-			start_timestamp,
-			end_timestamp,
-			is_complete,
-			is_successful
+			agent_seed.agent_seed_id,
+			agent_seed.target_hostname,
+			agent_seed.target_port,
+			agent_seed.source_hostname,
+			agent_seed.source_port,
+			agent_seed.seed_method,
+			agent_seed.resume_count,
+			agent_seed.attach_replica,
+			agent_seed.master_hostname,
+			agent_seed.master_port,
+			agent_seed.checksum_tables,
+			agent_seed.start_timestamp,
+			agent_seed.end_timestamp,
+			agent_seed.is_complete,
+			agent_seed.is_successful,
+			progress.bytes_copied,
+			progress.bytes_total,
+			progress.bytes_per_second,
+			ifnull(progress.eta_seconds, -1) as eta_seconds
 		from 
 			agent_seed
+			left join agent_seed_state progress on (
+				progress.agent_seed_state_id = (
+					select
+						max(agent_seed_state_id)
+					from
+						agent_seed_state
+					where
+						agent_seed_state.agent_seed_id = agent_seed.agent_seed_id
+						and agent_seed_state.bytes_total > 0
+				)
+			)
 		%s
 		order by
-			agent_seed_id desc
+			agent_seed.agent_seed_id desc
 		%s
 		`, whereCondition, limit)
 	err := db.QueryOrchestrator(query, args, func(m sqlutils.RowMap) error {
 		seedOperation := SeedOperation{}
 		seedOperation.SeedId = m.GetInt64("agent_seed_id")
 		seedOperation.TargetHostname = m.GetString("target_hostname")
+		seedOperation.TargetPort = m.GetInt("target_port")
 		seedOperation.SourceHostname = m.GetString("source_hostname")
+		seedOperation.SourcePort = m.GetInt("source_port")
+		seedOperation.SeedMethod = m.GetString("seed_method")
+		seedOperation.ResumeCount = m.GetUint("resume_count")
+		seedOperation.AttachReplica = m.GetBool("attach_replica")
+		seedOperation.MasterKey = inst.InstanceKey{Hostname: m.GetString("master_hostname"), Port: m.GetInt("master_port")}
+		seedOperation.ChecksumTables = []string{}
+		if checksumTables := m.GetString("checksum_tables"); checksumTables != "" {
+			seedOperation.ChecksumTables = strings.Split(checksumTables, ",")
+		}
 		seedOperation.StartTimestamp = m.GetString("start_timestamp")
 		seedOperation.EndTimestamp = m.GetString("end_timestamp")
 		seedOperation.IsComplete = m.GetBool("is_complete")
 		seedOperation.IsSuccessful = m.GetBool("is_successful")
+		seedOperation.Progress = SeedProgress{
+			BytesCopied:    m.GetInt64("bytes_copied"),
+			BytesTotal:     m.GetInt64("bytes_total"),
+			BytesPerSecond: m.GetInt64("bytes_per_second"),
+			ETASeconds:     m.GetInt64("eta_seconds"),
+		}
 
 		res = append(res, seedOperation)
 		return nil
@@ -819,6 +1038,7 @@ func readSeeds(whereCondition string, args []interface{}, limit string) ([]SeedO
 
 	if err != nil {
 		log.Errore(err)
+		return res, err
 	}
 	return res, err
 }
@@ -827,10 +1047,10 @@ func readSeeds(whereCondition string, args []interface{}, limit string) ([]SeedO
 func ReadActiveSeedsForHost(hostname string) ([]SeedOperation, error) {
 	whereCondition := `
 		where
-			is_complete = 0
+			agent_seed.is_complete = 0
 			and (
-				target_hostname = ?
-				or source_hostname = ?
+				agent_seed.target_hostname = ?
+				or agent_seed.source_hostname = ?
 			)
 		`
 	return readSeeds(whereCondition, sqlutils.Args(hostname, hostname), "")
@@ -840,10 +1060,10 @@ func ReadActiveSeedsForHost(hostname string) ([]SeedOperation, error) {
 func ReadRecentCompletedSeedsForHost(hostname string) ([]SeedOperation, error) {
 	whereCondition := `
 		where
-			is_complete = 1
+			agent_seed.is_complete = 1
 			and (
-				target_hostname = ?
-				or source_hostname = ?
+				agent_seed.target_hostname = ?
+				or agent_seed.source_hostname = ?
 			)
 		`
 	return readSeeds(whereCondition, sqlutils.Args(hostname, hostname), "limit 10")
@@ -853,7 +1073,7 @@ func ReadRecentCompletedSeedsForHost(hostname string) ([]SeedOperation, error) {
 func AgentSeedDetails(seedId int64) ([]SeedOperation, error) {
 	whereCondition := `
 		where
-			agent_seed_id = ?
+			agent_seed.agent_seed_id = ?
 		`
 	return readSeeds(whereCondition, sqlutils.Args(seedId), "")
 }
@@ -872,7 +1092,11 @@ func ReadSeedStates(seedId int64) ([]SeedOperationState, error) {
 			agent_seed_id,
 			state_timestamp,
 			state_action,
-			error_message
+			error_message,
+			bytes_copied,
+			bytes_total,
+			bytes_per_second,
+			eta_seconds
 		from 
 			agent_seed_state
 		where
@@ -887,6 +1111,10 @@ func ReadSeedStates(seedId int64) ([]SeedOperationState, error) {
 		seedState.StateTimestamp = m.GetString("state_timestamp")
 		seedState.Action = m.GetString("state_action")
 		seedState.ErrorMessage = m.GetString("error_message")
+		seedState.Progress.BytesCopied = m.GetInt64("bytes_copied")
+		seedState.Progress.BytesTotal = m.GetInt64("bytes_total")
+		seedState.Progress.BytesPerSecond = m.GetInt64("bytes_per_second")
+		seedState.Progress.ETASeconds = m.GetInt64("eta_seconds")
 
 		res = append(res, seedState)
 		return nil
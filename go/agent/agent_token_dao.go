@@ -0,0 +1,245 @@
+/*
+   Copyright 2014 Outbrain Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/outbrain/golib/log"
+	"github.com/outbrain/golib/sqlutils"
+	"github.com/outbrain/orchestrator/go/config"
+	"github.com/outbrain/orchestrator/go/db"
+)
+
+// agentTokenStore persists agents' tokens. Replacing and revoking a token are conditional on the token
+// in hand, such that an operation based on a stale token never affects a newer one.
+type agentTokenStore interface {
+	read(hostname string) (agent Agent, token string, err error)
+	markValidated(hostname string, token string) error
+	replace(hostname string, token string, newToken string) (replaced bool, err error)
+	revoke(hostname string, token string) (revoked bool, err error)
+	audit(auditType string, agent *Agent, message string)
+}
+
+// backendAgentTokenStore is the agentTokenStore backed by the host_agent table
+type backendAgentTokenStore struct{}
+
+var agentTokens agentTokenStore = &backendAgentTokenStore{}
+
+// agentTokensMutex keeps token verification (read locked) apart from token rotation (write locked).
+// An agent verified while being rotated would reject the token about to be replaced.
+var agentTokensMutex = &sync.RWMutex{}
+
+func (this *backendAgentTokenStore) read(hostname string) (Agent, string, error) {
+	return readAgentBasicInfo(hostname)
+}
+
+func (this *backendAgentTokenStore) markValidated(hostname string, token string) error {
+	_, err := db.ExecOrchestrator(`
+			update
+				host_agent
+			set
+				token_validated_at = NOW()
+			where
+				hostname = ?
+				and token = ?`,
+		hostname,
+		token,
+	)
+	return log.Errore(err)
+}
+
+func (this *backendAgentTokenStore) replace(hostname string, token string, newToken string) (bool, error) {
+	sqlResult, err := db.ExecOrchestrator(`
+			update
+				host_agent
+			set
+				token = ?,
+				token_rotated_at = NOW(),
+				token_validated_at = NOW()
+			where
+				hostname = ?
+				and token = ?`,
+		newToken,
+		hostname,
+		token,
+	)
+	if err != nil {
+		return false, log.Errore(err)
+	}
+	rows, err := sqlResult.RowsAffected()
+	return rows > 0, log.Errore(err)
+}
+
+func (this *backendAgentTokenStore) revoke(hostname string, token string) (bool, error) {
+	sqlResult, err := db.ExecOrchestrator(`
+			delete
+				from host_agent
+			where
+				hostname = ?
+				and token = ?`,
+		hostname,
+		token,
+	)
+	if err != nil {
+		return false, log.Errore(err)
+	}
+	rows, err := sqlResult.RowsAffected()
+	return rows > 0, log.Errore(err)
+}
+
+func (this *backendAgentTokenStore) audit(auditType string, agent *Agent, message string) {
+	auditAgentOperation(auditType, agent, message)
+}
+
+// RevokeAgent forgets an agent along with given token, which is the token the agent is known to reject
+// (or not to have accepted for long). An agent whose token has meanwhile changed is not affected.
+// The agent will need to register anew.
+func RevokeAgent(hostname string, token string, reason string) error {
+	revoked, err := agentTokens.revoke(hostname, token)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		log.Debugf("RevokeAgent: token of %s has changed; not revoking", hostname)
+		return nil
+	}
+	agentTokens.audit("agent-revoked", &Agent{Hostname: hostname}, reason)
+	return nil
+}
+
+// VerifyAgentToken makes an authenticated request to an agent. An agent accepting its token is marked
+// as validated; an agent rejecting it is revoked.
+func VerifyAgentToken(hostname string) error {
+	agentTokensMutex.RLock()
+	defer agentTokensMutex.RUnlock()
+
+	agent, token, err := agentTokens.read(hostname)
+	if err != nil {
+		return err
+	}
+	uri := baseAgentUri(agent.Hostname, agent.Port)
+	_, err = readResponse(httpGet(fmt.Sprintf("%s/mysql-port?token=%s", uri, token)))
+	if err == ErrAgentUnauthorized {
+		return RevokeAgent(hostname, token, "Agent rejected its token")
+	}
+	if err != nil {
+		return err
+	}
+	return agentTokens.markValidated(hostname, token)
+}
+
+// RotateAgentToken generates a new token for an agent, hands it over to the agent (authenticating with
+// the current token) and stores it. The agent must acknowledge the new token, see AgentTokenAcknowledgement().
+func RotateAgentToken(hostname string) error {
+	agentTokensMutex.Lock()
+	defer agentTokensMutex.Unlock()
+
+	agent, token, err := agentTokens.read(hostname)
+	if err != nil {
+		return err
+	}
+	newToken, err := generateAgentToken()
+	if err != nil {
+		return log.Errore(err)
+	}
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/rotate-token?token=%s", baseAgentUri(agent.Hostname, agent.Port), token), nil)
+	if err != nil {
+		return log.Errore(err)
+	}
+	req.Header.Set(AgentNewTokenHeader, newToken)
+	body, err := readResponse(httpClient.Do(req))
+	if err == ErrAgentUnauthorized {
+		return RevokeAgent(hostname, token, "Agent rejected its token on rotation")
+	}
+	if err != nil {
+		return log.Errore(err)
+	}
+	acknowledgement := ""
+	if err := json.Unmarshal(body, &acknowledgement); err != nil || acknowledgement != AgentTokenAcknowledgement(newToken) {
+		// The agent may or may not have switched to the new token; it keeps being verified with the current one
+		return log.Errorf("RotateAgentToken: %s did not acknowledge its new token", hostname)
+	}
+	replaced, err := agentTokens.replace(hostname, token, newToken)
+	if err != nil {
+		return err
+	}
+	if !replaced {
+		return log.Errorf("RotateAgentToken: token of %s has changed during rotation", hostname)
+	}
+	agentTokens.audit("agent-token-rotated", &agent, "Rotated agent token")
+	return nil
+}
+
+// RotateAgentTokens rotates tokens of agents whose token is older than AgentTokenRotationMinutes
+func RotateAgentTokens() error {
+	if config.Config.AgentTokenRotationMinutes == 0 {
+		return nil
+	}
+	hostnames := []string{}
+	query := `
+		select
+			hostname
+		from
+			host_agent
+		where
+			IFNULL(token_rotated_at < now() - interval ? minute, true)
+			`
+	err := db.QueryOrchestrator(query, sqlutils.Args(config.Config.AgentTokenRotationMinutes), func(m sqlutils.RowMap) error {
+		hostnames = append(hostnames, m.GetString("hostname"))
+		return nil
+	})
+	if err != nil {
+		return log.Errore(err)
+	}
+	for _, hostname := range hostnames {
+		RotateAgentToken(hostname)
+	}
+	return nil
+}
+
+// RevokeAgentsWithUnverifiedTokens revokes agents which have not been seen with a valid token for
+// AgentTokenRevokeMinutes
+func RevokeAgentsWithUnverifiedTokens() error {
+	if config.Config.AgentTokenRevokeMinutes == 0 {
+		return nil
+	}
+	tokens := map[string]string{}
+	query := `
+		select
+			hostname,
+			token
+		from
+			host_agent
+		where
+			IFNULL(token_validated_at, last_submitted) < now() - interval ? minute
+			`
+	err := db.QueryOrchestrator(query, sqlutils.Args(config.Config.AgentTokenRevokeMinutes), func(m sqlutils.RowMap) error {
+		tokens[m.GetString("hostname")] = m.GetString("token")
+		return nil
+	})
+	if err != nil {
+		return log.Errore(err)
+	}
+	for hostname, token := range tokens {
+		RevokeAgent(hostname, token, fmt.Sprintf("Not seen with a valid token for %d minutes", config.Config.AgentTokenRevokeMinutes))
+	}
+	return nil
+}
@@ -0,0 +1,220 @@
+/*
+   Copyright 2014 Outbrain Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package agent
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+
+	test "github.com/outbrain/golib/tests"
+	"github.com/outbrain/orchestrator/go/config"
+)
+
+type memoryAgentTokenStore struct {
+	agents map[string]Agent
+	tokens map[string]string
+	audits []string
+}
+
+func (this *memoryAgentTokenStore) read(hostname string) (Agent, string, error) {
+	token, found := this.tokens[hostname]
+	if !found {
+		return Agent{}, "", fmt.Errorf("Agent not found: %s", hostname)
+	}
+	return this.agents[hostname], token, nil
+}
+
+func (this *memoryAgentTokenStore) markValidated(hostname string, token string) error {
+	return nil
+}
+
+func (this *memoryAgentTokenStore) replace(hostname string, token string, newToken string) (bool, error) {
+	if this.tokens[hostname] != token {
+		return false, nil
+	}
+	this.tokens[hostname] = newToken
+	return true, nil
+}
+
+func (this *memoryAgentTokenStore) revoke(hostname string, token string) (bool, error) {
+	if current, found := this.tokens[hostname]; !found || current != token {
+		return false, nil
+	}
+	delete(this.tokens, hostname)
+	return true, nil
+}
+
+func (this *memoryAgentTokenStore) audit(auditType string, agent *Agent, message string) {
+	this.audits = append(this.audits, auditType)
+}
+
+// withAgentServer runs f against an in-memory token store holding a single agent served by handler
+func withAgentServer(t *testing.T, handler http.HandlerFunc, f func(store *memoryAgentTokenStore)) {
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	serverUrl, _ := url.Parse(server.URL)
+	port, _ := strconv.Atoi(serverUrl.Port())
+
+	defer func(store agentTokenStore, useSSL bool) {
+		agentTokens, config.Config.AgentsUseSSL = store, useSSL
+	}(agentTokens, config.Config.AgentsUseSSL)
+	config.Config.AgentsUseSSL = false
+	store := &memoryAgentTokenStore{
+		agents: map[string]Agent{"db1": {Hostname: serverUrl.Hostname(), Port: port}},
+		tokens: map[string]string{"db1": "t1"},
+	}
+	agentTokens = store
+	f(store)
+}
+
+func TestRotateAgentTokenAcknowledged(t *testing.T) {
+	withAgentServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("token") != "t1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprintf(w, `"%s"`, AgentTokenAcknowledgement(r.Header.Get(AgentNewTokenHeader)))
+	}, func(store *memoryAgentTokenStore) {
+		test.S(t).ExpectNil(RotateAgentToken("db1"))
+		test.S(t).ExpectNotEquals(store.tokens["db1"], "t1")
+		test.S(t).ExpectEquals(len(store.tokens["db1"]), 64)
+		test.S(t).ExpectEquals(len(store.audits), 1)
+	})
+}
+
+func TestRotateAgentTokenNotAcknowledged(t *testing.T) {
+	withAgentServer(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `"ok"`)
+	}, func(store *memoryAgentTokenStore) {
+		test.S(t).ExpectNotNil(RotateAgentToken("db1"))
+		test.S(t).ExpectEquals(store.tokens["db1"], "t1")
+	})
+	withAgentServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}, func(store *memoryAgentTokenStore) {
+		test.S(t).ExpectNotNil(RotateAgentToken("db1"))
+		test.S(t).ExpectEquals(store.tokens["db1"], "t1")
+		test.S(t).ExpectEquals(len(store.audits), 0)
+	})
+}
+
+func TestRotateAgentTokenRejected(t *testing.T) {
+	withAgentServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}, func(store *memoryAgentTokenStore) {
+		test.S(t).ExpectNil(RotateAgentToken("db1"))
+		_, found := store.tokens["db1"]
+		test.S(t).ExpectFalse(found)
+		test.S(t).ExpectEquals(len(store.audits), 1)
+	})
+}
+
+func TestVerifyAgentToken(t *testing.T) {
+	withAgentServer(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "3306")
+	}, func(store *memoryAgentTokenStore) {
+		test.S(t).ExpectNil(VerifyAgentToken("db1"))
+		test.S(t).ExpectEquals(store.tokens["db1"], "t1")
+		test.S(t).ExpectEquals(len(store.audits), 0)
+	})
+	withAgentServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}, func(store *memoryAgentTokenStore) {
+		test.S(t).ExpectNil(VerifyAgentToken("db1"))
+		_, found := store.tokens["db1"]
+		test.S(t).ExpectFalse(found)
+	})
+}
+
+func TestRevokeAgentStaleToken(t *testing.T) {
+	withAgentServer(t, func(w http.ResponseWriter, r *http.Request) {}, func(store *memoryAgentTokenStore) {
+		test.S(t).ExpectNil(RevokeAgent("db1", "t0", "test"))
+		test.S(t).ExpectEquals(store.tokens["db1"], "t1")
+		test.S(t).ExpectEquals(len(store.audits), 0)
+
+		test.S(t).ExpectNil(RevokeAgent("db1", "t1", "test"))
+		_, found := store.tokens["db1"]
+		test.S(t).ExpectFalse(found)
+		test.S(t).ExpectEquals(len(store.audits), 1)
+	})
+}
+
+func TestReadResponseStatus(t *testing.T) {
+	respond := func(status int) ([]byte, error) {
+		recorder := httptest.NewRecorder()
+		recorder.WriteHeader(status)
+		recorder.WriteString("body")
+		return readResponse(recorder.Result(), nil)
+	}
+	body, err := respond(http.StatusOK)
+	test.S(t).ExpectNil(err)
+	test.S(t).ExpectEquals(string(body), "body")
+
+	_, err = respond(http.StatusUnauthorized)
+	test.S(t).ExpectEquals(err, ErrAgentUnauthorized)
+	_, err = respond(http.StatusForbidden)
+	test.S(t).ExpectEquals(err, ErrAgentUnauthorized)
+	_, err = respond(http.StatusNotFound)
+	test.S(t).ExpectNotNil(err)
+	_, err = respond(http.StatusFound)
+	test.S(t).ExpectNotNil(err)
+}
+
+func TestCheckHost(t *testing.T) {
+	mysqlStatus := http.StatusOK
+	withAgentServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/mysql-status":
+			w.WriteHeader(mysqlStatus)
+			if mysqlStatus == http.StatusOK {
+				fmt.Fprint(w, "true")
+			}
+		case "/api/mysql-error-log-tail":
+			fmt.Fprint(w, `["mysqld got signal 11"]`)
+		}
+	}, func(store *memoryAgentTokenStore) {
+		check := CheckHost("db1")
+		test.S(t).ExpectTrue(check.HasAgent)
+		test.S(t).ExpectTrue(check.HostReachable)
+		test.S(t).ExpectTrue(check.MySQLRunning)
+		test.S(t).ExpectFalse(check.Unauthorized)
+
+		// The agent answers with an error when mysqld is down
+		mysqlStatus = http.StatusInternalServerError
+		check = CheckHost("db1")
+		test.S(t).ExpectTrue(check.HostReachable)
+		test.S(t).ExpectFalse(check.MySQLRunning)
+		test.S(t).ExpectFalse(check.Unauthorized)
+		test.S(t).ExpectEquals(len(check.ErrorLogTail), 1)
+
+		mysqlStatus = http.StatusUnauthorized
+		check = CheckHost("db1")
+		test.S(t).ExpectTrue(check.HostReachable)
+		test.S(t).ExpectFalse(check.MySQLRunning)
+		test.S(t).ExpectTrue(check.Unauthorized)
+	})
+	withAgentServer(t, func(w http.ResponseWriter, r *http.Request) {}, func(store *memoryAgentTokenStore) {
+		store.agents["db1"] = Agent{Hostname: "127.0.0.1", Port: 1}
+		check := CheckHost("db1")
+		test.S(t).ExpectTrue(check.HasAgent)
+		test.S(t).ExpectFalse(check.HostReachable)
+	})
+}
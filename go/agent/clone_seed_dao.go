@@ -0,0 +1,252 @@
+/*
+   Copyright 2014 Outbrain Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/outbrain/golib/log"
+	"github.com/outbrain/orchestrator/go/config"
+	"github.com/outbrain/orchestrator/go/db"
+	"github.com/outbrain/orchestrator/go/inst"
+)
+
+// Error returned by CLONE INSTANCE when data is cloned but the recipient is not managed by a supervisor
+// process, and so cannot restart by itself
+const cloneRestartFailedErrorNumber = 3707
+
+// submitCloneSeedEntry submits a new clone seed entry, returning its unique ID
+func submitCloneSeedEntry(targetKey *inst.InstanceKey, donorKey *inst.InstanceKey, seedOptions *SeedOptions) (int64, error) {
+	res, err := db.ExecOrchestrator(`
+			insert 
+				into agent_seed (
+					target_hostname, target_port, source_hostname, source_port, seed_method, attach_replica, master_hostname, master_port, checksum_tables, start_timestamp
+				) VALUES (
+					?, ?, ?, ?, ?, ?, ?, ?, ?, NOW()
+				)
+			`,
+		targetKey.Hostname,
+		targetKey.Port,
+		donorKey.Hostname,
+		donorKey.Port,
+		SeedMethodClone,
+		true,
+		seedOptions.MasterKey.Hostname,
+		seedOptions.MasterKey.Port,
+		strings.Join(seedOptions.ChecksumTables, ","),
+	)
+	if err != nil {
+		return 0, log.Errore(err)
+	}
+	id, err := res.LastInsertId()
+
+	return id, err
+}
+
+// waitForClone follows a clone operation on target until it completes, recording progress. The target
+// restarts once data is cloned, and is expected to be unreachable meanwhile. The wait fails once no
+// progress is seen for StaleSeedFailMinutes.
+func waitForClone(seedId int64, targetKey *inst.InstanceKey, previousStatus *inst.CloneStatus, cloneResult chan error) error {
+	var seedStateId int64
+	var bytesCopied int64 = 0
+	var sampledAt time.Time
+	var cloneErr error
+	cloneReturned := false
+	lastProgressAt := time.Now()
+
+	for {
+		select {
+		case cloneErr = <-cloneResult:
+			cloneReturned = true
+			if mysqlErr, ok := cloneErr.(*mysql.MySQLError); ok && mysqlErr.Number == cloneRestartFailedErrorNumber {
+				seedStateId, _ = submitSeedStateEntry(seedId, fmt.Sprintf("Data cloned, but %+v cannot restart by itself. Please restart MySQL on target", *targetKey), "")
+			}
+		default:
+		}
+
+		cloneStatus, err := inst.ReadCloneStatus(targetKey)
+		isCurrent := err == nil && cloneStatus != nil && (previousStatus == nil || cloneStatus.Id != previousStatus.Id || cloneStatus.BeginTime != previousStatus.BeginTime)
+		switch {
+		case err != nil:
+			// Expected while target restarts
+			log.Debugf("waitForClone: cannot read clone status on %+v: %+v", *targetKey, err)
+		case isCurrent && cloneStatus.State == inst.CloneStateCompleted:
+			submitSeedStateEntry(seedId, fmt.Sprintf("Clone completed; copied %d bytes", cloneStatus.BytesCopied), "")
+			return nil
+		case isCurrent && cloneStatus.State == inst.CloneStateFailed:
+			seedStateId, _ = submitSeedStateEntry(seedId, fmt.Sprintf("Clone failed on stage %s", cloneStatus.Stage), "")
+			return updateSeedStateEntry(seedStateId, fmt.Errorf("Error %d: %s", cloneStatus.ErrorNumber, cloneStatus.ErrorMessage))
+		case isCurrent:
+			var progress SeedProgress
+			if sampledAt.IsZero() {
+				// First sample: no throughput to tell of yet
+				progress = NewSeedProgress(cloneStatus.BytesCopied, cloneStatus.BytesTotal, cloneStatus.BytesCopied, 0)
+			} else {
+				progress = NewSeedProgress(cloneStatus.BytesCopied, cloneStatus.BytesTotal, bytesCopied, time.Since(sampledAt))
+			}
+			if cloneStatus.BytesCopied != bytesCopied || sampledAt.IsZero() {
+				lastProgressAt = time.Now()
+			}
+			bytesCopied = cloneStatus.BytesCopied
+			sampledAt = time.Now()
+			seedStateId, _ = submitSeedProgressEntry(seedId, fmt.Sprintf("Cloning, stage %s: copied %d/%d bytes (%d%%)", cloneStatus.Stage, progress.BytesCopied, progress.BytesTotal, progress.PercentComplete()), progress)
+		case cloneReturned:
+			// CLONE INSTANCE ended, yet no new clone operation is recorded: it was rejected upfront
+			if cloneErr == nil {
+				cloneErr = errors.New("CLONE INSTANCE returned, yet no clone operation is recorded on target")
+			}
+			seedStateId, _ = submitSeedStateEntry(seedId, "CLONE INSTANCE rejected", "")
+			return updateSeedStateEntry(seedStateId, cloneErr)
+		}
+
+		if time.Since(lastProgressAt) >= time.Duration(config.Config.StaleSeedFailMinutes)*time.Minute {
+			if err == nil {
+				err = cloneErr
+			}
+			seedStateId, _ = submitSeedStateEntry(seedId, fmt.Sprintf("No clone progress seen for %d minutes. Bailing out", config.Config.StaleSeedFailMinutes), "")
+			return updateSeedStateEntry(seedStateId, fmt.Errorf("Clone stalled: %+v", err))
+		}
+		time.Sleep(10 * time.Second)
+	}
+}
+
+// executeCloneSeed clones donor's data onto target via the MySQL CLONE plugin, waits for the target to return,
+// and attaches it as a replica via GTID auto-positioning.
+func executeCloneSeed(seedId int64, targetKey *inst.InstanceKey, donorKey *inst.InstanceKey, seedOptions *SeedOptions) error {
+	seedStateId, _ := submitSeedStateEntry(seedId, fmt.Sprintf("Reading target instance %+v", *targetKey), "")
+	target, err := inst.ReadTopologyInstanceUnbuffered(targetKey)
+	if err != nil {
+		return updateSeedStateEntry(seedStateId, err)
+	}
+	seedStateId, _ = submitSeedStateEntry(seedId, fmt.Sprintf("Reading donor instance %+v", *donorKey), "")
+	donor, err := inst.ReadTopologyInstanceUnbuffered(donorKey)
+	if err != nil {
+		return updateSeedStateEntry(seedStateId, err)
+	}
+
+	seedStateId, _ = submitSeedStateEntry(seedId, "Checking CLONE support on target and donor", "")
+	for _, instance := range []*inst.Instance{target, donor} {
+		if !instance.SupportsClone() {
+			return updateSeedStateEntry(seedStateId, fmt.Errorf("%+v (version %s) does not support CLONE. MySQL 8.0.17 or above is required", instance.Key, instance.Version))
+		}
+	}
+	if target.IsSlave() {
+		seedStateId, _ = submitSeedStateEntry(seedId, fmt.Sprintf("Stopping replication on %+v", *targetKey), "")
+		if _, err = inst.StopSlave(targetKey); err != nil {
+			return updateSeedStateEntry(seedStateId, err)
+		}
+	}
+	previousStatus, err := inst.ReadCloneStatus(targetKey)
+	if err != nil {
+		return updateSeedStateEntry(seedStateId, err)
+	}
+
+	seedStateId, _ = submitSeedStateEntry(seedId, fmt.Sprintf("Cloning %+v from %+v", *targetKey, *donorKey), "")
+	cloneResult := make(chan error, 1)
+	go func() {
+		cloneResult <- inst.CloneInstance(targetKey, donorKey)
+	}()
+	if err = waitForClone(seedId, targetKey, previousStatus, cloneResult); err != nil {
+		return err
+	}
+
+	if target, err = readSeededInstance(seedId, targetKey); err != nil {
+		return err
+	}
+	naturalMasterKey := &donor.Key
+	if !donor.LogBinEnabled && donor.IsSlave() {
+		naturalMasterKey = &donor.MasterKey
+	}
+	if err = attachSeededReplica(seedId, target, donor, seedOptions, true, naturalMasterKey, nil); err != nil {
+		return err
+	}
+	if err = waitForSeededReplicaCatchUp(seedId, targetKey); err != nil {
+		return err
+	}
+	if err = compareSeedChecksums(seedId, targetKey, donorKey, seedOptions.ChecksumTables); err != nil {
+		return err
+	}
+
+	submitSeedStateEntry(seedId, "Done", "")
+	return nil
+}
+
+// submitCloneSeed validates a clone seed request and submits its entry
+func submitCloneSeed(targetKey *inst.InstanceKey, donorKey *inst.InstanceKey, seedOptions *SeedOptions) (int64, error) {
+	if targetKey.Equals(donorKey) {
+		return 0, log.Errorf("Cannot clone %+v onto itself", *targetKey)
+	}
+	seedOptions.Method = SeedMethodClone
+	seedOptions.AttachReplica = true
+	if err := validateSeedChecksumTables(seedOptions.ChecksumTables); err != nil {
+		return 0, log.Errore(err)
+	}
+	target, err := inst.ReadTopologyInstanceUnbuffered(targetKey)
+	if err != nil {
+		return 0, log.Errore(err)
+	}
+	masters, err := inst.ReadClusterWriteableMaster(target.ClusterName)
+	if err != nil {
+		return 0, log.Errore(err)
+	}
+	isWriteableClusterMaster := len(masters) > 0 && masters[0].Key.Equals(targetKey)
+	if err := validateCloneTarget(target, isWriteableClusterMaster, seedOptions.Force); err != nil {
+		return 0, log.Errore(err)
+	}
+	for _, hostname := range []string{targetKey.Hostname, donorKey.Hostname} {
+		activeSeeds, err := ReadActiveSeedsForHost(hostname)
+		if err != nil {
+			return 0, log.Errore(err)
+		}
+		if len(activeSeeds) > 0 {
+			return 0, log.Errorf("%s participates in active seed %d", hostname, activeSeeds[0].SeedId)
+		}
+	}
+	return submitCloneSeedEntry(targetKey, donorKey, seedOptions)
+}
+
+// CloneSeed is the entry point for seeding target off donor via the MySQL CLONE plugin. No agents are involved:
+// MySQL is expected to be running on both. Once cloned, target is attached as a replica via GTID, and is
+// followed up just like agent seeds with AttachReplica. The seed runs in the background.
+func CloneSeed(targetKey *inst.InstanceKey, donorKey *inst.InstanceKey, seedOptions *SeedOptions) (int64, error) {
+	seedId, err := submitCloneSeed(targetKey, donorKey, seedOptions)
+	if err != nil {
+		return 0, err
+	}
+
+	go func() {
+		err := executeCloneSeed(seedId, targetKey, donorKey, seedOptions)
+		updateSeedComplete(seedId, err)
+	}()
+
+	return seedId, nil
+}
+
+// CloneInstance seeds target off donor via the MySQL CLONE plugin just as CloneSeed does, returning once done
+func CloneInstance(targetKey *inst.InstanceKey, donorKey *inst.InstanceKey, seedOptions *SeedOptions) (int64, error) {
+	seedId, err := submitCloneSeed(targetKey, donorKey, seedOptions)
+	if err != nil {
+		return 0, err
+	}
+	err = executeCloneSeed(seedId, targetKey, donorKey, seedOptions)
+	updateSeedComplete(seedId, err)
+	return seedId, err
+}
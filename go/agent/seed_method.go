@@ -0,0 +1,167 @@
+/*
+   Copyright 2014 Outbrain Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package agent
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/outbrain/orchestrator/go/inst"
+)
+
+const (
+	SeedMethodLVM        = "lvm"
+	SeedMethodXtrabackup = "xtrabackup"
+	// SeedMethodClone is not an agent seed method: clone seeds are taken via CloneSeed, directly over MySQL
+	SeedMethodClone = "clone"
+)
+
+// SeedMethod is a way of copying MySQL data from a source host onto a target host. Each step taken
+// by a method is to be recorded via submitSeedStateEntry.
+type SeedMethod interface {
+	// Copy transfers the data. Upon success, the target's datadir is ready for MySQL to start.
+	// With resume, a method may pick up a previously failed transfer rather than start afresh.
+	Copy(seedId int64, targetAgent *Agent, sourceAgent *Agent, resume bool) error
+	// Attach runs once MySQL is started on the target, e.g. to set up replication
+	Attach(seedId int64, targetAgent *Agent, sourceAgent *Agent, seedOptions *SeedOptions) error
+}
+
+// seedMethods lists known seed methods by name
+var seedMethods = map[string]SeedMethod{
+	SeedMethodLVM:        &lvmSeedMethod{},
+	SeedMethodXtrabackup: &xtrabackupSeedMethod{},
+}
+
+// GetSeedMethod returns a seed method by name
+func GetSeedMethod(name string) (SeedMethod, error) {
+	if seedMethod, found := seedMethods[name]; found {
+		return seedMethod, nil
+	}
+	if name == SeedMethodClone {
+		return nil, fmt.Errorf("Seed method %s does not use agents; use clone-instance instead", name)
+	}
+	return nil, fmt.Errorf("Unknown seed method: %s", name)
+}
+
+// XtrabackupInfo holds the replication information files produced by xtrabackup along with a backup
+type XtrabackupInfo struct {
+	BinlogInfo string // Content of xtrabackup_binlog_info: the source's own binary log coordinates
+	SlaveInfo  string // Content of xtrabackup_slave_info: the source's master coordinates, if the source is a replica
+}
+
+// parseXtrabackupBinlogInfo parses xtrabackup_binlog_info, of the form "<file>\t<position>[\t<gtid set>]"
+func parseXtrabackupBinlogInfo(binlogInfo string) (coordinates *inst.BinlogCoordinates, gtidSet string, err error) {
+	tokens := strings.Fields(strings.TrimSpace(binlogInfo))
+	if len(tokens) < 2 {
+		return nil, "", fmt.Errorf("Cannot parse xtrabackup_binlog_info: %s", binlogInfo)
+	}
+	logPos, err := strconv.ParseInt(tokens[1], 10, 0)
+	if err != nil {
+		return nil, "", fmt.Errorf("Cannot parse position in xtrabackup_binlog_info: %s", binlogInfo)
+	}
+	if len(tokens) > 2 {
+		gtidSet = strings.Join(tokens[2:], "")
+	}
+	return &inst.BinlogCoordinates{LogFile: tokens[0], LogPos: logPos}, gtidSet, nil
+}
+
+var (
+	xtrabackupSlaveInfoLogFileRegexp = regexp.MustCompile(`(?i)MASTER_LOG_FILE\s*=\s*'([^']+)'`)
+	xtrabackupSlaveInfoLogPosRegexp  = regexp.MustCompile(`(?i)MASTER_LOG_POS\s*=\s*([0-9]+)`)
+)
+
+// parseXtrabackupSlaveInfo parses xtrabackup_slave_info, of the form
+// "CHANGE MASTER TO MASTER_LOG_FILE='<file>', MASTER_LOG_POS=<position>"
+func parseXtrabackupSlaveInfo(slaveInfo string) (coordinates *inst.BinlogCoordinates, err error) {
+	logFileSubmatch := xtrabackupSlaveInfoLogFileRegexp.FindStringSubmatch(slaveInfo)
+	logPosSubmatch := xtrabackupSlaveInfoLogPosRegexp.FindStringSubmatch(slaveInfo)
+	if len(logFileSubmatch) < 2 || len(logPosSubmatch) < 2 {
+		return nil, fmt.Errorf("Cannot parse xtrabackup_slave_info: %s", slaveInfo)
+	}
+	logPos, err := strconv.ParseInt(logPosSubmatch[1], 10, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &inst.BinlogCoordinates{LogFile: logFileSubmatch[1], LogPos: logPos}, nil
+}
+
+// getXtrabackupReplicationSource decides which master a replica seeded off given source should replicate from,
+// and at which coordinates. A source with binary logs becomes the master; otherwise the replica becomes a
+// sibling of the source, under the source's own master.
+func getXtrabackupReplicationSource(source *inst.Instance, info *XtrabackupInfo) (masterKey *inst.InstanceKey, coordinates *inst.BinlogCoordinates, err error) {
+	if source.LogBinEnabled && strings.TrimSpace(info.BinlogInfo) != "" {
+		coordinates, _, err = parseXtrabackupBinlogInfo(info.BinlogInfo)
+		if err != nil {
+			return nil, nil, err
+		}
+		return &source.Key, coordinates, nil
+	}
+	if source.IsSlave() && strings.TrimSpace(info.SlaveInfo) != "" {
+		coordinates, err = parseXtrabackupSlaveInfo(info.SlaveInfo)
+		if err != nil {
+			return nil, nil, err
+		}
+		return &source.MasterKey, coordinates, nil
+	}
+	return nil, nil, fmt.Errorf("Cannot deduce replication coordinates for replica of %+v: source has no binary logs and no xtrabackup_slave_info is available", source.Key)
+}
+
+// resolveSeedReplicationSource decides how a seeded replica is to replicate from given master. naturalMasterKey &
+// naturalCoordinates are the master and position the copied data is known to be consistent with, if any.
+// gtidPositioned indicates the target's executed GTID set reflects the copied data.
+// GTID is preferred where it does and both target and master support it; otherwise the natural coordinates are required.
+func resolveSeedReplicationSource(target *inst.Instance, master *inst.Instance, gtidPositioned bool, naturalMasterKey *inst.InstanceKey, naturalCoordinates *inst.BinlogCoordinates) (coordinates *inst.BinlogCoordinates, useGTID bool, err error) {
+	if gtidPositioned && target.SupportsOracleGTID && master.SupportsOracleGTID {
+		return &inst.BinlogCoordinates{}, true, nil
+	}
+	if naturalMasterKey == nil || naturalCoordinates == nil || naturalCoordinates.LogFile == "" {
+		return nil, false, fmt.Errorf("Cannot attach %+v to %+v: GTID is unavailable and replication coordinates are unknown", target.Key, master.Key)
+	}
+	if !master.Key.Equals(naturalMasterKey) {
+		return nil, false, fmt.Errorf("Cannot attach %+v to %+v: GTID is unavailable and replication coordinates are only known relative to %+v", target.Key, master.Key, *naturalMasterKey)
+	}
+	return naturalCoordinates, false, nil
+}
+
+var seedChecksumTableRegexp = regexp.MustCompile(`^[0-9a-zA-Z$_]+[.][0-9a-zA-Z$_]+$`)
+
+// validateCloneTarget verifies a clone does not wipe an instance others depend on: CLONE INSTANCE replaces all of the
+// target's data. Unless forced, the target may be neither the writable master of its cluster nor have replicas.
+func validateCloneTarget(target *inst.Instance, isWriteableClusterMaster bool, force bool) error {
+	if force {
+		return nil
+	}
+	if isWriteableClusterMaster {
+		return fmt.Errorf("%+v is the writable master of %s; cloning would wipe its data. Force to override", target.Key, target.ClusterName)
+	}
+	if len(target.SlaveHosts) > 0 {
+		return fmt.Errorf("%+v has %d replicas; cloning would wipe its data. Force to override", target.Key, len(target.SlaveHosts))
+	}
+	return nil
+}
+
+// validateSeedChecksumTables verifies tables are given in plain schema.table form
+func validateSeedChecksumTables(tableNames []string) error {
+	for _, tableName := range tableNames {
+		if !seedChecksumTableRegexp.MatchString(tableName) {
+			return fmt.Errorf("Invalid checksum table: %s. Expected schema.table", tableName)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,514 @@
+/*
+   Copyright 2014 Outbrain Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/outbrain/golib/log"
+	"github.com/outbrain/orchestrator/go/config"
+	"github.com/outbrain/orchestrator/go/inst"
+)
+
+// waitForSeedCopy follows a running copy on the target host until completion, recording progress,
+// throughput and estimated time to completion. On failure it aborts the seed on both hosts and invokes cleanup.
+func waitForSeedCopy(seedId int64, targetHostname string, sourceHostname string, expectedBytes int64, cleanup func()) error {
+	var seedStateId int64
+	copyComplete := false
+	numStaleIterations := 0
+	var bytesCopied int64 = 0
+	var sampledAt time.Time
+
+	for !copyComplete {
+		targetAgentPoll, err := GetAgent(targetHostname)
+		if err != nil {
+			return log.Errore(err)
+		}
+
+		if targetAgentPoll.MySQLDiskUsage == bytesCopied {
+			numStaleIterations++
+		}
+		var progress SeedProgress
+		if sampledAt.IsZero() {
+			// First sample: no throughput to tell of yet
+			progress = NewSeedProgress(targetAgentPoll.MySQLDiskUsage, expectedBytes, targetAgentPoll.MySQLDiskUsage, 0)
+		} else {
+			progress = NewSeedProgress(targetAgentPoll.MySQLDiskUsage, expectedBytes, bytesCopied, time.Since(sampledAt))
+		}
+		bytesCopied = targetAgentPoll.MySQLDiskUsage
+		sampledAt = time.Now()
+
+		copyFailed := false
+		if _, commandCompleted, _ := seedCommandCompleted(targetHostname, seedId); commandCompleted {
+			copyComplete = true
+			if _, commandSucceeded, _ := seedCommandSucceeded(targetHostname, seedId); !commandSucceeded {
+				// failed.
+				copyFailed = true
+			}
+		}
+		if numStaleIterations > 10 {
+			copyFailed = true
+		}
+		if copyFailed {
+			AbortSeedCommand(sourceHostname, seedId)
+			AbortSeedCommand(targetHostname, seedId)
+			cleanup()
+			return updateSeedStateEntry(seedStateId, errors.New("10 iterations have passed without progress. Bailing out."))
+		}
+
+		seedStateId, _ = submitSeedProgressEntry(seedId, fmt.Sprintf("Copied %d/%d bytes (%d%%)", progress.BytesCopied, progress.BytesTotal, progress.PercentComplete()), progress)
+
+		if !copyComplete {
+			time.Sleep(30 * time.Second)
+		}
+	}
+	return nil
+}
+
+// lvmSeedMethod copies the MySQL data off an LVM snapshot on the source host
+type lvmSeedMethod struct{}
+
+func (this *lvmSeedMethod) Copy(seedId int64, targetAgent *Agent, sourceAgent *Agent, resume bool) error {
+	var err error
+	var seedStateId int64
+	targetHostname := targetAgent.Hostname
+	sourceHostname := sourceAgent.Hostname
+
+	seedStateId, _ = submitSeedStateEntry(seedId, fmt.Sprintf("Looking up available snapshots on source %s", sourceHostname), "")
+	if len(sourceAgent.LogicalVolumes) == 0 {
+		return updateSeedStateEntry(seedStateId, errors.New("No logical volumes found on source host"))
+	}
+
+	seedStateId, _ = submitSeedStateEntry(seedId, fmt.Sprintf("Checking mount point on source %s", sourceHostname), "")
+	if sourceAgent.MountPoint.IsMounted {
+		return updateSeedStateEntry(seedStateId, errors.New("Volume already mounted on source host; please unmount"))
+	}
+
+	seedFromLogicalVolume := sourceAgent.LogicalVolumes[0]
+	seedStateId, _ = submitSeedStateEntry(seedId, fmt.Sprintf("Mounting logical volume: %s", seedFromLogicalVolume.Path), "")
+	_, err = MountLV(sourceHostname, seedFromLogicalVolume.Path)
+	if err != nil {
+		return updateSeedStateEntry(seedStateId, err)
+	}
+	*sourceAgent, err = GetAgent(sourceHostname)
+	seedStateId, _ = submitSeedStateEntry(seedId, fmt.Sprintf("MySQL data volume on source host %s is %d bytes", sourceHostname, sourceAgent.MountPoint.MySQLDiskUsage), "")
+
+	lastCompletedFile := ""
+	if resume {
+		seedStateId, _ = submitSeedStateEntry(seedId, fmt.Sprintf("Looking up resume point on %s", targetHostname), "")
+		lastCompletedFile, err = seedResumePoint(targetHostname, seedId)
+		if err != nil {
+			// e.g. the agent does not support resuming
+			lastCompletedFile = ""
+			seedStateId, _ = submitSeedStateEntry(seedId, fmt.Sprintf("Cannot resume on %s; falling back to copying all data", targetHostname), err.Error())
+		} else if lastCompletedFile == "" {
+			seedStateId, _ = submitSeedStateEntry(seedId, fmt.Sprintf("No resume point available on %s; falling back to copying all data", targetHostname), "No completely received file")
+		} else {
+			seedStateId, _ = submitSeedStateEntry(seedId, fmt.Sprintf("Resuming copy after %s", lastCompletedFile), "")
+		}
+	}
+
+	if lastCompletedFile == "" {
+		seedStateId, _ = submitSeedStateEntry(seedId, fmt.Sprintf("Erasing MySQL data on %s", targetHostname), "")
+		_, err = deleteMySQLDatadir(targetHostname)
+		if err != nil {
+			return updateSeedStateEntry(seedStateId, err)
+		}
+	}
+
+	seedStateId, _ = submitSeedStateEntry(seedId, fmt.Sprintf("Aquiring target host datadir free space on %s", targetHostname), "")
+	*targetAgent, err = GetAgent(targetHostname)
+	if err != nil {
+		return updateSeedStateEntry(seedStateId, err)
+	}
+
+	requiredBytes := sourceAgent.MountPoint.MySQLDiskUsage
+	if lastCompletedFile != "" {
+		requiredBytes -= targetAgent.MySQLDiskUsage
+	}
+	if requiredBytes > targetAgent.MySQLDatadirDiskFree {
+		Unmount(sourceHostname)
+		return updateSeedStateEntry(seedStateId, fmt.Errorf("Not enough disk space on target host %s. Required: %d, available: %d. Bailing out.", targetHostname, requiredBytes, targetAgent.MySQLDatadirDiskFree))
+	}
+
+	// ...
+	seedStateId, _ = submitSeedStateEntry(seedId, fmt.Sprintf("%s will now receive data in background", targetHostname), "")
+	if lastCompletedFile == "" {
+		ReceiveMySQLSeedData(targetHostname, seedId)
+	} else {
+		ResumeReceiveMySQLSeedData(targetHostname, seedId)
+	}
+
+	seedStateId, _ = submitSeedStateEntry(seedId, fmt.Sprintf("Waiting some time for %s to start listening for incoming data", targetHostname), "")
+	time.Sleep(2 * time.Second)
+
+	seedStateId, _ = submitSeedStateEntry(seedId, fmt.Sprintf("%s will now send data to %s in background", sourceHostname, targetHostname), "")
+	if lastCompletedFile == "" {
+		SendMySQLSeedData(sourceHostname, targetHostname, seedId)
+	} else {
+		ResumeSendMySQLSeedData(sourceHostname, targetHostname, seedId, lastCompletedFile)
+	}
+
+	if err := waitForSeedCopy(seedId, targetHostname, sourceHostname, sourceAgent.MountPoint.MySQLDiskUsage, func() { Unmount(sourceHostname) }); err != nil {
+		return err
+	}
+
+	// Cleanup:
+	seedStateId, _ = submitSeedStateEntry(seedId, fmt.Sprintf("Executing post-copy command on %s", targetHostname), "")
+	_, err = PostCopy(targetHostname)
+	if err != nil {
+		return updateSeedStateEntry(seedStateId, err)
+	}
+
+	seedStateId, _ = submitSeedStateEntry(seedId, fmt.Sprintf("Unmounting logical volume: %s", seedFromLogicalVolume.Path), "")
+	_, err = Unmount(sourceHostname)
+	if err != nil {
+		return updateSeedStateEntry(seedStateId, err)
+	}
+	return nil
+}
+
+// Attach only applies when requested. A snapshot of a replica carries the replica's own replication position,
+// which is consistent with the data; a snapshot of a master is attached via GTID only.
+func (this *lvmSeedMethod) Attach(seedId int64, targetAgent *Agent, sourceAgent *Agent, seedOptions *SeedOptions) error {
+	if !seedOptions.AttachReplica {
+		return nil
+	}
+	seedStateId, _ := submitSeedStateEntry(seedId, fmt.Sprintf("Reading source instance %s", sourceAgent.Hostname), "")
+	source, err := inst.ReadTopologyInstanceUnbuffered(sourceAgent.GetInstance())
+	if err != nil {
+		return updateSeedStateEntry(seedStateId, err)
+	}
+	target, err := readSeededInstance(seedId, targetAgent.GetInstance())
+	if err != nil {
+		return err
+	}
+	if target.SlaveRunning() {
+		// MySQL may have started replicating on its own; take position after it stops
+		seedStateId, _ = submitSeedStateEntry(seedId, fmt.Sprintf("Stopping replication on %+v", target.Key), "")
+		if target, err = inst.StopSlave(&target.Key); err != nil {
+			return updateSeedStateEntry(seedStateId, err)
+		}
+	}
+	var naturalMasterKey *inst.InstanceKey
+	var naturalCoordinates *inst.BinlogCoordinates
+	if target.IsSlave() {
+		naturalMasterKey = &target.MasterKey
+		naturalCoordinates = &target.ExecBinlogCoordinates
+	} else {
+		naturalMasterKey = &source.Key
+	}
+	return attachSeededReplica(seedId, target, source, seedOptions, true, naturalMasterKey, naturalCoordinates)
+}
+
+// xtrabackupSeedMethod streams a hot backup, taken by xtrabackup on the source host, onto the target host,
+// where it is prepared. The new replica is then pointed at the right master based on the coordinates
+// recorded by xtrabackup.
+type xtrabackupSeedMethod struct{}
+
+func (this *xtrabackupSeedMethod) Copy(seedId int64, targetAgent *Agent, sourceAgent *Agent, resume bool) error {
+	var err error
+	var seedStateId int64
+	targetHostname := targetAgent.Hostname
+	sourceHostname := sourceAgent.Hostname
+
+	if resume {
+		// A backup stream is consistent only as a whole
+		seedStateId, _ = submitSeedStateEntry(seedId, "xtrabackup streams cannot be resumed; copying all data", "")
+	}
+
+	seedStateId, _ = submitSeedStateEntry(seedId, fmt.Sprintf("Checking MySQL status on source %s", sourceHostname), "")
+	if !sourceAgent.MySQLRunning {
+		return updateSeedStateEntry(seedStateId, errors.New("MySQL is not running on source host; xtrabackup requires a running server"))
+	}
+	seedStateId, _ = submitSeedStateEntry(seedId, fmt.Sprintf("MySQL data on source host %s is %d bytes", sourceHostname, sourceAgent.MySQLDiskUsage), "")
+
+	seedStateId, _ = submitSeedStateEntry(seedId, fmt.Sprintf("Erasing MySQL data on %s", targetHostname), "")
+	_, err = deleteMySQLDatadir(targetHostname)
+	if err != nil {
+		return updateSeedStateEntry(seedStateId, err)
+	}
+
+	seedStateId, _ = submitSeedStateEntry(seedId, fmt.Sprintf("Aquiring target host datadir free space on %s", targetHostname), "")
+	*targetAgent, err = GetAgent(targetHostname)
+	if err != nil {
+		return updateSeedStateEntry(seedStateId, err)
+	}
+	if sourceAgent.MySQLDiskUsage > targetAgent.MySQLDatadirDiskFree {
+		return updateSeedStateEntry(seedStateId, fmt.Errorf("Not enough disk space on target host %s. Required: %d, available: %d. Bailing out.", targetHostname, sourceAgent.MySQLDiskUsage, targetAgent.MySQLDatadirDiskFree))
+	}
+
+	seedStateId, _ = submitSeedStateEntry(seedId, fmt.Sprintf("%s will now receive xtrabackup stream in background", targetHostname), "")
+	if _, err = ReceiveXtrabackupSeedData(targetHostname, seedId); err != nil {
+		return updateSeedStateEntry(seedStateId, err)
+	}
+
+	seedStateId, _ = submitSeedStateEntry(seedId, fmt.Sprintf("Waiting some time for %s to start listening for incoming data", targetHostname), "")
+	time.Sleep(2 * time.Second)
+
+	seedStateId, _ = submitSeedStateEntry(seedId, fmt.Sprintf("%s will now stream xtrabackup to %s in background", sourceHostname, targetHostname), "")
+	if _, err = SendXtrabackupSeedData(sourceHostname, targetHostname, seedId); err != nil {
+		AbortSeedCommand(targetHostname, seedId)
+		return updateSeedStateEntry(seedStateId, err)
+	}
+
+	if err := waitForSeedCopy(seedId, targetHostname, sourceHostname, sourceAgent.MySQLDiskUsage, func() {}); err != nil {
+		return err
+	}
+
+	seedStateId, _ = submitSeedStateEntry(seedId, fmt.Sprintf("Preparing backup on %s", targetHostname), "")
+	if _, err = PrepareXtrabackupSeed(targetHostname, seedId); err != nil {
+		return updateSeedStateEntry(seedStateId, err)
+	}
+	// "Still preparing" state entries keep the seed from going stale, hence the explicit deadline
+	prepareStartedAt := time.Now()
+	for {
+		_, prepareCompleted, err := xtrabackupPrepareCompleted(targetHostname, seedId)
+		if err != nil {
+			return updateSeedStateEntry(seedStateId, err)
+		}
+		if prepareCompleted {
+			break
+		}
+		if time.Since(prepareStartedAt) >= time.Duration(config.Config.StaleSeedFailMinutes)*time.Minute {
+			AbortSeedCommand(targetHostname, seedId)
+			return updateSeedStateEntry(seedStateId, fmt.Errorf("xtrabackup --prepare on %s not completed within %d minutes. Bailing out.", targetHostname, config.Config.StaleSeedFailMinutes))
+		}
+		time.Sleep(30 * time.Second)
+		seedStateId, _ = submitSeedStateEntry(seedId, fmt.Sprintf("Still preparing backup on %s", targetHostname), "")
+	}
+	if _, prepareSucceeded, _ := xtrabackupPrepareSucceeded(targetHostname, seedId); !prepareSucceeded {
+		return updateSeedStateEntry(seedStateId, fmt.Errorf("xtrabackup --prepare failed on %s", targetHostname))
+	}
+	return nil
+}
+
+func (this *xtrabackupSeedMethod) Attach(seedId int64, targetAgent *Agent, sourceAgent *Agent, seedOptions *SeedOptions) error {
+	var err error
+	var seedStateId int64
+	targetHostname := targetAgent.Hostname
+
+	seedStateId, _ = submitSeedStateEntry(seedId, fmt.Sprintf("Reading xtrabackup replication info on %s", targetHostname), "")
+	xtrabackupInfo, err := ReadXtrabackupInfo(targetHostname, seedId)
+	if err != nil {
+		return updateSeedStateEntry(seedStateId, err)
+	}
+
+	seedStateId, _ = submitSeedStateEntry(seedId, fmt.Sprintf("Reading source instance %s", sourceAgent.Hostname), "")
+	source, err := inst.ReadTopologyInstanceUnbuffered(sourceAgent.GetInstance())
+	if err != nil {
+		return updateSeedStateEntry(seedStateId, err)
+	}
+	naturalMasterKey, naturalCoordinates, err := getXtrabackupReplicationSource(source, xtrabackupInfo)
+	if err != nil {
+		return updateSeedStateEntry(seedStateId, err)
+	}
+
+	target, err := readSeededInstance(seedId, targetAgent.GetInstance())
+	if err != nil {
+		return err
+	}
+	// A prepared backup carries no GTID state of its own; the GTID set it is consistent with is only recorded
+	// in xtrabackup_binlog_info. Without it, the replica can only be positioned by coordinates.
+	gtidPositioned := false
+	if _, gtidSet, _ := parseXtrabackupBinlogInfo(xtrabackupInfo.BinlogInfo); gtidSet != "" && target.SupportsOracleGTID {
+		seedStateId, _ = submitSeedStateEntry(seedId, fmt.Sprintf("Setting gtid_purged on %+v", target.Key), "")
+		if _, err = inst.SetGTIDPurged(&target.Key, gtidSet); err != nil {
+			return updateSeedStateEntry(seedStateId, err)
+		}
+		gtidPositioned = true
+	}
+	return attachSeededReplica(seedId, target, source, seedOptions, gtidPositioned, naturalMasterKey, naturalCoordinates)
+}
+
+// readSeededInstance reads the freshly seeded MySQL instance on target host, allowing for it to start up
+func readSeededInstance(seedId int64, targetKey *inst.InstanceKey) (target *inst.Instance, err error) {
+	seedStateId, _ := submitSeedStateEntry(seedId, fmt.Sprintf("Reading target instance %+v", *targetKey), "")
+	for i := 0; i < 10; i++ {
+		if target, err = inst.ReadTopologyInstanceUnbuffered(targetKey); err == nil {
+			return target, nil
+		}
+		time.Sleep(5 * time.Second)
+	}
+	return nil, updateSeedStateEntry(seedStateId, err)
+}
+
+// attachSeededReplica points a freshly seeded replica at the requested master (or else at the natural master
+// the copied data is consistent with), using GTID (where gtidPositioned) or the natural coordinates, and starts replication.
+func attachSeededReplica(seedId int64, target *inst.Instance, source *inst.Instance, seedOptions *SeedOptions, gtidPositioned bool, naturalMasterKey *inst.InstanceKey, naturalCoordinates *inst.BinlogCoordinates) error {
+	var err error
+	var seedStateId int64
+
+	masterKey := naturalMasterKey
+	if seedOptions.MasterKey.IsValid() {
+		masterKey = &seedOptions.MasterKey
+	}
+	if masterKey == nil {
+		seedStateId, _ = submitSeedStateEntry(seedId, fmt.Sprintf("Choosing master for %+v", target.Key), "")
+		return updateSeedStateEntry(seedStateId, fmt.Errorf("No master requested and none can be deduced for %+v", target.Key))
+	}
+
+	seedStateId, _ = submitSeedStateEntry(seedId, fmt.Sprintf("Reading master instance %+v", *masterKey), "")
+	master, err := inst.ReadTopologyInstanceUnbuffered(masterKey)
+	if err != nil {
+		return updateSeedStateEntry(seedStateId, err)
+	}
+	coordinates, useGTID, err := resolveSeedReplicationSource(target, master, gtidPositioned, naturalMasterKey, naturalCoordinates)
+	if err != nil {
+		return updateSeedStateEntry(seedStateId, err)
+	}
+
+	gtidHint := inst.GTIDHintDeny
+	if useGTID {
+		gtidHint = inst.GTIDHintForce
+		seedStateId, _ = submitSeedStateEntry(seedId, fmt.Sprintf("Changing master of %+v to %+v via GTID", target.Key, master.Key), "")
+	} else {
+		seedStateId, _ = submitSeedStateEntry(seedId, fmt.Sprintf("Changing master of %+v to %+v at %+v", target.Key, master.Key, *coordinates), "")
+	}
+	if target.SlaveRunning() {
+		if _, err = inst.StopSlave(&target.Key); err != nil {
+			return updateSeedStateEntry(seedStateId, err)
+		}
+	}
+	if target, err = inst.ChangeMasterTo(&target.Key, &master.Key, coordinates, false, gtidHint); err != nil {
+		return updateSeedStateEntry(seedStateId, err)
+	}
+	if !target.HasReplicationCredentials && source.ReplicationCredentialsAvailable {
+		seedStateId, _ = submitSeedStateEntry(seedId, fmt.Sprintf("Copying replication credentials from %+v", source.Key), "")
+		replicationUser, replicationPassword, err := inst.ReadReplicationCredentials(&source.Key)
+		if err != nil {
+			return updateSeedStateEntry(seedStateId, err)
+		}
+		if _, err = inst.ChangeMasterCredentials(&target.Key, replicationUser, replicationPassword); err != nil {
+			return updateSeedStateEntry(seedStateId, err)
+		}
+	}
+
+	seedStateId, _ = submitSeedStateEntry(seedId, fmt.Sprintf("Starting replication on %+v", target.Key), "")
+	if _, err = inst.StartSlave(&target.Key); err != nil {
+		return updateSeedStateEntry(seedStateId, err)
+	}
+	return nil
+}
+
+// waitForSeededReplicaCatchUp waits for a freshly attached replica to replicate with reasonable lag
+func waitForSeededReplicaCatchUp(seedId int64, targetKey *inst.InstanceKey) error {
+	seedStateId, _ := submitSeedStateEntry(seedId, fmt.Sprintf("Waiting for %+v to catch up with its master", *targetKey), "")
+	timeout := time.After(time.Duration(config.Config.SeedCatchUpTimeoutMinutes) * time.Minute)
+	for {
+		target, err := inst.ReadTopologyInstanceUnbuffered(targetKey)
+		if err != nil {
+			return updateSeedStateEntry(seedStateId, err)
+		}
+		if !target.SlaveRunning() {
+			return updateSeedStateEntry(seedStateId, fmt.Errorf("Replication is not running on %+v. IO error: %s; SQL error: %s", *targetKey, target.LastIOError, target.LastSQLError))
+		}
+		if target.SecondsBehindMaster.Valid && target.SecondsBehindMaster.Int64 <= int64(config.Config.ReasonableReplicationLagSeconds) {
+			submitSeedStateEntry(seedId, fmt.Sprintf("%+v has caught up; lag is %d seconds", *targetKey, target.SecondsBehindMaster.Int64), "")
+			return nil
+		}
+		select {
+		case <-timeout:
+			return updateSeedStateEntry(seedStateId, fmt.Errorf("%+v did not catch up within %d minutes", *targetKey, config.Config.SeedCatchUpTimeoutMinutes))
+		case <-time.After(30 * time.Second):
+		}
+		if target.SecondsBehindMaster.Valid {
+			seedStateId, _ = submitSeedStateEntry(seedId, fmt.Sprintf("%+v is %d seconds behind its master", *targetKey, target.SecondsBehindMaster.Int64), "")
+		}
+	}
+}
+
+// compareSeedChecksums compares CHECKSUM TABLE of given tables between the seeded replica and the source.
+// Replication on the two is stopped and aligned at the same position for the duration of the comparison,
+// where their topology allows. A writable source has the compared tables read locked meanwhile.
+func compareSeedChecksums(seedId int64, targetKey *inst.InstanceKey, sourceKey *inst.InstanceKey, tableNames []string) error {
+	if len(tableNames) == 0 {
+		return nil
+	}
+	seedStateId, _ := submitSeedStateEntry(seedId, fmt.Sprintf("Stopping replication on %+v for checksum comparison", *targetKey), "")
+	target, err := inst.StopSlave(targetKey)
+	if err != nil {
+		return updateSeedStateEntry(seedStateId, err)
+	}
+	defer inst.StartSlave(targetKey)
+
+	source, err := inst.ReadTopologyInstanceUnbuffered(sourceKey)
+	if err != nil {
+		return updateSeedStateEntry(seedStateId, err)
+	}
+	if source.IsSlave() && (target.MasterKey.Equals(sourceKey) || target.MasterKey.Equals(&source.MasterKey)) {
+		seedStateId, _ = submitSeedStateEntry(seedId, fmt.Sprintf("Stopping replication on %+v for checksum comparison", *sourceKey), "")
+		if source, err = inst.StopSlave(sourceKey); err != nil {
+			return updateSeedStateEntry(seedStateId, err)
+		}
+		defer inst.StartSlave(sourceKey)
+	}
+
+	seedStateId, _ = submitSeedStateEntry(seedId, fmt.Sprintf("Aligning %+v with %+v", *targetKey, *sourceKey), "")
+	switch {
+	case target.MasterKey.Equals(sourceKey) && !source.SlaveRunning():
+		if !source.ReadOnly {
+			// source takes writes; keep the compared tables static, then take its position
+			seedStateId, _ = submitSeedStateEntry(seedId, fmt.Sprintf("Read locking compared tables on writable %+v", *sourceKey), "")
+			unlock, err := inst.ReadLockTables(sourceKey, tableNames)
+			if err != nil {
+				return updateSeedStateEntry(seedStateId, err)
+			}
+			defer unlock()
+			if source, err = inst.ReadTopologyInstanceUnbuffered(sourceKey); err != nil {
+				return updateSeedStateEntry(seedStateId, err)
+			}
+		}
+		// compared tables on source are static; have target catch up with it
+		if target.ExecBinlogCoordinates.SmallerThan(&source.SelfBinlogCoordinates) {
+			if _, err = inst.StartSlaveUntilMasterCoordinates(targetKey, &source.SelfBinlogCoordinates); err != nil {
+				return updateSeedStateEntry(seedStateId, err)
+			}
+		}
+	case target.MasterKey.Equals(&source.MasterKey) && !source.SlaveRunning():
+		// siblings; have the one behind catch up with the other
+		if target.ExecBinlogCoordinates.SmallerThan(&source.ExecBinlogCoordinates) {
+			_, err = inst.StartSlaveUntilMasterCoordinates(targetKey, &source.ExecBinlogCoordinates)
+		} else if source.ExecBinlogCoordinates.SmallerThan(&target.ExecBinlogCoordinates) {
+			_, err = inst.StartSlaveUntilMasterCoordinates(sourceKey, &target.ExecBinlogCoordinates)
+		}
+		if err != nil {
+			return updateSeedStateEntry(seedStateId, err)
+		}
+	default:
+		submitSeedStateEntry(seedId, fmt.Sprintf("Cannot align %+v with %+v; checksums will only match if the tables are not being written to", *targetKey, *sourceKey), "")
+	}
+
+	for _, tableName := range tableNames {
+		seedStateId, _ = submitSeedStateEntry(seedId, fmt.Sprintf("Comparing checksum of %s", tableName), "")
+		targetChecksum, err := inst.ChecksumTable(targetKey, tableName)
+		if err != nil {
+			return updateSeedStateEntry(seedStateId, err)
+		}
+		sourceChecksum, err := inst.ChecksumTable(sourceKey, tableName)
+		if err != nil {
+			return updateSeedStateEntry(seedStateId, err)
+		}
+		if targetChecksum != sourceChecksum {
+			return updateSeedStateEntry(seedStateId, fmt.Errorf("Checksum mismatch on %s: %s on %+v, %s on %+v", tableName, targetChecksum, *targetKey, sourceChecksum, *sourceKey))
+		}
+	}
+	submitSeedStateEntry(seedId, fmt.Sprintf("Checksums of %d tables match", len(tableNames)), "")
+	return nil
+}
@@ -0,0 +1,148 @@
+/*
+   Copyright 2014 Outbrain Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package agent
+
+import (
+	test "github.com/outbrain/golib/tests"
+	"github.com/outbrain/orchestrator/go/inst"
+	"testing"
+	"time"
+)
+
+func TestGetSeedMethod(t *testing.T) {
+	_, err := GetSeedMethod(SeedMethodLVM)
+	test.S(t).ExpectNil(err)
+	_, err = GetSeedMethod(SeedMethodXtrabackup)
+	test.S(t).ExpectNil(err)
+	_, err = GetSeedMethod("rsync")
+	test.S(t).ExpectNotNil(err)
+}
+
+func TestParseXtrabackupBinlogInfo(t *testing.T) {
+	coordinates, gtidSet, err := parseXtrabackupBinlogInfo("mysql-bin.000123\t4567\n")
+	test.S(t).ExpectNil(err)
+	test.S(t).ExpectEquals(coordinates.LogFile, "mysql-bin.000123")
+	test.S(t).ExpectEquals(coordinates.LogPos, int64(4567))
+	test.S(t).ExpectEquals(gtidSet, "")
+
+	_, gtidSet, err = parseXtrabackupBinlogInfo("mysql-bin.000123\t4567\t00020194-3333-3333-3333-333333333333:1-100,\n00020195-3333-3333-3333-333333333333:1-7")
+	test.S(t).ExpectNil(err)
+	test.S(t).ExpectEquals(gtidSet, "00020194-3333-3333-3333-333333333333:1-100,00020195-3333-3333-3333-333333333333:1-7")
+
+	_, _, err = parseXtrabackupBinlogInfo("mysql-bin.000123")
+	test.S(t).ExpectNotNil(err)
+}
+
+func TestParseXtrabackupSlaveInfo(t *testing.T) {
+	coordinates, err := parseXtrabackupSlaveInfo("CHANGE MASTER TO MASTER_LOG_FILE='mysql-bin.000042', MASTER_LOG_POS=1234")
+	test.S(t).ExpectNil(err)
+	test.S(t).ExpectEquals(coordinates.LogFile, "mysql-bin.000042")
+	test.S(t).ExpectEquals(coordinates.LogPos, int64(1234))
+
+	_, err = parseXtrabackupSlaveInfo("SET GLOBAL gtid_purged='abc:1-10'")
+	test.S(t).ExpectNotNil(err)
+}
+
+func TestGetXtrabackupReplicationSource(t *testing.T) {
+	info := &XtrabackupInfo{BinlogInfo: "mysql-bin.000123\t4567", SlaveInfo: "CHANGE MASTER TO MASTER_LOG_FILE='mysql-bin.000042', MASTER_LOG_POS=1234"}
+	source := &inst.Instance{
+		Key:       inst.InstanceKey{Hostname: "source", Port: 3306},
+		MasterKey: inst.InstanceKey{Hostname: "master", Port: 3306},
+	}
+	source.ReadBinlogCoordinates = inst.BinlogCoordinates{LogFile: "mysql-bin.000042", LogPos: 1234}
+
+	source.LogBinEnabled = true
+	masterKey, coordinates, err := getXtrabackupReplicationSource(source, info)
+	test.S(t).ExpectNil(err)
+	test.S(t).ExpectEquals(masterKey.Hostname, "source")
+	test.S(t).ExpectEquals(coordinates.LogFile, "mysql-bin.000123")
+
+	source.LogBinEnabled = false
+	masterKey, coordinates, err = getXtrabackupReplicationSource(source, info)
+	test.S(t).ExpectNil(err)
+	test.S(t).ExpectEquals(masterKey.Hostname, "master")
+	test.S(t).ExpectEquals(coordinates.LogFile, "mysql-bin.000042")
+
+	source.MasterKey = inst.InstanceKey{}
+	_, _, err = getXtrabackupReplicationSource(source, info)
+	test.S(t).ExpectNotNil(err)
+}
+
+func TestNewSeedProgress(t *testing.T) {
+	progress := NewSeedProgress(400, 1000, 100, 10*time.Second)
+	test.S(t).ExpectEquals(progress.BytesPerSecond, int64(30))
+	test.S(t).ExpectEquals(progress.ETASeconds, int64(20))
+	test.S(t).ExpectEquals(progress.PercentComplete(), int64(40))
+
+	progress = NewSeedProgress(400, 1000, 400, 10*time.Second)
+	test.S(t).ExpectEquals(progress.BytesPerSecond, int64(0))
+	test.S(t).ExpectEquals(progress.ETASeconds, int64(-1))
+
+	progress = NewSeedProgress(1200, 1000, 1000, time.Second)
+	test.S(t).ExpectEquals(progress.ETASeconds, int64(0))
+	test.S(t).ExpectEquals(progress.PercentComplete(), int64(100))
+}
+
+func TestResolveSeedReplicationSource(t *testing.T) {
+	target := &inst.Instance{Key: inst.InstanceKey{Hostname: "target", Port: 3306}}
+	master := &inst.Instance{Key: inst.InstanceKey{Hostname: "master", Port: 3306}}
+	otherKey := &inst.InstanceKey{Hostname: "other", Port: 3306}
+	naturalCoordinates := &inst.BinlogCoordinates{LogFile: "mysql-bin.000042", LogPos: 1234}
+
+	coordinates, useGTID, err := resolveSeedReplicationSource(target, master, true, &master.Key, naturalCoordinates)
+	test.S(t).ExpectNil(err)
+	test.S(t).ExpectFalse(useGTID)
+	test.S(t).ExpectEquals(coordinates.LogPos, int64(1234))
+
+	_, _, err = resolveSeedReplicationSource(target, master, true, otherKey, naturalCoordinates)
+	test.S(t).ExpectNotNil(err)
+	_, _, err = resolveSeedReplicationSource(target, master, true, &master.Key, nil)
+	test.S(t).ExpectNotNil(err)
+
+	target.SupportsOracleGTID = true
+	master.SupportsOracleGTID = true
+	_, useGTID, err = resolveSeedReplicationSource(target, master, true, otherKey, nil)
+	test.S(t).ExpectNil(err)
+	test.S(t).ExpectTrue(useGTID)
+
+	// e.g. an xtrabackup backup with no GTID set recorded: the target's GTID state says nothing of its data
+	coordinates, useGTID, err = resolveSeedReplicationSource(target, master, false, &master.Key, naturalCoordinates)
+	test.S(t).ExpectNil(err)
+	test.S(t).ExpectFalse(useGTID)
+	test.S(t).ExpectEquals(coordinates.LogFile, "mysql-bin.000042")
+	_, _, err = resolveSeedReplicationSource(target, master, false, otherKey, nil)
+	test.S(t).ExpectNotNil(err)
+}
+
+func TestValidateSeedChecksumTables(t *testing.T) {
+	test.S(t).ExpectNil(validateSeedChecksumTables([]string{}))
+	test.S(t).ExpectNil(validateSeedChecksumTables([]string{"shop.orders", "shop.order_items"}))
+	test.S(t).ExpectNotNil(validateSeedChecksumTables([]string{"orders"}))
+	test.S(t).ExpectNotNil(validateSeedChecksumTables([]string{"shop.orders`; drop table x"}))
+}
+
+func TestValidateCloneTarget(t *testing.T) {
+	target := inst.NewInstance()
+	target.Key = inst.InstanceKey{Hostname: "db1", Port: 3306}
+	test.S(t).ExpectNil(validateCloneTarget(target, false, false))
+	test.S(t).ExpectNotNil(validateCloneTarget(target, true, false))
+	test.S(t).ExpectNil(validateCloneTarget(target, true, true))
+
+	target.SlaveHosts.AddKey(inst.InstanceKey{Hostname: "db2", Port: 3306})
+	test.S(t).ExpectNotNil(validateCloneTarget(target, false, false))
+	test.S(t).ExpectNil(validateCloneTarget(target, false, true))
+}
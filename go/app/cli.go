@@ -18,6 +18,7 @@ package app
 
 import (
 	"fmt"
+	"io/ioutil"
 	"net"
 	"os"
 	"os/user"
@@ -78,6 +79,25 @@ Usage for most commands:
 `, commandsListing())
 }
 
+// readDesiredTopology reads and parses the desired topology (JSON or YAML) from the -desired file, or from stdin
+func readDesiredTopology() *inst.DesiredTopologyNode {
+	var data []byte
+	var err error
+	if *config.RuntimeCLIFlags.DesiredTopology == "" {
+		data, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		data, err = ioutil.ReadFile(*config.RuntimeCLIFlags.DesiredTopology)
+	}
+	if err != nil {
+		log.Fatale(err)
+	}
+	desired, err := inst.ParseDesiredTopology(data)
+	if err != nil {
+		log.Fatale(err)
+	}
+	return desired
+}
+
 // getClusterName will make a best effort to deduce a cluster name using either a given alias
 // or an instanceKey. First attempt is at alias, and if that doesn't work, we try instanceKey.
 func getClusterName(clusterAlias string, instanceKey *inst.InstanceKey) (clusterName string) {
@@ -269,6 +289,45 @@ func Cli(command string, strict bool, instance string, destination string, owner
 				log.Fatale(err)
 			}
 		}
+		// Declarative topology
+	case registerCliCommand("plan-topology", "Declarative topology", `Show steps refactoring a cluster into the desired topology given by -desired`):
+		{
+			clusterName := getClusterName(clusterAlias, instanceKey)
+			steps, err := inst.PlanDesiredTopology(clusterName, readDesiredTopology())
+			if err != nil {
+				log.Fatale(err)
+			}
+			for _, step := range steps {
+				fmt.Println(step.String())
+			}
+		}
+	case registerCliCommand("diff-topology", "Declarative topology", `Compare a cluster's topology with the desired topology given by -desired`):
+		{
+			clusterName := getClusterName(clusterAlias, instanceKey)
+			diff, err := inst.DiffDesiredTopology(clusterName, readDesiredTopology())
+			if err != nil {
+				log.Fatale(err)
+			}
+			for _, entry := range diff {
+				fmt.Println(fmt.Sprintf("%s\t%s\t%s\t%s", entry.Key.DisplayString(), entry.Status, entry.CurrentMasterKey.DisplayString(), entry.DesiredMasterKey.DisplayString()))
+			}
+		}
+	case registerCliCommand("reconcile-topology", "Declarative topology", `Refactor a cluster towards the desired topology given by -desired, one step at a time`):
+		{
+			clusterName := getClusterName(clusterAlias, instanceKey)
+			executedSteps, diff, err := inst.ReconcileDesiredTopology(clusterName, readDesiredTopology())
+			for _, step := range executedSteps {
+				fmt.Println(step.String())
+			}
+			if err != nil {
+				for _, entry := range diff {
+					if entry.Status != inst.DesiredTopologyInPlace {
+						log.Errorf("%s is %s", entry.Key.DisplayString(), entry.Status)
+					}
+				}
+				log.Fatale(err)
+			}
+		}
 		// General replication commands
 		// move, binlog file:pos
 	case registerCliCommand("move-up", "Classic file:pos relocation", `Move a slave one level up the topology`):
@@ -730,6 +789,22 @@ func Cli(command string, strict bool, instance string, destination string, owner
 			}
 			fmt.Println(instanceKey.DisplayString())
 		}
+	case registerCliCommand("clone-instance", "Instance", `Provision an instance with a donor's data via the MySQL CLONE plugin, and attach it as a replica`):
+		{
+			instanceKey = deduceInstanceKeyIfNeeded(instance, instanceKey, true)
+			if destinationKey == nil {
+				log.Fatal("Cannot deduce donor:", destination)
+			}
+			seedOptions := &agent.SeedOptions{
+				ChecksumTables: config.Config.SeedChecksumTables,
+				Force:          *config.RuntimeCLIFlags.Force,
+			}
+			seedId, err := agent.CloneInstance(instanceKey, destinationKey, seedOptions)
+			if err != nil {
+				log.Fatale(err)
+			}
+			fmt.Println(fmt.Sprintf("%s<%s (seed %d)", instanceKey.DisplayString(), destinationKey.DisplayString(), seedId))
+		}
 		// Binary log operations
 	case registerCliCommand("flush-binary-logs", "Binary logs", `Flush binary logs on an instance`):
 		{
@@ -745,6 +820,28 @@ func Cli(command string, strict bool, instance string, destination string, owner
 			}
 			fmt.Println(instanceKey.DisplayString())
 		}
+	case registerCliCommand("binlog-purge-plan", "Binary logs", `Show which binary logs of a cluster's masters and intermediate masters can be safely purged`):
+		{
+			clusterName := getClusterName(clusterAlias, instanceKey)
+			plans, err := inst.PlanClusterBinlogPurge(clusterName)
+			if err != nil {
+				log.Fatale(err)
+			}
+			for _, plan := range plans {
+				fmt.Println(plan.TabulatedDescription())
+			}
+		}
+	case registerCliCommand("purge-cluster-binary-logs", "Binary logs", `Purge binary logs no longer needed by replicas, on a cluster's masters and intermediate masters`):
+		{
+			clusterName := getClusterName(clusterAlias, instanceKey)
+			plans, err := logic.PurgeClusterBinaryLogs(clusterName)
+			if err != nil {
+				log.Fatale(err)
+			}
+			for _, plan := range plans {
+				fmt.Println(plan.TabulatedDescription())
+			}
+		}
 	case registerCliCommand("purge-binary-logs", "Binary logs", `Purge binary logs of an instance`):
 		{
 			instanceKey = deduceInstanceKeyIfNeeded(instance, instanceKey, true)
@@ -778,6 +875,17 @@ func Cli(command string, strict bool, instance string, destination string, owner
 			}
 			fmt.Println(fmt.Sprintf("%+v:%s", *coordinates, text))
 		}
+	case registerCliCommand("inject-pseudo-gtid", "Binary logs", `Inject a Pseudo-GTID entry on a writeable master, in the format orchestrator uses with AutoPseudoGTID`):
+		{
+			instanceKey = deduceInstanceKeyIfNeeded(instance, instanceKey, true)
+			if instanceKey == nil {
+				log.Fatalf("Unresolved instance")
+			}
+			if err := inst.InjectPseudoGTID(instanceKey); err != nil {
+				log.Fatale(err)
+			}
+			fmt.Println(instanceKey.DisplayString())
+		}
 	case registerCliCommand("find-binlog-entry", "Binary logs", `Get binlog file:pos of entry given by --pattern (exact full match, not a regular expression) in a given instance`):
 		{
 			if pattern == "" {
@@ -910,10 +1018,85 @@ func Cli(command string, strict bool, instance string, destination string, owner
 				}
 			}
 		}
-	case registerCliCommand("topology", "Information", `Show an ascii-graph of a replication topology, given a member of that topology`):
+	case registerCliCommand("topology-snapshots", "Information", `List available topology snapshots of a cluster`):
+		{
+			clusterName := getClusterName(clusterAlias, instanceKey)
+			snapshots, err := inst.ReadTopologySnapshots(clusterName)
+			if err != nil {
+				log.Fatale(err)
+			}
+			for _, snapshot := range snapshots {
+				fmt.Println(fmt.Sprintf("%d\t%s\t%d", snapshot.SnapshotUnixTimestamp, snapshot.SnapshotTime, snapshot.CountInstances))
+			}
+		}
+	case registerCliCommand("topology-at", "Information", `Show an ascii-graph of a replication topology as recorded in the latest snapshot at or before -at`):
+		{
+			clusterName := getClusterName(clusterAlias, instanceKey)
+			if *config.RuntimeCLIFlags.At == "" {
+				log.Fatal("-at must be provided")
+			}
+			topology, err := inst.ReadHistoryTopology(clusterName, *config.RuntimeCLIFlags.At)
+			if err != nil {
+				log.Fatale(err)
+			}
+			fmt.Println(topology.ASCII())
+		}
+	case registerCliCommand("topology-history-diff", "Information", `Show instances which moved masters, appeared, disappeared or changed read_only between -since and -at (default: now)`):
+		{
+			clusterName := getClusterName(clusterAlias, instanceKey)
+			if *config.RuntimeCLIFlags.Since == "" {
+				log.Fatal("-since must be provided")
+			}
+			diff, _, _, err := inst.DiffHistoryTopology(clusterName, *config.RuntimeCLIFlags.Since, *config.RuntimeCLIFlags.At)
+			if err != nil {
+				log.Fatale(err)
+			}
+			for _, entry := range diff {
+				switch entry.Change {
+				case inst.TopologyHistoryReadOnlyChanged:
+					fmt.Println(fmt.Sprintf("%s\t%s\t%t\t%t", entry.Key.DisplayString(), entry.Change, entry.FromReadOnly, entry.ToReadOnly))
+				default:
+					fmt.Println(fmt.Sprintf("%s\t%s\t%s\t%s", entry.Key.DisplayString(), entry.Change, entry.FromMasterKey.DisplayString(), entry.ToMasterKey.DisplayString()))
+				}
+			}
+		}
+	case registerCliCommand("binlog-growth", "Information", `Report binlog write rate, trend and projected time until datadir is full, of a given instance`):
+		{
+			instanceKey = deduceInstanceKeyIfNeeded(instance, instanceKey, true)
+			if instanceKey == nil {
+				log.Fatalf("Unable to get binlog growth: unresolved instance")
+			}
+			growth, err := logic.GetInstanceBinlogGrowth(instanceKey)
+			if err != nil {
+				log.Fatale(err)
+			}
+			fmt.Println(growth.TabulatedDescription())
+		}
+	case registerCliCommand("cluster-binlog-growth", "Information", `Report binlog write rate, trend and projected time until datadir is full, of all instances of a cluster`):
 		{
 			clusterName := getClusterName(clusterAlias, instanceKey)
-			output, err := inst.ASCIITopology(clusterName, pattern)
+			clusterGrowth, err := logic.GetClusterBinlogGrowth(clusterName)
+			if err != nil {
+				log.Fatale(err)
+			}
+			for _, growth := range clusterGrowth.Instances {
+				fmt.Println(growth.TabulatedDescription())
+			}
+		}
+	case registerCliCommand("binlog-growth-spikes", "Information", `List clusters whose recent binlog write rate spikes relative to their baseline`):
+		{
+			clusterGrowths, err := inst.ReadSpikingBinlogGrowthClusters()
+			if err != nil {
+				log.Fatale(err)
+			}
+			for _, clusterGrowth := range clusterGrowths {
+				fmt.Println(clusterGrowth.ClusterName)
+			}
+		}
+	case registerCliCommand("topology", "Information", `Show an ascii-graph (or -format dot|json) of a replication topology, given a member of that topology`):
+		{
+			clusterName := getClusterName(clusterAlias, instanceKey)
+			output, err := inst.FormattedTopology(clusterName, pattern, *config.RuntimeCLIFlags.Format)
 			if err != nil {
 				log.Fatale(err)
 			}
@@ -1193,6 +1376,24 @@ func Cli(command string, strict bool, instance string, destination string, owner
 			fmt.Println(*promotedMasterCoordinates)
 			log.Debugf("Promoted %+v as new master. Binlog coordinates at time of promotion: %+v", topologyRecovery.SuccessorKey, *promotedMasterCoordinates)
 		}
+	case registerCliCommand("rolling-restart", "Recovery", `Restart all replicas of a cluster, one at a time and leaf-first, and wait for completion`):
+		{
+			clusterName := getClusterName(clusterAlias, instanceKey)
+			rollingRestart, err := logic.RollingRestartAndWait(clusterName, owner, false)
+			if err != nil {
+				log.Fatale(err)
+			}
+			fmt.Println(rollingRestart.Id)
+		}
+	case registerCliCommand("rolling-restart-takeover", "Recovery", `Restart all replicas of a cluster, then gracefully take over the master and restart it, and wait for completion`):
+		{
+			clusterName := getClusterName(clusterAlias, instanceKey)
+			rollingRestart, err := logic.RollingRestartAndWait(clusterName, owner, true)
+			if err != nil {
+				log.Fatale(err)
+			}
+			fmt.Println(rollingRestart.Id)
+		}
 	case registerCliCommand("replication-analysis", "Recovery", `Request an analysis of potential crash incidents in all known topologies`):
 		{
 			analysis, err := inst.GetReplicationAnalysis("", false, false)
@@ -1300,6 +1501,48 @@ func Cli(command string, strict bool, instance string, destination string, owner
 			}
 			fmt.Println(publicToken)
 		}
+	case registerCliCommand("create-api-key", "Meta", `Create a named, scoped API key, accepted via HTTP "Authorization: Bearer" header`):
+		{
+			var expirySeconds int = 0
+			if duration != "" {
+				expirySeconds, err = util.SimpleTimeToSeconds(duration)
+				if err != nil {
+					log.Fatale(err)
+				}
+				if expirySeconds < 0 {
+					log.Fatalf("Duration value must be non-negative. Given value: %d", expirySeconds)
+				}
+			}
+			apiKey, secret, err := process.CreateAPIKey(*config.RuntimeCLIFlags.APIKeyName, *config.RuntimeCLIFlags.APIKeyScopes, uint(expirySeconds), owner, nil)
+			if err != nil {
+				log.Fatale(err)
+			}
+			inst.AuditOperation("create-api-key", nil, fmt.Sprintf("API key %s created by %s; scopes: %s", apiKey.Name, apiKey.CreatedBy, apiKey.Scopes()))
+			fmt.Println(secret)
+		}
+	case registerCliCommand("api-keys", "Meta", `List API keys`):
+		{
+			apiKeys, err := process.ReadAPIKeys()
+			if err != nil {
+				log.Fatale(err)
+			}
+			for _, apiKey := range apiKeys {
+				status := "active"
+				if apiKey.IsRevoked {
+					status = "revoked"
+				}
+				fmt.Println(strings.Join([]string{apiKey.Name, apiKey.KeyPrefix, status, apiKey.Scopes(), apiKey.CreatedBy, apiKey.CreatedAt, apiKey.ExpiresAt, apiKey.LastUsedAt}, "\t"))
+			}
+		}
+	case registerCliCommand("revoke-api-key", "Meta", `Revoke an API key`):
+		{
+			apiKeyName := *config.RuntimeCLIFlags.APIKeyName
+			if err := process.RevokeAPIKey(apiKeyName); err != nil {
+				log.Fatale(err)
+			}
+			inst.AuditOperation("revoke-api-key", nil, fmt.Sprintf("API key %s revoked by %s", apiKeyName, owner))
+			fmt.Println(apiKeyName)
+		}
 	case registerCliCommand("resolve", "Meta", `Resolve given hostname`):
 		{
 			if rawInstanceKey == nil {
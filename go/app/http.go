@@ -68,6 +68,8 @@ func promptForSSLPasswords() {
 func standardHttp(discovery bool) {
 	m := martini.Classic()
 
+	// API keys are accepted regardless of authentication method
+	m.Use(http.APIKeyAuthenticate)
 	switch strings.ToLower(config.Config.AuthenticationMethod) {
 	case "basic":
 		{
@@ -75,7 +77,7 @@ func standardHttp(discovery bool) {
 				// Still allowed; may be disallowed in future versions
 				log.Warning("AuthenticationMethod is configured as 'basic' but HTTPAuthUser undefined. Running without authentication.")
 			}
-			m.Use(auth.Basic(config.Config.HTTPAuthUser, config.Config.HTTPAuthPassword))
+			m.Use(http.UnlessAPIKey(auth.Basic(config.Config.HTTPAuthUser, config.Config.HTTPAuthPassword)))
 		}
 	case "multi":
 		{
@@ -84,13 +86,24 @@ func standardHttp(discovery bool) {
 				log.Fatal("AuthenticationMethod is configured as 'multi' but HTTPAuthUser undefined")
 			}
 
-			m.Use(auth.BasicFunc(func(username, password string) bool {
+			m.Use(http.UnlessAPIKey(auth.BasicFunc(func(username, password string) bool {
 				if username == "readonly" {
 					// Will be treated as "read-only"
 					return true
 				}
 				return auth.SecureCompare(username, config.Config.HTTPAuthUser) && auth.SecureCompare(password, config.Config.HTTPAuthPassword)
-			}))
+			})))
+		}
+	case "oauth":
+		{
+			if config.Config.OAuthIssuerURL == "" || config.Config.OAuthClientId == "" {
+				log.Fatal("AuthenticationMethod is configured as 'oauth' but OAuthIssuerURL or OAuthClientId undefined")
+			}
+			if config.Config.OAuthSessionSecret == "" {
+				log.Fatal("AuthenticationMethod is configured as 'oauth' but OAuthSessionSecret undefined")
+			}
+			m.Use(http.UnlessAPIKey(http.OIDCAuthenticate))
+			http.RegisterOIDCRequests(m)
 		}
 	default:
 		{
@@ -0,0 +1,442 @@
+/*
+   Copyright 2014 Outbrain Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package binlog
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const eventHeaderLength = 19
+
+// Event is a decoded binary log event. EventType and Info follow the conventions of SHOW BINLOG EVENTS
+type Event struct {
+	LogFile   string
+	Pos       int64
+	NextPos   int64
+	EventType string
+	Info      string
+	// Artificial events (e.g. the fake rotate event sent at the beginning of a dump) are not part of the binary log
+	Artificial bool
+	// NextLogFile is set for Rotate events
+	NextLogFile string
+}
+
+const (
+	queryEventType            = 2
+	stopEventType             = 3
+	rotateEventType           = 4
+	intvarEventType           = 5
+	randEventType             = 13
+	userVarEventType          = 14
+	formatDescriptionType     = 15
+	xidEventType              = 16
+	tableMapEventType         = 19
+	writeRowsEventV1Type      = 23
+	updateRowsEventV1Type     = 24
+	deleteRowsEventV1Type     = 25
+	incidentEventType         = 26
+	heartbeatEventType        = 27
+	rowsQueryEventType        = 29
+	writeRowsEventV2Type      = 30
+	updateRowsEventV2Type     = 31
+	deleteRowsEventV2Type     = 32
+	gtidEventType             = 33
+	anonymousGtidEventType    = 34
+	previousGtidsEventType    = 35
+	mariadbAnnotateRowsType   = 160
+	mariadbBinlogCheckpoint   = 161
+	mariadbGtidEventType      = 162
+	mariadbGtidListEventType  = 163
+	logEventArtificialFlag    = 0x0020
+	binlogChecksumAlgOff      = 0
+	binlogChecksumAlgCRC32    = 1
+	binlogChecksumLength      = 4
+	rowsEventStatementEndFlag = 0x0001
+)
+
+var eventTypeNames = map[byte]string{
+	1:                        "Start_v3",
+	queryEventType:           "Query",
+	stopEventType:            "Stop",
+	rotateEventType:          "Rotate",
+	intvarEventType:          "Intvar",
+	6:                        "Load",
+	8:                        "Create_file",
+	9:                        "Append_block",
+	10:                       "Exec_load",
+	11:                       "Delete_file",
+	12:                       "New_load",
+	randEventType:            "RAND",
+	userVarEventType:         "User var",
+	formatDescriptionType:    "Format_desc",
+	xidEventType:             "Xid",
+	17:                       "Begin_load_query",
+	18:                       "Execute_load_query",
+	tableMapEventType:        "Table_map",
+	20:                       "Write_rows_event_old",
+	21:                       "Update_rows_event_old",
+	22:                       "Delete_rows_event_old",
+	writeRowsEventV1Type:     "Write_rows",
+	updateRowsEventV1Type:    "Update_rows",
+	deleteRowsEventV1Type:    "Delete_rows",
+	incidentEventType:        "Incident",
+	heartbeatEventType:       "Heartbeat",
+	28:                       "Ignorable",
+	rowsQueryEventType:       "Rows_query",
+	writeRowsEventV2Type:     "Write_rows",
+	updateRowsEventV2Type:    "Update_rows",
+	deleteRowsEventV2Type:    "Delete_rows",
+	gtidEventType:            "Gtid",
+	anonymousGtidEventType:   "Anonymous_Gtid",
+	previousGtidsEventType:   "Previous_gtids",
+	mariadbAnnotateRowsType:  "Annotate_rows",
+	mariadbBinlogCheckpoint:  "Binlog_checkpoint",
+	mariadbGtidEventType:     "Gtid",
+	mariadbGtidListEventType: "Gtid_list",
+}
+
+var serverVersionRegexp = regexp.MustCompile(`^([0-9]+)[.]([0-9]+)[.]([0-9]+)`)
+
+// eventDecoder decodes raw events of a single dump stream. It tracks the format description
+// (post header lengths, checksum) and the current log file
+type eventDecoder struct {
+	logFile           string
+	postHeaderLengths []byte
+	checksumAlg       byte
+}
+
+// serverVersionSupportsChecksum returns true for versions which append checksum algorithm to format description events
+func serverVersionSupportsChecksum(serverVersion string) bool {
+	submatch := serverVersionRegexp.FindStringSubmatch(serverVersion)
+	if len(submatch) == 0 {
+		return false
+	}
+	major, _ := strconv.Atoi(submatch[1])
+	minor, _ := strconv.Atoi(submatch[2])
+	patch, _ := strconv.Atoi(submatch[3])
+	if strings.Contains(serverVersion, "MariaDB") {
+		return major > 5 || (major == 5 && minor >= 3)
+	}
+	return major > 5 || (major == 5 && minor > 6) || (major == 5 && minor == 6 && patch >= 1)
+}
+
+func (this *eventDecoder) postHeaderLength(eventType byte, defaultLength int) int {
+	if int(eventType) <= len(this.postHeaderLengths) && eventType > 0 {
+		return int(this.postHeaderLengths[eventType-1])
+	}
+	return defaultLength
+}
+
+// decode decodes a single raw event (header included)
+func (this *eventDecoder) decode(data []byte) (*Event, error) {
+	if len(data) < eventHeaderLength {
+		return nil, fmt.Errorf("Event too short: %d bytes", len(data))
+	}
+	eventType := data[4]
+	eventSize := int64(binary.LittleEndian.Uint32(data[9:13]))
+	nextPos := int64(binary.LittleEndian.Uint32(data[13:17]))
+	flags := binary.LittleEndian.Uint16(data[17:19])
+
+	event := &Event{
+		LogFile:    this.logFile,
+		NextPos:    nextPos,
+		Pos:        nextPos - eventSize,
+		EventType:  eventTypeNames[eventType],
+		Artificial: (flags&logEventArtificialFlag != 0) || nextPos == 0,
+	}
+	if event.EventType == "" {
+		event.EventType = "Unknown"
+	}
+
+	body := data[eventHeaderLength:]
+	if eventType == formatDescriptionType {
+		this.decodeFormatDescription(event, body)
+		return event, nil
+	}
+	if this.checksumAlg == binlogChecksumAlgCRC32 && len(body) >= binlogChecksumLength {
+		body = body[:len(body)-binlogChecksumLength]
+	}
+
+	var err error
+	switch eventType {
+	case queryEventType:
+		err = this.decodeQuery(event, body)
+	case rotateEventType:
+		err = this.decodeRotate(event, body)
+	case xidEventType:
+		if len(body) >= 8 {
+			event.Info = fmt.Sprintf("COMMIT /* xid=%d */", binary.LittleEndian.Uint64(body[0:8]))
+		}
+	case intvarEventType:
+		if len(body) >= 9 {
+			name := "INVALID_INT"
+			switch body[0] {
+			case 1:
+				name = "LAST_INSERT_ID"
+			case 2:
+				name = "INSERT_ID"
+			}
+			event.Info = fmt.Sprintf("%s=%d", name, binary.LittleEndian.Uint64(body[1:9]))
+		}
+	case randEventType:
+		if len(body) >= 16 {
+			event.Info = fmt.Sprintf("rand_seed1=%d,rand_seed2=%d", binary.LittleEndian.Uint64(body[0:8]), binary.LittleEndian.Uint64(body[8:16]))
+		}
+	case userVarEventType:
+		event.Info = decodeUserVar(body)
+	case tableMapEventType:
+		err = this.decodeTableMap(event, body)
+	case writeRowsEventV1Type, updateRowsEventV1Type, deleteRowsEventV1Type, writeRowsEventV2Type, updateRowsEventV2Type, deleteRowsEventV2Type:
+		this.decodeRows(event, eventType, body)
+	case rowsQueryEventType:
+		if len(body) >= 1 {
+			event.Info = "# " + string(body[1:])
+		}
+	case gtidEventType:
+		if len(body) >= 25 {
+			event.Info = fmt.Sprintf("SET @@SESSION.GTID_NEXT= '%s:%d'", formatUUID(body[1:17]), binary.LittleEndian.Uint64(body[17:25]))
+		}
+	case anonymousGtidEventType:
+		event.Info = "SET @@SESSION.GTID_NEXT= 'ANONYMOUS'"
+	case previousGtidsEventType:
+		event.Info = decodeGtidSet(body)
+	case mariadbAnnotateRowsType:
+		event.Info = string(body)
+	case mariadbBinlogCheckpoint:
+		if len(body) >= 4 {
+			event.Info = string(body[4:])
+		}
+	case mariadbGtidEventType:
+		if len(body) >= 13 {
+			sequence := binary.LittleEndian.Uint64(body[0:8])
+			domain := binary.LittleEndian.Uint32(body[8:12])
+			serverId := binary.LittleEndian.Uint32(data[5:9])
+			if body[12]&0x01 != 0 {
+				// standalone event
+				event.Info = fmt.Sprintf("GTID %d-%d-%d", domain, serverId, sequence)
+			} else {
+				event.Info = fmt.Sprintf("BEGIN GTID %d-%d-%d", domain, serverId, sequence)
+			}
+		}
+	case mariadbGtidListEventType:
+		event.Info = decodeMariaDBGtidList(body)
+	}
+	return event, err
+}
+
+func (this *eventDecoder) decodeFormatDescription(event *Event, body []byte) {
+	if len(body) < 2+50+4+1 {
+		return
+	}
+	binlogVersion := binary.LittleEndian.Uint16(body[0:2])
+	serverVersion := string(bytes.TrimRight(body[2:52], "\x00"))
+	event.Info = fmt.Sprintf("Server ver: %s, Binlog ver: %d", serverVersion, binlogVersion)
+
+	postHeaderLengths := body[57:]
+	this.checksumAlg = binlogChecksumAlgOff
+	if serverVersionSupportsChecksum(serverVersion) && len(postHeaderLengths) >= 1+binlogChecksumLength {
+		this.checksumAlg = postHeaderLengths[len(postHeaderLengths)-1-binlogChecksumLength]
+		postHeaderLengths = postHeaderLengths[:len(postHeaderLengths)-1-binlogChecksumLength]
+	}
+	this.postHeaderLengths = append([]byte{}, postHeaderLengths...)
+}
+
+func (this *eventDecoder) decodeQuery(event *Event, body []byte) error {
+	postHeaderLength := this.postHeaderLength(queryEventType, 13)
+	if len(body) < postHeaderLength || postHeaderLength < 11 {
+		return fmt.Errorf("Malformed query event at %s:%d", event.LogFile, event.Pos)
+	}
+	schemaLength := int(body[8])
+	statusVarsLength := 0
+	if postHeaderLength >= 13 {
+		statusVarsLength = int(binary.LittleEndian.Uint16(body[11:13]))
+	}
+	pos := postHeaderLength + statusVarsLength
+	if len(body) < pos+schemaLength+1 {
+		return fmt.Errorf("Malformed query event at %s:%d", event.LogFile, event.Pos)
+	}
+	schema := string(body[pos : pos+schemaLength])
+	query := string(body[pos+schemaLength+1:])
+	if schema != "" {
+		event.Info = fmt.Sprintf("use `%s`; %s", schema, query)
+	} else {
+		event.Info = query
+	}
+	return nil
+}
+
+func (this *eventDecoder) decodeRotate(event *Event, body []byte) error {
+	postHeaderLength := this.postHeaderLength(rotateEventType, 8)
+	if len(body) < postHeaderLength || postHeaderLength < 8 {
+		return fmt.Errorf("Malformed rotate event at %s:%d", event.LogFile, event.Pos)
+	}
+	position := binary.LittleEndian.Uint64(body[0:8])
+	event.NextLogFile = string(body[postHeaderLength:])
+	event.Info = fmt.Sprintf("%s;pos=%d", event.NextLogFile, position)
+	return nil
+}
+
+// tableId reads a table id, which is 6 bytes long unless post header is 6 bytes long (old format)
+func (this *eventDecoder) tableId(eventType byte, body []byte) (tableId uint64, length int) {
+	length = 6
+	if this.postHeaderLength(eventType, 8) == 6 {
+		length = 4
+	}
+	if len(body) < length {
+		return 0, length
+	}
+	for i := length - 1; i >= 0; i-- {
+		tableId = tableId<<8 | uint64(body[i])
+	}
+	return tableId, length
+}
+
+func (this *eventDecoder) decodeTableMap(event *Event, body []byte) error {
+	tableId, idLength := this.tableId(tableMapEventType, body)
+	pos := this.postHeaderLength(tableMapEventType, idLength+2)
+	if len(body) < pos+1 {
+		return fmt.Errorf("Malformed table map event at %s:%d", event.LogFile, event.Pos)
+	}
+	schemaLength := int(body[pos])
+	pos++
+	if len(body) < pos+schemaLength+2 {
+		return fmt.Errorf("Malformed table map event at %s:%d", event.LogFile, event.Pos)
+	}
+	schema := string(body[pos : pos+schemaLength])
+	pos += schemaLength + 1
+	tableLength := int(body[pos])
+	pos++
+	if len(body) < pos+tableLength {
+		return fmt.Errorf("Malformed table map event at %s:%d", event.LogFile, event.Pos)
+	}
+	table := string(body[pos : pos+tableLength])
+	event.Info = fmt.Sprintf("table_id: %d (%s.%s)", tableId, schema, table)
+	return nil
+}
+
+func (this *eventDecoder) decodeRows(event *Event, eventType byte, body []byte) {
+	tableId, idLength := this.tableId(eventType, body)
+	if len(body) < idLength+2 {
+		return
+	}
+	flags := binary.LittleEndian.Uint16(body[idLength : idLength+2])
+	if flags&rowsEventStatementEndFlag != 0 {
+		event.Info = fmt.Sprintf("table_id: %d flags: STMT_END_F", tableId)
+	} else {
+		event.Info = fmt.Sprintf("table_id: %d", tableId)
+	}
+}
+
+func formatUUID(data []byte) string {
+	h := hex.EncodeToString(data)
+	return fmt.Sprintf("%s-%s-%s-%s-%s", h[0:8], h[8:12], h[12:16], h[16:20], h[20:32])
+}
+
+// decodeGtidSet decodes a Previous_gtids event body into the textual GTID set representation
+func decodeGtidSet(body []byte) string {
+	if len(body) < 8 {
+		return ""
+	}
+	sidsCount := binary.LittleEndian.Uint64(body[0:8])
+	pos := 8
+	sids := []string{}
+	for i := uint64(0); i < sidsCount; i++ {
+		if len(body) < pos+16+8 {
+			break
+		}
+		tokens := []string{formatUUID(body[pos : pos+16])}
+		pos += 16
+		intervalsCount := binary.LittleEndian.Uint64(body[pos : pos+8])
+		pos += 8
+		for j := uint64(0); j < intervalsCount; j++ {
+			if len(body) < pos+16 {
+				break
+			}
+			start := binary.LittleEndian.Uint64(body[pos : pos+8])
+			end := binary.LittleEndian.Uint64(body[pos+8 : pos+16])
+			pos += 16
+			if end-1 == start {
+				tokens = append(tokens, fmt.Sprintf("%d", start))
+			} else {
+				tokens = append(tokens, fmt.Sprintf("%d-%d", start, end-1))
+			}
+		}
+		sids = append(sids, strings.Join(tokens, ":"))
+	}
+	return strings.Join(sids, ",\n")
+}
+
+func decodeMariaDBGtidList(body []byte) string {
+	if len(body) < 4 {
+		return ""
+	}
+	count := binary.LittleEndian.Uint32(body[0:4]) & 0x0fffffff
+	pos := 4
+	gtids := []string{}
+	for i := uint32(0); i < count; i++ {
+		if len(body) < pos+16 {
+			break
+		}
+		domain := binary.LittleEndian.Uint32(body[pos : pos+4])
+		serverId := binary.LittleEndian.Uint32(body[pos+4 : pos+8])
+		sequence := binary.LittleEndian.Uint64(body[pos+8 : pos+16])
+		gtids = append(gtids, fmt.Sprintf("%d-%d-%d", domain, serverId, sequence))
+		pos += 16
+	}
+	return fmt.Sprintf("[%s]", strings.Join(gtids, ","))
+}
+
+// decodeUserVar provides a best effort representation of a user variable event
+func decodeUserVar(body []byte) string {
+	if len(body) < 4 {
+		return ""
+	}
+	nameLength := int(binary.LittleEndian.Uint32(body[0:4]))
+	if len(body) < 4+nameLength+1 {
+		return ""
+	}
+	name := string(body[4 : 4+nameLength])
+	pos := 4 + nameLength
+	if body[pos] != 0 {
+		return fmt.Sprintf("@`%s`=NULL", name)
+	}
+	pos++
+	// type (1), charset (4), value length (4)
+	if len(body) < pos+9 {
+		return fmt.Sprintf("@`%s`", name)
+	}
+	valueType := body[pos]
+	valueLength := int(binary.LittleEndian.Uint32(body[pos+5 : pos+9]))
+	pos += 9
+	if len(body) < pos+valueLength {
+		return fmt.Sprintf("@`%s`", name)
+	}
+	value := body[pos : pos+valueLength]
+	switch {
+	case valueType == 0:
+		return fmt.Sprintf("@`%s`='%s'", name, string(value))
+	case valueType == 2 && valueLength == 8:
+		return fmt.Sprintf("@`%s`=%d", name, int64(binary.LittleEndian.Uint64(value)))
+	}
+	return fmt.Sprintf("@`%s`=0x%s", name, hex.EncodeToString(value))
+}
@@ -0,0 +1,127 @@
+package binlog
+
+import (
+	"encoding/binary"
+	"testing"
+
+	test "github.com/outbrain/golib/tests"
+)
+
+func mkTestEvent(eventType byte, nextPos uint32, flags uint16, body []byte) []byte {
+	data := make([]byte, eventHeaderLength)
+	data[4] = eventType
+	binary.LittleEndian.PutUint32(data[5:9], 1)
+	binary.LittleEndian.PutUint32(data[9:13], uint32(eventHeaderLength+len(body)))
+	binary.LittleEndian.PutUint32(data[13:17], nextPos)
+	binary.LittleEndian.PutUint16(data[17:19], flags)
+	return append(data, body...)
+}
+
+func mkTestFormatDescriptionBody(serverVersion string, checksumAlg byte) []byte {
+	body := make([]byte, 57)
+	binary.LittleEndian.PutUint16(body[0:2], 4)
+	copy(body[2:52], serverVersion)
+	body[56] = eventHeaderLength
+	postHeaderLengths := make([]byte, 35)
+	postHeaderLengths[queryEventType-1] = 13
+	postHeaderLengths[rotateEventType-1] = 8
+	postHeaderLengths[tableMapEventType-1] = 8
+	postHeaderLengths[writeRowsEventV2Type-1] = 10
+	body = append(body, postHeaderLengths...)
+	return append(body, checksumAlg, 0, 0, 0, 0)
+}
+
+func mkTestQueryBody(schema string, query string) []byte {
+	body := make([]byte, 13)
+	body[8] = byte(len(schema))
+	body = append(body, []byte(schema)...)
+	body = append(body, 0)
+	return append(body, []byte(query)...)
+}
+
+func TestServerVersionSupportsChecksum(t *testing.T) {
+	test.S(t).ExpectTrue(serverVersionSupportsChecksum("5.6.28-log"))
+	test.S(t).ExpectTrue(serverVersionSupportsChecksum("5.7.10"))
+	test.S(t).ExpectTrue(serverVersionSupportsChecksum("10.1.9-MariaDB-log"))
+	test.S(t).ExpectFalse(serverVersionSupportsChecksum("5.5.46-log"))
+	test.S(t).ExpectFalse(serverVersionSupportsChecksum("5.6.0"))
+}
+
+func TestDecodeEventsWithChecksum(t *testing.T) {
+	decoder := &eventDecoder{logFile: "mysql-bin.000007"}
+
+	fde, err := decoder.decode(mkTestEvent(formatDescriptionType, 120, 0, mkTestFormatDescriptionBody("5.6.28-log", binlogChecksumAlgCRC32)))
+	test.S(t).ExpectNil(err)
+	test.S(t).ExpectEquals(fde.EventType, "Format_desc")
+	test.S(t).ExpectEquals(fde.Info, "Server ver: 5.6.28-log, Binlog ver: 4")
+	test.S(t).ExpectEquals(decoder.checksumAlg, byte(binlogChecksumAlgCRC32))
+	test.S(t).ExpectFalse(fde.Artificial)
+
+	checksum := []byte{1, 2, 3, 4}
+	{
+		body := append(mkTestQueryBody("meta", "drop view if exists `_pseudo_gtid_hint__asc:0001`"), checksum...)
+		event, err := decoder.decode(mkTestEvent(queryEventType, 500, 0, body))
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(event.EventType, "Query")
+		test.S(t).ExpectEquals(event.Info, "use `meta`; drop view if exists `_pseudo_gtid_hint__asc:0001`")
+		test.S(t).ExpectEquals(event.LogFile, "mysql-bin.000007")
+		test.S(t).ExpectEquals(event.NextPos, int64(500))
+		test.S(t).ExpectEquals(event.Pos, int64(500-eventHeaderLength-len(body)))
+	}
+	{
+		body := append(mkTestQueryBody("", "BEGIN"), checksum...)
+		event, err := decoder.decode(mkTestEvent(queryEventType, 600, 0, body))
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(event.Info, "BEGIN")
+	}
+	{
+		body := make([]byte, 8)
+		binary.LittleEndian.PutUint64(body, 1234)
+		event, err := decoder.decode(mkTestEvent(xidEventType, 700, 0, append(body, checksum...)))
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(event.EventType, "Xid")
+		test.S(t).ExpectEquals(event.Info, "COMMIT /* xid=1234 */")
+	}
+	{
+		body := make([]byte, 8)
+		binary.LittleEndian.PutUint64(body, 4)
+		body = append(body, []byte("mysql-bin.000008")...)
+		event, err := decoder.decode(mkTestEvent(rotateEventType, 800, 0, append(body, checksum...)))
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(event.EventType, "Rotate")
+		test.S(t).ExpectEquals(event.Info, "mysql-bin.000008;pos=4")
+		test.S(t).ExpectEquals(event.NextLogFile, "mysql-bin.000008")
+	}
+	{
+		body := []byte{70, 0, 0, 0, 0, 0, 1, 0, 0, 0}
+		event, err := decoder.decode(mkTestEvent(writeRowsEventV2Type, 900, 0, append(body, checksum...)))
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(event.EventType, "Write_rows")
+		test.S(t).ExpectEquals(event.Info, "table_id: 70 flags: STMT_END_F")
+	}
+}
+
+func TestDecodeArtificialRotate(t *testing.T) {
+	decoder := &eventDecoder{}
+	body := make([]byte, 8)
+	binary.LittleEndian.PutUint64(body, 4)
+	body = append(body, []byte("mysql-bin.000007")...)
+	event, err := decoder.decode(mkTestEvent(rotateEventType, 0, logEventArtificialFlag, body))
+	test.S(t).ExpectNil(err)
+	test.S(t).ExpectTrue(event.Artificial)
+	test.S(t).ExpectEquals(event.NextLogFile, "mysql-bin.000007")
+}
+
+func TestDecodeGtidSet(t *testing.T) {
+	body := make([]byte, 8)
+	binary.LittleEndian.PutUint64(body, 1)
+	body = append(body, []byte{0x3e, 0x11, 0xfa, 0x47, 0x71, 0xca, 0x11, 0xe1, 0x9e, 0x33, 0xc8, 0x0a, 0xa9, 0x42, 0x95, 0x62}...)
+	intervals := make([]byte, 8+32)
+	binary.LittleEndian.PutUint64(intervals[0:8], 2)
+	binary.LittleEndian.PutUint64(intervals[8:16], 1)
+	binary.LittleEndian.PutUint64(intervals[16:24], 6)
+	binary.LittleEndian.PutUint64(intervals[24:32], 8)
+	binary.LittleEndian.PutUint64(intervals[32:40], 9)
+	body = append(body, intervals...)
+	test.S(t).ExpectEquals(decodeGtidSet(body), "3e11fa47-71ca-11e1-9e33-c80aa9429562:1-5:8")
+}
@@ -0,0 +1,327 @@
+/*
+   Copyright 2014 Outbrain Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package binlog
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+// A minimal implementation of the MySQL client/server protocol; just enough to authenticate as a
+// replication client and request a binary log dump.
+
+const maxPacketSize = 1<<24 - 1
+
+const (
+	clientLongPassword     uint32 = 0x00000001
+	clientLongFlag         uint32 = 0x00000004
+	clientProtocol41       uint32 = 0x00000200
+	clientTransactions     uint32 = 0x00002000
+	clientSecureConnection uint32 = 0x00008000
+	clientPluginAuth       uint32 = 0x00080000
+)
+
+const (
+	comQuery      byte = 0x03
+	comBinlogDump byte = 0x12
+)
+
+const (
+	okPacket       byte = 0x00
+	authMoreData   byte = 0x01
+	eofPacket      byte = 0xfe
+	errPacket      byte = 0xff
+	authSwitchData byte = 0xfe
+)
+
+const (
+	nativePasswordPlugin      = "mysql_native_password"
+	cachingSha2PasswordPlugin = "caching_sha2_password"
+)
+
+// ServerError is an ERR packet returned by the server
+type ServerError struct {
+	Code    uint16
+	Message string
+}
+
+func (this *ServerError) Error() string {
+	return fmt.Sprintf("Error %d: %s", this.Code, this.Message)
+}
+
+// conn is a raw protocol connection to a MySQL server
+type conn struct {
+	netConn       net.Conn
+	reader        *bufio.Reader
+	sequence      byte
+	readTimeout   time.Duration
+	serverVersion string
+}
+
+func dial(host string, port int, connectTimeout time.Duration, readTimeout time.Duration) (*conn, error) {
+	netConn, err := net.DialTimeout("tcp", fmt.Sprintf("%s:%d", host, port), connectTimeout)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{netConn: netConn, reader: bufio.NewReaderSize(netConn, 64*1024), readTimeout: readTimeout}, nil
+}
+
+func (this *conn) Close() error {
+	return this.netConn.Close()
+}
+
+// readPacket reads a single logical packet, joining split (16MB+) packets
+func (this *conn) readPacket() ([]byte, error) {
+	var payload []byte
+	for {
+		if this.readTimeout > 0 {
+			this.netConn.SetReadDeadline(time.Now().Add(this.readTimeout))
+		}
+		header := make([]byte, 4)
+		if _, err := io.ReadFull(this.reader, header); err != nil {
+			return nil, err
+		}
+		length := int(uint32(header[0]) | uint32(header[1])<<8 | uint32(header[2])<<16)
+		this.sequence = header[3] + 1
+		data := make([]byte, length)
+		if _, err := io.ReadFull(this.reader, data); err != nil {
+			return nil, err
+		}
+		payload = append(payload, data...)
+		if length < maxPacketSize {
+			return payload, nil
+		}
+	}
+}
+
+// writePacket writes given payload, splitting into multiple packets as required
+func (this *conn) writePacket(payload []byte) error {
+	for {
+		length := len(payload)
+		if length > maxPacketSize {
+			length = maxPacketSize
+		}
+		header := []byte{byte(length), byte(length >> 8), byte(length >> 16), this.sequence}
+		if _, err := this.netConn.Write(append(header, payload[:length]...)); err != nil {
+			return err
+		}
+		this.sequence++
+		payload = payload[length:]
+		if length < maxPacketSize {
+			return nil
+		}
+	}
+}
+
+// writeCommand starts a new command phase
+func (this *conn) writeCommand(command byte, args []byte) error {
+	this.sequence = 0
+	return this.writePacket(append([]byte{command}, args...))
+}
+
+func parseErrPacket(data []byte) error {
+	if len(data) < 3 {
+		return &ServerError{Message: "malformed error packet"}
+	}
+	serverError := &ServerError{Code: binary.LittleEndian.Uint16(data[1:3])}
+	message := data[3:]
+	if len(message) > 0 && message[0] == '#' && len(message) >= 6 {
+		// skip SQL state marker and state
+		message = message[6:]
+	}
+	serverError.Message = string(message)
+	return serverError
+}
+
+// readResultOK reads a response expected to be an OK packet
+func (this *conn) readResultOK() error {
+	data, err := this.readPacket()
+	if err != nil {
+		return err
+	}
+	switch data[0] {
+	case okPacket:
+		return nil
+	case errPacket:
+		return parseErrPacket(data)
+	}
+	return fmt.Errorf("Unexpected response packet: 0x%02x", data[0])
+}
+
+// exec runs a statement which returns no result set
+func (this *conn) exec(query string) error {
+	if err := this.writeCommand(comQuery, []byte(query)); err != nil {
+		return err
+	}
+	return this.readResultOK()
+}
+
+// handshake reads the server greeting and authenticates
+func (this *conn) handshake(user string, password string) error {
+	data, err := this.readPacket()
+	if err != nil {
+		return err
+	}
+	if data[0] == errPacket {
+		return parseErrPacket(data)
+	}
+	if data[0] != 10 {
+		return fmt.Errorf("Unsupported protocol version: %d", data[0])
+	}
+	pos := 1
+	versionEnd := bytes.IndexByte(data[pos:], 0)
+	if versionEnd < 0 {
+		return fmt.Errorf("Malformed handshake packet")
+	}
+	this.serverVersion = string(data[pos : pos+versionEnd])
+	pos += versionEnd + 1
+	// connection id
+	pos += 4
+	if len(data) < pos+8 {
+		return fmt.Errorf("Malformed handshake packet")
+	}
+	scramble := append([]byte{}, data[pos:pos+8]...)
+	pos += 8 + 1
+	pluginName := nativePasswordPlugin
+	if len(data) >= pos+2 {
+		capabilities := uint32(binary.LittleEndian.Uint16(data[pos : pos+2]))
+		pos += 2
+		if len(data) >= pos+16 {
+			// charset, status flags
+			pos += 1 + 2
+			capabilities |= uint32(binary.LittleEndian.Uint16(data[pos:pos+2])) << 16
+			pos += 2
+			authDataLength := int(data[pos])
+			pos += 1 + 10
+			if capabilities&clientSecureConnection != 0 {
+				part2Length := authDataLength - 8
+				if part2Length < 13 {
+					part2Length = 13
+				}
+				if len(data) >= pos+part2Length {
+					// last byte is a terminating NUL
+					scramble = append(scramble, data[pos:pos+part2Length-1]...)
+					pos += part2Length
+				}
+			}
+			if capabilities&clientPluginAuth != 0 && pos < len(data) {
+				pluginName = string(bytes.TrimRight(data[pos:], "\x00"))
+			}
+		}
+	}
+	if pluginName != nativePasswordPlugin && pluginName != cachingSha2PasswordPlugin {
+		pluginName = nativePasswordPlugin
+	}
+	authResponse := scramblePassword(pluginName, scramble, password)
+
+	capabilities := clientLongPassword | clientLongFlag | clientProtocol41 | clientTransactions | clientSecureConnection | clientPluginAuth
+	response := make([]byte, 4+4+1+23)
+	binary.LittleEndian.PutUint32(response[0:4], capabilities)
+	binary.LittleEndian.PutUint32(response[4:8], maxPacketSize)
+	response[8] = 33 // utf8_general_ci
+	response = append(response, []byte(user)...)
+	response = append(response, 0)
+	response = append(response, byte(len(authResponse)))
+	response = append(response, authResponse...)
+	response = append(response, []byte(pluginName)...)
+	response = append(response, 0)
+	if err := this.writePacket(response); err != nil {
+		return err
+	}
+	return this.readAuthResult(pluginName, scramble, password)
+}
+
+// readAuthResult handles the server's response to authentication, including auth switch requests
+func (this *conn) readAuthResult(pluginName string, scramble []byte, password string) error {
+	for {
+		data, err := this.readPacket()
+		if err != nil {
+			return err
+		}
+		switch data[0] {
+		case okPacket:
+			return nil
+		case errPacket:
+			return parseErrPacket(data)
+		case authSwitchData:
+			nameEnd := bytes.IndexByte(data[1:], 0)
+			if nameEnd < 0 {
+				return fmt.Errorf("Malformed auth switch request")
+			}
+			pluginName = string(data[1 : 1+nameEnd])
+			scramble = bytes.TrimRight(data[1+nameEnd+1:], "\x00")
+			if pluginName != nativePasswordPlugin && pluginName != cachingSha2PasswordPlugin {
+				return fmt.Errorf("Unsupported authentication plugin: %s", pluginName)
+			}
+			if err := this.writePacket(scramblePassword(pluginName, scramble, password)); err != nil {
+				return err
+			}
+		case authMoreData:
+			if pluginName != cachingSha2PasswordPlugin || len(data) < 2 {
+				return fmt.Errorf("Unexpected authentication data")
+			}
+			switch data[1] {
+			case 3:
+				// fast authentication succeeded; OK packet follows
+				continue
+			case 4:
+				return fmt.Errorf("caching_sha2_password full authentication requires a secure connection, which is unsupported by binlog streaming")
+			}
+			return fmt.Errorf("Unexpected caching_sha2_password response: %d", data[1])
+		default:
+			return fmt.Errorf("Unexpected authentication response: 0x%02x", data[0])
+		}
+	}
+}
+
+// scramblePassword computes the authentication response for given plugin
+func scramblePassword(pluginName string, scramble []byte, password string) []byte {
+	if password == "" {
+		return []byte{}
+	}
+	if pluginName == cachingSha2PasswordPlugin {
+		// XOR(SHA256(password), SHA256(SHA256(SHA256(password)), scramble))
+		hash := sha256.Sum256([]byte(password))
+		doubleHash := sha256.Sum256(hash[:])
+		h := sha256.New()
+		h.Write(doubleHash[:])
+		h.Write(scramble)
+		scrambled := h.Sum(nil)
+		for i := range scrambled {
+			scrambled[i] ^= hash[i]
+		}
+		return scrambled
+	}
+	// XOR(SHA1(password), SHA1(scramble, SHA1(SHA1(password))))
+	hash := sha1.Sum([]byte(password))
+	doubleHash := sha1.Sum(hash[:])
+	h := sha1.New()
+	h.Write(scramble)
+	h.Write(doubleHash[:])
+	scrambled := h.Sum(nil)
+	for i := range scrambled {
+		scrambled[i] ^= hash[i]
+	}
+	return scrambled
+}
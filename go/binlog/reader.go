@@ -0,0 +1,123 @@
+/*
+   Copyright 2014 Outbrain Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package binlog
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/outbrain/golib/log"
+	"github.com/outbrain/orchestrator/go/config"
+)
+
+const binlogDumpNonBlockFlag uint16 = 0x01
+const minBinlogPos int64 = 4
+
+// ReadEvents connects to given server as a replication client (COM_BINLOG_DUMP) and reads up to limit events
+// of given binary log, starting at given position. Like SHOW BINLOG EVENTS, reading does not continue into the
+// next binary log; the Rotate event closing the log is included.
+// The dump is non blocking and requests server_id 0, such that it does not interfere with real replicas.
+// The topology user requires the REPLICATION SLAVE privilege.
+func ReadEvents(host string, port int, logFile string, pos int64, limit int) (events []Event, err error) {
+	if logFile == "" {
+		return events, fmt.Errorf("ReadEvents: empty binlog file name for %s:%d", host, port)
+	}
+	if pos < minBinlogPos {
+		pos = minBinlogPos
+	}
+	if pos > int64(^uint32(0)) {
+		return events, fmt.Errorf("ReadEvents: position %d exceeds binlog dump protocol limit", pos)
+	}
+	conn, err := dial(host, port,
+		time.Duration(config.Config.MySQLConnectTimeoutSeconds)*time.Second,
+		time.Duration(config.Config.MySQLTopologyReadTimeoutSeconds)*time.Second,
+	)
+	if err != nil {
+		return events, err
+	}
+	defer conn.Close()
+
+	if err := conn.handshake(config.Config.MySQLTopologyUser, config.Config.MySQLTopologyPassword); err != nil {
+		return events, err
+	}
+	// Servers with binlog checksums require the client to acknowledge them. Older servers do not know the variable.
+	if err := conn.exec("SET @master_binlog_checksum = @@global.binlog_checksum"); err != nil {
+		if _, isServerError := err.(*ServerError); !isServerError {
+			return events, err
+		}
+	}
+	// MariaDB: get GTID events as they appear in the binary log
+	if err := conn.exec("SET @mariadb_slave_capability = 4"); err != nil {
+		if _, isServerError := err.(*ServerError); !isServerError {
+			return events, err
+		}
+	}
+
+	args := make([]byte, 4+2+4)
+	binary.LittleEndian.PutUint32(args[0:4], uint32(pos))
+	binary.LittleEndian.PutUint16(args[4:6], binlogDumpNonBlockFlag)
+	binary.LittleEndian.PutUint32(args[6:10], 0)
+	args = append(args, []byte(logFile)...)
+	if err := conn.writeCommand(comBinlogDump, args); err != nil {
+		return events, err
+	}
+
+	decoder := &eventDecoder{}
+	for len(events) < limit {
+		data, err := conn.readPacket()
+		if err != nil {
+			return events, err
+		}
+		switch {
+		case data[0] == errPacket:
+			return events, parseErrPacket(data)
+		case data[0] == eofPacket && len(data) < 9:
+			// end of binary logs
+			return events, nil
+		case data[0] != okPacket:
+			return events, fmt.Errorf("Unexpected binlog dump packet: 0x%02x", data[0])
+		}
+		event, err := decoder.decode(data[1:])
+		if err != nil {
+			return events, err
+		}
+		if event.EventType == "Heartbeat" {
+			continue
+		}
+		if event.Artificial {
+			if event.EventType == "Rotate" {
+				if decoder.logFile != "" && decoder.logFile != event.NextLogFile {
+					// moved on to next binary log
+					return events, nil
+				}
+				decoder.logFile = event.NextLogFile
+			}
+			continue
+		}
+		if event.LogFile != logFile {
+			log.Debugf("ReadEvents: unexpected binlog %s while reading %s on %s:%d", event.LogFile, logFile, host, port)
+			return events, nil
+		}
+		events = append(events, *event)
+		if event.EventType == "Rotate" {
+			// end of this binary log
+			return events, nil
+		}
+	}
+	return events, nil
+}
@@ -0,0 +1,117 @@
+package binlog
+
+import (
+	"bufio"
+	"encoding/binary"
+	"net"
+	"testing"
+
+	test "github.com/outbrain/golib/tests"
+)
+
+// fakeMaster serves a single connection: handshake, statements, and a binlog dump of given raw events
+func fakeMaster(t *testing.T, listener net.Listener, dumpEvents [][]byte) {
+	netConn, err := listener.Accept()
+	if err != nil {
+		return
+	}
+	defer netConn.Close()
+	c := &conn{netConn: netConn, reader: bufio.NewReader(netConn)}
+
+	greeting := []byte{10}
+	greeting = append(greeting, []byte("5.6.28-log")...)
+	greeting = append(greeting, 0, 1, 0, 0, 0)
+	greeting = append(greeting, []byte("abcdefgh")...)
+	greeting = append(greeting, 0)
+	capabilities := clientProtocol41 | clientSecureConnection | clientPluginAuth
+	greeting = append(greeting, byte(capabilities), byte(capabilities>>8), 33, 2, 0, byte(capabilities>>16), byte(capabilities>>24), 21)
+	greeting = append(greeting, make([]byte, 10)...)
+	greeting = append(greeting, []byte("ijklmnopqrst")...)
+	greeting = append(greeting, 0)
+	greeting = append(greeting, []byte(nativePasswordPlugin)...)
+	greeting = append(greeting, 0)
+	c.sequence = 0
+	c.writePacket(greeting)
+	if _, err := c.readPacket(); err != nil {
+		return
+	}
+	c.writePacket([]byte{okPacket, 0, 0, 2, 0, 0, 0})
+
+	for {
+		data, err := c.readPacket()
+		if err != nil {
+			return
+		}
+		switch data[0] {
+		case comQuery:
+			c.writePacket([]byte{okPacket, 0, 0, 2, 0, 0, 0})
+		case comBinlogDump:
+			test.S(t).ExpectEquals(binary.LittleEndian.Uint32(data[1:5]), uint32(500))
+			test.S(t).ExpectEquals(string(data[11:]), "mysql-bin.000007")
+			for _, event := range dumpEvents {
+				if err := c.writePacket(append([]byte{okPacket}, event...)); err != nil {
+					return
+				}
+			}
+			c.writePacket([]byte{eofPacket, 0, 0, 2, 0})
+		}
+	}
+}
+
+func TestReadEvents(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	test.S(t).ExpectNil(err)
+	defer listener.Close()
+
+	rotateBody := func(logFile string) []byte {
+		body := make([]byte, 8)
+		binary.LittleEndian.PutUint64(body, 4)
+		return append(body, []byte(logFile)...)
+	}
+	checksum := []byte{0, 0, 0, 0}
+	dumpEvents := [][]byte{
+		mkTestEvent(rotateEventType, 0, logEventArtificialFlag, rotateBody("mysql-bin.000007")),
+		mkTestEvent(formatDescriptionType, 0, 0, mkTestFormatDescriptionBody("5.6.28-log", binlogChecksumAlgCRC32)),
+		mkTestEvent(queryEventType, 600, 0, append(mkTestQueryBody("", "BEGIN"), checksum...)),
+		mkTestEvent(queryEventType, 700, 0, append(mkTestQueryBody("meta", "drop view if exists `_pseudo_gtid_hint__asc:0001`"), checksum...)),
+		mkTestEvent(rotateEventType, 800, 0, append(rotateBody("mysql-bin.000008"), checksum...)),
+		mkTestEvent(rotateEventType, 0, logEventArtificialFlag, rotateBody("mysql-bin.000008")),
+		mkTestEvent(queryEventType, 200, 0, append(mkTestQueryBody("", "BEGIN"), checksum...)),
+	}
+	go fakeMaster(t, listener, dumpEvents)
+
+	port := listener.Addr().(*net.TCPAddr).Port
+	events, err := ReadEvents("127.0.0.1", port, "mysql-bin.000007", 500, 100)
+	test.S(t).ExpectNil(err)
+	test.S(t).ExpectEquals(len(events), 3)
+	test.S(t).ExpectEquals(events[0].Info, "BEGIN")
+	test.S(t).ExpectEquals(events[1].Info, "use `meta`; drop view if exists `_pseudo_gtid_hint__asc:0001`")
+	test.S(t).ExpectEquals(events[1].NextPos, int64(700))
+	test.S(t).ExpectEquals(events[2].EventType, "Rotate")
+	for _, event := range events {
+		test.S(t).ExpectEquals(event.LogFile, "mysql-bin.000007")
+	}
+}
+
+func TestReadEventsLimit(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	test.S(t).ExpectNil(err)
+	defer listener.Close()
+
+	body := make([]byte, 8)
+	binary.LittleEndian.PutUint64(body, 4)
+	body = append(body, []byte("mysql-bin.000007")...)
+	dumpEvents := [][]byte{
+		mkTestEvent(rotateEventType, 0, logEventArtificialFlag, body),
+		mkTestEvent(formatDescriptionType, 0, 0, mkTestFormatDescriptionBody("5.5.46-log", binlogChecksumAlgOff)),
+		mkTestEvent(queryEventType, 600, 0, mkTestQueryBody("", "BEGIN")),
+		mkTestEvent(queryEventType, 700, 0, mkTestQueryBody("", "COMMIT")),
+	}
+	go fakeMaster(t, listener, dumpEvents)
+
+	port := listener.Addr().(*net.TCPAddr).Port
+	events, err := ReadEvents("127.0.0.1", port, "mysql-bin.000007", 500, 1)
+	test.S(t).ExpectNil(err)
+	test.S(t).ExpectEquals(len(events), 1)
+	test.S(t).ExpectEquals(events[0].Info, "BEGIN")
+}
@@ -327,6 +327,42 @@ Cheatsheet:
 
             --debug is your friend.
 
+    Declarative topology
+        These commands compare and refactor a cluster against a desired topology. The desired topology is a JSON or
+        YAML tree, read from the file given by -desired, or from stdin. Example:
+
+            {"Key": "master.com:3306", "Replicas": [{"Key": "slave1.com:3306", "Replicas": [{"Key": "slave2.com:3306"}]}]}
+
+        or, in YAML:
+
+            key: master.com:3306
+            replicas:
+              - key: slave1.com:3306
+                replicas:
+                  - key: slave2.com:3306
+
+        The root of the tree is not moved. Instances not listed in the tree are left alone (they follow their master).
+        The cluster is deduced by -alias or -i.
+
+        plan-topology
+            Show the steps (move-up, move-gtid, match-below or relocate) which would refactor the cluster into the
+            desired topology. Nothing is changed. Example:
+
+            orchestrator -c plan-topology -alias mycluster -desired /path/to/desired-topology.json
+
+        diff-topology
+            Compare each instance's current master with its desired master. Instances are listed as in-place, misplaced,
+            unknown (listed in desired topology but not found in cluster) or unmanaged (not listed in desired topology). Example:
+
+            orchestrator -c diff-topology -alias mycluster -desired /path/to/desired-topology.json
+
+        reconcile-topology
+            Refactor the cluster towards the desired topology, one step at a time, re-planning after each step. Each step
+            waits (up to ReconcileTopologyLagWaitSeconds) for replication lag to be reasonable, and is verified once
+            complete. Execution stops on first failure, in which case the remaining diff is reported. Example:
+
+            cat /path/to/desired-topology.json | orchestrator -c reconcile-topology -i master.com
+
     General replication commands
         These commands issue various statements that relate to replication.
 
@@ -438,6 +474,18 @@ Cheatsheet:
             orchestrator -c set-writeable
                 -i not given, implicitly assumed local hostname
 
+        clone-instance
+            Provision an instance with the data of a donor (given by -s) via the MySQL CLONE plugin (MySQL 8.0.17 and above,
+            plugin installed on both). The instance's data is replaced. Once the instance restarts and returns, it is attached
+            as a replica of the donor (or of the donor's master, if the donor has no binary logs) via GTID auto-positioning,
+            and orchestrator waits for it to catch up. No orchestrator-agent is required. Progress is recorded as a seed,
+            visible along with agent seeds. Tables listed in SeedChecksumTables are compared between instance and donor.
+            The instance may not be the writable master of its cluster nor have replicas, unless --force is given. Examples:
+
+            orchestrator -c clone-instance -i new.replica.com -s donor.replica.com
+
+            orchestrator -c clone-instance -i former.intermediate.master.com -s donor.replica.com --force
+
     Binlog commands
         Commands that investigate/work on binary logs
 
@@ -456,12 +504,33 @@ Cheatsheet:
 
                 Purges binary logs until given log
 
+        binlog-purge-plan
+            Show which binary logs of a cluster's masters and intermediate masters can be safely purged. Output is
+            tab separated: instance, oldest binary log, oldest binary log still read by a replica, oldest binary log
+            to keep (accounting for Pseudo-GTID, BinlogPurgeRetainFiles and BinlogPurgeRetainHours), binary log to
+            purge to, and what blocks purging, if anything. Cluster is deduced by -alias or -i. Example:
+
+            orchestrator -c binlog-purge-plan -alias mycluster
+
+        purge-cluster-binary-logs
+            Purge binary logs on a cluster's masters and intermediate masters, as shown by binlog-purge-plan.
+            This is what orchestrator periodically does on clusters matching BinlogPurgeClusterFilters. Example:
+
+            orchestrator -c purge-cluster-binary-logs -alias mycluster
+
         last-pseudo-gtid
             Information command; an authoritative way of detecting whether a Pseudo-GTID event exist for an instance,
             and if so, output the last Pseudo-GTID entry and its location. Example:
 
             orchestrator -c last-pseudo-gtid -i instance.with.possible.pseudo-gtid.injection
 
+        inject-pseudo-gtid
+            Inject a Pseudo-GTID entry on given instance, which must be a writeable (read_only=0) master. The entry is
+            the same as orchestrator injects by itself when AutoPseudoGTID is enabled, and must match PseudoGTIDPattern.
+            Example:
+
+            orchestrator -c inject-pseudo-gtid -i master.to.inject.com
+
         find-binlog-entry
             Get binlog file:pos of entry given by --pattern (exact full match, not a regular expression) in a given instance.
             This will search the instance's binary logs starting with most recent, and terminate as soon as an exact match is found.
@@ -556,6 +625,13 @@ Cheatsheet:
             orchestrator -c topology
                 -i not given, implicitly assumed local hostname
 
+            orchestrator -c topology -i instance.belonging.to.a.topology.com -format dot | dot -Tpng > topology.png
+                Graphviz DOT output: nodes are colored by health, lag and downtime; edges are annotated with
+                replication thread state and lag.
+
+            orchestrator -c topology -i instance.belonging.to.a.topology.com -format json
+                Nested JSON tree: each instance lists its Replicas.
+
             Instance must be already known to orchestrator. Topology is generated by orchestrator's mapping
             and not from synchronuous investigation of the instances. The generated topology may include
             instances that are dead, or whose replication is broken.
@@ -701,13 +777,52 @@ Cheatsheet:
 
         snapshot-topologies
             Take a snapshot of existing topologies. This will record minimal replication topology data: the identity
-            of an instance, its master and its cluster.
+            of an instance, its master, its cluster, version and read_only state.
             Taking a snapshot later allows for reviewing changes in topologies. One might wish to invoke this command
             on a daily basis, and later be able to solve questions like 'where was this instacne replicating from before
             we moved it?', 'which instances were replication from this instance a week ago?' etc. Example:
 
             orchestrator -c snapshot-topologies
 
+        topology-snapshots
+            List available topology snapshots of a cluster (unix timestamp, local time, number of instances),
+            most recent first. Cluster is deduced by -alias or -i. Example:
+
+            orchestrator -c topology-snapshots -alias mycluster
+
+        topology-at
+            Show an ascii-graph of a replication topology as recorded in the latest snapshot taken at or before
+            the time given by -at (unix timestamp, or local time such as '2016-01-02 15:04:05'). Example:
+
+            orchestrator -c topology-at -alias mycluster -at '2016-01-02 15:04:05'
+
+        topology-history-diff
+            Compare a cluster's topology at two points in time: -since and -at (when -at is not given, the current
+            topology is used). Lists instances which moved masters, appeared, disappeared or changed read_only.
+            Useful for reconstructing what an incident did to a topology. Example:
+
+            orchestrator -c topology-history-diff -alias mycluster -since '2016-01-02 03:00' -at '2016-01-02 05:00'
+
+        binlog-growth
+            Report binlog write rate of a given instance, based on binlog file history (requires BinlogFileHistoryDays).
+            Output is tab separated: instance, cluster, baseline bytes/hour, recent bytes/hour (last
+            BinlogGrowthRecentHours), trend (bytes/hour per day), spike flag and hours until binary logs fill the
+            datadir ("-" when no agent runs on the host). Example:
+
+            orchestrator -c binlog-growth -i instance.with.binlogs.com
+
+        cluster-binlog-growth
+            Report binlog write rate of all instances of a cluster, in the same format as binlog-growth.
+            Cluster is deduced by -alias or -i. Example:
+
+            orchestrator -c cluster-binlog-growth -alias mycluster
+
+        binlog-growth-spikes
+            List clusters where the recent binlog write rate of any instance exceeds its baseline rate by
+            BinlogGrowthSpikeFactor. Example:
+
+            orchestrator -c binlog-growth-spikes
+
     Orchestrator instance management
         These command dig into the way orchestrator manages instances and operations on instances
 
@@ -843,6 +958,22 @@ Cheatsheet:
 								Indicate cluster by an instance. You don't structly need to specify the master, orchestrator
 								will infer the master's identify.
 
+        rolling-restart
+            Restart all replicas of a cluster, one at a time and leaf-first: each is downtimed, stopped replicating,
+            restarted via RollingRestartCommand (or orchestrator-agent), and waited for to catch up. Waits for the
+            rolling restart to complete, and prints its id. A failed rolling restart may be resumed via the
+            /api/resume-rolling-restart/:id API. Examples:
+
+            orchestrator -c rolling-restart -alias mycluster
+
+            orchestrator -c rolling-restart -i instance.in.relevant.cluster.com
+
+        rolling-restart-takeover
+            As rolling-restart, then gracefully take over the master (which must have exactly one direct replica,
+            see graceful-master-takeover) and restart the former master. Example:
+
+            orchestrator -c rolling-restart-takeover -alias mycluster
+
         replication-analysis
             Request an analysis of potential crash incidents in all known topologies.
             Output format is not yet stabilized and may change in the future. Do not trust the output
@@ -956,6 +1087,26 @@ Cheatsheet:
 
 						orchestrator -c access-token
 
+        create-api-key
+            Create a named API key for automation, accepted by the HTTP API via "Authorization: Bearer <key>" header.
+            The key is printed once and only its hash is kept. Scopes (-scopes) are a comma delimited list of:
+            "read-only", "cluster=<regexp>" (cluster name or alias), "operation=<api operation>", e.g. "relocate".
+            Keys have no admin operations unless explicitly listed. Optional expiry via -duration. Examples:
+
+            orchestrator -c create-api-key -api-key deploy-bot -scopes "cluster=^team-a-,operation=begin-downtime,operation=end-downtime"
+
+            orchestrator -c create-api-key -api-key dashboards -scopes read-only -duration 4w
+
+        api-keys
+            List API keys, their scopes, expiry and last usage. Example:
+
+            orchestrator -c api-keys
+
+        revoke-api-key
+            Revoke an API key by name. Example:
+
+            orchestrator -c revoke-api-key -api-key deploy-bot
+
         reset-hostname-resolve-cache
             Clear the hostname resolve cache; it will be refilled by following host discoveries
 
@@ -1000,6 +1151,13 @@ func main() {
 	config.RuntimeCLIFlags.BinlogFile = flag.String("binlog", "", "Binary log file name")
 	config.RuntimeCLIFlags.Statement = flag.String("statement", "", "Statement/hint")
 	config.RuntimeCLIFlags.GrabElection = flag.Bool("grab-election", false, "Grab leadership (only applies to continuous mode)")
+	config.RuntimeCLIFlags.DesiredTopology = flag.String("desired", "", "Desired topology JSON or YAML file (applies for plan-topology, diff-topology, reconcile-topology); read from stdin when empty")
+	config.RuntimeCLIFlags.At = flag.String("at", "", "Point in time, unix timestamp or local time such as '2016-01-02 15:04:05' (applies for topology-at, topology-history-diff)")
+	config.RuntimeCLIFlags.Since = flag.String("since", "", "Point in time, unix timestamp or local time such as '2016-01-02 15:04:05' (applies for topology-history-diff)")
+	config.RuntimeCLIFlags.Format = flag.String("format", "ascii", "Output format: ascii|dot|json (applies for topology)")
+	config.RuntimeCLIFlags.APIKeyName = flag.String("api-key", "", "API key name (applies for create-api-key, revoke-api-key)")
+	config.RuntimeCLIFlags.APIKeyScopes = flag.String("scopes", "", "Comma delimited API key scopes: read-only, cluster=<regexp>, operation=<api operation> (applies for create-api-key)")
+	config.RuntimeCLIFlags.Force = flag.Bool("force", false, "Override safety checks (applies for clone-instance: allow a target which is a writable master or has replicas)")
 	config.RuntimeCLIFlags.PromotionRule = flag.String("promotion-rule", "prefer", "Promotion rule for register-andidate (prefer|neutral|must_not)")
 	config.RuntimeCLIFlags.Version = flag.Bool("version", false, "Print version and exit")
 	flag.Parse()
@@ -27,6 +27,13 @@ type CLIFlags struct {
 	Version            *bool
 	Statement          *string
 	PromotionRule      *string
+	DesiredTopology    *string
+	At                 *string
+	Since              *string
+	Format             *string
+	APIKeyName         *string
+	APIKeyScopes       *string
+	Force              *bool
 	ConfiguredVersion  string
 }
 
@@ -31,6 +31,22 @@ var (
 	envVariableRegexp = regexp.MustCompile("[$][{](.*)[}]")
 )
 
+// RemediationRule maps a broken replication thread to a remediation action
+type RemediationRule struct {
+	Thread       string // "sql" or "io": the replication thread this rule applies to
+	ErrorPattern string // Regexp matched against the thread's last error message. Empty matches any error
+	ErrorCodes   []uint // When non empty, the thread's last error number must be one of these
+	Action       string // One of "restart-io-thread", "restart-replication", "skip-transaction", "downtime"
+}
+
+// RoleBinding grants a role to users and/or groups, optionally limited to a set of clusters
+type RoleBinding struct {
+	Role            string   // One of "viewer", "operator", "recoverer", "admin"
+	Users           []string // Authenticated user names granted this role. "*" matches any authenticated user
+	Groups          []string // Groups (as provided by AuthGroupsHeader) granted this role
+	ClusterPatterns []string // Regexp patterns matched against cluster name or cluster alias. Empty means all clusters
+}
+
 // Configuration makes for orchestrator configuration input, which can be provided by user via JSON formatted file.
 // Some of the parameteres have reasonable default values, and some (like database credentials) are
 // strictly expected from user.
@@ -39,6 +55,7 @@ type Configuration struct {
 	EnableSyslog                                 bool   // Should logs be directed (in addition) to syslog daemon?
 	ListenAddress                                string // Where orchestrator HTTP should listen for TCP
 	ListenSocket                                 string // Where orchestrator HTTP should listen for unix socket (default: empty; when given, TCP is disabled)
+	HTTPAdvertise                                string // URL by which other orchestrator nodes reach this node's HTTP API, e.g. "http://orchestrator1.example.com:3000". Default: derived from hostname and ListenAddress
 	AgentsServerPort                             string // port orchestrator agents talk back to
 	MySQLTopologyUser                            string
 	MySQLTopologyPassword                        string // my.cnf style configuration file from where to pick credentials. Expecting `user`, `password` under `[client]` section
@@ -77,6 +94,12 @@ type Configuration struct {
 	InstanceFlushIntervalMilliseconds            int      // Max interval between instance write buffer flushes
 	ReadLongRunningQueries                       bool     // Whether orchestrator should read and record current long running executing queries.
 	BinlogFileHistoryDays                        int      // When > 0, amount of days for which orchestrator records per-instance binlog files & sizes
+	BinlogGrowthRecentHours                      uint     // Binlog growth reports compare the write rate of this many recent hours against the preceding history
+	BinlogGrowthSpikeFactor                      float64  // An instance whose recent binlog write rate exceeds its baseline rate by this factor is flagged as spiking
+	LagHistoryResolutionSeconds                  uint     // When > 0, orchestrator records per-instance replication lag samples at this resolution. Default: 0 (disabled)
+	LagHistoryDownsampleHours                    uint     // Lag samples older than this many hours are downsampled to LagHistoryDownsampleResolutionSeconds
+	LagHistoryDownsampleResolutionSeconds        uint     // Resolution of downsampled lag samples
+	LagHistoryRetentionDays                      uint     // Amount of days for which lag samples are kept
 	UnseenInstanceForgetHours                    uint     // Number of hours after which an unseen instance is forgotten
 	SnapshotTopologiesIntervalHours              uint     // Interval in hour between snapshot-topologies invocation. Default: 0 (disabled)
 	DiscoveryMaxConcurrency                      uint     // Number of goroutines doing hosts discovery
@@ -94,8 +117,15 @@ type Configuration struct {
 	VerifyReplicationFilters                     bool     // Include replication filters check before approving topology refactoring
 	MaintenanceOwner                             string   // (Default) name of maintenance owner to use if none provided
 	ReasonableMaintenanceReplicationLagSeconds   int      // Above this value move-up and move-below are blocked
+	ReconcileTopologyLagWaitSeconds              uint     // Max time reconcile-topology waits for replication lag to drop below ReasonableMaintenanceReplicationLagSeconds before each step
+	RollingRestartCommand                        string   // Command restarting MySQL on an instance during rolling-restart. Placeholders: {host}, {port}, {clusterName}. When empty, MySQL is restarted via orchestrator-agent
+	RollingRestartInstanceTimeoutSeconds         uint     // Max time rolling-restart waits for a restarted instance to return and catch up below ReasonableMaintenanceReplicationLagSeconds
+	RollingRestartDowntimeMinutes                uint     // Downtime applied to each instance while being restarted by rolling-restart
 	MaintenanceExpireMinutes                     uint     // Minutes after which a maintenance flag is considered stale and is cleared
 	MaintenancePurgeDays                         uint     // Days after which maintenance entries are purged from the database
+	AsyncRequestWorkers                          uint     // Number of async requests (queued operations) the elected node executes concurrently
+	ClusterLockTTLSeconds                        uint     // A cluster operation lock not refreshed by its holder within this time is considered stale and may be taken over
+	ClusterLockWaitSeconds                       uint     // Time a topology operation or recovery waits for a cluster operation lock held by another orchestrator process. 0 to fail fast
 	CandidateInstanceExpireMinutes               uint     // Minutes after which a suggestion to use an instance as a candidate slave (to be preferably promoted on master failover) is expired.
 	AuditLogFile                                 string   // Name of log file for audit operations. Disabled when empty.
 	AuditToSyslog                                bool     // If true, audit messages are written to syslog
@@ -103,14 +133,22 @@ type Configuration struct {
 	AuditPurgeDays                               uint   // Days after which audit entries are purged from the database
 	RemoveTextFromHostnameDisplay                string // Text to strip off the hostname on cluster/clusters pages
 	ReadOnly                                     bool
-	AuthenticationMethod                         string // Type of autherntication to use, if any. "" for none, "basic" for BasicAuth, "multi" for advanced BasicAuth, "proxy" for forwarded credentials via reverse proxy, "token" for token based access
+	AuthenticationMethod                         string // Type of autherntication to use, if any. "" for none, "basic" for BasicAuth, "multi" for advanced BasicAuth, "proxy" for forwarded credentials via reverse proxy, "token" for token based access, "oauth" for OpenID Connect login
 	OAuthClientId                                string
 	OAuthClientSecret                            string
 	OAuthScopes                                  []string
+	OAuthIssuerURL                               string            // OpenID Connect issuer, e.g. "https://accounts.example.com". Provider endpoints are discovered via its /.well-known/openid-configuration
+	OAuthRedirectURL                             string            // Callback URL as registered with the provider, e.g. "https://orchestrator.example.com/oauth2callback". When empty, derived from the request's host
+	OAuthUserClaim                               string            // ID token claim identifying the user
+	OAuthGroupsClaim                             string            // ID token claim listing the user's groups, usable in AuthorizationRoles
+	OAuthSessionSecret                           string            // Secret signing session cookies; must be shared by all orchestrator nodes serving the same users. Required with "oauth" AuthenticationMethod
+	OAuthSessionExpiryMinutes                    uint              // Time after which a login session expires
 	HTTPAuthUser                                 string            // Username for HTTP Basic authentication (blank disables authentication)
 	HTTPAuthPassword                             string            // Password for HTTP Basic authentication
 	AuthUserHeader                               string            // HTTP header indicating auth user, when AuthenticationMethod is "proxy"
-	PowerAuthUsers                               []string          // On AuthenticationMethod == "proxy", list of users that can make changes. All others are read-only.
+	PowerAuthUsers                               []string          // On AuthenticationMethod == "proxy" or "oauth", list of users that can make changes. All others are read-only.
+	AuthGroupsHeader                             string            // HTTP header indicating comma delimited groups of auth user, when AuthenticationMethod is "proxy"
+	AuthorizationRoles                           []RoleBinding     // When non empty, enables role based access control on the API, overriding PowerAuthUsers and the "multi" readonly user
 	AccessTokenUseExpirySeconds                  uint              // Time by which an issued token must be used
 	AccessTokenExpiryMinutes                     uint              // Time after which HTTP access token expires
 	ClusterNameToAlias                           map[string]string // map between regex matching cluster name to a human friendly alias
@@ -126,6 +164,7 @@ type Configuration struct {
 	SupportFuzzyPoolHostnames                    bool              // Should "submit-pool-instances" command be able to pass list of fuzzy instances (fuzzy means non-fqdn, but unique enough to recognize). Defaults 'true', implies more queries on backend db
 	InstancePoolExpiryMinutes                    uint              // Time after which entries in database_instance_pool are expired (resubmit via `submit-pool-instances`)
 	PromotionIgnoreHostnameFilters               []string          // Orchestrator will not promote slaves with hostname matching pattern (via -c recovery; for example, avoid promoting dev-dedicated machines)
+	PromotionIgnoreDelayedSlaves                 bool              // When true (default), slaves configured with SQL_Delay (MASTER_DELAY) are never chosen as promotion candidates
 	ServeAgentsHttp                              bool              // Spawn another HTTP interface dedicated for orchestrator-agent
 	AgentsUseSSL                                 bool              // When "true" orchestrator will listen on agents port with SSL as well as connect to agents via SSL
 	AgentsUseMutualTLS                           bool              // When "true" Use mutual TLS for the server to agent communication
@@ -134,6 +173,9 @@ type Configuration struct {
 	AgentSSLCertFile                             string            // Name of Agent SSL certification file, applies only when AgentsUseSSL = true
 	AgentSSLCAFile                               string            // Name of the Agent Certificate Authority file, applies only when AgentsUseSSL = true
 	AgentSSLValidOUs                             []string          // Valid organizational units when using mutual TLS to communicate with the agents
+	AgentEnrollmentSecret                        string            // When non-empty, agents must sign their registration with this pre-shared secret
+	AgentTokenRotationMinutes                    uint              // Interval at which per-agent tokens are rotated. 0 disables rotation
+	AgentTokenRevokeMinutes                      uint              // Agents not seen with a valid token for this many minutes are revoked. 0 disables revocation
 	UseSSL                                       bool              // Use SSL on the server web port
 	UseMutualTLS                                 bool              // When "true" Use mutual TLS for the server's web and API connections
 	SSLSkipVerify                                bool              // When using SSL, should we ignore SSL certification error
@@ -150,19 +192,39 @@ type Configuration struct {
 	UnseenAgentForgetHours                       uint              // Number of hours after which an unseen agent is forgotten
 	StaleSeedFailMinutes                         uint              // Number of minutes after which a stale (no progress) seed is considered failed.
 	SeedAcceptableBytesDiff                      int64             // Difference in bytes between seed source & target data size that is still considered as successful copy
+	DefaultSeedMethod                            string            // Seed method used when a seed request does not specify one: "lvm" (snapshot copy) or "xtrabackup" (streamed backup)
+	SeedAttachReplica                            bool              // When true, seeds not saying otherwise end by attaching the target into replication, waiting for it to catch up and comparing checksums
+	SeedChecksumTables                           []string          // Tables (schema.table) compared via CHECKSUM TABLE between seed target and source, when attaching a replica
+	SeedCatchUpTimeoutMinutes                    uint              // Time allowed for a freshly seeded replica to catch up with its master
 	PseudoGTIDPattern                            string            // Pattern to look for in binary logs that makes for a unique entry (pseudo GTID). When empty, Pseudo-GTID based refactoring is disabled.
 	PseudoGTIDPatternIsFixedSubstring            bool              // If true, then PseudoGTIDPattern is not treated as regular expression but as fixed substring, and can boost search time
 	PseudoGTIDMonotonicHint                      string            // subtring in Pseudo-GTID entry which indicates Pseudo-GTID entries are expected to be monotonically increasing
 	DetectPseudoGTIDQuery                        string            // Optional query which is used to authoritatively decide whether pseudo gtid is enabled on instance
+	AutoPseudoGTID                               bool              // When true, orchestrator (the elected node) periodically injects Pseudo-GTID entries on all writeable cluster masters. Injected entries must match PseudoGTIDPattern
+	PseudoGTIDInjectionIntervalSeconds           uint              // Interval between Pseudo-GTID injections, applies when AutoPseudoGTID is true
+	PseudoGTIDInjectionSchema                    string            // Schema name used in injected Pseudo-GTID entries (e.g. `drop view if exists meta._pseudo_gtid_hint__...`). Created on masters if missing
+	PseudoGTIDIndexHours                         uint              // When > 0, orchestrator maintains a persisted index of Pseudo-GTID entries to binlog coordinates, used to speed up Pseudo-GTID lookups. Entries are kept for this many hours; this should not exceed binary log retention
+	PseudoGTIDSearchConcurrency                  uint              // Max number of binary logs scanned concurrently on a single instance when searching for Pseudo-GTID entries
 	PseudoGTIDCoordinatesHistoryHeuristicMinutes int               // Significantly reducing Pseudo-GTID lookup time, this indicates the most recent N minutes binlog position where search for Pseudo-GTID will heuristically begin (there is a fallback on fullscan if unsuccessful)
 	BinlogEventsChunkSize                        int               // Chunk size (X) for SHOW BINLOG|RELAYLOG EVENTS LIMIT ?,X statements. Smaller means less locking and mroe work to be done
 	BufferBinlogEvents                           bool              // Should we used buffered read on SHOW BINLOG|RELAYLOG EVENTS -- releases the database lock sooner (recommended)
+	StreamBinlogEvents                           bool              // When true, binary log events (not relay log events) are read via a replication connection (COM_BINLOG_DUMP) rather than SHOW BINLOG EVENTS. Requires REPLICATION SLAVE privilege; not supported with MySQLTopologyUseMutualTLS
 	SkipBinlogEventsContaining                   []string          // When scanning/comparing binlogs for Pseudo-GTID, skip entries containing given texts. These are NOT regular expressions (would consume too much CPU while scanning binlogs), just substrings to find.
 	ReduceReplicationAnalysisCount               bool              // When true, replication analysis will only report instances where possibility of handled problems is possible in the first place (e.g. will not report most leaf nodes, that are mostly uninteresting). When false, provides an entry for every known instance
 	FailureDetectionPeriodBlockMinutes           int               // The time for which an instance's failure discovery is kept "active", so as to avoid concurrent "discoveries" of the instance's failure; this preceeds any recovery process, if any.
 	RecoveryPollSeconds                          int               // Interval between checks for a recovery scenario and initiation of a recovery process
 	RecoveryPeriodBlockMinutes                   int               // (supported for backwards compatibility but please use newer `RecoveryPeriodBlockSeconds` instead) The time for which an instance's recovery is kept "active", so as to avoid concurrent recoveries on smae instance as well as flapping
 	RecoveryPeriodBlockSeconds                   int               // (overrides `RecoveryPeriodBlockMinutes`) The time for which an instance's recovery is kept "active", so as to avoid concurrent recoveries on smae instance as well as flapping
+	MasterRecoveryRateLimitCount                 uint              // Max number of automated master recoveries (across all clusters) allowed within `MasterRecoveryRateLimitMinutes`. Tripping the limit disables recoveries globally. 0 to disable this limit
+	MasterRecoveryRateLimitPerDataCenterCount    uint              // Max number of automated master recoveries of masters in same data center allowed within `MasterRecoveryRateLimitMinutes`. Tripping the limit disables recoveries globally. 0 to disable this limit
+	MasterRecoveryRateLimitMinutes               uint              // Time window for `MasterRecoveryRateLimitCount` and `MasterRecoveryRateLimitPerDataCenterCount`
+	FailureConfirmationQuorum                    uint              // Number of orchestrator nodes (including the elected node) that must independently deem a master dead before automated master recovery proceeds. 0 or 1 disables quorum confirmation
+	FailureConfirmationTimeoutSeconds            uint              // Timeout for a single orchestrator node to respond to a failure confirmation request
+	AgentFailureVerification                     bool              // When true, the orchestrator-agent on a dead master's host is asked whether the host is reachable and mysqld running, before recovery
+	AgentFailureVerificationTimeoutSeconds       uint              // Timeout for the agent to respond to failure verification
+	AgentFailureVerificationCacheSeconds         uint              // Time for which a host's failure verification verdict is reused rather than asking the agent again
+	AgentMySQLRunningBlocksFailover              bool              // When true (and AgentFailureVerification), a dead master whose agent reports mysqld as running is not failed over
+	AgentRestartDeadMySQL                        bool              // When true (and AgentFailureVerification), a dead master whose agent reports mysqld as not running is restarted via the agent rather than failed over
 	RecoveryIgnoreHostnameFilters                []string          // Recovery analysis will completely ignore hosts matching given patterns
 	RecoverMasterClusterFilters                  []string          // Only do master recovery on clusters matching these regexp patterns (of course the ".*" pattern matches everything)
 	RecoverIntermediateMasterClusterFilters      []string          // Only do IM recovery on clusters matching these regexp patterns (of course the ".*" pattern matches everything)
@@ -174,6 +236,18 @@ type Configuration struct {
 	PostMasterFailoverProcesses                  []string          // Processes to execute after doing a master failover (order of execution undefined). Uses same placeholders as PostFailoverProcesses
 	PostIntermediateMasterFailoverProcesses      []string          // Processes to execute after doing a master failover (order of execution undefined). Uses same placeholders as PostFailoverProcesses
 	UnreachableMasterWithStaleSlavesProcesses    []string          // Processes to execute when detecting an UnreachableMasterWithStaleSlaves scenario.
+	RecoveryRateLimitProcesses                   []string          // Processes to execute when a master recovery rate limit trips and recoveries get disabled globally. Uses same placeholders as OnFailureDetectionProcesses
+	ReplicationRemediationClusterFilters         []string          // Only do automated remediation of broken replication on clusters matching these regexp patterns (same syntax as RecoverMasterClusterFilters). Empty means no remediation
+	ReplicationRemediationRules                  []RemediationRule // Rules mapping replication thread errors to remediation actions. Rules are evaluated in order; first matching rule applies
+	ReplicationRemediationSkipErrorCodes         []uint            // Whitelist of SQL thread error numbers (Last_SQL_Errno) for which the "skip-transaction" action is allowed to run
+	ReplicationRemediationRateLimitCount         uint              // Max number of remediation actions on a single instance within ReplicationRemediationRateLimitMinutes. 0 means unlimited
+	ReplicationRemediationRateLimitMinutes       uint              // Time window for ReplicationRemediationRateLimitCount
+	ReplicationRemediationDowntimeMinutes        uint              // Downtime duration applied by the "downtime" remediation action
+	ReplicationRemediationAlertProcesses         []string          // Processes to execute by the "downtime" remediation action. Uses same placeholders as OnFailureDetectionProcesses; {failureType} is "ReplicationRemediation" and {failureDescription} holds the replication error
+	BinlogPurgeClusterFilters                    []string          // Clusters (same syntax as RecoverMasterClusterFilters) on which orchestrator periodically purges binary logs no longer needed by any replica. Empty means no managed purging
+	BinlogPurgeIntervalMinutes                   uint              // Interval between managed binary log purges
+	BinlogPurgeRetainFiles                       uint              // Number of binary logs to keep beyond the oldest binary log still needed by any replica
+	BinlogPurgeRetainHours                       uint              // When > 0, binary logs written to within this many hours are kept. Requires BinlogFileHistoryDays to cover this period
 	CoMasterRecoveryMustPromoteOtherCoMaster     bool              // When 'false', anything can get promoted (and candidates are prefered over others). When 'true', orchestrator will promote the other co-master or else fail
 	DetachLostSlavesAfterMasterFailover          bool              // Should slaves that are not to be lost in master recovery (i.e. were more up-to-date than promoted slave) be forcibly detached
 	ApplyMySQLPromotionAfterMasterFailover       bool              // Should orchestrator take upon itself to apply MySQL master promotion: set read_only=0, detach replication, etc.
@@ -211,6 +285,7 @@ func newConfiguration() *Configuration {
 		Debug:                                        false,
 		EnableSyslog:                                 false,
 		ListenAddress:                                ":3000",
+		HTTPAdvertise:                                "",
 		ListenSocket:                                 "",
 		AgentsServerPort:                             ":3001",
 		StatusEndpoint:                               "/api/status",
@@ -234,6 +309,12 @@ func newConfiguration() *Configuration {
 		InstanceFlushIntervalMilliseconds:            100,
 		ReadLongRunningQueries:                       true,
 		BinlogFileHistoryDays:                        0,
+		BinlogGrowthRecentHours:                      1,
+		BinlogGrowthSpikeFactor:                      3,
+		LagHistoryResolutionSeconds:                  0,
+		LagHistoryDownsampleHours:                    24,
+		LagHistoryDownsampleResolutionSeconds:        300,
+		LagHistoryRetentionDays:                      7,
 		UnseenInstanceForgetHours:                    240,
 		SnapshotTopologiesIntervalHours:              0,
 		SlaveStartPostWaitMilliseconds:               1000,
@@ -253,8 +334,15 @@ func newConfiguration() *Configuration {
 		VerifyReplicationFilters:                     false,
 		MaintenanceOwner:                             "orchestrator",
 		ReasonableMaintenanceReplicationLagSeconds:   20,
+		ReconcileTopologyLagWaitSeconds:              60,
+		RollingRestartCommand:                        "",
+		RollingRestartInstanceTimeoutSeconds:         600,
+		RollingRestartDowntimeMinutes:                30,
 		MaintenanceExpireMinutes:                     10,
 		MaintenancePurgeDays:                         365,
+		AsyncRequestWorkers:                          4,
+		ClusterLockTTLSeconds:                        60,
+		ClusterLockWaitSeconds:                       0,
 		CandidateInstanceExpireMinutes:               60,
 		AuditLogFile:                                 "",
 		AuditToSyslog:                                false,
@@ -263,10 +351,18 @@ func newConfiguration() *Configuration {
 		RemoveTextFromHostnameDisplay:                "",
 		ReadOnly:                                     false,
 		AuthenticationMethod:                         "basic",
+		OAuthIssuerURL:                               "",
+		OAuthRedirectURL:                             "",
+		OAuthUserClaim:                               "email",
+		OAuthGroupsClaim:                             "groups",
+		OAuthSessionSecret:                           "",
+		OAuthSessionExpiryMinutes:                    480,
 		HTTPAuthUser:                                 "",
 		HTTPAuthPassword:                             "",
 		AuthUserHeader:                               "X-Forwarded-User",
 		PowerAuthUsers:                               []string{"*"},
+		AuthGroupsHeader:                             "",
+		AuthorizationRoles:                           []RoleBinding{},
 		AccessTokenUseExpirySeconds:                  60,
 		AccessTokenExpiryMinutes:                     1440,
 		ClusterNameToAlias:                           make(map[string]string),
@@ -282,10 +378,14 @@ func newConfiguration() *Configuration {
 		SupportFuzzyPoolHostnames:                    true,
 		InstancePoolExpiryMinutes:                    60,
 		PromotionIgnoreHostnameFilters:               []string{},
+		PromotionIgnoreDelayedSlaves:                 true,
 		ServeAgentsHttp:                              false,
 		AgentsUseSSL:                                 false,
 		AgentsUseMutualTLS:                           false,
 		AgentSSLValidOUs:                             []string{},
+		AgentEnrollmentSecret:                        "",
+		AgentTokenRotationMinutes:                    0,
+		AgentTokenRevokeMinutes:                      0,
 		AgentSSLSkipVerify:                           false,
 		AgentSSLPrivateKeyFile:                       "",
 		AgentSSLCertFile:                             "",
@@ -303,19 +403,39 @@ func newConfiguration() *Configuration {
 		UnseenAgentForgetHours:                       6,
 		StaleSeedFailMinutes:                         60,
 		SeedAcceptableBytesDiff:                      8192,
+		DefaultSeedMethod:                            "lvm",
+		SeedAttachReplica:                            false,
+		SeedChecksumTables:                           []string{},
+		SeedCatchUpTimeoutMinutes:                    60,
 		PseudoGTIDPattern:                            "",
 		PseudoGTIDPatternIsFixedSubstring:            false,
 		PseudoGTIDMonotonicHint:                      "",
 		DetectPseudoGTIDQuery:                        "",
+		AutoPseudoGTID:                               false,
+		PseudoGTIDInjectionIntervalSeconds:           5,
+		PseudoGTIDInjectionSchema:                    "meta",
+		PseudoGTIDIndexHours:                         0,
+		PseudoGTIDSearchConcurrency:                  3,
 		PseudoGTIDCoordinatesHistoryHeuristicMinutes: 2,
 		BinlogEventsChunkSize:                        10000,
 		BufferBinlogEvents:                           true,
+		StreamBinlogEvents:                           false,
 		SkipBinlogEventsContaining:                   []string{},
 		ReduceReplicationAnalysisCount:               true,
 		FailureDetectionPeriodBlockMinutes:           60,
 		RecoveryPollSeconds:                          10,
 		RecoveryPeriodBlockMinutes:                   60,
 		RecoveryPeriodBlockSeconds:                   3600,
+		MasterRecoveryRateLimitCount:                 0,
+		MasterRecoveryRateLimitPerDataCenterCount:    0,
+		MasterRecoveryRateLimitMinutes:               10,
+		FailureConfirmationQuorum:                    0,
+		FailureConfirmationTimeoutSeconds:            5,
+		AgentFailureVerification:                     false,
+		AgentFailureVerificationTimeoutSeconds:       5,
+		AgentFailureVerificationCacheSeconds:         60,
+		AgentMySQLRunningBlocksFailover:              false,
+		AgentRestartDeadMySQL:                        false,
 		RecoveryIgnoreHostnameFilters:                []string{},
 		RecoverMasterClusterFilters:                  []string{},
 		RecoverIntermediateMasterClusterFilters:      []string{},
@@ -327,6 +447,18 @@ func newConfiguration() *Configuration {
 		PostFailoverProcesses:                        []string{},
 		PostUnsuccessfulFailoverProcesses:            []string{},
 		UnreachableMasterWithStaleSlavesProcesses:    []string{},
+		RecoveryRateLimitProcesses:                   []string{},
+		ReplicationRemediationClusterFilters:         []string{},
+		BinlogPurgeClusterFilters:                    []string{},
+		BinlogPurgeIntervalMinutes:                   60,
+		BinlogPurgeRetainFiles:                       10,
+		BinlogPurgeRetainHours:                       0,
+		ReplicationRemediationRules:                  []RemediationRule{},
+		ReplicationRemediationSkipErrorCodes:         []uint{},
+		ReplicationRemediationRateLimitCount:         3,
+		ReplicationRemediationRateLimitMinutes:       60,
+		ReplicationRemediationDowntimeMinutes:        60,
+		ReplicationRemediationAlertProcesses:         []string{},
 		CoMasterRecoveryMustPromoteOtherCoMaster:     true,
 		DetachLostSlavesAfterMasterFailover:          true,
 		ApplyMySQLPromotionAfterMasterFailover:       false,
@@ -398,6 +530,9 @@ func postReadAdjustments() {
 		// still supported in config file for backwards compatibility
 		Config.RecoveryPeriodBlockSeconds = Config.RecoveryPeriodBlockMinutes * 60
 	}
+	if Config.PseudoGTIDSearchConcurrency == 0 {
+		Config.PseudoGTIDSearchConcurrency = 1
+	}
 
 	if Config.URLPrefix != "" {
 		// Ensure the prefix starts with "/" and has no trailing one.
@@ -504,6 +504,137 @@ var generateSQLBase = []string{
 		  PRIMARY KEY (disable_recovery)
 		) ENGINE=InnoDB DEFAULT CHARSET=ascii
 	`,
+	`
+		CREATE TABLE IF NOT EXISTS database_instance_pseudo_gtid_injection (
+		  hostname varchar(128) CHARACTER SET ascii NOT NULL,
+		  port smallint(5) unsigned NOT NULL,
+		  last_attempted_timestamp timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		  last_injected_timestamp timestamp NULL DEFAULT NULL,
+		  is_successful TINYINT UNSIGNED NOT NULL DEFAULT 0,
+		  last_error text NOT NULL,
+		  PRIMARY KEY (hostname, port),
+		  KEY last_attempted_timestamp_idx (last_attempted_timestamp)
+		) ENGINE=InnoDB DEFAULT CHARSET=ascii
+	`,
+	`
+		CREATE TABLE IF NOT EXISTS database_instance_pseudo_gtid_index (
+		  hostname varchar(128) CHARACTER SET ascii NOT NULL,
+		  port smallint(5) unsigned NOT NULL,
+		  binary_log_file varchar(128) NOT NULL,
+		  binary_log_pos bigint(20) unsigned NOT NULL,
+		  entry_text varchar(512) CHARACTER SET utf8 NOT NULL,
+		  recorded_timestamp timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		  PRIMARY KEY (hostname, port, binary_log_file, binary_log_pos),
+		  KEY recorded_timestamp_idx (recorded_timestamp)
+		) ENGINE=InnoDB DEFAULT CHARSET=ascii
+	`,
+	`
+		CREATE TABLE IF NOT EXISTS replication_remediation (
+		  remediation_id bigint(20) unsigned NOT NULL AUTO_INCREMENT,
+		  hostname varchar(128) CHARACTER SET ascii NOT NULL,
+		  port smallint(5) unsigned NOT NULL,
+		  cluster_name varchar(128) CHARACTER SET ascii NOT NULL,
+		  remediation_timestamp timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		  replication_thread varchar(8) CHARACTER SET ascii NOT NULL,
+		  error_number int unsigned NOT NULL,
+		  error_message text CHARACTER SET utf8 NOT NULL,
+		  action varchar(32) CHARACTER SET ascii NOT NULL,
+		  is_successful tinyint unsigned NOT NULL,
+		  result_message text CHARACTER SET utf8 NOT NULL,
+		  PRIMARY KEY (remediation_id),
+		  KEY hostname_port_timestamp_idx (hostname, port, remediation_timestamp),
+		  KEY remediation_timestamp_idx (remediation_timestamp)
+		) ENGINE=InnoDB DEFAULT CHARSET=ascii
+	`,
+	`
+		CREATE TABLE IF NOT EXISTS topology_failure_detection_vote (
+		  detection_id bigint(20) unsigned NOT NULL,
+		  voter_hostname varchar(128) CHARACTER SET ascii NOT NULL,
+		  voter_token varchar(128) NOT NULL,
+		  vote varchar(16) CHARACTER SET ascii NOT NULL,
+		  vote_message text CHARACTER SET utf8 NOT NULL,
+		  vote_timestamp timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		  PRIMARY KEY (detection_id, voter_hostname, voter_token),
+		  KEY vote_timestamp_idx (vote_timestamp)
+		) ENGINE=InnoDB DEFAULT CHARSET=ascii
+	`,
+	`
+		CREATE TABLE IF NOT EXISTS cluster_operation_lock (
+		  cluster_name varchar(128) CHARACTER SET ascii NOT NULL,
+		  owner varchar(128) CHARACTER SET utf8 NOT NULL,
+		  reason text CHARACTER SET utf8 NOT NULL,
+		  processing_node_hostname varchar(128) CHARACTER SET ascii NOT NULL,
+		  processing_node_token varchar(128) NOT NULL,
+		  acquired_timestamp timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		  heartbeat_timestamp timestamp NOT NULL,
+		  PRIMARY KEY (cluster_name),
+		  KEY heartbeat_timestamp_idx (heartbeat_timestamp)
+		) ENGINE=InnoDB DEFAULT CHARSET=ascii
+	`,
+	`
+		CREATE TABLE IF NOT EXISTS api_key (
+		  api_key_id bigint unsigned NOT NULL AUTO_INCREMENT,
+		  key_name varchar(128) CHARACTER SET utf8 NOT NULL,
+		  key_prefix varchar(16) NOT NULL,
+		  key_hash varchar(128) NOT NULL,
+		  is_read_only tinyint unsigned NOT NULL DEFAULT '0',
+		  cluster_patterns text CHARACTER SET utf8 NOT NULL,
+		  operations text NOT NULL,
+		  created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		  created_by varchar(128) CHARACTER SET utf8 NOT NULL,
+		  expires_at timestamp NOT NULL DEFAULT '1971-01-01 00:00:00',
+		  last_used_at timestamp NOT NULL DEFAULT '1971-01-01 00:00:00',
+		  is_revoked tinyint unsigned NOT NULL DEFAULT '0',
+		  revoked_at timestamp NOT NULL DEFAULT '1971-01-01 00:00:00',
+		  PRIMARY KEY (api_key_id),
+		  UNIQUE KEY key_name_idx (key_name),
+		  UNIQUE KEY key_hash_idx (key_hash)
+		) ENGINE=InnoDB DEFAULT CHARSET=ascii
+	`,
+	`
+		CREATE TABLE IF NOT EXISTS rolling_restart (
+		  rolling_restart_id bigint unsigned NOT NULL AUTO_INCREMENT,
+		  cluster_name varchar(128) CHARACTER SET ascii NOT NULL,
+		  owner varchar(128) CHARACTER SET utf8 NOT NULL,
+		  takeover_master tinyint unsigned NOT NULL DEFAULT '0',
+		  status varchar(32) CHARACTER SET ascii NOT NULL,
+		  message text CHARACTER SET utf8 NOT NULL,
+		  processing_node_hostname varchar(128) CHARACTER SET ascii NOT NULL,
+		  start_timestamp timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		  end_timestamp timestamp NULL DEFAULT NULL,
+		  PRIMARY KEY (rolling_restart_id),
+		  KEY cluster_name_idx (cluster_name, rolling_restart_id),
+		  KEY start_timestamp_idx (start_timestamp)
+		) ENGINE=InnoDB DEFAULT CHARSET=ascii
+	`,
+	`
+		CREATE TABLE IF NOT EXISTS rolling_restart_step (
+		  rolling_restart_id bigint unsigned NOT NULL,
+		  step_order int unsigned NOT NULL,
+		  hostname varchar(128) CHARACTER SET ascii NOT NULL,
+		  port smallint(5) unsigned NOT NULL,
+		  is_master tinyint unsigned NOT NULL DEFAULT '0',
+		  status varchar(32) CHARACTER SET ascii NOT NULL,
+		  message text CHARACTER SET utf8 NOT NULL,
+		  start_timestamp timestamp NULL DEFAULT NULL,
+		  end_timestamp timestamp NULL DEFAULT NULL,
+		  PRIMARY KEY (rolling_restart_id, step_order)
+		) ENGINE=InnoDB DEFAULT CHARSET=ascii
+	`,
+	`
+		CREATE TABLE IF NOT EXISTS database_instance_lag_history (
+		  hostname varchar(128) CHARACTER SET ascii NOT NULL,
+		  port smallint(5) unsigned NOT NULL,
+		  resolution_seconds int unsigned NOT NULL,
+		  sample_unix_timestamp int unsigned NOT NULL,
+		  cluster_name varchar(128) CHARACTER SET ascii NOT NULL,
+		  seconds_behind_master bigint(20) unsigned DEFAULT NULL,
+		  slave_lag_seconds bigint(20) unsigned DEFAULT NULL,
+		  PRIMARY KEY (hostname, port, resolution_seconds, sample_unix_timestamp),
+		  KEY cluster_name_idx (cluster_name, sample_unix_timestamp),
+		  KEY sample_unix_timestamp_idx (sample_unix_timestamp)
+		) ENGINE=InnoDB DEFAULT CHARSET=ascii
+	`,
 }
 
 // generateSQLPatches contains DDLs for patching schema to the latest version.
@@ -855,6 +986,134 @@ var generateSQLPatches = []string{
 		ALTER TABLE node_health
 			ADD COLUMN app_version varchar(30) CHARACTER SET ascii NOT NULL DEFAULT ""
 	`,
+	`
+		ALTER TABLE
+			database_instance
+			ADD COLUMN last_sql_errno INT UNSIGNED NOT NULL DEFAULT 0 AFTER last_io_error
+	`,
+	`
+		ALTER TABLE
+			database_instance
+			ADD COLUMN last_io_errno INT UNSIGNED NOT NULL DEFAULT 0 AFTER last_sql_errno
+	`,
+	`
+		ALTER TABLE node_health
+			ADD COLUMN http_advertise varchar(256) CHARACTER SET ascii NOT NULL DEFAULT ""
+	`,
+	`
+		ALTER TABLE
+			async_request
+			ADD COLUMN status varchar(32) CHARACTER SET ascii NOT NULL DEFAULT "" AFTER gtid_hint
+	`,
+	`
+		ALTER TABLE
+			async_request
+			ADD COLUMN progress text CHARACTER SET utf8 NOT NULL AFTER status
+	`,
+	`
+		ALTER TABLE
+			async_request
+			ADD COLUMN result mediumtext CHARACTER SET utf8 NOT NULL AFTER progress
+	`,
+	`
+		ALTER TABLE
+			async_request
+			ADD COLUMN error_message text CHARACTER SET utf8 NOT NULL AFTER result
+	`,
+	`
+		ALTER TABLE
+			async_request
+			ADD KEY status_idx (status, request_id)
+	`,
+	`
+		ALTER TABLE database_instance_topology_history
+			ADD COLUMN read_only TINYINT UNSIGNED NULL
+	`,
+	`
+		ALTER TABLE agent_seed
+			ADD COLUMN seed_method varchar(32) NOT NULL DEFAULT 'lvm'
+	`,
+	`
+		ALTER TABLE agent_seed
+			ADD COLUMN resume_count int unsigned NOT NULL DEFAULT 0
+	`,
+	`
+		ALTER TABLE agent_seed_state
+			ADD COLUMN bytes_copied bigint NOT NULL DEFAULT 0
+	`,
+	`
+		ALTER TABLE agent_seed_state
+			ADD COLUMN bytes_total bigint NOT NULL DEFAULT 0
+	`,
+	`
+		ALTER TABLE agent_seed_state
+			ADD COLUMN bytes_per_second bigint NOT NULL DEFAULT 0
+	`,
+	`
+		ALTER TABLE agent_seed_state
+			ADD COLUMN eta_seconds bigint NOT NULL DEFAULT -1
+	`,
+	`
+		ALTER TABLE agent_seed
+			ADD COLUMN attach_replica tinyint unsigned NOT NULL DEFAULT 0
+	`,
+	`
+		ALTER TABLE agent_seed
+			ADD COLUMN master_hostname varchar(128) NOT NULL DEFAULT ''
+	`,
+	`
+		ALTER TABLE agent_seed
+			ADD COLUMN master_port smallint(5) unsigned NOT NULL DEFAULT 0
+	`,
+	`
+		ALTER TABLE agent_seed
+			ADD COLUMN checksum_tables varchar(1024) NOT NULL DEFAULT ''
+	`,
+	`
+		ALTER TABLE host_agent
+			ADD COLUMN token_rotated_at timestamp NULL DEFAULT NULL
+	`,
+	`
+		ALTER TABLE host_agent
+			ADD COLUMN token_validated_at timestamp NULL DEFAULT NULL
+	`,
+	`
+		ALTER TABLE agent_seed
+			ADD COLUMN target_port smallint(5) unsigned NOT NULL DEFAULT '0'
+	`,
+	`
+		ALTER TABLE agent_seed
+			ADD COLUMN source_port smallint(5) unsigned NOT NULL DEFAULT '0'
+	`,
+	`
+		ALTER TABLE
+			async_request
+			ADD COLUMN worker_hostname varchar(128) CHARACTER SET ascii NOT NULL DEFAULT "" AFTER error_message
+	`,
+	`
+		ALTER TABLE
+			async_request
+			ADD COLUMN worker_token varchar(128) CHARACTER SET ascii NOT NULL DEFAULT "" AFTER worker_hostname
+	`,
+	`
+		ALTER TABLE
+			cluster_operation_lock
+			ADD COLUMN lock_token varchar(128) CHARACTER SET ascii NOT NULL DEFAULT "" AFTER processing_node_token
+	`,
+	`
+		ALTER TABLE database_instance_topology_history
+			MODIFY COLUMN read_only TINYINT UNSIGNED NULL
+	`,
+	`
+		ALTER TABLE
+			topology_recovery
+			ADD COLUMN agent_host_check text CHARACTER SET utf8 NOT NULL
+	`,
+	`
+		ALTER TABLE
+			database_instance_pseudo_gtid_index
+			ADD COLUMN history_id bigint(20) unsigned NOT NULL DEFAULT 0
+	`,
 }
 
 // Track if a TLS has already been configured for topology
@@ -875,6 +1134,12 @@ func OpenTopology(host string, port int) (*sql.DB, error) {
 	return openTopology(host, port, config.Config.MySQLTopologyReadTimeoutSeconds)
 }
 
+// OpenTopologyLongRunning returns a DB instance to access a topology instance, with no read timeout.
+// It is intended for statements expected to run for long, such as CLONE INSTANCE.
+func OpenTopologyLongRunning(host string, port int) (*sql.DB, error) {
+	return openTopology(host, port, 0)
+}
+
 func openTopology(host string, port int, readTimeout int) (*sql.DB, error) {
 	mysql_uri := fmt.Sprintf("%s:%s@tcp(%s:%d)/?timeout=%ds&readTimeout=%ds",
 		config.Config.MySQLTopologyUser,
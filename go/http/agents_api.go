@@ -17,6 +17,7 @@
 package http
 
 import (
+	"crypto/x509"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -36,12 +37,21 @@ type HttpAgentsAPI struct {
 var AgentsAPI HttpAgentsAPI = HttpAgentsAPI{}
 
 // SubmitAgent registeres an agent. It is initiated by an agent to register itself.
-func (this *HttpAgentsAPI) SubmitAgent(params martini.Params, r render.Render) {
+func (this *HttpAgentsAPI) SubmitAgent(params martini.Params, r render.Render, req *http.Request) {
 	port, err := strconv.Atoi(params["port"])
 	if err != nil {
 		r.JSON(200, &APIResponse{Code: ERROR, Message: err.Error()})
 		return
 	}
+	var clientCertificate *x509.Certificate
+	if req.TLS != nil && len(req.TLS.VerifiedChains) > 0 && len(req.TLS.VerifiedChains[0]) > 0 {
+		clientCertificate = req.TLS.VerifiedChains[0][0]
+	}
+	if err := agent.VerifyAgentRegistration(params["host"], port, params["token"], req.URL.Query().Get("signature"), clientCertificate); err != nil {
+		agent.AuditRejectedAgentRegistration(params["host"], port, err)
+		r.JSON(http.StatusUnauthorized, &APIResponse{Code: ERROR, Message: "Unauthorized"})
+		return
+	}
 
 	output, err := agent.SubmitAgent(params["host"], port, params["token"])
 	if err != nil {
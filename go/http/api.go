@@ -19,9 +19,12 @@ package http
 import (
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-martini/martini"
 	"github.com/martini-contrib/auth"
@@ -308,6 +311,62 @@ func (this *HttpAPI) EndDowntime(params martini.Params, r render.Render, req *ht
 	r.JSON(200, &APIResponse{Code: OK, Message: fmt.Sprintf("Downtime ended: %+v", instanceKey)})
 }
 
+// queueAsyncRequestIfRequested queues given command as an async request when the "async" query param is set,
+// and responds with the request id. It returns true when the request was handled as such.
+func (this *HttpAPI) queueAsyncRequestIfRequested(command string, instanceKey *inst.InstanceKey, destinationKey *inst.InstanceKey, r render.Render, req *http.Request, user auth.User) bool {
+	if req.URL.Query().Get("async") != "true" {
+		return false
+	}
+	story := fmt.Sprintf("Requested via API by %s", getUserId(req, user))
+	asyncRequest := logic.NewAsyncRequest(story, command, instanceKey, destinationKey, req.URL.Query().Get("pattern"), inst.GTIDHintNeutral)
+	if err := logic.QueueAsyncRequest(asyncRequest); err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: err.Error()})
+		return true
+	}
+	r.JSON(200, &APIResponse{Code: OK, Message: fmt.Sprintf("Queued %s as async request %d", command, asyncRequest.Id), Details: asyncRequest})
+	return true
+}
+
+// AsyncRequest returns the status, progress and result of an async request
+func (this *HttpAPI) AsyncRequest(params martini.Params, r render.Render, req *http.Request) {
+	requestId, err := strconv.ParseInt(params["id"], 10, 0)
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: err.Error()})
+		return
+	}
+	asyncRequest, err := logic.ReadAsyncRequest(requestId)
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: err.Error()})
+		return
+	}
+
+	r.JSON(200, asyncRequest)
+}
+
+// CancelAsyncRequest cancels a queued async request
+func (this *HttpAPI) CancelAsyncRequest(params martini.Params, r render.Render, req *http.Request, user auth.User) {
+	if !isAuthorizedForAction(req, user) {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: "Unauthorized"})
+		return
+	}
+	requestId, err := strconv.ParseInt(params["id"], 10, 0)
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: err.Error()})
+		return
+	}
+	canceled, err := logic.CancelAsyncRequest(requestId)
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: err.Error()})
+		return
+	}
+	if !canceled {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("Async request %d not found or no longer queued", requestId)})
+		return
+	}
+
+	r.JSON(200, &APIResponse{Code: OK, Message: fmt.Sprintf("Async request %d canceled", requestId)})
+}
+
 // MoveUp attempts to move an instance up the topology
 func (this *HttpAPI) MoveUp(params martini.Params, r render.Render, req *http.Request, user auth.User) {
 	if !isAuthorizedForAction(req, user) {
@@ -341,6 +400,9 @@ func (this *HttpAPI) MoveUpSlaves(params martini.Params, r render.Render, req *h
 		return
 	}
 
+	if this.queueAsyncRequestIfRequested("move-up-slaves", &instanceKey, nil, r, req, user) {
+		return
+	}
 	slaves, newMaster, err, errs := inst.MoveUpSlaves(&instanceKey, req.URL.Query().Get("pattern"))
 	if err != nil {
 		r.JSON(200, &APIResponse{Code: ERROR, Message: err.Error()})
@@ -590,6 +652,9 @@ func (this *HttpAPI) MoveSlavesGTID(params martini.Params, r render.Render, req
 		return
 	}
 
+	if this.queueAsyncRequestIfRequested("move-slaves-gtid", &instanceKey, &belowKey, r, req, user) {
+		return
+	}
 	movedSlaves, _, err, errs := inst.MoveSlavesGTID(&instanceKey, &belowKey, req.URL.Query().Get("pattern"))
 	if err != nil {
 		r.JSON(200, &APIResponse{Code: ERROR, Message: err.Error()})
@@ -659,6 +724,9 @@ func (this *HttpAPI) RelocateBelow(params martini.Params, r render.Render, req *
 		return
 	}
 
+	if this.queueAsyncRequestIfRequested("relocate", &instanceKey, &belowKey, r, req, user) {
+		return
+	}
 	instance, err := inst.RelocateBelow(&instanceKey, &belowKey)
 	if err != nil {
 		r.JSON(200, &APIResponse{Code: ERROR, Message: err.Error()})
@@ -685,6 +753,9 @@ func (this *HttpAPI) RelocateSlaves(params martini.Params, r render.Render, req
 		return
 	}
 
+	if this.queueAsyncRequestIfRequested("relocate-slaves", &instanceKey, &belowKey, r, req, user) {
+		return
+	}
 	slaves, _, err, errs := inst.RelocateSlaves(&instanceKey, &belowKey, req.URL.Query().Get("pattern"))
 	if err != nil {
 		r.JSON(200, &APIResponse{Code: ERROR, Message: err.Error()})
@@ -694,6 +765,73 @@ func (this *HttpAPI) RelocateSlaves(params martini.Params, r render.Render, req
 	r.JSON(200, &APIResponse{Code: OK, Message: fmt.Sprintf("Relocated %d slaves of %+v below %+v; %d errors: %+v", len(slaves), instanceKey, belowKey, len(errs), errs), Details: slaves})
 }
 
+// getDesiredTopology reads the cluster name from params and the desired topology from the request body
+func (this *HttpAPI) getDesiredTopology(params martini.Params, req *http.Request) (clusterName string, desired *inst.DesiredTopologyNode, err error) {
+	clusterName, err = inst.ReadClusterNameByAlias(params["clusterName"])
+	if err != nil {
+		return clusterName, desired, err
+	}
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return clusterName, desired, err
+	}
+	desired, err = inst.ParseDesiredTopology(body)
+	return clusterName, desired, err
+}
+
+// PlanDesiredTopology returns the steps which would refactor a cluster into the desired topology given in request body
+func (this *HttpAPI) PlanDesiredTopology(params martini.Params, r render.Render, req *http.Request) {
+	clusterName, desired, err := this.getDesiredTopology(params, req)
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: err.Error()})
+		return
+	}
+	steps, err := inst.PlanDesiredTopology(clusterName, desired)
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: err.Error()})
+		return
+	}
+
+	r.JSON(200, &APIResponse{Code: OK, Message: fmt.Sprintf("%d steps planned for cluster %s", len(steps), clusterName), Details: steps})
+}
+
+// DiffDesiredTopology compares a cluster's current topology with the desired topology given in request body
+func (this *HttpAPI) DiffDesiredTopology(params martini.Params, r render.Render, req *http.Request) {
+	clusterName, desired, err := this.getDesiredTopology(params, req)
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: err.Error()})
+		return
+	}
+	diff, err := inst.DiffDesiredTopology(clusterName, desired)
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: err.Error()})
+		return
+	}
+
+	r.JSON(200, &APIResponse{Code: OK, Message: fmt.Sprintf("Topology diff for cluster %s", clusterName), Details: diff})
+}
+
+// ReconcileDesiredTopology refactors a cluster towards the desired topology given in request body
+func (this *HttpAPI) ReconcileDesiredTopology(params martini.Params, r render.Render, req *http.Request, user auth.User) {
+	if !isAuthorizedForAction(req, user) {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: "Unauthorized"})
+		return
+	}
+	clusterName, desired, err := this.getDesiredTopology(params, req)
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: err.Error()})
+		return
+	}
+	executedSteps, diff, err := inst.ReconcileDesiredTopology(clusterName, desired)
+	details := map[string]interface{}{"ExecutedSteps": executedSteps, "Diff": diff}
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: err.Error(), Details: details})
+		return
+	}
+
+	r.JSON(200, &APIResponse{Code: OK, Message: fmt.Sprintf("Cluster %s reconciled in %d steps", clusterName, len(executedSteps)), Details: details})
+}
+
 // MoveEquivalent attempts to move an instance below another, baseed on known equivalence master coordinates
 func (this *HttpAPI) MoveEquivalent(params martini.Params, r render.Render, req *http.Request, user auth.User) {
 	if !isAuthorizedForAction(req, user) {
@@ -755,6 +893,26 @@ func (this *HttpAPI) LastPseudoGTID(params martini.Params, r render.Render, req
 	r.JSON(200, &APIResponse{Code: OK, Message: fmt.Sprintf("%+v", *coordinates), Details: text})
 }
 
+// InjectPseudoGTID injects a Pseudo-GTID entry on a writeable master
+func (this *HttpAPI) InjectPseudoGTID(params martini.Params, r render.Render, req *http.Request, user auth.User) {
+	if !isAuthorizedForAction(req, user) {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: "Unauthorized"})
+		return
+	}
+	instanceKey, err := this.getInstanceKey(params["host"], params["port"])
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: err.Error()})
+		return
+	}
+
+	if err := inst.InjectPseudoGTID(&instanceKey); err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: err.Error()})
+		return
+	}
+
+	r.JSON(200, &APIResponse{Code: OK, Message: fmt.Sprintf("Pseudo-GTID injected on %+v", instanceKey), Details: instanceKey})
+}
+
 // MatchBelow attempts to move an instance below another via pseudo GTID matching of binlog entries
 func (this *HttpAPI) MatchBelow(params martini.Params, r render.Render, req *http.Request, user auth.User) {
 	if !isAuthorizedForAction(req, user) {
@@ -819,6 +977,9 @@ func (this *HttpAPI) MultiMatchSlaves(params martini.Params, r render.Render, re
 		return
 	}
 
+	if this.queueAsyncRequestIfRequested("multi-match-slaves", &instanceKey, &belowKey, r, req, user) {
+		return
+	}
 	slaves, newMaster, err, errs := inst.MultiMatchSlaves(&instanceKey, &belowKey, req.URL.Query().Get("pattern"))
 	if err != nil {
 		r.JSON(200, &APIResponse{Code: ERROR, Message: err.Error()})
@@ -840,6 +1001,9 @@ func (this *HttpAPI) MatchUpSlaves(params martini.Params, r render.Render, req *
 		return
 	}
 
+	if this.queueAsyncRequestIfRequested("match-up-slaves", &instanceKey, nil, r, req, user) {
+		return
+	}
 	slaves, newMaster, err, errs := inst.MatchUpSlaves(&instanceKey, req.URL.Query().Get("pattern"))
 	if err != nil {
 		r.JSON(200, &APIResponse{Code: ERROR, Message: err.Error()})
@@ -862,6 +1026,9 @@ func (this *HttpAPI) RegroupSlaves(params martini.Params, r render.Render, req *
 		return
 	}
 
+	if this.queueAsyncRequestIfRequested("regroup-slaves", &instanceKey, nil, r, req, user) {
+		return
+	}
 	lostSlaves, equalSlaves, aheadSlaves, cannotReplicateSlaves, promotedSlave, err := inst.RegroupSlaves(&instanceKey, false, nil, nil)
 	lostSlaves = append(lostSlaves, cannotReplicateSlaves...)
 	if err != nil {
@@ -910,6 +1077,9 @@ func (this *HttpAPI) RegroupSlavesGTID(params martini.Params, r render.Render, r
 		return
 	}
 
+	if this.queueAsyncRequestIfRequested("regroup-slaves-gtid", &instanceKey, nil, r, req, user) {
+		return
+	}
 	lostSlaves, movedSlaves, cannotReplicateSlaves, promotedSlave, err := inst.RegroupSlavesGTID(&instanceKey, false, nil)
 	lostSlaves = append(lostSlaves, cannotReplicateSlaves...)
 
@@ -1725,7 +1895,26 @@ func (this *HttpAPI) AgentSeed(params martini.Params, r render.Render, req *http
 		return
 	}
 
-	output, err := agent.Seed(params["targetHost"], params["sourceHost"])
+	seedOptions := &agent.SeedOptions{
+		Method:         req.URL.Query().Get("method"),
+		AttachReplica:  config.Config.SeedAttachReplica,
+		ChecksumTables: config.Config.SeedChecksumTables,
+	}
+	if attach := req.URL.Query().Get("attach"); attach != "" {
+		seedOptions.AttachReplica = (attach == "true")
+	}
+	if master := req.URL.Query().Get("master"); master != "" {
+		masterKey, err := inst.ParseInstanceKeyLoose(master)
+		if err != nil {
+			r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("%+v", err)})
+			return
+		}
+		seedOptions.MasterKey = *masterKey
+	}
+	if checksum := req.URL.Query().Get("checksum"); checksum != "" {
+		seedOptions.ChecksumTables = strings.Split(checksum, ",")
+	}
+	output, err := agent.Seed(params["targetHost"], params["sourceHost"], seedOptions)
 
 	if err != nil {
 		r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("%+v", err)})
@@ -1735,17 +1924,53 @@ func (this *HttpAPI) AgentSeed(params martini.Params, r render.Render, req *http
 	r.JSON(200, output)
 }
 
-// AgentActiveSeeds lists active seeds and their state
-func (this *HttpAPI) AgentActiveSeeds(params martini.Params, r render.Render, req *http.Request, user auth.User) {
+// CloneInstance provisions an instance with a donor's data via the MySQL CLONE plugin. No agents are required.
+func (this *HttpAPI) CloneInstance(params martini.Params, r render.Render, req *http.Request, user auth.User) {
 	if !isAuthorizedForAction(req, user) {
 		r.JSON(200, &APIResponse{Code: ERROR, Message: "Unauthorized"})
 		return
 	}
-	if !config.Config.ServeAgentsHttp {
-		r.JSON(200, &APIResponse{Code: ERROR, Message: "Agents not served"})
+	instanceKey, err := this.getInstanceKey(params["host"], params["port"])
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: err.Error()})
+		return
+	}
+	donorKey, err := this.getInstanceKey(params["donorHost"], params["donorPort"])
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: err.Error()})
+		return
+	}
+
+	seedOptions := &agent.SeedOptions{
+		ChecksumTables: config.Config.SeedChecksumTables,
+	}
+	if master := req.URL.Query().Get("master"); master != "" {
+		masterKey, err := inst.ParseInstanceKeyLoose(master)
+		if err != nil {
+			r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("%+v", err)})
+			return
+		}
+		seedOptions.MasterKey = *masterKey
+	}
+	if checksum := req.URL.Query().Get("checksum"); checksum != "" {
+		seedOptions.ChecksumTables = strings.Split(checksum, ",")
+	}
+	seedOptions.Force = (req.URL.Query().Get("force") == "true")
+	seedId, err := agent.CloneSeed(&instanceKey, &donorKey, seedOptions)
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("%+v", err)})
 		return
 	}
 
+	r.JSON(200, seedId)
+}
+
+// AgentActiveSeeds lists active seeds and their state
+func (this *HttpAPI) AgentActiveSeeds(params martini.Params, r render.Render, req *http.Request, user auth.User) {
+	if !isAuthorizedForAction(req, user) {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: "Unauthorized"})
+		return
+	}
 	output, err := agent.ReadActiveSeedsForHost(params["host"])
 
 	if err != nil {
@@ -1762,11 +1987,6 @@ func (this *HttpAPI) AgentRecentSeeds(params martini.Params, r render.Render, re
 		r.JSON(200, &APIResponse{Code: ERROR, Message: "Unauthorized"})
 		return
 	}
-	if !config.Config.ServeAgentsHttp {
-		r.JSON(200, &APIResponse{Code: ERROR, Message: "Agents not served"})
-		return
-	}
-
 	output, err := agent.ReadRecentCompletedSeedsForHost(params["host"])
 
 	if err != nil {
@@ -1783,11 +2003,6 @@ func (this *HttpAPI) AgentSeedDetails(params martini.Params, r render.Render, re
 		r.JSON(200, &APIResponse{Code: ERROR, Message: "Unauthorized"})
 		return
 	}
-	if !config.Config.ServeAgentsHttp {
-		r.JSON(200, &APIResponse{Code: ERROR, Message: "Agents not served"})
-		return
-	}
-
 	seedId, err := strconv.ParseInt(params["seedId"], 10, 0)
 	output, err := agent.AgentSeedDetails(seedId)
 
@@ -1805,11 +2020,6 @@ func (this *HttpAPI) AgentSeedStates(params martini.Params, r render.Render, req
 		r.JSON(200, &APIResponse{Code: ERROR, Message: "Unauthorized"})
 		return
 	}
-	if !config.Config.ServeAgentsHttp {
-		r.JSON(200, &APIResponse{Code: ERROR, Message: "Agents not served"})
-		return
-	}
-
 	seedId, err := strconv.ParseInt(params["seedId"], 10, 0)
 	output, err := agent.ReadSeedStates(seedId)
 
@@ -1859,11 +2069,6 @@ func (this *HttpAPI) Seeds(params martini.Params, r render.Render, req *http.Req
 		r.JSON(200, &APIResponse{Code: ERROR, Message: "Unauthorized"})
 		return
 	}
-	if !config.Config.ServeAgentsHttp {
-		r.JSON(200, &APIResponse{Code: ERROR, Message: "Agents not served"})
-		return
-	}
-
 	output, err := agent.ReadRecentSeeds()
 
 	if err != nil {
@@ -1896,6 +2101,27 @@ func (this *HttpAPI) AbortSeed(params martini.Params, r render.Render, req *http
 	r.JSON(200, err == nil)
 }
 
+// ResumeSeed restarts a failed seed, picking up data transfer where agents support it
+func (this *HttpAPI) ResumeSeed(params martini.Params, r render.Render, req *http.Request, user auth.User) {
+	if !isAuthorizedForAction(req, user) {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: "Unauthorized"})
+		return
+	}
+	seedId, err := strconv.ParseInt(params["seedId"], 10, 0)
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("%+v", err)})
+		return
+	}
+	err = agent.ResumeSeed(seedId)
+
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("%+v", err)})
+		return
+	}
+
+	r.JSON(200, &APIResponse{Code: OK, Message: fmt.Sprintf("Seed %d resumed", seedId), Details: seedId})
+}
+
 // Headers is a self-test call which returns HTTP headers
 func (this *HttpAPI) Headers(params martini.Params, r render.Render, req *http.Request) {
 	r.JSON(200, req.Header)
@@ -1983,6 +2209,61 @@ func (this *HttpAPI) ReloadConfiguration(params martini.Params, r render.Render,
 
 }
 
+// APIKeys lists API keys. Secrets are never returned.
+func (this *HttpAPI) APIKeys(params martini.Params, r render.Render, req *http.Request, user auth.User) {
+	if !isAuthorizedForAction(req, user) {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: "Unauthorized"})
+		return
+	}
+	apiKeys, err := process.ReadAPIKeys()
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("%+v", err)})
+		return
+	}
+
+	r.JSON(200, apiKeys)
+}
+
+// CreateAPIKey generates a named API key, with optional scopes and duration query params.
+// The key's secret is only ever returned by this call.
+func (this *HttpAPI) CreateAPIKey(params martini.Params, r render.Render, req *http.Request, user auth.User) {
+	if !isAuthorizedForAction(req, user) {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: "Unauthorized"})
+		return
+	}
+	var expirySeconds int = 0
+	if duration := req.URL.Query().Get("duration"); duration != "" {
+		var err error
+		if expirySeconds, err = util.SimpleTimeToSeconds(duration); err != nil || expirySeconds < 0 {
+			r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("Invalid duration: %s", duration)})
+			return
+		}
+	}
+	apiKey, secret, err := process.CreateAPIKey(params["name"], req.URL.Query().Get("scopes"), uint(expirySeconds), getUserId(req, user), getRequestAPIKey(req))
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("%+v", err)})
+		return
+	}
+	inst.AuditOperation("create-api-key", nil, fmt.Sprintf("API key %s created by %s; scopes: %s", apiKey.Name, apiKey.CreatedBy, apiKey.Scopes()))
+
+	r.JSON(200, &APIResponse{Code: OK, Message: fmt.Sprintf("API key created: %s", apiKey.Name), Details: secret})
+}
+
+// RevokeAPIKey disables an API key
+func (this *HttpAPI) RevokeAPIKey(params martini.Params, r render.Render, req *http.Request, user auth.User) {
+	if !isAuthorizedForAction(req, user) {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: "Unauthorized"})
+		return
+	}
+	if err := process.RevokeAPIKey(params["name"]); err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("%+v", err)})
+		return
+	}
+	inst.AuditOperation("revoke-api-key", nil, fmt.Sprintf("API key %s revoked by %s", params["name"], getUserId(req, user)))
+
+	r.JSON(200, &APIResponse{Code: OK, Message: fmt.Sprintf("API key revoked: %s", params["name"])})
+}
+
 // ReplicationAnalysis retuens list of issues
 func (this *HttpAPI) ReplicationAnalysis(params martini.Params, r render.Render, req *http.Request) {
 	analysis, err := inst.GetReplicationAnalysis(params["clusterName"], true, false)
@@ -2126,6 +2407,54 @@ func (this *HttpAPI) AuditRecovery(params martini.Params, r render.Render, req *
 	r.JSON(200, audits)
 }
 
+// ProbeInstanceLiveness has this node independently probe an instance, on behalf of the elected node
+// seeking failure confirmation. Only healthy orchestrator nodes may request a probe.
+func (this *HttpAPI) ProbeInstanceLiveness(params martini.Params, r render.Render, req *http.Request) {
+	if isHealthyNode, _ := process.TokenBelongsToHealthyHttpService(req.Header.Get(logic.FailureConfirmationNodeTokenHeader)); !isHealthyNode {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: "Unauthorized: not a healthy orchestrator node"})
+		return
+	}
+	instanceKey, err := this.getInstanceKey(params["host"], params["port"])
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: err.Error()})
+		return
+	}
+
+	vote := logic.ProbeInstanceLiveness(&instanceKey)
+	r.JSON(200, &APIResponse{Code: OK, Message: fmt.Sprintf("%+v: %s", instanceKey, vote.Vote), Details: vote})
+}
+
+// FailureDetectionVotes lists the votes cast by orchestrator nodes on a failure detection
+func (this *HttpAPI) FailureDetectionVotes(params martini.Params, r render.Render, req *http.Request) {
+	detectionId, err := strconv.ParseInt(params["detectionId"], 10, 0)
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: err.Error()})
+		return
+	}
+	votes, err := logic.ReadFailureConfirmationVotes(detectionId)
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("%+v", err)})
+		return
+	}
+
+	r.JSON(200, votes)
+}
+
+// AuditReplicationRemediation provides list of replication-remediation entries
+func (this *HttpAPI) AuditReplicationRemediation(params martini.Params, r render.Render, req *http.Request) {
+	page, derr := strconv.Atoi(params["page"])
+	if derr != nil || page < 0 {
+		page = 0
+	}
+	remediations, err := logic.ReadReplicationRemediations(params["clusterName"], page)
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("%+v", err)})
+		return
+	}
+
+	r.JSON(200, remediations)
+}
+
 // ActiveClusterRecovery returns recoveries in-progress for a given cluster
 func (this *HttpAPI) ActiveClusterRecovery(params martini.Params, r render.Render, req *http.Request) {
 	recoveries, err := logic.ReadActiveClusterRecovery(params["clusterName"])
@@ -2264,6 +2593,396 @@ func (this *HttpAPI) AcknowledgeRecovery(params martini.Params, r render.Render,
 	r.JSON(200, countAcnowledgedRecoveries)
 }
 
+// Topology returns the topology of a cluster as ascii-graph (default), Graphviz DOT or nested JSON tree, by "format" param
+func (this *HttpAPI) Topology(params martini.Params, r render.Render, req *http.Request) {
+	clusterName, err := inst.ReadClusterNameByAlias(params["clusterName"])
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("%+v", err)})
+		return
+	}
+	format := req.URL.Query().Get("format")
+	switch format {
+	case inst.TopologyFormatJSON:
+		trees, err := inst.JSONTopology(clusterName, "")
+		if err != nil {
+			r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("%+v", err)})
+			return
+		}
+		r.JSON(200, trees)
+	default:
+		output, err := inst.FormattedTopology(clusterName, "", format)
+		if err != nil {
+			r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("%+v", err)})
+			return
+		}
+		r.Text(200, output)
+	}
+}
+
+// TopologySnapshots lists available topology snapshots of a cluster
+func (this *HttpAPI) TopologySnapshots(params martini.Params, r render.Render, req *http.Request) {
+	clusterName, err := inst.ReadClusterNameByAlias(params["clusterName"])
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("%+v", err)})
+		return
+	}
+	snapshots, err := inst.ReadTopologySnapshots(clusterName)
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("%+v", err)})
+		return
+	}
+
+	r.JSON(200, snapshots)
+}
+
+// TopologyAt returns a cluster's topology as recorded in the latest snapshot at or before given time.
+// With format=ascii the topology is returned as ascii-graph.
+func (this *HttpAPI) TopologyAt(params martini.Params, r render.Render, req *http.Request) {
+	clusterName, err := inst.ReadClusterNameByAlias(params["clusterName"])
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("%+v", err)})
+		return
+	}
+	topology, err := inst.ReadHistoryTopology(clusterName, params["at"])
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("%+v", err)})
+		return
+	}
+	message := fmt.Sprintf("Topology of %s as of %s", clusterName, topology.SnapshotTime)
+	if req.URL.Query().Get("format") == "ascii" {
+		r.JSON(200, &APIResponse{Code: OK, Message: message, Details: topology.ASCII()})
+		return
+	}
+
+	r.JSON(200, &APIResponse{Code: OK, Message: message, Details: topology})
+}
+
+// TopologyHistoryDiff compares a cluster's topology at two points in time; the later defaults to now
+func (this *HttpAPI) TopologyHistoryDiff(params martini.Params, r render.Render, req *http.Request) {
+	clusterName, err := inst.ReadClusterNameByAlias(params["clusterName"])
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("%+v", err)})
+		return
+	}
+	diff, from, to, err := inst.DiffHistoryTopology(clusterName, params["since"], params["until"])
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("%+v", err)})
+		return
+	}
+
+	r.JSON(200, &APIResponse{Code: OK, Message: fmt.Sprintf("Topology changes of %s between %s and %s", clusterName, from.SnapshotTime, to.SnapshotTime), Details: diff})
+}
+
+// getLagHistorySince parses the optional "since" parameter of lag history requests; it defaults to a day ago
+func (this *HttpAPI) getLagHistorySince(since string) (int64, error) {
+	if since == "" {
+		return time.Now().Add(-24 * time.Hour).Unix(), nil
+	}
+	return inst.ParseTopologySnapshotTime(since)
+}
+
+// InstanceLagHistory returns the recorded replication lag samples of an instance, oldest first
+func (this *HttpAPI) InstanceLagHistory(params martini.Params, r render.Render, req *http.Request) {
+	instanceKey, err := this.getInstanceKey(params["host"], params["port"])
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: err.Error()})
+		return
+	}
+	since, err := this.getLagHistorySince(params["since"])
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("%+v", err)})
+		return
+	}
+	history, err := inst.ReadInstanceLagHistory(&instanceKey, since)
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("%+v", err)})
+		return
+	}
+
+	r.JSON(200, history)
+}
+
+// ClusterLagHistory returns the recorded replication lag samples of a cluster's instances, one series per instance
+func (this *HttpAPI) ClusterLagHistory(params martini.Params, r render.Render, req *http.Request) {
+	clusterName, err := inst.ReadClusterNameByAlias(params["clusterName"])
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("%+v", err)})
+		return
+	}
+	since, err := this.getLagHistorySince(params["since"])
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("%+v", err)})
+		return
+	}
+	histories, err := inst.ReadClusterLagHistory(clusterName, since)
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("%+v", err)})
+		return
+	}
+
+	r.JSON(200, histories)
+}
+
+// ClusterHeuristicLag returns the heuristic lag of a cluster. When a percentile is given, it is computed
+// over the lag history (by default, of the last day) rather than current lag.
+func (this *HttpAPI) ClusterHeuristicLag(params martini.Params, r render.Render, req *http.Request) {
+	clusterName, err := inst.ReadClusterNameByAlias(params["clusterName"])
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("%+v", err)})
+		return
+	}
+	if params["percentile"] == "" {
+		lag, err := inst.GetClusterHeuristicLag(clusterName)
+		if err != nil {
+			r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("%+v", err)})
+			return
+		}
+		r.JSON(200, &APIResponse{Code: OK, Message: fmt.Sprintf("Heuristic lag of %s: %d", clusterName, lag), Details: lag})
+		return
+	}
+	percentile, err := strconv.ParseFloat(params["percentile"], 64)
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("Invalid percentile: %s", params["percentile"])})
+		return
+	}
+	since, err := this.getLagHistorySince(params["since"])
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("%+v", err)})
+		return
+	}
+	lag, err := inst.GetClusterHeuristicLagPercentile(clusterName, percentile, since)
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("%+v", err)})
+		return
+	}
+
+	r.JSON(200, &APIResponse{Code: OK, Message: fmt.Sprintf("Heuristic lag p%s of %s: %d", params["percentile"], clusterName, lag), Details: lag})
+}
+
+// InstanceBinlogGrowth reports an instance's binlog write rate, its trend and projected time until the datadir is full
+func (this *HttpAPI) InstanceBinlogGrowth(params martini.Params, r render.Render, req *http.Request) {
+	instanceKey, err := this.getInstanceKey(params["host"], params["port"])
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: err.Error()})
+		return
+	}
+	growth, err := logic.GetInstanceBinlogGrowth(&instanceKey)
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("%+v", err)})
+		return
+	}
+
+	r.JSON(200, growth)
+}
+
+// ClusterBinlogGrowth reports binlog write rates, trends and projected time until the datadir is full
+// for all instances of a cluster
+func (this *HttpAPI) ClusterBinlogGrowth(params martini.Params, r render.Render, req *http.Request) {
+	clusterName, err := inst.ReadClusterNameByAlias(params["clusterName"])
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("%+v", err)})
+		return
+	}
+	clusterGrowth, err := logic.GetClusterBinlogGrowth(clusterName)
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("%+v", err)})
+		return
+	}
+
+	r.JSON(200, clusterGrowth)
+}
+
+// BinlogGrowthSpikes lists clusters whose recent binlog write rate spikes relative to their baseline
+func (this *HttpAPI) BinlogGrowthSpikes(params martini.Params, r render.Render, req *http.Request) {
+	clusterGrowths, err := inst.ReadSpikingBinlogGrowthClusters()
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("%+v", err)})
+		return
+	}
+
+	r.JSON(200, clusterGrowths)
+}
+
+// BinlogPurgePlan shows which binary logs of a cluster's masters and intermediate masters can be safely purged
+func (this *HttpAPI) BinlogPurgePlan(params martini.Params, r render.Render, req *http.Request) {
+	clusterName, err := inst.ReadClusterNameByAlias(params["clusterName"])
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("%+v", err)})
+		return
+	}
+	plans, err := inst.PlanClusterBinlogPurge(clusterName)
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("%+v", err)})
+		return
+	}
+
+	r.JSON(200, plans)
+}
+
+// PurgeClusterBinaryLogs purges binary logs no longer needed by replicas on a cluster's masters and intermediate masters
+func (this *HttpAPI) PurgeClusterBinaryLogs(params martini.Params, r render.Render, req *http.Request, user auth.User) {
+	if !isAuthorizedForAction(req, user) {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: "Unauthorized"})
+		return
+	}
+	clusterName, err := inst.ReadClusterNameByAlias(params["clusterName"])
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("%+v", err)})
+		return
+	}
+	plans, err := logic.PurgeClusterBinaryLogs(clusterName)
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("%+v", err)})
+		return
+	}
+
+	r.JSON(200, &APIResponse{Code: OK, Message: fmt.Sprintf("Purged binary logs on %s", clusterName), Details: plans})
+}
+
+// ClusterLocks lists currently held cluster operation locks
+func (this *HttpAPI) ClusterLocks(params martini.Params, r render.Render, req *http.Request) {
+	locks, err := inst.ReadClusterLocks()
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("%+v", err)})
+		return
+	}
+
+	r.JSON(200, locks)
+}
+
+// ClusterLock shows the operation lock held on a cluster, if any
+func (this *HttpAPI) ClusterLock(params martini.Params, r render.Render, req *http.Request) {
+	clusterName, err := inst.ReadClusterNameByAlias(params["clusterName"])
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("%+v", err)})
+		return
+	}
+	lock, err := inst.ReadClusterLock(clusterName)
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("%+v", err)})
+		return
+	}
+	if lock == nil {
+		r.JSON(200, &APIResponse{Code: OK, Message: fmt.Sprintf("Cluster %s is not locked", clusterName)})
+		return
+	}
+
+	r.JSON(200, &APIResponse{Code: OK, Message: fmt.Sprintf("Cluster %s is locked by %s", clusterName, lock.Owner), Details: lock})
+}
+
+// ForceReleaseClusterLock removes the operation lock on a cluster, whoever holds it
+func (this *HttpAPI) ForceReleaseClusterLock(params martini.Params, r render.Render, req *http.Request, user auth.User) {
+	if !isAuthorizedForAction(req, user) {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: "Unauthorized"})
+		return
+	}
+	clusterName, err := inst.ReadClusterNameByAlias(params["clusterName"])
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("%+v", err)})
+		return
+	}
+	if err := inst.ForceReleaseClusterLock(clusterName); err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("%+v", err)})
+		return
+	}
+
+	r.JSON(200, &APIResponse{Code: OK, Message: fmt.Sprintf("Released lock on cluster %s", clusterName)})
+}
+
+// beginRollingRestart begins a rolling restart of a cluster, optionally ending with a master takeover
+func (this *HttpAPI) beginRollingRestart(params martini.Params, r render.Render, req *http.Request, user auth.User, takeoverMaster bool) {
+	if !isAuthorizedForAction(req, user) {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: "Unauthorized"})
+		return
+	}
+	clusterName, err := inst.ReadClusterNameByAlias(params["clusterName"])
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("%+v", err)})
+		return
+	}
+	owner := getUserId(req, user)
+	if owner == "" {
+		owner = config.Config.MaintenanceOwner
+	}
+	rollingRestart, err := logic.BeginRollingRestart(clusterName, owner, takeoverMaster)
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("%+v", err), Details: rollingRestart})
+		return
+	}
+
+	r.JSON(200, &APIResponse{Code: OK, Message: fmt.Sprintf("Began rolling restart %d of %s", rollingRestart.Id, clusterName), Details: rollingRestart})
+}
+
+// RollingRestart begins a rolling restart of a cluster's replicas
+func (this *HttpAPI) RollingRestart(params martini.Params, r render.Render, req *http.Request, user auth.User) {
+	this.beginRollingRestart(params, r, req, user, false)
+}
+
+// RollingRestartWithTakeover begins a rolling restart of a cluster's replicas, followed by a graceful master
+// takeover and restart of the former master
+func (this *HttpAPI) RollingRestartWithTakeover(params martini.Params, r render.Render, req *http.Request, user auth.User) {
+	this.beginRollingRestart(params, r, req, user, true)
+}
+
+// ResumeRollingRestart resumes a failed or interrupted rolling restart
+func (this *HttpAPI) ResumeRollingRestart(params martini.Params, r render.Render, req *http.Request, user auth.User) {
+	if !isAuthorizedForAction(req, user) {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: "Unauthorized"})
+		return
+	}
+	rollingRestartId, err := strconv.ParseInt(params["id"], 10, 0)
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: err.Error()})
+		return
+	}
+	rollingRestart, err := logic.ResumeRollingRestart(rollingRestartId)
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("%+v", err)})
+		return
+	}
+
+	r.JSON(200, &APIResponse{Code: OK, Message: fmt.Sprintf("Resumed rolling restart %d", rollingRestartId), Details: rollingRestart})
+}
+
+// RollingRestartDetails returns a rolling restart along with the status of each of its instances
+func (this *HttpAPI) RollingRestartDetails(params martini.Params, r render.Render, req *http.Request) {
+	rollingRestartId, err := strconv.ParseInt(params["id"], 10, 0)
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: err.Error()})
+		return
+	}
+	rollingRestart, err := logic.ReadRollingRestart(rollingRestartId)
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("%+v", err)})
+		return
+	}
+
+	r.JSON(200, rollingRestart)
+}
+
+// RollingRestarts lists recent rolling restarts, optionally filtered by cluster name
+func (this *HttpAPI) RollingRestarts(params martini.Params, r render.Render, req *http.Request) {
+	page, derr := strconv.Atoi(params["page"])
+	if derr != nil || page < 0 {
+		page = 0
+	}
+	clusterName := ""
+	if params["clusterName"] != "" {
+		var err error
+		if clusterName, err = inst.ReadClusterNameByAlias(params["clusterName"]); err != nil {
+			r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("%+v", err)})
+			return
+		}
+	}
+	rollingRestarts, err := logic.ReadRecentRollingRestarts(clusterName, page)
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("%+v", err)})
+		return
+	}
+
+	r.JSON(200, rollingRestarts)
+}
+
 // BlockedRecoveries reads list of currently blocked recoveries, optionally filtered by cluster name
 func (this *HttpAPI) BlockedRecoveries(params martini.Params, r render.Render, req *http.Request) {
 	blockedRecoveries, err := logic.ReadBlockedRecoveries(params["clusterName"])
@@ -2277,175 +2996,236 @@ func (this *HttpAPI) BlockedRecoveries(params martini.Params, r render.Render, r
 }
 
 // RegisterRequests makes for the de-facto list of known API calls
+// Each route declares the permission it requires via requirePermission(), enforced when AuthorizationRoles are configured.
 func (this *HttpAPI) RegisterRequests(m *martini.ClassicMartini) {
 	// Smart relocation:
-	m.Get(this.URLPrefix+"/api/relocate/:host/:port/:belowHost/:belowPort", this.RelocateBelow)
-	m.Get(this.URLPrefix+"/api/relocate-below/:host/:port/:belowHost/:belowPort", this.RelocateBelow)
-	m.Get(this.URLPrefix+"/api/relocate-slaves/:host/:port/:belowHost/:belowPort", this.RelocateSlaves)
-	m.Get(this.URLPrefix+"/api/regroup-slaves/:host/:port", this.RegroupSlaves)
+	m.Get(this.URLPrefix+"/api/relocate/:host/:port/:belowHost/:belowPort", this.requirePermission(PermissionOperate), this.RelocateBelow)
+	m.Get(this.URLPrefix+"/api/relocate-below/:host/:port/:belowHost/:belowPort", this.requirePermission(PermissionOperate), this.RelocateBelow)
+	m.Get(this.URLPrefix+"/api/relocate-slaves/:host/:port/:belowHost/:belowPort", this.requirePermission(PermissionOperate), this.RelocateSlaves)
+	m.Get(this.URLPrefix+"/api/regroup-slaves/:host/:port", this.requirePermission(PermissionOperate), this.RegroupSlaves)
+
+	m.Get(this.URLPrefix+"/api/topology/:clusterName", this.requirePermission(PermissionRead), this.Topology)
+
+	// Topology history:
+	m.Get(this.URLPrefix+"/api/topology-snapshots/:clusterName", this.requirePermission(PermissionRead), this.TopologySnapshots)
+	m.Get(this.URLPrefix+"/api/topology-at/:clusterName/:at", this.requirePermission(PermissionRead), this.TopologyAt)
+	m.Get(this.URLPrefix+"/api/topology-history-diff/:clusterName/:since", this.requirePermission(PermissionRead), this.TopologyHistoryDiff)
+	m.Get(this.URLPrefix+"/api/topology-history-diff/:clusterName/:since/:until", this.requirePermission(PermissionRead), this.TopologyHistoryDiff)
+
+	// Replication lag history:
+	m.Get(this.URLPrefix+"/api/instance-lag-history/:host/:port", this.requirePermission(PermissionRead), this.InstanceLagHistory)
+	m.Get(this.URLPrefix+"/api/instance-lag-history/:host/:port/:since", this.requirePermission(PermissionRead), this.InstanceLagHistory)
+	m.Get(this.URLPrefix+"/api/cluster-lag-history/:clusterName", this.requirePermission(PermissionRead), this.ClusterLagHistory)
+	m.Get(this.URLPrefix+"/api/cluster-lag-history/:clusterName/:since", this.requirePermission(PermissionRead), this.ClusterLagHistory)
+	m.Get(this.URLPrefix+"/api/cluster-heuristic-lag/:clusterName", this.requirePermission(PermissionRead), this.ClusterHeuristicLag)
+	m.Get(this.URLPrefix+"/api/cluster-heuristic-lag/:clusterName/:percentile", this.requirePermission(PermissionRead), this.ClusterHeuristicLag)
+	m.Get(this.URLPrefix+"/api/cluster-heuristic-lag/:clusterName/:percentile/:since", this.requirePermission(PermissionRead), this.ClusterHeuristicLag)
+
+	// Binlog growth:
+	m.Get(this.URLPrefix+"/api/binlog-growth/:host/:port", this.requirePermission(PermissionRead), this.InstanceBinlogGrowth)
+	m.Get(this.URLPrefix+"/api/cluster-binlog-growth/:clusterName", this.requirePermission(PermissionRead), this.ClusterBinlogGrowth)
+	m.Get(this.URLPrefix+"/api/binlog-growth-spikes", this.requirePermission(PermissionRead), this.BinlogGrowthSpikes)
+	m.Get(this.URLPrefix+"/api/binlog-purge-plan/:clusterName", this.requirePermission(PermissionRead), this.BinlogPurgePlan)
+	m.Get(this.URLPrefix+"/api/purge-cluster-binary-logs/:clusterName", this.requirePermission(PermissionOperate), this.PurgeClusterBinaryLogs)
+
+	// Cluster operation locks:
+	m.Get(this.URLPrefix+"/api/cluster-locks", this.requirePermission(PermissionRead), this.ClusterLocks)
+	m.Get(this.URLPrefix+"/api/cluster-lock/:clusterName", this.requirePermission(PermissionRead), this.ClusterLock)
+	m.Get(this.URLPrefix+"/api/force-release-cluster-lock/:clusterName", this.requirePermission(PermissionAdmin), this.ForceReleaseClusterLock)
+
+	// Rolling restarts:
+	m.Get(this.URLPrefix+"/api/rolling-restart/:clusterName", this.requirePermission(PermissionOperate), this.RollingRestart)
+	m.Get(this.URLPrefix+"/api/rolling-restart-takeover/:clusterName", this.requirePermission(PermissionRecover), this.RollingRestartWithTakeover)
+	m.Get(this.URLPrefix+"/api/resume-rolling-restart/:id", this.requireRollingRestartPermission(), this.ResumeRollingRestart)
+	m.Get(this.URLPrefix+"/api/rolling-restart-details/:id", this.requirePermission(PermissionRead), this.RollingRestartDetails)
+	m.Get(this.URLPrefix+"/api/rolling-restarts", this.requirePermission(PermissionRead), this.RollingRestarts)
+	m.Get(this.URLPrefix+"/api/rolling-restarts/:page", this.requirePermission(PermissionRead), this.RollingRestarts)
+	m.Get(this.URLPrefix+"/api/rolling-restarts/cluster/:clusterName", this.requirePermission(PermissionRead), this.RollingRestarts)
+	m.Get(this.URLPrefix+"/api/rolling-restarts/cluster/:clusterName/:page", this.requirePermission(PermissionRead), this.RollingRestarts)
+
+	// Async requests:
+	m.Get(this.URLPrefix+"/api/async-request/:id", this.requirePermission(PermissionRead), this.AsyncRequest)
+	m.Get(this.URLPrefix+"/api/cancel-async-request/:id", this.requirePermission(PermissionOperate), this.CancelAsyncRequest)
+
+	// Declarative topology:
+	m.Post(this.URLPrefix+"/api/desired-topology/plan/:clusterName", this.requirePermission(PermissionRead), this.PlanDesiredTopology)
+	m.Post(this.URLPrefix+"/api/desired-topology/diff/:clusterName", this.requirePermission(PermissionRead), this.DiffDesiredTopology)
+	m.Post(this.URLPrefix+"/api/reconcile-topology/:clusterName", this.requirePermission(PermissionOperate), this.ReconcileDesiredTopology)
 
 	// Classic file:pos relocation:
-	m.Get(this.URLPrefix+"/api/move-up/:host/:port", this.MoveUp)
-	m.Get(this.URLPrefix+"/api/move-up-slaves/:host/:port", this.MoveUpSlaves)
-	m.Get(this.URLPrefix+"/api/move-below/:host/:port/:siblingHost/:siblingPort", this.MoveBelow)
-	m.Get(this.URLPrefix+"/api/move-equivalent/:host/:port/:belowHost/:belowPort", this.MoveEquivalent)
-	m.Get(this.URLPrefix+"/api/repoint-slaves/:host/:port", this.RepointSlaves)
-	m.Get(this.URLPrefix+"/api/make-co-master/:host/:port", this.MakeCoMaster)
-	m.Get(this.URLPrefix+"/api/enslave-siblings/:host/:port", this.EnslaveSiblings)
-	m.Get(this.URLPrefix+"/api/enslave-master/:host/:port", this.EnslaveMaster)
-	m.Get(this.URLPrefix+"/api/master-equivalent/:host/:port/:logFile/:logPos", this.MasterEquivalent)
+	m.Get(this.URLPrefix+"/api/move-up/:host/:port", this.requirePermission(PermissionOperate), this.MoveUp)
+	m.Get(this.URLPrefix+"/api/move-up-slaves/:host/:port", this.requirePermission(PermissionOperate), this.MoveUpSlaves)
+	m.Get(this.URLPrefix+"/api/move-below/:host/:port/:siblingHost/:siblingPort", this.requirePermission(PermissionOperate), this.MoveBelow)
+	m.Get(this.URLPrefix+"/api/move-equivalent/:host/:port/:belowHost/:belowPort", this.requirePermission(PermissionOperate), this.MoveEquivalent)
+	m.Get(this.URLPrefix+"/api/repoint-slaves/:host/:port", this.requirePermission(PermissionOperate), this.RepointSlaves)
+	m.Get(this.URLPrefix+"/api/make-co-master/:host/:port", this.requirePermission(PermissionOperate), this.MakeCoMaster)
+	m.Get(this.URLPrefix+"/api/enslave-siblings/:host/:port", this.requirePermission(PermissionOperate), this.EnslaveSiblings)
+	m.Get(this.URLPrefix+"/api/enslave-master/:host/:port", this.requirePermission(PermissionOperate), this.EnslaveMaster)
+	m.Get(this.URLPrefix+"/api/master-equivalent/:host/:port/:logFile/:logPos", this.requirePermission(PermissionOperate), this.MasterEquivalent)
 
 	// Binlog server relocation:
-	m.Get(this.URLPrefix+"/api/regroup-slaves-bls/:host/:port", this.RegroupSlavesBinlogServers)
+	m.Get(this.URLPrefix+"/api/regroup-slaves-bls/:host/:port", this.requirePermission(PermissionOperate), this.RegroupSlavesBinlogServers)
 
 	// GTID relocation:
-	m.Get(this.URLPrefix+"/api/move-below-gtid/:host/:port/:belowHost/:belowPort", this.MoveBelowGTID)
-	m.Get(this.URLPrefix+"/api/move-slaves-gtid/:host/:port/:belowHost/:belowPort", this.MoveSlavesGTID)
-	m.Get(this.URLPrefix+"/api/regroup-slaves-gtid/:host/:port", this.RegroupSlavesGTID)
+	m.Get(this.URLPrefix+"/api/move-below-gtid/:host/:port/:belowHost/:belowPort", this.requirePermission(PermissionOperate), this.MoveBelowGTID)
+	m.Get(this.URLPrefix+"/api/move-slaves-gtid/:host/:port/:belowHost/:belowPort", this.requirePermission(PermissionOperate), this.MoveSlavesGTID)
+	m.Get(this.URLPrefix+"/api/regroup-slaves-gtid/:host/:port", this.requirePermission(PermissionOperate), this.RegroupSlavesGTID)
 
 	// Pseudo-GTID relocation:
-	m.Get(this.URLPrefix+"/api/match/:host/:port/:belowHost/:belowPort", this.MatchBelow)
-	m.Get(this.URLPrefix+"/api/match-below/:host/:port/:belowHost/:belowPort", this.MatchBelow)
-	m.Get(this.URLPrefix+"/api/match-up/:host/:port", this.MatchUp)
-	m.Get(this.URLPrefix+"/api/match-slaves/:host/:port/:belowHost/:belowPort", this.MultiMatchSlaves)
-	m.Get(this.URLPrefix+"/api/multi-match-slaves/:host/:port/:belowHost/:belowPort", this.MultiMatchSlaves)
-	m.Get(this.URLPrefix+"/api/match-up-slaves/:host/:port", this.MatchUpSlaves)
-	m.Get(this.URLPrefix+"/api/regroup-slaves-pgtid/:host/:port", this.RegroupSlavesPseudoGTID)
+	m.Get(this.URLPrefix+"/api/match/:host/:port/:belowHost/:belowPort", this.requirePermission(PermissionOperate), this.MatchBelow)
+	m.Get(this.URLPrefix+"/api/match-below/:host/:port/:belowHost/:belowPort", this.requirePermission(PermissionOperate), this.MatchBelow)
+	m.Get(this.URLPrefix+"/api/match-up/:host/:port", this.requirePermission(PermissionOperate), this.MatchUp)
+	m.Get(this.URLPrefix+"/api/match-slaves/:host/:port/:belowHost/:belowPort", this.requirePermission(PermissionOperate), this.MultiMatchSlaves)
+	m.Get(this.URLPrefix+"/api/multi-match-slaves/:host/:port/:belowHost/:belowPort", this.requirePermission(PermissionOperate), this.MultiMatchSlaves)
+	m.Get(this.URLPrefix+"/api/match-up-slaves/:host/:port", this.requirePermission(PermissionOperate), this.MatchUpSlaves)
+	m.Get(this.URLPrefix+"/api/regroup-slaves-pgtid/:host/:port", this.requirePermission(PermissionOperate), this.RegroupSlavesPseudoGTID)
 	// Legacy, need to revisit:
-	m.Get(this.URLPrefix+"/api/make-master/:host/:port", this.MakeMaster)
-	m.Get(this.URLPrefix+"/api/make-local-master/:host/:port", this.MakeLocalMaster)
+	m.Get(this.URLPrefix+"/api/make-master/:host/:port", this.requirePermission(PermissionOperate), this.MakeMaster)
+	m.Get(this.URLPrefix+"/api/make-local-master/:host/:port", this.requirePermission(PermissionOperate), this.MakeLocalMaster)
 
 	// Replication, general:
-	m.Get(this.URLPrefix+"/api/enable-gtid/:host/:port", this.EnableGTID)
-	m.Get(this.URLPrefix+"/api/disable-gtid/:host/:port", this.DisableGTID)
-	m.Get(this.URLPrefix+"/api/skip-query/:host/:port", this.SkipQuery)
-	m.Get(this.URLPrefix+"/api/start-slave/:host/:port", this.StartSlave)
-	m.Get(this.URLPrefix+"/api/restart-slave/:host/:port", this.RestartSlave)
-	m.Get(this.URLPrefix+"/api/stop-slave/:host/:port", this.StopSlave)
-	m.Get(this.URLPrefix+"/api/stop-slave-nice/:host/:port", this.StopSlaveNicely)
-	m.Get(this.URLPrefix+"/api/reset-slave/:host/:port", this.ResetSlave)
-	m.Get(this.URLPrefix+"/api/detach-slave/:host/:port", this.DetachSlave)
-	m.Get(this.URLPrefix+"/api/reattach-slave/:host/:port", this.ReattachSlave)
-	m.Get(this.URLPrefix+"/api/reattach-slave-master-host/:host/:port", this.ReattachSlaveMasterHost)
+	m.Get(this.URLPrefix+"/api/enable-gtid/:host/:port", this.requirePermission(PermissionOperate), this.EnableGTID)
+	m.Get(this.URLPrefix+"/api/disable-gtid/:host/:port", this.requirePermission(PermissionOperate), this.DisableGTID)
+	m.Get(this.URLPrefix+"/api/skip-query/:host/:port", this.requirePermission(PermissionOperate), this.SkipQuery)
+	m.Get(this.URLPrefix+"/api/start-slave/:host/:port", this.requirePermission(PermissionOperate), this.StartSlave)
+	m.Get(this.URLPrefix+"/api/restart-slave/:host/:port", this.requirePermission(PermissionOperate), this.RestartSlave)
+	m.Get(this.URLPrefix+"/api/stop-slave/:host/:port", this.requirePermission(PermissionOperate), this.StopSlave)
+	m.Get(this.URLPrefix+"/api/stop-slave-nice/:host/:port", this.requirePermission(PermissionOperate), this.StopSlaveNicely)
+	m.Get(this.URLPrefix+"/api/reset-slave/:host/:port", this.requirePermission(PermissionOperate), this.ResetSlave)
+	m.Get(this.URLPrefix+"/api/detach-slave/:host/:port", this.requirePermission(PermissionOperate), this.DetachSlave)
+	m.Get(this.URLPrefix+"/api/reattach-slave/:host/:port", this.requirePermission(PermissionOperate), this.ReattachSlave)
+	m.Get(this.URLPrefix+"/api/reattach-slave-master-host/:host/:port", this.requirePermission(PermissionOperate), this.ReattachSlaveMasterHost)
 
 	// Instance:
-	m.Get(this.URLPrefix+"/api/set-read-only/:host/:port", this.SetReadOnly)
-	m.Get(this.URLPrefix+"/api/set-writeable/:host/:port", this.SetWriteable)
-	m.Get(this.URLPrefix+"/api/kill-query/:host/:port/:process", this.KillQuery)
+	m.Get(this.URLPrefix+"/api/set-read-only/:host/:port", this.requirePermission(PermissionOperate), this.SetReadOnly)
+	m.Get(this.URLPrefix+"/api/set-writeable/:host/:port", this.requirePermission(PermissionOperate), this.SetWriteable)
+	m.Get(this.URLPrefix+"/api/kill-query/:host/:port/:process", this.requirePermission(PermissionOperate), this.KillQuery)
 
 	// Binary logs:
-	m.Get(this.URLPrefix+"/api/last-pseudo-gtid/:host/:port", this.LastPseudoGTID)
+	m.Get(this.URLPrefix+"/api/last-pseudo-gtid/:host/:port", this.requirePermission(PermissionOperate), this.LastPseudoGTID)
+	m.Get(this.URLPrefix+"/api/inject-pseudo-gtid/:host/:port", this.requirePermission(PermissionOperate), this.InjectPseudoGTID)
 
 	// Pools:
-	m.Get(this.URLPrefix+"/api/submit-pool-instances/:pool", this.SubmitPoolInstances)
-	m.Get(this.URLPrefix+"/api/cluster-pool-instances/:clusterName", this.ReadClusterPoolInstancesMap)
-	m.Get(this.URLPrefix+"/api/cluster-pool-instances/:clusterName/:pool", this.ReadClusterPoolInstancesMap)
-	m.Get(this.URLPrefix+"/api/heuristic-cluster-pool-instances/:clusterName", this.GetHeuristicClusterPoolInstances)
-	m.Get(this.URLPrefix+"/api/heuristic-cluster-pool-instances/:clusterName/:pool", this.GetHeuristicClusterPoolInstances)
-	m.Get(this.URLPrefix+"/api/heuristic-cluster-pool-lag/:clusterName", this.GetHeuristicClusterPoolInstancesLag)
-	m.Get(this.URLPrefix+"/api/heuristic-cluster-pool-lag/:clusterName/:pool", this.GetHeuristicClusterPoolInstancesLag)
+	m.Get(this.URLPrefix+"/api/submit-pool-instances/:pool", this.requirePermission(PermissionOperate), this.SubmitPoolInstances)
+	m.Get(this.URLPrefix+"/api/cluster-pool-instances/:clusterName", this.requirePermission(PermissionOperate), this.ReadClusterPoolInstancesMap)
+	m.Get(this.URLPrefix+"/api/cluster-pool-instances/:clusterName/:pool", this.requirePermission(PermissionOperate), this.ReadClusterPoolInstancesMap)
+	m.Get(this.URLPrefix+"/api/heuristic-cluster-pool-instances/:clusterName", this.requirePermission(PermissionOperate), this.GetHeuristicClusterPoolInstances)
+	m.Get(this.URLPrefix+"/api/heuristic-cluster-pool-instances/:clusterName/:pool", this.requirePermission(PermissionOperate), this.GetHeuristicClusterPoolInstances)
+	m.Get(this.URLPrefix+"/api/heuristic-cluster-pool-lag/:clusterName", this.requirePermission(PermissionOperate), this.GetHeuristicClusterPoolInstancesLag)
+	m.Get(this.URLPrefix+"/api/heuristic-cluster-pool-lag/:clusterName/:pool", this.requirePermission(PermissionOperate), this.GetHeuristicClusterPoolInstancesLag)
 
 	// Information:
-	m.Get(this.URLPrefix+"/api/search/:searchString", this.Search)
-	m.Get(this.URLPrefix+"/api/search", this.Search)
+	m.Get(this.URLPrefix+"/api/search/:searchString", this.requirePermission(PermissionRead), this.Search)
+	m.Get(this.URLPrefix+"/api/search", this.requirePermission(PermissionRead), this.Search)
 
 	// Cluster
-	m.Get(this.URLPrefix+"/api/cluster/:clusterName", this.Cluster)
-	m.Get(this.URLPrefix+"/api/cluster/alias/:clusterAlias", this.ClusterByAlias)
-	m.Get(this.URLPrefix+"/api/cluster/instance/:host/:port", this.ClusterByInstance)
-	m.Get(this.URLPrefix+"/api/cluster-info/:clusterName", this.ClusterInfo)
-	m.Get(this.URLPrefix+"/api/cluster-info/alias/:clusterAlias", this.ClusterInfoByAlias)
-	m.Get(this.URLPrefix+"/api/cluster-osc-slaves/:clusterName", this.ClusterOSCSlaves)
-	m.Get(this.URLPrefix+"/api/set-cluster-alias/:clusterName", this.SetClusterAlias)
-	m.Get(this.URLPrefix+"/api/clusters", this.Clusters)
-	m.Get(this.URLPrefix+"/api/clusters-info", this.ClustersInfo)
+	m.Get(this.URLPrefix+"/api/cluster/:clusterName", this.requirePermission(PermissionRead), this.Cluster)
+	m.Get(this.URLPrefix+"/api/cluster/alias/:clusterAlias", this.requirePermission(PermissionRead), this.ClusterByAlias)
+	m.Get(this.URLPrefix+"/api/cluster/instance/:host/:port", this.requirePermission(PermissionRead), this.ClusterByInstance)
+	m.Get(this.URLPrefix+"/api/cluster-info/:clusterName", this.requirePermission(PermissionRead), this.ClusterInfo)
+	m.Get(this.URLPrefix+"/api/cluster-info/alias/:clusterAlias", this.requirePermission(PermissionRead), this.ClusterInfoByAlias)
+	m.Get(this.URLPrefix+"/api/cluster-osc-slaves/:clusterName", this.requirePermission(PermissionRead), this.ClusterOSCSlaves)
+	m.Get(this.URLPrefix+"/api/set-cluster-alias/:clusterName", this.requirePermission(PermissionAdmin), this.SetClusterAlias)
+	m.Get(this.URLPrefix+"/api/clusters", this.requirePermission(PermissionRead), this.Clusters)
+	m.Get(this.URLPrefix+"/api/clusters-info", this.requirePermission(PermissionRead), this.ClustersInfo)
 
 	// Instance management:
-	m.Get(this.URLPrefix+"/api/instance/:host/:port", this.Instance)
-	m.Get(this.URLPrefix+"/api/discover/:host/:port", this.Discover)
-	m.Get(this.URLPrefix+"/api/refresh/:host/:port", this.Refresh)
-	m.Get(this.URLPrefix+"/api/forget/:host/:port", this.Forget)
-	m.Get(this.URLPrefix+"/api/begin-maintenance/:host/:port/:owner/:reason", this.BeginMaintenance)
-	m.Get(this.URLPrefix+"/api/end-maintenance/:host/:port", this.EndMaintenanceByInstanceKey)
-	m.Get(this.URLPrefix+"/api/end-maintenance/:maintenanceKey", this.EndMaintenance)
-	m.Get(this.URLPrefix+"/api/begin-downtime/:host/:port/:owner/:reason", this.BeginDowntime)
-	m.Get(this.URLPrefix+"/api/begin-downtime/:host/:port/:owner/:reason/:duration", this.BeginDowntime)
-	m.Get(this.URLPrefix+"/api/end-downtime/:host/:port", this.EndDowntime)
+	m.Get(this.URLPrefix+"/api/instance/:host/:port", this.requirePermission(PermissionRead), this.Instance)
+	m.Get(this.URLPrefix+"/api/discover/:host/:port", this.requirePermission(PermissionOperate), this.Discover)
+	m.Get(this.URLPrefix+"/api/refresh/:host/:port", this.requirePermission(PermissionOperate), this.Refresh)
+	m.Get(this.URLPrefix+"/api/forget/:host/:port", this.requirePermission(PermissionAdmin), this.Forget)
+	m.Get(this.URLPrefix+"/api/begin-maintenance/:host/:port/:owner/:reason", this.requirePermission(PermissionOperate), this.BeginMaintenance)
+	m.Get(this.URLPrefix+"/api/end-maintenance/:host/:port", this.requirePermission(PermissionOperate), this.EndMaintenanceByInstanceKey)
+	m.Get(this.URLPrefix+"/api/end-maintenance/:maintenanceKey", this.requirePermission(PermissionOperate), this.EndMaintenance)
+	m.Get(this.URLPrefix+"/api/begin-downtime/:host/:port/:owner/:reason", this.requirePermission(PermissionOperate), this.BeginDowntime)
+	m.Get(this.URLPrefix+"/api/begin-downtime/:host/:port/:owner/:reason/:duration", this.requirePermission(PermissionOperate), this.BeginDowntime)
+	m.Get(this.URLPrefix+"/api/end-downtime/:host/:port", this.requirePermission(PermissionOperate), this.EndDowntime)
 
 	// Recovery:
-	m.Get(this.URLPrefix+"/api/replication-analysis", this.ReplicationAnalysis)
-	m.Get(this.URLPrefix+"/api/replication-analysis/:clusterName", this.ReplicationAnalysis)
-	m.Get(this.URLPrefix+"/api/recover/:host/:port", this.Recover)
-	m.Get(this.URLPrefix+"/api/recover/:host/:port/:candidateHost/:candidatePort", this.Recover)
-	m.Get(this.URLPrefix+"/api/recover-lite/:host/:port", this.RecoverLite)
-	m.Get(this.URLPrefix+"/api/recover-lite/:host/:port/:candidateHost/:candidatePort", this.RecoverLite)
-	m.Get(this.URLPrefix+"/api/register-candidate/:host/:port/:promotionRule", this.RegisterCandidate)
-	m.Get(this.URLPrefix+"/api/automated-recovery-filters", this.AutomatedRecoveryFilters)
-	m.Get(this.URLPrefix+"/api/audit-failure-detection", this.AuditFailureDetection)
-	m.Get(this.URLPrefix+"/api/audit-failure-detection/:page", this.AuditFailureDetection)
-	m.Get(this.URLPrefix+"/api/audit-failure-detection/id/:id", this.AuditFailureDetection)
-	m.Get(this.URLPrefix+"/api/replication-analysis-changelog", this.ReadReplicationAnalysisChangelog)
-	m.Get(this.URLPrefix+"/api/audit-recovery", this.AuditRecovery)
-	m.Get(this.URLPrefix+"/api/audit-recovery/:page", this.AuditRecovery)
-	m.Get(this.URLPrefix+"/api/audit-recovery/id/:id", this.AuditRecovery)
-	m.Get(this.URLPrefix+"/api/audit-recovery/cluster/:clusterName", this.AuditRecovery)
-	m.Get(this.URLPrefix+"/api/audit-recovery/cluster/:clusterName/:page", this.AuditRecovery)
-	m.Get(this.URLPrefix+"/api/active-cluster-recovery/:clusterName", this.ActiveClusterRecovery)
-	m.Get(this.URLPrefix+"/api/recently-active-cluster-recovery/:clusterName", this.RecentlyActiveClusterRecovery)
-	m.Get(this.URLPrefix+"/api/recently-active-instance-recovery/:host/:port", this.RecentlyActiveInstanceRecovery)
-	m.Get(this.URLPrefix+"/api/ack-recovery/cluster/:clusterName", this.AcknowledgeClusterRecoveries)
-	m.Get(this.URLPrefix+"/api/ack-recovery/cluster/alias/:clusterAlias", this.AcknowledgeClusterRecoveries)
-	m.Get(this.URLPrefix+"/api/ack-recovery/instance/:host/:port", this.AcknowledgeInstanceRecoveries)
-	m.Get(this.URLPrefix+"/api/ack-recovery/:recoveryId", this.AcknowledgeRecovery)
-	m.Get(this.URLPrefix+"/api/blocked-recoveries", this.BlockedRecoveries)
-	m.Get(this.URLPrefix+"/api/blocked-recoveries/cluster/:clusterName", this.BlockedRecoveries)
+	m.Get(this.URLPrefix+"/api/replication-analysis", this.requirePermission(PermissionRead), this.ReplicationAnalysis)
+	m.Get(this.URLPrefix+"/api/replication-analysis/:clusterName", this.requirePermission(PermissionRead), this.ReplicationAnalysis)
+	m.Get(this.URLPrefix+"/api/recover/:host/:port", this.requirePermission(PermissionRecover), this.Recover)
+	m.Get(this.URLPrefix+"/api/recover/:host/:port/:candidateHost/:candidatePort", this.requirePermission(PermissionRecover), this.Recover)
+	m.Get(this.URLPrefix+"/api/recover-lite/:host/:port", this.requirePermission(PermissionRecover), this.RecoverLite)
+	m.Get(this.URLPrefix+"/api/recover-lite/:host/:port/:candidateHost/:candidatePort", this.requirePermission(PermissionRecover), this.RecoverLite)
+	m.Get(this.URLPrefix+"/api/register-candidate/:host/:port/:promotionRule", this.requirePermission(PermissionOperate), this.RegisterCandidate)
+	m.Get(this.URLPrefix+"/api/automated-recovery-filters", this.requirePermission(PermissionRead), this.AutomatedRecoveryFilters)
+	m.Get(this.URLPrefix+"/api/audit-failure-detection", this.requirePermission(PermissionRead), this.AuditFailureDetection)
+	m.Get(this.URLPrefix+"/api/audit-failure-detection/:page", this.requirePermission(PermissionRead), this.AuditFailureDetection)
+	m.Get(this.URLPrefix+"/api/audit-failure-detection/id/:id", this.requirePermission(PermissionRead), this.AuditFailureDetection)
+	m.Get(this.URLPrefix+"/api/replication-analysis-changelog", this.requirePermission(PermissionRead), this.ReadReplicationAnalysisChangelog)
+	m.Get(this.URLPrefix+"/api/audit-recovery", this.requirePermission(PermissionRead), this.AuditRecovery)
+	m.Get(this.URLPrefix+"/api/audit-recovery/:page", this.requirePermission(PermissionRead), this.AuditRecovery)
+	m.Get(this.URLPrefix+"/api/audit-recovery/id/:id", this.requirePermission(PermissionRead), this.AuditRecovery)
+	m.Get(this.URLPrefix+"/api/audit-recovery/cluster/:clusterName", this.requirePermission(PermissionRead), this.AuditRecovery)
+	m.Get(this.URLPrefix+"/api/audit-recovery/cluster/:clusterName/:page", this.requirePermission(PermissionRead), this.AuditRecovery)
+	m.Get(this.URLPrefix+"/api/probe-instance-liveness/:host/:port", this.ProbeInstanceLiveness)
+	m.Get(this.URLPrefix+"/api/failure-detection-votes/:detectionId", this.requirePermission(PermissionRead), this.FailureDetectionVotes)
+	m.Get(this.URLPrefix+"/api/audit-replication-remediation", this.requirePermission(PermissionRead), this.AuditReplicationRemediation)
+	m.Get(this.URLPrefix+"/api/audit-replication-remediation/:page", this.requirePermission(PermissionRead), this.AuditReplicationRemediation)
+	m.Get(this.URLPrefix+"/api/audit-replication-remediation/cluster/:clusterName", this.requirePermission(PermissionRead), this.AuditReplicationRemediation)
+	m.Get(this.URLPrefix+"/api/audit-replication-remediation/cluster/:clusterName/:page", this.requirePermission(PermissionRead), this.AuditReplicationRemediation)
+	m.Get(this.URLPrefix+"/api/active-cluster-recovery/:clusterName", this.requirePermission(PermissionRead), this.ActiveClusterRecovery)
+	m.Get(this.URLPrefix+"/api/recently-active-cluster-recovery/:clusterName", this.requirePermission(PermissionRead), this.RecentlyActiveClusterRecovery)
+	m.Get(this.URLPrefix+"/api/recently-active-instance-recovery/:host/:port", this.requirePermission(PermissionRead), this.RecentlyActiveInstanceRecovery)
+	m.Get(this.URLPrefix+"/api/ack-recovery/cluster/:clusterName", this.requirePermission(PermissionRecover), this.AcknowledgeClusterRecoveries)
+	m.Get(this.URLPrefix+"/api/ack-recovery/cluster/alias/:clusterAlias", this.requirePermission(PermissionRecover), this.AcknowledgeClusterRecoveries)
+	m.Get(this.URLPrefix+"/api/ack-recovery/instance/:host/:port", this.requirePermission(PermissionRecover), this.AcknowledgeInstanceRecoveries)
+	m.Get(this.URLPrefix+"/api/ack-recovery/:recoveryId", this.requirePermission(PermissionRecover), this.AcknowledgeRecovery)
+	m.Get(this.URLPrefix+"/api/blocked-recoveries", this.requirePermission(PermissionRead), this.BlockedRecoveries)
+	m.Get(this.URLPrefix+"/api/blocked-recoveries/cluster/:clusterName", this.requirePermission(PermissionRead), this.BlockedRecoveries)
 
 	// General
-	m.Get(this.URLPrefix+"/api/problems", this.Problems)
-	m.Get(this.URLPrefix+"/api/problems/:clusterName", this.Problems)
-	m.Get(this.URLPrefix+"/api/long-queries", this.LongQueries)
-	m.Get(this.URLPrefix+"/api/long-queries/:filter", this.LongQueries)
-	m.Get(this.URLPrefix+"/api/audit", this.Audit)
-	m.Get(this.URLPrefix+"/api/audit/:page", this.Audit)
-	m.Get(this.URLPrefix+"/api/audit/instance/:host/:port", this.Audit)
-	m.Get(this.URLPrefix+"/api/audit/instance/:host/:port/:page", this.Audit)
-	m.Get(this.URLPrefix+"/api/resolve/:host/:port", this.Resolve)
+	m.Get(this.URLPrefix+"/api/problems", this.requirePermission(PermissionRead), this.Problems)
+	m.Get(this.URLPrefix+"/api/problems/:clusterName", this.requirePermission(PermissionRead), this.Problems)
+	m.Get(this.URLPrefix+"/api/long-queries", this.requirePermission(PermissionRead), this.LongQueries)
+	m.Get(this.URLPrefix+"/api/long-queries/:filter", this.requirePermission(PermissionRead), this.LongQueries)
+	m.Get(this.URLPrefix+"/api/audit", this.requirePermission(PermissionRead), this.Audit)
+	m.Get(this.URLPrefix+"/api/audit/:page", this.requirePermission(PermissionRead), this.Audit)
+	m.Get(this.URLPrefix+"/api/audit/instance/:host/:port", this.requirePermission(PermissionRead), this.Audit)
+	m.Get(this.URLPrefix+"/api/audit/instance/:host/:port/:page", this.requirePermission(PermissionRead), this.Audit)
+	m.Get(this.URLPrefix+"/api/resolve/:host/:port", this.requirePermission(PermissionRead), this.Resolve)
 
 	// Meta
-	m.Get(this.URLPrefix+"/api/maintenance", this.Maintenance)
-	m.Get(this.URLPrefix+"/api/headers", this.Headers)
+	m.Get(this.URLPrefix+"/api/maintenance", this.requirePermission(PermissionRead), this.Maintenance)
+	m.Get(this.URLPrefix+"/api/headers", this.requirePermission(PermissionRead), this.Headers)
 	m.Get(this.URLPrefix+"/api/health", this.Health)
 	m.Get(this.URLPrefix+"/api/lb-check", this.LBCheck)
-	m.Get(this.URLPrefix+"/api/grab-election", this.GrabElection)
-	m.Get(this.URLPrefix+"/api/reelect", this.Reelect)
-	m.Get(this.URLPrefix+"/api/reload-configuration", this.ReloadConfiguration)
-	m.Get(this.URLPrefix+"/api/reload-cluster-alias", this.ReloadClusterAlias)
-	m.Get(this.URLPrefix+"/api/hostname-resolve-cache", this.HostnameResolveCache)
-	m.Get(this.URLPrefix+"/api/reset-hostname-resolve-cache", this.ResetHostnameResolveCache)
-	m.Get(this.URLPrefix+"/api/deregister-hostname-unresolve/:host/:port", this.DeregisterHostnameUnresolve)
-	m.Get(this.URLPrefix+"/api/register-hostname-unresolve/:host/:port/:virtualname", this.RegisterHostnameUnresolve)
+	m.Get(this.URLPrefix+"/api/grab-election", this.requirePermission(PermissionAdmin), this.GrabElection)
+	m.Get(this.URLPrefix+"/api/reelect", this.requirePermission(PermissionAdmin), this.Reelect)
+	m.Get(this.URLPrefix+"/api/reload-configuration", this.requirePermission(PermissionAdmin), this.ReloadConfiguration)
+	m.Get(this.URLPrefix+"/api/api-keys", this.requirePermission(PermissionAdmin), this.APIKeys)
+	m.Get(this.URLPrefix+"/api/create-api-key/:name", this.requirePermission(PermissionAdmin), this.CreateAPIKey)
+	m.Get(this.URLPrefix+"/api/revoke-api-key/:name", this.requirePermission(PermissionAdmin), this.RevokeAPIKey)
+	m.Get(this.URLPrefix+"/api/reload-cluster-alias", this.requirePermission(PermissionAdmin), this.ReloadClusterAlias)
+	m.Get(this.URLPrefix+"/api/hostname-resolve-cache", this.requirePermission(PermissionRead), this.HostnameResolveCache)
+	m.Get(this.URLPrefix+"/api/reset-hostname-resolve-cache", this.requirePermission(PermissionAdmin), this.ResetHostnameResolveCache)
+	m.Get(this.URLPrefix+"/api/deregister-hostname-unresolve/:host/:port", this.requirePermission(PermissionAdmin), this.DeregisterHostnameUnresolve)
+	m.Get(this.URLPrefix+"/api/register-hostname-unresolve/:host/:port/:virtualname", this.requirePermission(PermissionAdmin), this.RegisterHostnameUnresolve)
 
 	// Bulk access to information
-	m.Get("/api/bulk-instances", this.BulkInstances)
-	m.Get("/api/bulk-promotion-rules", this.BulkPromotionRules)
+	m.Get("/api/bulk-instances", this.requirePermission(PermissionOperate), this.BulkInstances)
+	m.Get("/api/bulk-promotion-rules", this.requirePermission(PermissionOperate), this.BulkPromotionRules)
 
 	// Agents
-	m.Get(this.URLPrefix+"/api/agents", this.Agents)
-	m.Get(this.URLPrefix+"/api/agent/:host", this.Agent)
-	m.Get(this.URLPrefix+"/api/agent-umount/:host", this.AgentUnmount)
-	m.Get(this.URLPrefix+"/api/agent-mount/:host", this.AgentMountLV)
-	m.Get(this.URLPrefix+"/api/agent-create-snapshot/:host", this.AgentCreateSnapshot)
-	m.Get(this.URLPrefix+"/api/agent-removelv/:host", this.AgentRemoveLV)
-	m.Get(this.URLPrefix+"/api/agent-mysql-stop/:host", this.AgentMySQLStop)
-	m.Get(this.URLPrefix+"/api/agent-mysql-start/:host", this.AgentMySQLStart)
-	m.Get(this.URLPrefix+"/api/agent-seed/:targetHost/:sourceHost", this.AgentSeed)
-	m.Get(this.URLPrefix+"/api/agent-active-seeds/:host", this.AgentActiveSeeds)
-	m.Get(this.URLPrefix+"/api/agent-recent-seeds/:host", this.AgentRecentSeeds)
-	m.Get(this.URLPrefix+"/api/agent-seed-details/:seedId", this.AgentSeedDetails)
-	m.Get(this.URLPrefix+"/api/agent-seed-states/:seedId", this.AgentSeedStates)
-	m.Get(this.URLPrefix+"/api/agent-abort-seed/:seedId", this.AbortSeed)
-	m.Get(this.URLPrefix+"/api/agent-custom-command/:host/:command", this.AgentCustomCommand)
-	m.Get(this.URLPrefix+"/api/seeds", this.Seeds)
+	m.Get(this.URLPrefix+"/api/agents", this.requirePermission(PermissionOperate), this.Agents)
+	m.Get(this.URLPrefix+"/api/agent/:host", this.requirePermission(PermissionOperate), this.Agent)
+	m.Get(this.URLPrefix+"/api/agent-umount/:host", this.requirePermission(PermissionOperate), this.AgentUnmount)
+	m.Get(this.URLPrefix+"/api/agent-mount/:host", this.requirePermission(PermissionOperate), this.AgentMountLV)
+	m.Get(this.URLPrefix+"/api/agent-create-snapshot/:host", this.requirePermission(PermissionOperate), this.AgentCreateSnapshot)
+	m.Get(this.URLPrefix+"/api/agent-removelv/:host", this.requirePermission(PermissionOperate), this.AgentRemoveLV)
+	m.Get(this.URLPrefix+"/api/agent-mysql-stop/:host", this.requirePermission(PermissionOperate), this.AgentMySQLStop)
+	m.Get(this.URLPrefix+"/api/agent-mysql-start/:host", this.requirePermission(PermissionOperate), this.AgentMySQLStart)
+	m.Get(this.URLPrefix+"/api/agent-seed/:targetHost/:sourceHost", this.requirePermission(PermissionOperate), this.AgentSeed)
+	m.Get(this.URLPrefix+"/api/clone-instance/:host/:port/:donorHost/:donorPort", this.requirePermission(PermissionOperate), this.CloneInstance)
+	m.Get(this.URLPrefix+"/api/agent-active-seeds/:host", this.requirePermission(PermissionOperate), this.AgentActiveSeeds)
+	m.Get(this.URLPrefix+"/api/agent-recent-seeds/:host", this.requirePermission(PermissionOperate), this.AgentRecentSeeds)
+	m.Get(this.URLPrefix+"/api/agent-seed-details/:seedId", this.requirePermission(PermissionOperate), this.AgentSeedDetails)
+	m.Get(this.URLPrefix+"/api/agent-seed-states/:seedId", this.requirePermission(PermissionOperate), this.AgentSeedStates)
+	m.Get(this.URLPrefix+"/api/agent-abort-seed/:seedId", this.requirePermission(PermissionOperate), this.AbortSeed)
+	m.Get(this.URLPrefix+"/api/agent-resume-seed/:seedId", this.requirePermission(PermissionOperate), this.ResumeSeed)
+	m.Get(this.URLPrefix+"/api/agent-custom-command/:host/:command", this.requirePermission(PermissionOperate), this.AgentCustomCommand)
+	m.Get(this.URLPrefix+"/api/seeds", this.requirePermission(PermissionOperate), this.Seeds)
 
 	// Configurable status check endpoint
 	m.Get(config.Config.StatusEndpoint, this.StatusCheck)
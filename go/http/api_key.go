@@ -0,0 +1,143 @@
+/*
+   Copyright 2014 Outbrain Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-martini/martini"
+	"github.com/martini-contrib/auth"
+	"github.com/outbrain/golib/log"
+
+	"github.com/outbrain/orchestrator/go/config"
+	"github.com/outbrain/orchestrator/go/inst"
+	"github.com/outbrain/orchestrator/go/process"
+)
+
+type apiKeyContextKey struct{}
+
+// getBearerToken returns the token of an "Authorization: Bearer" header, if any
+func getBearerToken(req *http.Request) string {
+	authorization := req.Header.Get("Authorization")
+	if len(authorization) > len("Bearer ") && strings.EqualFold(authorization[0:len("Bearer ")], "Bearer ") {
+		return strings.TrimSpace(authorization[len("Bearer "):])
+	}
+	return ""
+}
+
+// getRequestAPIKey returns the API key the request was authenticated by, or nil
+func getRequestAPIKey(req *http.Request) *process.APIKey {
+	if apiKey, ok := req.Context().Value(apiKeyContextKey{}).(*process.APIKey); ok {
+		return apiKey
+	}
+	return nil
+}
+
+// getAPIOperation returns the API operation name of the request, e.g. "relocate" for /api/relocate/...
+func getAPIOperation(req *http.Request) string {
+	path := strings.TrimPrefix(req.URL.Path, config.Config.URLPrefix)
+	path = strings.TrimPrefix(path, "/api/")
+	return strings.Split(path, "/")[0]
+}
+
+// writeAPIError responds with an API error, for use by handlers running ahead of the renderer
+func writeAPIError(resp http.ResponseWriter, status int, message string) {
+	resp.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	resp.WriteHeader(status)
+	json.NewEncoder(resp).Encode(&APIResponse{Code: ERROR, Message: message})
+}
+
+// APIKeyAuthenticate is a martini handler authenticating API requests bearing an API key. It applies
+// regardless of AuthenticationMethod; requests without a bearer token pass through untouched.
+func APIKeyAuthenticate(c martini.Context, resp http.ResponseWriter, req *http.Request) {
+	secret := getBearerToken(req)
+	if secret == "" {
+		return
+	}
+	if !strings.HasPrefix(req.URL.Path, config.Config.URLPrefix+"/api/") {
+		return
+	}
+	apiKey, err := process.AuthenticateAPIKey(secret)
+	if err != nil {
+		writeAPIError(resp, http.StatusInternalServerError, fmt.Sprintf("%+v", err))
+		return
+	}
+	if apiKey == nil {
+		writeAPIError(resp, http.StatusUnauthorized, "Invalid API key")
+		return
+	}
+	c.Map(req.WithContext(context.WithValue(req.Context(), apiKeyContextKey{}, apiKey)))
+	c.Map(auth.User(apiKey.UserId()))
+}
+
+// UnlessAPIKey wraps an authentication handler such that it is skipped for requests already authenticated
+// by an API key.
+func UnlessAPIKey(handler martini.Handler) martini.Handler {
+	return func(c martini.Context, req *http.Request) {
+		if getRequestAPIKey(req) != nil {
+			return
+		}
+		if _, err := c.Invoke(handler); err != nil {
+			log.Errore(err)
+		}
+	}
+}
+
+// isAuthorizedForAPIKey checks the key's scopes against the request. Keys do not have the "admin"
+// permission unless the operation is explicitly listed in their scopes.
+func isAuthorizedForAPIKey(apiKey *process.APIKey, params martini.Params, req *http.Request, permission Permission) (bool, error) {
+	if config.Config.ReadOnly && permission != PermissionRead {
+		return false, nil
+	}
+	if apiKey.ReadOnly && permission != PermissionRead {
+		return false, nil
+	}
+	operation := getAPIOperation(req)
+	if permission == PermissionAdmin && len(apiKey.Operations) == 0 {
+		return false, nil
+	}
+	if !apiKey.AllowsOperation(operation) {
+		return false, nil
+	}
+	clusterNames := getRequestClusters(params)
+	if len(clusterNames) == 0 {
+		return apiKey.AllowsCluster("", ""), nil
+	}
+	for _, clusterName := range clusterNames {
+		clusterAlias, err := inst.ReadAliasByClusterName(clusterName)
+		if err != nil {
+			return false, err
+		}
+		if !apiKey.AllowsCluster(clusterName, clusterAlias) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// auditAPIKeyUsage records an API call made with given key
+func auditAPIKeyUsage(apiKey *process.APIKey, params martini.Params, req *http.Request) {
+	var instanceKey *inst.InstanceKey
+	if params["host"] != "" {
+		instanceKey, _ = inst.NewInstanceKeyFromStrings(params["host"], params["port"])
+	}
+	inst.AuditOperation("api-key", instanceKey, fmt.Sprintf("%s: %s %s", apiKey.Name, req.Method, req.URL.Path))
+}
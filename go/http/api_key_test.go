@@ -0,0 +1,103 @@
+package http
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-martini/martini"
+	test "github.com/outbrain/golib/tests"
+
+	"github.com/outbrain/orchestrator/go/config"
+	"github.com/outbrain/orchestrator/go/process"
+)
+
+func TestGetBearerToken(t *testing.T) {
+	req := httptest.NewRequest("GET", "/api/clusters", nil)
+	test.S(t).ExpectEquals(getBearerToken(req), "")
+	req.Header.Set("Authorization", "Bearer orc_abc")
+	test.S(t).ExpectEquals(getBearerToken(req), "orc_abc")
+	req.Header.Set("Authorization", "bearer orc_abc")
+	test.S(t).ExpectEquals(getBearerToken(req), "orc_abc")
+	req.Header.Set("Authorization", "Basic dXNlcjpwYXNz")
+	test.S(t).ExpectEquals(getBearerToken(req), "")
+}
+
+func TestGetAPIOperation(t *testing.T) {
+	config.Config.URLPrefix = ""
+	test.S(t).ExpectEquals(getAPIOperation(httptest.NewRequest("GET", "/api/relocate/db1/3306/db2/3306", nil)), "relocate")
+	test.S(t).ExpectEquals(getAPIOperation(httptest.NewRequest("GET", "/api/clusters", nil)), "clusters")
+	config.Config.URLPrefix = "/orchestrator"
+	defer func() { config.Config.URLPrefix = "" }()
+	test.S(t).ExpectEquals(getAPIOperation(httptest.NewRequest("GET", "/orchestrator/api/begin-downtime/db1/3306/me/why/1h", nil)), "begin-downtime")
+}
+
+func TestParseAPIKeyScopes(t *testing.T) {
+	apiKey, err := process.ParseAPIKeyScopes("read-only, cluster=^team-a-,operation=relocate")
+	test.S(t).ExpectNil(err)
+	test.S(t).ExpectTrue(apiKey.ReadOnly)
+	test.S(t).ExpectEquals(len(apiKey.ClusterPatterns), 1)
+	test.S(t).ExpectEquals(apiKey.Operations[0], "relocate")
+	test.S(t).ExpectEquals(apiKey.Scopes(), "read-only,cluster=^team-a-,operation=relocate")
+
+	_, err = process.ParseAPIKeyScopes("superuser")
+	test.S(t).ExpectNotNil(err)
+	_, err = process.ParseAPIKeyScopes("cluster=[")
+	test.S(t).ExpectNotNil(err)
+}
+
+func TestIsAuthorizedForAPIKey(t *testing.T) {
+	config.Config.URLPrefix = ""
+	params := martini.Params{}
+	clustersRequest := httptest.NewRequest("GET", "/api/clusters", nil)
+	discoverRequest := httptest.NewRequest("GET", "/api/discover", nil)
+	reloadRequest := httptest.NewRequest("GET", "/api/reload-configuration", nil)
+
+	readOnlyKey, _ := process.ParseAPIKeyScopes("read-only")
+	authorized, _ := isAuthorizedForAPIKey(readOnlyKey, params, clustersRequest, PermissionRead)
+	test.S(t).ExpectTrue(authorized)
+	authorized, _ = isAuthorizedForAPIKey(readOnlyKey, params, discoverRequest, PermissionOperate)
+	test.S(t).ExpectFalse(authorized)
+
+	unscopedKey, _ := process.ParseAPIKeyScopes("")
+	authorized, _ = isAuthorizedForAPIKey(unscopedKey, params, discoverRequest, PermissionOperate)
+	test.S(t).ExpectTrue(authorized)
+	authorized, _ = isAuthorizedForAPIKey(unscopedKey, params, reloadRequest, PermissionAdmin)
+	test.S(t).ExpectFalse(authorized)
+
+	operationKey, _ := process.ParseAPIKeyScopes("operation=reload-configuration")
+	authorized, _ = isAuthorizedForAPIKey(operationKey, params, reloadRequest, PermissionAdmin)
+	test.S(t).ExpectTrue(authorized)
+	authorized, _ = isAuthorizedForAPIKey(operationKey, params, discoverRequest, PermissionOperate)
+	test.S(t).ExpectFalse(authorized)
+
+	clusterKey, _ := process.ParseAPIKeyScopes("cluster=^team-a-")
+	authorized, _ = isAuthorizedForAPIKey(clusterKey, params, discoverRequest, PermissionOperate)
+	test.S(t).ExpectFalse(authorized)
+	test.S(t).ExpectTrue(clusterKey.AllowsCluster("db-1:3306", "team-a-orders"))
+	test.S(t).ExpectFalse(clusterKey.AllowsCluster("db-2:3306", "team-b-users"))
+}
+
+func TestAPIKeyCoversScopesOf(t *testing.T) {
+	parse := func(scopes string) *process.APIKey {
+		apiKey, err := process.ParseAPIKeyScopes(scopes)
+		test.S(t).ExpectNil(err)
+		return apiKey
+	}
+	unscopedKey := parse("")
+	test.S(t).ExpectNil(unscopedKey.CoversScopesOf(parse("")))
+	test.S(t).ExpectNil(unscopedKey.CoversScopesOf(parse("read-only,cluster=^team-a-")))
+
+	issuerKey := parse("operation=create-api-key")
+	test.S(t).ExpectNotNil(issuerKey.CoversScopesOf(parse("")))
+	test.S(t).ExpectNotNil(issuerKey.CoversScopesOf(parse("operation=relocate")))
+	test.S(t).ExpectNil(issuerKey.CoversScopesOf(parse("operation=create-api-key,cluster=^team-a-")))
+
+	teamKey := parse("cluster=^team-a-,operation=relocate,operation=create-api-key")
+	test.S(t).ExpectNil(teamKey.CoversScopesOf(parse("cluster=^team-a-,operation=relocate")))
+	test.S(t).ExpectNotNil(teamKey.CoversScopesOf(parse("operation=relocate")))
+	test.S(t).ExpectNotNil(teamKey.CoversScopesOf(parse("cluster=.*,operation=relocate")))
+
+	readOnlyKey := parse("read-only,operation=create-api-key")
+	test.S(t).ExpectNotNil(readOnlyKey.CoversScopesOf(parse("operation=create-api-key")))
+	test.S(t).ExpectNil(readOnlyKey.CoversScopesOf(parse("read-only,operation=create-api-key")))
+}
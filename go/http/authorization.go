@@ -0,0 +1,311 @@
+/*
+   Copyright 2014 Outbrain Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-martini/martini"
+	"github.com/martini-contrib/auth"
+	"github.com/martini-contrib/render"
+	"github.com/outbrain/golib/log"
+
+	"github.com/outbrain/orchestrator/go/config"
+	"github.com/outbrain/orchestrator/go/inst"
+	"github.com/outbrain/orchestrator/go/logic"
+)
+
+// Permission is what an API request requires of the calling user
+type Permission string
+
+const (
+	PermissionRead    Permission = "read"
+	PermissionOperate Permission = "operate"
+	PermissionRecover Permission = "recover"
+	PermissionAdmin   Permission = "admin"
+)
+
+// rolePermissions lists the permissions granted by each role. Roles are cumulative.
+var rolePermissions = map[string][]Permission{
+	"viewer":    {PermissionRead},
+	"operator":  {PermissionRead, PermissionOperate},
+	"recoverer": {PermissionRead, PermissionOperate, PermissionRecover},
+	"admin":     {PermissionRead, PermissionOperate, PermissionRecover, PermissionAdmin},
+}
+
+// instanceParams are route parameter pairs naming instances; a request touches the clusters of all
+// instances it names.
+var instanceParams = [][2]string{
+	{"host", "port"},
+	{"belowHost", "belowPort"},
+	{"siblingHost", "siblingPort"},
+	{"candidateHost", "candidatePort"},
+	{"donorHost", "donorPort"},
+}
+
+// isRoleBasedAccessControlEnabled returns true when role bindings are configured
+func isRoleBasedAccessControlEnabled() bool {
+	return len(config.Config.AuthorizationRoles) > 0
+}
+
+// getAuthorizationUser returns the authenticated user, as used for matching role bindings
+func getAuthorizationUser(req *http.Request, user auth.User) string {
+	switch strings.ToLower(config.Config.AuthenticationMethod) {
+	case "basic", "multi":
+		return string(user)
+	case "proxy":
+		return getProxyAuthUser(req)
+	case "oauth":
+		return getOIDCUser(req)
+	}
+	return ""
+}
+
+// getAuthorizationGroups returns the groups the authenticated user belongs to
+func getAuthorizationGroups(req *http.Request) (groups []string) {
+	if strings.ToLower(config.Config.AuthenticationMethod) == "oauth" {
+		return getOIDCGroups(req)
+	}
+	if config.Config.AuthGroupsHeader == "" {
+		return groups
+	}
+	if strings.ToLower(config.Config.AuthenticationMethod) != "proxy" {
+		return groups
+	}
+	for _, header := range req.Header[http.CanonicalHeaderKey(config.Config.AuthGroupsHeader)] {
+		for _, group := range strings.Split(header, ",") {
+			if group = strings.TrimSpace(group); group != "" {
+				groups = append(groups, group)
+			}
+		}
+	}
+	return groups
+}
+
+// roleBindingMatchesSubject checks whether given binding applies to given user or any of given groups.
+// An unauthenticated (empty) user is never matched.
+func roleBindingMatchesSubject(binding *config.RoleBinding, user string, groups []string) bool {
+	if user == "" {
+		return false
+	}
+	for _, bindingUser := range binding.Users {
+		if bindingUser == "*" || bindingUser == user {
+			return true
+		}
+	}
+	for _, bindingGroup := range binding.Groups {
+		for _, group := range groups {
+			if bindingGroup == group {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// roleBindingMatchesCluster checks whether given binding is in effect on given cluster.
+// An unscoped binding applies to all clusters, as well as to requests which do not refer to any cluster.
+// A scoped binding only applies to clusters whose name or alias match any of its patterns.
+func roleBindingMatchesCluster(binding *config.RoleBinding, clusterName string, clusterAlias string) bool {
+	if len(binding.ClusterPatterns) == 0 {
+		return true
+	}
+	if clusterName == "" {
+		return false
+	}
+	for _, pattern := range binding.ClusterPatterns {
+		if matched, _ := regexp.MatchString(pattern, clusterName); matched {
+			return true
+		}
+		if clusterAlias != "" {
+			if matched, _ := regexp.MatchString(pattern, clusterAlias); matched {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// roleGrantsPermission checks whether given role includes given permission
+func roleGrantsPermission(role string, permission Permission) bool {
+	for _, rolePermission := range rolePermissions[strings.ToLower(role)] {
+		if rolePermission == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// hasPermission checks whether the user/groups are granted a permission on given cluster.
+// An empty clusterName indicates the request does not refer to a specific cluster.
+func hasPermission(user string, groups []string, permission Permission, clusterName string, clusterAlias string) bool {
+	for i := range config.Config.AuthorizationRoles {
+		binding := &config.Config.AuthorizationRoles[i]
+		if !roleGrantsPermission(binding.Role, permission) {
+			continue
+		}
+		if !roleBindingMatchesSubject(binding, user, groups) {
+			continue
+		}
+		if clusterName == "" && permission == PermissionRead {
+			// Cluster-less read requests (e.g. listing clusters) are allowed to anyone who may read any cluster
+			return true
+		}
+		if roleBindingMatchesCluster(binding, clusterName, clusterAlias) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasPermissionOnAnyCluster checks whether the user/groups are granted a permission on at least one cluster.
+// This is what the UI uses to decide whether to offer actions at all; requirePermission() verifies the
+// actual clusters per request.
+func hasPermissionOnAnyCluster(user string, groups []string, permission Permission) bool {
+	for i := range config.Config.AuthorizationRoles {
+		binding := &config.Config.AuthorizationRoles[i]
+		if roleGrantsPermission(binding.Role, permission) && roleBindingMatchesSubject(binding, user, groups) {
+			return true
+		}
+	}
+	return false
+}
+
+// getRequestClusters returns the names of the clusters a request refers to, either by cluster name/alias
+// or via instances it names. Instances not known to orchestrator do not map to any cluster.
+func getRequestClusters(params martini.Params) (clusterNames []string) {
+	if clusterName := params["clusterName"]; clusterName != "" {
+		if resolved, err := inst.ReadClusterNameByAlias(clusterName); err == nil {
+			clusterName = resolved
+		}
+		clusterNames = append(clusterNames, clusterName)
+	}
+	if clusterAlias := params["clusterAlias"]; clusterAlias != "" {
+		if clusterName, err := inst.ReadClusterNameByAlias(clusterAlias); err == nil {
+			clusterNames = append(clusterNames, clusterName)
+		}
+	}
+	for _, instanceParam := range instanceParams {
+		if params[instanceParam[0]] == "" {
+			continue
+		}
+		instanceKey, err := inst.NewInstanceKeyFromStrings(params[instanceParam[0]], params[instanceParam[1]])
+		if err != nil {
+			continue
+		}
+		if clusterName, err := inst.GetClusterName(instanceKey); err == nil && clusterName != "" {
+			clusterNames = append(clusterNames, clusterName)
+		}
+	}
+	return clusterNames
+}
+
+// isAuthorizedForPermission checks whether the authenticated user is granted given permission on all clusters
+// the request refers to. With no role bindings configured, any permission beyond "read" falls back to isAuthorizedForAction.
+func isAuthorizedForPermission(params martini.Params, req *http.Request, user auth.User, permission Permission) (bool, error) {
+	if !isRoleBasedAccessControlEnabled() {
+		if permission == PermissionRead {
+			return true, nil
+		}
+		return isAuthorizedForAction(req, user), nil
+	}
+	if config.Config.ReadOnly && permission != PermissionRead {
+		return false, nil
+	}
+	authUser := getAuthorizationUser(req, user)
+	groups := getAuthorizationGroups(req)
+
+	clusterNames := getRequestClusters(params)
+	if len(clusterNames) == 0 {
+		return hasPermission(authUser, groups, permission, "", ""), nil
+	}
+	for _, clusterName := range clusterNames {
+		clusterAlias, err := inst.ReadAliasByClusterName(clusterName)
+		if err != nil {
+			return false, err
+		}
+		if !hasPermission(authUser, groups, permission, clusterName, clusterAlias) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// requirePermission returns a martini handler which rejects the request unless the authenticated user
+// is granted given permission. It is registered ahead of the API handler on each route.
+// Requests authenticated by an API key are subject to the key's scopes rather than to role bindings.
+func (this *HttpAPI) requirePermission(permission Permission) martini.Handler {
+	return func(params martini.Params, r render.Render, req *http.Request, user auth.User) {
+		if apiKey := getRequestAPIKey(req); apiKey != nil {
+			authorized, err := isAuthorizedForAPIKey(apiKey, params, req, permission)
+			if err != nil {
+				log.Errore(err)
+				r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("%+v", err)})
+				return
+			}
+			if !authorized {
+				r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("Unauthorized: API key %s is not scoped for %s", apiKey.Name, getAPIOperation(req))})
+				return
+			}
+			auditAPIKeyUsage(apiKey, params, req)
+			return
+		}
+		if !isRoleBasedAccessControlEnabled() {
+			// Handlers apply isAuthorizedForAction on their own
+			return
+		}
+		authorized, err := isAuthorizedForPermission(params, req, user, permission)
+		if err != nil {
+			log.Errore(err)
+			r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("%+v", err)})
+			return
+		}
+		if !authorized {
+			r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("Unauthorized: %s permission required", permission)})
+			return
+		}
+	}
+}
+
+// requireRollingRestartPermission requires permission on the cluster of the rolling restart of the request:
+// "operate", or "recover" when the rolling restart ends with a master takeover
+func (this *HttpAPI) requireRollingRestartPermission() martini.Handler {
+	return func(c martini.Context, params martini.Params, r render.Render) {
+		rollingRestartId, err := strconv.ParseInt(params["id"], 10, 0)
+		if err != nil {
+			r.JSON(200, &APIResponse{Code: ERROR, Message: err.Error()})
+			return
+		}
+		rollingRestart, err := logic.ReadRollingRestart(rollingRestartId)
+		if err != nil {
+			r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("%+v", err)})
+			return
+		}
+		params["clusterName"] = rollingRestart.ClusterName
+		permission := PermissionOperate
+		if rollingRestart.TakeoverMaster {
+			permission = PermissionRecover
+		}
+		if _, err := c.Invoke(this.requirePermission(permission)); err != nil {
+			log.Errore(err)
+		}
+	}
+}
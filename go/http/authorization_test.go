@@ -0,0 +1,102 @@
+package http
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/martini-contrib/auth"
+	test "github.com/outbrain/golib/tests"
+
+	"github.com/outbrain/orchestrator/go/config"
+)
+
+func TestRoleBindingMatchesSubject(t *testing.T) {
+	anyone := &config.RoleBinding{Role: "viewer", Users: []string{"*"}}
+	test.S(t).ExpectTrue(roleBindingMatchesSubject(anyone, "bob", nil))
+	test.S(t).ExpectFalse(roleBindingMatchesSubject(anyone, "", nil))
+
+	dba := &config.RoleBinding{Role: "admin", Users: []string{"alice"}, Groups: []string{"dba"}}
+	test.S(t).ExpectTrue(roleBindingMatchesSubject(dba, "alice", nil))
+	test.S(t).ExpectTrue(roleBindingMatchesSubject(dba, "bob", []string{"dev", "dba"}))
+	test.S(t).ExpectFalse(roleBindingMatchesSubject(dba, "bob", []string{"dev"}))
+	test.S(t).ExpectFalse(roleBindingMatchesSubject(dba, "", []string{"dba"}))
+}
+
+func TestRoleBindingMatchesCluster(t *testing.T) {
+	unscoped := &config.RoleBinding{Role: "operator"}
+	test.S(t).ExpectTrue(roleBindingMatchesCluster(unscoped, "db-1:3306", "orders"))
+	test.S(t).ExpectTrue(roleBindingMatchesCluster(unscoped, "", ""))
+
+	scoped := &config.RoleBinding{Role: "operator", ClusterPatterns: []string{"^team-a-", "^db-9:"}}
+	test.S(t).ExpectTrue(roleBindingMatchesCluster(scoped, "db-1:3306", "team-a-orders"))
+	test.S(t).ExpectTrue(roleBindingMatchesCluster(scoped, "db-9:3306", ""))
+	test.S(t).ExpectFalse(roleBindingMatchesCluster(scoped, "db-1:3306", "team-b-users"))
+	test.S(t).ExpectFalse(roleBindingMatchesCluster(scoped, "db-1:3306", ""))
+	test.S(t).ExpectFalse(roleBindingMatchesCluster(scoped, "", ""))
+}
+
+func TestHasPermission(t *testing.T) {
+	config.Config.AuthorizationRoles = []config.RoleBinding{
+		{Role: "viewer", Users: []string{"*"}},
+		{Role: "operator", Users: []string{"bob"}, ClusterPatterns: []string{"^team-a-"}},
+		{Role: "recoverer", Groups: []string{"oncall"}},
+	}
+	defer func() { config.Config.AuthorizationRoles = []config.RoleBinding{} }()
+
+	test.S(t).ExpectTrue(hasPermission("carol", nil, PermissionRead, "db-1:3306", "team-a-orders"))
+	test.S(t).ExpectTrue(hasPermission("carol", nil, PermissionRead, "", ""))
+	test.S(t).ExpectFalse(hasPermission("carol", nil, PermissionOperate, "db-1:3306", "team-a-orders"))
+	test.S(t).ExpectFalse(hasPermission("", nil, PermissionRead, "", ""))
+
+	test.S(t).ExpectTrue(hasPermission("bob", nil, PermissionOperate, "db-1:3306", "team-a-orders"))
+	test.S(t).ExpectFalse(hasPermission("bob", nil, PermissionOperate, "db-2:3306", "team-b-users"))
+	test.S(t).ExpectFalse(hasPermission("bob", nil, PermissionOperate, "", ""))
+	test.S(t).ExpectFalse(hasPermission("bob", nil, PermissionRecover, "db-1:3306", "team-a-orders"))
+
+	test.S(t).ExpectTrue(hasPermission("dave", []string{"oncall"}, PermissionRecover, "db-2:3306", ""))
+	test.S(t).ExpectTrue(hasPermission("dave", []string{"oncall"}, PermissionOperate, "", ""))
+	test.S(t).ExpectFalse(hasPermission("dave", []string{"oncall"}, PermissionAdmin, "", ""))
+
+	test.S(t).ExpectTrue(hasPermissionOnAnyCluster("bob", nil, PermissionOperate))
+	test.S(t).ExpectFalse(hasPermissionOnAnyCluster("carol", nil, PermissionOperate))
+}
+
+func TestIsAuthorizedForActionWithRoles(t *testing.T) {
+	config.Config.AuthenticationMethod = "multi"
+	config.Config.AuthorizationRoles = []config.RoleBinding{
+		{Role: "viewer", Users: []string{"*"}},
+		{Role: "operator", Users: []string{"bob"}, ClusterPatterns: []string{"^team-a-"}},
+	}
+	defer func() {
+		config.Config.AuthenticationMethod = ""
+		config.Config.AuthorizationRoles = []config.RoleBinding{}
+	}()
+
+	req := httptest.NewRequest("GET", "/web/clusters", nil)
+	test.S(t).ExpectTrue(isAuthorizedForAction(req, auth.User("bob")))
+	test.S(t).ExpectFalse(isAuthorizedForAction(req, auth.User("carol")))
+	test.S(t).ExpectFalse(isAuthorizedForAction(req, auth.User("")))
+}
+
+func TestGetAuthorizationGroups(t *testing.T) {
+	config.Config.AuthenticationMethod = "proxy"
+	config.Config.AuthGroupsHeader = "X-Groups"
+	defer func() {
+		config.Config.AuthenticationMethod = ""
+		config.Config.AuthGroupsHeader = ""
+	}()
+
+	req := httptest.NewRequest("GET", "/api/clusters", nil)
+	test.S(t).ExpectEquals(len(getAuthorizationGroups(req)), 0)
+	req.Header.Add("X-Groups", "dba, team-a,")
+	req.Header.Add("X-Groups", "oncall")
+	test.S(t).ExpectEquals(strings.Join(getAuthorizationGroups(req), ","), "dba,team-a,oncall")
+
+	config.Config.AuthenticationMethod = "basic"
+	test.S(t).ExpectEquals(len(getAuthorizationGroups(req)), 0)
+
+	config.Config.AuthenticationMethod = "proxy"
+	config.Config.AuthGroupsHeader = ""
+	test.S(t).ExpectEquals(len(getAuthorizationGroups(req)), 0)
+}
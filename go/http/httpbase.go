@@ -40,6 +40,14 @@ func isAuthorizedForAction(req *http.Request, user auth.User) bool {
 	if config.Config.ReadOnly {
 		return false
 	}
+	if apiKey := getRequestAPIKey(req); apiKey != nil {
+		// Scopes are verified per route, see requirePermission()
+		return !apiKey.ReadOnly
+	}
+	if isRoleBasedAccessControlEnabled() {
+		// Per cluster permissions are further verified per route, see requirePermission()
+		return hasPermissionOnAnyCluster(getAuthorizationUser(req, user), getAuthorizationGroups(req), PermissionOperate)
+	}
 
 	switch strings.ToLower(config.Config.AuthenticationMethod) {
 	case "basic":
@@ -80,6 +88,15 @@ func isAuthorizedForAction(req *http.Request, user auth.User) bool {
 		}
 	case "oauth":
 		{
+			authUser := getOIDCUser(req)
+			if authUser == "" {
+				return false
+			}
+			for _, configPowerAuthUser := range config.Config.PowerAuthUsers {
+				if configPowerAuthUser == "*" || configPowerAuthUser == authUser {
+					return true
+				}
+			}
 			return false
 		}
 	default:
@@ -106,6 +123,9 @@ func getUserId(req *http.Request, user auth.User) string {
 	if config.Config.ReadOnly {
 		return ""
 	}
+	if apiKey := getRequestAPIKey(req); apiKey != nil {
+		return apiKey.UserId()
+	}
 
 	switch strings.ToLower(config.Config.AuthenticationMethod) {
 	case "basic":
@@ -124,6 +144,10 @@ func getUserId(req *http.Request, user auth.User) string {
 		{
 			return ""
 		}
+	case "oauth":
+		{
+			return getOIDCUser(req)
+		}
 	default:
 		{
 			return ""
@@ -0,0 +1,580 @@
+/*
+   Copyright 2014 Outbrain Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package http
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-martini/martini"
+	"github.com/martini-contrib/auth"
+	"github.com/outbrain/golib/log"
+
+	"github.com/outbrain/orchestrator/go/config"
+	"github.com/outbrain/orchestrator/go/logic"
+	"github.com/outbrain/orchestrator/go/process"
+)
+
+const (
+	oidcSessionCookieName = "orchestrator-session"
+	oidcStateCookieName   = "orchestrator-oidc-state"
+	oidcStateExpiry       = 10 * time.Minute
+	oidcCSRFHeader        = "X-Requested-With"
+	oidcRequestTimeout    = 10 * time.Second
+
+	OIDCPathLogin    = "/login"
+	OIDCPathCallback = "/oauth2callback"
+	OIDCPathLogout   = "/logout"
+)
+
+// OIDCSession is the identity of a user logged in via OpenID Connect, as kept in a signed session cookie
+type OIDCSession struct {
+	User   string
+	Groups []string
+	Expiry int64
+}
+
+// oidcState is kept in a short lived signed cookie between login and callback
+type oidcState struct {
+	State  string
+	Nonce  string
+	Next   string
+	Expiry int64
+}
+
+// oidcProviderMetadata is the subset of the provider's discovery document we use
+type oidcProviderMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type oidcJSONWebKey struct {
+	KeyType string `json:"kty"`
+	KeyId   string `json:"kid"`
+	N       string `json:"n"`
+	E       string `json:"e"`
+}
+
+var (
+	oidcMutex          = &sync.Mutex{}
+	oidcProvider       *oidcProviderMetadata
+	oidcProviderIssuer string
+	oidcKeys           = map[string]*rsa.PublicKey{}
+	oidcHttpClient     = &http.Client{Timeout: oidcRequestTimeout}
+)
+
+func randomOIDCToken(size int) string {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		log.Errore(err)
+	}
+	return hex.EncodeToString(buf)
+}
+
+// oidcSessionKey is the key signing session and state cookies. It is shared by all orchestrator nodes,
+// and is required to be configured; see app.Http()
+func oidcSessionKey() []byte {
+	return []byte(config.Config.OAuthSessionSecret)
+}
+
+// signOIDCCookieValue returns a tamper proof encoding of given value
+func signOIDCCookieValue(value interface{}) (string, error) {
+	payload, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	encodedPayload := base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, oidcSessionKey())
+	mac.Write([]byte(encodedPayload))
+	return fmt.Sprintf("%s.%s", encodedPayload, base64.RawURLEncoding.EncodeToString(mac.Sum(nil))), nil
+}
+
+// verifyOIDCCookieValue validates the signature of a value encoded by signOIDCCookieValue, and decodes it
+func verifyOIDCCookieValue(signed string, value interface{}) error {
+	tokens := strings.Split(signed, ".")
+	if len(tokens) != 2 {
+		return fmt.Errorf("Malformed cookie value")
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(tokens[1])
+	if err != nil {
+		return err
+	}
+	mac := hmac.New(sha256.New, oidcSessionKey())
+	mac.Write([]byte(tokens[0]))
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return fmt.Errorf("Invalid cookie signature")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(tokens[0])
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(payload, value)
+}
+
+// setOIDCCookie sets a signed cookie. The session cookie is strict, and is never sent on cross site requests;
+// the state cookie is lax, as it must accompany the identity provider's redirect back to the callback.
+func setOIDCCookie(resp http.ResponseWriter, name string, value string, expiry time.Time, sameSite http.SameSite) {
+	http.SetCookie(resp, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		Expires:  expiry,
+		HttpOnly: true,
+		Secure:   config.Config.UseSSL,
+		SameSite: sameSite,
+	})
+}
+
+func clearOIDCCookie(resp http.ResponseWriter, name string) {
+	http.SetCookie(resp, &http.Cookie{Name: name, Value: "", Path: "/", MaxAge: -1, HttpOnly: true, Secure: config.Config.UseSSL})
+}
+
+// getOIDCSession returns the valid session of the request's user, or an error if the user is not logged in
+func getOIDCSession(req *http.Request) (*OIDCSession, error) {
+	cookie, err := req.Cookie(oidcSessionCookieName)
+	if err != nil {
+		return nil, err
+	}
+	session := &OIDCSession{}
+	if err := verifyOIDCCookieValue(cookie.Value, session); err != nil {
+		return nil, err
+	}
+	if time.Now().Unix() > session.Expiry {
+		return nil, fmt.Errorf("Session expired")
+	}
+	return session, nil
+}
+
+// getOIDCUser returns the logged in user, or empty string if no user is logged in
+func getOIDCUser(req *http.Request) string {
+	if session, err := getOIDCSession(req); err == nil {
+		return session.User
+	}
+	return ""
+}
+
+// getOIDCGroups returns the groups of the logged in user, as provided by the identity provider
+func getOIDCGroups(req *http.Request) []string {
+	if session, err := getOIDCSession(req); err == nil {
+		return session.Groups
+	}
+	return []string{}
+}
+
+func oidcGetJSON(uri string, value interface{}) error {
+	resp, err := oidcHttpClient.Get(uri)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned HTTP status %d", uri, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(value)
+}
+
+// getOIDCProvider returns the provider's metadata, read once per configured issuer via OpenID Connect discovery
+func getOIDCProvider() (*oidcProviderMetadata, error) {
+	oidcMutex.Lock()
+	defer oidcMutex.Unlock()
+
+	issuer := strings.TrimSuffix(config.Config.OAuthIssuerURL, "/")
+	if issuer == "" {
+		return nil, fmt.Errorf("OAuthIssuerURL is not configured")
+	}
+	if oidcProvider != nil && oidcProviderIssuer == issuer {
+		return oidcProvider, nil
+	}
+	provider := &oidcProviderMetadata{}
+	if err := oidcGetJSON(issuer+"/.well-known/openid-configuration", provider); err != nil {
+		return nil, err
+	}
+	if strings.TrimSuffix(provider.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("Provider issuer %s does not match OAuthIssuerURL %s", provider.Issuer, issuer)
+	}
+	oidcProvider = provider
+	oidcProviderIssuer = issuer
+	oidcKeys = map[string]*rsa.PublicKey{}
+	return oidcProvider, nil
+}
+
+// getOIDCKey returns the provider's signing key by key id. Keys are re-read on unknown key id, so as to follow key rotation.
+func getOIDCKey(provider *oidcProviderMetadata, keyId string) (*rsa.PublicKey, error) {
+	oidcMutex.Lock()
+	defer oidcMutex.Unlock()
+
+	if key, found := oidcKeys[keyId]; found {
+		return key, nil
+	}
+	var keySet struct {
+		Keys []oidcJSONWebKey `json:"keys"`
+	}
+	if err := oidcGetJSON(provider.JWKSURI, &keySet); err != nil {
+		return nil, err
+	}
+	keys := map[string]*rsa.PublicKey{}
+	for _, jwk := range keySet.Keys {
+		if jwk.KeyType != "RSA" {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		keys[jwk.KeyId] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+	oidcKeys = keys
+	if key, found := oidcKeys[keyId]; found {
+		return key, nil
+	}
+	return nil, fmt.Errorf("Unknown ID token signing key: %s", keyId)
+}
+
+// verifyOIDCIdToken validates an RS256 signed ID token: signature, issuer, audience, expiry and nonce, and returns its claims
+func verifyOIDCIdToken(provider *oidcProviderMetadata, idToken string, nonce string) (claims map[string]interface{}, err error) {
+	tokens := strings.Split(idToken, ".")
+	if len(tokens) != 3 {
+		return claims, fmt.Errorf("Malformed ID token")
+	}
+	var header struct {
+		Algorithm string `json:"alg"`
+		KeyId     string `json:"kid"`
+	}
+	headerPayload, err := base64.RawURLEncoding.DecodeString(tokens[0])
+	if err != nil {
+		return claims, err
+	}
+	if err := json.Unmarshal(headerPayload, &header); err != nil {
+		return claims, err
+	}
+	if header.Algorithm != "RS256" {
+		return claims, fmt.Errorf("Unsupported ID token algorithm: %s", header.Algorithm)
+	}
+	key, err := getOIDCKey(provider, header.KeyId)
+	if err != nil {
+		return claims, err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(tokens[2])
+	if err != nil {
+		return claims, err
+	}
+	digest := sha256.Sum256([]byte(tokens[0] + "." + tokens[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+		return claims, fmt.Errorf("Invalid ID token signature: %+v", err)
+	}
+
+	claimsPayload, err := base64.RawURLEncoding.DecodeString(tokens[1])
+	if err != nil {
+		return claims, err
+	}
+	if err := json.Unmarshal(claimsPayload, &claims); err != nil {
+		return claims, err
+	}
+	if issuer, _ := claims["iss"].(string); issuer != provider.Issuer {
+		return claims, fmt.Errorf("Unexpected ID token issuer: %s", issuer)
+	}
+	if !oidcClaimContains(claims["aud"], config.Config.OAuthClientId) {
+		return claims, fmt.Errorf("ID token not issued for client %s", config.Config.OAuthClientId)
+	}
+	if expiry, _ := claims["exp"].(float64); int64(expiry) < time.Now().Unix() {
+		return claims, fmt.Errorf("ID token expired")
+	}
+	if claimedNonce, _ := claims["nonce"].(string); claimedNonce != nonce {
+		return claims, fmt.Errorf("ID token nonce mismatch")
+	}
+	return claims, nil
+}
+
+// oidcClaimStrings returns a claim as list of strings; claims may be either a single string or a list
+func oidcClaimStrings(claim interface{}) (values []string) {
+	switch claim := claim.(type) {
+	case string:
+		values = append(values, claim)
+	case []interface{}:
+		for _, value := range claim {
+			if value, ok := value.(string); ok {
+				values = append(values, value)
+			}
+		}
+	}
+	return values
+}
+
+func oidcClaimContains(claim interface{}, expected string) bool {
+	for _, value := range oidcClaimStrings(claim) {
+		if value == expected {
+			return true
+		}
+	}
+	return false
+}
+
+// newOIDCSession builds a session out of ID token claims
+func newOIDCSession(claims map[string]interface{}) (*OIDCSession, error) {
+	user, _ := claims[config.Config.OAuthUserClaim].(string)
+	if user == "" {
+		user, _ = claims["sub"].(string)
+	}
+	if user == "" {
+		return nil, fmt.Errorf("ID token has neither %s nor sub claims", config.Config.OAuthUserClaim)
+	}
+	session := &OIDCSession{
+		User:   user,
+		Groups: oidcClaimStrings(claims[config.Config.OAuthGroupsClaim]),
+		Expiry: time.Now().Add(time.Duration(config.Config.OAuthSessionExpiryMinutes) * time.Minute).Unix(),
+	}
+	return session, nil
+}
+
+func getOIDCRedirectURL(req *http.Request) string {
+	if config.Config.OAuthRedirectURL != "" {
+		return config.Config.OAuthRedirectURL
+	}
+	scheme := "http"
+	if req.TLS != nil {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s%s%s", scheme, req.Host, config.Config.URLPrefix, OIDCPathCallback)
+}
+
+// sanitizeOIDCNext only allows redirecting back to local paths
+func sanitizeOIDCNext(next string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		return config.Config.URLPrefix + "/"
+	}
+	return next
+}
+
+// exchangeOIDCCode exchanges an authorization code for the ID token
+func exchangeOIDCCode(provider *oidcProviderMetadata, code string, redirectURL string) (idToken string, err error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", redirectURL)
+	tokenRequest, err := http.NewRequest("POST", provider.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	tokenRequest.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	tokenRequest.SetBasicAuth(url.QueryEscape(config.Config.OAuthClientId), url.QueryEscape(config.Config.OAuthClientSecret))
+
+	resp, err := oidcHttpClient.Do(tokenRequest)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	var tokenResponse struct {
+		IdToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResponse); err != nil {
+		return "", err
+	}
+	if tokenResponse.Error != "" {
+		return "", fmt.Errorf("Token endpoint error: %s %s", tokenResponse.Error, tokenResponse.ErrorDescription)
+	}
+	if tokenResponse.IdToken == "" {
+		return "", fmt.Errorf("Token endpoint returned no id_token")
+	}
+	return tokenResponse.IdToken, nil
+}
+
+// OIDCLogin redirects the user to the identity provider's authorization endpoint
+func OIDCLogin(resp http.ResponseWriter, req *http.Request) {
+	provider, err := getOIDCProvider()
+	if err != nil {
+		log.Errore(err)
+		http.Error(resp, "OpenID Connect provider unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	state := oidcState{
+		State:  randomOIDCToken(16),
+		Nonce:  randomOIDCToken(16),
+		Next:   sanitizeOIDCNext(req.URL.Query().Get("next")),
+		Expiry: time.Now().Add(oidcStateExpiry).Unix(),
+	}
+	signedState, err := signOIDCCookieValue(state)
+	if err != nil {
+		http.Error(resp, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	setOIDCCookie(resp, oidcStateCookieName, signedState, time.Now().Add(oidcStateExpiry), http.SameSiteLaxMode)
+
+	scopes := []string{"openid"}
+	for _, scope := range config.Config.OAuthScopes {
+		if scope != "openid" {
+			scopes = append(scopes, scope)
+		}
+	}
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", config.Config.OAuthClientId)
+	query.Set("redirect_uri", getOIDCRedirectURL(req))
+	query.Set("scope", strings.Join(scopes, " "))
+	query.Set("state", state.State)
+	query.Set("nonce", state.Nonce)
+
+	separator := "?"
+	if strings.Contains(provider.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	http.Redirect(resp, req, provider.AuthorizationEndpoint+separator+query.Encode(), http.StatusFound)
+}
+
+// OIDCCallback completes the authorization code flow and establishes the user's session
+func OIDCCallback(resp http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+	if errorCode := query.Get("error"); errorCode != "" {
+		http.Error(resp, fmt.Sprintf("Login failed: %s %s", errorCode, query.Get("error_description")), http.StatusUnauthorized)
+		return
+	}
+	stateCookie, err := req.Cookie(oidcStateCookieName)
+	if err != nil {
+		http.Error(resp, "Login state not found", http.StatusBadRequest)
+		return
+	}
+	clearOIDCCookie(resp, oidcStateCookieName)
+	state := oidcState{}
+	if err := verifyOIDCCookieValue(stateCookie.Value, &state); err != nil {
+		http.Error(resp, "Invalid login state", http.StatusBadRequest)
+		return
+	}
+	if time.Now().Unix() > state.Expiry || !hmac.Equal([]byte(query.Get("state")), []byte(state.State)) {
+		http.Error(resp, "Login state mismatch", http.StatusBadRequest)
+		return
+	}
+
+	provider, err := getOIDCProvider()
+	if err != nil {
+		log.Errore(err)
+		http.Error(resp, "OpenID Connect provider unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	idToken, err := exchangeOIDCCode(provider, query.Get("code"), getOIDCRedirectURL(req))
+	if err != nil {
+		log.Errore(err)
+		http.Error(resp, "Login failed", http.StatusUnauthorized)
+		return
+	}
+	claims, err := verifyOIDCIdToken(provider, idToken, state.Nonce)
+	if err != nil {
+		log.Errore(err)
+		http.Error(resp, "Login failed", http.StatusUnauthorized)
+		return
+	}
+	session, err := newOIDCSession(claims)
+	if err != nil {
+		log.Errore(err)
+		http.Error(resp, "Login failed", http.StatusUnauthorized)
+		return
+	}
+	signedSession, err := signOIDCCookieValue(session)
+	if err != nil {
+		http.Error(resp, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	setOIDCCookie(resp, oidcSessionCookieName, signedSession, time.Unix(session.Expiry, 0), http.SameSiteStrictMode)
+	log.Infof("OpenID Connect login: %s", session.User)
+	// The callback is reached by a cross site redirect, on which a strict cookie is not sent. Redirecting from
+	// within a page makes for a same site navigation, which carries the new session cookie.
+	resp.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprintf(resp, `<html><head><meta http-equiv="refresh" content="0;url=%s"></head><body><a href="%s">Continue</a></body></html>`,
+		html.EscapeString(state.Next), html.EscapeString(state.Next))
+}
+
+// OIDCLogout ends the user's session
+func OIDCLogout(resp http.ResponseWriter, req *http.Request) {
+	clearOIDCCookie(resp, oidcSessionCookieName)
+	http.Redirect(resp, req, config.Config.URLPrefix+"/", http.StatusFound)
+}
+
+// tokenBelongsToHealthyNode checks the node token of peer orchestrator requests
+var tokenBelongsToHealthyNode = process.TokenBelongsToHealthyHttpService
+
+// isPeerNodeRequest returns true for a liveness probe requested by a healthy orchestrator node, which
+// authenticates by its node token rather than by a user session
+func isPeerNodeRequest(req *http.Request) bool {
+	if !strings.HasPrefix(req.URL.Path, config.Config.URLPrefix+"/api/probe-instance-liveness/") {
+		return false
+	}
+	token := req.Header.Get(logic.FailureConfirmationNodeTokenHeader)
+	if token == "" {
+		return false
+	}
+	isHealthyNode, _ := tokenBelongsToHealthyNode(token)
+	return isHealthyNode
+}
+
+// OIDCAuthenticate is a martini handler requiring a logged in user. Web pages are redirected to the login
+// page, whereas API requests are rejected with 401.
+// API requests authenticated by the session cookie must further carry the X-Requested-With header, which
+// cross site forms and links cannot set. The web interface's jQuery requests send it implicitly.
+// Liveness probes by peer orchestrator nodes are authenticated by node token.
+func OIDCAuthenticate(c martini.Context, resp http.ResponseWriter, req *http.Request) {
+	switch req.URL.Path {
+	case config.Config.URLPrefix + OIDCPathLogin, config.Config.URLPrefix + OIDCPathCallback, config.Config.URLPrefix + OIDCPathLogout:
+		c.Map(auth.User(""))
+		return
+	}
+	if isPeerNodeRequest(req) {
+		c.Map(auth.User(""))
+		return
+	}
+	isAPIRequest := strings.HasPrefix(req.URL.Path, config.Config.URLPrefix+"/api/")
+	if session, err := getOIDCSession(req); err == nil {
+		if isAPIRequest && req.Header.Get(oidcCSRFHeader) == "" {
+			writeAPIError(resp, http.StatusForbidden, fmt.Sprintf("Missing %s header", oidcCSRFHeader))
+			return
+		}
+		c.Map(auth.User(session.User))
+		return
+	}
+	if isAPIRequest {
+		writeAPIError(resp, http.StatusUnauthorized, "Unauthenticated")
+		return
+	}
+	loginURL := fmt.Sprintf("%s%s?next=%s", config.Config.URLPrefix, OIDCPathLogin, url.QueryEscape(req.URL.RequestURI()))
+	http.Redirect(resp, req, loginURL, http.StatusFound)
+}
+
+// RegisterOIDCRequests registers the login flow endpoints
+func RegisterOIDCRequests(m *martini.ClassicMartini) {
+	m.Get(config.Config.URLPrefix+OIDCPathLogin, OIDCLogin)
+	m.Get(config.Config.URLPrefix+OIDCPathCallback, OIDCCallback)
+	m.Get(config.Config.URLPrefix+OIDCPathLogout, OIDCLogout)
+}
@@ -0,0 +1,310 @@
+package http
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-martini/martini"
+	"github.com/martini-contrib/auth"
+	test "github.com/outbrain/golib/tests"
+
+	"github.com/outbrain/orchestrator/go/config"
+)
+
+const mockIdPKeyId = "mock-key"
+
+// mockIdP is a minimal OpenID Connect provider, issuing ID tokens for a fixed identity
+type mockIdP struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	nonces map[string]string
+	claims map[string]interface{}
+}
+
+func newMockIdP(t *testing.T) *mockIdP {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	test.S(t).ExpectNil(err)
+	idp := &mockIdP{key: key, nonces: map[string]string{}}
+	idp.claims = map[string]interface{}{"email": "alice@example.com", "groups": []string{"dba", "team-a"}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(resp http.ResponseWriter, req *http.Request) {
+		json.NewEncoder(resp).Encode(map[string]string{
+			"issuer":                 idp.server.URL,
+			"authorization_endpoint": idp.server.URL + "/authorize",
+			"token_endpoint":         idp.server.URL + "/token",
+			"jwks_uri":               idp.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(resp http.ResponseWriter, req *http.Request) {
+		json.NewEncoder(resp).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": mockIdPKeyId,
+				"n":   base64.RawURLEncoding.EncodeToString(key.PublicKey.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.PublicKey.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/authorize", func(resp http.ResponseWriter, req *http.Request) {
+		query := req.URL.Query()
+		code := fmt.Sprintf("code-%d", len(idp.nonces))
+		idp.nonces[code] = query.Get("nonce")
+		http.Redirect(resp, req, fmt.Sprintf("%s?code=%s&state=%s", query.Get("redirect_uri"), code, query.Get("state")), http.StatusFound)
+	})
+	mux.HandleFunc("/token", func(resp http.ResponseWriter, req *http.Request) {
+		clientId, clientSecret, _ := req.BasicAuth()
+		nonce, found := idp.nonces[req.FormValue("code")]
+		if !found || clientId != config.Config.OAuthClientId || clientSecret != config.Config.OAuthClientSecret {
+			json.NewEncoder(resp).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		json.NewEncoder(resp).Encode(map[string]string{"id_token": idp.signIdToken(t, nonce, config.Config.OAuthClientId)})
+	})
+	idp.server = httptest.NewServer(mux)
+	return idp
+}
+
+func (idp *mockIdP) signIdToken(t *testing.T, nonce string, audience string) string {
+	claims := map[string]interface{}{
+		"iss":   idp.server.URL,
+		"sub":   "alice",
+		"aud":   audience,
+		"exp":   time.Now().Add(time.Minute).Unix(),
+		"nonce": nonce,
+	}
+	for name, value := range idp.claims {
+		claims[name] = value
+	}
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": mockIdPKeyId})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, idp.key, crypto.SHA256, digest[:])
+	test.S(t).ExpectNil(err)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func configureMockIdP(idp *mockIdP) {
+	config.Config.AuthenticationMethod = "oauth"
+	config.Config.OAuthIssuerURL = idp.server.URL
+	config.Config.OAuthClientId = "orchestrator"
+	config.Config.OAuthClientSecret = "s3cr3t"
+	config.Config.OAuthRedirectURL = "http://orchestrator.test/oauth2callback"
+	config.Config.OAuthSessionSecret = "shared-session-secret"
+	config.Config.URLPrefix = ""
+}
+
+func getResponseCookie(resp *httptest.ResponseRecorder, name string) *http.Cookie {
+	for _, cookie := range resp.Result().Cookies() {
+		if cookie.Name == name {
+			return cookie
+		}
+	}
+	return nil
+}
+
+// loginViaMockIdP runs the full authorization code flow and returns the session cookie
+func loginViaMockIdP(t *testing.T) *http.Cookie {
+	loginResp := httptest.NewRecorder()
+	OIDCLogin(loginResp, httptest.NewRequest("GET", "/login?next=/web/clusters", nil))
+	test.S(t).ExpectEquals(loginResp.Code, http.StatusFound)
+	stateCookie := getResponseCookie(loginResp, oidcStateCookieName)
+	test.S(t).ExpectNotNil(stateCookie)
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	authorizeResp, err := client.Get(loginResp.Header().Get("Location"))
+	test.S(t).ExpectNil(err)
+	callbackURL, err := url.Parse(authorizeResp.Header.Get("Location"))
+	test.S(t).ExpectNil(err)
+
+	callbackReq := httptest.NewRequest("GET", "/oauth2callback?"+callbackURL.RawQuery, nil)
+	callbackReq.AddCookie(stateCookie)
+	callbackResp := httptest.NewRecorder()
+	OIDCCallback(callbackResp, callbackReq)
+	test.S(t).ExpectEquals(callbackResp.Code, http.StatusOK)
+	test.S(t).ExpectTrue(strings.Contains(callbackResp.Body.String(), `content="0;url=/web/clusters"`))
+	sessionCookie := getResponseCookie(callbackResp, oidcSessionCookieName)
+	test.S(t).ExpectNotNil(sessionCookie)
+	test.S(t).ExpectEquals(sessionCookie.SameSite, http.SameSiteStrictMode)
+	return sessionCookie
+}
+
+func TestOIDCLoginFlow(t *testing.T) {
+	idp := newMockIdP(t)
+	defer idp.server.Close()
+	configureMockIdP(idp)
+
+	sessionCookie := loginViaMockIdP(t)
+	test.S(t).ExpectNotNil(sessionCookie)
+
+	req := httptest.NewRequest("GET", "/api/clusters", nil)
+	req.AddCookie(sessionCookie)
+	test.S(t).ExpectEquals(getOIDCUser(req), "alice@example.com")
+	test.S(t).ExpectEquals(getUserId(req, auth.User("")), "alice@example.com")
+	test.S(t).ExpectEquals(strings.Join(getAuthorizationGroups(req), ","), "dba,team-a")
+	test.S(t).ExpectTrue(isAuthorizedForAction(req, auth.User("")))
+}
+
+func TestOIDCCallbackStateMismatch(t *testing.T) {
+	idp := newMockIdP(t)
+	defer idp.server.Close()
+	configureMockIdP(idp)
+
+	loginResp := httptest.NewRecorder()
+	OIDCLogin(loginResp, httptest.NewRequest("GET", "/login", nil))
+	callbackReq := httptest.NewRequest("GET", "/oauth2callback?code=code-0&state=forged", nil)
+	callbackReq.AddCookie(getResponseCookie(loginResp, oidcStateCookieName))
+	callbackResp := httptest.NewRecorder()
+	OIDCCallback(callbackResp, callbackReq)
+	test.S(t).ExpectEquals(callbackResp.Code, http.StatusBadRequest)
+	test.S(t).ExpectTrue(getResponseCookie(callbackResp, oidcSessionCookieName) == nil)
+}
+
+func TestOIDCVerifyIdToken(t *testing.T) {
+	idp := newMockIdP(t)
+	defer idp.server.Close()
+	configureMockIdP(idp)
+	provider, err := getOIDCProvider()
+	test.S(t).ExpectNil(err)
+
+	claims, err := verifyOIDCIdToken(provider, idp.signIdToken(t, "n1", "orchestrator"), "n1")
+	test.S(t).ExpectNil(err)
+	test.S(t).ExpectEquals(claims["email"], "alice@example.com")
+
+	_, err = verifyOIDCIdToken(provider, idp.signIdToken(t, "n1", "orchestrator"), "n2")
+	test.S(t).ExpectNotNil(err)
+	_, err = verifyOIDCIdToken(provider, idp.signIdToken(t, "n1", "other-client"), "n1")
+	test.S(t).ExpectNotNil(err)
+
+	tokens := strings.Split(idp.signIdToken(t, "n1", "orchestrator"), ".")
+	forgedPayload := base64.RawURLEncoding.EncodeToString([]byte(`{"email":"mallory@example.com"}`))
+	_, err = verifyOIDCIdToken(provider, tokens[0]+"."+forgedPayload+"."+tokens[2], "n1")
+	test.S(t).ExpectNotNil(err)
+}
+
+func TestOIDCSessionTampering(t *testing.T) {
+	config.Config.OAuthSessionSecret = "shared-session-secret"
+	signed, err := signOIDCCookieValue(&OIDCSession{User: "alice", Expiry: time.Now().Add(time.Minute).Unix()})
+	test.S(t).ExpectNil(err)
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.AddCookie(&http.Cookie{Name: oidcSessionCookieName, Value: signed})
+	test.S(t).ExpectEquals(getOIDCUser(req), "alice")
+
+	forged, _ := json.Marshal(&OIDCSession{User: "mallory", Expiry: time.Now().Add(time.Minute).Unix()})
+	forgedValue := base64.RawURLEncoding.EncodeToString(forged) + signed[strings.Index(signed, "."):]
+	req = httptest.NewRequest("GET", "/", nil)
+	req.AddCookie(&http.Cookie{Name: oidcSessionCookieName, Value: forgedValue})
+	test.S(t).ExpectEquals(getOIDCUser(req), "")
+
+	expired, _ := signOIDCCookieValue(&OIDCSession{User: "alice", Expiry: time.Now().Add(-time.Minute).Unix()})
+	req = httptest.NewRequest("GET", "/", nil)
+	req.AddCookie(&http.Cookie{Name: oidcSessionCookieName, Value: expired})
+	test.S(t).ExpectEquals(getOIDCUser(req), "")
+
+	config.Config.OAuthSessionSecret = "other-node-secret"
+	req = httptest.NewRequest("GET", "/", nil)
+	req.AddCookie(&http.Cookie{Name: oidcSessionCookieName, Value: signed})
+	test.S(t).ExpectEquals(getOIDCUser(req), "")
+}
+
+func TestOIDCAuthenticate(t *testing.T) {
+	idp := newMockIdP(t)
+	defer idp.server.Close()
+	configureMockIdP(idp)
+
+	router := martini.NewRouter()
+	router.Get("/api/whoami", func(user auth.User) string { return string(user) })
+	router.Get("/web/clusters", func(user auth.User) string { return string(user) })
+	m := martini.New()
+	m.Use(OIDCAuthenticate)
+	m.Action(router.Handle)
+
+	resp := httptest.NewRecorder()
+	m.ServeHTTP(resp, httptest.NewRequest("GET", "/api/whoami", nil))
+	test.S(t).ExpectEquals(resp.Code, http.StatusUnauthorized)
+
+	resp = httptest.NewRecorder()
+	m.ServeHTTP(resp, httptest.NewRequest("GET", "/web/clusters", nil))
+	test.S(t).ExpectEquals(resp.Code, http.StatusFound)
+	test.S(t).ExpectEquals(resp.Header().Get("Location"), "/login?next=%2Fweb%2Fclusters")
+
+	sessionCookie := loginViaMockIdP(t)
+	req := httptest.NewRequest("GET", "/api/whoami", nil)
+	req.AddCookie(sessionCookie)
+	resp = httptest.NewRecorder()
+	m.ServeHTTP(resp, req)
+	test.S(t).ExpectEquals(resp.Code, http.StatusForbidden)
+
+	req = httptest.NewRequest("GET", "/api/whoami", nil)
+	req.AddCookie(sessionCookie)
+	req.Header.Set("X-Requested-With", "XMLHttpRequest")
+	resp = httptest.NewRecorder()
+	m.ServeHTTP(resp, req)
+	test.S(t).ExpectEquals(resp.Code, http.StatusOK)
+	test.S(t).ExpectEquals(resp.Body.String(), "alice@example.com")
+
+	req = httptest.NewRequest("GET", "/web/clusters", nil)
+	req.AddCookie(sessionCookie)
+	resp = httptest.NewRecorder()
+	m.ServeHTTP(resp, req)
+	test.S(t).ExpectEquals(resp.Code, http.StatusOK)
+}
+
+func TestOIDCGroupRoleBinding(t *testing.T) {
+	config.Config.AuthorizationRoles = []config.RoleBinding{
+		{Role: "admin", Groups: []string{"dba"}},
+		{Role: "operator", Groups: []string{"team-a"}, ClusterPatterns: []string{"^team-a-"}},
+	}
+	defer func() { config.Config.AuthorizationRoles = []config.RoleBinding{} }()
+
+	test.S(t).ExpectTrue(hasPermission("bob", []string{"team-a"}, PermissionOperate, "db-1:3306", "team-a-orders"))
+	test.S(t).ExpectFalse(hasPermission("bob", []string{"team-a"}, PermissionOperate, "db-2:3306", "team-b-users"))
+	test.S(t).ExpectFalse(hasPermission("bob", []string{"team-a"}, PermissionRecover, "db-1:3306", "team-a-orders"))
+	test.S(t).ExpectFalse(hasPermission("bob", []string{"team-a"}, PermissionOperate, "", ""))
+	test.S(t).ExpectTrue(hasPermission("bob", []string{"team-a"}, PermissionRead, "", ""))
+	test.S(t).ExpectTrue(hasPermission("alice", []string{"dba"}, PermissionAdmin, "", ""))
+	test.S(t).ExpectFalse(hasPermission("carol", []string{}, PermissionRead, "", ""))
+}
+
+func TestOIDCAuthenticatePeerNodeProbe(t *testing.T) {
+	defer func(f func(string) (bool, error)) { tokenBelongsToHealthyNode = f }(tokenBelongsToHealthyNode)
+	tokenBelongsToHealthyNode = func(token string) (bool, error) { return token == "healthy-node-token", nil }
+
+	router := martini.NewRouter()
+	router.Get("/api/probe-instance-liveness/:host/:port", func(params martini.Params) string { return params["host"] })
+	router.Get("/api/whoami", func(user auth.User) string { return string(user) })
+	m := martini.New()
+	m.Use(UnlessAPIKey(OIDCAuthenticate))
+	m.Action(router.Handle)
+
+	probe := func(path string, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		if token != "" {
+			req.Header.Set("X-Orchestrator-Node-Token", token)
+		}
+		resp := httptest.NewRecorder()
+		m.ServeHTTP(resp, req)
+		return resp
+	}
+	resp := probe("/api/probe-instance-liveness/db1/3306", "healthy-node-token")
+	test.S(t).ExpectEquals(resp.Code, http.StatusOK)
+	test.S(t).ExpectEquals(resp.Body.String(), "db1")
+
+	test.S(t).ExpectEquals(probe("/api/probe-instance-liveness/db1/3306", "").Code, http.StatusUnauthorized)
+	test.S(t).ExpectEquals(probe("/api/probe-instance-liveness/db1/3306", "unknown-token").Code, http.StatusUnauthorized)
+	test.S(t).ExpectEquals(probe("/api/whoami", "healthy-node-token").Code, http.StatusUnauthorized)
+}
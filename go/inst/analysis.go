@@ -57,6 +57,8 @@ const (
 	StatementAndRowLoggingSlavesStructureWarning                         = "StatementAndRowLoggingSlavesStructureWarning"
 	MixedAndRowLoggingSlavesStructureWarning                             = "MixedAndRowLoggingSlavesStructureWarning"
 	MultipleMajorVersionsLoggingSlaves                                   = "MultipleMajorVersionsLoggingSlaves"
+	AllMasterSlavesDelayedStructureWarning                               = "AllMasterSlavesDelayedStructureWarning"
+	PseudoGTIDInjectionFailingWarning                                    = "PseudoGTIDInjectionFailingWarning"
 )
 
 // ReplicationAnalysis notes analysis on replication chain status, per instance
@@ -72,6 +74,8 @@ type ReplicationAnalysis struct {
 	CountValidReplicatingSlaves             uint
 	CountSlavesFailingToConnectToMaster     uint
 	CountStaleSlaves                        uint
+	CountDelayedSlaves                      uint
+	IsPseudoGTIDInjectionFailing            bool
 	ReplicationDepth                        uint
 	SlaveHosts                              InstanceKeyMap
 	IsFailingToConnectToMaster              bool
@@ -90,6 +94,17 @@ type ReplicationAnalysis struct {
 	CountMixedBasedLoggingSlaves            uint
 	CountRowBasedLoggingSlaves              uint
 	CountDistinctMajorVersionsLoggingSlaves uint
+	AgentHostCheck                          *AgentHostCheck
+}
+
+// AgentHostCheck is what the orchestrator-agent on a failed instance's host has to tell about it
+type AgentHostCheck struct {
+	HasAgent      bool     // Is there an agent registered for the host
+	HostReachable bool     // Did the agent respond
+	Unauthorized  bool     // Did the agent reject its token, in which case mysqld's state is unknown
+	MySQLRunning  bool     // Does the agent report mysqld as running
+	ErrorLogTail  []string // Last lines of the MySQL error log, as reported by the agent
+	Message       string
 }
 
 type ReplicationAnalysisChangelog struct {
@@ -42,7 +42,7 @@ var recentInstantAnalysis = cache.New(time.Duration(config.Config.RecoveryPollSe
 func GetReplicationAnalysis(clusterName string, includeDowntimed bool, auditAnalysis bool) ([]ReplicationAnalysis, error) {
 	result := []ReplicationAnalysis{}
 
-	args := sqlutils.Args(config.Config.InstancePollSeconds, clusterName)
+	args := sqlutils.Args(config.Config.InstancePollSeconds, config.Config.PseudoGTIDInjectionIntervalSeconds, clusterName)
 	analysisQueryReductionClause := ``
 	if config.Config.ReduceReplicationAnalysisCount {
 		analysisQueryReductionClause = `
@@ -109,6 +109,12 @@ func GetReplicationAnalysis(clusterName string, includeDowntimed bool, auditAnal
 									and current_relay_log_pos=prev_relay_log_pos
 									and current_seen != prev_seen),
 								0) AS count_stale_slaves,
+						IFNULL(SUM(slave_instance.sql_delay > 0),
+								0) AS count_delayed_slaves,
+						MIN(
+								database_instance_pseudo_gtid_injection.is_successful = 0
+								AND database_instance_pseudo_gtid_injection.last_attempted_timestamp >= NOW() - INTERVAL (3 * ?) SECOND
+							) IS TRUE AS is_pseudo_gtid_injection_failing,
 		        MIN(master_instance.replication_depth) AS replication_depth,
 		        GROUP_CONCAT(slave_instance.Hostname, ':', slave_instance.Port) as slave_hosts,
 		        MIN(
@@ -191,6 +197,9 @@ func GetReplicationAnalysis(clusterName string, includeDowntimed bool, auditAnal
 		        		AND database_instance_downtime.downtime_active = 1)
 		        	LEFT JOIN
 		        cluster_alias ON (cluster_alias.cluster_name = master_instance.cluster_name)
+		        	LEFT JOIN
+		        database_instance_pseudo_gtid_injection ON (master_instance.hostname = database_instance_pseudo_gtid_injection.hostname
+		        		AND master_instance.port = database_instance_pseudo_gtid_injection.port)
 						  LEFT JOIN
 						database_instance_recent_relaylog_history ON (
 								slave_instance.hostname = database_instance_recent_relaylog_history.hostname
@@ -222,6 +231,8 @@ func GetReplicationAnalysis(clusterName string, includeDowntimed bool, auditAnal
 		a.CountValidReplicatingSlaves = m.GetUint("count_valid_replicating_slaves")
 		a.CountSlavesFailingToConnectToMaster = m.GetUint("count_slaves_failing_to_connect_to_master")
 		a.CountStaleSlaves = m.GetUint("count_stale_slaves")
+		a.CountDelayedSlaves = m.GetUint("count_delayed_slaves")
+		a.IsPseudoGTIDInjectionFailing = m.GetBool("is_pseudo_gtid_injection_failing")
 		a.ReplicationDepth = m.GetUint("replication_depth")
 		a.IsFailingToConnectToMaster = m.GetBool("is_failing_to_connect_to_master")
 		a.IsDowntimed = m.GetBool("is_downtimed")
@@ -386,6 +397,14 @@ func GetReplicationAnalysis(clusterName string, includeDowntimed bool, auditAnal
 			if a.IsMaster && a.CountDistinctMajorVersionsLoggingSlaves > 1 {
 				a.StructureAnalysis = append(a.StructureAnalysis, MultipleMajorVersionsLoggingSlaves)
 			}
+			if a.IsMaster && a.CountSlaves > 0 && a.CountDelayedSlaves == a.CountSlaves && config.Config.PromotionIgnoreDelayedSlaves {
+				// None of the slaves can be promoted on master failure
+				a.StructureAnalysis = append(a.StructureAnalysis, AllMasterSlavesDelayedStructureWarning)
+			}
+			if a.IsPseudoGTIDInjectionFailing && config.Config.AutoPseudoGTID {
+				// Slaves of this master cannot rely on Pseudo-GTID for recent binlog entries
+				a.StructureAnalysis = append(a.StructureAnalysis, PseudoGTIDInjectionFailingWarning)
+			}
 		}
 		appendAnalysis(&a)
 
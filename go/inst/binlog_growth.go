@@ -0,0 +1,185 @@
+/*
+   Copyright 2014 Outbrain Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package inst
+
+import (
+	"fmt"
+	"math"
+)
+
+const secondsPerHour = 3600
+
+// BinlogFileHistoryEntry is a binary log file as recorded by RecordInstanceBinlogFileHistory.
+// Size is the last position seen in the file, which for rotated files is the file's full size.
+type BinlogFileHistoryEntry struct {
+	BinaryLogFile          string
+	Size                   int64
+	FirstSeenUnixTimestamp int64
+	LastSeenUnixTimestamp  int64
+}
+
+// BinlogGrowthSample is the amount of binary log bytes written by an instance within a single hour
+type BinlogGrowthSample struct {
+	HourUnixTimestamp int64
+	Bytes             int64
+}
+
+// BinlogGrowth is a report of an instance's binary log write rate, its trend, and (where agent data is
+// available) the projected time until the binary logs fill the MySQL datadir.
+type BinlogGrowth struct {
+	Key                     InstanceKey
+	ClusterName             string
+	CountBinlogFiles        int
+	BaselineBytesPerHour    int64
+	RecentBytesPerHour      int64
+	TrendBytesPerHourPerDay int64
+	IsSpiking               bool
+	DatadirDiskFree         int64   // -1 when unknown
+	HoursUntilFull          float64 // -1 when unknown or when binary logs are not growing
+	HourlySamples           []BinlogGrowthSample
+}
+
+// ClusterBinlogGrowth is a binlog growth report of all instances of a cluster
+type ClusterBinlogGrowth struct {
+	ClusterName string
+	IsSpiking   bool
+	Instances   []BinlogGrowth
+}
+
+// NewClusterBinlogGrowth groups per-instance reports of a single cluster. The cluster is flagged as
+// spiking when any of its instances is.
+func NewClusterBinlogGrowth(clusterName string, growths []BinlogGrowth) *ClusterBinlogGrowth {
+	clusterGrowth := &ClusterBinlogGrowth{ClusterName: clusterName, Instances: growths}
+	for _, growth := range growths {
+		if growth.IsSpiking {
+			clusterGrowth.IsSpiking = true
+		}
+	}
+	return clusterGrowth
+}
+
+// ApplyDatadirDiskFree sets the free disk space on the instance's datadir, and projects the number of hours
+// until binary logs, written at their recent rate, fill it up
+func (this *BinlogGrowth) ApplyDatadirDiskFree(diskFree int64) {
+	this.DatadirDiskFree = diskFree
+	this.HoursUntilFull = -1
+	if diskFree >= 0 && this.RecentBytesPerHour > 0 {
+		this.HoursUntilFull = float64(diskFree) / float64(this.RecentBytesPerHour)
+	}
+}
+
+// TabulatedDescription returns a tab separated, single line summary: instance, cluster, baseline bytes/hour,
+// recent bytes/hour, trend (bytes/hour per day), spike flag and hours until datadir is full
+func (this *BinlogGrowth) TabulatedDescription() string {
+	spiking := "-"
+	if this.IsSpiking {
+		spiking = "spiking"
+	}
+	hoursUntilFull := "-"
+	if this.HoursUntilFull >= 0 {
+		hoursUntilFull = fmt.Sprintf("%.1f", this.HoursUntilFull)
+	}
+	return fmt.Sprintf("%s\t%s\t%d\t%d\t%d\t%s\t%s", this.Key.DisplayString(), this.ClusterName, this.BaselineBytesPerHour, this.RecentBytesPerHour, this.TrendBytesPerHourPerDay, spiking, hoursUntilFull)
+}
+
+// bytesWrittenBetween estimates the binary log bytes written between two points in time. A file's bytes
+// are assumed to have been written evenly between the first and last time it was seen.
+func bytesWrittenBetween(entries []BinlogFileHistoryEntry, fromUnixTimestamp int64, toUnixTimestamp int64) int64 {
+	var bytes float64
+	for _, entry := range entries {
+		if entry.LastSeenUnixTimestamp <= entry.FirstSeenUnixTimestamp {
+			if entry.FirstSeenUnixTimestamp >= fromUnixTimestamp && entry.FirstSeenUnixTimestamp < toUnixTimestamp {
+				bytes += float64(entry.Size)
+			}
+			continue
+		}
+		overlapFrom := math.Max(float64(entry.FirstSeenUnixTimestamp), float64(fromUnixTimestamp))
+		overlapTo := math.Min(float64(entry.LastSeenUnixTimestamp), float64(toUnixTimestamp))
+		if overlapTo <= overlapFrom {
+			continue
+		}
+		bytes += float64(entry.Size) * (overlapTo - overlapFrom) / float64(entry.LastSeenUnixTimestamp-entry.FirstSeenUnixTimestamp)
+	}
+	return int64(bytes)
+}
+
+// bytesPerHour returns the average hourly write rate between two points in time
+func bytesPerHour(entries []BinlogFileHistoryEntry, fromUnixTimestamp int64, toUnixTimestamp int64) int64 {
+	if toUnixTimestamp <= fromUnixTimestamp {
+		return 0
+	}
+	bytes := bytesWrittenBetween(entries, fromUnixTimestamp, toUnixTimestamp)
+	return int64(float64(bytes) * secondsPerHour / float64(toUnixTimestamp-fromUnixTimestamp))
+}
+
+// hourlyTrend returns the least squares slope of hourly samples, in bytes/hour per hour
+func hourlyTrend(samples []BinlogGrowthSample) float64 {
+	n := float64(len(samples))
+	if n < 2 {
+		return 0
+	}
+	var sumX, sumY, sumXY, sumXX float64
+	for i, sample := range samples {
+		x := float64(i)
+		y := float64(sample.Bytes)
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumXX += x * x
+	}
+	denominator := n*sumXX - sumX*sumX
+	if denominator == 0 {
+		return 0
+	}
+	return (n*sumXY - sumX*sumY) / denominator
+}
+
+// computeBinlogGrowth computes write rates of an instance's binlog file history as of given time.
+// The recent rate covers the last recentHours; the baseline covers all history preceding it.
+// The instance is spiking when its recent rate exceeds spikeFactor times its baseline rate.
+func computeBinlogGrowth(entries []BinlogFileHistoryEntry, nowUnixTimestamp int64, recentHours uint, spikeFactor float64) BinlogGrowth {
+	growth := BinlogGrowth{CountBinlogFiles: len(entries), DatadirDiskFree: -1, HoursUntilFull: -1, HourlySamples: []BinlogGrowthSample{}}
+	if len(entries) == 0 {
+		return growth
+	}
+	historyStart := entries[0].FirstSeenUnixTimestamp
+	for _, entry := range entries {
+		if entry.FirstSeenUnixTimestamp < historyStart {
+			historyStart = entry.FirstSeenUnixTimestamp
+		}
+	}
+	recentStart := nowUnixTimestamp - int64(recentHours)*secondsPerHour
+	if recentStart < historyStart {
+		recentStart = historyStart
+	}
+	growth.RecentBytesPerHour = bytesPerHour(entries, recentStart, nowUnixTimestamp)
+	growth.BaselineBytesPerHour = bytesPerHour(entries, historyStart, recentStart)
+	if growth.BaselineBytesPerHour > 0 && spikeFactor > 0 {
+		growth.IsSpiking = float64(growth.RecentBytesPerHour) > spikeFactor*float64(growth.BaselineBytesPerHour)
+	}
+
+	// Full hours only: the hour in which history begins is partially covered, and the current hour is still being written
+	firstHour := historyStart - historyStart%secondsPerHour
+	if firstHour < historyStart {
+		firstHour += secondsPerHour
+	}
+	for hour := firstHour; hour+secondsPerHour <= nowUnixTimestamp; hour += secondsPerHour {
+		growth.HourlySamples = append(growth.HourlySamples, BinlogGrowthSample{HourUnixTimestamp: hour, Bytes: bytesWrittenBetween(entries, hour, hour+secondsPerHour)})
+	}
+	growth.TrendBytesPerHourPerDay = int64(hourlyTrend(growth.HourlySamples) * 24)
+	return growth
+}
@@ -0,0 +1,165 @@
+/*
+   Copyright 2014 Outbrain Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package inst
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/outbrain/golib/log"
+	"github.com/outbrain/golib/sqlutils"
+	"github.com/outbrain/orchestrator/go/config"
+	"github.com/outbrain/orchestrator/go/db"
+)
+
+// readBinlogGrowth reads binlog file history of instances matching given condition, and computes
+// the binlog growth of each
+func readBinlogGrowth(whereCondition string, args []interface{}) ([]BinlogGrowth, error) {
+	res := []BinlogGrowth{}
+	if config.Config.BinlogFileHistoryDays == 0 {
+		return res, log.Errorf("Binlog growth requires BinlogFileHistoryDays to be set")
+	}
+	type instanceHistory struct {
+		key         InstanceKey
+		clusterName string
+		entries     []BinlogFileHistoryEntry
+	}
+	histories := []*instanceHistory{}
+	query := fmt.Sprintf(`
+		select
+			database_instance_binlog_files_history.hostname,
+			database_instance_binlog_files_history.port,
+			database_instance.cluster_name,
+			database_instance_binlog_files_history.binary_log_file,
+			database_instance_binlog_files_history.binary_log_pos,
+			unix_timestamp(database_instance_binlog_files_history.first_seen) as first_seen_unix_timestamp,
+			unix_timestamp(database_instance_binlog_files_history.last_seen) as last_seen_unix_timestamp
+		from
+			database_instance_binlog_files_history
+			join database_instance using (hostname, port)
+		where
+			%s
+		order by
+			hostname, port, database_instance_binlog_files_history.first_seen
+		`, whereCondition)
+	err := db.QueryOrchestrator(query, args, func(m sqlutils.RowMap) error {
+		key := InstanceKey{Hostname: m.GetString("hostname"), Port: m.GetInt("port")}
+		if len(histories) == 0 || !histories[len(histories)-1].key.Equals(&key) {
+			histories = append(histories, &instanceHistory{key: key, clusterName: m.GetString("cluster_name")})
+		}
+		history := histories[len(histories)-1]
+		history.entries = append(history.entries, BinlogFileHistoryEntry{
+			BinaryLogFile:          m.GetString("binary_log_file"),
+			Size:                   m.GetInt64("binary_log_pos"),
+			FirstSeenUnixTimestamp: m.GetInt64("first_seen_unix_timestamp"),
+			LastSeenUnixTimestamp:  m.GetInt64("last_seen_unix_timestamp"),
+		})
+		return nil
+	})
+	if err != nil {
+		return res, log.Errore(err)
+	}
+	now := time.Now().Unix()
+	for _, history := range histories {
+		growth := computeBinlogGrowth(history.entries, now, config.Config.BinlogGrowthRecentHours, config.Config.BinlogGrowthSpikeFactor)
+		growth.Key = history.key
+		growth.ClusterName = history.clusterName
+		res = append(res, growth)
+	}
+	return res, nil
+}
+
+// ReadInstanceBinlogGrowth computes the binlog write rate and trend of a single instance
+func ReadInstanceBinlogGrowth(instanceKey *InstanceKey) (*BinlogGrowth, error) {
+	condition := `
+		database_instance.hostname = ?
+		and database_instance.port = ?
+		`
+	growths, err := readBinlogGrowth(condition, sqlutils.Args(instanceKey.Hostname, instanceKey.Port))
+	if err != nil {
+		return nil, err
+	}
+	if len(growths) == 0 {
+		return nil, log.Errorf("No binlog file history found for %+v", *instanceKey)
+	}
+	return &growths[0], nil
+}
+
+// ReadClusterBinlogGrowth computes the binlog write rate and trend of all instances of a cluster
+func ReadClusterBinlogGrowth(clusterName string) (*ClusterBinlogGrowth, error) {
+	condition := `
+		database_instance.cluster_name = ?
+		`
+	growths, err := readBinlogGrowth(condition, sqlutils.Args(clusterName))
+	if err != nil {
+		return nil, err
+	}
+	return NewClusterBinlogGrowth(clusterName, growths), nil
+}
+
+// ReadSpikingBinlogGrowthClusters lists clusters where the recent binlog write rate of any instance
+// spikes relative to its baseline
+func ReadSpikingBinlogGrowthClusters() ([]ClusterBinlogGrowth, error) {
+	res := []ClusterBinlogGrowth{}
+	growths, err := readBinlogGrowth(`1 = 1`, sqlutils.Args())
+	if err != nil {
+		return res, err
+	}
+	clusterNames := []string{}
+	clusterGrowths := make(map[string][]BinlogGrowth)
+	for _, growth := range growths {
+		if _, found := clusterGrowths[growth.ClusterName]; !found {
+			clusterNames = append(clusterNames, growth.ClusterName)
+		}
+		clusterGrowths[growth.ClusterName] = append(clusterGrowths[growth.ClusterName], growth)
+	}
+	for _, clusterName := range clusterNames {
+		if clusterGrowth := NewClusterBinlogGrowth(clusterName, clusterGrowths[clusterName]); clusterGrowth.IsSpiking {
+			res = append(res, *clusterGrowth)
+		}
+	}
+	return res, nil
+}
+
+// ReadInstanceBinlogFileHistory reads the recorded binlog file history of given instance, oldest first
+func ReadInstanceBinlogFileHistory(instanceKey *InstanceKey) ([]BinlogFileHistoryEntry, error) {
+	res := []BinlogFileHistoryEntry{}
+	query := `
+		select
+			binary_log_file,
+			binary_log_pos,
+			unix_timestamp(first_seen) as first_seen_unix_timestamp,
+			unix_timestamp(last_seen) as last_seen_unix_timestamp
+		from
+			database_instance_binlog_files_history
+		where
+			hostname = ?
+			and port = ?
+		order by
+			first_seen
+		`
+	err := db.QueryOrchestrator(query, sqlutils.Args(instanceKey.Hostname, instanceKey.Port), func(m sqlutils.RowMap) error {
+		res = append(res, BinlogFileHistoryEntry{
+			BinaryLogFile:          m.GetString("binary_log_file"),
+			Size:                   m.GetInt64("binary_log_pos"),
+			FirstSeenUnixTimestamp: m.GetInt64("first_seen_unix_timestamp"),
+			LastSeenUnixTimestamp:  m.GetInt64("last_seen_unix_timestamp"),
+		})
+		return nil
+	})
+	return res, log.Errore(err)
+}
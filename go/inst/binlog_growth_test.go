@@ -0,0 +1,92 @@
+/*
+   Copyright 2014 Outbrain Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package inst
+
+import (
+	test "github.com/outbrain/golib/tests"
+	"testing"
+)
+
+// steadyBinlogHistory returns hourly rotated binlogs of given size, covering given hours up to now
+func steadyBinlogHistory(now int64, hours int, size int64) []BinlogFileHistoryEntry {
+	entries := []BinlogFileHistoryEntry{}
+	for i := hours; i > 0; i-- {
+		from := now - int64(i)*secondsPerHour
+		entries = append(entries, BinlogFileHistoryEntry{Size: size, FirstSeenUnixTimestamp: from, LastSeenUnixTimestamp: from + secondsPerHour})
+	}
+	return entries
+}
+
+func TestBytesWrittenBetween(t *testing.T) {
+	entries := []BinlogFileHistoryEntry{
+		{Size: 1000, FirstSeenUnixTimestamp: 0, LastSeenUnixTimestamp: 100},
+		{Size: 500, FirstSeenUnixTimestamp: 100, LastSeenUnixTimestamp: 100},
+	}
+	test.S(t).ExpectEquals(bytesWrittenBetween(entries, 0, 50), int64(500))
+	test.S(t).ExpectEquals(bytesWrittenBetween(entries, 50, 101), int64(1000))
+	test.S(t).ExpectEquals(bytesWrittenBetween(entries, 200, 300), int64(0))
+}
+
+func TestComputeBinlogGrowthSteady(t *testing.T) {
+	var now int64 = 100 * secondsPerHour
+	growth := computeBinlogGrowth(steadyBinlogHistory(now, 24, 1000), now, 1, 3)
+	test.S(t).ExpectEquals(growth.CountBinlogFiles, 24)
+	test.S(t).ExpectEquals(growth.BaselineBytesPerHour, int64(1000))
+	test.S(t).ExpectEquals(growth.RecentBytesPerHour, int64(1000))
+	test.S(t).ExpectEquals(growth.TrendBytesPerHourPerDay, int64(0))
+	test.S(t).ExpectEquals(len(growth.HourlySamples), 24)
+	test.S(t).ExpectFalse(growth.IsSpiking)
+	test.S(t).ExpectEquals(growth.HoursUntilFull, float64(-1))
+}
+
+func TestComputeBinlogGrowthSpike(t *testing.T) {
+	var now int64 = 100 * secondsPerHour
+	entries := steadyBinlogHistory(now, 24, 1000)
+	entries[len(entries)-1].Size = 5000
+	growth := computeBinlogGrowth(entries, now, 1, 3)
+	test.S(t).ExpectEquals(growth.BaselineBytesPerHour, int64(1000))
+	test.S(t).ExpectEquals(growth.RecentBytesPerHour, int64(5000))
+	test.S(t).ExpectTrue(growth.IsSpiking)
+	test.S(t).ExpectTrue(growth.TrendBytesPerHourPerDay > 0)
+
+	growth = computeBinlogGrowth(entries, now, 1, 6)
+	test.S(t).ExpectFalse(growth.IsSpiking)
+}
+
+func TestComputeBinlogGrowthEmpty(t *testing.T) {
+	growth := computeBinlogGrowth([]BinlogFileHistoryEntry{}, 1000, 1, 3)
+	test.S(t).ExpectEquals(growth.RecentBytesPerHour, int64(0))
+	test.S(t).ExpectFalse(growth.IsSpiking)
+}
+
+func TestApplyDatadirDiskFree(t *testing.T) {
+	growth := &BinlogGrowth{RecentBytesPerHour: 1000}
+	growth.ApplyDatadirDiskFree(48000)
+	test.S(t).ExpectEquals(growth.DatadirDiskFree, int64(48000))
+	test.S(t).ExpectEquals(growth.HoursUntilFull, float64(48))
+
+	growth.RecentBytesPerHour = 0
+	growth.ApplyDatadirDiskFree(48000)
+	test.S(t).ExpectEquals(growth.HoursUntilFull, float64(-1))
+}
+
+func TestNewClusterBinlogGrowth(t *testing.T) {
+	clusterGrowth := NewClusterBinlogGrowth("c1", []BinlogGrowth{{}, {IsSpiking: true}})
+	test.S(t).ExpectTrue(clusterGrowth.IsSpiking)
+	clusterGrowth = NewClusterBinlogGrowth("c1", []BinlogGrowth{{}, {}})
+	test.S(t).ExpectFalse(clusterGrowth.IsSpiking)
+}
@@ -0,0 +1,130 @@
+/*
+   Copyright 2014 Outbrain Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package inst
+
+import (
+	"fmt"
+)
+
+// BinlogPurgePlan describes which binary logs of a master or intermediate master may be safely purged, as
+// computed by PlanBinlogPurge. When Blocker is non-empty, nothing may be purged.
+type BinlogPurgePlan struct {
+	Key                InstanceKey
+	ClusterName        string
+	OldestBinlog       string // Oldest binary log present on the instance
+	OldestNeededBinlog string // Oldest binary log still being read by any replica
+	KeepFromBinlog     string // Oldest binary log which must be kept: also accounts for Pseudo-GTID and age margin
+	PurgeToBinlog      string // Binary logs older than this one will be purged. Empty when there is nothing to purge
+	Blocker            string // Reason purging is not allowed
+}
+
+// CanPurge returns true when the plan allows purging any binary log
+func (this *BinlogPurgePlan) CanPurge() bool {
+	return this.Blocker == "" && this.PurgeToBinlog != ""
+}
+
+// TabulatedDescription returns a tab separated, single line summary: instance, oldest binary log, oldest needed
+// binary log, oldest kept binary log, purge-to binary log and blocker. Unknown values show as "-"
+func (this *BinlogPurgePlan) TabulatedDescription() string {
+	values := []string{this.Key.DisplayString(), this.OldestBinlog, this.OldestNeededBinlog, this.KeepFromBinlog, this.PurgeToBinlog, this.Blocker}
+	for i := range values {
+		if values[i] == "" {
+			values[i] = "-"
+		}
+	}
+	return fmt.Sprintf("%s\t%s\t%s\t%s\t%s\t%s", values[0], values[1], values[2], values[3], values[4], values[5])
+}
+
+// binlogPurgeReplicasBlocker returns the reason, if any, replicas block purging binary logs of their (possibly
+// indirect) master: a replica which is unreachable or in maintenance may yet need any of them.
+func binlogPurgeReplicasBlocker(replicas [](*Instance), inMaintenance *InstanceKeyMap) string {
+	for _, replica := range replicas {
+		if inMaintenance.HasKey(replica.Key) {
+			return fmt.Sprintf("replica %+v is in maintenance", replica.Key.DisplayString())
+		}
+		if !replica.IsLastCheckValid {
+			return fmt.Sprintf("replica %+v is unreachable", replica.Key.DisplayString())
+		}
+	}
+	return ""
+}
+
+// pseudoGTIDCoordinatesOnInstance maps a replica's executed coordinates, given in the binary logs of its master, to
+// the coordinates in the binary logs of an instance up the replica's chain of the last Pseudo-GTID entry the replica
+// has executed. instanceIndex and masterIndex are the Pseudo-GTID indexes of the instance and of the replica's
+// master, which are the same for a direct replica. Returns nil when the indexes do not cover the entry.
+func pseudoGTIDCoordinatesOnInstance(instanceIndex PseudoGTIDIndex, masterIndex PseudoGTIDIndex, execCoordinates *BinlogCoordinates) *BinlogCoordinates {
+	if execCoordinates.LogFile == "" {
+		return nil
+	}
+	masterEntry := masterIndex.LastNotAfter(execCoordinates)
+	if masterEntry == nil {
+		return nil
+	}
+	instanceEntry := instanceIndex.Find(masterEntry.EntryText)
+	if instanceEntry == nil {
+		return nil
+	}
+	coordinates := instanceEntry.Coordinates
+	return &coordinates
+}
+
+// earlierBinlog returns the earlier of two binary log files; empty names are ignored
+func earlierBinlog(binlog string, other string) string {
+	if binlog == "" {
+		return other
+	}
+	if other == "" {
+		return binlog
+	}
+	coordinates := BinlogCoordinates{LogFile: binlog}
+	if coordinates.FileSmallerThan(&BinlogCoordinates{LogFile: other}) {
+		return binlog
+	}
+	return other
+}
+
+// binlogPurgeTarget returns the binary log to 'PURGE BINARY LOGS TO' such that keepFromBinlog, and retainFiles
+// binary logs preceding it, are kept. binlogs is the list of binary logs on the instance, oldest first.
+// Returns empty string when there is nothing to purge.
+func binlogPurgeTarget(binlogs []string, keepFromBinlog string, retainFiles uint) string {
+	for i, binlog := range binlogs {
+		if binlog != keepFromBinlog {
+			continue
+		}
+		if target := i - int(retainFiles); target > 0 {
+			return binlogs[target]
+		}
+		return ""
+	}
+	return ""
+}
+
+// binlogRetainedByAge returns the oldest binary log written to at or after given time, according to binlog
+// file history. covered is false when history does not go back as far as given time, in which case the
+// age of older binary logs cannot be told.
+func binlogRetainedByAge(entries []BinlogFileHistoryEntry, cutoffUnixTimestamp int64) (binlog string, covered bool) {
+	for _, entry := range entries {
+		if entry.FirstSeenUnixTimestamp <= cutoffUnixTimestamp {
+			covered = true
+		}
+		if entry.LastSeenUnixTimestamp >= cutoffUnixTimestamp {
+			binlog = earlierBinlog(binlog, entry.BinaryLogFile)
+		}
+	}
+	return binlog, covered
+}
@@ -0,0 +1,235 @@
+/*
+   Copyright 2014 Outbrain Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package inst
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/outbrain/golib/log"
+	"github.com/outbrain/golib/sqlutils"
+	"github.com/outbrain/orchestrator/go/config"
+	"github.com/outbrain/orchestrator/go/db"
+)
+
+// ShowBinaryLogs lists the binary logs present on given instance, oldest first
+func ShowBinaryLogs(instanceKey *InstanceKey) ([]string, error) {
+	binlogs := []string{}
+	db, err := db.OpenTopology(instanceKey.Hostname, instanceKey.Port)
+	if err != nil {
+		return binlogs, err
+	}
+	err = sqlutils.QueryRowsMap(db, "show binary logs", func(m sqlutils.RowMap) error {
+		binlogs = append(binlogs, m.GetString("Log_name"))
+		return nil
+	})
+	return binlogs, log.Errore(err)
+}
+
+// readReplicasSubtree reads all replicas below given instance: its replicas, their replicas and so forth
+func readReplicasSubtree(instanceKey *InstanceKey) ([](*Instance), error) {
+	subtree := [](*Instance){}
+	visited := NewInstanceKeyMap()
+	visited.AddKey(*instanceKey)
+	masterKeys := []InstanceKey{*instanceKey}
+	for len(masterKeys) > 0 {
+		masterKey := masterKeys[0]
+		masterKeys = masterKeys[1:]
+		replicas, err := ReadSlaveInstances(&masterKey)
+		if err != nil {
+			return subtree, err
+		}
+		for _, replica := range replicas {
+			if visited.HasKey(replica.Key) {
+				continue
+			}
+			visited.AddKey(replica.Key)
+			subtree = append(subtree, replica)
+			masterKeys = append(masterKeys, replica.Key)
+		}
+	}
+	return subtree, nil
+}
+
+// oldestPseudoGTIDCoordinates returns the coordinates, in the binary logs of given instance, of the oldest
+// Pseudo-GTID entry any of the replicas in its subtree relies on for matching: the last entry each has executed.
+// It returns a blocker when the Pseudo-GTID index cannot tell.
+func oldestPseudoGTIDCoordinates(instanceKey *InstanceKey, subtree [](*Instance)) (oldest *BinlogCoordinates, blocker string, err error) {
+	if config.Config.PseudoGTIDIndexHours == 0 {
+		return nil, "Pseudo-GTID index is disabled (PseudoGTIDIndexHours)", nil
+	}
+	indexes := make(map[InstanceKey]PseudoGTIDIndex)
+	readIndex := func(key *InstanceKey) (PseudoGTIDIndex, error) {
+		if index, found := indexes[*key]; found {
+			return index, nil
+		}
+		index, err := ReadPseudoGTIDIndex(key)
+		indexes[*key] = index
+		return index, err
+	}
+	instanceIndex, err := readIndex(instanceKey)
+	if err != nil {
+		return nil, "", err
+	}
+	for _, replica := range subtree {
+		masterIndex, err := readIndex(&replica.MasterKey)
+		if err != nil {
+			return nil, "", err
+		}
+		coordinates := pseudoGTIDCoordinatesOnInstance(instanceIndex, masterIndex, &replica.ExecBinlogCoordinates)
+		if coordinates == nil {
+			return nil, fmt.Sprintf("Pseudo-GTID index does not cover replica %+v", replica.Key.DisplayString()), nil
+		}
+		if oldest == nil || coordinates.SmallerThan(oldest) {
+			oldest = coordinates
+		}
+	}
+	return oldest, "", nil
+}
+
+// PlanBinlogPurge computes which binary logs of given instance can be safely purged: those no longer read by
+// any of its replicas, beyond a margin of BinlogPurgeRetainFiles files and BinlogPurgeRetainHours hours, and
+// not required for Pseudo-GTID matching of any replica in its subtree. Purging is blocked while any replica in
+// the subtree is unreachable or in maintenance, while a connected replica is unknown, and while the
+// Pseudo-GTID index cannot tell which binary logs are required.
+func PlanBinlogPurge(instanceKey *InstanceKey) (*BinlogPurgePlan, error) {
+	instance, found, err := ReadInstance(instanceKey)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, log.Errorf("PlanBinlogPurge: instance not found: %+v", *instanceKey)
+	}
+	plan := &BinlogPurgePlan{Key: instance.Key, ClusterName: instance.ClusterName}
+	if !instance.IsLastCheckValid {
+		plan.Blocker = "instance is not reachable"
+		return plan, nil
+	}
+	if !instance.LogBinEnabled {
+		plan.Blocker = "binary logs are not enabled"
+		return plan, nil
+	}
+	subtree, err := readReplicasSubtree(&instance.Key)
+	if err != nil {
+		return nil, err
+	}
+	replicas := [](*Instance){}
+	for _, replica := range subtree {
+		if replica.MasterKey.Equals(&instance.Key) {
+			replicas = append(replicas, replica)
+		}
+	}
+	if len(replicas) == 0 {
+		plan.Blocker = "instance has no replicas"
+		return plan, nil
+	}
+	maintenances, err := ReadActiveMaintenance()
+	if err != nil {
+		return nil, err
+	}
+	inMaintenance := NewInstanceKeyMap()
+	for _, maintenance := range maintenances {
+		inMaintenance.AddKey(maintenance.Key)
+	}
+
+	if plan.Blocker = binlogPurgeReplicasBlocker(subtree, inMaintenance); plan.Blocker != "" {
+		return plan, nil
+	}
+
+	var oldestNeededCoordinates *BinlogCoordinates
+	knownReplicas := NewInstanceKeyMap()
+	for _, replica := range replicas {
+		knownReplicas.AddKey(replica.Key)
+		if replica.ReadBinlogCoordinates.LogFile == "" {
+			plan.Blocker = fmt.Sprintf("replica %+v has unknown read coordinates", replica.Key.DisplayString())
+			return plan, nil
+		}
+		if oldestNeededCoordinates == nil || replica.ReadBinlogCoordinates.SmallerThan(oldestNeededCoordinates) {
+			coordinates := replica.ReadBinlogCoordinates
+			oldestNeededCoordinates = &coordinates
+		}
+	}
+	for _, replicaKey := range instance.SlaveHosts.GetInstanceKeys() {
+		if !knownReplicas.HasKey(replicaKey) {
+			plan.Blocker = fmt.Sprintf("replica %+v is connected but not known", replicaKey.DisplayString())
+			return plan, nil
+		}
+	}
+	plan.OldestNeededBinlog = oldestNeededCoordinates.LogFile
+	keepFromBinlog := plan.OldestNeededBinlog
+
+	if config.Config.PseudoGTIDPattern != "" {
+		// Matching a replica relies on the last Pseudo-GTID entry it has executed
+		pseudoGTIDCoordinates, blocker, err := oldestPseudoGTIDCoordinates(&instance.Key, subtree)
+		if err != nil {
+			return nil, err
+		}
+		if blocker != "" {
+			plan.Blocker = blocker
+			return plan, nil
+		}
+		keepFromBinlog = earlierBinlog(keepFromBinlog, pseudoGTIDCoordinates.LogFile)
+	}
+	if config.Config.BinlogPurgeRetainHours > 0 {
+		history, err := ReadInstanceBinlogFileHistory(&instance.Key)
+		if err != nil {
+			return nil, err
+		}
+		cutoff := time.Now().Add(-time.Duration(config.Config.BinlogPurgeRetainHours) * time.Hour).Unix()
+		retainedBinlog, covered := binlogRetainedByAge(history, cutoff)
+		if !covered || retainedBinlog == "" {
+			plan.Blocker = "binlog file history does not cover BinlogPurgeRetainHours"
+			return plan, nil
+		}
+		keepFromBinlog = earlierBinlog(keepFromBinlog, retainedBinlog)
+	}
+	plan.KeepFromBinlog = keepFromBinlog
+
+	binlogs, err := ShowBinaryLogs(&instance.Key)
+	if err != nil {
+		return nil, err
+	}
+	if len(binlogs) > 0 {
+		plan.OldestBinlog = binlogs[0]
+	}
+	plan.PurgeToBinlog = binlogPurgeTarget(binlogs, keepFromBinlog, config.Config.BinlogPurgeRetainFiles)
+	return plan, nil
+}
+
+// PlanClusterBinlogPurge computes binlog purge plans for all masters and intermediate masters of given cluster
+func PlanClusterBinlogPurge(clusterName string) ([]BinlogPurgePlan, error) {
+	plans := []BinlogPurgePlan{}
+	instances, err := ReadClusterInstances(clusterName)
+	if err != nil {
+		return plans, err
+	}
+	masterKeys := NewInstanceKeyMap()
+	for _, instance := range instances {
+		masterKeys.AddKey(instance.MasterKey)
+	}
+	for _, instance := range instances {
+		if !masterKeys.HasKey(instance.Key) || instance.IsBinlogServer() {
+			continue
+		}
+		plan, err := PlanBinlogPurge(&instance.Key)
+		if err != nil {
+			return plans, err
+		}
+		plans = append(plans, *plan)
+	}
+	return plans, nil
+}
@@ -0,0 +1,122 @@
+/*
+   Copyright 2014 Outbrain Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package inst
+
+import (
+	test "github.com/outbrain/golib/tests"
+	"testing"
+)
+
+var purgeTestBinlogs = []string{"mysql-bin.000010", "mysql-bin.000011", "mysql-bin.000012", "mysql-bin.000013", "mysql-bin.000014", "mysql-bin.000015"}
+
+func TestEarlierBinlog(t *testing.T) {
+	test.S(t).ExpectEquals(earlierBinlog("mysql-bin.000012", "mysql-bin.000011"), "mysql-bin.000011")
+	test.S(t).ExpectEquals(earlierBinlog("mysql-bin.000009", "mysql-bin.000011"), "mysql-bin.000009")
+	test.S(t).ExpectEquals(earlierBinlog("", "mysql-bin.000011"), "mysql-bin.000011")
+	test.S(t).ExpectEquals(earlierBinlog("mysql-bin.000011", ""), "mysql-bin.000011")
+}
+
+func TestBinlogPurgeTarget(t *testing.T) {
+	test.S(t).ExpectEquals(binlogPurgeTarget(purgeTestBinlogs, "mysql-bin.000014", 0), "mysql-bin.000014")
+	test.S(t).ExpectEquals(binlogPurgeTarget(purgeTestBinlogs, "mysql-bin.000014", 2), "mysql-bin.000012")
+	test.S(t).ExpectEquals(binlogPurgeTarget(purgeTestBinlogs, "mysql-bin.000014", 4), "")
+	test.S(t).ExpectEquals(binlogPurgeTarget(purgeTestBinlogs, "mysql-bin.000014", 10), "")
+	test.S(t).ExpectEquals(binlogPurgeTarget(purgeTestBinlogs, "mysql-bin.000010", 0), "")
+	test.S(t).ExpectEquals(binlogPurgeTarget(purgeTestBinlogs, "mysql-bin.000009", 0), "")
+}
+
+func TestBinlogRetainedByAge(t *testing.T) {
+	entries := []BinlogFileHistoryEntry{
+		{BinaryLogFile: "mysql-bin.000012", FirstSeenUnixTimestamp: 100, LastSeenUnixTimestamp: 200},
+		{BinaryLogFile: "mysql-bin.000013", FirstSeenUnixTimestamp: 200, LastSeenUnixTimestamp: 300},
+		{BinaryLogFile: "mysql-bin.000014", FirstSeenUnixTimestamp: 300, LastSeenUnixTimestamp: 400},
+	}
+	binlog, covered := binlogRetainedByAge(entries, 250)
+	test.S(t).ExpectTrue(covered)
+	test.S(t).ExpectEquals(binlog, "mysql-bin.000013")
+
+	binlog, covered = binlogRetainedByAge(entries, 50)
+	test.S(t).ExpectFalse(covered)
+	test.S(t).ExpectEquals(binlog, "mysql-bin.000012")
+
+	binlog, covered = binlogRetainedByAge(entries, 500)
+	test.S(t).ExpectTrue(covered)
+	test.S(t).ExpectEquals(binlog, "")
+}
+
+func TestBinlogPurgePlanCanPurge(t *testing.T) {
+	plan := &BinlogPurgePlan{PurgeToBinlog: "mysql-bin.000012"}
+	test.S(t).ExpectTrue(plan.CanPurge())
+	plan.Blocker = "replica db2:3306 is in maintenance"
+	test.S(t).ExpectFalse(plan.CanPurge())
+	plan = &BinlogPurgePlan{}
+	test.S(t).ExpectFalse(plan.CanPurge())
+}
+
+func TestBinlogPurgePlanTabulatedDescription(t *testing.T) {
+	plan := &BinlogPurgePlan{Key: InstanceKey{Hostname: "db1", Port: 3306}, OldestBinlog: "mysql-bin.000010", Blocker: "instance has no replicas"}
+	test.S(t).ExpectEquals(plan.TabulatedDescription(), "db1:3306\tmysql-bin.000010\t-\t-\t-\tinstance has no replicas")
+}
+
+func TestBinlogPurgeReplicasBlocker(t *testing.T) {
+	newReplica := func(hostname string, reachable bool) *Instance {
+		replica := NewInstance()
+		replica.Key = InstanceKey{Hostname: hostname, Port: 3306}
+		replica.IsLastCheckValid = reachable
+		return replica
+	}
+	inMaintenance := NewInstanceKeyMap()
+
+	test.S(t).ExpectEquals(binlogPurgeReplicasBlocker([](*Instance){}, inMaintenance), "")
+	test.S(t).ExpectEquals(binlogPurgeReplicasBlocker([](*Instance){newReplica("db2", true), newReplica("db3", true)}, inMaintenance), "")
+	test.S(t).ExpectEquals(binlogPurgeReplicasBlocker([](*Instance){newReplica("db2", true), newReplica("db3", false)}, inMaintenance), "replica db3:3306 is unreachable")
+
+	inMaintenance.AddKey(InstanceKey{Hostname: "db2", Port: 3306})
+	test.S(t).ExpectEquals(binlogPurgeReplicasBlocker([](*Instance){newReplica("db2", true), newReplica("db3", false)}, inMaintenance), "replica db2:3306 is in maintenance")
+}
+
+func TestPseudoGTIDCoordinatesOnInstance(t *testing.T) {
+	entry := func(hostname string, logFile string, logPos int64, entryText string) PseudoGTIDIndexEntry {
+		return PseudoGTIDIndexEntry{Key: InstanceKey{Hostname: hostname, Port: 3306}, Coordinates: BinlogCoordinates{LogFile: logFile, LogPos: logPos}, EntryText: entryText}
+	}
+	masterIndex := PseudoGTIDIndex{
+		entry("db1", "mysql-bin.000010", 400, "pgtid-1"),
+		entry("db1", "mysql-bin.000011", 400, "pgtid-2"),
+		entry("db1", "mysql-bin.000012", 400, "pgtid-3"),
+	}
+	intermediateIndex := PseudoGTIDIndex{
+		entry("db2", "db2-bin.000020", 800, "pgtid-2"),
+		entry("db2", "db2-bin.000020", 1600, "pgtid-3"),
+	}
+
+	// Direct replica
+	coordinates := pseudoGTIDCoordinatesOnInstance(masterIndex, masterIndex, &BinlogCoordinates{LogFile: "mysql-bin.000011", LogPos: 900})
+	test.S(t).ExpectNotNil(coordinates)
+	test.S(t).ExpectEquals(coordinates.LogFile, "mysql-bin.000011")
+	test.S(t).ExpectEquals(coordinates.LogPos, int64(400))
+
+	// Replica of an intermediate master
+	coordinates = pseudoGTIDCoordinatesOnInstance(masterIndex, intermediateIndex, &BinlogCoordinates{LogFile: "db2-bin.000020", LogPos: 1000})
+	test.S(t).ExpectNotNil(coordinates)
+	test.S(t).ExpectEquals(coordinates.LogFile, "mysql-bin.000011")
+
+	// Not covered by the index
+	test.S(t).ExpectTrue(pseudoGTIDCoordinatesOnInstance(masterIndex, masterIndex, &BinlogCoordinates{LogFile: "mysql-bin.000009", LogPos: 900}) == nil)
+	test.S(t).ExpectTrue(pseudoGTIDCoordinatesOnInstance(masterIndex, intermediateIndex, &BinlogCoordinates{LogFile: "db2-bin.000019", LogPos: 900}) == nil)
+	test.S(t).ExpectTrue(pseudoGTIDCoordinatesOnInstance(PseudoGTIDIndex{}, intermediateIndex, &BinlogCoordinates{LogFile: "db2-bin.000020", LogPos: 1000}) == nil)
+	test.S(t).ExpectTrue(pseudoGTIDCoordinatesOnInstance(masterIndex, masterIndex, &BinlogCoordinates{}) == nil)
+}
@@ -0,0 +1,119 @@
+/*
+   Copyright 2014 Outbrain Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package inst
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// States of a clone operation, as in performance_schema.clone_status and clone_progress
+const (
+	CloneStateNotStarted = "Not Started"
+	CloneStateInProgress = "In Progress"
+	CloneStateCompleted  = "Completed"
+	CloneStateFailed     = "Failed"
+)
+
+// CloneStageProgress is a row in performance_schema.clone_progress
+type CloneStageProgress struct {
+	Stage    string
+	State    string
+	Estimate int64
+	Data     int64
+}
+
+// CloneStatus describes the most recent clone operation on an instance, as read from
+// performance_schema.clone_status and clone_progress
+type CloneStatus struct {
+	Id           int64
+	State        string
+	BeginTime    string
+	EndTime      string
+	Source       string
+	ErrorNumber  int
+	ErrorMessage string
+	Stage        string // Stage currently in progress, if any
+	BytesCopied  int64
+	BytesTotal   int64
+}
+
+// IsComplete returns true when the clone operation has ended, successfully or not
+func (this *CloneStatus) IsComplete() bool {
+	return this.State == CloneStateCompleted || this.State == CloneStateFailed
+}
+
+// ApplyStagesProgress summarizes per-stage progress: the stage in progress, and data copied out of
+// the total estimate over all stages
+func (this *CloneStatus) ApplyStagesProgress(stages []CloneStageProgress) {
+	this.Stage = ""
+	this.BytesCopied = 0
+	this.BytesTotal = 0
+	for _, stage := range stages {
+		if stage.State == CloneStateInProgress {
+			this.Stage = stage.Stage
+		}
+		this.BytesCopied += stage.Data
+		this.BytesTotal += stage.Estimate
+	}
+}
+
+// supportsCloneVersion returns true for MySQL versions shipping the CLONE plugin (8.0.17 and above)
+func supportsCloneVersion(version string) bool {
+	tokens := strings.Split(version, ".")
+	if len(tokens) < 3 {
+		return false
+	}
+	major, err := strconv.Atoi(tokens[0])
+	if err != nil {
+		return false
+	}
+	minor, err := strconv.Atoi(tokens[1])
+	if err != nil {
+		return false
+	}
+	patchDigits := tokens[2]
+	if i := strings.IndexFunc(tokens[2], func(r rune) bool { return r < '0' || r > '9' }); i >= 0 {
+		patchDigits = tokens[2][:i]
+	}
+	patch, err := strconv.Atoi(patchDigits)
+	if err != nil {
+		return false
+	}
+	if major != 8 {
+		return major > 8
+	}
+	if minor != 0 {
+		return minor > 0
+	}
+	return patch >= 17
+}
+
+// quoteMySQLString quotes given text as a MySQL string literal
+func quoteMySQLString(text string) string {
+	text = strings.Replace(text, `\`, `\\`, -1)
+	text = strings.Replace(text, `'`, `\'`, -1)
+	return fmt.Sprintf("'%s'", text)
+}
+
+// cloneInstanceStatement returns the CLONE INSTANCE statement copying data from given donor. CLONE
+// does not accept placeholders, hence credentials are quoted as literals.
+func cloneInstanceStatement(donorKey *InstanceKey, user string, password string) string {
+	return fmt.Sprintf("clone instance from %s@%s:%d identified by %s",
+		quoteMySQLString(user), quoteMySQLString(donorKey.Hostname), donorKey.Port, quoteMySQLString(password))
+}
@@ -0,0 +1,112 @@
+/*
+   Copyright 2014 Outbrain Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package inst
+
+import (
+	"fmt"
+
+	"github.com/outbrain/golib/log"
+	"github.com/outbrain/golib/sqlutils"
+	"github.com/outbrain/orchestrator/go/config"
+	"github.com/outbrain/orchestrator/go/db"
+)
+
+// CloneInstance copies data from donor onto target via the CLONE plugin, on behalf of the topology user
+// (which requires BACKUP_ADMIN on the donor and CLONE_ADMIN on the target). The call blocks until the clone
+// ends. Upon success the target restarts, typically breaking the executing connection; the outcome is
+// therefore best read via ReadCloneStatus.
+func CloneInstance(targetKey *InstanceKey, donorKey *InstanceKey) error {
+	if *config.RuntimeCLIFlags.Noop {
+		return fmt.Errorf("noop: aborting clone-instance operation on %+v; signalling error but nothing went wrong.", *targetKey)
+	}
+	if _, err := ExecInstance(targetKey, `set global clone_valid_donor_list := ?`, fmt.Sprintf("%s:%d", donorKey.Hostname, donorKey.Port)); err != nil {
+		return log.Errore(err)
+	}
+	db, err := db.OpenTopologyLongRunning(targetKey.Hostname, targetKey.Port)
+	if err != nil {
+		return log.Errore(err)
+	}
+	AuditOperation("clone-instance", targetKey, fmt.Sprintf("cloning from %+v", *donorKey))
+	_, err = sqlutils.ExecNoPrepare(db, cloneInstanceStatement(donorKey, config.Config.MySQLTopologyUser, config.Config.MySQLTopologyPassword))
+	return err
+}
+
+// ReadCloneStatus reads the status of the most recent clone operation on given instance. It returns nil
+// when no clone has ever run on the instance.
+func ReadCloneStatus(instanceKey *InstanceKey) (*CloneStatus, error) {
+	db, err := db.OpenTopology(instanceKey.Hostname, instanceKey.Port)
+	if err != nil {
+		return nil, err
+	}
+	var cloneStatus *CloneStatus
+	query := `
+		select
+			id,
+			state,
+			ifnull(begin_time, '') as begin_time,
+			ifnull(end_time, '') as end_time,
+			source,
+			error_no,
+			error_message
+		from
+			performance_schema.clone_status
+		order by
+			id desc
+		limit 1
+		`
+	err = sqlutils.QueryRowsMap(db, query, func(m sqlutils.RowMap) error {
+		cloneStatus = &CloneStatus{}
+		cloneStatus.Id = m.GetInt64("id")
+		cloneStatus.State = m.GetString("state")
+		cloneStatus.BeginTime = m.GetString("begin_time")
+		cloneStatus.EndTime = m.GetString("end_time")
+		cloneStatus.Source = m.GetString("source")
+		cloneStatus.ErrorNumber = m.GetInt("error_no")
+		cloneStatus.ErrorMessage = m.GetString("error_message")
+		return nil
+	})
+	if err != nil || cloneStatus == nil {
+		return nil, err
+	}
+
+	stages := []CloneStageProgress{}
+	query = `
+		select
+			stage,
+			state,
+			ifnull(estimate, 0) as estimate,
+			ifnull(data, 0) as data
+		from
+			performance_schema.clone_progress
+		order by
+			id, stage
+		`
+	err = sqlutils.QueryRowsMap(db, query, func(m sqlutils.RowMap) error {
+		stages = append(stages, CloneStageProgress{
+			Stage:    m.GetString("stage"),
+			State:    m.GetString("state"),
+			Estimate: m.GetInt64("estimate"),
+			Data:     m.GetInt64("data"),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	cloneStatus.ApplyStagesProgress(stages)
+	return cloneStatus, nil
+}
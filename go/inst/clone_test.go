@@ -0,0 +1,61 @@
+/*
+   Copyright 2014 Outbrain Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package inst
+
+import (
+	test "github.com/outbrain/golib/tests"
+	"testing"
+)
+
+func TestSupportsCloneVersion(t *testing.T) {
+	test.S(t).ExpectTrue(supportsCloneVersion("8.0.17"))
+	test.S(t).ExpectTrue(supportsCloneVersion("8.0.21-log"))
+	test.S(t).ExpectTrue(supportsCloneVersion("8.1.0"))
+	test.S(t).ExpectFalse(supportsCloneVersion("8.0.16"))
+	test.S(t).ExpectFalse(supportsCloneVersion("8.0.4-rc-log"))
+	test.S(t).ExpectFalse(supportsCloneVersion("5.7.30-log"))
+	test.S(t).ExpectFalse(supportsCloneVersion("8.0"))
+}
+
+func TestSupportsClone(t *testing.T) {
+	instance := &Instance{Version: "8.0.20"}
+	test.S(t).ExpectTrue(instance.SupportsClone())
+	instance.Version = "10.4.12-MariaDB"
+	test.S(t).ExpectFalse(instance.SupportsClone())
+}
+
+func TestCloneInstanceStatement(t *testing.T) {
+	donorKey := &InstanceKey{Hostname: "db1", Port: 3306}
+	test.S(t).ExpectEquals(cloneInstanceStatement(donorKey, "orc", "secret"), "clone instance from 'orc'@'db1':3306 identified by 'secret'")
+	test.S(t).ExpectEquals(cloneInstanceStatement(donorKey, "orc", `it's\`), `clone instance from 'orc'@'db1':3306 identified by 'it\'s\\'`)
+}
+
+func TestCloneStatusApplyStagesProgress(t *testing.T) {
+	cloneStatus := &CloneStatus{State: CloneStateInProgress}
+	cloneStatus.ApplyStagesProgress([]CloneStageProgress{
+		{Stage: "DROP DATA", State: CloneStateCompleted},
+		{Stage: "FILE COPY", State: CloneStateCompleted, Estimate: 1000, Data: 1000},
+		{Stage: "PAGE COPY", State: CloneStateInProgress, Estimate: 200, Data: 50},
+		{Stage: "REDO COPY", State: CloneStateNotStarted},
+	})
+	test.S(t).ExpectEquals(cloneStatus.Stage, "PAGE COPY")
+	test.S(t).ExpectEquals(cloneStatus.BytesCopied, int64(1050))
+	test.S(t).ExpectEquals(cloneStatus.BytesTotal, int64(1200))
+	test.S(t).ExpectFalse(cloneStatus.IsComplete())
+	cloneStatus.State = CloneStateFailed
+	test.S(t).ExpectTrue(cloneStatus.IsComplete())
+}
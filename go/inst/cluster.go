@@ -31,12 +31,16 @@ type ClusterInfo struct {
 	HeuristicLag                           int64
 	HasAutomatedMasterRecovery             bool
 	HasAutomatedIntermediateMasterRecovery bool
+	HasAutomatedReplicationRemediation     bool
+	HasAutomatedBinlogPurge                bool
 }
 
 // ReadRecoveryInfo
 func (this *ClusterInfo) ReadRecoveryInfo() {
 	this.HasAutomatedMasterRecovery = this.filtersMatchCluster(config.Config.RecoverMasterClusterFilters)
 	this.HasAutomatedIntermediateMasterRecovery = this.filtersMatchCluster(config.Config.RecoverIntermediateMasterClusterFilters)
+	this.HasAutomatedReplicationRemediation = this.filtersMatchCluster(config.Config.ReplicationRemediationClusterFilters)
+	this.HasAutomatedBinlogPurge = this.filtersMatchCluster(config.Config.BinlogPurgeClusterFilters)
 }
 
 // filtersMatchCluster will see whether the given filters match the given cluster details
@@ -64,7 +64,7 @@ func ReadAliasByClusterName(clusterName string) (alias string, err error) {
 		alias = m.GetString("alias")
 		return nil
 	})
-	return clusterName, err
+	return alias, err
 }
 
 // WriteClusterAlias will write (and override) a single cluster name mapping
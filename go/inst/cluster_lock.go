@@ -0,0 +1,99 @@
+/*
+   Copyright 2014 Outbrain Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package inst
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// ClusterLock is a cluster-scoped operation lock (also in the database). While held, topology
+// refactoring and recoveries on the cluster are only allowed for the holding operation.
+type ClusterLock struct {
+	ClusterName            string
+	Owner                  string
+	Reason                 string
+	ProcessingNodeHostname string
+	ProcessingNodeToken    string
+	AcquiredTimestamp      string
+	HeartbeatTimestamp     string
+}
+
+// clusterLockHolding is the state of a cluster lock acquired by this process. It is shared by the
+// acquiring operation and by the nested operations it hands its token to.
+type clusterLockHolding struct {
+	lost          int32
+	released      int32
+	stopHeartbeat chan bool
+}
+
+// ClusterLockToken is held by an operation which acquired a cluster lock. The lock is exclusive, also
+// within a single orchestrator process: a nested operation re-enters the lock only when explicitly handed
+// the token of its enclosing operation. A nil token is valid and holds nothing.
+type ClusterLockToken struct {
+	ClusterName string
+	token       string
+	holding     *clusterLockHolding
+	reentered   bool
+}
+
+func newClusterLockToken(clusterName string, token string) *ClusterLockToken {
+	return &ClusterLockToken{
+		ClusterName: clusterName,
+		token:       token,
+		holding:     &clusterLockHolding{stopHeartbeat: make(chan bool)},
+	}
+}
+
+// reenter returns a token for a nested operation. Releasing it does not release the lock.
+func (this *ClusterLockToken) reenter() *ClusterLockToken {
+	if this == nil {
+		return nil
+	}
+	return &ClusterLockToken{
+		ClusterName: this.ClusterName,
+		token:       this.token,
+		holding:     this.holding,
+		reentered:   true,
+	}
+}
+
+// isReleased returns true when the lock was released by the operation which acquired it
+func (this *ClusterLockToken) isReleased() bool {
+	return atomic.LoadInt32(&this.holding.released) == 1
+}
+
+func (this *ClusterLockToken) markLost() {
+	atomic.StoreInt32(&this.holding.lost, 1)
+}
+
+// Lost returns true when the lock was found to no longer be held by this token, e.g. it was
+// force-released, or was taken over after failing to heartbeat
+func (this *ClusterLockToken) Lost() bool {
+	if this == nil {
+		return false
+	}
+	return atomic.LoadInt32(&this.holding.lost) == 1
+}
+
+// Err returns an error when the lock has been lost, in which case the holding operation must abort
+func (this *ClusterLockToken) Err() error {
+	if this.Lost() {
+		return fmt.Errorf("Lost lock on cluster %s; aborting operation", this.ClusterName)
+	}
+	return nil
+}
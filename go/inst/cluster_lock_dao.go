@@ -0,0 +1,311 @@
+/*
+   Copyright 2014 Outbrain Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package inst
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/outbrain/golib/log"
+	"github.com/outbrain/golib/sqlutils"
+	"github.com/outbrain/orchestrator/go/config"
+	"github.com/outbrain/orchestrator/go/db"
+	"github.com/outbrain/orchestrator/go/process"
+)
+
+// readClusterLocks reads non-stale cluster locks, optionally filtered by cluster name
+func readClusterLocks(clusterName string) ([]ClusterLock, error) {
+	res := []ClusterLock{}
+	whereCondition := ``
+	args := sqlutils.Args(config.Config.ClusterLockTTLSeconds)
+	if clusterName != "" {
+		whereCondition = `and cluster_name = ?`
+		args = append(args, clusterName)
+	}
+	query := fmt.Sprintf(`
+		select
+			cluster_name,
+			owner,
+			reason,
+			processing_node_hostname,
+			processing_node_token,
+			acquired_timestamp,
+			heartbeat_timestamp
+		from
+			cluster_operation_lock
+		where
+			heartbeat_timestamp >= NOW() - INTERVAL ? SECOND
+			%s
+		order by
+			cluster_name
+		`, whereCondition)
+	err := db.QueryOrchestrator(query, args, func(m sqlutils.RowMap) error {
+		lock := ClusterLock{}
+		lock.ClusterName = m.GetString("cluster_name")
+		lock.Owner = m.GetString("owner")
+		lock.Reason = m.GetString("reason")
+		lock.ProcessingNodeHostname = m.GetString("processing_node_hostname")
+		lock.ProcessingNodeToken = m.GetString("processing_node_token")
+		lock.AcquiredTimestamp = m.GetString("acquired_timestamp")
+		lock.HeartbeatTimestamp = m.GetString("heartbeat_timestamp")
+
+		res = append(res, lock)
+		return nil
+	})
+	return res, log.Errore(err)
+}
+
+// ReadClusterLocks returns all currently held cluster locks
+func ReadClusterLocks() ([]ClusterLock, error) {
+	return readClusterLocks("")
+}
+
+// ReadClusterLock returns the lock held on given cluster, or nil when the cluster is not locked
+func ReadClusterLock(clusterName string) (*ClusterLock, error) {
+	locks, err := readClusterLocks(clusterName)
+	if err != nil || len(locks) == 0 {
+		return nil, err
+	}
+	return &locks[0], nil
+}
+
+// clusterLockStore persists cluster locks. Locks are kept in the backend database; tests substitute
+// an in-memory store.
+type clusterLockStore interface {
+	tryAcquire(clusterName string, owner string, reason string, token string) (bool, error)
+	heartbeat(clusterName string, token string) (bool, error)
+	release(clusterName string, token string) error
+	read(clusterName string) (*ClusterLock, error)
+}
+
+type backendClusterLockStore struct{}
+
+var clusterLocks clusterLockStore = &backendClusterLockStore{}
+
+// tryAcquire attempts to write a lock entry for given cluster, replacing a stale entry if such exists.
+func (this *backendClusterLockStore) tryAcquire(clusterName string, owner string, reason string, token string) (bool, error) {
+	_, err := db.ExecOrchestrator(`
+			delete from
+				cluster_operation_lock
+			where
+				cluster_name = ?
+				and heartbeat_timestamp < NOW() - INTERVAL ? SECOND
+			`, clusterName, config.Config.ClusterLockTTLSeconds,
+	)
+	if err != nil {
+		return false, log.Errore(err)
+	}
+	sqlResult, err := db.ExecOrchestrator(`
+			insert ignore
+				into cluster_operation_lock (
+					cluster_name, owner, reason, processing_node_hostname, processing_node_token, lock_token, acquired_timestamp, heartbeat_timestamp
+				) values (
+					?, ?, ?, ?, ?, ?, NOW(), NOW()
+				)
+			`, clusterName, owner, reason, process.ThisHostname, process.ProcessToken.Hash, token,
+	)
+	if err != nil {
+		return false, log.Errore(err)
+	}
+	rows, err := sqlResult.RowsAffected()
+	return (rows > 0), err
+}
+
+// heartbeat refreshes the lock entry of given token, and returns false when there is no such entry
+func (this *backendClusterLockStore) heartbeat(clusterName string, token string) (bool, error) {
+	sqlResult, err := db.ExecOrchestrator(`
+			update
+				cluster_operation_lock
+			set
+				heartbeat_timestamp = NOW()
+			where
+				cluster_name = ?
+				and lock_token = ?
+			`, clusterName, token,
+	)
+	if err != nil {
+		return true, log.Errore(err)
+	}
+	if rows, _ := sqlResult.RowsAffected(); rows > 0 {
+		return true, nil
+	}
+	// No rows changed: either the entry is gone, or it was refreshed within the same second
+	held := false
+	err = db.QueryOrchestrator(`
+			select
+				count(*) as held
+			from
+				cluster_operation_lock
+			where
+				cluster_name = ?
+				and lock_token = ?
+			`, sqlutils.Args(clusterName, token), func(m sqlutils.RowMap) error {
+		held = (m.GetInt("held") > 0)
+		return nil
+	})
+	if err != nil {
+		return true, log.Errore(err)
+	}
+	return held, nil
+}
+
+// release deletes the lock entry of given token, if it still exists
+func (this *backendClusterLockStore) release(clusterName string, token string) error {
+	_, err := db.ExecOrchestrator(`
+			delete from
+				cluster_operation_lock
+			where
+				cluster_name = ?
+				and lock_token = ?
+			`, clusterName, token,
+	)
+	return log.Errore(err)
+}
+
+func (this *backendClusterLockStore) read(clusterName string) (*ClusterLock, error) {
+	return ReadClusterLock(clusterName)
+}
+
+// refreshClusterLock heartbeats given lock. It marks the lock as lost and returns false when the lock
+// is no longer held by the token.
+func refreshClusterLock(clusterLock *ClusterLockToken) bool {
+	held, err := clusterLocks.heartbeat(clusterLock.ClusterName, clusterLock.token)
+	if err != nil {
+		// Cannot tell; the lock goes stale should the backend remain unavailable
+		return true
+	}
+	if !held {
+		clusterLock.markLost()
+		log.Errorf("Lost lock on cluster %s; the operation holding it will abort", clusterLock.ClusterName)
+		return false
+	}
+	return true
+}
+
+// heartbeatClusterLock periodically refreshes given lock until it is released or lost
+func heartbeatClusterLock(clusterLock *ClusterLockToken) {
+	interval := time.Duration(config.Config.ClusterLockTTLSeconds) * time.Second / 3
+	if interval < time.Second {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if !refreshClusterLock(clusterLock) {
+				return
+			}
+		case <-clusterLock.holding.stopHeartbeat:
+			return
+		}
+	}
+}
+
+// Release releases the lock. Releasing a re-entered (nested) token, a nil token, or an already
+// released token is a no-op.
+func (this *ClusterLockToken) Release() {
+	if this == nil || this.reentered {
+		return
+	}
+	if !atomic.CompareAndSwapInt32(&this.holding.released, 0, 1) {
+		return
+	}
+	close(this.holding.stopHeartbeat)
+	if err := clusterLocks.release(this.ClusterName, this.token); err != nil {
+		return
+	}
+	log.Debugf("Released cluster lock on %s", this.ClusterName)
+}
+
+// AcquireClusterLock takes the operation lock on given cluster. An operation nested within a locked
+// operation passes the token of the enclosing operation as heldLock, and re-enters that lock. Otherwise,
+// if the lock is held (by any operation, of this or any other orchestrator process), it waits up to
+// ClusterLockWaitSeconds for the lock to be released (or to go stale) and then fails.
+// The returned token is released via its Release() method.
+func AcquireClusterLock(clusterName string, owner string, reason string, heldLock *ClusterLockToken) (*ClusterLockToken, error) {
+	if clusterName == "" || config.Config.DatabaselessMode__experimental {
+		return heldLock.reenter(), nil
+	}
+	if heldLock != nil && heldLock.ClusterName == clusterName && !heldLock.isReleased() {
+		if err := heldLock.Err(); err != nil {
+			return nil, err
+		}
+		return heldLock.reenter(), nil
+	}
+	token := process.NewToken().Hash
+	startTime := time.Now()
+	for {
+		acquired, err := clusterLocks.tryAcquire(clusterName, owner, reason, token)
+		if err != nil {
+			return nil, err
+		}
+		if acquired {
+			clusterLock := newClusterLockToken(clusterName, token)
+			go heartbeatClusterLock(clusterLock)
+			log.Debugf("Acquired cluster lock on %s; owner: %s, reason: %s", clusterName, owner, reason)
+			return clusterLock, nil
+		}
+		if time.Since(startTime) >= time.Duration(config.Config.ClusterLockWaitSeconds)*time.Second {
+			break
+		}
+		time.Sleep(time.Second)
+	}
+	lock, _ := clusterLocks.read(clusterName)
+	if lock == nil {
+		return nil, fmt.Errorf("Cannot acquire lock on cluster %s", clusterName)
+	}
+	return nil, fmt.Errorf("Cluster %s is locked by %s on %s since %s; reason: %s", clusterName, lock.Owner, lock.ProcessingNodeHostname, lock.AcquiredTimestamp, lock.Reason)
+}
+
+// AcquireClusterLockOfInstance takes the operation lock on the cluster of given instance, on behalf of
+// the named topology operation, re-entering heldLock if given. Instances of unknown cluster are not locked.
+func AcquireClusterLockOfInstance(instanceKey *InstanceKey, operation string, heldLock *ClusterLockToken) (*ClusterLockToken, error) {
+	if instanceKey == nil {
+		return heldLock.reenter(), nil
+	}
+	instance, found, err := ReadInstance(instanceKey)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return heldLock.reenter(), nil
+	}
+	return AcquireClusterLock(instance.ClusterName, GetMaintenanceOwner(), fmt.Sprintf("%s %+v", operation, *instanceKey), heldLock)
+}
+
+// ForceReleaseClusterLock removes the lock on given cluster, whoever holds it. The holding operation
+// finds out on its next heartbeat, and aborts on its next step; this is intended for cleaning up after
+// a stuck operation.
+func ForceReleaseClusterLock(clusterName string) error {
+	sqlResult, err := db.ExecOrchestrator(`
+			delete from
+				cluster_operation_lock
+			where
+				cluster_name = ?
+			`, clusterName,
+	)
+	if err != nil {
+		return log.Errore(err)
+	}
+	if rows, _ := sqlResult.RowsAffected(); rows == 0 {
+		return fmt.Errorf("Cluster %s is not locked", clusterName)
+	}
+	AuditOperation("force-release-cluster-lock", nil, clusterName)
+	return nil
+}
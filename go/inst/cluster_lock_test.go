@@ -0,0 +1,204 @@
+/*
+   Copyright 2014 Outbrain Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package inst
+
+import (
+	"sync"
+	"testing"
+
+	test "github.com/outbrain/golib/tests"
+	"github.com/outbrain/orchestrator/go/config"
+)
+
+// memoryClusterLockStore is a clusterLockStore for tests. A lock marked stale may be taken over.
+type memoryClusterLockStore struct {
+	mutex  sync.Mutex
+	tokens map[string]string
+	stale  map[string]bool
+}
+
+func newMemoryClusterLockStore() *memoryClusterLockStore {
+	return &memoryClusterLockStore{tokens: make(map[string]string), stale: make(map[string]bool)}
+}
+
+func (this *memoryClusterLockStore) tryAcquire(clusterName string, owner string, reason string, token string) (bool, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if _, found := this.tokens[clusterName]; found && !this.stale[clusterName] {
+		return false, nil
+	}
+	this.tokens[clusterName] = token
+	this.stale[clusterName] = false
+	return true, nil
+}
+
+func (this *memoryClusterLockStore) heartbeat(clusterName string, token string) (bool, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.tokens[clusterName] == token, nil
+}
+
+func (this *memoryClusterLockStore) release(clusterName string, token string) error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if this.tokens[clusterName] == token {
+		delete(this.tokens, clusterName)
+	}
+	return nil
+}
+
+func (this *memoryClusterLockStore) read(clusterName string) (*ClusterLock, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if _, found := this.tokens[clusterName]; !found {
+		return nil, nil
+	}
+	return &ClusterLock{ClusterName: clusterName, Owner: "test"}, nil
+}
+
+func (this *memoryClusterLockStore) holder(clusterName string) string {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.tokens[clusterName]
+}
+
+func (this *memoryClusterLockStore) expire(clusterName string) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.stale[clusterName] = true
+}
+
+func withMemoryClusterLockStore(f func(store *memoryClusterLockStore)) {
+	store := newMemoryClusterLockStore()
+	originalStore := clusterLocks
+	originalWaitSeconds := config.Config.ClusterLockWaitSeconds
+	clusterLocks = store
+	config.Config.ClusterLockWaitSeconds = 0
+	defer func() {
+		clusterLocks = originalStore
+		config.Config.ClusterLockWaitSeconds = originalWaitSeconds
+	}()
+	f(store)
+}
+
+func TestAcquireClusterLock(t *testing.T) {
+	withMemoryClusterLockStore(func(store *memoryClusterLockStore) {
+		clusterLock, err := AcquireClusterLock("c1", "test", "first", nil)
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(store.holder("c1"), clusterLock.token)
+
+		// The lock is exclusive within this process, too
+		_, err = AcquireClusterLock("c1", "test", "second", nil)
+		test.S(t).ExpectNotNil(err)
+
+		otherClusterLock, err := AcquireClusterLock("c2", "test", "other", nil)
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectNotEquals(otherClusterLock.token, clusterLock.token)
+
+		clusterLock.Release()
+		otherClusterLock.Release()
+		test.S(t).ExpectEquals(store.holder("c1"), "")
+		test.S(t).ExpectEquals(store.holder("c2"), "")
+
+		clusterLock, err = AcquireClusterLock("c1", "test", "third", nil)
+		test.S(t).ExpectNil(err)
+		clusterLock.Release()
+	})
+}
+
+func TestAcquireClusterLockNoCluster(t *testing.T) {
+	withMemoryClusterLockStore(func(store *memoryClusterLockStore) {
+		clusterLock, err := AcquireClusterLock("", "test", "no cluster", nil)
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectTrue(clusterLock == nil)
+		// Releasing a nil token is a no-op
+		clusterLock.Release()
+		test.S(t).ExpectNil(clusterLock.Err())
+	})
+}
+
+func TestReenterClusterLock(t *testing.T) {
+	withMemoryClusterLockStore(func(store *memoryClusterLockStore) {
+		clusterLock, err := AcquireClusterLock("c1", "test", "outer", nil)
+		test.S(t).ExpectNil(err)
+
+		nestedLock, err := AcquireClusterLock("c1", "test", "nested", clusterLock)
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(nestedLock.token, clusterLock.token)
+
+		nestedLock.Release()
+		test.S(t).ExpectEquals(store.holder("c1"), clusterLock.token)
+		_, err = AcquireClusterLock("c1", "test", "other", nil)
+		test.S(t).ExpectNotNil(err)
+
+		// A token of another cluster does not re-enter
+		otherClusterLock, err := AcquireClusterLock("c2", "test", "other cluster", clusterLock)
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectNotEquals(otherClusterLock.token, clusterLock.token)
+		otherClusterLock.Release()
+
+		clusterLock.Release()
+		test.S(t).ExpectEquals(store.holder("c1"), "")
+
+		// A released token is not re-entered; the lock is acquired anew
+		reacquiredLock, err := AcquireClusterLock("c1", "test", "after release", clusterLock)
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectNotEquals(reacquiredLock.token, clusterLock.token)
+		reacquiredLock.Release()
+	})
+}
+
+func TestReleaseClusterLock(t *testing.T) {
+	withMemoryClusterLockStore(func(store *memoryClusterLockStore) {
+		clusterLock, err := AcquireClusterLock("c1", "test", "first", nil)
+		test.S(t).ExpectNil(err)
+		clusterLock.Release()
+		otherLock, err := AcquireClusterLock("c1", "test", "second", nil)
+		test.S(t).ExpectNil(err)
+
+		// Releasing again does not release someone else's lock
+		clusterLock.Release()
+		test.S(t).ExpectEquals(store.holder("c1"), otherLock.token)
+		otherLock.Release()
+	})
+}
+
+func TestExpiredClusterLock(t *testing.T) {
+	withMemoryClusterLockStore(func(store *memoryClusterLockStore) {
+		clusterLock, err := AcquireClusterLock("c1", "test", "stuck", nil)
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectTrue(refreshClusterLock(clusterLock))
+		test.S(t).ExpectFalse(clusterLock.Lost())
+
+		store.expire("c1")
+		takeoverLock, err := AcquireClusterLock("c1", "test", "takeover", nil)
+		test.S(t).ExpectNil(err)
+
+		test.S(t).ExpectFalse(refreshClusterLock(clusterLock))
+		test.S(t).ExpectTrue(clusterLock.Lost())
+		test.S(t).ExpectNotNil(clusterLock.Err())
+
+		// Nested operations of the lost lock abort
+		_, err = AcquireClusterLock("c1", "test", "nested", clusterLock)
+		test.S(t).ExpectNotNil(err)
+
+		clusterLock.Release()
+		test.S(t).ExpectEquals(store.holder("c1"), takeoverLock.token)
+		test.S(t).ExpectTrue(refreshClusterLock(takeoverLock))
+		takeoverLock.Release()
+	})
+}
@@ -0,0 +1,241 @@
+/*
+   Copyright 2014 Outbrain Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package inst
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	DesiredTopologyMoveUp     = "move-up"
+	DesiredTopologyMoveGTID   = "move-gtid"
+	DesiredTopologyMatchBelow = "match-below"
+	DesiredTopologyRelocate   = "relocate"
+)
+
+const (
+	DesiredTopologyInPlace   = "in-place"
+	DesiredTopologyMisplaced = "misplaced"
+	DesiredTopologyUnknown   = "unknown"
+	DesiredTopologyUnmanaged = "unmanaged"
+)
+
+// DesiredTopologyNode is a node in a desired topology tree: an instance and its desired replicas.
+// The root of the tree is not moved; all other instances are to replicate from their parent node.
+type DesiredTopologyNode struct {
+	Key      string
+	Replicas []*DesiredTopologyNode
+}
+
+// DesiredTopologyStep is a single refactoring operation towards a desired topology
+type DesiredTopologyStep struct {
+	Operation string
+	Key       InstanceKey
+	BelowKey  InstanceKey
+}
+
+// DesiredTopologyDiffEntry compares an instance's current master with its desired master
+type DesiredTopologyDiffEntry struct {
+	Key              InstanceKey
+	CurrentMasterKey InstanceKey
+	DesiredMasterKey InstanceKey
+	Status           string
+}
+
+// ParseDesiredTopology reads a desired topology tree from JSON or YAML. Input starting with "{" is
+// parsed as JSON; anything else as YAML. Field names are case insensitive in both.
+func ParseDesiredTopology(data []byte) (*DesiredTopologyNode, error) {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] != '{' {
+		var parsed interface{}
+		if err := yaml.Unmarshal(data, &parsed); err != nil {
+			return nil, fmt.Errorf("Cannot parse desired topology: %+v", err)
+		}
+		jsonData, err := json.Marshal(yamlToJSONCompatible(parsed))
+		if err != nil {
+			return nil, fmt.Errorf("Cannot parse desired topology: %+v", err)
+		}
+		data = jsonData
+	}
+	desired := &DesiredTopologyNode{}
+	if err := json.Unmarshal(data, desired); err != nil {
+		return nil, fmt.Errorf("Cannot parse desired topology: %+v", err)
+	}
+	if desired.Key == "" {
+		return nil, fmt.Errorf("Desired topology has no root Key")
+	}
+	return desired, nil
+}
+
+// yamlToJSONCompatible converts the maps of parsed YAML, which may have non-string keys, into string keyed maps
+func yamlToJSONCompatible(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[interface{}]interface{}:
+		converted := make(map[string]interface{})
+		for key, element := range value {
+			converted[fmt.Sprintf("%v", key)] = yamlToJSONCompatible(element)
+		}
+		return converted
+	case []interface{}:
+		for i := range value {
+			value[i] = yamlToJSONCompatible(value[i])
+		}
+		return value
+	}
+	return value
+}
+
+// String returns a readable description of this step
+func (this *DesiredTopologyStep) String() string {
+	return fmt.Sprintf("%s %+v below %+v", this.Operation, this.Key, this.BelowKey)
+}
+
+// desiredMasters parses this tree into its root key, the desired master of each non-root instance,
+// and the non-root instances in top-down (breadth first) order.
+func (this *DesiredTopologyNode) desiredMasters() (rootKey InstanceKey, masters map[InstanceKey]InstanceKey, order []InstanceKey, err error) {
+	masters = make(map[InstanceKey]InstanceKey)
+	parsedRootKey, err := ParseRawInstanceKeyLoose(this.Key)
+	if err != nil {
+		return rootKey, masters, order, err
+	}
+	rootKey = *parsedRootKey
+	seen := map[InstanceKey]bool{rootKey: true}
+
+	type queuedNode struct {
+		node      *DesiredTopologyNode
+		masterKey InstanceKey
+	}
+	queue := []queuedNode{}
+	for _, replica := range this.Replicas {
+		queue = append(queue, queuedNode{replica, rootKey})
+	}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if current.node == nil {
+			continue
+		}
+		key, err := ParseRawInstanceKeyLoose(current.node.Key)
+		if err != nil {
+			return rootKey, masters, order, err
+		}
+		if seen[*key] {
+			return rootKey, masters, order, fmt.Errorf("Instance %+v appears more than once in desired topology", *key)
+		}
+		seen[*key] = true
+		masters[*key] = current.masterKey
+		order = append(order, *key)
+		for _, replica := range current.node.Replicas {
+			queue = append(queue, queuedNode{replica, *key})
+		}
+	}
+	return rootKey, masters, order, nil
+}
+
+// chooseDesiredTopologyOperation picks the operation for moving given instance below given target,
+// where currentGrandMasterKey is the instance's master's master at time of the move.
+func chooseDesiredTopologyOperation(instance *Instance, target *Instance, currentGrandMasterKey *InstanceKey) string {
+	if currentGrandMasterKey != nil && currentGrandMasterKey.Equals(&target.Key) {
+		return DesiredTopologyMoveUp
+	}
+	if instance.UsingGTID() && target.SupportsOracleGTID {
+		return DesiredTopologyMoveGTID
+	}
+	if instance.UsingPseudoGTID && target.UsingPseudoGTID {
+		return DesiredTopologyMatchBelow
+	}
+	return DesiredTopologyRelocate
+}
+
+// planDesiredTopology computes the steps leading given instances to the desired topology. Only instances
+// whose master differs from their desired master are moved; replicas follow their master implicitly.
+// Moves are planned top-down, such that an instance is only moved below an already-placed instance.
+func planDesiredTopology(desired *DesiredTopologyNode, instances [](*Instance)) (steps []DesiredTopologyStep, err error) {
+	rootKey, desiredMasters, order, err := desired.desiredMasters()
+	if err != nil {
+		return steps, err
+	}
+	instancesMap := make(map[InstanceKey]*Instance)
+	simulatedMasters := make(map[InstanceKey]InstanceKey)
+	for _, instance := range instances {
+		instancesMap[instance.Key] = instance
+		simulatedMasters[instance.Key] = instance.MasterKey
+	}
+	if _, found := instancesMap[rootKey]; !found {
+		return steps, fmt.Errorf("Desired topology root %+v not found in cluster", rootKey)
+	}
+	for _, key := range order {
+		if _, found := instancesMap[key]; !found {
+			return steps, fmt.Errorf("Desired topology instance %+v not found in cluster", key)
+		}
+	}
+	for _, key := range order {
+		desiredMasterKey := desiredMasters[key]
+		currentMasterKey := simulatedMasters[key]
+		if currentMasterKey.Equals(&desiredMasterKey) {
+			continue
+		}
+		var currentGrandMasterKey *InstanceKey
+		if grandMasterKey, found := simulatedMasters[currentMasterKey]; found {
+			currentGrandMasterKey = &grandMasterKey
+		}
+		step := DesiredTopologyStep{
+			Operation: chooseDesiredTopologyOperation(instancesMap[key], instancesMap[desiredMasterKey], currentGrandMasterKey),
+			Key:       key,
+			BelowKey:  desiredMasterKey,
+		}
+		steps = append(steps, step)
+		simulatedMasters[key] = desiredMasterKey
+	}
+	return steps, nil
+}
+
+// diffDesiredTopology compares given instances with the desired topology
+func diffDesiredTopology(desired *DesiredTopologyNode, instances [](*Instance)) (diff []DesiredTopologyDiffEntry, err error) {
+	rootKey, desiredMasters, order, err := desired.desiredMasters()
+	if err != nil {
+		return diff, err
+	}
+	instancesMap := make(map[InstanceKey]*Instance)
+	for _, instance := range instances {
+		instancesMap[instance.Key] = instance
+	}
+	for _, key := range order {
+		entry := DesiredTopologyDiffEntry{Key: key, DesiredMasterKey: desiredMasters[key], Status: DesiredTopologyUnknown}
+		if instance, found := instancesMap[key]; found {
+			entry.CurrentMasterKey = instance.MasterKey
+			entry.Status = DesiredTopologyMisplaced
+			if instance.MasterKey.Equals(&entry.DesiredMasterKey) {
+				entry.Status = DesiredTopologyInPlace
+			}
+		}
+		diff = append(diff, entry)
+	}
+	for _, instance := range instances {
+		if _, found := desiredMasters[instance.Key]; found {
+			continue
+		}
+		if instance.Key.Equals(&rootKey) {
+			continue
+		}
+		diff = append(diff, DesiredTopologyDiffEntry{Key: instance.Key, CurrentMasterKey: instance.MasterKey, Status: DesiredTopologyUnmanaged})
+	}
+	return diff, nil
+}
@@ -0,0 +1,172 @@
+/*
+   Copyright 2014 Outbrain Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package inst
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/outbrain/golib/log"
+	"github.com/outbrain/orchestrator/go/config"
+)
+
+// formalizeDesiredTopology resolves the hostnames of all keys in given desired topology tree
+func formalizeDesiredTopology(node *DesiredTopologyNode) error {
+	if node == nil {
+		return nil
+	}
+	key, err := ParseInstanceKeyLoose(node.Key)
+	if err != nil {
+		return err
+	}
+	node.Key = key.StringCode()
+	for _, replica := range node.Replicas {
+		if err := formalizeDesiredTopology(replica); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PlanDesiredTopology returns the refactoring steps which would lead given cluster to the desired topology
+func PlanDesiredTopology(clusterName string, desired *DesiredTopologyNode) ([]DesiredTopologyStep, error) {
+	if err := formalizeDesiredTopology(desired); err != nil {
+		return nil, err
+	}
+	instances, err := ReadClusterInstances(clusterName)
+	if err != nil {
+		return nil, err
+	}
+	return planDesiredTopology(desired, instances)
+}
+
+// DiffDesiredTopology compares the current topology of given cluster with the desired topology
+func DiffDesiredTopology(clusterName string, desired *DesiredTopologyNode) ([]DesiredTopologyDiffEntry, error) {
+	if err := formalizeDesiredTopology(desired); err != nil {
+		return nil, err
+	}
+	instances, err := ReadClusterInstances(clusterName)
+	if err != nil {
+		return nil, err
+	}
+	return diffDesiredTopology(desired, instances)
+}
+
+// waitForReasonableMaintenanceLag waits until given instances have reasonable replication lag, up to
+// ReconcileTopologyLagWaitSeconds
+func waitForReasonableMaintenanceLag(instanceKeys ...*InstanceKey) error {
+	startTime := time.Now()
+	for {
+		laggingKeys := []InstanceKey{}
+		for _, instanceKey := range instanceKeys {
+			instance, err := ReadTopologyInstanceUnbuffered(instanceKey)
+			if err != nil {
+				return err
+			}
+			if !instance.HasReasonableMaintenanceReplicationLag() {
+				laggingKeys = append(laggingKeys, *instanceKey)
+			}
+		}
+		if len(laggingKeys) == 0 {
+			return nil
+		}
+		if time.Since(startTime) >= time.Duration(config.Config.ReconcileTopologyLagWaitSeconds)*time.Second {
+			return fmt.Errorf("Replication lag too high on %+v", laggingKeys)
+		}
+		log.Debugf("waitForReasonableMaintenanceLag: lag too high on %+v; waiting", laggingKeys)
+		time.Sleep(time.Second)
+	}
+}
+
+// executeDesiredTopologyStep runs a single refactoring step, and verifies the instance ends up below its target
+func executeDesiredTopologyStep(clusterLock *ClusterLockToken, step *DesiredTopologyStep) error {
+	if err := clusterLock.Err(); err != nil {
+		return err
+	}
+	if err := waitForReasonableMaintenanceLag(&step.Key, &step.BelowKey); err != nil {
+		return err
+	}
+	var err error
+	switch step.Operation {
+	case DesiredTopologyMoveUp:
+		_, err = MoveUpUnderLock(clusterLock, &step.Key)
+	case DesiredTopologyMoveGTID:
+		_, err = MoveBelowGTIDUnderLock(clusterLock, &step.Key, &step.BelowKey)
+	case DesiredTopologyMatchBelow:
+		_, _, err = MatchBelowUnderLock(clusterLock, &step.Key, &step.BelowKey, true)
+	case DesiredTopologyRelocate:
+		_, err = RelocateBelowUnderLock(clusterLock, &step.Key, &step.BelowKey)
+	default:
+		err = fmt.Errorf("Unsupported desired topology operation: %s", step.Operation)
+	}
+	if err != nil {
+		return err
+	}
+	instance, err := ReadTopologyInstanceUnbuffered(&step.Key)
+	if err != nil {
+		return err
+	}
+	if !instance.MasterKey.Equals(&step.BelowKey) {
+		return fmt.Errorf("%s: %+v ended up below %+v", step.String(), step.Key, instance.MasterKey)
+	}
+	return nil
+}
+
+// ReconcileDesiredTopology refactors given cluster towards the desired topology, one step at a time.
+// The plan is re-evaluated after each step. Execution stops on first failure. In any case the
+// executed steps and the remaining diff between current and desired topology are returned.
+func ReconcileDesiredTopology(clusterName string, desired *DesiredTopologyNode) (executedSteps []DesiredTopologyStep, diff []DesiredTopologyDiffEntry, err error) {
+	steps, err := PlanDesiredTopology(clusterName, desired)
+	if err != nil {
+		return executedSteps, diff, err
+	}
+	if *config.RuntimeCLIFlags.Noop {
+		diff, _ = DiffDesiredTopology(clusterName, desired)
+		return executedSteps, diff, fmt.Errorf("noop: aborting reconcile-topology operation on %s; signalling error but nothing went wrong.", clusterName)
+	}
+	clusterLock, err := AcquireClusterLock(clusterName, GetMaintenanceOwner(), "reconcile-topology", nil)
+	if err != nil {
+		return executedSteps, diff, err
+	}
+	defer clusterLock.Release()
+
+	// A re-planned step may differ from the original, but the number of steps cannot grow
+	// beyond the number of moved instances
+	maxSteps := len(steps)
+	for len(steps) > 0 && len(executedSteps) < maxSteps {
+		step := steps[0]
+		log.Infof("reconcile-topology: %s", step.String())
+		if err = executeDesiredTopologyStep(clusterLock, &step); err != nil {
+			err = fmt.Errorf("reconcile-topology: failed %s: %+v", step.String(), err)
+			break
+		}
+		executedSteps = append(executedSteps, step)
+		AuditOperation("reconcile-topology", &step.Key, step.String())
+
+		if steps, err = PlanDesiredTopology(clusterName, desired); err != nil {
+			break
+		}
+	}
+	if err == nil && len(steps) > 0 {
+		err = fmt.Errorf("reconcile-topology: %s has not converged after %d steps", clusterName, len(executedSteps))
+	}
+	diff, diffErr := DiffDesiredTopology(clusterName, desired)
+	if diffErr != nil {
+		log.Errore(diffErr)
+	}
+	return executedSteps, diff, log.Errore(err)
+}
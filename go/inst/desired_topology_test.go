@@ -0,0 +1,139 @@
+package inst
+
+import (
+	test "github.com/outbrain/golib/tests"
+	"testing"
+)
+
+// generateDesiredTopologyTestInstances returns: i710 is master of i720, i730; i720 is master of i810
+func generateDesiredTopologyTestInstances() (instances [](*Instance), instancesMap map[string](*Instance)) {
+	instances, instancesMap = generateTestInstances()
+	instances = instances[0:4]
+	instancesMap[i720Key.StringCode()].MasterKey = i710Key
+	instancesMap[i730Key.StringCode()].MasterKey = i710Key
+	instancesMap[i810Key.StringCode()].MasterKey = i720Key
+	return instances, instancesMap
+}
+
+func TestParseDesiredTopology(t *testing.T) {
+	desired, err := ParseDesiredTopology([]byte(`{"Key": "i710:3306", "Replicas": [{"Key": "i720:3306"}, {"Key": "i730:3306", "Replicas": [{"Key": "i810:3306"}]}]}`))
+	test.S(t).ExpectNil(err)
+	test.S(t).ExpectEquals(desired.Key, "i710:3306")
+	test.S(t).ExpectEquals(len(desired.Replicas), 2)
+	test.S(t).ExpectEquals(desired.Replicas[1].Replicas[0].Key, "i810:3306")
+}
+
+func TestParseDesiredTopologyYAML(t *testing.T) {
+	desired, err := ParseDesiredTopology([]byte(`
+key: i710:3306
+replicas:
+  - key: i720:3306
+  - Key: i730:3306
+    Replicas:
+      - key: i810:3306
+`))
+	test.S(t).ExpectNil(err)
+	test.S(t).ExpectEquals(desired.Key, "i710:3306")
+	test.S(t).ExpectEquals(len(desired.Replicas), 2)
+	test.S(t).ExpectEquals(desired.Replicas[1].Key, "i730:3306")
+	test.S(t).ExpectEquals(desired.Replicas[1].Replicas[0].Key, "i810:3306")
+
+	_, err = ParseDesiredTopology([]byte("key: [i710:3306"))
+	test.S(t).ExpectNotNil(err)
+	_, err = ParseDesiredTopology([]byte("replicas: []"))
+	test.S(t).ExpectNotNil(err)
+}
+
+func TestParseDesiredTopologyInvalid(t *testing.T) {
+	_, err := ParseDesiredTopology([]byte(`{"Key": "i710:3306"`))
+	test.S(t).ExpectNotNil(err)
+	_, err = ParseDesiredTopology([]byte(`{"Replicas": []}`))
+	test.S(t).ExpectNotNil(err)
+}
+
+func TestPlanDesiredTopologyInPlace(t *testing.T) {
+	instances, _ := generateDesiredTopologyTestInstances()
+	desired, _ := ParseDesiredTopology([]byte(`{"Key": "i710:3306", "Replicas": [{"Key": "i720:3306", "Replicas": [{"Key": "i810:3306"}]}, {"Key": "i730:3306"}]}`))
+	steps, err := planDesiredTopology(desired, instances)
+	test.S(t).ExpectNil(err)
+	test.S(t).ExpectEquals(len(steps), 0)
+}
+
+func TestPlanDesiredTopologyMoveUp(t *testing.T) {
+	instances, _ := generateDesiredTopologyTestInstances()
+	desired, _ := ParseDesiredTopology([]byte(`{"Key": "i710:3306", "Replicas": [{"Key": "i720:3306"}, {"Key": "i730:3306"}, {"Key": "i810:3306"}]}`))
+	steps, err := planDesiredTopology(desired, instances)
+	test.S(t).ExpectNil(err)
+	test.S(t).ExpectEquals(len(steps), 1)
+	test.S(t).ExpectEquals(steps[0].Operation, DesiredTopologyMoveUp)
+	test.S(t).ExpectEquals(steps[0].Key, i810Key)
+	test.S(t).ExpectEquals(steps[0].BelowKey, i710Key)
+}
+
+func TestPlanDesiredTopologyOrdering(t *testing.T) {
+	instances, instancesMap := generateDesiredTopologyTestInstances()
+	for _, instance := range instances {
+		instance.UsingPseudoGTID = true
+	}
+	instancesMap[i730Key.StringCode()].UsingPseudoGTID = false
+	// i810 is to move below i730, which itself is to move below i720
+	desired, _ := ParseDesiredTopology([]byte(`{"Key": "i710:3306", "Replicas": [{"Key": "i720:3306", "Replicas": [{"Key": "i730:3306", "Replicas": [{"Key": "i810:3306"}]}]}]}`))
+	steps, err := planDesiredTopology(desired, instances)
+	test.S(t).ExpectNil(err)
+	test.S(t).ExpectEquals(len(steps), 2)
+	test.S(t).ExpectEquals(steps[0].Key, i730Key)
+	test.S(t).ExpectEquals(steps[0].BelowKey, i720Key)
+	test.S(t).ExpectEquals(steps[0].Operation, DesiredTopologyRelocate)
+	test.S(t).ExpectEquals(steps[1].Key, i810Key)
+	test.S(t).ExpectEquals(steps[1].BelowKey, i730Key)
+	test.S(t).ExpectEquals(steps[1].Operation, DesiredTopologyRelocate)
+}
+
+func TestPlanDesiredTopologyMatchBelow(t *testing.T) {
+	instances, _ := generateDesiredTopologyTestInstances()
+	for _, instance := range instances {
+		instance.UsingPseudoGTID = true
+	}
+	desired, _ := ParseDesiredTopology([]byte(`{"Key": "i710:3306", "Replicas": [{"Key": "i720:3306"}, {"Key": "i730:3306", "Replicas": [{"Key": "i810:3306"}]}]}`))
+	steps, err := planDesiredTopology(desired, instances)
+	test.S(t).ExpectNil(err)
+	test.S(t).ExpectEquals(len(steps), 1)
+	test.S(t).ExpectEquals(steps[0].Operation, DesiredTopologyMatchBelow)
+	test.S(t).ExpectEquals(steps[0].Key, i810Key)
+	test.S(t).ExpectEquals(steps[0].BelowKey, i730Key)
+}
+
+func TestPlanDesiredTopologyInvalid(t *testing.T) {
+	instances, _ := generateDesiredTopologyTestInstances()
+	{
+		desired, _ := ParseDesiredTopology([]byte(`{"Key": "i710:3306", "Replicas": [{"Key": "i720:3306"}, {"Key": "i720:3306"}]}`))
+		_, err := planDesiredTopology(desired, instances)
+		test.S(t).ExpectNotNil(err)
+	}
+	{
+		desired, _ := ParseDesiredTopology([]byte(`{"Key": "i710:3306", "Replicas": [{"Key": "i999:3306"}]}`))
+		_, err := planDesiredTopology(desired, instances)
+		test.S(t).ExpectNotNil(err)
+	}
+	{
+		desired, _ := ParseDesiredTopology([]byte(`{"Key": "i999:3306", "Replicas": [{"Key": "i720:3306"}]}`))
+		_, err := planDesiredTopology(desired, instances)
+		test.S(t).ExpectNotNil(err)
+	}
+}
+
+func TestDiffDesiredTopology(t *testing.T) {
+	instances, _ := generateDesiredTopologyTestInstances()
+	desired, _ := ParseDesiredTopology([]byte(`{"Key": "i710:3306", "Replicas": [{"Key": "i720:3306"}, {"Key": "i810:3306"}, {"Key": "i999:3306"}]}`))
+	diff, err := diffDesiredTopology(desired, instances)
+	test.S(t).ExpectNil(err)
+	test.S(t).ExpectEquals(len(diff), 4)
+	test.S(t).ExpectEquals(diff[0].Key, i720Key)
+	test.S(t).ExpectEquals(diff[0].Status, DesiredTopologyInPlace)
+	test.S(t).ExpectEquals(diff[1].Key, i810Key)
+	test.S(t).ExpectEquals(diff[1].Status, DesiredTopologyMisplaced)
+	test.S(t).ExpectEquals(diff[1].CurrentMasterKey, i720Key)
+	test.S(t).ExpectEquals(diff[2].Status, DesiredTopologyUnknown)
+	test.S(t).ExpectEquals(diff[3].Key, i730Key)
+	test.S(t).ExpectEquals(diff[3].Status, DesiredTopologyUnmanaged)
+}
@@ -80,8 +80,11 @@ type Instance struct {
 	RelaylogCoordinates    BinlogCoordinates
 	LastSQLError           string
 	LastIOError            string
+	LastSQLErrno           uint
+	LastIOErrno            uint
 	SecondsBehindMaster    sql.NullInt64
 	SQLDelay               uint
+	IsDelayedSlave         bool
 	ExecutedGtidSet        string
 	GtidPurged             string
 
@@ -206,6 +209,11 @@ func (this *Instance) IsOracleMySQL() bool {
 	return true
 }
 
+// SupportsClone checks whether this is an Oracle MySQL version shipping the CLONE plugin
+func (this *Instance) SupportsClone() bool {
+	return this.IsOracleMySQL() && supportsCloneVersion(this.Version)
+}
+
 // NameAndMarjorVersionString returns something like MariaDB-10.1 MaxScale-1.4 MySQL-5.7
 func (instance *Instance) NameAndMajorVersionString() string {
 	var name string
@@ -332,13 +340,20 @@ func (this *Instance) CanReplicateFrom(other *Instance) (bool, error) {
 	return true, nil
 }
 
+// LagBeyondSQLDelay returns given lag as measured relative to this instance's configured SQL_Delay.
+// A delayed slave is expected to lag by its delay; it only truly lags when exceeding it. A delayed slave
+// may also lag by less than its delay (e.g. quiet master), which we do not consider as lag at all.
+func (this *Instance) LagBeyondSQLDelay(lagSeconds int64) int64 {
+	if this.SQLDelay == 0 {
+		return lagSeconds
+	}
+	return math.MaxInt64(lagSeconds-int64(this.SQLDelay), 0)
+}
+
 // HasReasonableMaintenanceReplicationLag returns true when the slave lag is reasonable, and maintenance operations should have a green light to go.
 func (this *Instance) HasReasonableMaintenanceReplicationLag() bool {
 	// Slaves with SQLDelay are a special case
-	if this.SQLDelay > 0 {
-		return math.AbsInt64(this.SecondsBehindMaster.Int64-int64(this.SQLDelay)) <= int64(config.Config.ReasonableMaintenanceReplicationLagSeconds)
-	}
-	return this.SecondsBehindMaster.Int64 <= int64(config.Config.ReasonableMaintenanceReplicationLagSeconds)
+	return this.LagBeyondSQLDelay(this.SecondsBehindMaster.Int64) <= int64(config.Config.ReasonableMaintenanceReplicationLagSeconds)
 }
 
 // CanMove returns true if this instance's state allows it to be repositioned. For example,
@@ -453,6 +468,9 @@ func (this *Instance) HumanReadableDescription() string {
 	if this.UsingPseudoGTID {
 		tokens = append(tokens, "P-GTID")
 	}
+	if this.IsDelayedSlave {
+		tokens = append(tokens, fmt.Sprintf("delayed:%ds", this.SQLDelay))
+	}
 	if this.IsDowntimed {
 		tokens = append(tokens, "downtimed")
 	}
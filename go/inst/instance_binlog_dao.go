@@ -20,11 +20,13 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/outbrain/golib/log"
 	"github.com/outbrain/golib/math"
 	"github.com/outbrain/golib/sqlutils"
+	"github.com/outbrain/orchestrator/go/binlog"
 	"github.com/outbrain/orchestrator/go/config"
 	"github.com/outbrain/orchestrator/go/db"
 	"github.com/patrickmn/go-cache"
@@ -125,18 +127,107 @@ func getLastPseudoGTIDEntryInBinlog(pseudoGTIDRegexp *regexp.Regexp, instanceKey
 	return &binlogCoordinates, entryText, err
 }
 
+// binlogSearchFunc searches a single binary log, optionally starting at given coordinates (nil means beginning of log).
+// It returns nil coordinates when the search is unsuccessful; an error is reserved to SQL problems.
+type binlogSearchFunc func(binlog string, minCoordinates *BinlogCoordinates) (*BinlogCoordinates, string, error)
+
+// searchBinlogsConcurrently applies given search on given binary logs, which are expected to be ordered newest first.
+// Up to PseudoGTIDSearchConcurrency binary logs are searched at a time; the result from the newest binary log on which
+// the search succeeds is returned.
+// minCoordinates only apply to the binary log they refer to. If searching from minCoordinates is unsuccessful and
+// rescanWithoutMin is true, that binary log is then searched in full.
+// An error on any binary log terminates the search, but only after all newer binary logs have been accounted for.
+// This is how we learn we've gone past the oldest binary log.
+func searchBinlogsConcurrently(binlogs []string, minCoordinates *BinlogCoordinates, rescanWithoutMin bool, search binlogSearchFunc) (*BinlogCoordinates, string, error) {
+	type binlogSearchResult struct {
+		coordinates *BinlogCoordinates
+		text        string
+		err         error
+	}
+	results := make([]binlogSearchResult, len(binlogs))
+	concurrency := int(config.Config.PseudoGTIDSearchConcurrency)
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	for batchStart := 0; batchStart < len(binlogs); batchStart += concurrency {
+		batchEnd := batchStart + concurrency
+		if batchEnd > len(binlogs) {
+			batchEnd = len(binlogs)
+		}
+		var wg sync.WaitGroup
+		for i := batchStart; i < batchEnd; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				binlog := binlogs[i]
+				var binlogMinCoordinates *BinlogCoordinates
+				if minCoordinates != nil && minCoordinates.LogFile == binlog {
+					binlogMinCoordinates = minCoordinates
+				}
+				coordinates, text, err := search(binlog, binlogMinCoordinates)
+				if err == nil && coordinates == nil && binlogMinCoordinates != nil && rescanWithoutMin {
+					// We tried and failed with the minCoordinates hint. We no longer require it,
+					// and scan same log file again, with no heuristic
+					log.Debugf("Heuristic search failed on binlog %+v; rescanning entire binlog", binlog)
+					coordinates, text, err = search(binlog, nil)
+				}
+				results[i] = binlogSearchResult{coordinates: coordinates, text: text, err: err}
+			}(i)
+		}
+		wg.Wait()
+		for i := batchStart; i < batchEnd; i++ {
+			if results[i].err != nil {
+				return nil, "", results[i].err
+			}
+			if results[i].coordinates != nil {
+				return results[i].coordinates, results[i].text, nil
+			}
+		}
+	}
+	return nil, "", nil
+}
+
+// getBinlogsBackwards returns the names of up to count binary logs, starting with given coordinates' log and going
+// backwards, as well as the coordinates of the log preceding the last returned one. Names are guessed and
+// are not guaranteed to exist.
+func getBinlogsBackwards(coordinates BinlogCoordinates, count int) (binlogs []string, previous BinlogCoordinates, err error) {
+	previous = coordinates
+	for len(binlogs) < count {
+		binlogs = append(binlogs, previous.LogFile)
+		if previous, err = previous.PreviousFileCoordinates(); err != nil {
+			return binlogs, previous, err
+		}
+	}
+	return binlogs, previous, nil
+}
+
+// getBinlogsBetween returns the names of binary logs from upper's log down to lower's log, newest first
+func getBinlogsBetween(lower *BinlogCoordinates, upper *BinlogCoordinates) (binlogs []string, err error) {
+	current := *upper
+	for {
+		binlogs = append(binlogs, current.LogFile)
+		if !lower.FileSmallerThan(&current) {
+			return binlogs, nil
+		}
+		if current, err = current.PreviousFileCoordinates(); err != nil {
+			return binlogs, err
+		}
+	}
+}
+
 func getLastPseudoGTIDEntryInInstance(instance *Instance, minBinlogCoordinates *BinlogCoordinates, maxBinlogCoordinates *BinlogCoordinates, exhaustiveSearch bool) (*BinlogCoordinates, string, error) {
 	pseudoGTIDRegexp, err := compilePseudoGTIDPattern()
 	if err != nil {
 		return nil, "", err
 	}
+	search := func(binlog string, minCoordinates *BinlogCoordinates) (*BinlogCoordinates, string, error) {
+		log.Debugf("Searching for latest pseudo gtid entry in binlog %+v of %+v", binlog, instance.Key)
+		return getLastPseudoGTIDEntryInBinlog(pseudoGTIDRegexp, &instance.Key, binlog, BinaryLog, minCoordinates, maxBinlogCoordinates)
+	}
 	// Look for last GTID in instance:
 	currentBinlog := instance.SelfBinlogCoordinates
-
-	err = nil
-	for err == nil {
-		log.Debugf("Searching for latest pseudo gtid entry in binlog %+v of %+v", currentBinlog.LogFile, instance.Key)
-		resultCoordinates, entryInfo, err := getLastPseudoGTIDEntryInBinlog(pseudoGTIDRegexp, &instance.Key, currentBinlog.LogFile, BinaryLog, minBinlogCoordinates, maxBinlogCoordinates)
+	if !exhaustiveSearch {
+		resultCoordinates, entryInfo, err := searchBinlogsConcurrently([]string{currentBinlog.LogFile}, minBinlogCoordinates, false, search)
 		if err != nil {
 			return nil, "", err
 		}
@@ -144,25 +235,24 @@ func getLastPseudoGTIDEntryInInstance(instance *Instance, minBinlogCoordinates *
 			log.Debugf("Found pseudo gtid entry in %+v, %+v", instance.Key, resultCoordinates)
 			return resultCoordinates, entryInfo, err
 		}
-		if !exhaustiveSearch {
-			log.Debugf("Not an exhaustive search. Bailing out")
-			break
+		log.Debugf("Not an exhaustive search. Bailing out")
+		return nil, "", log.Errorf("Cannot find pseudo GTID entry in binlogs of %+v", instance.Key)
+	}
+	for {
+		binlogs, previousBinlog, previousErr := getBinlogsBackwards(currentBinlog, int(config.Config.PseudoGTIDSearchConcurrency))
+		resultCoordinates, entryInfo, err := searchBinlogsConcurrently(binlogs, minBinlogCoordinates, true, search)
+		if err != nil {
+			return nil, "", err
 		}
-		if minBinlogCoordinates != nil && minBinlogCoordinates.LogFile == currentBinlog.LogFile {
-			// We tried and failed with the minBinlogCoordinates hint. We no longer require it,
-			// and continue with exhaustive search.
-			minBinlogCoordinates = nil
-			log.Debugf("Heuristic binlog search failed; continuing exhaustive search")
-			// And we do NOT iterate the log file: we scan same log faile again, with no heuristic
-			//return nil, "", log.Errorf("past minBinlogCoordinates (%+v); skipping iteration over rest of binary logs", *minBinlogCoordinates)
-		} else {
-			currentBinlog, err = currentBinlog.PreviousFileCoordinates()
-			if err != nil {
-				return nil, "", err
-			}
+		if resultCoordinates != nil {
+			log.Debugf("Found pseudo gtid entry in %+v, %+v", instance.Key, resultCoordinates)
+			return resultCoordinates, entryInfo, err
+		}
+		if previousErr != nil {
+			return nil, "", previousErr
 		}
+		currentBinlog = previousBinlog
 	}
-	return nil, "", log.Errorf("Cannot find pseudo GTID entry in binlogs of %+v", instance.Key)
 }
 
 func getLastPseudoGTIDEntryInRelayLogs(instance *Instance, minBinlogCoordinates *BinlogCoordinates, recordedInstanceRelayLogCoordinates BinlogCoordinates, exhaustiveSearch bool) (*BinlogCoordinates, string, error) {
@@ -282,7 +372,32 @@ func SearchEntryInBinlog(pseudoGTIDRegexp *regexp.Regexp, instanceKey *InstanceK
 	return binlogCoordinates, (binlogCoordinates.LogPos != 0), err
 }
 
+// throttleBinlogSearch waits for given instance, if a replicating slave, to have reasonable replication lag.
+// Binary log search might turn to be a heavyweight operation and we do not wish the instance to suffer.
+// It returns a freshly read instance.
+func throttleBinlogSearch(instance *Instance) (*Instance, error) {
+	if !instance.SlaveRunning() {
+		return instance, nil
+	}
+	for {
+		log.Debugf("%+v is a replicating slave. Verifying lag", instance.Key)
+		readInstance, err := ReadTopologyInstanceUnbuffered(&instance.Key)
+		if err != nil {
+			return instance, err
+		}
+		instance = readInstance
+		if instance.HasReasonableMaintenanceReplicationLag() {
+			// is good to go!
+			return instance, nil
+		}
+		log.Debugf("lag is too high on %+v. Throttling the search for pseudo gtid entry", instance.Key)
+		time.Sleep(time.Duration(config.Config.ReasonableMaintenanceReplicationLagSeconds) * time.Second)
+	}
+}
+
 // SearchEntryInInstanceBinlogs will search for a specific text entry within the binary logs of a given instance.
+// The Pseudo-GTID index, if maintained, is consulted first. Otherwise, or if that fails, binary logs are searched
+// newest first, several at a time.
 func SearchEntryInInstanceBinlogs(instance *Instance, entryText string, monotonicPseudoGTIDEntries bool, minBinlogCoordinates *BinlogCoordinates) (*BinlogCoordinates, error) {
 	pseudoGTIDRegexp, err := compilePseudoGTIDPattern()
 	if err != nil {
@@ -295,59 +410,99 @@ func SearchEntryInInstanceBinlogs(instance *Instance, entryText string, monotoni
 		log.Debugf("Found instance Pseudo GTID entry coordinates in cache: %+v, %+v, %+v", instance.Key, entryText, coords)
 		return coords.(*BinlogCoordinates), nil
 	}
+	search := func(binlog string, minCoordinates *BinlogCoordinates) (*BinlogCoordinates, string, error) {
+		log.Debugf("Searching for given pseudo gtid entry in binlog %+v of %+v", binlog, instance.Key)
+		resultCoordinates, found, err := SearchEntryInBinlog(pseudoGTIDRegexp, &instance.Key, binlog, entryText, monotonicPseudoGTIDEntries, minCoordinates)
+		if !found {
+			return nil, "", err
+		}
+		return &resultCoordinates, entryText, err
+	}
+
+	exactCoordinates, lowerCoordinates, upperCoordinates := lookupPseudoGTIDIndex(instance, entryText, monotonicPseudoGTIDEntries)
+	if exactCoordinates != nil {
+		instanceBinlogEntryCache.Set(cacheKey, exactCoordinates, 0)
+		return exactCoordinates, nil
+	}
+	if lowerCoordinates != nil {
+		// The index tells us which binary logs the entry must reside in
+		if upperCoordinates == nil {
+			upperCoordinates = &instance.SelfBinlogCoordinates
+		}
+		if binlogs, err := getBinlogsBetween(lowerCoordinates, upperCoordinates); err == nil {
+			if instance, err = throttleBinlogSearch(instance); err != nil {
+				return nil, log.Errore(err)
+			}
+			resultCoordinates, _, err := searchBinlogsConcurrently(binlogs, lowerCoordinates, false, search)
+			if err == nil && resultCoordinates != nil {
+				log.Debugf("Matched entry in %+v via Pseudo-GTID index: %+v", instance.Key, resultCoordinates)
+				instanceBinlogEntryCache.Set(cacheKey, resultCoordinates, 0)
+				return resultCoordinates, nil
+			}
+		}
+		log.Debugf("Pseudo-GTID index search failed on %+v; continuing exhaustive search", instance.Key)
+	}
 
 	// Look for GTID entry in given instance:
 	log.Debugf("Searching for given pseudo gtid entry in %+v. monotonicPseudoGTIDEntries=%+v", instance.Key, monotonicPseudoGTIDEntries)
 	currentBinlog := instance.SelfBinlogCoordinates
-	err = nil
 	for {
-		log.Debugf("Searching for given pseudo gtid entry in binlog %+v of %+v", currentBinlog.LogFile, instance.Key)
-		// loop iteration per binary log. This might turn to be a heavyweight operation. We wish to throttle the operation such that
-		// the instance does not suffer. If it is a slave, we will only act as long as it's not lagging too much.
-		if instance.SlaveRunning() {
-			for {
-				log.Debugf("%+v is a replicating slave. Verifying lag", instance.Key)
-				instance, err = ReadTopologyInstanceUnbuffered(&instance.Key)
-				if err != nil {
-					break
-				}
-				if instance.HasReasonableMaintenanceReplicationLag() {
-					// is good to go!
-					break
-				}
-				log.Debugf("lag is too high on %+v. Throttling the search for pseudo gtid entry", instance.Key)
-				time.Sleep(time.Duration(config.Config.ReasonableMaintenanceReplicationLagSeconds) * time.Second)
-			}
+		binlogs, previousBinlog, previousErr := getBinlogsBackwards(currentBinlog, int(config.Config.PseudoGTIDSearchConcurrency))
+		if instance, err = throttleBinlogSearch(instance); err != nil {
+			break
 		}
-		var resultCoordinates BinlogCoordinates
-		var found bool = false
-		resultCoordinates, found, err = SearchEntryInBinlog(pseudoGTIDRegexp, &instance.Key, currentBinlog.LogFile, entryText, monotonicPseudoGTIDEntries, minBinlogCoordinates)
+		var resultCoordinates *BinlogCoordinates
+		resultCoordinates, _, err = searchBinlogsConcurrently(binlogs, minBinlogCoordinates, true, search)
 		if err != nil {
 			break
 		}
-		if found {
+		if resultCoordinates != nil {
 			log.Debugf("Matched entry in %+v: %+v", instance.Key, resultCoordinates)
-			instanceBinlogEntryCache.Set(cacheKey, &resultCoordinates, 0)
-			return &resultCoordinates, nil
+			instanceBinlogEntryCache.Set(cacheKey, resultCoordinates, 0)
+			return resultCoordinates, nil
 		}
 		// Got here? Unfound. Keep looking
-		if minBinlogCoordinates != nil && minBinlogCoordinates.LogFile == currentBinlog.LogFile {
-			log.Debugf("Heuristic master binary logs search failed; continuing exhaustive search")
-			minBinlogCoordinates = nil
-		} else {
-			currentBinlog, err = currentBinlog.PreviousFileCoordinates()
-			if err != nil {
-				break
-			}
-			log.Debugf("- Will move next to binlog %+v", currentBinlog.LogFile)
+		if previousErr != nil {
+			err = previousErr
+			break
 		}
+		currentBinlog = previousBinlog
+		log.Debugf("- Will move next to binlog %+v", currentBinlog.LogFile)
 	}
 
 	return nil, log.Errorf("Cannot match pseudo GTID entry in binlogs of %+v; err: %+v", instance.Key, err)
 }
 
+// streamBinlogEventsChunk reads a chunk of binary log events via a replication connection
+func streamBinlogEventsChunk(instanceKey *InstanceKey, startingCoordinates BinlogCoordinates) ([]BinlogEvent, error) {
+	events := []BinlogEvent{}
+	streamedEvents, err := binlog.ReadEvents(instanceKey.Hostname, instanceKey.Port, startingCoordinates.LogFile, startingCoordinates.LogPos, config.Config.BinlogEventsChunkSize)
+	if err != nil {
+		return events, err
+	}
+	for _, streamedEvent := range streamedEvents {
+		binlogEvent := BinlogEvent{}
+		binlogEvent.Coordinates.LogFile = streamedEvent.LogFile
+		binlogEvent.Coordinates.LogPos = streamedEvent.Pos
+		binlogEvent.Coordinates.Type = startingCoordinates.Type
+		binlogEvent.NextEventPos = streamedEvent.NextPos
+		binlogEvent.EventType = streamedEvent.EventType
+		binlogEvent.Info = streamedEvent.Info
+
+		events = append(events, binlogEvent)
+	}
+	return events, nil
+}
+
 // Read (as much as possible of) a chunk of binary log events starting the given startingCoordinates
 func readBinlogEventsChunk(instanceKey *InstanceKey, startingCoordinates BinlogCoordinates) ([]BinlogEvent, error) {
+	if startingCoordinates.Type == BinaryLog && config.Config.StreamBinlogEvents && !config.Config.MySQLTopologyUseMutualTLS {
+		events, err := streamBinlogEventsChunk(instanceKey, startingCoordinates)
+		if err == nil {
+			return events, nil
+		}
+		log.Warningf("readBinlogEventsChunk: unable to stream binlog events from %+v at %+v; falling back to SHOW BINLOG EVENTS. Error: %+v", *instanceKey, startingCoordinates, err)
+	}
 	events := []BinlogEvent{}
 	db, err := db.OpenTopology(instanceKey.Hostname, instanceKey.Port)
 	if err != nil {
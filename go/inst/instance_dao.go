@@ -289,7 +289,10 @@ func ReadTopologyInstance(instanceKey *InstanceKey, bufferWrites bool) (*Instanc
 		instance.RelaylogCoordinates.Type = RelayLog
 		instance.LastSQLError = strconv.QuoteToASCII(m.GetString("Last_SQL_Error"))
 		instance.LastIOError = strconv.QuoteToASCII(m.GetString("Last_IO_Error"))
+		instance.LastSQLErrno = m.GetUintD("Last_SQL_Errno", 0)
+		instance.LastIOErrno = m.GetUintD("Last_IO_Errno", 0)
 		instance.SQLDelay = m.GetUintD("SQL_Delay", 0)
+		instance.IsDelayedSlave = (instance.SQLDelay > 0)
 		instance.UsingOracleGTID = (m.GetIntD("Auto_Position", 0) == 1)
 		instance.ExecutedGtidSet = m.GetStringD("Executed_Gtid_Set", "")
 		instance.UsingMariaDBGTID = (m.GetStringD("Using_Gtid", "No") != "No")
@@ -465,6 +468,10 @@ func ReadTopologyInstance(instanceKey *InstanceKey, bufferWrites bool) (*Instanc
 			logReadTopologyInstanceError(instanceKey, "DetectPseudoGTIDQuery", err)
 		}
 	}
+	if config.Config.AutoPseudoGTID && config.Config.DetectPseudoGTIDQuery == "" && !isMaxScale {
+		// orchestrator injects Pseudo-GTID by itself
+		instance.UsingPseudoGTID = true
+	}
 
 	if config.Config.SlaveLagQuery != "" && !isMaxScale {
 		if err := db.QueryRow(config.Config.SlaveLagQuery).Scan(&instance.SlaveLagSeconds); err == nil {
@@ -736,9 +743,12 @@ func readInstanceRow(m sqlutils.RowMap) *Instance {
 	instance.RelaylogCoordinates.Type = RelayLog
 	instance.LastSQLError = m.GetString("last_sql_error")
 	instance.LastIOError = m.GetString("last_io_error")
+	instance.LastSQLErrno = m.GetUint("last_sql_errno")
+	instance.LastIOErrno = m.GetUint("last_io_errno")
 	instance.SecondsBehindMaster = m.GetNullInt64("seconds_behind_master")
 	instance.SlaveLagSeconds = m.GetNullInt64("slave_lag_seconds")
 	instance.SQLDelay = m.GetUint("sql_delay")
+	instance.IsDelayedSlave = (instance.SQLDelay > 0)
 	slaveHostsJSON := m.GetString("slave_hosts")
 	instance.ClusterName = m.GetString("cluster_name")
 	instance.SuggestedClusterAlias = m.GetString("suggested_cluster_alias")
@@ -945,8 +955,8 @@ func ReadProblemInstances(clusterName string) ([](*Instance), error) {
 				or (not ifnull(timestampdiff(second, last_checked, now()) <= ?, false))
 				or (not slave_sql_running)
 				or (not slave_io_running)
-				or (abs(cast(seconds_behind_master as signed) - cast(sql_delay as signed)) > ?)
-				or (abs(cast(slave_lag_seconds as signed) - cast(sql_delay as signed)) > ?)
+				or (cast(seconds_behind_master as signed) - cast(sql_delay as signed) > ?)
+				or (cast(slave_lag_seconds as signed) - cast(sql_delay as signed) > ?)
 			)
 		`
 
@@ -973,6 +983,31 @@ func ReadProblemInstances(clusterName string) ([](*Instance), error) {
 	return reportedInstances, nil
 }
 
+// ReadBrokenReplicationInstances reads replicas whose SQL or IO thread is stopped on error.
+// Downtimed instances are excluded.
+func ReadBrokenReplicationInstances() ([](*Instance), error) {
+	condition := `
+			master_host != ''
+			and (
+				(not slave_sql_running and last_sql_errno > 0)
+				or (not slave_io_running and last_io_errno > 0)
+			)
+			and ifnull(timestampdiff(second, last_checked, now()) <= ?, false)
+			and last_seen >= last_checked
+		`
+	instances, err := readInstancesByCondition(condition, sqlutils.Args(config.Config.InstancePollSeconds), "")
+	if err != nil {
+		return instances, err
+	}
+	var brokenInstances [](*Instance)
+	for _, instance := range instances {
+		if !instance.IsDowntimed {
+			brokenInstances = append(brokenInstances, instance)
+		}
+	}
+	return brokenInstances, nil
+}
+
 // SearchInstances reads all instances qualifying for some searchString
 func SearchInstances(searchString string) ([](*Instance), error) {
 	searchString = strings.TrimSpace(searchString)
@@ -1246,8 +1281,11 @@ func GetInstancesMaxLag(instances [](*Instance)) (maxLag int64, err error) {
 		return 0, log.Errorf("No instances found in GetInstancesMaxLag")
 	}
 	for _, clusterInstance := range instances {
-		if clusterInstance.SlaveLagSeconds.Valid && clusterInstance.SlaveLagSeconds.Int64 > maxLag {
-			maxLag = clusterInstance.SlaveLagSeconds.Int64
+		if !clusterInstance.SlaveLagSeconds.Valid {
+			continue
+		}
+		if lag := clusterInstance.LagBeyondSQLDelay(clusterInstance.SlaveLagSeconds.Int64); lag > maxLag {
+			maxLag = lag
 		}
 	}
 	return maxLag, nil
@@ -1807,6 +1845,8 @@ func mkInsertOdkuForInstances(instances []*Instance, instanceWasActuallyFound bo
 		"relay_log_pos",
 		"last_sql_error",
 		"last_io_error",
+		"last_sql_errno",
+		"last_io_errno",
 		"seconds_behind_master",
 		"slave_lag_seconds",
 		"sql_delay",
@@ -1873,6 +1913,8 @@ func mkInsertOdkuForInstances(instances []*Instance, instanceWasActuallyFound bo
 		args = append(args, instance.RelaylogCoordinates.LogPos)
 		args = append(args, instance.LastSQLError)
 		args = append(args, instance.LastIOError)
+		args = append(args, instance.LastSQLErrno)
+		args = append(args, instance.LastIOErrno)
 		args = append(args, instance.SecondsBehindMaster)
 		args = append(args, instance.SlaveLagSeconds)
 		args = append(args, instance.SQLDelay)
@@ -2083,10 +2125,10 @@ func SnapshotTopologies() error {
 		_, err := db.ExecOrchestrator(`
         	insert ignore into
         		database_instance_topology_history (snapshot_unix_timestamp,
-        			hostname, port, master_host, master_port, cluster_name, version)
+        			hostname, port, master_host, master_port, cluster_name, version, read_only)
         	select
         		UNIX_TIMESTAMP(NOW()),
-        		hostname, port, master_host, master_port, cluster_name, version
+        		hostname, port, master_host, master_port, cluster_name, version, read_only
 			from
 				database_instance
 				`,
@@ -2102,20 +2144,31 @@ func SnapshotTopologies() error {
 
 // ReadHistoryClusterInstances reads (thin) instances from history
 func ReadHistoryClusterInstances(clusterName string, historyTimestampPattern string) ([](*Instance), error) {
+	condition := `
+			snapshot_unix_timestamp rlike ?
+			and cluster_name = ?
+		`
+	instances, _, err := readHistoryInstances(condition, sqlutils.Args(historyTimestampPattern, clusterName))
+	return instances, err
+}
+
+// readHistoryInstances reads (thin) instances from history, matching given condition. It also returns the
+// keys of instances whose read_only state was not recorded (snapshots taken before read_only was tracked).
+func readHistoryInstances(condition string, args []interface{}) ([](*Instance), *InstanceKeyMap, error) {
 	instances := [](*Instance){}
+	unknownReadOnly := NewInstanceKeyMap()
 
-	query := `
+	query := fmt.Sprintf(`
 		select
 			*
 		from
 			database_instance_topology_history
 		where
-			snapshot_unix_timestamp rlike ?
-			and cluster_name = ?
+			%s
 		order by
-			hostname, port`
+			hostname, port`, condition)
 
-	err := db.QueryOrchestrator(query, sqlutils.Args(historyTimestampPattern, clusterName), func(m sqlutils.RowMap) error {
+	err := db.QueryOrchestrator(query, args, func(m sqlutils.RowMap) error {
 		instance := NewInstance()
 
 		instance.Key.Hostname = m.GetString("hostname")
@@ -2123,14 +2176,20 @@ func ReadHistoryClusterInstances(clusterName string, historyTimestampPattern str
 		instance.MasterKey.Hostname = m.GetString("master_host")
 		instance.MasterKey.Port = m.GetInt("master_port")
 		instance.ClusterName = m.GetString("cluster_name")
+		instance.Version = m.GetString("version")
+		readOnly := m.GetNullInt64("read_only")
+		instance.ReadOnly = readOnly.Valid && readOnly.Int64 != 0
+		if !readOnly.Valid {
+			unknownReadOnly.AddKey(instance.Key)
+		}
 
 		instances = append(instances, instance)
 		return nil
 	})
 	if err != nil {
-		return instances, log.Errore(err)
+		return instances, unknownReadOnly, log.Errore(err)
 	}
-	return instances, err
+	return instances, unknownReadOnly, err
 }
 
 // RegisterCandidateInstance markes a given instance as suggested for successoring a master in the event of failover.
@@ -211,6 +211,29 @@ func TestCanReplicateFrom(t *testing.T) {
 	test.S(t).ExpectFalse(canReplicate)
 }
 
+func TestHasReasonableMaintenanceReplicationLag(t *testing.T) {
+	i := Instance{Key: key1}
+	i.SecondsBehindMaster.Int64 = 5
+	test.S(t).ExpectTrue(i.HasReasonableMaintenanceReplicationLag())
+	i.SecondsBehindMaster.Int64 = 3600
+	test.S(t).ExpectFalse(i.HasReasonableMaintenanceReplicationLag())
+
+	i.SQLDelay = 3600
+	test.S(t).ExpectTrue(i.HasReasonableMaintenanceReplicationLag())
+	i.SecondsBehindMaster.Int64 = 0
+	test.S(t).ExpectTrue(i.HasReasonableMaintenanceReplicationLag())
+	i.SecondsBehindMaster.Int64 = 7200
+	test.S(t).ExpectFalse(i.HasReasonableMaintenanceReplicationLag())
+}
+
+func TestLagBeyondSQLDelay(t *testing.T) {
+	i := Instance{Key: key1}
+	test.S(t).ExpectEquals(i.LagBeyondSQLDelay(17), int64(17))
+	i.SQLDelay = 60
+	test.S(t).ExpectEquals(i.LagBeyondSQLDelay(17), int64(0))
+	test.S(t).ExpectEquals(i.LagBeyondSQLDelay(77), int64(17))
+}
+
 func TestNewInstanceKeyFromStrings(t *testing.T) {
 	i, err := NewInstanceKeyFromStrings("127.0.0.1", "3306")
 	test.S(t).ExpectNil(err)
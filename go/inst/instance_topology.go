@@ -58,25 +58,33 @@ func getASCIITopologyEntry(depth int, instance *Instance, replicationMap map[*In
 	return result
 }
 
-// ASCIITopology returns a string representation of the topology of given cluster.
-func ASCIITopology(clusterName string, historyTimestampPattern string) (result string, err error) {
-	var instances [](*Instance)
+// readTopologyInstances reads the instances of given cluster, either current or from history
+func readTopologyInstances(clusterName string, historyTimestampPattern string) ([](*Instance), error) {
 	if historyTimestampPattern == "" {
-		instances, err = ReadClusterInstances(clusterName)
-	} else {
-		instances, err = ReadHistoryClusterInstances(clusterName, historyTimestampPattern)
+		return ReadClusterInstances(clusterName)
 	}
+	return ReadHistoryClusterInstances(clusterName, historyTimestampPattern)
+}
+
+// ASCIITopology returns a string representation of the topology of given cluster.
+func ASCIITopology(clusterName string, historyTimestampPattern string) (result string, err error) {
+	instances, err := readTopologyInstances(clusterName, historyTimestampPattern)
 	if err != nil {
 		return "", err
 	}
+	return asciiTopologyFromInstances(instances, historyTimestampPattern == ""), nil
+}
 
+// getTopologyTree maps each instance to its slaves, and returns the roots of the topology along with their depth.
+// A topology has a single master at depth 0; co-masters are each put in their own branch, at depth 1.
+func getTopologyTree(instances [](*Instance)) (roots [](*Instance), rootDepth int, replicationMap map[*Instance]([]*Instance)) {
 	instancesMap := make(map[InstanceKey](*Instance))
 	for _, instance := range instances {
 		log.Debugf("instanceKey: %+v", instance.Key)
 		instancesMap[instance.Key] = instance
 	}
 
-	replicationMap := make(map[*Instance]([]*Instance))
+	replicationMap = make(map[*Instance]([]*Instance))
 	var masterInstance *Instance
 	// Investigate slaves:
 	for _, instance := range instances {
@@ -90,19 +98,27 @@ func ASCIITopology(clusterName string, historyTimestampPattern string) (result s
 			masterInstance = instance
 		}
 	}
-	// Get entries:
-	var entries []string
 	if masterInstance != nil {
 		// Single master
-		entries = getASCIITopologyEntry(0, masterInstance, replicationMap, historyTimestampPattern == "")
-	} else {
-		// Co-masters? For visualization we put each in its own branch while ignoring its other co-masters.
-		for _, instance := range instances {
-			if instance.IsCoMaster {
-				entries = append(entries, getASCIITopologyEntry(1, instance, replicationMap, historyTimestampPattern == "")...)
-			}
+		return [](*Instance){masterInstance}, 0, replicationMap
+	}
+	// Co-masters? For visualization we put each in its own branch while ignoring its other co-masters.
+	for _, instance := range instances {
+		if instance.IsCoMaster {
+			roots = append(roots, instance)
 		}
 	}
+	return roots, 1, replicationMap
+}
+
+// asciiTopologyFromInstances returns a string representation of the topology formed by given instances.
+func asciiTopologyFromInstances(instances [](*Instance), extendedOutput bool) (result string) {
+	roots, rootDepth, replicationMap := getTopologyTree(instances)
+	// Get entries:
+	var entries []string
+	for _, root := range roots {
+		entries = append(entries, getASCIITopologyEntry(rootDepth, root, replicationMap, extendedOutput)...)
+	}
 	// Beautify: make sure the "[...]" part is nicely aligned for all instances.
 	{
 		maxIndent := 0
@@ -111,7 +127,7 @@ func ASCIITopology(clusterName string, historyTimestampPattern string) (result s
 		}
 		for i, entry := range entries {
 			entryIndent := strings.Index(entry, "[")
-			if maxIndent > entryIndent {
+			if entryIndent >= 0 && maxIndent > entryIndent {
 				tokens := strings.Split(entry, "[")
 				newEntry := fmt.Sprintf("%s%s[%s", tokens[0], strings.Repeat(" ", maxIndent-entryIndent), tokens[1])
 				entries[i] = newEntry
@@ -120,7 +136,7 @@ func ASCIITopology(clusterName string, historyTimestampPattern string) (result s
 	}
 	// Turn into string
 	result = strings.Join(entries, "\n")
-	return result, nil
+	return result
 }
 
 // GetInstanceMaster synchronously reaches into the replication topology
@@ -160,6 +176,17 @@ func InstanceIsMasterOf(allegedMaster, allegedSlave *Instance) bool {
 // MoveEquivalent will attempt moving instance indicated by instanceKey below another instance,
 // based on known master coordinates equivalence
 func MoveEquivalent(instanceKey, otherKey *InstanceKey) (*Instance, error) {
+	return MoveEquivalentUnderLock(nil, instanceKey, otherKey)
+}
+
+// MoveEquivalentUnderLock is MoveEquivalent, on behalf of an operation holding given cluster lock
+func MoveEquivalentUnderLock(heldLock *ClusterLockToken, instanceKey, otherKey *InstanceKey) (*Instance, error) {
+	clusterLock, err := AcquireClusterLockOfInstance(instanceKey, "move-equivalent", heldLock)
+	if err != nil {
+		return nil, err
+	}
+	defer clusterLock.Release()
+
 	instance, found, err := ReadInstance(instanceKey)
 	if err != nil || !found {
 		return instance, err
@@ -211,6 +238,17 @@ Cleanup:
 // It will perform all safety and sanity checks and will tamper with this instance's replication
 // as well as its master.
 func MoveUp(instanceKey *InstanceKey) (*Instance, error) {
+	return MoveUpUnderLock(nil, instanceKey)
+}
+
+// MoveUpUnderLock is MoveUp, on behalf of an operation holding given cluster lock
+func MoveUpUnderLock(heldLock *ClusterLockToken, instanceKey *InstanceKey) (*Instance, error) {
+	clusterLock, err := AcquireClusterLockOfInstance(instanceKey, "move-up", heldLock)
+	if err != nil {
+		return nil, err
+	}
+	defer clusterLock.Release()
+
 	instance, err := ReadTopologyInstanceUnbuffered(instanceKey)
 	if err != nil {
 		return instance, err
@@ -236,7 +274,7 @@ func MoveUp(instanceKey *InstanceKey) (*Instance, error) {
 	}
 	if master.IsBinlogServer() {
 		// Quick solution via binlog servers
-		return Repoint(instanceKey, &master.MasterKey, GTIDHintDeny)
+		return RepointUnderLock(clusterLock, instanceKey, &master.MasterKey, GTIDHintDeny)
 	}
 
 	log.Infof("Will move %+v up the topology", *instanceKey)
@@ -297,6 +335,12 @@ Cleanup:
 // Clock-time, this is fater than moving one at a time. However this means all slaves of the given instance, and the instance itself,
 // will all stop replicating together.
 func MoveUpSlaves(instanceKey *InstanceKey, pattern string) ([](*Instance), *Instance, error, []error) {
+	clusterLock, err := AcquireClusterLockOfInstance(instanceKey, "move-up-slaves", nil)
+	if err != nil {
+		return nil, nil, err, nil
+	}
+	defer clusterLock.Release()
+
 	res := [](*Instance){}
 	errs := []error{}
 	slaveMutex := make(chan bool, 1)
@@ -315,7 +359,7 @@ func MoveUpSlaves(instanceKey *InstanceKey, pattern string) ([](*Instance), *Ins
 	}
 
 	if instance.IsBinlogServer() {
-		slaves, err, errors := RepointSlavesTo(instanceKey, pattern, &instance.MasterKey)
+		slaves, err, errors := RepointSlavesToUnderLock(clusterLock, instanceKey, pattern, &instance.MasterKey)
 		// Bail out!
 		return slaves, instance, err, errors
 	}
@@ -367,7 +411,7 @@ func MoveUpSlaves(instanceKey *InstanceKey, pattern string) ([](*Instance), *Ins
 				}
 				if instance.IsBinlogServer() {
 					// Special case. Just repoint
-					slave, err = Repoint(&slave.Key, instanceKey, GTIDHintDeny)
+					slave, err = RepointUnderLock(clusterLock, &slave.Key, instanceKey, GTIDHintDeny)
 					if err != nil {
 						slaveErr = err
 						return
@@ -426,6 +470,17 @@ Cleanup:
 // It will perform all safety and sanity checks and will tamper with this instance's replication
 // as well as its sibling.
 func MoveBelow(instanceKey, siblingKey *InstanceKey) (*Instance, error) {
+	return MoveBelowUnderLock(nil, instanceKey, siblingKey)
+}
+
+// MoveBelowUnderLock is MoveBelow, on behalf of an operation holding given cluster lock
+func MoveBelowUnderLock(heldLock *ClusterLockToken, instanceKey, siblingKey *InstanceKey) (*Instance, error) {
+	clusterLock, err := AcquireClusterLockOfInstance(instanceKey, "move-below", heldLock)
+	if err != nil {
+		return nil, err
+	}
+	defer clusterLock.Release()
+
 	instance, err := ReadTopologyInstanceUnbuffered(instanceKey)
 	if err != nil {
 		return instance, err
@@ -438,7 +493,7 @@ func MoveBelow(instanceKey, siblingKey *InstanceKey) (*Instance, error) {
 	if sibling.IsBinlogServer() {
 		// Binlog server has same coordinates as master
 		// Easy solution!
-		return Repoint(instanceKey, &sibling.Key, GTIDHintDeny)
+		return RepointUnderLock(clusterLock, instanceKey, &sibling.Key, GTIDHintDeny)
 	}
 
 	rinstance, _, _ := ReadInstance(&instance.Key)
@@ -570,6 +625,17 @@ Cleanup:
 
 // MoveBelowGTID will attempt moving instance indicated by instanceKey below another instance using either Oracle GTID or MariaDB GTID.
 func MoveBelowGTID(instanceKey, otherKey *InstanceKey) (*Instance, error) {
+	return MoveBelowGTIDUnderLock(nil, instanceKey, otherKey)
+}
+
+// MoveBelowGTIDUnderLock is MoveBelowGTID, on behalf of an operation holding given cluster lock
+func MoveBelowGTIDUnderLock(heldLock *ClusterLockToken, instanceKey, otherKey *InstanceKey) (*Instance, error) {
+	clusterLock, err := AcquireClusterLockOfInstance(instanceKey, "move-below-gtid", heldLock)
+	if err != nil {
+		return nil, err
+	}
+	defer clusterLock.Release()
+
 	instance, err := ReadTopologyInstanceUnbuffered(instanceKey)
 	if err != nil {
 		return instance, err
@@ -635,6 +701,12 @@ func moveSlavesViaGTID(slaves [](*Instance), other *Instance) (movedSlaves [](*I
 
 // MoveSlavesGTID will (attempt to) move all slaves of given master below given instance.
 func MoveSlavesGTID(masterKey *InstanceKey, belowKey *InstanceKey, pattern string) (movedSlaves [](*Instance), unmovedSlaves [](*Instance), err error, errs []error) {
+	clusterLock, err := AcquireClusterLockOfInstance(masterKey, "move-slaves-gtid", nil)
+	if err != nil {
+		return movedSlaves, unmovedSlaves, err, errs
+	}
+	defer clusterLock.Release()
+
 	belowInstance, err := ReadTopologyInstanceUnbuffered(belowKey)
 	if err != nil {
 		// Can't access "below" ==> can't move slaves beneath it
@@ -665,6 +737,17 @@ func MoveSlavesGTID(masterKey *InstanceKey, belowKey *InstanceKey, pattern strin
 // - masterKey is nil: use case is corrupted relay logs on slave
 // - masterKey is not nil: using Binlog servers (coordinates remain the same)
 func Repoint(instanceKey *InstanceKey, masterKey *InstanceKey, gtidHint OperationGTIDHint) (*Instance, error) {
+	return RepointUnderLock(nil, instanceKey, masterKey, gtidHint)
+}
+
+// RepointUnderLock is Repoint, on behalf of an operation holding given cluster lock
+func RepointUnderLock(heldLock *ClusterLockToken, instanceKey *InstanceKey, masterKey *InstanceKey, gtidHint OperationGTIDHint) (*Instance, error) {
+	clusterLock, err := AcquireClusterLockOfInstance(instanceKey, "repoint", heldLock)
+	if err != nil {
+		return nil, err
+	}
+	defer clusterLock.Release()
+
 	instance, err := ReadTopologyInstanceUnbuffered(instanceKey)
 	if err != nil {
 		return instance, err
@@ -739,6 +822,17 @@ Cleanup:
 // RepointTo repoints list of slaves onto another master.
 // Binlog Server is the major use case
 func RepointTo(slaves [](*Instance), belowKey *InstanceKey) ([](*Instance), error, []error) {
+	return RepointToUnderLock(nil, slaves, belowKey)
+}
+
+// RepointToUnderLock is RepointTo, on behalf of an operation holding given cluster lock
+func RepointToUnderLock(heldLock *ClusterLockToken, slaves [](*Instance), belowKey *InstanceKey) ([](*Instance), error, []error) {
+	clusterLock, err := AcquireClusterLockOfInstance(belowKey, "repoint-to", heldLock)
+	if err != nil {
+		return nil, err, nil
+	}
+	defer clusterLock.Release()
+
 	res := [](*Instance){}
 	errs := []error{}
 
@@ -761,7 +855,7 @@ func RepointTo(slaves [](*Instance), belowKey *InstanceKey) ([](*Instance), erro
 		go func() {
 			defer func() { barrier <- &slave.Key }()
 			ExecuteOnTopology(func() {
-				slave, slaveErr := Repoint(&slave.Key, belowKey, GTIDHintNeutral)
+				slave, slaveErr := RepointUnderLock(clusterLock, &slave.Key, belowKey, GTIDHintNeutral)
 
 				func() {
 					// Instantaneous mutex.
@@ -792,6 +886,17 @@ func RepointTo(slaves [](*Instance), belowKey *InstanceKey) ([](*Instance), erro
 // RepointSlavesTo repoints slaves of a given instance (possibly filtered) onto another master.
 // Binlog Server is the major use case
 func RepointSlavesTo(instanceKey *InstanceKey, pattern string, belowKey *InstanceKey) ([](*Instance), error, []error) {
+	return RepointSlavesToUnderLock(nil, instanceKey, pattern, belowKey)
+}
+
+// RepointSlavesToUnderLock is RepointSlavesTo, on behalf of an operation holding given cluster lock
+func RepointSlavesToUnderLock(heldLock *ClusterLockToken, instanceKey *InstanceKey, pattern string, belowKey *InstanceKey) ([](*Instance), error, []error) {
+	clusterLock, err := AcquireClusterLockOfInstance(instanceKey, "repoint-slaves", heldLock)
+	if err != nil {
+		return nil, err, nil
+	}
+	defer clusterLock.Release()
+
 	res := [](*Instance){}
 	errs := []error{}
 
@@ -810,7 +915,7 @@ func RepointSlavesTo(instanceKey *InstanceKey, pattern string, belowKey *Instanc
 		belowKey = &slaves[0].MasterKey
 	}
 	log.Infof("Will repoint slaves of %+v to %+v", *instanceKey, *belowKey)
-	return RepointTo(slaves, belowKey)
+	return RepointToUnderLock(clusterLock, slaves, belowKey)
 }
 
 // RepointSlaves repoints all slaves of a given instance onto its existing master.
@@ -821,6 +926,12 @@ func RepointSlaves(instanceKey *InstanceKey, pattern string) ([](*Instance), err
 // MakeCoMaster will attempt to make an instance co-master with its master, by making its master a slave of its own.
 // This only works out if the master is not replicating; the master does not have a known master (it may have an unknown master).
 func MakeCoMaster(instanceKey *InstanceKey) (*Instance, error) {
+	clusterLock, err := AcquireClusterLockOfInstance(instanceKey, "make-co-master", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer clusterLock.Release()
+
 	instance, err := ReadTopologyInstanceUnbuffered(instanceKey)
 	if err != nil {
 		return instance, err
@@ -919,6 +1030,17 @@ Cleanup:
 
 // ResetSlaveOperation will reset a slave
 func ResetSlaveOperation(instanceKey *InstanceKey) (*Instance, error) {
+	return ResetSlaveOperationUnderLock(nil, instanceKey)
+}
+
+// ResetSlaveOperationUnderLock is ResetSlaveOperation, on behalf of an operation holding given cluster lock
+func ResetSlaveOperationUnderLock(heldLock *ClusterLockToken, instanceKey *InstanceKey) (*Instance, error) {
+	clusterLock, err := AcquireClusterLockOfInstance(instanceKey, "reset-slave", heldLock)
+	if err != nil {
+		return nil, err
+	}
+	defer clusterLock.Release()
+
 	instance, err := ReadTopologyInstanceUnbuffered(instanceKey)
 	if err != nil {
 		return instance, err
@@ -960,6 +1082,17 @@ Cleanup:
 
 // DetachSlaveOperation will detach a slave from its master by forcibly corrupting its replication coordinates
 func DetachSlaveOperation(instanceKey *InstanceKey) (*Instance, error) {
+	return DetachSlaveOperationUnderLock(nil, instanceKey)
+}
+
+// DetachSlaveOperationUnderLock is DetachSlaveOperation, on behalf of an operation holding given cluster lock
+func DetachSlaveOperationUnderLock(heldLock *ClusterLockToken, instanceKey *InstanceKey) (*Instance, error) {
+	clusterLock, err := AcquireClusterLockOfInstance(instanceKey, "detach-slave", heldLock)
+	if err != nil {
+		return nil, err
+	}
+	defer clusterLock.Release()
+
 	instance, err := ReadTopologyInstanceUnbuffered(instanceKey)
 	if err != nil {
 		return instance, err
@@ -1001,6 +1134,12 @@ Cleanup:
 
 // ReattachSlaveOperation will detach a slave from its master by forcibly corrupting its replication coordinates
 func ReattachSlaveOperation(instanceKey *InstanceKey) (*Instance, error) {
+	clusterLock, err := AcquireClusterLockOfInstance(instanceKey, "reattach-slave", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer clusterLock.Release()
+
 	instance, err := ReadTopologyInstanceUnbuffered(instanceKey)
 	if err != nil {
 		return instance, err
@@ -1042,6 +1181,17 @@ Cleanup:
 
 // DetachSlaveMasterHost detaches a slave from its master by corrupting the Master_Host (in such way that is reversible)
 func DetachSlaveMasterHost(instanceKey *InstanceKey) (*Instance, error) {
+	return DetachSlaveMasterHostUnderLock(nil, instanceKey)
+}
+
+// DetachSlaveMasterHostUnderLock is DetachSlaveMasterHost, on behalf of an operation holding given cluster lock
+func DetachSlaveMasterHostUnderLock(heldLock *ClusterLockToken, instanceKey *InstanceKey) (*Instance, error) {
+	clusterLock, err := AcquireClusterLockOfInstance(instanceKey, "detach-slave-master-host", heldLock)
+	if err != nil {
+		return nil, err
+	}
+	defer clusterLock.Release()
+
 	instance, err := ReadTopologyInstanceUnbuffered(instanceKey)
 	if err != nil {
 		return instance, err
@@ -1086,6 +1236,12 @@ Cleanup:
 
 // ReattachSlaveMasterHost reattaches a slave back onto its master by undoing a DetachSlaveMasterHost operation
 func ReattachSlaveMasterHost(instanceKey *InstanceKey) (*Instance, error) {
+	clusterLock, err := AcquireClusterLockOfInstance(instanceKey, "reattach-slave-master-host", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer clusterLock.Release()
+
 	instance, err := ReadTopologyInstanceUnbuffered(instanceKey)
 	if err != nil {
 		return instance, err
@@ -1133,6 +1289,12 @@ Cleanup:
 
 // EnableGTID will attempt to enable GTID-mode (either Oracle or MariaDB)
 func EnableGTID(instanceKey *InstanceKey) (*Instance, error) {
+	clusterLock, err := AcquireClusterLockOfInstance(instanceKey, "enable-gtid", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer clusterLock.Release()
+
 	instance, err := ReadTopologyInstanceUnbuffered(instanceKey)
 	if err != nil {
 		return instance, err
@@ -1143,7 +1305,7 @@ func EnableGTID(instanceKey *InstanceKey) (*Instance, error) {
 
 	log.Infof("Will attempt to enable GTID on %+v", *instanceKey)
 
-	instance, err = Repoint(instanceKey, nil, GTIDHintForce)
+	instance, err = RepointUnderLock(clusterLock, instanceKey, nil, GTIDHintForce)
 	if err != nil {
 		return instance, err
 	}
@@ -1158,6 +1320,12 @@ func EnableGTID(instanceKey *InstanceKey) (*Instance, error) {
 
 // DisableGTID will attempt to disable GTID-mode (either Oracle or MariaDB) and revert to binlog file:pos replication
 func DisableGTID(instanceKey *InstanceKey) (*Instance, error) {
+	clusterLock, err := AcquireClusterLockOfInstance(instanceKey, "disable-gtid", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer clusterLock.Release()
+
 	instance, err := ReadTopologyInstanceUnbuffered(instanceKey)
 	if err != nil {
 		return instance, err
@@ -1168,7 +1336,7 @@ func DisableGTID(instanceKey *InstanceKey) (*Instance, error) {
 
 	log.Infof("Will attempt to disable GTID on %+v", *instanceKey)
 
-	instance, err = Repoint(instanceKey, nil, GTIDHintDeny)
+	instance, err = RepointUnderLock(clusterLock, instanceKey, nil, GTIDHintDeny)
 	if err != nil {
 		return instance, err
 	}
@@ -1186,6 +1354,12 @@ func DisableGTID(instanceKey *InstanceKey) (*Instance, error) {
 // this will enable new slaves to be attached to given instance without complaints about missing/purged entries.
 // This function requires that the instance does not have slaves.
 func ResetMasterGTIDOperation(instanceKey *InstanceKey, removeSelfUUID bool, uuidToRemove string) (*Instance, error) {
+	clusterLock, err := AcquireClusterLockOfInstance(instanceKey, "reset-master-gtid", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer clusterLock.Release()
+
 	instance, err := ReadTopologyInstanceUnbuffered(instanceKey)
 	if err != nil {
 		return instance, err
@@ -1261,6 +1435,9 @@ func FindLastPseudoGTIDEntry(instance *Instance, recordedInstanceRelayLogCoordin
 	}
 
 	minBinlogCoordinates, minRelaylogCoordinates, err := GetHeuristiclyRecentCoordinatesForInstance(&instance.Key)
+	if minBinlogCoordinates == nil {
+		minBinlogCoordinates = getPseudoGTIDIndexHeuristicCoordinates(&instance.Key, maxBinlogCoordinates)
+	}
 	if instance.LogBinEnabled && instance.LogSlaveUpdatesEnabled && (expectedBinlogFormat == nil || instance.Binlog_format == *expectedBinlogFormat) {
 		// Well no need to search this instance's binary logs if it doesn't have any...
 		// With regard log-slave-updates, some edge cases are possible, like having this instance's log-slave-updates
@@ -1330,6 +1507,17 @@ func CorrelateBinlogCoordinates(instance *Instance, binlogCoordinates *BinlogCoo
 // a cousin of some sort (though unlikely). The only important thing is that the "other instance" is more
 // advanced in replication than given instance.
 func MatchBelow(instanceKey, otherKey *InstanceKey, requireInstanceMaintenance bool) (*Instance, *BinlogCoordinates, error) {
+	return MatchBelowUnderLock(nil, instanceKey, otherKey, requireInstanceMaintenance)
+}
+
+// MatchBelowUnderLock is MatchBelow, on behalf of an operation holding given cluster lock
+func MatchBelowUnderLock(heldLock *ClusterLockToken, instanceKey, otherKey *InstanceKey, requireInstanceMaintenance bool) (*Instance, *BinlogCoordinates, error) {
+	clusterLock, err := AcquireClusterLockOfInstance(instanceKey, "match-below", heldLock)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer clusterLock.Release()
+
 	instance, err := ReadTopologyInstanceUnbuffered(instanceKey)
 	if err != nil {
 		return instance, nil, err
@@ -1406,6 +1594,12 @@ Cleanup:
 
 // RematchSlave will re-match a slave to its master, using pseudo-gtid
 func RematchSlave(instanceKey *InstanceKey, requireInstanceMaintenance bool) (*Instance, *BinlogCoordinates, error) {
+	clusterLock, err := AcquireClusterLockOfInstance(instanceKey, "rematch", nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer clusterLock.Release()
+
 	instance, err := ReadTopologyInstanceUnbuffered(instanceKey)
 	if err != nil {
 		return instance, nil, err
@@ -1414,12 +1608,18 @@ func RematchSlave(instanceKey *InstanceKey, requireInstanceMaintenance bool) (*I
 	if err != nil || !found {
 		return instance, nil, err
 	}
-	return MatchBelow(instanceKey, &masterInstance.Key, requireInstanceMaintenance)
+	return MatchBelowUnderLock(clusterLock, instanceKey, &masterInstance.Key, requireInstanceMaintenance)
 }
 
 // MakeMaster will take an instance, make all its siblings its slaves (via pseudo-GTID) and make it master
 // (stop its replicaiton, make writeable).
 func MakeMaster(instanceKey *InstanceKey) (*Instance, error) {
+	clusterLock, err := AcquireClusterLockOfInstance(instanceKey, "make-master", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer clusterLock.Release()
+
 	instance, err := ReadTopologyInstanceUnbuffered(instanceKey)
 	if err != nil {
 		return instance, err
@@ -1456,7 +1656,7 @@ func MakeMaster(instanceKey *InstanceKey) (*Instance, error) {
 		defer EndMaintenance(maintenanceToken)
 	}
 
-	_, _, err, _ = MultiMatchBelow(siblings, instanceKey, false, nil)
+	_, _, err, _ = MultiMatchBelowUnderLock(clusterLock, siblings, instanceKey, false, nil)
 	if err != nil {
 		goto Cleanup
 	}
@@ -1476,6 +1676,12 @@ Cleanup:
 // EnslaveSiblings is a convenience method for turning sublings of a slave to be its subordinates.
 // This uses normal connected replication (does not utilize Pseudo-GTID)
 func EnslaveSiblings(instanceKey *InstanceKey) (*Instance, int, error) {
+	clusterLock, err := AcquireClusterLockOfInstance(instanceKey, "enslave-siblings", nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer clusterLock.Release()
+
 	instance, err := ReadTopologyInstanceUnbuffered(instanceKey)
 	if err != nil {
 		return instance, 0, err
@@ -1490,7 +1696,7 @@ func EnslaveSiblings(instanceKey *InstanceKey) (*Instance, int, error) {
 	}
 	enslavedSiblings := 0
 	for _, sibling := range siblings {
-		if _, err := MoveBelow(&sibling.Key, &instance.Key); err == nil {
+		if _, err := MoveBelowUnderLock(clusterLock, &sibling.Key, &instance.Key); err == nil {
 			enslavedSiblings++
 		}
 	}
@@ -1504,6 +1710,17 @@ func EnslaveSiblings(instanceKey *InstanceKey) (*Instance, int, error) {
 // Note that the master must itself be a slave; however the grandparent does not necessarily have to be reachable
 // and can in fact be dead.
 func EnslaveMaster(instanceKey *InstanceKey) (*Instance, error) {
+	return EnslaveMasterUnderLock(nil, instanceKey)
+}
+
+// EnslaveMasterUnderLock is EnslaveMaster, on behalf of an operation holding given cluster lock
+func EnslaveMasterUnderLock(heldLock *ClusterLockToken, instanceKey *InstanceKey) (*Instance, error) {
+	clusterLock, err := AcquireClusterLockOfInstance(instanceKey, "enslave-master", heldLock)
+	if err != nil {
+		return nil, err
+	}
+	defer clusterLock.Release()
+
 	instance, err := ReadTopologyInstanceUnbuffered(instanceKey)
 	if err != nil {
 		return instance, err
@@ -1563,6 +1780,12 @@ Cleanup:
 // which is most advanced among its siblings.
 // This method utilizes Pseudo GTID
 func MakeLocalMaster(instanceKey *InstanceKey) (*Instance, error) {
+	clusterLock, err := AcquireClusterLockOfInstance(instanceKey, "make-local-master", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer clusterLock.Release()
+
 	instance, err := ReadTopologyInstanceUnbuffered(instanceKey)
 	if err != nil {
 		return instance, err
@@ -1590,12 +1813,12 @@ func MakeLocalMaster(instanceKey *InstanceKey) (*Instance, error) {
 		goto Cleanup
 	}
 
-	_, _, err = MatchBelow(instanceKey, &grandparentInstance.Key, true)
+	_, _, err = MatchBelowUnderLock(clusterLock, instanceKey, &grandparentInstance.Key, true)
 	if err != nil {
 		goto Cleanup
 	}
 
-	_, _, err, _ = MultiMatchBelow(siblings, instanceKey, false, nil)
+	_, _, err, _ = MultiMatchBelowUnderLock(clusterLock, siblings, instanceKey, false, nil)
 	if err != nil {
 		goto Cleanup
 	}
@@ -1650,6 +1873,17 @@ func sortedSlaves(slaves [](*Instance), shouldStopSlaves bool) [](*Instance) {
 // MultiMatchBelow will efficiently match multiple slaves below a given instance.
 // It is assumed that all given slaves are siblings
 func MultiMatchBelow(slaves [](*Instance), belowKey *InstanceKey, slavesAlreadyStopped bool, postponedFunctionsContainer *PostponedFunctionsContainer) ([](*Instance), *Instance, error, []error) {
+	return MultiMatchBelowUnderLock(nil, slaves, belowKey, slavesAlreadyStopped, postponedFunctionsContainer)
+}
+
+// MultiMatchBelowUnderLock is MultiMatchBelow, on behalf of an operation holding given cluster lock
+func MultiMatchBelowUnderLock(heldLock *ClusterLockToken, slaves [](*Instance), belowKey *InstanceKey, slavesAlreadyStopped bool, postponedFunctionsContainer *PostponedFunctionsContainer) ([](*Instance), *Instance, error, []error) {
+	clusterLock, err := AcquireClusterLockOfInstance(belowKey, "multi-match-below", heldLock)
+	if err != nil {
+		return nil, nil, err, nil
+	}
+	defer clusterLock.Release()
+
 	res := [](*Instance){}
 	errs := []error{}
 	slaveMutex := make(chan bool, 1)
@@ -1728,7 +1962,7 @@ func MultiMatchBelow(slaves [](*Instance), belowKey *InstanceKey, slavesAlreadyS
 					log.Debugf("MultiMatchBelow: attempting slave %+v in bucket %+v", slave.Key, execCoordinates)
 					matchFunc := func() error {
 						ExecuteOnTopology(func() {
-							_, matchedCoordinates, slaveErr = MatchBelow(&slave.Key, &belowInstance.Key, false)
+							_, matchedCoordinates, slaveErr = MatchBelowUnderLock(clusterLock, &slave.Key, &belowInstance.Key, false)
 						})
 						return nil
 					}
@@ -1834,6 +2068,17 @@ func MultiMatchBelow(slaves [](*Instance), belowKey *InstanceKey, slavesAlreadyS
 
 // MultiMatchSlaves will match (via pseudo-gtid) all slaves of given master below given instance.
 func MultiMatchSlaves(masterKey *InstanceKey, belowKey *InstanceKey, pattern string) ([](*Instance), *Instance, error, []error) {
+	return MultiMatchSlavesUnderLock(nil, masterKey, belowKey, pattern)
+}
+
+// MultiMatchSlavesUnderLock is MultiMatchSlaves, on behalf of an operation holding given cluster lock
+func MultiMatchSlavesUnderLock(heldLock *ClusterLockToken, masterKey *InstanceKey, belowKey *InstanceKey, pattern string) ([](*Instance), *Instance, error, []error) {
+	clusterLock, err := AcquireClusterLockOfInstance(masterKey, "multi-match-slaves", heldLock)
+	if err != nil {
+		return nil, nil, err, nil
+	}
+	defer clusterLock.Release()
+
 	res := [](*Instance){}
 	errs := []error{}
 
@@ -1864,7 +2109,7 @@ func MultiMatchSlaves(masterKey *InstanceKey, belowKey *InstanceKey, pattern str
 		binlogCase = true
 	}
 	if binlogCase {
-		slaves, err, errors := RepointSlavesTo(masterKey, pattern, belowKey)
+		slaves, err, errors := RepointSlavesToUnderLock(clusterLock, masterKey, pattern, belowKey)
 		// Bail out!
 		return slaves, masterInstance, err, errors
 	}
@@ -1877,7 +2122,7 @@ func MultiMatchSlaves(masterKey *InstanceKey, belowKey *InstanceKey, pattern str
 		return res, belowInstance, err, errs
 	}
 	slaves = filterInstancesByPattern(slaves, pattern)
-	matchedSlaves, belowInstance, err, errs := MultiMatchBelow(slaves, &belowInstance.Key, false, nil)
+	matchedSlaves, belowInstance, err, errs := MultiMatchBelowUnderLock(clusterLock, slaves, &belowInstance.Key, false, nil)
 
 	if len(matchedSlaves) != len(slaves) {
 		err = fmt.Errorf("MultiMatchSlaves: only matched %d out of %d slaves of %+v; error is: %+v", len(matchedSlaves), len(slaves), *masterKey, err)
@@ -1889,6 +2134,12 @@ func MultiMatchSlaves(masterKey *InstanceKey, belowKey *InstanceKey, pattern str
 
 // MatchUp will move a slave up the replication chain, so that it becomes sibling of its master, via Pseudo-GTID
 func MatchUp(instanceKey *InstanceKey, requireInstanceMaintenance bool) (*Instance, *BinlogCoordinates, error) {
+	clusterLock, err := AcquireClusterLockOfInstance(instanceKey, "match-up", nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer clusterLock.Release()
+
 	instance, found, err := ReadInstance(instanceKey)
 	if err != nil || !found {
 		return nil, nil, err
@@ -1905,13 +2156,19 @@ func MatchUp(instanceKey *InstanceKey, requireInstanceMaintenance bool) (*Instan
 		return instance, nil, fmt.Errorf("master is not a slave itself: %+v", master.Key)
 	}
 
-	return MatchBelow(instanceKey, &master.MasterKey, requireInstanceMaintenance)
+	return MatchBelowUnderLock(clusterLock, instanceKey, &master.MasterKey, requireInstanceMaintenance)
 }
 
 // MatchUpSlaves will move all slaves of given master up the replication chain,
 // so that they become siblings of their master.
 // This should be called when the local master dies, and all its slaves are to be resurrected via Pseudo-GTID
 func MatchUpSlaves(masterKey *InstanceKey, pattern string) ([](*Instance), *Instance, error, []error) {
+	clusterLock, err := AcquireClusterLockOfInstance(masterKey, "match-up-slaves", nil)
+	if err != nil {
+		return nil, nil, err, nil
+	}
+	defer clusterLock.Release()
+
 	res := [](*Instance){}
 	errs := []error{}
 
@@ -1920,7 +2177,7 @@ func MatchUpSlaves(masterKey *InstanceKey, pattern string) ([](*Instance), *Inst
 		return res, nil, err, errs
 	}
 
-	return MultiMatchSlaves(masterKey, &masterInstance.MasterKey, pattern)
+	return MultiMatchSlavesUnderLock(clusterLock, masterKey, &masterInstance.MasterKey, pattern)
 }
 
 func isGenerallyValidAsBinlogSource(slave *Instance) bool {
@@ -1977,6 +2234,10 @@ func isBannedFromBeingCandidateSlave(slave *Instance) bool {
 		log.Debugf("instance %+v is banned because of promotion rule", slave.Key)
 		return true
 	}
+	if slave.SQLDelay > 0 && config.Config.PromotionIgnoreDelayedSlaves {
+		log.Debugf("instance %+v is banned because it is a delayed slave (SQL_Delay=%d)", slave.Key, slave.SQLDelay)
+		return true
+	}
 	for _, filter := range config.Config.PromotionIgnoreHostnameFilters {
 		if matched, _ := regexp.MatchString(filter, slave.Key.Hostname); matched {
 			return true
@@ -2143,6 +2404,17 @@ func GetCandidateSlaveOfBinlogServerTopology(masterKey *InstanceKey) (candidateS
 
 // RegroupSlavesPseudoGTID will choose a candidate slave of a given instance, and enslave its siblings using pseudo-gtid
 func RegroupSlavesPseudoGTID(masterKey *InstanceKey, returnSlaveEvenOnFailureToRegroup bool, onCandidateSlaveChosen func(*Instance), postponedFunctionsContainer *PostponedFunctionsContainer) ([](*Instance), [](*Instance), [](*Instance), [](*Instance), *Instance, error) {
+	return RegroupSlavesPseudoGTIDUnderLock(nil, masterKey, returnSlaveEvenOnFailureToRegroup, onCandidateSlaveChosen, postponedFunctionsContainer)
+}
+
+// RegroupSlavesPseudoGTIDUnderLock is RegroupSlavesPseudoGTID, on behalf of an operation holding given cluster lock
+func RegroupSlavesPseudoGTIDUnderLock(heldLock *ClusterLockToken, masterKey *InstanceKey, returnSlaveEvenOnFailureToRegroup bool, onCandidateSlaveChosen func(*Instance), postponedFunctionsContainer *PostponedFunctionsContainer) ([](*Instance), [](*Instance), [](*Instance), [](*Instance), *Instance, error) {
+	clusterLock, err := AcquireClusterLockOfInstance(masterKey, "regroup-slaves-pgtid", heldLock)
+	if err != nil {
+		return nil, nil, nil, nil, nil, err
+	}
+	defer clusterLock.Release()
+
 	candidateSlave, aheadSlaves, equalSlaves, laterSlaves, cannotReplicateSlaves, err := GetCandidateSlave(masterKey, true)
 	if err != nil {
 		if !returnSlaveEvenOnFailureToRegroup {
@@ -2178,7 +2450,7 @@ func RegroupSlavesPseudoGTID(masterKey *InstanceKey, returnSlaveEvenOnFailureToR
 
 	log.Debugf("RegroupSlaves: multi matching %d later slaves", len(laterSlaves))
 	// As for the laterSlaves, we'll have to apply pseudo GTID
-	laterSlaves, instance, err, _ := MultiMatchBelow(laterSlaves, &candidateSlave.Key, true, postponedFunctionsContainer)
+	laterSlaves, instance, err, _ := MultiMatchBelowUnderLock(clusterLock, laterSlaves, &candidateSlave.Key, true, postponedFunctionsContainer)
 
 	operatedSlaves := append(equalSlaves, candidateSlave)
 	operatedSlaves = append(operatedSlaves, laterSlaves...)
@@ -2224,6 +2496,17 @@ func getMostUpToDateActiveBinlogServer(masterKey *InstanceKey) (mostAdvancedBinl
 // of given instance. The function also drill in to slaves of binlog servers that are replicating from given instance,
 // and other recursive binlog servers, as long as they're in the same binlog-server-family.
 func RegroupSlavesPseudoGTIDIncludingSubSlavesOfBinlogServers(masterKey *InstanceKey, returnSlaveEvenOnFailureToRegroup bool, onCandidateSlaveChosen func(*Instance), postponedFunctionsContainer *PostponedFunctionsContainer) ([](*Instance), [](*Instance), [](*Instance), [](*Instance), *Instance, error) {
+	return RegroupSlavesPseudoGTIDIncludingSubSlavesOfBinlogServersUnderLock(nil, masterKey, returnSlaveEvenOnFailureToRegroup, onCandidateSlaveChosen, postponedFunctionsContainer)
+}
+
+// RegroupSlavesPseudoGTIDIncludingSubSlavesOfBinlogServersUnderLock is RegroupSlavesPseudoGTIDIncludingSubSlavesOfBinlogServers, on behalf of an operation holding given cluster lock
+func RegroupSlavesPseudoGTIDIncludingSubSlavesOfBinlogServersUnderLock(heldLock *ClusterLockToken, masterKey *InstanceKey, returnSlaveEvenOnFailureToRegroup bool, onCandidateSlaveChosen func(*Instance), postponedFunctionsContainer *PostponedFunctionsContainer) ([](*Instance), [](*Instance), [](*Instance), [](*Instance), *Instance, error) {
+	clusterLock, err := AcquireClusterLockOfInstance(masterKey, "regroup-slaves-pgtid", heldLock)
+	if err != nil {
+		return nil, nil, nil, nil, nil, err
+	}
+	defer clusterLock.Release()
+
 	// First, handle binlog server issues:
 	func() error {
 		log.Debugf("RegroupSlavesIncludingSubSlavesOfBinlogServers: starting on slaves of %+v", *masterKey)
@@ -2254,7 +2537,7 @@ func RegroupSlavesPseudoGTIDIncludingSubSlavesOfBinlogServers(masterKey *Instanc
 		if candidateSlave.ExecBinlogCoordinates.SmallerThan(&mostUpToDateBinlogServer.ExecBinlogCoordinates) {
 			log.Debugf("RegroupSlavesIncludingSubSlavesOfBinlogServers: candidate slave %+v coordinates smaller than binlog server %+v", candidateSlave.Key, mostUpToDateBinlogServer.Key)
 			// Need to align under binlog server...
-			candidateSlave, err = Repoint(&candidateSlave.Key, &mostUpToDateBinlogServer.Key, GTIDHintDeny)
+			candidateSlave, err = RepointUnderLock(clusterLock, &candidateSlave.Key, &mostUpToDateBinlogServer.Key, GTIDHintDeny)
 			if err != nil {
 				return log.Errore(err)
 			}
@@ -2265,7 +2548,7 @@ func RegroupSlavesPseudoGTIDIncludingSubSlavesOfBinlogServers(masterKey *Instanc
 			}
 			log.Debugf("RegroupSlavesIncludingSubSlavesOfBinlogServers: aligned candidate slave %+v under binlog server %+v", candidateSlave.Key, mostUpToDateBinlogServer.Key)
 			// and move back
-			candidateSlave, err = Repoint(&candidateSlave.Key, masterKey, GTIDHintDeny)
+			candidateSlave, err = RepointUnderLock(clusterLock, &candidateSlave.Key, masterKey, GTIDHintDeny)
 			if err != nil {
 				return log.Errore(err)
 			}
@@ -2278,7 +2561,7 @@ func RegroupSlavesPseudoGTIDIncludingSubSlavesOfBinlogServers(masterKey *Instanc
 			log.Debugf("RegroupSlavesIncludingSubSlavesOfBinlogServers: matching slaves of binlog server %+v below %+v", binlogServer.Key, candidateSlave.Key)
 			// Right now sequentially.
 			// At this point just do what you can, don't return an error
-			MultiMatchSlaves(&binlogServer.Key, &candidateSlave.Key, "")
+			MultiMatchSlavesUnderLock(clusterLock, &binlogServer.Key, &candidateSlave.Key, "")
 			log.Debugf("RegroupSlavesIncludingSubSlavesOfBinlogServers: done matching slaves of binlog server %+v below %+v", binlogServer.Key, candidateSlave.Key)
 		}
 		log.Debugf("RegroupSlavesIncludingSubSlavesOfBinlogServers: done handling binlog regrouping for %+v; will proceed with normal RegroupSlaves", *masterKey)
@@ -2286,11 +2569,22 @@ func RegroupSlavesPseudoGTIDIncludingSubSlavesOfBinlogServers(masterKey *Instanc
 		return nil
 	}()
 	// Proceed to normal regroup:
-	return RegroupSlavesPseudoGTID(masterKey, returnSlaveEvenOnFailureToRegroup, onCandidateSlaveChosen, postponedFunctionsContainer)
+	return RegroupSlavesPseudoGTIDUnderLock(clusterLock, masterKey, returnSlaveEvenOnFailureToRegroup, onCandidateSlaveChosen, postponedFunctionsContainer)
 }
 
 // RegroupSlavesGTID will choose a candidate slave of a given instance, and enslave its siblings using GTID
 func RegroupSlavesGTID(masterKey *InstanceKey, returnSlaveEvenOnFailureToRegroup bool, onCandidateSlaveChosen func(*Instance)) ([](*Instance), [](*Instance), [](*Instance), *Instance, error) {
+	return RegroupSlavesGTIDUnderLock(nil, masterKey, returnSlaveEvenOnFailureToRegroup, onCandidateSlaveChosen)
+}
+
+// RegroupSlavesGTIDUnderLock is RegroupSlavesGTID, on behalf of an operation holding given cluster lock
+func RegroupSlavesGTIDUnderLock(heldLock *ClusterLockToken, masterKey *InstanceKey, returnSlaveEvenOnFailureToRegroup bool, onCandidateSlaveChosen func(*Instance)) ([](*Instance), [](*Instance), [](*Instance), *Instance, error) {
+	clusterLock, err := AcquireClusterLockOfInstance(masterKey, "regroup-slaves-gtid", heldLock)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	defer clusterLock.Release()
+
 	var emptySlaves [](*Instance)
 	candidateSlave, aheadSlaves, equalSlaves, laterSlaves, cannotReplicateSlaves, err := GetCandidateSlave(masterKey, true)
 	if err != nil {
@@ -2322,6 +2616,17 @@ func RegroupSlavesGTID(masterKey *InstanceKey, returnSlaveEvenOnFailureToRegroup
 // RegroupSlavesBinlogServers works on a binlog-servers topology. It picks the most up-to-date BLS and repoints all other
 // BLS below it
 func RegroupSlavesBinlogServers(masterKey *InstanceKey, returnSlaveEvenOnFailureToRegroup bool) (repointedBinlogServers [](*Instance), promotedBinlogServer *Instance, err error) {
+	return RegroupSlavesBinlogServersUnderLock(nil, masterKey, returnSlaveEvenOnFailureToRegroup)
+}
+
+// RegroupSlavesBinlogServersUnderLock is RegroupSlavesBinlogServers, on behalf of an operation holding given cluster lock
+func RegroupSlavesBinlogServersUnderLock(heldLock *ClusterLockToken, masterKey *InstanceKey, returnSlaveEvenOnFailureToRegroup bool) (repointedBinlogServers [](*Instance), promotedBinlogServer *Instance, err error) {
+	clusterLock, err := AcquireClusterLockOfInstance(masterKey, "regroup-slaves-bls", heldLock)
+	if err != nil {
+		return repointedBinlogServers, promotedBinlogServer, err
+	}
+	defer clusterLock.Release()
+
 	var binlogServerSlaves [](*Instance)
 	promotedBinlogServer, binlogServerSlaves, err = getMostUpToDateActiveBinlogServer(masterKey)
 
@@ -2336,7 +2641,7 @@ func RegroupSlavesBinlogServers(masterKey *InstanceKey, returnSlaveEvenOnFailure
 		return resultOnError(err)
 	}
 
-	repointedBinlogServers, err, _ = RepointTo(binlogServerSlaves, &promotedBinlogServer.Key)
+	repointedBinlogServers, err, _ = RepointToUnderLock(clusterLock, binlogServerSlaves, &promotedBinlogServer.Key)
 
 	if err != nil {
 		return resultOnError(err)
@@ -2351,6 +2656,20 @@ func RegroupSlaves(masterKey *InstanceKey, returnSlaveEvenOnFailureToRegroup boo
 	onCandidateSlaveChosen func(*Instance),
 	postponedFunctionsContainer *PostponedFunctionsContainer) (
 	aheadSlaves [](*Instance), equalSlaves [](*Instance), laterSlaves [](*Instance), cannotReplicateSlaves [](*Instance), instance *Instance, err error) {
+	return RegroupSlavesUnderLock(nil, masterKey, returnSlaveEvenOnFailureToRegroup, onCandidateSlaveChosen, postponedFunctionsContainer)
+}
+
+// RegroupSlavesUnderLock is RegroupSlaves, on behalf of an operation holding given cluster lock
+func RegroupSlavesUnderLock(heldLock *ClusterLockToken, masterKey *InstanceKey, returnSlaveEvenOnFailureToRegroup bool,
+	onCandidateSlaveChosen func(*Instance),
+	postponedFunctionsContainer *PostponedFunctionsContainer) (
+	aheadSlaves [](*Instance), equalSlaves [](*Instance), laterSlaves [](*Instance), cannotReplicateSlaves [](*Instance), instance *Instance, err error) {
+	clusterLock, err := AcquireClusterLockOfInstance(masterKey, "regroup-slaves", heldLock)
+	if err != nil {
+		return aheadSlaves, equalSlaves, laterSlaves, cannotReplicateSlaves, instance, err
+	}
+	defer clusterLock.Release()
+
 	//
 	var emptySlaves [](*Instance)
 
@@ -2380,44 +2699,44 @@ func RegroupSlaves(masterKey *InstanceKey, returnSlaveEvenOnFailureToRegroup boo
 	}
 	if allGTID {
 		log.Debugf("RegroupSlaves: using GTID to regroup slaves of %+v", *masterKey)
-		unmovedSlaves, movedSlaves, cannotReplicateSlaves, candidateSlave, err := RegroupSlavesGTID(masterKey, returnSlaveEvenOnFailureToRegroup, onCandidateSlaveChosen)
+		unmovedSlaves, movedSlaves, cannotReplicateSlaves, candidateSlave, err := RegroupSlavesGTIDUnderLock(clusterLock, masterKey, returnSlaveEvenOnFailureToRegroup, onCandidateSlaveChosen)
 		return unmovedSlaves, emptySlaves, movedSlaves, cannotReplicateSlaves, candidateSlave, err
 	}
 	if allBinlogServers {
 		log.Debugf("RegroupSlaves: using binlog servers to regroup slaves of %+v", *masterKey)
-		movedSlaves, candidateSlave, err := RegroupSlavesBinlogServers(masterKey, returnSlaveEvenOnFailureToRegroup)
+		movedSlaves, candidateSlave, err := RegroupSlavesBinlogServersUnderLock(clusterLock, masterKey, returnSlaveEvenOnFailureToRegroup)
 		return emptySlaves, emptySlaves, movedSlaves, cannotReplicateSlaves, candidateSlave, err
 	}
 	if allPseudoGTID {
 		log.Debugf("RegroupSlaves: using Pseudo-GTID to regroup slaves of %+v", *masterKey)
-		return RegroupSlavesPseudoGTID(masterKey, returnSlaveEvenOnFailureToRegroup, onCandidateSlaveChosen, postponedFunctionsContainer)
+		return RegroupSlavesPseudoGTIDUnderLock(clusterLock, masterKey, returnSlaveEvenOnFailureToRegroup, onCandidateSlaveChosen, postponedFunctionsContainer)
 	}
 	// And, as last resort, we do PseudoGTID & binlog servers
 	log.Warningf("RegroupSlaves: unsure what method to invoke for %+v; trying Pseudo-GTID+Binlog Servers", *masterKey)
-	return RegroupSlavesPseudoGTIDIncludingSubSlavesOfBinlogServers(masterKey, returnSlaveEvenOnFailureToRegroup, onCandidateSlaveChosen, postponedFunctionsContainer)
+	return RegroupSlavesPseudoGTIDIncludingSubSlavesOfBinlogServersUnderLock(clusterLock, masterKey, returnSlaveEvenOnFailureToRegroup, onCandidateSlaveChosen, postponedFunctionsContainer)
 }
 
 // relocateBelowInternal is a protentially recursive function which chooses how to relocate an instance below another.
 // It may choose to use Pseudo-GTID, or normal binlog positions, or take advantage of binlog servers,
 // or it may combine any of the above in a multi-step operation.
-func relocateBelowInternal(instance, other *Instance) (*Instance, error) {
+func relocateBelowInternal(clusterLock *ClusterLockToken, instance, other *Instance) (*Instance, error) {
 	if canReplicate, err := instance.CanReplicateFrom(other); !canReplicate {
 		return instance, log.Errorf("%+v cannot replicate from %+v. Reason: %+v", instance.Key, other.Key, err)
 	}
 	// simplest:
 	if InstanceIsMasterOf(other, instance) {
 		// already the desired setup.
-		return Repoint(&instance.Key, &other.Key, GTIDHintNeutral)
+		return RepointUnderLock(clusterLock, &instance.Key, &other.Key, GTIDHintNeutral)
 	}
 	// Do we have record of equivalent coordinates?
 	if !instance.IsBinlogServer() {
-		if movedInstance, err := MoveEquivalent(&instance.Key, &other.Key); err == nil {
+		if movedInstance, err := MoveEquivalentUnderLock(clusterLock, &instance.Key, &other.Key); err == nil {
 			return movedInstance, nil
 		}
 	}
 	// Try and take advantage of binlog servers:
 	if InstancesAreSiblings(instance, other) && other.IsBinlogServer() {
-		return MoveBelow(&instance.Key, &other.Key)
+		return MoveBelowUnderLock(clusterLock, &instance.Key, &other.Key)
 	}
 	instanceMaster, _, err := ReadInstance(&instance.MasterKey)
 	if err != nil {
@@ -2425,12 +2744,12 @@ func relocateBelowInternal(instance, other *Instance) (*Instance, error) {
 	}
 	if instanceMaster != nil && instanceMaster.MasterKey.Equals(&other.Key) && instanceMaster.IsBinlogServer() {
 		// Moving to grandparent via binlog server
-		return Repoint(&instance.Key, &instanceMaster.MasterKey, GTIDHintDeny)
+		return RepointUnderLock(clusterLock, &instance.Key, &instanceMaster.MasterKey, GTIDHintDeny)
 	}
 	if other.IsBinlogServer() {
 		if instanceMaster != nil && instanceMaster.IsBinlogServer() && InstancesAreSiblings(instanceMaster, other) {
 			// Special case: this is a binlog server family; we move under the uncle, in one single step
-			return Repoint(&instance.Key, &other.Key, GTIDHintDeny)
+			return RepointUnderLock(clusterLock, &instance.Key, &other.Key, GTIDHintDeny)
 		}
 
 		// Relocate to its master, then repoint to the binlog server
@@ -2446,10 +2765,10 @@ func relocateBelowInternal(instance, other *Instance) (*Instance, error) {
 		}
 
 		log.Debugf("Relocating to a binlog server; will first attempt to relocate to the binlog server's master: %+v, and then repoint down", otherMaster.Key)
-		if _, err := relocateBelowInternal(instance, otherMaster); err != nil {
+		if _, err := relocateBelowInternal(clusterLock, instance, otherMaster); err != nil {
 			return instance, err
 		}
-		return Repoint(&instance.Key, &other.Key, GTIDHintDeny)
+		return RepointUnderLock(clusterLock, &instance.Key, &other.Key, GTIDHintDeny)
 	}
 	if instance.IsBinlogServer() {
 		// Can only move within the binlog-server family tree
@@ -2466,27 +2785,27 @@ func relocateBelowInternal(instance, other *Instance) (*Instance, error) {
 	if instance.UsingPseudoGTID && other.UsingPseudoGTID {
 		// We prefer PseudoGTID to anything else because, while it takes longer to run, it does not issue
 		// a STOP SLAVE on any server other than "instance" itself.
-		instance, _, err := MatchBelow(&instance.Key, &other.Key, true)
+		instance, _, err := MatchBelowUnderLock(clusterLock, &instance.Key, &other.Key, true)
 		return instance, err
 	}
 	// No Pseudo-GTID; cehck simple binlog file/pos operations:
 	if InstancesAreSiblings(instance, other) {
 		// If comastering, only move below if it's read-only
 		if !other.IsCoMaster || other.ReadOnly {
-			return MoveBelow(&instance.Key, &other.Key)
+			return MoveBelowUnderLock(clusterLock, &instance.Key, &other.Key)
 		}
 	}
 	// See if we need to MoveUp
 	if instanceMaster != nil && instanceMaster.MasterKey.Equals(&other.Key) {
 		// Moving to grandparent--handles co-mastering writable case
-		return MoveUp(&instance.Key)
+		return MoveUpUnderLock(clusterLock, &instance.Key)
 	}
 	if instanceMaster != nil && instanceMaster.IsBinlogServer() {
 		// Break operation into two: move (repoint) up, then continue
-		if _, err := MoveUp(&instance.Key); err != nil {
+		if _, err := MoveUpUnderLock(clusterLock, &instance.Key); err != nil {
 			return instance, err
 		}
-		return relocateBelowInternal(instance, other)
+		return relocateBelowInternal(clusterLock, instance, other)
 	}
 	// Too complex
 	return nil, log.Errorf("Relocating %+v below %+v turns to be too complex; please do it manually", instance.Key, other.Key)
@@ -2496,6 +2815,17 @@ func relocateBelowInternal(instance, other *Instance) (*Instance, error) {
 // Orchestrator will try and figure out the best way to relocate the server. This could span normal
 // binlog-position, pseudo-gtid, repointing, binlog servers...
 func RelocateBelow(instanceKey, otherKey *InstanceKey) (*Instance, error) {
+	return RelocateBelowUnderLock(nil, instanceKey, otherKey)
+}
+
+// RelocateBelowUnderLock is RelocateBelow, on behalf of an operation holding given cluster lock
+func RelocateBelowUnderLock(heldLock *ClusterLockToken, instanceKey, otherKey *InstanceKey) (*Instance, error) {
+	clusterLock, err := AcquireClusterLockOfInstance(instanceKey, "relocate", heldLock)
+	if err != nil {
+		return nil, err
+	}
+	defer clusterLock.Release()
+
 	instance, found, err := ReadInstance(instanceKey)
 	if err != nil || !found {
 		return instance, log.Errorf("Error reading %+v", *instanceKey)
@@ -2504,7 +2834,7 @@ func RelocateBelow(instanceKey, otherKey *InstanceKey) (*Instance, error) {
 	if err != nil || !found {
 		return instance, log.Errorf("Error reading %+v", *otherKey)
 	}
-	instance, err = relocateBelowInternal(instance, other)
+	instance, err = relocateBelowInternal(clusterLock, instance, other)
 	if err == nil {
 		AuditOperation("relocate-below", instanceKey, fmt.Sprintf("relocated %+v below %+v", *instanceKey, *otherKey))
 	}
@@ -2515,26 +2845,26 @@ func RelocateBelow(instanceKey, otherKey *InstanceKey) (*Instance, error) {
 // slaves of an instance below another.
 // It may choose to use Pseudo-GTID, or normal binlog positions, or take advantage of binlog servers,
 // or it may combine any of the above in a multi-step operation.
-func relocateSlavesInternal(slaves [](*Instance), instance, other *Instance) ([](*Instance), error, []error) {
+func relocateSlavesInternal(clusterLock *ClusterLockToken, slaves [](*Instance), instance, other *Instance) ([](*Instance), error, []error) {
 	errs := []error{}
 	var err error
 	// simplest:
 	if instance.Key.Equals(&other.Key) {
 		// already the desired setup.
-		return RepointTo(slaves, &other.Key)
+		return RepointToUnderLock(clusterLock, slaves, &other.Key)
 	}
 	// Try and take advantage of binlog servers:
 	if InstanceIsMasterOf(other, instance) && instance.IsBinlogServer() {
 		// Up from a binlog server
-		return RepointTo(slaves, &other.Key)
+		return RepointToUnderLock(clusterLock, slaves, &other.Key)
 	}
 	if InstanceIsMasterOf(instance, other) && other.IsBinlogServer() {
 		// Down under a binlog server
-		return RepointTo(slaves, &other.Key)
+		return RepointToUnderLock(clusterLock, slaves, &other.Key)
 	}
 	if InstancesAreSiblings(instance, other) && instance.IsBinlogServer() && other.IsBinlogServer() {
 		// Between siblings
-		return RepointTo(slaves, &other.Key)
+		return RepointToUnderLock(clusterLock, slaves, &other.Key)
 	}
 	if other.IsBinlogServer() {
 		// Relocate to binlog server's parent (recursive call), then repoint down
@@ -2542,12 +2872,12 @@ func relocateSlavesInternal(slaves [](*Instance), instance, other *Instance) ([]
 		if err != nil || !found {
 			return nil, err, errs
 		}
-		slaves, err, errs = relocateSlavesInternal(slaves, instance, otherMaster)
+		slaves, err, errs = relocateSlavesInternal(clusterLock, slaves, instance, otherMaster)
 		if err != nil {
 			return slaves, err, errs
 		}
 
-		return RepointTo(slaves, &other.Key)
+		return RepointToUnderLock(clusterLock, slaves, &other.Key)
 	}
 	// GTID
 	{
@@ -2558,7 +2888,7 @@ func relocateSlavesInternal(slaves [](*Instance), instance, other *Instance) ([]
 			return movedSlaves, err, errs
 		} else if len(movedSlaves) > 0 {
 			// something was moved via GTID; let's try further on
-			return relocateSlavesInternal(unmovedSlaves, instance, other)
+			return relocateSlavesInternal(clusterLock, unmovedSlaves, instance, other)
 		}
 		// Otherwise nothing was moved via GTID. Maybe we don't have any GTIDs, we continue.
 	}
@@ -2572,7 +2902,7 @@ func relocateSlavesInternal(slaves [](*Instance), instance, other *Instance) ([]
 				pseudoGTIDSlaves = append(pseudoGTIDSlaves, slave)
 			}
 		}
-		pseudoGTIDSlaves, _, err, errs = MultiMatchBelow(pseudoGTIDSlaves, &other.Key, false, nil)
+		pseudoGTIDSlaves, _, err, errs = MultiMatchBelowUnderLock(clusterLock, pseudoGTIDSlaves, &other.Key, false, nil)
 		return pseudoGTIDSlaves, err, errs
 	}
 
@@ -2589,6 +2919,16 @@ func relocateSlavesInternal(slaves [](*Instance), instance, other *Instance) ([]
 // Orchestrator will try and figure out the best way to relocate the servers. This could span normal
 // binlog-position, pseudo-gtid, repointing, binlog servers...
 func RelocateSlaves(instanceKey, otherKey *InstanceKey, pattern string) (slaves [](*Instance), other *Instance, err error, errs []error) {
+	return RelocateSlavesUnderLock(nil, instanceKey, otherKey, pattern)
+}
+
+// RelocateSlavesUnderLock is RelocateSlaves, on behalf of an operation holding given cluster lock
+func RelocateSlavesUnderLock(heldLock *ClusterLockToken, instanceKey, otherKey *InstanceKey, pattern string) (slaves [](*Instance), other *Instance, err error, errs []error) {
+	clusterLock, err := AcquireClusterLockOfInstance(instanceKey, "relocate-slaves", heldLock)
+	if err != nil {
+		return slaves, other, err, errs
+	}
+	defer clusterLock.Release()
 
 	instance, found, err := ReadInstance(instanceKey)
 	if err != nil || !found {
@@ -2609,7 +2949,7 @@ func RelocateSlaves(instanceKey, otherKey *InstanceKey, pattern string) (slaves
 		// Nothing to do
 		return slaves, other, nil, errs
 	}
-	slaves, err, errs = relocateSlavesInternal(slaves, instance, other)
+	slaves, err, errs = relocateSlavesInternal(clusterLock, slaves, instance, other)
 
 	if err == nil {
 		AuditOperation("relocate-slaves", instanceKey, fmt.Sprintf("relocated %+v slaves of %+v below %+v", len(slaves), *instanceKey, *otherKey))
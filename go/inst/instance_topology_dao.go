@@ -17,8 +17,10 @@
 package inst
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/outbrain/golib/log"
@@ -62,6 +64,17 @@ func ExecInstanceNoPrepare(instanceKey *InstanceKey, query string, args ...inter
 	return res, err
 }
 
+// ProbeInstance checks that given MySQL topology instance is reachable and responsive.
+// It neither reads nor persists the instance's state.
+func ProbeInstance(instanceKey *InstanceKey) error {
+	db, err := db.OpenTopology(instanceKey.Hostname, instanceKey.Port)
+	if err != nil {
+		return err
+	}
+	var result int
+	return db.QueryRow(`select 1`).Scan(&result)
+}
+
 // ExecuteOnTopology will execute given function while maintaining concurrency limit
 // on topology servers. It is safe in the sense that we will not leak tokens.
 func ExecuteOnTopology(f func()) {
@@ -391,6 +404,31 @@ func RestartSlave(instanceKey *InstanceKey) (instance *Instance, err error) {
 
 }
 
+// RestartSlaveIOThread stops & starts the IO thread on a given instance, leaving the SQL thread as is
+func RestartSlaveIOThread(instanceKey *InstanceKey) (*Instance, error) {
+	instance, err := ReadTopologyInstanceUnbuffered(instanceKey)
+	if err != nil {
+		return instance, log.Errore(err)
+	}
+	if !instance.IsSlave() {
+		return instance, fmt.Errorf("instance is not a slave: %+v", instanceKey)
+	}
+	if *config.RuntimeCLIFlags.Noop {
+		return instance, fmt.Errorf("noop: aborting restart-slave-io-thread operation on %+v; signalling error but nothing went wrong.", *instanceKey)
+	}
+	if _, err := ExecInstanceNoPrepare(instanceKey, `stop slave io_thread`); err != nil {
+		return instance, log.Errore(err)
+	}
+	if _, err := ExecInstanceNoPrepare(instanceKey, `start slave io_thread`); err != nil {
+		return instance, log.Errore(err)
+	}
+	log.Infof("Restarted slave IO thread on %+v", instanceKey)
+	if config.Config.SlaveStartPostWaitMilliseconds > 0 {
+		time.Sleep(time.Duration(config.Config.SlaveStartPostWaitMilliseconds) * time.Millisecond)
+	}
+	return ReadTopologyInstanceUnbuffered(instanceKey)
+}
+
 // StartSlaves will do concurrent start-slave
 func StartSlaves(slaves [](*Instance)) {
 	// use concurrency but wait for all to complete
@@ -540,42 +578,48 @@ func ChangeMasterTo(instanceKey *InstanceKey, masterKey *InstanceKey, masterBinl
 	originalMasterKey := instance.MasterKey
 	originalExecBinlogCoordinates := instance.ExecBinlogCoordinates
 
+	// A delayed slave keeps its delay when repointed
+	masterDelayClause := ""
+	if instance.SQLDelay > 0 && !instance.IsBinlogServer() {
+		masterDelayClause = fmt.Sprintf(", master_delay=%d", instance.SQLDelay)
+	}
+
 	changedViaGTID := false
 	if instance.UsingMariaDBGTID && gtidHint != GTIDHintDeny {
 		// MariaDB has a bug: a CHANGE MASTER TO statement does not work properly with prepared statement... :P
 		// See https://mariadb.atlassian.net/browse/MDEV-7640
 		// This is the reason for ExecInstanceNoPrepare
 		// Keep on using GTID
-		_, err = ExecInstanceNoPrepare(instanceKey, fmt.Sprintf("change master to master_host='%s', master_port=%d",
-			changeToMasterKey.Hostname, changeToMasterKey.Port))
+		_, err = ExecInstanceNoPrepare(instanceKey, fmt.Sprintf("change master to master_host='%s', master_port=%d%s",
+			changeToMasterKey.Hostname, changeToMasterKey.Port, masterDelayClause))
 		changedViaGTID = true
 	} else if instance.UsingMariaDBGTID && gtidHint == GTIDHintDeny {
 		// Make sure to not use GTID
-		_, err = ExecInstanceNoPrepare(instanceKey, fmt.Sprintf("change master to master_host='%s', master_port=%d, master_log_file='%s', master_log_pos=%d, master_use_gtid=no",
-			changeToMasterKey.Hostname, changeToMasterKey.Port, masterBinlogCoordinates.LogFile, masterBinlogCoordinates.LogPos))
+		_, err = ExecInstanceNoPrepare(instanceKey, fmt.Sprintf("change master to master_host='%s', master_port=%d, master_log_file='%s', master_log_pos=%d, master_use_gtid=no%s",
+			changeToMasterKey.Hostname, changeToMasterKey.Port, masterBinlogCoordinates.LogFile, masterBinlogCoordinates.LogPos, masterDelayClause))
 	} else if instance.IsMariaDB() && gtidHint == GTIDHintForce {
 		// Is MariaDB; not using GTID, turn into GTID
-		_, err = ExecInstanceNoPrepare(instanceKey, fmt.Sprintf("change master to master_host='%s', master_port=%d, master_use_gtid=slave_pos",
-			changeToMasterKey.Hostname, changeToMasterKey.Port))
+		_, err = ExecInstanceNoPrepare(instanceKey, fmt.Sprintf("change master to master_host='%s', master_port=%d, master_use_gtid=slave_pos%s",
+			changeToMasterKey.Hostname, changeToMasterKey.Port, masterDelayClause))
 		changedViaGTID = true
 	} else if instance.UsingOracleGTID && gtidHint != GTIDHintDeny {
 		// Is Oracle; already uses GTID; keep using it.
-		_, err = ExecInstanceNoPrepare(instanceKey, fmt.Sprintf("change master to master_host='%s', master_port=%d",
-			changeToMasterKey.Hostname, changeToMasterKey.Port))
+		_, err = ExecInstanceNoPrepare(instanceKey, fmt.Sprintf("change master to master_host='%s', master_port=%d%s",
+			changeToMasterKey.Hostname, changeToMasterKey.Port, masterDelayClause))
 		changedViaGTID = true
 	} else if instance.UsingOracleGTID && gtidHint == GTIDHintDeny {
 		// Is Oracle; already uses GTID
-		_, err = ExecInstanceNoPrepare(instanceKey, fmt.Sprintf("change master to master_host='%s', master_port=%d, master_log_file='%s', master_log_pos=%d, master_auto_position=0",
-			changeToMasterKey.Hostname, changeToMasterKey.Port, masterBinlogCoordinates.LogFile, masterBinlogCoordinates.LogPos))
+		_, err = ExecInstanceNoPrepare(instanceKey, fmt.Sprintf("change master to master_host='%s', master_port=%d, master_log_file='%s', master_log_pos=%d, master_auto_position=0%s",
+			changeToMasterKey.Hostname, changeToMasterKey.Port, masterBinlogCoordinates.LogFile, masterBinlogCoordinates.LogPos, masterDelayClause))
 	} else if instance.SupportsOracleGTID && gtidHint == GTIDHintForce {
 		// Is Oracle; not using GTID right now; turn into GTID
-		_, err = ExecInstanceNoPrepare(instanceKey, fmt.Sprintf("change master to master_host='%s', master_port=%d, master_auto_position=1",
-			changeToMasterKey.Hostname, changeToMasterKey.Port))
+		_, err = ExecInstanceNoPrepare(instanceKey, fmt.Sprintf("change master to master_host='%s', master_port=%d, master_auto_position=1%s",
+			changeToMasterKey.Hostname, changeToMasterKey.Port, masterDelayClause))
 		changedViaGTID = true
 	} else {
 		// Normal binlog file:pos
-		_, err = ExecInstanceNoPrepare(instanceKey, fmt.Sprintf("change master to master_host='%s', master_port=%d, master_log_file='%s', master_log_pos=%d",
-			changeToMasterKey.Hostname, changeToMasterKey.Port, masterBinlogCoordinates.LogFile, masterBinlogCoordinates.LogPos))
+		_, err = ExecInstanceNoPrepare(instanceKey, fmt.Sprintf("change master to master_host='%s', master_port=%d, master_log_file='%s', master_log_pos=%d%s",
+			changeToMasterKey.Hostname, changeToMasterKey.Port, masterBinlogCoordinates.LogFile, masterBinlogCoordinates.LogPos, masterDelayClause))
 	}
 	if err != nil {
 		return instance, log.Errore(err)
@@ -846,6 +890,85 @@ func ReadReplicationCredentials(instanceKey *InstanceKey) (replicationUser strin
 	return replicationUser, replicationPassword, err
 }
 
+// ChecksumTable returns the CHECKSUM TABLE result of given table (in schema.table form) on given instance.
+// The table name is expected to have been validated by the caller.
+func ChecksumTable(instanceKey *InstanceKey, tableName string) (checksum string, err error) {
+	var checksumTableName string
+	var tableChecksum sql.NullString
+	tokens := strings.SplitN(tableName, ".", 2)
+	if len(tokens) != 2 {
+		return "", fmt.Errorf("ChecksumTable: expected schema.table, got %s", tableName)
+	}
+	query := fmt.Sprintf("checksum table `%s`.`%s`", tokens[0], tokens[1])
+	if err = ScanInstanceRow(instanceKey, query, &checksumTableName, &tableChecksum); err != nil {
+		return "", log.Errore(err)
+	}
+	if !tableChecksum.Valid {
+		return "", fmt.Errorf("ChecksumTable: table %s does not exist on %+v", tableName, *instanceKey)
+	}
+	return tableChecksum.String, nil
+}
+
+// ReadLockTables issues FLUSH TABLES ... WITH READ LOCK on given tables (in schema.table form) on given instance,
+// blocking writes to those tables only. The lock is held on a dedicated connection until the returned function is called.
+// Table names are expected to have been validated by the caller.
+func ReadLockTables(instanceKey *InstanceKey, tableNames []string) (unlock func(), err error) {
+	quotedTableNames := []string{}
+	for _, tableName := range tableNames {
+		tokens := strings.SplitN(tableName, ".", 2)
+		if len(tokens) != 2 {
+			return nil, fmt.Errorf("ReadLockTables: expected schema.table, got %s", tableName)
+		}
+		quotedTableNames = append(quotedTableNames, fmt.Sprintf("`%s`.`%s`", tokens[0], tokens[1]))
+	}
+	if *config.RuntimeCLIFlags.Noop {
+		return nil, fmt.Errorf("noop: aborting read-lock-tables operation on %+v; signalling error but nothing went wrong.", *instanceKey)
+	}
+	sqlDb, err := db.OpenTopology(instanceKey.Hostname, instanceKey.Port)
+	if err != nil {
+		return nil, log.Errore(err)
+	}
+	conn, err := sqlDb.Conn(context.Background())
+	if err != nil {
+		return nil, log.Errore(err)
+	}
+	if _, err = conn.ExecContext(context.Background(), fmt.Sprintf("flush tables %s with read lock", strings.Join(quotedTableNames, ", "))); err != nil {
+		conn.Close()
+		return nil, log.Errore(err)
+	}
+	log.Infof("Read locked %s on %+v", strings.Join(tableNames, ", "), *instanceKey)
+	unlock = func() {
+		if _, err := conn.ExecContext(context.Background(), "unlock tables"); err != nil {
+			log.Errore(err)
+		}
+		conn.Close()
+		log.Infof("Unlocked %s on %+v", strings.Join(tableNames, ", "), *instanceKey)
+	}
+	return unlock, nil
+}
+
+// SetGTIDPurged resets the binary logs & GTID state of given instance and sets its gtid_purged to given GTID set,
+// such that it may replicate via GTID from the point at which it was copied
+func SetGTIDPurged(instanceKey *InstanceKey, gtidSet string) (*Instance, error) {
+	instance, err := ReadTopologyInstanceUnbuffered(instanceKey)
+	if err != nil {
+		return instance, log.Errore(err)
+	}
+	if *config.RuntimeCLIFlags.Noop {
+		return instance, fmt.Errorf("noop: aborting set-gtid-purged operation on %+v; signalling error but nothing went wrong.", *instanceKey)
+	}
+	if _, err = ExecInstanceNoPrepare(instanceKey, `reset master`); err != nil {
+		return instance, log.Errore(err)
+	}
+	if _, err = ExecInstance(instanceKey, `set global gtid_purged := ?`, gtidSet); err != nil {
+		return instance, log.Errore(err)
+	}
+	log.Infof("Set gtid_purged on %+v to %s", *instanceKey, gtidSet)
+
+	instance, err = ReadTopologyInstanceUnbuffered(instanceKey)
+	return instance, err
+}
+
 // SetReadOnly sets or clears the instance's global read_only variable
 func SetReadOnly(instanceKey *InstanceKey, readOnly bool) (*Instance, error) {
 	instance, err := ReadTopologyInstanceUnbuffered(instanceKey)
@@ -169,6 +169,19 @@ func TestIsBannedFromBeingCandidateSlave(t *testing.T) {
 	}
 }
 
+func TestIsBannedFromBeingCandidateSlaveDelayed(t *testing.T) {
+	instances, instancesMap := generateTestInstances()
+	instancesMap[i810Key.StringCode()].SQLDelay = 3600
+	for _, instance := range instances {
+		test.S(t).ExpectEquals(isBannedFromBeingCandidateSlave(instance), instance.Key.Equals(&i810Key))
+	}
+	config.Config.PromotionIgnoreDelayedSlaves = false
+	defer func() { config.Config.PromotionIgnoreDelayedSlaves = true }()
+	for _, instance := range instances {
+		test.S(t).ExpectFalse(isBannedFromBeingCandidateSlave(instance))
+	}
+}
+
 func TestChooseCandidateSlaveNoCandidateSlave(t *testing.T) {
 	instances, _ := generateTestInstances()
 	for _, instance := range instances {
@@ -208,6 +221,20 @@ func TestChooseCandidateSlave2(t *testing.T) {
 	test.S(t).ExpectEquals(len(cannotReplicateSlaves), 0)
 }
 
+func TestChooseCandidateSlaveSkipsDelayedSlave(t *testing.T) {
+	instances, instancesMap := generateTestInstances()
+	applyGeneralGoodToGoReplicationParams(instances)
+	instancesMap[i830Key.StringCode()].SQLDelay = 3600
+	instances = sortedSlaves(instances, false)
+	candidate, aheadSlaves, equalSlaves, laterSlaves, cannotReplicateSlaves, err := chooseCandidateSlave(instances)
+	test.S(t).ExpectNil(err)
+	test.S(t).ExpectEquals(candidate.Key, i820Key)
+	test.S(t).ExpectEquals(len(aheadSlaves), 1)
+	test.S(t).ExpectEquals(len(equalSlaves), 0)
+	test.S(t).ExpectEquals(len(laterSlaves), 4)
+	test.S(t).ExpectEquals(len(cannotReplicateSlaves), 0)
+}
+
 func TestChooseCandidateSlaveSameCoordinatesDifferentVersions(t *testing.T) {
 	instances, instancesMap := generateTestInstances()
 	applyGeneralGoodToGoReplicationParams(instances)
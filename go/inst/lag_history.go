@@ -0,0 +1,65 @@
+/*
+   Copyright 2014 Outbrain Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package inst
+
+import (
+	"database/sql"
+	"math"
+	"sort"
+)
+
+// LagSample is a single replication lag sample of an instance. Samples are bucketed by resolution;
+// a sample stands for the maximum lag observed within its bucket.
+type LagSample struct {
+	SampleUnixTimestamp int64
+	SampleTime          string
+	ResolutionSeconds   uint
+	SecondsBehindMaster sql.NullInt64
+	SlaveLagSeconds     sql.NullInt64
+}
+
+// LagHistory is the series of lag samples of a single instance, oldest first
+type LagHistory struct {
+	Key         InstanceKey
+	ClusterName string
+	Samples     []LagSample
+}
+
+// LagPercentile returns the given percentile (0-100) of given lag values, using the nearest-rank method
+func LagPercentile(lags []int64, percentile float64) int64 {
+	if len(lags) == 0 {
+		return 0
+	}
+	sorted := make([]int64, len(lags))
+	copy(sorted, lags)
+	sort.Sort(int64Slice(sorted))
+
+	rank := int(math.Ceil(percentile / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > len(sorted) {
+		rank = len(sorted)
+	}
+	return sorted[rank-1]
+}
+
+type int64Slice []int64
+
+func (this int64Slice) Len() int           { return len(this) }
+func (this int64Slice) Swap(i, j int)      { this[i], this[j] = this[j], this[i] }
+func (this int64Slice) Less(i, j int) bool { return this[i] < this[j] }
@@ -0,0 +1,215 @@
+/*
+   Copyright 2014 Outbrain Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package inst
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/outbrain/golib/log"
+	"github.com/outbrain/golib/sqlutils"
+	"github.com/outbrain/orchestrator/go/config"
+	"github.com/outbrain/orchestrator/go/db"
+)
+
+// RecordInstanceLagHistory samples the current replication lag of all replicating instances, at
+// LagHistoryResolutionSeconds resolution. Multiple polls within the same bucket retain the maximum lag.
+func RecordInstanceLagHistory() error {
+	if config.Config.LagHistoryResolutionSeconds == 0 {
+		return nil
+	}
+	writeFunc := func() error {
+		_, err := db.ExecOrchestrator(`
+			insert into
+				database_instance_lag_history (
+					hostname, port, resolution_seconds, sample_unix_timestamp,
+					cluster_name, seconds_behind_master, slave_lag_seconds
+				)
+			select
+				hostname, port, ?, unix_timestamp(last_seen) - mod(unix_timestamp(last_seen), ?),
+				cluster_name, seconds_behind_master, slave_lag_seconds
+			from
+				database_instance
+			where
+				master_host != ''
+				and last_seen >= last_checked
+			on duplicate key update
+				cluster_name = VALUES(cluster_name),
+				seconds_behind_master = coalesce(greatest(database_instance_lag_history.seconds_behind_master, VALUES(seconds_behind_master)), VALUES(seconds_behind_master), database_instance_lag_history.seconds_behind_master),
+				slave_lag_seconds = coalesce(greatest(database_instance_lag_history.slave_lag_seconds, VALUES(slave_lag_seconds)), VALUES(slave_lag_seconds), database_instance_lag_history.slave_lag_seconds)
+			`, config.Config.LagHistoryResolutionSeconds, config.Config.LagHistoryResolutionSeconds,
+		)
+		return log.Errore(err)
+	}
+	return ExecDBWriteFunc(writeFunc)
+}
+
+// DownsampleInstanceLagHistory merges lag samples older than LagHistoryDownsampleHours into
+// LagHistoryDownsampleResolutionSeconds buckets, keeping the maximum lag of each bucket.
+func DownsampleInstanceLagHistory() error {
+	if config.Config.LagHistoryDownsampleHours == 0 || config.Config.LagHistoryDownsampleResolutionSeconds == 0 {
+		return nil
+	}
+	resolution := config.Config.LagHistoryDownsampleResolutionSeconds
+	// Computed once so that we never delete samples which were not merged
+	cutoffUnixTimestamp := time.Now().Add(-time.Duration(config.Config.LagHistoryDownsampleHours) * time.Hour).Unix()
+	writeFunc := func() error {
+		_, err := db.ExecOrchestrator(`
+			insert into
+				database_instance_lag_history (
+					hostname, port, resolution_seconds, sample_unix_timestamp,
+					cluster_name, seconds_behind_master, slave_lag_seconds
+				)
+			select
+				hostname, port, ?, sample_unix_timestamp - mod(sample_unix_timestamp, ?) as bucket_unix_timestamp,
+				max(cluster_name), max(seconds_behind_master), max(slave_lag_seconds)
+			from
+				database_instance_lag_history as fine_samples
+			where
+				resolution_seconds < ?
+				and sample_unix_timestamp < ?
+			group by
+				hostname, port, bucket_unix_timestamp
+			on duplicate key update
+				seconds_behind_master = coalesce(greatest(database_instance_lag_history.seconds_behind_master, VALUES(seconds_behind_master)), VALUES(seconds_behind_master), database_instance_lag_history.seconds_behind_master),
+				slave_lag_seconds = coalesce(greatest(database_instance_lag_history.slave_lag_seconds, VALUES(slave_lag_seconds)), VALUES(slave_lag_seconds), database_instance_lag_history.slave_lag_seconds)
+			`, resolution, resolution, resolution, cutoffUnixTimestamp,
+		)
+		if err != nil {
+			return log.Errore(err)
+		}
+		_, err = db.ExecOrchestrator(`
+			delete from database_instance_lag_history
+			where
+				resolution_seconds < ?
+				and sample_unix_timestamp < ?
+			`, resolution, cutoffUnixTimestamp,
+		)
+		return log.Errore(err)
+	}
+	return ExecDBWriteFunc(writeFunc)
+}
+
+// ExpireInstanceLagHistory removes lag samples older than LagHistoryRetentionDays
+func ExpireInstanceLagHistory() error {
+	writeFunc := func() error {
+		_, err := db.ExecOrchestrator(`
+			delete from database_instance_lag_history
+			where
+				sample_unix_timestamp < unix_timestamp(NOW() - INTERVAL ? DAY)
+			`, config.Config.LagHistoryRetentionDays,
+		)
+		return log.Errore(err)
+	}
+	return ExecDBWriteFunc(writeFunc)
+}
+
+// readLagHistory reads lag samples matching given condition, grouped into per-instance series
+func readLagHistory(whereCondition string, args []interface{}) ([]LagHistory, error) {
+	res := []LagHistory{}
+	query := fmt.Sprintf(`
+		select
+			hostname,
+			port,
+			cluster_name,
+			resolution_seconds,
+			sample_unix_timestamp,
+			seconds_behind_master,
+			slave_lag_seconds
+		from
+			database_instance_lag_history
+		where
+			%s
+		order by
+			hostname, port, sample_unix_timestamp
+		`, whereCondition)
+	err := db.QueryOrchestrator(query, args, func(m sqlutils.RowMap) error {
+		key := InstanceKey{Hostname: m.GetString("hostname"), Port: m.GetInt("port")}
+		if len(res) == 0 || !res[len(res)-1].Key.Equals(&key) {
+			res = append(res, LagHistory{Key: key, Samples: []LagSample{}})
+		}
+		history := &res[len(res)-1]
+		history.ClusterName = m.GetString("cluster_name")
+
+		sample := LagSample{}
+		sample.SampleUnixTimestamp = m.GetInt64("sample_unix_timestamp")
+		sample.SampleTime = formatSnapshotTime(sample.SampleUnixTimestamp)
+		sample.ResolutionSeconds = m.GetUint("resolution_seconds")
+		sample.SecondsBehindMaster = m.GetNullInt64("seconds_behind_master")
+		sample.SlaveLagSeconds = m.GetNullInt64("slave_lag_seconds")
+		history.Samples = append(history.Samples, sample)
+		return nil
+	})
+	return res, log.Errore(err)
+}
+
+// ReadInstanceLagHistory reads the lag samples of given instance taken at or after given unix timestamp
+func ReadInstanceLagHistory(instanceKey *InstanceKey, sinceUnixTimestamp int64) (*LagHistory, error) {
+	condition := `
+		hostname = ?
+		and port = ?
+		and sample_unix_timestamp >= ?
+		`
+	histories, err := readLagHistory(condition, sqlutils.Args(instanceKey.Hostname, instanceKey.Port, sinceUnixTimestamp))
+	if err != nil {
+		return nil, err
+	}
+	if len(histories) == 0 {
+		return &LagHistory{Key: *instanceKey, Samples: []LagSample{}}, nil
+	}
+	return &histories[0], nil
+}
+
+// ReadClusterLagHistory reads the lag samples of all instances of given cluster, taken at or after given unix timestamp
+func ReadClusterLagHistory(clusterName string, sinceUnixTimestamp int64) ([]LagHistory, error) {
+	condition := `
+		cluster_name = ?
+		and sample_unix_timestamp >= ?
+		`
+	return readLagHistory(condition, sqlutils.Args(clusterName, sinceUnixTimestamp))
+}
+
+// GetClusterHeuristicLagPercentile returns the given percentile of the lag of a cluster's OSC slaves
+// as sampled since given unix timestamp. This complements GetClusterHeuristicLag, which only observes current lag.
+func GetClusterHeuristicLagPercentile(clusterName string, percentile float64, sinceUnixTimestamp int64) (int64, error) {
+	if percentile < 0 || percentile > 100 {
+		return 0, log.Errorf("Invalid percentile: %+v. Expected a value between 0 and 100", percentile)
+	}
+	instances, err := GetClusterOSCSlaves(clusterName)
+	if err != nil {
+		return 0, err
+	}
+	if len(instances) == 0 {
+		return 0, log.Errorf("No instances found in GetClusterHeuristicLagPercentile")
+	}
+	lags := []int64{}
+	for _, instance := range instances {
+		history, err := ReadInstanceLagHistory(&instance.Key, sinceUnixTimestamp)
+		if err != nil {
+			return 0, err
+		}
+		for _, sample := range history.Samples {
+			if sample.SlaveLagSeconds.Valid {
+				lags = append(lags, instance.LagBeyondSQLDelay(sample.SlaveLagSeconds.Int64))
+			}
+		}
+	}
+	if len(lags) == 0 {
+		return 0, log.Errorf("No lag history found for %s; is LagHistoryResolutionSeconds enabled?", clusterName)
+	}
+	return LagPercentile(lags, percentile), nil
+}
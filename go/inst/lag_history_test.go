@@ -0,0 +1,39 @@
+/*
+   Copyright 2014 Outbrain Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package inst
+
+import (
+	test "github.com/outbrain/golib/tests"
+	"testing"
+)
+
+func TestLagPercentile(t *testing.T) {
+	lags := []int64{7, 1, 3, 10, 2, 0, 5, 4, 9, 8}
+	test.S(t).ExpectEquals(LagPercentile(lags, 50), int64(4))
+	test.S(t).ExpectEquals(LagPercentile(lags, 90), int64(9))
+	test.S(t).ExpectEquals(LagPercentile(lags, 95), int64(10))
+	test.S(t).ExpectEquals(LagPercentile(lags, 100), int64(10))
+	test.S(t).ExpectEquals(LagPercentile(lags, 0), int64(0))
+	// input is not reordered
+	test.S(t).ExpectEquals(lags[0], int64(7))
+}
+
+func TestLagPercentileSingleAndEmpty(t *testing.T) {
+	test.S(t).ExpectEquals(LagPercentile([]int64{}, 99), int64(0))
+	test.S(t).ExpectEquals(LagPercentile([]int64{42}, 1), int64(42))
+	test.S(t).ExpectEquals(LagPercentile([]int64{42}, 99), int64(42))
+}
@@ -68,6 +68,19 @@ func ReadActiveMaintenance() ([]Maintenance, error) {
 
 }
 
+// ReadActiveMaintenanceKeys returns the keys of instances currently in maintenance
+func ReadActiveMaintenanceKeys() (*InstanceKeyMap, error) {
+	keys := NewInstanceKeyMap()
+	maintenances, err := ReadActiveMaintenance()
+	if err != nil {
+		return keys, err
+	}
+	for _, maintenance := range maintenances {
+		keys.AddKey(maintenance.Key)
+	}
+	return keys, nil
+}
+
 // BeginBoundedMaintenance will make new maintenance entry for given instanceKey.
 func BeginBoundedMaintenance(instanceKey *InstanceKey, owner string, reason string, durationSeconds uint, explicitlyBounded bool) (int64, error) {
 	var maintenanceToken int64 = 0
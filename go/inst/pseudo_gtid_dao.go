@@ -0,0 +1,206 @@
+/*
+   Copyright 2014 Outbrain Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package inst
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/outbrain/golib/log"
+	"github.com/outbrain/orchestrator/go/config"
+	"github.com/outbrain/orchestrator/go/db"
+	"github.com/rcrowley/go-metrics"
+)
+
+var pseudoGTIDInjectionCounter = uint64(time.Now().UnixNano())
+
+// pseudoGTIDSchemaVerified notes masters on which the injection schema is known to exist
+var pseudoGTIDSchemaVerified = make(map[InstanceKey]bool)
+var pseudoGTIDSchemaVerifiedMutex = &sync.Mutex{}
+
+var pseudoGTIDInjectionSuccessCounter = metrics.NewCounter()
+var pseudoGTIDInjectionFailureCounter = metrics.NewCounter()
+
+func init() {
+	metrics.Register("pseudo_gtid.injection.success", pseudoGTIDInjectionSuccessCounter)
+	metrics.Register("pseudo_gtid.injection.failure", pseudoGTIDInjectionFailureCounter)
+}
+
+// generatePseudoGTIDHint returns a unique hint, which is ascending within the lifetime of this process
+// and, given timestamp prefix, across elected orchestrator nodes.
+func generatePseudoGTIDHint() string {
+	counter := atomic.AddUint64(&pseudoGTIDInjectionCounter, 1)
+	return fmt.Sprintf("%08x:%016x:%08x", time.Now().Unix(), counter, rand.Uint32())
+}
+
+// generatePseudoGTIDStatement returns the statement injected by orchestrator on writeable masters.
+// The statement is a no-op, but still gets written to the binary log.
+func generatePseudoGTIDStatement() string {
+	return fmt.Sprintf("drop view if exists `%s`.`_pseudo_gtid_hint__%s%s`",
+		config.Config.PseudoGTIDInjectionSchema, config.Config.PseudoGTIDMonotonicHint, generatePseudoGTIDHint())
+}
+
+// ValidatePseudoGTIDInjection verifies the statement orchestrator would inject is identified as Pseudo-GTID
+// by the configured PseudoGTIDPattern. Otherwise injected entries would be useless.
+func ValidatePseudoGTIDInjection() error {
+	if config.Config.PseudoGTIDPattern == "" {
+		return fmt.Errorf("PseudoGTIDPattern is empty; refusing to inject Pseudo-GTID")
+	}
+	if config.Config.PseudoGTIDInjectionSchema == "" {
+		return fmt.Errorf("PseudoGTIDInjectionSchema is empty; refusing to inject Pseudo-GTID")
+	}
+	pseudoGTIDRegexp, err := compilePseudoGTIDPattern()
+	if err != nil {
+		return err
+	}
+	statement := generatePseudoGTIDStatement()
+	if !pseudoGTIDMatches(pseudoGTIDRegexp, statement) {
+		return fmt.Errorf("Injected Pseudo-GTID statement would not match PseudoGTIDPattern (%s): %s", config.Config.PseudoGTIDPattern, statement)
+	}
+	return nil
+}
+
+// ensurePseudoGTIDSchema creates the injection schema on given master, once per master per process lifetime
+func ensurePseudoGTIDSchema(instanceKey *InstanceKey) error {
+	pseudoGTIDSchemaVerifiedMutex.Lock()
+	defer pseudoGTIDSchemaVerifiedMutex.Unlock()
+
+	if pseudoGTIDSchemaVerified[*instanceKey] {
+		return nil
+	}
+	query := fmt.Sprintf("create database if not exists `%s`", config.Config.PseudoGTIDInjectionSchema)
+	if _, err := ExecInstanceNoPrepare(instanceKey, query); err != nil {
+		return err
+	}
+	pseudoGTIDSchemaVerified[*instanceKey] = true
+	return nil
+}
+
+// InjectPseudoGTID injects a Pseudo-GTID entry on given instance, which is expected to be a writeable master.
+// The read_only state is verified on the instance itself, not via backend data, so that a demoted master
+// does not get injected with entries.
+func InjectPseudoGTID(instanceKey *InstanceKey) (err error) {
+	defer func() {
+		if err != nil {
+			pseudoGTIDInjectionFailureCounter.Inc(1)
+		} else {
+			pseudoGTIDInjectionSuccessCounter.Inc(1)
+		}
+		writePseudoGTIDInjectionStatus(instanceKey, err)
+	}()
+
+	if err := ValidatePseudoGTIDInjection(); err != nil {
+		return log.Errore(err)
+	}
+	var readOnly bool
+	if err := ScanInstanceRow(instanceKey, "select @@global.read_only", &readOnly); err != nil {
+		return log.Errore(err)
+	}
+	if readOnly {
+		return log.Errorf("InjectPseudoGTID: %+v is read_only; will not inject Pseudo-GTID", *instanceKey)
+	}
+	if err := ensurePseudoGTIDSchema(instanceKey); err != nil {
+		return log.Errore(err)
+	}
+	if _, err := ExecInstanceNoPrepare(instanceKey, generatePseudoGTIDStatement()); err != nil {
+		return log.Errore(err)
+	}
+	return nil
+}
+
+// InjectPseudoGTIDOnWriteableMasters injects a Pseudo-GTID entry on the writeable master of each known cluster.
+// Injections run concurrently; failures are recorded per master and do not affect other clusters.
+func InjectPseudoGTIDOnWriteableMasters() error {
+	if err := ValidatePseudoGTIDInjection(); err != nil {
+		return log.Errore(err)
+	}
+	masters, err := ReadWriteableClustersMasters()
+	if err != nil {
+		return log.Errore(err)
+	}
+	inMaintenance, err := ReadActiveMaintenanceKeys()
+	if err != nil {
+		return log.Errore(err)
+	}
+	var wg sync.WaitGroup
+	for _, instanceKey := range pseudoGTIDInjectionCandidates(masters, inMaintenance) {
+		wg.Add(1)
+		go func(instanceKey InstanceKey) {
+			defer wg.Done()
+			InjectPseudoGTID(&instanceKey)
+		}(instanceKey)
+	}
+	wg.Wait()
+	return nil
+}
+
+// pseudoGTIDInjectionCandidates filters the masters on which Pseudo-GTID should be injected: reachable,
+// not binlog servers, not downtimed and not in maintenance
+func pseudoGTIDInjectionCandidates(masters [](*Instance), inMaintenance *InstanceKeyMap) (candidates []InstanceKey) {
+	for _, master := range masters {
+		if !master.IsLastCheckValid {
+			continue
+		}
+		if master.IsBinlogServer() {
+			continue
+		}
+		if master.IsDowntimed || inMaintenance.HasKey(master.Key) {
+			continue
+		}
+		candidates = append(candidates, master.Key)
+	}
+	return candidates
+}
+
+// writePseudoGTIDInjectionStatus records the outcome of latest injection attempt on given master
+func writePseudoGTIDInjectionStatus(instanceKey *InstanceKey, injectionErr error) error {
+	isSuccessful := (injectionErr == nil)
+	lastError := ""
+	if injectionErr != nil {
+		lastError = injectionErr.Error()
+	}
+	_, err := db.ExecOrchestrator(`
+			insert into
+				database_instance_pseudo_gtid_injection (
+					hostname, port, last_attempted_timestamp, last_injected_timestamp, is_successful, last_error
+				) values (
+					?, ?, NOW(), IF(?, NOW(), NULL), ?, ?
+				)
+			on duplicate key update
+				last_attempted_timestamp = values(last_attempted_timestamp),
+				last_injected_timestamp = IFNULL(values(last_injected_timestamp), last_injected_timestamp),
+				is_successful = values(is_successful),
+				last_error = values(last_error)
+			`, instanceKey.Hostname, instanceKey.Port, isSuccessful, isSuccessful, lastError,
+	)
+	return log.Errore(err)
+}
+
+// ExpirePseudoGTIDInjectionStatus removes injection status of masters not attempted in a long while
+func ExpirePseudoGTIDInjectionStatus() error {
+	_, err := db.ExecOrchestrator(`
+			delete
+				from database_instance_pseudo_gtid_injection
+			where
+				last_attempted_timestamp < NOW() - INTERVAL ? HOUR
+			`, config.Config.UnseenInstanceForgetHours,
+	)
+	return log.Errore(err)
+}
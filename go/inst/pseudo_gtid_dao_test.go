@@ -0,0 +1,63 @@
+package inst
+
+import (
+	"strings"
+	"testing"
+
+	test "github.com/outbrain/golib/tests"
+	"github.com/outbrain/orchestrator/go/config"
+)
+
+func TestValidatePseudoGTIDInjection(t *testing.T) {
+	defer func(pattern string, isFixed bool, hint string) {
+		config.Config.PseudoGTIDPattern = pattern
+		config.Config.PseudoGTIDPatternIsFixedSubstring = isFixed
+		config.Config.PseudoGTIDMonotonicHint = hint
+	}(config.Config.PseudoGTIDPattern, config.Config.PseudoGTIDPatternIsFixedSubstring, config.Config.PseudoGTIDMonotonicHint)
+
+	config.Config.PseudoGTIDMonotonicHint = "asc:"
+
+	config.Config.PseudoGTIDPattern = ""
+	test.S(t).ExpectNotNil(ValidatePseudoGTIDInjection())
+
+	config.Config.PseudoGTIDPatternIsFixedSubstring = false
+	config.Config.PseudoGTIDPattern = "drop view if exists .*?`_pseudo_gtid_hint__"
+	test.S(t).ExpectNil(ValidatePseudoGTIDInjection())
+
+	config.Config.PseudoGTIDPatternIsFixedSubstring = true
+	config.Config.PseudoGTIDPattern = "drop view if exists `meta`.`_pseudo_gtid_hint__asc:"
+	test.S(t).ExpectNil(ValidatePseudoGTIDInjection())
+
+	config.Config.PseudoGTIDPattern = "create or replace view"
+	test.S(t).ExpectNotNil(ValidatePseudoGTIDInjection())
+}
+
+func TestGeneratePseudoGTIDStatementAscending(t *testing.T) {
+	prev := generatePseudoGTIDStatement()
+	for i := 0; i < 100; i++ {
+		statement := generatePseudoGTIDStatement()
+		test.S(t).ExpectTrue(strings.Compare(statement[:len(statement)-10], prev[:len(prev)-10]) > 0)
+		prev = statement
+	}
+}
+
+func TestPseudoGTIDInjectionCandidates(t *testing.T) {
+	newMaster := func(hostname string) *Instance {
+		return &Instance{Key: InstanceKey{Hostname: hostname, Port: 3306}, IsLastCheckValid: true, Version: "5.6.28-log"}
+	}
+	healthy := newMaster("healthy")
+	unreachable := newMaster("unreachable")
+	unreachable.IsLastCheckValid = false
+	binlogServer := newMaster("binlog-server")
+	binlogServer.Version = "1.0.4-maxscale"
+	downtimed := newMaster("downtimed")
+	downtimed.IsDowntimed = true
+	maintained := newMaster("maintained")
+
+	inMaintenance := NewInstanceKeyMap()
+	inMaintenance.AddKey(maintained.Key)
+
+	candidates := pseudoGTIDInjectionCandidates([](*Instance){healthy, unreachable, binlogServer, downtimed, maintained}, inMaintenance)
+	test.S(t).ExpectEquals(len(candidates), 1)
+	test.S(t).ExpectEquals(candidates[0], healthy.Key)
+}
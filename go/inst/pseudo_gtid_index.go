@@ -0,0 +1,79 @@
+/*
+   Copyright 2014 Outbrain Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package inst
+
+import (
+	"sort"
+)
+
+// PseudoGTIDIndexEntry maps a Pseudo-GTID entry to its coordinates in the binary logs of an instance
+type PseudoGTIDIndexEntry struct {
+	Key         InstanceKey
+	Coordinates BinlogCoordinates
+	EntryText   string
+	HistoryId   int64 // The database_instance_coordinates_history entry this entry was indexed from
+}
+
+// PseudoGTIDIndex is the list of known Pseudo-GTID entries of a single instance, sorted by coordinates
+type PseudoGTIDIndex []PseudoGTIDIndexEntry
+
+// PseudoGTIDIndex sorts by coordinates, as compared by BinlogCoordinates.SmallerThan
+func (this PseudoGTIDIndex) Len() int      { return len(this) }
+func (this PseudoGTIDIndex) Swap(i, j int) { this[i], this[j] = this[j], this[i] }
+func (this PseudoGTIDIndex) Less(i, j int) bool {
+	return this[i].Coordinates.SmallerThan(&this[j].Coordinates)
+}
+
+// Find returns the index entry of given text, or nil when not indexed
+func (this PseudoGTIDIndex) Find(entryText string) *PseudoGTIDIndexEntry {
+	for i := range this {
+		if this[i].EntryText == entryText {
+			return &this[i]
+		}
+	}
+	return nil
+}
+
+// Bisect applies to monotonic Pseudo-GTID entries, where ordering by text is the same as ordering by coordinates.
+// It returns the last entry whose text is smaller than or equal to given text, and the first entry whose text is
+// greater than given text. Either may be nil.
+func (this PseudoGTIDIndex) Bisect(entryText string) (lower *PseudoGTIDIndexEntry, upper *PseudoGTIDIndexEntry) {
+	i := sort.Search(len(this), func(i int) bool { return this[i].EntryText > entryText })
+	if i > 0 {
+		lower = &this[i-1]
+	}
+	if i < len(this) {
+		upper = &this[i]
+	}
+	return lower, upper
+}
+
+// LastNotAfter returns the last entry whose coordinates are smaller than or equal to given coordinates.
+// With nil coordinates it returns the last entry. Returns nil when there is no such entry.
+func (this PseudoGTIDIndex) LastNotAfter(coordinates *BinlogCoordinates) *PseudoGTIDIndexEntry {
+	if coordinates == nil {
+		if len(this) == 0 {
+			return nil
+		}
+		return &this[len(this)-1]
+	}
+	i := sort.Search(len(this), func(i int) bool { return coordinates.SmallerThan(&this[i].Coordinates) })
+	if i == 0 {
+		return nil
+	}
+	return &this[i-1]
+}
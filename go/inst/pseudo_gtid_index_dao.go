@@ -0,0 +1,285 @@
+/*
+   Copyright 2014 Outbrain Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package inst
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/outbrain/golib/log"
+	"github.com/outbrain/golib/sqlutils"
+	"github.com/outbrain/orchestrator/go/config"
+	"github.com/outbrain/orchestrator/go/db"
+)
+
+const maxConcurrentPseudoGTIDIndexScans = 10
+const maxPseudoGTIDIndexEntryLength = 512
+
+// lastIndexedCoordinatesHistoryId is the latest database_instance_coordinates_history entry processed by the indexer.
+// Upon startup it is read from the index, see readLastIndexedCoordinatesHistoryId()
+var lastIndexedCoordinatesHistoryId int64 = 0
+var pseudoGTIDIndexingInProgress int64 = 0
+
+// IndexPseudoGTIDEntries incrementally builds the Pseudo-GTID index. Each recorded instance coordinates
+// (see RecordInstanceCoordinatesHistory) is resolved into the first Pseudo-GTID entry following those
+// coordinates, which only requires reading a single chunk of binlog events.
+// History entries are only processed once they are old enough for Pseudo-GTID to have been injected after them.
+func IndexPseudoGTIDEntries() error {
+	if config.Config.PseudoGTIDIndexHours == 0 || config.Config.PseudoGTIDPattern == "" {
+		return nil
+	}
+	if !atomic.CompareAndSwapInt64(&pseudoGTIDIndexingInProgress, 0, 1) {
+		log.Debugf("IndexPseudoGTIDEntries: already in progress")
+		return nil
+	}
+	defer atomic.StoreInt64(&pseudoGTIDIndexingInProgress, 0)
+
+	pseudoGTIDRegexp, err := compilePseudoGTIDPattern()
+	if err != nil {
+		return log.Errore(err)
+	}
+
+	if atomic.LoadInt64(&lastIndexedCoordinatesHistoryId) == 0 {
+		historyId, err := readLastIndexedCoordinatesHistoryId()
+		if err != nil {
+			return log.Errore(err)
+		}
+		atomic.StoreInt64(&lastIndexedCoordinatesHistoryId, historyId)
+	}
+
+	historyEntries := []PseudoGTIDIndexEntry{}
+	maxHistoryId := atomic.LoadInt64(&lastIndexedCoordinatesHistoryId)
+	query := `
+		select
+			database_instance_coordinates_history.history_id,
+			database_instance_coordinates_history.hostname,
+			database_instance_coordinates_history.port,
+			database_instance_coordinates_history.binary_log_file,
+			database_instance_coordinates_history.binary_log_pos
+		from
+			database_instance_coordinates_history
+			join database_instance using (hostname, port)
+		where
+			database_instance_coordinates_history.history_id > ?
+			and database_instance_coordinates_history.recorded_timestamp <= NOW() - INTERVAL ? MINUTE
+			and database_instance_coordinates_history.binary_log_file != ''
+			and database_instance.pseudo_gtid = 1
+			and database_instance.log_bin = 1
+			and (database_instance.log_slave_updates = 1 or database_instance.replication_depth = 0)
+			and database_instance.binlog_server = 0
+		order by
+			database_instance_coordinates_history.history_id
+		`
+	err = db.QueryOrchestrator(query, sqlutils.Args(maxHistoryId, config.Config.PseudoGTIDCoordinatesHistoryHeuristicMinutes), func(m sqlutils.RowMap) error {
+		entry := PseudoGTIDIndexEntry{}
+		entry.Key = InstanceKey{Hostname: m.GetString("hostname"), Port: m.GetInt("port")}
+		entry.Coordinates = BinlogCoordinates{LogFile: m.GetString("binary_log_file"), LogPos: m.GetInt64("binary_log_pos"), Type: BinaryLog}
+		entry.HistoryId = m.GetInt64("history_id")
+		historyEntries = append(historyEntries, entry)
+		if entry.HistoryId > maxHistoryId {
+			maxHistoryId = entry.HistoryId
+		}
+		return nil
+	})
+	if err != nil {
+		return log.Errore(err)
+	}
+
+	var wg sync.WaitGroup
+	scansSemaphore := make(chan bool, maxConcurrentPseudoGTIDIndexScans)
+	for _, historyEntry := range historyEntries {
+		wg.Add(1)
+		go func(historyEntry PseudoGTIDIndexEntry) {
+			defer wg.Done()
+			scansSemaphore <- true
+			defer func() { <-scansSemaphore }()
+
+			indexEntry, err := scanNextPseudoGTIDEntry(pseudoGTIDRegexp, &historyEntry.Key, historyEntry.Coordinates)
+			if err != nil {
+				log.Errore(err)
+				return
+			}
+			if indexEntry != nil {
+				indexEntry.HistoryId = historyEntry.HistoryId
+				writePseudoGTIDIndexEntry(indexEntry)
+			}
+		}(historyEntry)
+	}
+	wg.Wait()
+	atomic.StoreInt64(&lastIndexedCoordinatesHistoryId, maxHistoryId)
+	return nil
+}
+
+// readLastIndexedCoordinatesHistoryId returns the latest coordinates history entry which made it into the index.
+// History entries following it are (re)processed by the indexer.
+func readLastIndexedCoordinatesHistoryId() (int64, error) {
+	var historyId int64
+	query := `
+		select
+			ifnull(max(history_id), 0) as max_history_id
+		from
+			database_instance_pseudo_gtid_index
+		`
+	err := db.QueryOrchestrator(query, sqlutils.Args(), func(m sqlutils.RowMap) error {
+		historyId = m.GetInt64("max_history_id")
+		return nil
+	})
+	return historyId, err
+}
+
+// scanNextPseudoGTIDEntry reads a single chunk of binlog events starting at given coordinates, and returns the
+// first Pseudo-GTID entry found, if any.
+func scanNextPseudoGTIDEntry(pseudoGTIDRegexp *regexp.Regexp, instanceKey *InstanceKey, coordinates BinlogCoordinates) (*PseudoGTIDIndexEntry, error) {
+	events, err := readBinlogEventsChunk(instanceKey, coordinates)
+	if err != nil {
+		return nil, err
+	}
+	for _, event := range events {
+		if len(event.Info) > maxPseudoGTIDIndexEntryLength {
+			continue
+		}
+		if pseudoGTIDMatches(pseudoGTIDRegexp, event.Info) {
+			return &PseudoGTIDIndexEntry{Key: *instanceKey, Coordinates: event.Coordinates, EntryText: event.Info}, nil
+		}
+	}
+	return nil, nil
+}
+
+// writePseudoGTIDIndexEntry persists a single index entry
+func writePseudoGTIDIndexEntry(entry *PseudoGTIDIndexEntry) error {
+	writeFunc := func() error {
+		_, err := db.ExecOrchestrator(`
+			insert ignore into
+				database_instance_pseudo_gtid_index (
+					hostname, port, binary_log_file, binary_log_pos, entry_text, history_id, recorded_timestamp
+				) values (
+					?, ?, ?, ?, ?, ?, NOW()
+				)
+			`, entry.Key.Hostname, entry.Key.Port, entry.Coordinates.LogFile, entry.Coordinates.LogPos, entry.EntryText, entry.HistoryId,
+		)
+		return log.Errore(err)
+	}
+	return ExecDBWriteFunc(writeFunc)
+}
+
+// ReadPseudoGTIDIndex reads the Pseudo-GTID index of given instance, sorted by coordinates
+func ReadPseudoGTIDIndex(instanceKey *InstanceKey) (PseudoGTIDIndex, error) {
+	index := PseudoGTIDIndex{}
+	query := `
+		select
+			hostname, port, binary_log_file, binary_log_pos, entry_text
+		from
+			database_instance_pseudo_gtid_index
+		where
+			hostname = ?
+			and port = ?
+		order by
+			binary_log_file, binary_log_pos
+		`
+	err := db.QueryOrchestrator(query, sqlutils.Args(instanceKey.Hostname, instanceKey.Port), func(m sqlutils.RowMap) error {
+		entry := PseudoGTIDIndexEntry{}
+		entry.Key = InstanceKey{Hostname: m.GetString("hostname"), Port: m.GetInt("port")}
+		entry.Coordinates = BinlogCoordinates{LogFile: m.GetString("binary_log_file"), LogPos: m.GetInt64("binary_log_pos"), Type: BinaryLog}
+		entry.EntryText = m.GetString("entry_text")
+		index = append(index, entry)
+		return nil
+	})
+	// The backend's collation may not agree with SmallerThan, on which lookups rely
+	sort.Sort(index)
+	return index, log.Errore(err)
+}
+
+// verifyPseudoGTIDIndexEntry confirms the indexed entry still exists on the instance at indexed coordinates;
+// binary logs may have been purged or the server may have been rebuilt since.
+func verifyPseudoGTIDIndexEntry(entry *PseudoGTIDIndexEntry) bool {
+	db, err := db.OpenTopology(entry.Key.Hostname, entry.Key.Port)
+	if err != nil {
+		return false
+	}
+	verified := false
+	query := fmt.Sprintf("show binlog events in '%s' FROM %d LIMIT 1", entry.Coordinates.LogFile, entry.Coordinates.LogPos)
+	err = sqlutils.QueryRowsMap(db, query, func(m sqlutils.RowMap) error {
+		verified = (m.GetString("Info") == entry.EntryText)
+		return nil
+	})
+	return err == nil && verified
+}
+
+// lookupPseudoGTIDIndex consults the Pseudo-GTID index of given instance for given entry.
+// If the entry is indexed (and verified on the instance), its coordinates are returned as exactCoordinates.
+// Otherwise, for monotonic entries, the index is bisected for the coordinates between which the entry must reside.
+// upperCoordinates may be nil, in which case the entry resides between lowerCoordinates and the end of the binary logs.
+func lookupPseudoGTIDIndex(instance *Instance, entryText string, monotonicPseudoGTIDEntries bool) (exactCoordinates *BinlogCoordinates, lowerCoordinates *BinlogCoordinates, upperCoordinates *BinlogCoordinates) {
+	if config.Config.PseudoGTIDIndexHours == 0 {
+		return nil, nil, nil
+	}
+	index, err := ReadPseudoGTIDIndex(&instance.Key)
+	if err != nil || len(index) == 0 {
+		return nil, nil, nil
+	}
+	if entry := index.Find(entryText); entry != nil && verifyPseudoGTIDIndexEntry(entry) {
+		log.Debugf("Found Pseudo-GTID entry in index: %+v, %+v", instance.Key, entry.Coordinates)
+		return &entry.Coordinates, nil, nil
+	}
+	if !monotonicPseudoGTIDEntries {
+		return nil, nil, nil
+	}
+	lower, upper := index.Bisect(entryText)
+	if lower == nil {
+		return nil, nil, nil
+	}
+	lowerCoordinates = &lower.Coordinates
+	if upper != nil {
+		upperCoordinates = &upper.Coordinates
+	}
+	log.Debugf("Pseudo-GTID index of %+v bisected: entry is between %+v and %+v", instance.Key, lowerCoordinates, upperCoordinates)
+	return nil, lowerCoordinates, upperCoordinates
+}
+
+// getPseudoGTIDIndexHeuristicCoordinates returns coordinates of latest indexed Pseudo-GTID entry not beyond given
+// coordinates, which can be used as starting point when looking for the last Pseudo-GTID entry of an instance.
+func getPseudoGTIDIndexHeuristicCoordinates(instanceKey *InstanceKey, maxCoordinates *BinlogCoordinates) *BinlogCoordinates {
+	if config.Config.PseudoGTIDIndexHours == 0 {
+		return nil
+	}
+	index, err := ReadPseudoGTIDIndex(instanceKey)
+	if err != nil {
+		return nil
+	}
+	if entry := index.LastNotAfter(maxCoordinates); entry != nil {
+		return &entry.Coordinates
+	}
+	return nil
+}
+
+// ExpirePseudoGTIDIndex removes index entries older than PseudoGTIDIndexHours
+func ExpirePseudoGTIDIndex() error {
+	writeFunc := func() error {
+		_, err := db.ExecOrchestrator(`
+			delete
+				from database_instance_pseudo_gtid_index
+			where
+				recorded_timestamp < NOW() - INTERVAL ? HOUR
+			`, config.Config.PseudoGTIDIndexHours,
+		)
+		return log.Errore(err)
+	}
+	return ExecDBWriteFunc(writeFunc)
+}
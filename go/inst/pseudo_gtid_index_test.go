@@ -0,0 +1,134 @@
+package inst
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	test "github.com/outbrain/golib/tests"
+	"github.com/outbrain/orchestrator/go/config"
+)
+
+func mkTestPseudoGTIDIndex() PseudoGTIDIndex {
+	return PseudoGTIDIndex{
+		{Key: key1, Coordinates: BinlogCoordinates{LogFile: "mysql-bin.000011", LogPos: 100}, EntryText: "asc:0010"},
+		{Key: key1, Coordinates: BinlogCoordinates{LogFile: "mysql-bin.000011", LogPos: 900}, EntryText: "asc:0020"},
+		{Key: key1, Coordinates: BinlogCoordinates{LogFile: "mysql-bin.000013", LogPos: 400}, EntryText: "asc:0030"},
+	}
+}
+
+func TestPseudoGTIDIndexSort(t *testing.T) {
+	index := PseudoGTIDIndex{
+		{Key: key1, Coordinates: BinlogCoordinates{LogFile: "mysql-bin.000013", LogPos: 400}, EntryText: "asc:0030"},
+		{Key: key1, Coordinates: BinlogCoordinates{LogFile: "mysql-bin.000011", LogPos: 900}, EntryText: "asc:0020"},
+		{Key: key1, Coordinates: BinlogCoordinates{LogFile: "mysql-bin.000011", LogPos: 100}, EntryText: "asc:0010"},
+	}
+	sort.Sort(index)
+	test.S(t).ExpectEquals(index[0].EntryText, "asc:0010")
+	test.S(t).ExpectEquals(index[1].EntryText, "asc:0020")
+	test.S(t).ExpectEquals(index[2].EntryText, "asc:0030")
+	test.S(t).ExpectEquals(index.LastNotAfter(&BinlogCoordinates{LogFile: "mysql-bin.000012", LogPos: 4}).EntryText, "asc:0020")
+}
+
+func TestPseudoGTIDIndexFind(t *testing.T) {
+	index := mkTestPseudoGTIDIndex()
+	test.S(t).ExpectEquals(index.Find("asc:0020").Coordinates.LogPos, int64(900))
+	test.S(t).ExpectTrue(index.Find("asc:0025") == nil)
+}
+
+func TestPseudoGTIDIndexBisect(t *testing.T) {
+	index := mkTestPseudoGTIDIndex()
+	{
+		lower, upper := index.Bisect("asc:0025")
+		test.S(t).ExpectEquals(lower.EntryText, "asc:0020")
+		test.S(t).ExpectEquals(upper.EntryText, "asc:0030")
+	}
+	{
+		lower, upper := index.Bisect("asc:0005")
+		test.S(t).ExpectTrue(lower == nil)
+		test.S(t).ExpectEquals(upper.EntryText, "asc:0010")
+	}
+	{
+		lower, upper := index.Bisect("asc:0040")
+		test.S(t).ExpectEquals(lower.EntryText, "asc:0030")
+		test.S(t).ExpectTrue(upper == nil)
+	}
+	{
+		lower, _ := PseudoGTIDIndex{}.Bisect("asc:0040")
+		test.S(t).ExpectTrue(lower == nil)
+	}
+}
+
+func TestPseudoGTIDIndexLastNotAfter(t *testing.T) {
+	index := mkTestPseudoGTIDIndex()
+	test.S(t).ExpectEquals(index.LastNotAfter(nil).EntryText, "asc:0030")
+	test.S(t).ExpectEquals(index.LastNotAfter(&BinlogCoordinates{LogFile: "mysql-bin.000012", LogPos: 4}).EntryText, "asc:0020")
+	test.S(t).ExpectEquals(index.LastNotAfter(&BinlogCoordinates{LogFile: "mysql-bin.000011", LogPos: 900}).EntryText, "asc:0020")
+	test.S(t).ExpectTrue(index.LastNotAfter(&BinlogCoordinates{LogFile: "mysql-bin.000011", LogPos: 4}) == nil)
+}
+
+func TestGetBinlogsBetween(t *testing.T) {
+	lower := BinlogCoordinates{LogFile: "mysql-bin.000011", LogPos: 100}
+	upper := BinlogCoordinates{LogFile: "mysql-bin.000013", LogPos: 400}
+	binlogs, err := getBinlogsBetween(&lower, &upper)
+	test.S(t).ExpectNil(err)
+	test.S(t).ExpectEquals(fmt.Sprintf("%+v", binlogs), "[mysql-bin.000013 mysql-bin.000012 mysql-bin.000011]")
+
+	binlogs, err = getBinlogsBetween(&lower, &lower)
+	test.S(t).ExpectNil(err)
+	test.S(t).ExpectEquals(fmt.Sprintf("%+v", binlogs), "[mysql-bin.000011]")
+}
+
+func TestGetBinlogsBackwards(t *testing.T) {
+	binlogs, previous, err := getBinlogsBackwards(BinlogCoordinates{LogFile: "mysql-bin.000013", LogPos: 400}, 2)
+	test.S(t).ExpectNil(err)
+	test.S(t).ExpectEquals(fmt.Sprintf("%+v", binlogs), "[mysql-bin.000013 mysql-bin.000012]")
+	test.S(t).ExpectEquals(previous.LogFile, "mysql-bin.000011")
+}
+
+func TestSearchBinlogsConcurrently(t *testing.T) {
+	defer func(concurrency uint) {
+		config.Config.PseudoGTIDSearchConcurrency = concurrency
+	}(config.Config.PseudoGTIDSearchConcurrency)
+	config.Config.PseudoGTIDSearchConcurrency = 2
+
+	binlogs := []string{"mysql-bin.000014", "mysql-bin.000013", "mysql-bin.000012", "mysql-bin.000011", "mysql-bin.000010"}
+	entries := map[string]int64{"mysql-bin.000012": 120, "mysql-bin.000011": 110}
+	search := func(binlog string, minCoordinates *BinlogCoordinates) (*BinlogCoordinates, string, error) {
+		if binlog == "mysql-bin.000010" {
+			return nil, "", fmt.Errorf("Could not find target log")
+		}
+		pos, ok := entries[binlog]
+		if !ok || (minCoordinates != nil && minCoordinates.LogPos > pos) {
+			return nil, "", nil
+		}
+		return &BinlogCoordinates{LogFile: binlog, LogPos: pos}, binlog, nil
+	}
+	{
+		coordinates, _, err := searchBinlogsConcurrently(binlogs, nil, true, search)
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(coordinates.LogFile, "mysql-bin.000012")
+	}
+	{
+		// heuristic coordinates past the entry, with rescan
+		coordinates, _, err := searchBinlogsConcurrently(binlogs, &BinlogCoordinates{LogFile: "mysql-bin.000012", LogPos: 500}, true, search)
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(coordinates.LogFile, "mysql-bin.000012")
+	}
+	{
+		// heuristic coordinates past the entry, without rescan
+		coordinates, _, err := searchBinlogsConcurrently(binlogs, &BinlogCoordinates{LogFile: "mysql-bin.000012", LogPos: 500}, false, search)
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(coordinates.LogFile, "mysql-bin.000011")
+	}
+	{
+		coordinates, _, err := searchBinlogsConcurrently(binlogs[:2], nil, true, search)
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectTrue(coordinates == nil)
+	}
+	{
+		coordinates, _, err := searchBinlogsConcurrently(binlogs[4:], nil, true, search)
+		test.S(t).ExpectNotNil(err)
+		test.S(t).ExpectTrue(coordinates == nil)
+	}
+}
@@ -0,0 +1,187 @@
+/*
+   Copyright 2014 Outbrain Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package inst
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const (
+	TopologyFormatASCII = "ascii"
+	TopologyFormatDOT   = "dot"
+	TopologyFormatJSON  = "json"
+)
+
+// TopologyTreeNode is an instance in a nested (JSON friendly) topology tree
+type TopologyTreeNode struct {
+	Key               InstanceKey
+	Version           string
+	ReadOnly          bool
+	Status            string
+	LagStatus         string
+	SlaveIORunning    bool
+	SlaveSQLRunning   bool
+	IsDowntimed       bool
+	IsCoMaster        bool
+	Replicas          [](*TopologyTreeNode)
+	ReplicasTruncated bool `json:",omitempty"`
+}
+
+// getJSONTopologyEntry will get a nested topology tree rooted at given instance. It recursively builds the tree.
+func getJSONTopologyEntry(depth int, instance *Instance, replicationMap map[*Instance]([]*Instance), extendedOutput bool) *TopologyTreeNode {
+	if instance == nil {
+		return nil
+	}
+	node := &TopologyTreeNode{
+		Key:        instance.Key,
+		Version:    instance.Version,
+		ReadOnly:   instance.ReadOnly,
+		IsCoMaster: instance.IsCoMaster,
+		Replicas:   [](*TopologyTreeNode){},
+	}
+	if extendedOutput {
+		node.Status = instance.StatusString()
+		node.LagStatus = instance.LagStatusString()
+		node.SlaveIORunning = instance.Slave_IO_Running
+		node.SlaveSQLRunning = instance.Slave_SQL_Running
+		node.IsDowntimed = instance.IsDowntimed
+	}
+	if instance.IsCoMaster && depth > 1 {
+		// The other co-master; its own branch lists its slaves
+		node.ReplicasTruncated = true
+		return node
+	}
+	for _, slave := range replicationMap[instance] {
+		if slaveNode := getJSONTopologyEntry(depth+1, slave, replicationMap, extendedOutput); slaveNode != nil {
+			node.Replicas = append(node.Replicas, slaveNode)
+		}
+	}
+	return node
+}
+
+// dotNodeColor returns the fill color of an instance's node, by health, lag and downtime
+func dotNodeColor(instance *Instance, extendedOutput bool) string {
+	if !extendedOutput {
+		return "white"
+	}
+	if instance.IsDowntimed {
+		return "lightgray"
+	}
+	switch instance.StatusString() {
+	case "ok":
+		return "palegreen"
+	case "lag":
+		return "orange"
+	case "nonreplicating":
+		return "tomato"
+	}
+	return "gray"
+}
+
+// getDOTTopologyEntry will get the Graphviz DOT node and edge statements of the topology tree rooted at given
+// instance. It recursively draws the tree.
+func getDOTTopologyEntry(depth int, instance *Instance, replicationMap map[*Instance]([]*Instance), extendedOutput bool) []string {
+	if instance == nil {
+		return []string{}
+	}
+	if instance.IsCoMaster && depth > 1 {
+		// The other co-master is drawn in its own branch
+		return []string{}
+	}
+	labelTokens := []string{instance.Key.DisplayString()}
+	if extendedOutput {
+		labelTokens = append(labelTokens, instance.Version, instance.StatusString())
+		if instance.ReadOnly {
+			labelTokens = append(labelTokens, "ro")
+		}
+		if instance.IsDowntimed {
+			labelTokens = append(labelTokens, "downtimed")
+		}
+	}
+	result := []string{
+		fmt.Sprintf(`  "%s" [label="%s", style=filled, fillcolor=%s];`, instance.Key.DisplayString(), strings.Join(labelTokens, `\n`), dotNodeColor(instance, extendedOutput)),
+	}
+	for _, slave := range replicationMap[instance] {
+		edgeAttributes := ""
+		if extendedOutput {
+			edgeLabel := fmt.Sprintf(`IO:%t SQL:%t\nlag:%s`, slave.Slave_IO_Running, slave.Slave_SQL_Running, slave.LagStatusString())
+			edgeStyle := "solid"
+			edgeColor := "black"
+			if !slave.Slave_IO_Running || !slave.Slave_SQL_Running {
+				edgeStyle = "dashed"
+				edgeColor = "red"
+			}
+			edgeAttributes = fmt.Sprintf(` [label="%s", style=%s, color=%s]`, edgeLabel, edgeStyle, edgeColor)
+		}
+		result = append(result, fmt.Sprintf(`  "%s" -> "%s"%s;`, instance.Key.DisplayString(), slave.Key.DisplayString(), edgeAttributes))
+		result = append(result, getDOTTopologyEntry(depth+1, slave, replicationMap, extendedOutput)...)
+	}
+	return result
+}
+
+// FormattedTopology returns the topology of given cluster in given format: ascii (default), dot or json
+func FormattedTopology(clusterName string, historyTimestampPattern string, format string) (string, error) {
+	switch format {
+	case "", TopologyFormatASCII:
+		return ASCIITopology(clusterName, historyTimestampPattern)
+	case TopologyFormatDOT:
+		return DOTTopology(clusterName, historyTimestampPattern)
+	case TopologyFormatJSON:
+		trees, err := JSONTopology(clusterName, historyTimestampPattern)
+		if err != nil {
+			return "", err
+		}
+		b, err := json.MarshalIndent(trees, "", "  ")
+		return string(b), err
+	}
+	return "", fmt.Errorf("Unsupported topology format: %s. Expected one of: ascii, dot, json", format)
+}
+
+// JSONTopology returns the topology of given cluster as nested trees, one per master (more than one for co-masters)
+func JSONTopology(clusterName string, historyTimestampPattern string) ([](*TopologyTreeNode), error) {
+	instances, err := readTopologyInstances(clusterName, historyTimestampPattern)
+	if err != nil {
+		return nil, err
+	}
+	roots, rootDepth, replicationMap := getTopologyTree(instances)
+	trees := [](*TopologyTreeNode){}
+	for _, root := range roots {
+		trees = append(trees, getJSONTopologyEntry(rootDepth, root, replicationMap, historyTimestampPattern == ""))
+	}
+	return trees, nil
+}
+
+// DOTTopology returns a Graphviz DOT representation of the topology of given cluster
+func DOTTopology(clusterName string, historyTimestampPattern string) (string, error) {
+	instances, err := readTopologyInstances(clusterName, historyTimestampPattern)
+	if err != nil {
+		return "", err
+	}
+	roots, rootDepth, replicationMap := getTopologyTree(instances)
+	entries := []string{
+		fmt.Sprintf(`digraph "%s" {`, clusterName),
+		`  node [shape=box, fontname="Helvetica"];`,
+		`  edge [fontname="Helvetica", fontsize=10];`,
+	}
+	for _, root := range roots {
+		entries = append(entries, getDOTTopologyEntry(rootDepth, root, replicationMap, historyTimestampPattern == "")...)
+	}
+	entries = append(entries, "}")
+	return strings.Join(entries, "\n"), nil
+}
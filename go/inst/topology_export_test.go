@@ -0,0 +1,88 @@
+package inst
+
+import (
+	"database/sql"
+	test "github.com/outbrain/golib/tests"
+	"strings"
+	"testing"
+)
+
+// generateTopologyExportTestInstances returns: i710 is master of i720, i730; i720 is master of i810
+func generateTopologyExportTestInstances() (instances [](*Instance), instancesMap map[string](*Instance)) {
+	instances, instancesMap = generateTestInstances()
+	instances = instances[0:4]
+	instancesMap[i720Key.StringCode()].MasterKey = i710Key
+	instancesMap[i730Key.StringCode()].MasterKey = i710Key
+	instancesMap[i810Key.StringCode()].MasterKey = i720Key
+	return instances, instancesMap
+}
+
+func TestGetTopologyTree(t *testing.T) {
+	instances, instancesMap := generateTopologyExportTestInstances()
+	roots, rootDepth, replicationMap := getTopologyTree(instances)
+	test.S(t).ExpectEquals(len(roots), 1)
+	test.S(t).ExpectEquals(rootDepth, 0)
+	test.S(t).ExpectEquals(roots[0].Key, i710Key)
+	test.S(t).ExpectEquals(len(replicationMap[instancesMap[i710Key.StringCode()]]), 2)
+	test.S(t).ExpectEquals(len(replicationMap[instancesMap[i720Key.StringCode()]]), 1)
+}
+
+func TestGetTopologyTreeCoMasters(t *testing.T) {
+	instances, instancesMap := generateTopologyExportTestInstances()
+	instancesMap[i710Key.StringCode()].MasterKey = i720Key
+	instancesMap[i710Key.StringCode()].IsCoMaster = true
+	instancesMap[i720Key.StringCode()].IsCoMaster = true
+	roots, rootDepth, _ := getTopologyTree(instances)
+	test.S(t).ExpectEquals(len(roots), 2)
+	test.S(t).ExpectEquals(rootDepth, 1)
+
+	trees := [](*TopologyTreeNode){}
+	for _, root := range roots {
+		trees = append(trees, getJSONTopologyEntry(rootDepth, root, nil, false))
+	}
+	test.S(t).ExpectEquals(len(trees), 2)
+}
+
+func TestASCIITopologyFromInstances(t *testing.T) {
+	instances, _ := generateTopologyExportTestInstances()
+	ascii := asciiTopologyFromInstances(instances, false)
+	test.S(t).ExpectEquals(ascii, "i710:3306\n- i720:3306\n  - i810:3306\n- i730:3306")
+}
+
+func TestGetJSONTopologyEntry(t *testing.T) {
+	instances, _ := generateTopologyExportTestInstances()
+	roots, rootDepth, replicationMap := getTopologyTree(instances)
+	tree := getJSONTopologyEntry(rootDepth, roots[0], replicationMap, true)
+	test.S(t).ExpectEquals(tree.Key, i710Key)
+	test.S(t).ExpectEquals(len(tree.Replicas), 2)
+	test.S(t).ExpectEquals(tree.Replicas[0].Key, i720Key)
+	test.S(t).ExpectEquals(len(tree.Replicas[0].Replicas), 1)
+	test.S(t).ExpectEquals(tree.Replicas[0].Replicas[0].Key, i810Key)
+	test.S(t).ExpectEquals(tree.Replicas[1].Key, i730Key)
+	test.S(t).ExpectEquals(len(tree.Replicas[1].Replicas), 0)
+	test.S(t).ExpectEquals(tree.Status, "invalid")
+}
+
+func TestGetDOTTopologyEntry(t *testing.T) {
+	instances, instancesMap := generateTopologyExportTestInstances()
+	applyGeneralGoodToGoReplicationParams(instances)
+	for _, instance := range instances {
+		instance.IsRecentlyChecked = true
+		if instance.MasterKey.IsValid() {
+			instance.ReadBinlogCoordinates = instance.ExecBinlogCoordinates
+		}
+	}
+	instancesMap[i720Key.StringCode()].Slave_IO_Running = true
+	instancesMap[i720Key.StringCode()].Slave_SQL_Running = true
+	instancesMap[i720Key.StringCode()].SecondsBehindMaster = sql.NullInt64{Int64: 3, Valid: true}
+	instancesMap[i720Key.StringCode()].SlaveLagSeconds = sql.NullInt64{Int64: 3, Valid: true}
+	instancesMap[i730Key.StringCode()].IsDowntimed = true
+
+	roots, rootDepth, replicationMap := getTopologyTree(instances)
+	dot := strings.Join(getDOTTopologyEntry(rootDepth, roots[0], replicationMap, true), "\n")
+	test.S(t).ExpectTrue(strings.Contains(dot, `"i710:3306" -> "i720:3306" [label="IO:true SQL:true\nlag:3s", style=solid, color=black];`))
+	test.S(t).ExpectTrue(strings.Contains(dot, `"i720:3306" -> "i810:3306" [label="IO:false SQL:false\nlag:null", style=dashed, color=red];`))
+	test.S(t).ExpectTrue(strings.Contains(dot, `"i720:3306" [label="i720:3306\n5.6.7\nok", style=filled, fillcolor=palegreen];`))
+	test.S(t).ExpectTrue(strings.Contains(dot, `"i810:3306" [label="i810:3306\n5.6.7\nnonreplicating", style=filled, fillcolor=tomato];`))
+	test.S(t).ExpectTrue(strings.Contains(dot, `"i730:3306" [label="i730:3306\n5.6.7\nnonreplicating\ndowntimed", style=filled, fillcolor=lightgray];`))
+}
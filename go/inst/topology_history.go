@@ -0,0 +1,202 @@
+/*
+   Copyright 2014 Outbrain Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package inst
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	TopologyHistoryMoved           = "moved"
+	TopologyHistoryAppeared        = "appeared"
+	TopologyHistoryDisappeared     = "disappeared"
+	TopologyHistoryReadOnlyChanged = "read-only-changed"
+)
+
+// topologySnapshotTimeFormats are the accepted formats for pointing at a point in time in topology history,
+// in addition to a unix timestamp
+var topologySnapshotTimeFormats = []string{
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04",
+	"2006-01-02",
+}
+
+// TopologySnapshot describes a single topology snapshot of a cluster
+type TopologySnapshot struct {
+	SnapshotUnixTimestamp int64
+	SnapshotTime          string
+	CountInstances        uint
+}
+
+// HistoryTopologyNode is an instance within a history topology tree, along with its replicas.
+// ReadOnly is nil when the read_only state was not recorded.
+type HistoryTopologyNode struct {
+	Key      InstanceKey
+	Version  string
+	ReadOnly *bool
+	Replicas [](*HistoryTopologyNode)
+}
+
+// HistoryTopology is a cluster's topology as recorded in a snapshot. Instances is the flat list of
+// recorded instances; Tree, which is what gets serialized, nests them by master, rooted at the
+// cluster's master (or co-masters).
+type HistoryTopology struct {
+	ClusterName           string
+	SnapshotUnixTimestamp int64
+	SnapshotTime          string
+	Instances             [](*Instance) `json:"-"`
+	Tree                  [](*HistoryTopologyNode)
+	unknownReadOnly       *InstanceKeyMap
+}
+
+func newHistoryTopology(clusterName string, snapshotUnixTimestamp int64, instances [](*Instance), unknownReadOnly *InstanceKeyMap) *HistoryTopology {
+	return &HistoryTopology{
+		ClusterName:           clusterName,
+		SnapshotUnixTimestamp: snapshotUnixTimestamp,
+		SnapshotTime:          formatSnapshotTime(snapshotUnixTimestamp),
+		Instances:             instances,
+		Tree:                  historyTopologyTree(instances, unknownReadOnly),
+		unknownReadOnly:       unknownReadOnly,
+	}
+}
+
+// historyTopologyTree nests given instances by master. Roots are instances whose master is not among
+// given instances. Co-masters, replicating from each other, have no such root: the first of them is made
+// a root, and the other is nested below it.
+func historyTopologyTree(instances [](*Instance), unknownReadOnly *InstanceKeyMap) [](*HistoryTopologyNode) {
+	nodes := make(map[InstanceKey]*HistoryTopologyNode)
+	for _, instance := range instances {
+		node := &HistoryTopologyNode{Key: instance.Key, Version: instance.Version, Replicas: [](*HistoryTopologyNode){}}
+		if !unknownReadOnly.HasKey(instance.Key) {
+			readOnly := instance.ReadOnly
+			node.ReadOnly = &readOnly
+		}
+		nodes[instance.Key] = node
+	}
+	tree := [](*HistoryTopologyNode){}
+	attached := make(map[InstanceKey]bool)
+	var attach func(node *HistoryTopologyNode)
+	attach = func(node *HistoryTopologyNode) {
+		attached[node.Key] = true
+		for _, instance := range instances {
+			if instance.MasterKey.Equals(&node.Key) && !attached[instance.Key] {
+				replica := nodes[instance.Key]
+				node.Replicas = append(node.Replicas, replica)
+				attach(replica)
+			}
+		}
+	}
+	for _, instance := range instances {
+		if _, found := nodes[instance.MasterKey]; !found {
+			tree = append(tree, nodes[instance.Key])
+			attach(nodes[instance.Key])
+		}
+	}
+	for _, instance := range instances {
+		if !attached[instance.Key] {
+			tree = append(tree, nodes[instance.Key])
+			attach(nodes[instance.Key])
+		}
+	}
+	return tree
+}
+
+// TopologyHistoryDiffEntry describes a change to an instance between two points in time
+type TopologyHistoryDiffEntry struct {
+	Key           InstanceKey
+	Change        string
+	FromMasterKey InstanceKey
+	ToMasterKey   InstanceKey
+	FromReadOnly  bool
+	ToReadOnly    bool
+}
+
+// ParseTopologySnapshotTime parses a point in time given either as unix timestamp or as local time
+func ParseTopologySnapshotTime(timeString string) (int64, error) {
+	if unixTimestamp, err := strconv.ParseInt(timeString, 10, 64); err == nil {
+		return unixTimestamp, nil
+	}
+	for _, format := range topologySnapshotTimeFormats {
+		if t, err := time.ParseInLocation(format, timeString, time.Local); err == nil {
+			return t.Unix(), nil
+		}
+	}
+	return 0, fmt.Errorf("Cannot parse time: %s. Expected unix timestamp or a time such as 2006-01-02 15:04:05", timeString)
+}
+
+// formatSnapshotTime returns a human readable (local time) representation of a unix timestamp
+func formatSnapshotTime(unixTimestamp int64) string {
+	return time.Unix(unixTimestamp, 0).Format(topologySnapshotTimeFormats[0])
+}
+
+// ASCII returns a string representation of this topology
+func (this *HistoryTopology) ASCII() string {
+	return asciiTopologyFromInstances(this.Instances, false)
+}
+
+// diffHistoryInstances compares two sets of (thin) instances, and lists instances which moved masters,
+// appeared, disappeared or changed read_only. Each change is listed on its own entry. read_only is not
+// compared for instances in unknownReadOnly, whose read_only state was not recorded at either point in time.
+func diffHistoryInstances(fromInstances [](*Instance), toInstances [](*Instance), unknownReadOnly *InstanceKeyMap) (diff []TopologyHistoryDiffEntry) {
+	fromMap := make(map[InstanceKey]*Instance)
+	for _, instance := range fromInstances {
+		fromMap[instance.Key] = instance
+	}
+	toMap := make(map[InstanceKey]*Instance)
+	for _, instance := range toInstances {
+		toMap[instance.Key] = instance
+	}
+	for _, to := range toInstances {
+		from, found := fromMap[to.Key]
+		if !found {
+			diff = append(diff, TopologyHistoryDiffEntry{Key: to.Key, Change: TopologyHistoryAppeared, ToMasterKey: to.MasterKey, ToReadOnly: to.ReadOnly})
+			continue
+		}
+		entry := TopologyHistoryDiffEntry{Key: to.Key, FromMasterKey: from.MasterKey, ToMasterKey: to.MasterKey, FromReadOnly: from.ReadOnly, ToReadOnly: to.ReadOnly}
+		if !from.MasterKey.Equals(&to.MasterKey) {
+			entry.Change = TopologyHistoryMoved
+			diff = append(diff, entry)
+		}
+		if from.ReadOnly != to.ReadOnly && !unknownReadOnly.HasKey(to.Key) {
+			entry.Change = TopologyHistoryReadOnlyChanged
+			diff = append(diff, entry)
+		}
+	}
+	for _, from := range fromInstances {
+		if _, found := toMap[from.Key]; !found {
+			diff = append(diff, TopologyHistoryDiffEntry{Key: from.Key, Change: TopologyHistoryDisappeared, FromMasterKey: from.MasterKey, FromReadOnly: from.ReadOnly})
+		}
+	}
+	sort.Sort(topologyHistoryDiffByKey(diff))
+	return diff
+}
+
+type topologyHistoryDiffByKey []TopologyHistoryDiffEntry
+
+func (this topologyHistoryDiffByKey) Len() int      { return len(this) }
+func (this topologyHistoryDiffByKey) Swap(i, j int) { this[i], this[j] = this[j], this[i] }
+func (this topologyHistoryDiffByKey) Less(i, j int) bool {
+	if this[i].Key.Equals(&this[j].Key) {
+		return this[i].Change < this[j].Change
+	}
+	return this[i].Key.DisplayString() < this[j].Key.DisplayString()
+}
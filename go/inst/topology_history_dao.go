@@ -0,0 +1,122 @@
+/*
+   Copyright 2014 Outbrain Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package inst
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/outbrain/golib/log"
+	"github.com/outbrain/golib/sqlutils"
+	"github.com/outbrain/orchestrator/go/db"
+)
+
+// ReadTopologySnapshots lists the available topology snapshots of given cluster, most recent first
+func ReadTopologySnapshots(clusterName string) ([]TopologySnapshot, error) {
+	res := []TopologySnapshot{}
+	query := `
+		select
+			snapshot_unix_timestamp,
+			count(*) as count_instances
+		from
+			database_instance_topology_history
+		where
+			cluster_name = ?
+		group by
+			snapshot_unix_timestamp
+		order by
+			snapshot_unix_timestamp desc
+		`
+	err := db.QueryOrchestrator(query, sqlutils.Args(clusterName), func(m sqlutils.RowMap) error {
+		snapshot := TopologySnapshot{}
+		snapshot.SnapshotUnixTimestamp = m.GetInt64("snapshot_unix_timestamp")
+		snapshot.SnapshotTime = formatSnapshotTime(snapshot.SnapshotUnixTimestamp)
+		snapshot.CountInstances = m.GetUint("count_instances")
+
+		res = append(res, snapshot)
+		return nil
+	})
+	return res, log.Errore(err)
+}
+
+// ReadHistoryTopology reads the topology of given cluster as of given time, which is either a unix timestamp
+// or local time. The latest snapshot taken at or before that time is used.
+func ReadHistoryTopology(clusterName string, asOf string) (*HistoryTopology, error) {
+	asOfUnixTimestamp, err := ParseTopologySnapshotTime(asOf)
+	if err != nil {
+		return nil, err
+	}
+	var snapshotUnixTimestamp int64
+	query := `
+		select
+			ifnull(max(snapshot_unix_timestamp), 0) as snapshot_unix_timestamp
+		from
+			database_instance_topology_history
+		where
+			cluster_name = ?
+			and snapshot_unix_timestamp <= ?
+		`
+	err = db.QueryOrchestrator(query, sqlutils.Args(clusterName, asOfUnixTimestamp), func(m sqlutils.RowMap) error {
+		snapshotUnixTimestamp = m.GetInt64("snapshot_unix_timestamp")
+		return nil
+	})
+	if err != nil {
+		return nil, log.Errore(err)
+	}
+	if snapshotUnixTimestamp == 0 {
+		return nil, fmt.Errorf("No topology snapshot found for cluster %s as of %s", clusterName, formatSnapshotTime(asOfUnixTimestamp))
+	}
+	condition := `
+			snapshot_unix_timestamp = ?
+			and cluster_name = ?
+		`
+	instances, unknownReadOnly, err := readHistoryInstances(condition, sqlutils.Args(snapshotUnixTimestamp, clusterName))
+	if err != nil {
+		return nil, err
+	}
+	return newHistoryTopology(clusterName, snapshotUnixTimestamp, instances, unknownReadOnly), nil
+}
+
+// readCurrentTopology reads the current topology of given cluster, in same form as history topology
+func readCurrentTopology(clusterName string) (*HistoryTopology, error) {
+	instances, err := ReadClusterInstances(clusterName)
+	if err != nil {
+		return nil, err
+	}
+	return newHistoryTopology(clusterName, time.Now().Unix(), instances, NewInstanceKeyMap()), nil
+}
+
+// DiffHistoryTopology compares the topology of given cluster at two points in time. An empty `until`
+// stands for the current topology.
+func DiffHistoryTopology(clusterName string, since string, until string) (diff []TopologyHistoryDiffEntry, from *HistoryTopology, to *HistoryTopology, err error) {
+	if from, err = ReadHistoryTopology(clusterName, since); err != nil {
+		return diff, from, to, err
+	}
+	if until == "" {
+		to, err = readCurrentTopology(clusterName)
+	} else {
+		to, err = ReadHistoryTopology(clusterName, until)
+	}
+	if err != nil {
+		return diff, from, to, err
+	}
+	unknownReadOnly := NewInstanceKeyMap()
+	unknownReadOnly.AddKeys(from.unknownReadOnly.GetInstanceKeys())
+	unknownReadOnly.AddKeys(to.unknownReadOnly.GetInstanceKeys())
+	diff = diffHistoryInstances(from.Instances, to.Instances, unknownReadOnly)
+	return diff, from, to, nil
+}
@@ -0,0 +1,132 @@
+package inst
+
+import (
+	test "github.com/outbrain/golib/tests"
+	"testing"
+	"time"
+)
+
+func TestParseTopologySnapshotTime(t *testing.T) {
+	{
+		unixTimestamp, err := ParseTopologySnapshotTime("1450000000")
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(unixTimestamp, int64(1450000000))
+	}
+	{
+		unixTimestamp, err := ParseTopologySnapshotTime("2016-01-02 15:04:05")
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(unixTimestamp, time.Date(2016, 1, 2, 15, 4, 5, 0, time.Local).Unix())
+	}
+	{
+		unixTimestamp, err := ParseTopologySnapshotTime("2016-01-02T15:04")
+		test.S(t).ExpectNil(err)
+		test.S(t).ExpectEquals(unixTimestamp, time.Date(2016, 1, 2, 15, 4, 0, 0, time.Local).Unix())
+	}
+	{
+		_, err := ParseTopologySnapshotTime("yesterday")
+		test.S(t).ExpectNotNil(err)
+	}
+}
+
+func TestDiffHistoryInstances(t *testing.T) {
+	fromInstances, fromMap := generateTestInstances()
+	fromMap[i720Key.StringCode()].MasterKey = i710Key
+	fromMap[i730Key.StringCode()].MasterKey = i710Key
+	fromMap[i810Key.StringCode()].MasterKey = i720Key
+	fromMap[i710Key.StringCode()].ReadOnly = false
+	fromInstances = fromInstances[0:4]
+
+	toInstances, toMap := generateTestInstances()
+	toMap[i720Key.StringCode()].MasterKey = i710Key
+	toMap[i730Key.StringCode()].MasterKey = i710Key
+	toMap[i810Key.StringCode()].MasterKey = i730Key
+	toMap[i710Key.StringCode()].ReadOnly = true
+	// i730 gone; i820 appeared
+	toInstances = [](*Instance){toMap[i710Key.StringCode()], toMap[i720Key.StringCode()], toMap[i810Key.StringCode()], toMap[i820Key.StringCode()]}
+
+	diff := diffHistoryInstances(fromInstances, toInstances, NewInstanceKeyMap())
+	test.S(t).ExpectEquals(len(diff), 4)
+
+	test.S(t).ExpectEquals(diff[0].Key, i710Key)
+	test.S(t).ExpectEquals(diff[0].Change, TopologyHistoryReadOnlyChanged)
+	test.S(t).ExpectFalse(diff[0].FromReadOnly)
+	test.S(t).ExpectTrue(diff[0].ToReadOnly)
+
+	test.S(t).ExpectEquals(diff[1].Key, i730Key)
+	test.S(t).ExpectEquals(diff[1].Change, TopologyHistoryDisappeared)
+
+	test.S(t).ExpectEquals(diff[2].Key, i810Key)
+	test.S(t).ExpectEquals(diff[2].Change, TopologyHistoryMoved)
+	test.S(t).ExpectEquals(diff[2].FromMasterKey, i720Key)
+	test.S(t).ExpectEquals(diff[2].ToMasterKey, i730Key)
+
+	test.S(t).ExpectEquals(diff[3].Key, i820Key)
+	test.S(t).ExpectEquals(diff[3].Change, TopologyHistoryAppeared)
+}
+
+func TestDiffHistoryInstancesNoChange(t *testing.T) {
+	fromInstances, _ := generateTestInstances()
+	toInstances, _ := generateTestInstances()
+	diff := diffHistoryInstances(fromInstances, toInstances, NewInstanceKeyMap())
+	test.S(t).ExpectEquals(len(diff), 0)
+}
+
+func TestDiffHistoryInstancesUnknownReadOnly(t *testing.T) {
+	fromInstances, fromMap := generateTestInstances()
+	fromMap[i710Key.StringCode()].ReadOnly = false
+	toInstances, toMap := generateTestInstances()
+	toMap[i710Key.StringCode()].ReadOnly = true
+
+	unknownReadOnly := NewInstanceKeyMap()
+	unknownReadOnly.AddKey(i710Key)
+	diff := diffHistoryInstances(fromInstances, toInstances, unknownReadOnly)
+	test.S(t).ExpectEquals(len(diff), 0)
+}
+
+func TestHistoryTopologyTree(t *testing.T) {
+	instances, instancesMap := generateTestInstances()
+	instancesMap[i720Key.StringCode()].MasterKey = i710Key
+	instancesMap[i730Key.StringCode()].MasterKey = i710Key
+	instancesMap[i810Key.StringCode()].MasterKey = i720Key
+	instancesMap[i820Key.StringCode()].MasterKey = i810Key
+	instancesMap[i830Key.StringCode()].MasterKey = i710Key
+	instancesMap[i710Key.StringCode()].ReadOnly = false
+	instancesMap[i720Key.StringCode()].ReadOnly = true
+
+	unknownReadOnly := NewInstanceKeyMap()
+	unknownReadOnly.AddKey(i730Key)
+	tree := historyTopologyTree(instances, unknownReadOnly)
+	test.S(t).ExpectEquals(len(tree), 1)
+
+	master := tree[0]
+	test.S(t).ExpectEquals(master.Key, i710Key)
+	test.S(t).ExpectFalse(*master.ReadOnly)
+	test.S(t).ExpectEquals(len(master.Replicas), 3)
+	test.S(t).ExpectEquals(master.Replicas[0].Key, i720Key)
+	test.S(t).ExpectTrue(*master.Replicas[0].ReadOnly)
+	test.S(t).ExpectEquals(master.Replicas[1].Key, i730Key)
+	test.S(t).ExpectTrue(master.Replicas[1].ReadOnly == nil)
+	test.S(t).ExpectEquals(master.Replicas[2].Key, i830Key)
+
+	intermediateMaster := master.Replicas[0]
+	test.S(t).ExpectEquals(len(intermediateMaster.Replicas), 1)
+	test.S(t).ExpectEquals(intermediateMaster.Replicas[0].Key, i810Key)
+	test.S(t).ExpectEquals(len(intermediateMaster.Replicas[0].Replicas), 1)
+	test.S(t).ExpectEquals(intermediateMaster.Replicas[0].Replicas[0].Key, i820Key)
+}
+
+func TestHistoryTopologyTreeCoMasters(t *testing.T) {
+	instances, instancesMap := generateTestInstances()
+	instances = instances[0:3]
+	instancesMap[i710Key.StringCode()].MasterKey = i720Key
+	instancesMap[i720Key.StringCode()].MasterKey = i710Key
+	instancesMap[i730Key.StringCode()].MasterKey = i710Key
+
+	tree := historyTopologyTree(instances, NewInstanceKeyMap())
+	test.S(t).ExpectEquals(len(tree), 1)
+	test.S(t).ExpectEquals(tree[0].Key, i710Key)
+	test.S(t).ExpectEquals(len(tree[0].Replicas), 2)
+	test.S(t).ExpectEquals(tree[0].Replicas[0].Key, i720Key)
+	test.S(t).ExpectEquals(len(tree[0].Replicas[0].Replicas), 0)
+	test.S(t).ExpectEquals(tree[0].Replicas[1].Key, i730Key)
+}
@@ -0,0 +1,128 @@
+/*
+   Copyright 2014 Outbrain Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package logic
+
+// This file holds agent assisted verification of master failures. From orchestrator's point of view
+// a crashed mysqld, a dead host and a network problem all look alike. The orchestrator-agent running
+// on the master's host, when there is one, is able to tell these apart.
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/outbrain/golib/log"
+	"github.com/patrickmn/go-cache"
+
+	"github.com/outbrain/orchestrator/go/agent"
+	"github.com/outbrain/orchestrator/go/config"
+	"github.com/outbrain/orchestrator/go/inst"
+)
+
+type AgentVerificationAction string
+
+const (
+	AgentVerificationProceed      AgentVerificationAction = "proceed"
+	AgentVerificationBlock        AgentVerificationAction = "block"
+	AgentVerificationRestartMySQL AgentVerificationAction = "restart-mysql"
+)
+
+// agentFailureVerification is the verdict of a recent verification: the agent's report, and whether recovery was to proceed
+type agentFailureVerification struct {
+	check   *inst.AgentHostCheck
+	proceed bool
+}
+
+// recentAgentMySQLRestarts keeps hosts on which mysqld was recently restarted, so as not to restart it over and over
+var recentAgentMySQLRestarts = cache.New(time.Duration(config.Config.RecoveryPeriodBlockSeconds)*time.Second, time.Second)
+
+// recentAgentFailureVerifications keeps verdicts per host, such that a failure which keeps being analyzed
+// (e.g. a blocked DeadMaster) does not query the agent and get audited on each recovery poll
+var recentAgentFailureVerifications = cache.New(time.Duration(config.Config.AgentFailureVerificationCacheSeconds)*time.Second, time.Second)
+
+// getAgentVerificationAction decides, based on the agent's report, whether recovery should proceed
+func getAgentVerificationAction(check *inst.AgentHostCheck, recentlyRestarted bool) AgentVerificationAction {
+	if check == nil || !check.HasAgent || !check.HostReachable {
+		// No agent, or the host itself is dead/unreachable: this is a true failure as far as we can tell
+		return AgentVerificationProceed
+	}
+	if check.Unauthorized {
+		// The agent cannot tell about mysqld, nor act on it
+		return AgentVerificationProceed
+	}
+	if check.MySQLRunning {
+		if config.Config.AgentMySQLRunningBlocksFailover {
+			return AgentVerificationBlock
+		}
+		return AgentVerificationProceed
+	}
+	if config.Config.AgentRestartDeadMySQL && !recentlyRestarted {
+		return AgentVerificationRestartMySQL
+	}
+	return AgentVerificationProceed
+}
+
+// VerifyFailureWithAgent asks the agent on the failed instance's host about the failure, attaches its report
+// to the analysis entry, and possibly restarts mysqld via the agent. It returns true when recovery should proceed.
+// A host's verdict is reused for AgentFailureVerificationCacheSeconds.
+func VerifyFailureWithAgent(analysisEntry *inst.ReplicationAnalysis) (proceed bool) {
+	if !config.Config.AgentFailureVerification || !config.Config.ServeAgentsHttp {
+		return true
+	}
+	instanceKey := &analysisEntry.AnalyzedInstanceKey
+	if verification, found := recentAgentFailureVerifications.Get(instanceKey.Hostname); found {
+		verification := verification.(*agentFailureVerification)
+		analysisEntry.AgentHostCheck = verification.check
+		return verification.proceed
+	}
+	check := agent.CheckHost(instanceKey.Hostname)
+	analysisEntry.AgentHostCheck = check
+	proceed = actOnAgentHostCheck(analysisEntry, check)
+	recentAgentFailureVerifications.Set(instanceKey.Hostname, &agentFailureVerification{check: check, proceed: proceed}, time.Duration(config.Config.AgentFailureVerificationCacheSeconds)*time.Second)
+	return proceed
+}
+
+// actOnAgentHostCheck audits the agent's report and acts upon it
+func actOnAgentHostCheck(analysisEntry *inst.ReplicationAnalysis, check *inst.AgentHostCheck) (proceed bool) {
+	instanceKey := &analysisEntry.AnalyzedInstanceKey
+	_, recentlyRestarted := recentAgentMySQLRestarts.Get(instanceKey.Hostname)
+	action := getAgentVerificationAction(check, recentlyRestarted)
+	summary := fmt.Sprintf("%+v: agent: %t, host reachable: %t, mysqld running: %t; action: %s", analysisEntry.Analysis, check.HasAgent, check.HostReachable, check.MySQLRunning, action)
+	if check.Unauthorized {
+		summary = fmt.Sprintf("%s; agent rejected its token", summary)
+	}
+	if len(check.ErrorLogTail) > 0 {
+		summary = fmt.Sprintf("%s; error log: %s", summary, strings.Join(check.ErrorLogTail, " | "))
+	}
+	log.Infof("VerifyFailureWithAgent: %+v: %s", *instanceKey, summary)
+	inst.AuditOperation("agent-failure-verification", instanceKey, summary)
+
+	switch action {
+	case AgentVerificationBlock:
+		return false
+	case AgentVerificationRestartMySQL:
+		recentAgentMySQLRestarts.Set(instanceKey.Hostname, true, time.Duration(config.Config.RecoveryPeriodBlockSeconds)*time.Second)
+		if _, err := agent.MySQLStart(instanceKey.Hostname); err != nil {
+			log.Errore(err)
+			inst.AuditOperation("agent-restart-mysql", instanceKey, fmt.Sprintf("Failed restarting mysqld: %+v; proceeding with recovery", err))
+			return true
+		}
+		inst.AuditOperation("agent-restart-mysql", instanceKey, "Restarted mysqld via agent instead of recovering")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,81 @@
+/*
+   Copyright 2014 Outbrain Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package logic
+
+import (
+	"testing"
+	"time"
+
+	test "github.com/outbrain/golib/tests"
+	"github.com/outbrain/orchestrator/go/config"
+	"github.com/outbrain/orchestrator/go/inst"
+)
+
+func TestGetAgentVerificationAction(t *testing.T) {
+	defer func(blocks bool, restart bool) {
+		config.Config.AgentMySQLRunningBlocksFailover = blocks
+		config.Config.AgentRestartDeadMySQL = restart
+	}(config.Config.AgentMySQLRunningBlocksFailover, config.Config.AgentRestartDeadMySQL)
+
+	noAgent := &inst.AgentHostCheck{}
+	hostDown := &inst.AgentHostCheck{HasAgent: true}
+	mysqlRunning := &inst.AgentHostCheck{HasAgent: true, HostReachable: true, MySQLRunning: true}
+	mysqlDead := &inst.AgentHostCheck{HasAgent: true, HostReachable: true}
+	unauthorized := &inst.AgentHostCheck{HasAgent: true, HostReachable: true, Unauthorized: true}
+
+	expectAction := func(check *inst.AgentHostCheck, recentlyRestarted bool, expected AgentVerificationAction) {
+		test.S(t).ExpectEquals(string(getAgentVerificationAction(check, recentlyRestarted)), string(expected))
+	}
+
+	config.Config.AgentMySQLRunningBlocksFailover = true
+	config.Config.AgentRestartDeadMySQL = true
+	expectAction(nil, false, AgentVerificationProceed)
+	expectAction(noAgent, false, AgentVerificationProceed)
+	expectAction(hostDown, false, AgentVerificationProceed)
+	expectAction(mysqlRunning, false, AgentVerificationBlock)
+	expectAction(mysqlRunning, true, AgentVerificationBlock)
+	expectAction(mysqlDead, false, AgentVerificationRestartMySQL)
+	expectAction(mysqlDead, true, AgentVerificationProceed)
+	expectAction(unauthorized, false, AgentVerificationProceed)
+
+	config.Config.AgentMySQLRunningBlocksFailover = false
+	config.Config.AgentRestartDeadMySQL = false
+	expectAction(hostDown, false, AgentVerificationProceed)
+	expectAction(mysqlRunning, false, AgentVerificationProceed)
+	expectAction(mysqlDead, false, AgentVerificationProceed)
+}
+
+func TestVerifyFailureWithAgentCached(t *testing.T) {
+	defer func(verification bool, serveAgents bool) {
+		config.Config.AgentFailureVerification = verification
+		config.Config.ServeAgentsHttp = serveAgents
+	}(config.Config.AgentFailureVerification, config.Config.ServeAgentsHttp)
+	config.Config.AgentFailureVerification = true
+	config.Config.ServeAgentsHttp = true
+
+	check := &inst.AgentHostCheck{HasAgent: true, HostReachable: true, MySQLRunning: true}
+	recentAgentFailureVerifications.Set("cached-master", &agentFailureVerification{check: check, proceed: false}, time.Minute)
+	defer recentAgentFailureVerifications.Delete("cached-master")
+
+	// A cached verdict neither queries the agent nor audits
+	analysisEntry := &inst.ReplicationAnalysis{AnalyzedInstanceKey: inst.InstanceKey{Hostname: "cached-master", Port: 3306}}
+	test.S(t).ExpectFalse(VerifyFailureWithAgent(analysisEntry))
+	test.S(t).ExpectTrue(analysisEntry.AgentHostCheck == check)
+
+	recentAgentFailureVerifications.Set("cached-master", &agentFailureVerification{check: check, proceed: true}, time.Minute)
+	test.S(t).ExpectTrue(VerifyFailureWithAgent(analysisEntry))
+}
@@ -20,6 +20,14 @@ import (
 	"github.com/outbrain/orchestrator/go/inst"
 )
 
+const (
+	AsyncRequestQueued    = "queued"
+	AsyncRequestRunning   = "running"
+	AsyncRequestCompleted = "completed"
+	AsyncRequestFailed    = "failed"
+	AsyncRequestCanceled  = "canceled"
+)
+
 // AsyncRequest represents an entry in the async_request table
 type AsyncRequest struct {
 	Id                  int64
@@ -29,11 +37,18 @@ type AsyncRequest struct {
 	DestinationKey      *inst.InstanceKey
 	Pattern             string
 	GTIDHint            inst.OperationGTIDHint
+	Status              string
+	Progress            string
+	Result              string
+	ErrorMessage        string
+	BeginTimestamp      string
+	EndTimestamp        string
 }
 
 func NewEmptyAsyncRequest() *AsyncRequest {
 	asyncRequest := &AsyncRequest{}
 	asyncRequest.GTIDHint = inst.GTIDHintNeutral
+	asyncRequest.Status = AsyncRequestQueued
 	return asyncRequest
 }
 
@@ -23,9 +23,10 @@ import (
 	"github.com/outbrain/orchestrator/go/config"
 	"github.com/outbrain/orchestrator/go/db"
 	"github.com/outbrain/orchestrator/go/inst"
+	"github.com/outbrain/orchestrator/go/process"
 )
 
-// WriteAsyncRequest
+// WriteAsyncRequest queues a request, and updates its Id
 func WriteAsyncRequest(asyncRequest *AsyncRequest) error {
 	if asyncRequest.OperatedInstanceKey == nil {
 		return log.Errorf("WriteAsyncRequest received asyncRequest.OperatedInstanceKey for command %+v", asyncRequest.Command)
@@ -35,23 +36,27 @@ func WriteAsyncRequest(asyncRequest *AsyncRequest) error {
 		destinationKey = &inst.InstanceKey{}
 	}
 	writeFunc := func() error {
-		_, err := db.ExecOrchestrator(`
+		sqlResult, err := db.ExecOrchestrator(`
 			insert into async_request (
-					command, hostname, port, destination_hostname, destination_port, pattern, gtid_hint, story, begin_timestamp, end_timestamp
+					command, hostname, port, destination_hostname, destination_port, pattern, gtid_hint, status, progress, result, error_message, story, begin_timestamp, end_timestamp
 				) values (
-					?, ?, ?, ?, ?, ?, ?, ?, NULL, NULL
+					?, ?, ?, ?, ?, ?, ?, ?, '', '', '', ?, NULL, NULL
 				)
 				`, asyncRequest.Command, asyncRequest.OperatedInstanceKey.Hostname, asyncRequest.OperatedInstanceKey.Port,
-			destinationKey.Hostname, destinationKey.Port, asyncRequest.Pattern, string(asyncRequest.GTIDHint), asyncRequest.Story,
+			destinationKey.Hostname, destinationKey.Port, asyncRequest.Pattern, string(asyncRequest.GTIDHint), AsyncRequestQueued, asyncRequest.Story,
 		)
+		if err != nil {
+			return log.Errore(err)
+		}
+		asyncRequest.Id, err = sqlResult.LastInsertId()
 		return log.Errore(err)
 	}
 	return inst.ExecDBWriteFunc(writeFunc)
 }
 
-func ReadPendingAsyncRequests(limit int) (res [](*AsyncRequest), err error) {
+// readAsyncRequests reads async requests matching given where clause
+func readAsyncRequests(whereCondition string, limit int, args []interface{}) (res [](*AsyncRequest), err error) {
 	limitClause := ``
-	args := sqlutils.Args()
 	if limit > 0 {
 		limitClause = `limit ?`
 		args = append(args, limit)
@@ -66,15 +71,20 @@ func ReadPendingAsyncRequests(limit int) (res [](*AsyncRequest), err error) {
 			destination_port,
 			pattern,
 			gtid_hint,
+			status,
+			progress,
+			result,
+			error_message,
+			ifnull(begin_timestamp, '') as begin_timestamp,
+			ifnull(end_timestamp, '') as end_timestamp,
 			story
 		from
 			async_request
-		where
-			begin_timestamp IS NULL
+		%s
 		order by
 			request_id asc
 		%s
-		`, limitClause)
+		`, whereCondition, limitClause)
 	err = db.QueryOrchestrator(query, args, func(m sqlutils.RowMap) error {
 		asyncRequest := NewEmptyAsyncRequest()
 		asyncRequest.Id = m.GetInt64("request_id")
@@ -92,6 +102,12 @@ func ReadPendingAsyncRequests(limit int) (res [](*AsyncRequest), err error) {
 
 		asyncRequest.Pattern = m.GetString("pattern")
 		asyncRequest.GTIDHint = inst.OperationGTIDHint(m.GetString("gtid_hint"))
+		asyncRequest.Status = m.GetString("status")
+		asyncRequest.Progress = m.GetString("progress")
+		asyncRequest.Result = m.GetString("result")
+		asyncRequest.ErrorMessage = m.GetString("error_message")
+		asyncRequest.BeginTimestamp = m.GetString("begin_timestamp")
+		asyncRequest.EndTimestamp = m.GetString("end_timestamp")
 		asyncRequest.Story = m.GetString("story")
 
 		res = append(res, asyncRequest)
@@ -104,36 +120,141 @@ func ReadPendingAsyncRequests(limit int) (res [](*AsyncRequest), err error) {
 	return res, err
 }
 
+// ReadPendingAsyncRequests reads queued requests, oldest first
+func ReadPendingAsyncRequests(limit int) (res [](*AsyncRequest), err error) {
+	whereCondition := `
+		where
+			begin_timestamp IS NULL
+			and status = ?
+		`
+	return readAsyncRequests(whereCondition, limit, sqlutils.Args(AsyncRequestQueued))
+}
+
+// ReadAsyncRequest reads a single request by id
+func ReadAsyncRequest(requestId int64) (*AsyncRequest, error) {
+	whereCondition := `
+		where
+			request_id = ?
+		`
+	res, err := readAsyncRequests(whereCondition, 0, sqlutils.Args(requestId))
+	if err != nil {
+		return nil, err
+	}
+	if len(res) == 0 {
+		return nil, fmt.Errorf("Async request %d not found", requestId)
+	}
+	return res[0], nil
+}
+
+// BeginAsyncRequest marks a queued request as running, claimed by this orchestrator process.
+// It returns false when the request was already begun or canceled.
 func BeginAsyncRequest(asyncRequest *AsyncRequest) (bool, error) {
 	sqlResult, err := db.ExecOrchestrator(`
 			update
 				async_request
 			set
-				begin_timestamp = NOW()
+				begin_timestamp = NOW(),
+				status = ?,
+				worker_hostname = ?,
+				worker_token = ?
 			where
 				request_id = ?
 				and begin_timestamp IS NULL
-			`, asyncRequest.Id,
+				and status = ?
+			`, AsyncRequestRunning, process.ThisHostname, process.ProcessToken.Hash, asyncRequest.Id, AsyncRequestQueued,
 	)
 	if err != nil {
 		return false, log.Errore(err)
 	}
 	rows, err := sqlResult.RowsAffected()
+	if rows > 0 {
+		asyncRequest.Status = AsyncRequestRunning
+	}
 	return (rows > 0), err
+}
 
+// UpdateAsyncRequestProgress sets the progress message of a running request
+func UpdateAsyncRequestProgress(asyncRequest *AsyncRequest, progress string) error {
+	asyncRequest.Progress = progress
+	_, err := db.ExecOrchestrator(`
+			update
+				async_request
+			set
+				progress = ?
+			where
+				request_id = ?
+			`, progress, asyncRequest.Id,
+	)
+	return log.Errore(err)
 }
 
-// ExpireAsyncRequests will mark "lost" entries as being completed
+// EndAsyncRequest marks a running request as completed or failed, along with its result or error
+func EndAsyncRequest(asyncRequest *AsyncRequest, result string, requestErr error) error {
+	asyncRequest.Status = AsyncRequestCompleted
+	asyncRequest.Result = result
+	if requestErr != nil {
+		asyncRequest.Status = AsyncRequestFailed
+		asyncRequest.ErrorMessage = requestErr.Error()
+	}
+	_, err := db.ExecOrchestrator(`
+			update
+				async_request
+			set
+				end_timestamp = NOW(),
+				status = ?,
+				result = ?,
+				error_message = ?
+			where
+				request_id = ?
+			`, asyncRequest.Status, asyncRequest.Result, asyncRequest.ErrorMessage, asyncRequest.Id,
+	)
+	return log.Errore(err)
+}
+
+// CancelAsyncRequest cancels a queued request. Requests which have already begun cannot be canceled.
+func CancelAsyncRequest(requestId int64) (bool, error) {
+	sqlResult, err := db.ExecOrchestrator(`
+			update
+				async_request
+			set
+				begin_timestamp = NOW(),
+				end_timestamp = NOW(),
+				status = ?
+			where
+				request_id = ?
+				and begin_timestamp IS NULL
+				and status = ?
+			`, AsyncRequestCanceled, requestId, AsyncRequestQueued,
+	)
+	if err != nil {
+		return false, log.Errore(err)
+	}
+	rows, err := sqlResult.RowsAffected()
+	return (rows > 0), err
+}
+
+// ExpireAsyncRequests will mark "lost" entries as being failed. A running request is lost when the
+// orchestrator process which claimed it is no longer seen active; requests executed by a live worker
+// are never expired, however long they take.
 func ExpireAsyncRequests() error {
 	_, err := db.ExecOrchestrator(`
 			update
 				async_request
 			set
-				end_timestamp = NOW()
+				end_timestamp = NOW(),
+				status = ?,
+				error_message = 'Expired: lost while running'
 			where
 				end_timestamp IS NULL
-				and begin_timestamp < NOW() - INTERVAL ? MINUTE
-			`, config.Config.MaintenanceExpireMinutes,
+				and begin_timestamp < NOW() - INTERVAL ? SECOND
+				and not exists (
+					select 1 from node_health
+					where
+						node_health.hostname = async_request.worker_hostname
+						and node_health.token = async_request.worker_token
+						and node_health.last_seen_active >= NOW() - INTERVAL ? SECOND
+				)
+			`, AsyncRequestFailed, config.Config.ActiveNodeExpireSeconds, config.Config.ActiveNodeExpireSeconds,
 	)
 	if err != nil {
 		log.Errore(err)
@@ -0,0 +1,90 @@
+/*
+   Copyright 2015 Shlomi Noach, courtesy Booking.com
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package logic
+
+import (
+	"errors"
+	"testing"
+
+	test "github.com/outbrain/golib/tests"
+	"github.com/outbrain/orchestrator/go/inst"
+)
+
+func TestNewAsyncRequest(t *testing.T) {
+	instanceKey := &inst.InstanceKey{Hostname: "host1", Port: 3306}
+	asyncRequest := NewSimpleAsyncRequest("story", "move-up-slaves", instanceKey)
+	test.S(t).ExpectEquals(asyncRequest.Status, AsyncRequestQueued)
+	test.S(t).ExpectEquals(string(asyncRequest.GTIDHint), string(inst.GTIDHintNeutral))
+	test.S(t).ExpectTrue(asyncRequest.DestinationKey == nil)
+	test.S(t).ExpectEquals(asyncRequest.OperatedInstanceKey.Hostname, "host1")
+}
+
+func TestValidateAsyncRequest(t *testing.T) {
+	instanceKey := &inst.InstanceKey{Hostname: "host1", Port: 3306}
+	destinationKey := &inst.InstanceKey{Hostname: "host2", Port: 3306}
+
+	test.S(t).ExpectNil(validateAsyncRequest(NewSimpleAsyncRequest("", "move-up-slaves", instanceKey)))
+	test.S(t).ExpectNil(validateAsyncRequest(NewSimpleAsyncRequest("", "regroup-slaves", instanceKey)))
+	test.S(t).ExpectNil(validateAsyncRequest(NewAsyncRequest("", "relocate", instanceKey, destinationKey, "", inst.GTIDHintNeutral)))
+	test.S(t).ExpectNil(validateAsyncRequest(NewAsyncRequest("", "relocate-slaves", instanceKey, destinationKey, "mysql3", inst.GTIDHintNeutral)))
+
+	test.S(t).ExpectNotNil(validateAsyncRequest(NewSimpleAsyncRequest("", "begin-maintenance", instanceKey)))
+	test.S(t).ExpectNotNil(validateAsyncRequest(NewSimpleAsyncRequest("", "relocate", instanceKey)))
+	test.S(t).ExpectNotNil(validateAsyncRequest(NewSimpleAsyncRequest("", "multi-match-slaves", instanceKey)))
+	test.S(t).ExpectNotNil(validateAsyncRequest(NewSimpleAsyncRequest("", "move-up-slaves", nil)))
+}
+
+func TestNewAsyncRequestResult(t *testing.T) {
+	instance := inst.NewInstance()
+	instance.Key = inst.InstanceKey{Hostname: "master", Port: 3306}
+	slave := inst.NewInstance()
+	slave.Key = inst.InstanceKey{Hostname: "slave", Port: 3306}
+
+	result := newAsyncRequestResult(instance, [](*inst.Instance){slave}, []error{errors.New("failed")})
+	test.S(t).ExpectEquals(*result.Instance, instance.Key)
+	test.S(t).ExpectEquals(len(result.Slaves), 1)
+	test.S(t).ExpectEquals(result.Slaves[0], slave.Key)
+	test.S(t).ExpectEquals(len(result.Errors), 1)
+	test.S(t).ExpectEquals(result.Errors[0], "failed")
+
+	result = newAsyncRequestResult(nil, nil, nil)
+	test.S(t).ExpectTrue(result.Instance == nil)
+	test.S(t).ExpectEquals(len(result.Slaves), 0)
+	test.S(t).ExpectEquals(len(result.Errors), 0)
+}
+
+func TestAsyncRequestWorkerPool(t *testing.T) {
+	pool := newAsyncRequestWorkerPool(2)
+	test.S(t).ExpectEquals(pool.free(), 2)
+	test.S(t).ExpectTrue(pool.tryAcquire())
+	test.S(t).ExpectEquals(pool.free(), 1)
+	test.S(t).ExpectTrue(pool.tryAcquire())
+	test.S(t).ExpectEquals(pool.free(), 0)
+	test.S(t).ExpectFalse(pool.tryAcquire())
+	pool.release()
+	test.S(t).ExpectEquals(pool.free(), 1)
+	test.S(t).ExpectTrue(pool.tryAcquire())
+	pool.release()
+	pool.release()
+	test.S(t).ExpectEquals(pool.free(), 2)
+}
+
+func TestAsyncRequestWorkerPoolDisabled(t *testing.T) {
+	pool := newAsyncRequestWorkerPool(0)
+	test.S(t).ExpectEquals(pool.free(), 0)
+	test.S(t).ExpectFalse(pool.tryAcquire())
+}
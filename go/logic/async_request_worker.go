@@ -0,0 +1,227 @@
+/*
+   Copyright 2014 Outbrain Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package logic
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/outbrain/golib/log"
+	"github.com/outbrain/orchestrator/go/config"
+	"github.com/outbrain/orchestrator/go/inst"
+	"github.com/rcrowley/go-metrics"
+)
+
+// AsyncRequestCommands lists the commands which may be queued as async requests
+var AsyncRequestCommands = map[string]bool{
+	"relocate":            true,
+	"relocate-slaves":     true,
+	"move-up-slaves":      true,
+	"move-slaves-gtid":    true,
+	"multi-match-slaves":  true,
+	"match-up-slaves":     true,
+	"regroup-slaves":      true,
+	"regroup-slaves-gtid": true,
+}
+
+// asyncRequestResult is the (JSON serialized) outcome of an async request
+type asyncRequestResult struct {
+	Instance *inst.InstanceKey
+	Slaves   []inst.InstanceKey
+	Errors   []string
+}
+
+// asyncRequestWorkerPool bounds the number of concurrently executing async requests
+type asyncRequestWorkerPool struct {
+	slots chan bool
+}
+
+func newAsyncRequestWorkerPool(size uint) *asyncRequestWorkerPool {
+	return &asyncRequestWorkerPool{slots: make(chan bool, size)}
+}
+
+// free returns the number of idle workers
+func (this *asyncRequestWorkerPool) free() int {
+	return cap(this.slots) - len(this.slots)
+}
+
+// tryAcquire grabs an idle worker, returning false when all workers are busy
+func (this *asyncRequestWorkerPool) tryAcquire() bool {
+	select {
+	case this.slots <- true:
+		return true
+	default:
+		return false
+	}
+}
+
+// release returns a worker to the pool
+func (this *asyncRequestWorkerPool) release() {
+	<-this.slots
+}
+
+var asyncRequestWorkers *asyncRequestWorkerPool
+var asyncRequestWorkersOnce sync.Once
+
+var asyncRequestsExecutedCounter = metrics.NewCounter()
+var asyncRequestsFailedCounter = metrics.NewCounter()
+
+func init() {
+	metrics.Register("async_request.executed", asyncRequestsExecutedCounter)
+	metrics.Register("async_request.failed", asyncRequestsFailedCounter)
+}
+
+func newAsyncRequestResult(instance *inst.Instance, slaves [](*inst.Instance), errs []error) *asyncRequestResult {
+	result := &asyncRequestResult{Slaves: []inst.InstanceKey{}, Errors: []string{}}
+	if instance != nil {
+		result.Instance = &instance.Key
+	}
+	for _, slave := range slaves {
+		result.Slaves = append(result.Slaves, slave.Key)
+	}
+	for _, err := range errs {
+		result.Errors = append(result.Errors, err.Error())
+	}
+	return result
+}
+
+// validateAsyncRequest checks that given request can be queued and executed
+func validateAsyncRequest(asyncRequest *AsyncRequest) error {
+	if !AsyncRequestCommands[asyncRequest.Command] {
+		return fmt.Errorf("Command %s cannot be queued as async request", asyncRequest.Command)
+	}
+	if asyncRequest.OperatedInstanceKey == nil {
+		return fmt.Errorf("%s requires an instance", asyncRequest.Command)
+	}
+	requiresDestination := asyncRequest.Command == "relocate" || asyncRequest.Command == "relocate-slaves" ||
+		asyncRequest.Command == "move-slaves-gtid" || asyncRequest.Command == "multi-match-slaves"
+	if requiresDestination && asyncRequest.DestinationKey == nil {
+		return fmt.Errorf("%s requires a destination", asyncRequest.Command)
+	}
+	return nil
+}
+
+// QueueAsyncRequest validates and queues a request, to be executed by the elected node
+func QueueAsyncRequest(asyncRequest *AsyncRequest) error {
+	if err := validateAsyncRequest(asyncRequest); err != nil {
+		return err
+	}
+	return WriteAsyncRequest(asyncRequest)
+}
+
+// executeAsyncRequest runs the command of given request
+func executeAsyncRequest(asyncRequest *AsyncRequest) (result *asyncRequestResult, err error) {
+	if err := validateAsyncRequest(asyncRequest); err != nil {
+		return nil, err
+	}
+	instanceKey := asyncRequest.OperatedInstanceKey
+	destinationKey := asyncRequest.DestinationKey
+	onCandidateSlaveChosen := func(candidateSlave *inst.Instance) {
+		UpdateAsyncRequestProgress(asyncRequest, fmt.Sprintf("Candidate slave chosen: %+v", candidateSlave.Key))
+	}
+	switch asyncRequest.Command {
+	case "relocate":
+		instance, err := inst.RelocateBelow(instanceKey, destinationKey)
+		return newAsyncRequestResult(instance, nil, nil), err
+	case "relocate-slaves":
+		slaves, other, err, errs := inst.RelocateSlaves(instanceKey, destinationKey, asyncRequest.Pattern)
+		return newAsyncRequestResult(other, slaves, errs), err
+	case "move-up-slaves":
+		slaves, newMaster, err, errs := inst.MoveUpSlaves(instanceKey, asyncRequest.Pattern)
+		return newAsyncRequestResult(newMaster, slaves, errs), err
+	case "move-slaves-gtid":
+		movedSlaves, _, err, errs := inst.MoveSlavesGTID(instanceKey, destinationKey, asyncRequest.Pattern)
+		return newAsyncRequestResult(nil, movedSlaves, errs), err
+	case "multi-match-slaves":
+		slaves, newMaster, err, errs := inst.MultiMatchSlaves(instanceKey, destinationKey, asyncRequest.Pattern)
+		return newAsyncRequestResult(newMaster, slaves, errs), err
+	case "match-up-slaves":
+		slaves, newMaster, err, errs := inst.MatchUpSlaves(instanceKey, asyncRequest.Pattern)
+		return newAsyncRequestResult(newMaster, slaves, errs), err
+	case "regroup-slaves":
+		lostSlaves, equalSlaves, aheadSlaves, cannotReplicateSlaves, promotedSlave, err := inst.RegroupSlaves(instanceKey, false, onCandidateSlaveChosen, nil)
+		result := newAsyncRequestResult(promotedSlave, append(equalSlaves, aheadSlaves...), nil)
+		for _, lostSlave := range append(lostSlaves, cannotReplicateSlaves...) {
+			result.Errors = append(result.Errors, fmt.Sprintf("Lost slave: %+v", lostSlave.Key))
+		}
+		return result, err
+	case "regroup-slaves-gtid":
+		lostSlaves, movedSlaves, cannotReplicateSlaves, promotedSlave, err := inst.RegroupSlavesGTID(instanceKey, false, onCandidateSlaveChosen)
+		result := newAsyncRequestResult(promotedSlave, movedSlaves, nil)
+		for _, lostSlave := range append(lostSlaves, cannotReplicateSlaves...) {
+			result.Errors = append(result.Errors, fmt.Sprintf("Lost slave: %+v", lostSlave.Key))
+		}
+		return result, err
+	}
+	return nil, fmt.Errorf("Unsupported async request command: %s", asyncRequest.Command)
+}
+
+// runAsyncRequest executes given (already begun) request and records its outcome
+func runAsyncRequest(asyncRequest *AsyncRequest) {
+	log.Infof("Executing async request %d: %s on %+v", asyncRequest.Id, asyncRequest.Command, *asyncRequest.OperatedInstanceKey)
+	UpdateAsyncRequestProgress(asyncRequest, fmt.Sprintf("Executing %s", asyncRequest.Command))
+
+	result, err := executeAsyncRequest(asyncRequest)
+	resultJSON := ""
+	if result != nil {
+		if b, jsonErr := json.Marshal(result); jsonErr == nil {
+			resultJSON = string(b)
+		} else {
+			log.Errore(jsonErr)
+		}
+	}
+	asyncRequestsExecutedCounter.Inc(1)
+	if err != nil {
+		asyncRequestsFailedCounter.Inc(1)
+		log.Errorf("Async request %d failed: %+v", asyncRequest.Id, err)
+	}
+	EndAsyncRequest(asyncRequest, resultJSON, err)
+}
+
+// ProcessPendingAsyncRequests hands queued requests over to the worker pool, as long as there
+// are free workers. It is expected to run on the elected node only.
+func ProcessPendingAsyncRequests() error {
+	asyncRequestWorkersOnce.Do(func() {
+		asyncRequestWorkers = newAsyncRequestWorkerPool(config.Config.AsyncRequestWorkers)
+	})
+	freeWorkers := asyncRequestWorkers.free()
+	if freeWorkers <= 0 {
+		return nil
+	}
+	asyncRequests, err := ReadPendingAsyncRequests(freeWorkers)
+	if err != nil {
+		return err
+	}
+	for _, asyncRequest := range asyncRequests {
+		if !asyncRequestWorkers.tryAcquire() {
+			// All workers busy; remaining requests are picked up on a later round
+			return nil
+		}
+		begun, err := BeginAsyncRequest(asyncRequest)
+		if err != nil || !begun {
+			// Canceled, or picked up by another round
+			asyncRequestWorkers.release()
+			continue
+		}
+		go func(asyncRequest *AsyncRequest) {
+			defer asyncRequestWorkers.release()
+			runAsyncRequest(asyncRequest)
+		}(asyncRequest)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+/*
+   Copyright 2014 Outbrain Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package logic
+
+import (
+	"github.com/outbrain/golib/log"
+
+	"github.com/outbrain/orchestrator/go/agent"
+	"github.com/outbrain/orchestrator/go/inst"
+)
+
+// applyAgentsDatadirDiskFree projects time until binary logs fill the datadir, for those instances
+// whose hosts run an agent
+func applyAgentsDatadirDiskFree(growths []inst.BinlogGrowth) error {
+	agents, err := agent.ReadAgents()
+	if err != nil {
+		return err
+	}
+	agentHostnames := make(map[string]bool)
+	for _, hostAgent := range agents {
+		agentHostnames[hostAgent.Hostname] = true
+	}
+	for i := range growths {
+		if !agentHostnames[growths[i].Key.Hostname] {
+			continue
+		}
+		diskFree, err := agent.GetMySQLDatadirDiskFree(growths[i].Key.Hostname)
+		if err != nil {
+			log.Errore(err)
+			continue
+		}
+		growths[i].ApplyDatadirDiskFree(diskFree)
+	}
+	return nil
+}
+
+// GetInstanceBinlogGrowth reports the binlog write rate and trend of an instance, and, where an agent
+// is available, the projected time until binary logs fill the datadir
+func GetInstanceBinlogGrowth(instanceKey *inst.InstanceKey) (*inst.BinlogGrowth, error) {
+	growth, err := inst.ReadInstanceBinlogGrowth(instanceKey)
+	if err != nil {
+		return nil, err
+	}
+	growths := []inst.BinlogGrowth{*growth}
+	if err := applyAgentsDatadirDiskFree(growths); err != nil {
+		return nil, err
+	}
+	return &growths[0], nil
+}
+
+// GetClusterBinlogGrowth reports the binlog write rate and trend of all instances of a cluster, and, where
+// agents are available, the projected time until binary logs fill their datadirs
+func GetClusterBinlogGrowth(clusterName string) (*inst.ClusterBinlogGrowth, error) {
+	clusterGrowth, err := inst.ReadClusterBinlogGrowth(clusterName)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyAgentsDatadirDiskFree(clusterGrowth.Instances); err != nil {
+		return nil, err
+	}
+	return clusterGrowth, nil
+}
@@ -0,0 +1,91 @@
+/*
+   Copyright 2014 Outbrain Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package logic
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/outbrain/golib/log"
+	"github.com/outbrain/orchestrator/go/config"
+	"github.com/outbrain/orchestrator/go/inst"
+)
+
+var managedBinlogPurgeRunning int64 = 0
+
+// PurgeClusterBinaryLogs purges binary logs on masters and intermediate masters of given cluster, to the extent
+// allowed by their purge plans (see inst.PlanBinlogPurge). The cluster lock is held throughout, so that
+// no replica is being relocated while plans are computed and applied.
+func PurgeClusterBinaryLogs(clusterName string) ([]inst.BinlogPurgePlan, error) {
+	clusterLock, err := inst.AcquireClusterLock(clusterName, inst.GetMaintenanceOwner(), "purge-binary-logs", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer clusterLock.Release()
+
+	plans, err := inst.PlanClusterBinlogPurge(clusterName)
+	if err != nil {
+		return plans, err
+	}
+	for _, plan := range plans {
+		if !plan.CanPurge() {
+			if plan.Blocker != "" {
+				log.Debugf("Not purging binary logs on %+v: %s", plan.Key, plan.Blocker)
+			}
+			continue
+		}
+		if err := clusterLock.Err(); err != nil {
+			return plans, err
+		}
+		if _, err := inst.PurgeBinaryLogsTo(&plan.Key, plan.PurgeToBinlog); err != nil {
+			log.Errore(err)
+			continue
+		}
+		inst.AuditOperation("managed-purge-binary-logs", &plan.Key, fmt.Sprintf("purged to %s; oldest needed by replicas: %s", plan.PurgeToBinlog, plan.OldestNeededBinlog))
+	}
+	return plans, nil
+}
+
+// PurgeManagedBinaryLogs purges unneeded binary logs on all clusters opted in via BinlogPurgeClusterFilters
+func PurgeManagedBinaryLogs() error {
+	if len(config.Config.BinlogPurgeClusterFilters) == 0 {
+		return nil
+	}
+	if !atomic.CompareAndSwapInt64(&managedBinlogPurgeRunning, 0, 1) {
+		return nil
+	}
+	defer atomic.StoreInt64(&managedBinlogPurgeRunning, 0)
+
+	clusterNames, err := inst.ReadClusters()
+	if err != nil {
+		return log.Errore(err)
+	}
+	for _, clusterName := range clusterNames {
+		clusterInfo, err := inst.ReadClusterInfo(clusterName)
+		if err != nil {
+			log.Errore(err)
+			continue
+		}
+		if !clusterInfo.HasAutomatedBinlogPurge {
+			continue
+		}
+		if _, err := PurgeClusterBinaryLogs(clusterName); err != nil {
+			log.Errore(err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+/*
+   Copyright 2014 Outbrain Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package logic
+
+// This file holds quorum based confirmation of master failures. The elected node only sees
+// the topology from its own network position; a partition between the elected node and a
+// data center could otherwise look like a DeadMaster. Before recovering a master, the elected
+// node asks the other healthy orchestrator nodes to independently probe the master, and only
+// proceeds when enough of them agree it is dead.
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/outbrain/golib/log"
+	"github.com/outbrain/orchestrator/go/config"
+	"github.com/outbrain/orchestrator/go/inst"
+	"github.com/outbrain/orchestrator/go/process"
+)
+
+const (
+	FailureVoteDead    = "dead"
+	FailureVoteAlive   = "alive"
+	FailureVoteUnknown = "unknown"
+)
+
+// FailureConfirmationNodeTokenHeader carries the requesting node's process token on failure confirmation requests
+const FailureConfirmationNodeTokenHeader = "X-Orchestrator-Node-Token"
+
+// FailureConfirmationVote is a single orchestrator node's opinion on a suspected failure
+type FailureConfirmationVote struct {
+	DetectionId   int64
+	VoterHostname string
+	VoterToken    string
+	Vote          string
+	VoteMessage   string
+	VoteTimestamp string
+}
+
+// failureConfirmationResponse is the API response of a peer node's probe
+type failureConfirmationResponse struct {
+	Code    string
+	Message string
+	Details FailureConfirmationVote
+}
+
+// ProbeInstanceLiveness has this node independently probe given instance, and returns this node's vote
+func ProbeInstanceLiveness(instanceKey *inst.InstanceKey) *FailureConfirmationVote {
+	vote := &FailureConfirmationVote{
+		VoterHostname: process.ThisHostname,
+		VoterToken:    process.ProcessToken.Hash,
+		Vote:          FailureVoteAlive,
+		VoteMessage:   "OK",
+	}
+	if err := inst.ProbeInstance(instanceKey); err != nil {
+		vote.Vote = FailureVoteDead
+		vote.VoteMessage = err.Error()
+	}
+	return vote
+}
+
+// requestFailureConfirmationVote asks given orchestrator node to probe given instance
+func requestFailureConfirmationVote(node process.NodeHTTPEndpoint, instanceKey *inst.InstanceKey) *FailureConfirmationVote {
+	vote := &FailureConfirmationVote{
+		VoterHostname: node.Hostname,
+		VoterToken:    node.Token,
+		Vote:          FailureVoteUnknown,
+	}
+	timeout := time.Duration(config.Config.FailureConfirmationTimeoutSeconds) * time.Second
+	client := &http.Client{
+		Timeout:   timeout,
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: config.Config.SSLSkipVerify}},
+	}
+	url := fmt.Sprintf("%s%s/api/probe-instance-liveness/%s/%d", strings.TrimRight(node.HTTPAdvertise, "/"), config.Config.URLPrefix, instanceKey.Hostname, instanceKey.Port)
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		vote.VoteMessage = err.Error()
+		return vote
+	}
+	req.Header.Set(FailureConfirmationNodeTokenHeader, process.ProcessToken.Hash)
+	if config.Config.HTTPAuthUser != "" {
+		req.SetBasicAuth(config.Config.HTTPAuthUser, config.Config.HTTPAuthPassword)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		vote.VoteMessage = err.Error()
+		return vote
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		vote.VoteMessage = err.Error()
+		return vote
+	}
+	response := failureConfirmationResponse{}
+	if err := json.Unmarshal(body, &response); err != nil {
+		vote.VoteMessage = fmt.Sprintf("Cannot parse response (HTTP status %d): %+v", resp.StatusCode, err)
+		return vote
+	}
+	if response.Code != "OK" {
+		vote.VoteMessage = response.Message
+		return vote
+	}
+	switch response.Details.Vote {
+	case FailureVoteDead, FailureVoteAlive:
+		vote.Vote = response.Details.Vote
+	}
+	vote.VoteMessage = response.Details.VoteMessage
+	return vote
+}
+
+// countFailureVotes returns the number of votes deeming the instance dead
+func countFailureVotes(votes []FailureConfirmationVote) (countDead uint) {
+	for _, vote := range votes {
+		if vote.Vote == FailureVoteDead {
+			countDead++
+		}
+	}
+	return countDead
+}
+
+// isFailureQuorumReached returns true when enough votes deem the instance dead
+func isFailureQuorumReached(votes []FailureConfirmationVote, quorum uint) bool {
+	return countFailureVotes(votes) >= quorum
+}
+
+// isFailureQuorumReachable returns true when given number of other nodes, along with this node, could make for quorum
+func isFailureQuorumReachable(quorum uint, countOtherNodes int) bool {
+	return quorum <= uint(1+countOtherNodes)
+}
+
+// ConfirmFailureByQuorum has other healthy orchestrator nodes probe the failed instance of given analysis.
+// It returns true when at least FailureConfirmationQuorum nodes, this node included, deem the instance dead.
+// Votes are stored along with the instance's failure detection.
+func ConfirmFailureByQuorum(analysisEntry *inst.ReplicationAnalysis) (confirmed bool, err error) {
+	if config.Config.FailureConfirmationQuorum <= 1 {
+		return true, nil
+	}
+	instanceKey := &analysisEntry.AnalyzedInstanceKey
+	detectionId, err := readLatestFailureDetectionId(instanceKey)
+	if err != nil {
+		return false, log.Errore(err)
+	}
+	nodes, err := process.ReadAvailableHttpNodeEndpoints()
+	if err != nil {
+		return false, log.Errore(err)
+	}
+	if !isFailureQuorumReachable(config.Config.FailureConfirmationQuorum, len(nodes)) {
+		message := fmt.Sprintf("FailureConfirmationQuorum is %d, yet only %d nodes are available to vote: failure cannot be confirmed and master recovery is disabled", config.Config.FailureConfirmationQuorum, 1+len(nodes))
+		log.Warningf("ConfirmFailureByQuorum: %+v: %s", *instanceKey, message)
+		inst.AuditOperation("failure-confirmation-unreachable-quorum", instanceKey, message)
+	}
+
+	// This node's analysis counts as a vote
+	votes := []FailureConfirmationVote{{
+		VoterHostname: process.ThisHostname,
+		VoterToken:    process.ProcessToken.Hash,
+		Vote:          FailureVoteDead,
+		VoteMessage:   fmt.Sprintf("analysis: %s", analysisEntry.Analysis),
+	}}
+	votesChan := make(chan *FailureConfirmationVote, len(nodes))
+	for _, node := range nodes {
+		node := node
+		go func() {
+			votesChan <- requestFailureConfirmationVote(node, instanceKey)
+		}()
+	}
+	for range nodes {
+		votes = append(votes, *<-votesChan)
+	}
+	for i := range votes {
+		votes[i].DetectionId = detectionId
+	}
+	if err := writeFailureConfirmationVotes(votes); err != nil {
+		log.Errore(err)
+	}
+
+	countDead := countFailureVotes(votes)
+	confirmed = isFailureQuorumReached(votes, config.Config.FailureConfirmationQuorum)
+	summary := fmt.Sprintf("%+v: %d of %d nodes deem instance dead; quorum: %d; confirmed: %t", analysisEntry.Analysis, countDead, len(votes), config.Config.FailureConfirmationQuorum, confirmed)
+	log.Infof("ConfirmFailureByQuorum: %+v: %s", *instanceKey, summary)
+	inst.AuditOperation("failure-confirmation", instanceKey, summary)
+	return confirmed, nil
+}
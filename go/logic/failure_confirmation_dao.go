@@ -0,0 +1,115 @@
+/*
+   Copyright 2014 Outbrain Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package logic
+
+import (
+	"github.com/outbrain/golib/log"
+	"github.com/outbrain/golib/sqlutils"
+	"github.com/outbrain/orchestrator/go/config"
+	"github.com/outbrain/orchestrator/go/db"
+	"github.com/outbrain/orchestrator/go/inst"
+)
+
+// readLatestFailureDetectionId returns the id of the most recent failure detection of given instance
+func readLatestFailureDetectionId(instanceKey *inst.InstanceKey) (detectionId int64, err error) {
+	query := `
+		select
+			ifnull(max(detection_id), 0) as detection_id
+		from
+			topology_failure_detection
+		where
+			hostname = ?
+			and port = ?
+		`
+	err = db.QueryOrchestrator(query, sqlutils.Args(instanceKey.Hostname, instanceKey.Port), func(m sqlutils.RowMap) error {
+		detectionId = m.GetInt64("detection_id")
+		return nil
+	})
+	return detectionId, log.Errore(err)
+}
+
+// writeFailureConfirmationVotes stores votes of a failure detection
+func writeFailureConfirmationVotes(votes []FailureConfirmationVote) error {
+	for _, vote := range votes {
+		_, err := db.ExecOrchestrator(`
+			insert
+				into topology_failure_detection_vote (
+					detection_id,
+					voter_hostname,
+					voter_token,
+					vote,
+					vote_message,
+					vote_timestamp
+				) values (
+					?, ?, ?, ?, ?, NOW()
+				)
+				on duplicate key update
+					vote=values(vote),
+					vote_message=values(vote_message),
+					vote_timestamp=values(vote_timestamp)
+			`, vote.DetectionId, vote.VoterHostname, vote.VoterToken, vote.Vote, vote.VoteMessage,
+		)
+		if err != nil {
+			return log.Errore(err)
+		}
+	}
+	return nil
+}
+
+// ReadFailureConfirmationVotes returns the votes cast for given failure detection
+func ReadFailureConfirmationVotes(detectionId int64) ([]FailureConfirmationVote, error) {
+	res := []FailureConfirmationVote{}
+	query := `
+		select
+			detection_id,
+			voter_hostname,
+			voter_token,
+			vote,
+			vote_message,
+			vote_timestamp
+		from
+			topology_failure_detection_vote
+		where
+			detection_id = ?
+		order by
+			voter_hostname
+		`
+	err := db.QueryOrchestrator(query, sqlutils.Args(detectionId), func(m sqlutils.RowMap) error {
+		vote := FailureConfirmationVote{}
+		vote.DetectionId = m.GetInt64("detection_id")
+		vote.VoterHostname = m.GetString("voter_hostname")
+		vote.VoterToken = m.GetString("voter_token")
+		vote.Vote = m.GetString("vote")
+		vote.VoteMessage = m.GetString("vote_message")
+		vote.VoteTimestamp = m.GetString("vote_timestamp")
+		res = append(res, vote)
+		return nil
+	})
+	return res, log.Errore(err)
+}
+
+// ExpireFailureConfirmationVotes removes old votes
+func ExpireFailureConfirmationVotes() error {
+	_, err := db.ExecOrchestrator(`
+			delete
+				from topology_failure_detection_vote
+			where
+				vote_timestamp < NOW() - INTERVAL ? DAY
+			`, config.Config.AuditPurgeDays,
+	)
+	return log.Errore(err)
+}
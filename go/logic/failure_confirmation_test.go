@@ -0,0 +1,93 @@
+/*
+   Copyright 2014 Outbrain Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package logic
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	test "github.com/outbrain/golib/tests"
+	"github.com/outbrain/orchestrator/go/inst"
+	"github.com/outbrain/orchestrator/go/process"
+)
+
+func newFailureConfirmationVotes(votes ...string) []FailureConfirmationVote {
+	res := []FailureConfirmationVote{}
+	for _, vote := range votes {
+		res = append(res, FailureConfirmationVote{Vote: vote})
+	}
+	return res
+}
+
+func TestCountFailureVotes(t *testing.T) {
+	test.S(t).ExpectEquals(countFailureVotes(newFailureConfirmationVotes()), uint(0))
+	test.S(t).ExpectEquals(countFailureVotes(newFailureConfirmationVotes(FailureVoteDead, FailureVoteAlive, FailureVoteUnknown)), uint(1))
+	test.S(t).ExpectEquals(countFailureVotes(newFailureConfirmationVotes(FailureVoteDead, FailureVoteDead, FailureVoteUnknown)), uint(2))
+}
+
+func TestIsFailureQuorumReached(t *testing.T) {
+	votes := newFailureConfirmationVotes(FailureVoteDead, FailureVoteDead, FailureVoteAlive, FailureVoteUnknown)
+	test.S(t).ExpectTrue(isFailureQuorumReached(votes, 1))
+	test.S(t).ExpectTrue(isFailureQuorumReached(votes, 2))
+	test.S(t).ExpectFalse(isFailureQuorumReached(votes, 3))
+
+	// Unknown votes do not count
+	test.S(t).ExpectFalse(isFailureQuorumReached(newFailureConfirmationVotes(FailureVoteDead, FailureVoteUnknown, FailureVoteUnknown), 2))
+}
+
+func TestIsFailureQuorumReachable(t *testing.T) {
+	test.S(t).ExpectTrue(isFailureQuorumReachable(2, 1))
+	test.S(t).ExpectTrue(isFailureQuorumReachable(2, 2))
+	test.S(t).ExpectFalse(isFailureQuorumReachable(2, 0))
+	test.S(t).ExpectFalse(isFailureQuorumReachable(4, 2))
+}
+
+func TestRequestFailureConfirmationVote(t *testing.T) {
+	instanceKey := &inst.InstanceKey{Hostname: "db1", Port: 3306}
+	requestVote := func(status int, body string) *FailureConfirmationVote {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			test.S(t).ExpectEquals(r.URL.Path, "/api/probe-instance-liveness/db1/3306")
+			test.S(t).ExpectNotEquals(r.Header.Get(FailureConfirmationNodeTokenHeader), "")
+			w.WriteHeader(status)
+			fmt.Fprint(w, body)
+		}))
+		defer server.Close()
+		return requestFailureConfirmationVote(process.NodeHTTPEndpoint{Hostname: "node2", Token: "node2-token", HTTPAdvertise: server.URL}, instanceKey)
+	}
+
+	vote := requestVote(http.StatusOK, `{"Code": "OK", "Message": "", "Details": {"Vote": "dead", "VoteMessage": "connection refused"}}`)
+	test.S(t).ExpectEquals(vote.Vote, FailureVoteDead)
+	test.S(t).ExpectEquals(vote.VoteMessage, "connection refused")
+	test.S(t).ExpectEquals(vote.VoterHostname, "node2")
+	test.S(t).ExpectEquals(vote.VoterToken, "node2-token")
+
+	vote = requestVote(http.StatusOK, `{"Code": "OK", "Message": "", "Details": {"Vote": "alive", "VoteMessage": "OK"}}`)
+	test.S(t).ExpectEquals(vote.Vote, FailureVoteAlive)
+
+	vote = requestVote(http.StatusOK, `{"Code": "ERROR", "Message": "Unauthorized: not a healthy orchestrator node"}`)
+	test.S(t).ExpectEquals(vote.Vote, FailureVoteUnknown)
+	test.S(t).ExpectEquals(vote.VoteMessage, "Unauthorized: not a healthy orchestrator node")
+
+	vote = requestVote(http.StatusUnauthorized, `Unauthorized`)
+	test.S(t).ExpectEquals(vote.Vote, FailureVoteUnknown)
+	test.S(t).ExpectNotEquals(vote.VoteMessage, "")
+
+	vote = requestVote(http.StatusOK, `{"Code": "OK", "Details": {"Vote": "maybe"}}`)
+	test.S(t).ExpectEquals(vote.Vote, FailureVoteUnknown)
+}
@@ -194,6 +194,18 @@ func ContinuousDiscovery() {
 	if config.Config.SnapshotTopologiesIntervalHours > 0 {
 		snapshotTopologiesTick = time.Tick(time.Duration(config.Config.SnapshotTopologiesIntervalHours) * time.Hour)
 	}
+	var binlogPurgeTick <-chan time.Time
+	if len(config.Config.BinlogPurgeClusterFilters) > 0 && config.Config.BinlogPurgeIntervalMinutes > 0 {
+		binlogPurgeTick = time.Tick(time.Duration(config.Config.BinlogPurgeIntervalMinutes) * time.Minute)
+	}
+	var autoPseudoGTIDTick <-chan time.Time
+	if config.Config.AutoPseudoGTID && config.Config.PseudoGTIDInjectionIntervalSeconds > 0 {
+		if err := inst.ValidatePseudoGTIDInjection(); err != nil {
+			log.Errorf("AutoPseudoGTID is enabled but Pseudo-GTID injection is invalid; will not inject. %+v", err)
+		} else {
+			autoPseudoGTIDTick = time.Tick(time.Duration(config.Config.PseudoGTIDInjectionIntervalSeconds) * time.Second)
+		}
+	}
 
 	go ometrics.InitGraphiteMetrics()
 	go acceptSignals()
@@ -230,6 +242,7 @@ func ContinuousDiscovery() {
 							discoveryQueue.Push(instanceKey)
 						}
 					}
+					go ProcessPendingAsyncRequests()
 					if wasAlreadyElected == 0 {
 						// Just turned to be leader!
 						go process.RegisterNode("", "", false)
@@ -251,6 +264,8 @@ func ContinuousDiscovery() {
 				if atomic.LoadInt64(&isElectedNode) == 1 {
 					go inst.UpdateInstanceRecentRelaylogHistory()
 					go inst.RecordInstanceCoordinatesHistory()
+					go inst.IndexPseudoGTIDEntries()
+					go inst.RecordInstanceLagHistory()
 				}
 			}()
 		case <-caretakingTick:
@@ -258,6 +273,8 @@ func ContinuousDiscovery() {
 			go func() {
 				if atomic.LoadInt64(&isElectedNode) == 1 {
 					go inst.RecordInstanceBinlogFileHistory()
+					go inst.DownsampleInstanceLagHistory()
+					go inst.ExpireInstanceLagHistory()
 					go inst.ForgetLongUnseenInstances()
 					go inst.ForgetUnseenInstancesDifferentlyResolved()
 					go inst.ForgetExpiredHostnameResolves()
@@ -274,6 +291,11 @@ func ContinuousDiscovery() {
 					go inst.ExpireAudit()
 					go inst.ExpireMasterPositionEquivalence()
 					go inst.ExpirePoolInstances()
+					go inst.ExpirePseudoGTIDInjectionStatus()
+					go inst.ExpirePseudoGTIDIndex()
+					go ExpireReplicationRemediations()
+					go ExpireFailureConfirmationVotes()
+					go ExpireAsyncRequests()
 					go inst.FlushNontrivialResolveCacheToDatabase()
 					go process.ExpireNodesHistory()
 					go process.ExpireAccessTokens()
@@ -291,17 +313,36 @@ func ContinuousDiscovery() {
 					go AcknowledgeCrashedRecoveries()
 					go inst.ExpireInstanceAnalysisChangelog()
 					go CheckAndRecover(nil, nil, false)
+					go RemediateBrokenReplication()
 				}
 			}()
 		case <-snapshotTopologiesTick:
 			go func() {
 				go inst.SnapshotTopologies()
 			}()
+		case <-binlogPurgeTick:
+			go func() {
+				if atomic.LoadInt64(&isElectedNode) == 1 {
+					go PurgeManagedBinaryLogs()
+				}
+			}()
+		case <-autoPseudoGTIDTick:
+			go func() {
+				if atomic.LoadInt64(&isElectedNode) == 1 {
+					go inst.InjectPseudoGTIDOnWriteableMasters()
+				}
+			}()
 		}
 	}
 }
 
-func pollAgent(hostname string) error {
+// pollAgent reads an agent's status, verifying its token first if so requested
+func pollAgent(hostname string, verifyToken bool) error {
+	if verifyToken {
+		if err := agent.VerifyAgentToken(hostname); err != nil {
+			log.Errore(err)
+		}
+	}
 	polledAgent, err := agent.GetAgent(hostname)
 	agent.UpdateAgentLastChecked(hostname)
 
@@ -327,11 +368,27 @@ func ContinuousAgentsPoll() {
 
 	tick := time.Tick(time.Duration(config.Config.GetDiscoveryPollSeconds()) * time.Second)
 	caretakingTick := time.Tick(time.Hour)
+	tokenRotationTick := time.Tick(time.Minute)
 	for range tick {
+		// Agents are polled by all nodes serving agents; tokens are only verified, rotated and revoked by the
+		// elected node. A tick rotating tokens does not verify them.
+		isElected := atomic.LoadInt64(&isElectedNode) == 1
+		rotateTokens := false
+		select {
+		case <-tokenRotationTick:
+			rotateTokens = isElected
+		default:
+		}
 		agentsHosts, _ := agent.ReadOutdatedAgentsHosts()
 		log.Debugf("outdated agents hosts: %+v", agentsHosts)
 		for _, hostname := range agentsHosts {
-			go pollAgent(hostname)
+			go pollAgent(hostname, isElected && !rotateTokens)
+		}
+		if rotateTokens {
+			go agent.RotateAgentTokens()
+		}
+		if isElected {
+			go agent.RevokeAgentsWithUnverifiedTokens()
 		}
 		// See if we should also forget agents (lower frequency)
 		select {
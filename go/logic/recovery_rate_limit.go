@@ -0,0 +1,214 @@
+/*
+   Copyright 2014 Outbrain Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package logic
+
+// This file holds the global anti-flapping mechanism. While RecoveryPeriodBlockSeconds
+// protects a single instance or cluster from flapping, nothing prevents many clusters
+// from being failed over in a short time, which is typical of a network partition
+// isolating orchestrator rather than of actual masters failing.
+// Once too many master recoveries take place within a time window (globally or within
+// a single data center), recoveries are disabled globally (see disable_recovery.go)
+// until a human re-enables them via `-c enable-global-recoveries`.
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/outbrain/golib/log"
+	"github.com/outbrain/golib/sqlutils"
+	"github.com/outbrain/orchestrator/go/config"
+	"github.com/outbrain/orchestrator/go/db"
+	"github.com/outbrain/orchestrator/go/inst"
+	"github.com/rcrowley/go-metrics"
+)
+
+// rateLimitedRecoveryAnalysisCodes are the analysis types counted towards, and subject to, master recovery rate limits
+var rateLimitedRecoveryAnalysisCodes = []inst.AnalysisCode{
+	inst.DeadMaster,
+	inst.DeadMasterAndSomeSlaves,
+	inst.DeadCoMaster,
+	inst.DeadCoMasterAndSomeSlaves,
+}
+
+// recoveryRateLimitMutex serializes rate limit check and recovery registration, such that
+// concurrently detected failures cannot all slip in under the limit
+var recoveryRateLimitMutex sync.Mutex
+
+var recoveryRateLimitTrippedCounter = metrics.NewCounter()
+
+// recoveryRateLimitStore reads recent master recoveries, and acts upon a tripped rate limit
+type recoveryRateLimitStore interface {
+	readDataCenter(instanceKey *inst.InstanceKey) (string, error)
+	readRecentRecoveries(dataCenter string) ([]TopologyRecovery, error)
+	disableRecovery() error
+	registerBlockedRecoveries(analysisEntry *inst.ReplicationAnalysis, blockingRecoveries []TopologyRecovery) error
+	notifyTripped(analysisEntry *inst.ReplicationAnalysis, description string)
+}
+
+// backendRecoveryRateLimitStore is the recoveryRateLimitStore backed by the orchestrator backend database
+type backendRecoveryRateLimitStore struct{}
+
+var recoveryRateLimits recoveryRateLimitStore = &backendRecoveryRateLimitStore{}
+
+func (this *backendRecoveryRateLimitStore) readDataCenter(instanceKey *inst.InstanceKey) (string, error) {
+	return readInstanceDataCenter(instanceKey)
+}
+
+func (this *backendRecoveryRateLimitStore) readRecentRecoveries(dataCenter string) ([]TopologyRecovery, error) {
+	return readRecentRateLimitedRecoveries(dataCenter)
+}
+
+func (this *backendRecoveryRateLimitStore) disableRecovery() error {
+	return DisableRecovery()
+}
+
+func (this *backendRecoveryRateLimitStore) registerBlockedRecoveries(analysisEntry *inst.ReplicationAnalysis, blockingRecoveries []TopologyRecovery) error {
+	return RegisterBlockedRecoveries(analysisEntry, blockingRecoveries)
+}
+
+func (this *backendRecoveryRateLimitStore) notifyTripped(analysisEntry *inst.ReplicationAnalysis, description string) {
+	inst.AuditOperation("recovery-rate-limit", &analysisEntry.AnalyzedInstanceKey, fmt.Sprintf("%+v recovery blocked and recoveries disabled globally: %s", analysisEntry.Analysis, description))
+	executeProcesses(config.Config.RecoveryRateLimitProcesses, "RecoveryRateLimitProcesses", NewTopologyRecovery(*analysisEntry), false)
+}
+
+func init() {
+	metrics.Register("recover.rate_limit.tripped", recoveryRateLimitTrippedCounter)
+}
+
+// isRateLimitedRecoveryAnalysis returns true when given analysis is subject to master recovery rate limits
+func isRateLimitedRecoveryAnalysis(analysisCode inst.AnalysisCode) bool {
+	for _, code := range rateLimitedRecoveryAnalysisCodes {
+		if code == analysisCode {
+			return true
+		}
+	}
+	return false
+}
+
+// isRecoveryRateLimitConfigured returns true when any master recovery rate limit is in place
+func isRecoveryRateLimitConfigured() bool {
+	if config.Config.MasterRecoveryRateLimitMinutes == 0 {
+		return false
+	}
+	return config.Config.MasterRecoveryRateLimitCount > 0 || config.Config.MasterRecoveryRateLimitPerDataCenterCount > 0
+}
+
+// exceedsRecoveryRateLimit returns true when registering one more recovery on top of given count would exceed given limit.
+// A zero limit means no limit.
+func exceedsRecoveryRateLimit(countRecentRecoveries int, limit uint) bool {
+	if limit == 0 {
+		return false
+	}
+	return uint(countRecentRecoveries) >= limit
+}
+
+// readInstanceDataCenter returns the data center of given instance as last recorded in the backend
+func readInstanceDataCenter(instanceKey *inst.InstanceKey) (dataCenter string, err error) {
+	query := `
+		select
+			data_center
+		from
+			database_instance
+		where
+			hostname = ?
+			and port = ?
+		`
+	err = db.QueryOrchestrator(query, sqlutils.Args(instanceKey.Hostname, instanceKey.Port), func(m sqlutils.RowMap) error {
+		dataCenter = m.GetString("data_center")
+		return nil
+	})
+	return dataCenter, log.Errore(err)
+}
+
+// readRecentRateLimitedRecoveries returns master recoveries registered within the rate limit window, most recent first.
+// When dataCenter is non empty, only recoveries of masters in that data center are returned.
+func readRecentRateLimitedRecoveries(dataCenter string) ([]TopologyRecovery, error) {
+	analysisCodes := []interface{}{}
+	for _, code := range rateLimitedRecoveryAnalysisCodes {
+		analysisCodes = append(analysisCodes, string(code))
+	}
+	whereClause := fmt.Sprintf(`
+		where
+			analysis in (?%s)
+			and start_active_period >= now() - interval ? minute
+			and (
+				? = ''
+				or (hostname, port) in (
+					select hostname, port from database_instance where data_center = ?
+				)
+			)
+		`, strings.Repeat(", ?", len(analysisCodes)-1))
+	args := append(analysisCodes, config.Config.MasterRecoveryRateLimitMinutes, dataCenter, dataCenter)
+	return readRecoveries(whereClause, ``, args)
+}
+
+// checkRecoveryRateLimits returns an error when registering a recovery for given analysis would exceed
+// the global or the per-data-center master recovery rate limit. In such case it also trips the limit:
+// recoveries get disabled globally, the blocked recovery is registered and notification hooks are invoked.
+func checkRecoveryRateLimits(analysisEntry *inst.ReplicationAnalysis) error {
+	if !isRecoveryRateLimitConfigured() {
+		return nil
+	}
+	if !isRateLimitedRecoveryAnalysis(analysisEntry.Analysis) {
+		return nil
+	}
+	if config.Config.MasterRecoveryRateLimitCount > 0 {
+		recoveries, err := recoveryRateLimits.readRecentRecoveries("")
+		if err != nil {
+			return log.Errore(err)
+		}
+		if exceedsRecoveryRateLimit(len(recoveries), config.Config.MasterRecoveryRateLimitCount) {
+			description := fmt.Sprintf("%d master recoveries in past %d minutes (limit: %d)", len(recoveries), config.Config.MasterRecoveryRateLimitMinutes, config.Config.MasterRecoveryRateLimitCount)
+			return tripRecoveryRateLimit(analysisEntry, recoveries, description)
+		}
+	}
+	if config.Config.MasterRecoveryRateLimitPerDataCenterCount > 0 {
+		dataCenter, err := recoveryRateLimits.readDataCenter(&analysisEntry.AnalyzedInstanceKey)
+		if err != nil {
+			return err
+		}
+		if dataCenter == "" {
+			log.Debugf("checkRecoveryRateLimits: unknown data center for %+v; per data center limit does not apply", analysisEntry.AnalyzedInstanceKey)
+			return nil
+		}
+		recoveries, err := recoveryRateLimits.readRecentRecoveries(dataCenter)
+		if err != nil {
+			return log.Errore(err)
+		}
+		if exceedsRecoveryRateLimit(len(recoveries), config.Config.MasterRecoveryRateLimitPerDataCenterCount) {
+			description := fmt.Sprintf("%d master recoveries in data center %s in past %d minutes (limit: %d)", len(recoveries), dataCenter, config.Config.MasterRecoveryRateLimitMinutes, config.Config.MasterRecoveryRateLimitPerDataCenterCount)
+			return tripRecoveryRateLimit(analysisEntry, recoveries, description)
+		}
+	}
+	return nil
+}
+
+// tripRecoveryRateLimit disables recoveries globally and records & notifies the blocked recovery.
+// It returns an error describing the blockage.
+func tripRecoveryRateLimit(analysisEntry *inst.ReplicationAnalysis, recentRecoveries []TopologyRecovery, description string) error {
+	recoveryRateLimitTrippedCounter.Inc(1)
+	if err := recoveryRateLimits.disableRecovery(); err != nil {
+		log.Errore(err)
+	}
+	if len(recentRecoveries) > 0 {
+		// Blocked on the most recent recovery; acknowledging it releases the blocked_topology_recovery entry
+		recoveryRateLimits.registerBlockedRecoveries(analysisEntry, recentRecoveries[0:1])
+	}
+	recoveryRateLimits.notifyTripped(analysisEntry, description)
+	return log.Errorf("Recovery rate limit exceeded: %s. Will not recover %+v on %+v. Recoveries are now disabled globally; re-enable via `-c enable-global-recoveries`", description, analysisEntry.Analysis, analysisEntry.AnalyzedInstanceKey)
+}
@@ -0,0 +1,190 @@
+/*
+   Copyright 2014 Outbrain Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package logic
+
+import (
+	"testing"
+
+	test "github.com/outbrain/golib/tests"
+	"github.com/outbrain/orchestrator/go/config"
+	"github.com/outbrain/orchestrator/go/inst"
+)
+
+// memoryRecoveryRateLimitStore holds recent recoveries per data center; the empty data center holds all of them
+type memoryRecoveryRateLimitStore struct {
+	dataCenters        map[inst.InstanceKey]string
+	recentRecoveries   map[string][]TopologyRecovery
+	recoveryDisabled   bool
+	blockingRecoveries []TopologyRecovery
+	trippedNotices     []string
+}
+
+func (this *memoryRecoveryRateLimitStore) readDataCenter(instanceKey *inst.InstanceKey) (string, error) {
+	return this.dataCenters[*instanceKey], nil
+}
+
+func (this *memoryRecoveryRateLimitStore) readRecentRecoveries(dataCenter string) ([]TopologyRecovery, error) {
+	return this.recentRecoveries[dataCenter], nil
+}
+
+func (this *memoryRecoveryRateLimitStore) disableRecovery() error {
+	this.recoveryDisabled = true
+	return nil
+}
+
+func (this *memoryRecoveryRateLimitStore) registerBlockedRecoveries(analysisEntry *inst.ReplicationAnalysis, blockingRecoveries []TopologyRecovery) error {
+	this.blockingRecoveries = append(this.blockingRecoveries, blockingRecoveries...)
+	return nil
+}
+
+func (this *memoryRecoveryRateLimitStore) notifyTripped(analysisEntry *inst.ReplicationAnalysis, description string) {
+	this.trippedNotices = append(this.trippedNotices, description)
+}
+
+func newRateLimitTestRecoveries(ids ...int64) []TopologyRecovery {
+	recoveries := []TopologyRecovery{}
+	for _, id := range ids {
+		recoveries = append(recoveries, TopologyRecovery{Id: id})
+	}
+	return recoveries
+}
+
+// withRecoveryRateLimits runs f with given limits, against an in-memory store
+func withRecoveryRateLimits(globalLimit uint, dataCenterLimit uint, minutes uint, f func(store *memoryRecoveryRateLimitStore)) {
+	defer func(globalLimit uint, dataCenterLimit uint, minutes uint, store recoveryRateLimitStore) {
+		config.Config.MasterRecoveryRateLimitCount = globalLimit
+		config.Config.MasterRecoveryRateLimitPerDataCenterCount = dataCenterLimit
+		config.Config.MasterRecoveryRateLimitMinutes = minutes
+		recoveryRateLimits = store
+	}(config.Config.MasterRecoveryRateLimitCount, config.Config.MasterRecoveryRateLimitPerDataCenterCount, config.Config.MasterRecoveryRateLimitMinutes, recoveryRateLimits)
+
+	config.Config.MasterRecoveryRateLimitCount = globalLimit
+	config.Config.MasterRecoveryRateLimitPerDataCenterCount = dataCenterLimit
+	config.Config.MasterRecoveryRateLimitMinutes = minutes
+	store := &memoryRecoveryRateLimitStore{
+		dataCenters: map[inst.InstanceKey]string{
+			{Hostname: "db-east", Port: 3306}: "east",
+			{Hostname: "db-west", Port: 3306}: "west",
+		},
+		recentRecoveries: map[string][]TopologyRecovery{
+			"":     newRateLimitTestRecoveries(5, 4, 3),
+			"east": newRateLimitTestRecoveries(5, 3),
+			"west": newRateLimitTestRecoveries(4),
+		},
+	}
+	recoveryRateLimits = store
+	f(store)
+}
+
+func TestExceedsRecoveryRateLimit(t *testing.T) {
+	tests := []struct {
+		countRecentRecoveries int
+		limit                 uint
+		expected              bool
+	}{
+		{0, 0, false},
+		{100, 0, false},
+		{0, 1, false},
+		{1, 1, true},
+		{2, 3, false},
+		{3, 3, true},
+		{4, 3, true},
+	}
+	for _, tt := range tests {
+		test.S(t).ExpectEquals(exceedsRecoveryRateLimit(tt.countRecentRecoveries, tt.limit), tt.expected)
+	}
+}
+
+func TestIsRecoveryRateLimitConfigured(t *testing.T) {
+	tests := []struct {
+		globalLimit     uint
+		dataCenterLimit uint
+		minutes         uint
+		expected        bool
+	}{
+		{0, 0, 10, false},
+		{3, 0, 10, true},
+		{0, 2, 10, true},
+		{3, 2, 0, false},
+	}
+	for _, tt := range tests {
+		withRecoveryRateLimits(tt.globalLimit, tt.dataCenterLimit, tt.minutes, func(store *memoryRecoveryRateLimitStore) {
+			test.S(t).ExpectEquals(isRecoveryRateLimitConfigured(), tt.expected)
+		})
+	}
+}
+
+func TestCheckRecoveryRateLimits(t *testing.T) {
+	analysisOf := func(hostname string, analysis inst.AnalysisCode) *inst.ReplicationAnalysis {
+		return &inst.ReplicationAnalysis{AnalyzedInstanceKey: inst.InstanceKey{Hostname: hostname, Port: 3306}, Analysis: analysis}
+	}
+	tests := []struct {
+		globalLimit     uint
+		dataCenterLimit uint
+		minutes         uint
+		hostname        string
+		analysis        inst.AnalysisCode
+		expectTripped   bool
+	}{
+		// Unconfigured limits
+		{0, 0, 10, "db-east", inst.DeadMaster, false},
+		{3, 2, 0, "db-east", inst.DeadMaster, false},
+		// Global window: 3 recent recoveries
+		{4, 0, 10, "db-east", inst.DeadMaster, false},
+		{3, 0, 10, "db-east", inst.DeadMaster, true},
+		{3, 0, 10, "db-west", inst.DeadCoMaster, true},
+		// Not a master recovery
+		{3, 0, 10, "db-east", inst.DeadIntermediateMaster, false},
+		// Per data center window: 2 recent recoveries in east, 1 in west
+		{0, 2, 10, "db-east", inst.DeadMaster, true},
+		{0, 2, 10, "db-west", inst.DeadMaster, false},
+		{0, 3, 10, "db-east", inst.DeadMasterAndSomeSlaves, false},
+		// Unknown data center
+		{0, 1, 10, "db-north", inst.DeadMaster, false},
+	}
+	for _, tt := range tests {
+		withRecoveryRateLimits(tt.globalLimit, tt.dataCenterLimit, tt.minutes, func(store *memoryRecoveryRateLimitStore) {
+			err := checkRecoveryRateLimits(analysisOf(tt.hostname, tt.analysis))
+			test.S(t).ExpectEquals(err != nil, tt.expectTripped)
+			test.S(t).ExpectEquals(store.recoveryDisabled, tt.expectTripped)
+			test.S(t).ExpectEquals(len(store.trippedNotices), len(store.blockingRecoveries))
+		})
+	}
+}
+
+func TestTripRecoveryRateLimit(t *testing.T) {
+	withRecoveryRateLimits(0, 2, 10, func(store *memoryRecoveryRateLimitStore) {
+		err := checkRecoveryRateLimits(&inst.ReplicationAnalysis{AnalyzedInstanceKey: inst.InstanceKey{Hostname: "db-east", Port: 3306}, Analysis: inst.DeadMaster})
+		test.S(t).ExpectNotNil(err)
+		test.S(t).ExpectTrue(store.recoveryDisabled)
+		// Blocked on the most recent recovery in the data center
+		test.S(t).ExpectEquals(len(store.blockingRecoveries), 1)
+		test.S(t).ExpectEquals(store.blockingRecoveries[0].Id, int64(5))
+		test.S(t).ExpectEquals(len(store.trippedNotices), 1)
+	})
+	withRecoveryRateLimits(0, 1, 10, func(store *memoryRecoveryRateLimitStore) {
+		// No recent recoveries to block on
+		store.recentRecoveries["east"] = []TopologyRecovery{}
+		test.S(t).ExpectNil(checkRecoveryRateLimits(&inst.ReplicationAnalysis{AnalyzedInstanceKey: inst.InstanceKey{Hostname: "db-east", Port: 3306}, Analysis: inst.DeadMaster}))
+
+		err := tripRecoveryRateLimit(&inst.ReplicationAnalysis{AnalyzedInstanceKey: inst.InstanceKey{Hostname: "db-east", Port: 3306}, Analysis: inst.DeadMaster}, []TopologyRecovery{}, "test")
+		test.S(t).ExpectNotNil(err)
+		test.S(t).ExpectTrue(store.recoveryDisabled)
+		test.S(t).ExpectEquals(len(store.blockingRecoveries), 0)
+		test.S(t).ExpectEquals(len(store.trippedNotices), 1)
+	})
+}
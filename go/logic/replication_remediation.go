@@ -0,0 +1,274 @@
+/*
+   Copyright 2014 Outbrain Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package logic
+
+import (
+	"fmt"
+	"regexp"
+	"sync/atomic"
+
+	"github.com/outbrain/golib/log"
+	"github.com/outbrain/orchestrator/go/config"
+	"github.com/outbrain/orchestrator/go/inst"
+	"github.com/rcrowley/go-metrics"
+)
+
+const (
+	RemediationRestartIOThread    = "restart-io-thread"
+	RemediationRestartReplication = "restart-replication"
+	RemediationSkipTransaction    = "skip-transaction"
+	RemediationDowntime           = "downtime"
+)
+
+const (
+	replicationThreadSQL = "sql"
+	replicationThreadIO  = "io"
+)
+
+// ReplicationRemediationAnalysis is the failure type reported to ReplicationRemediationAlertProcesses
+const ReplicationRemediationAnalysis inst.AnalysisCode = "ReplicationRemediation"
+
+// ReplicationRemediation represents an entry in the replication_remediation table
+type ReplicationRemediation struct {
+	Id                   int64
+	Key                  inst.InstanceKey
+	ClusterName          string
+	RemediationTimestamp string
+	ReplicationThread    string
+	ErrorNumber          uint
+	ErrorMessage         string
+	Action               string
+	IsSuccessful         bool
+	ResultMessage        string
+}
+
+var replicationRemediationRunning int64
+
+var replicationRemediationCounter = metrics.NewCounter()
+var replicationRemediationFailureCounter = metrics.NewCounter()
+
+func init() {
+	metrics.Register("remediation.replication.start", replicationRemediationCounter)
+	metrics.Register("remediation.replication.fail", replicationRemediationFailureCounter)
+}
+
+// isValidRemediationRule checks a configured rule for sanity
+func isValidRemediationRule(rule *config.RemediationRule) error {
+	if rule.Thread != replicationThreadSQL && rule.Thread != replicationThreadIO {
+		return fmt.Errorf("Invalid remediation rule thread: %q. Expected %q or %q", rule.Thread, replicationThreadSQL, replicationThreadIO)
+	}
+	switch rule.Action {
+	case RemediationRestartIOThread, RemediationRestartReplication, RemediationDowntime:
+	case RemediationSkipTransaction:
+		if rule.Thread != replicationThreadSQL {
+			return fmt.Errorf("Invalid remediation rule: %s only applies to %s thread", rule.Action, replicationThreadSQL)
+		}
+	default:
+		return fmt.Errorf("Invalid remediation rule action: %q", rule.Action)
+	}
+	if _, err := regexp.Compile(rule.ErrorPattern); err != nil {
+		return fmt.Errorf("Invalid remediation rule error pattern %q: %+v", rule.ErrorPattern, err)
+	}
+	return nil
+}
+
+// remediationRuleMatches checks whether given rule applies to given thread error
+func remediationRuleMatches(rule *config.RemediationRule, thread string, errorNumber uint, errorMessage string) bool {
+	if rule.Thread != thread {
+		return false
+	}
+	if len(rule.ErrorCodes) > 0 {
+		found := false
+		for _, code := range rule.ErrorCodes {
+			if code == errorNumber {
+				found = true
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if rule.ErrorPattern != "" {
+		if matched, _ := regexp.MatchString(rule.ErrorPattern, errorMessage); !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// isSkipWhitelistedErrorCode checks whether given SQL thread error number is whitelisted for skipping a transaction
+func isSkipWhitelistedErrorCode(errorNumber uint) bool {
+	for _, code := range config.Config.ReplicationRemediationSkipErrorCodes {
+		if code == errorNumber {
+			return true
+		}
+	}
+	return false
+}
+
+// isRemediationRateLimited checks whether given count of recent remediations on an instance reaches
+// ReplicationRemediationRateLimitCount. A limit of 0 means unlimited.
+func isRemediationRateLimited(countRecent uint) bool {
+	if config.Config.ReplicationRemediationRateLimitCount == 0 {
+		return false
+	}
+	return countRecent >= config.Config.ReplicationRemediationRateLimitCount
+}
+
+// getReplicationRemediation returns the remediation due for given instance, based on configured rules,
+// or nil when no rule applies. The SQL thread is evaluated before the IO thread.
+func getReplicationRemediation(instance *inst.Instance) *ReplicationRemediation {
+	type threadError struct {
+		thread       string
+		isRunning    bool
+		errorNumber  uint
+		errorMessage string
+	}
+	threadErrors := []threadError{
+		{replicationThreadSQL, instance.Slave_SQL_Running, instance.LastSQLErrno, instance.LastSQLError},
+		{replicationThreadIO, instance.Slave_IO_Running, instance.LastIOErrno, instance.LastIOError},
+	}
+	for _, threadError := range threadErrors {
+		if threadError.isRunning || threadError.errorNumber == 0 {
+			continue
+		}
+		for _, rule := range config.Config.ReplicationRemediationRules {
+			rule := rule
+			if err := isValidRemediationRule(&rule); err != nil {
+				log.Errore(err)
+				continue
+			}
+			if remediationRuleMatches(&rule, threadError.thread, threadError.errorNumber, threadError.errorMessage) {
+				return &ReplicationRemediation{
+					Key:               instance.Key,
+					ClusterName:       instance.ClusterName,
+					ReplicationThread: threadError.thread,
+					ErrorNumber:       threadError.errorNumber,
+					ErrorMessage:      threadError.errorMessage,
+					Action:            rule.Action,
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// executeReplicationRemediation runs the action of given remediation
+func executeReplicationRemediation(remediation *ReplicationRemediation, clusterInfo *inst.ClusterInfo) error {
+	switch remediation.Action {
+	case RemediationRestartIOThread:
+		_, err := inst.RestartSlaveIOThread(&remediation.Key)
+		return err
+	case RemediationRestartReplication:
+		_, err := inst.RestartSlave(&remediation.Key)
+		return err
+	case RemediationSkipTransaction:
+		if remediation.ReplicationThread != replicationThreadSQL || !isSkipWhitelistedErrorCode(remediation.ErrorNumber) {
+			return fmt.Errorf("Error %d is not whitelisted in ReplicationRemediationSkipErrorCodes; will not skip transaction", remediation.ErrorNumber)
+		}
+		_, err := inst.SkipQuery(&remediation.Key)
+		return err
+	case RemediationDowntime:
+		reason := fmt.Sprintf("replication remediation: %s thread error %d", remediation.ReplicationThread, remediation.ErrorNumber)
+		if err := inst.BeginDowntime(&remediation.Key, "orchestrator", reason, config.Config.ReplicationRemediationDowntimeMinutes*60); err != nil {
+			return err
+		}
+		analysisEntry := inst.ReplicationAnalysis{
+			AnalyzedInstanceKey: remediation.Key,
+			ClusterDetails:      *clusterInfo,
+			Analysis:            ReplicationRemediationAnalysis,
+			Description:         remediation.ErrorMessage,
+			IsDowntimed:         true,
+		}
+		return executeProcesses(config.Config.ReplicationRemediationAlertProcesses, "ReplicationRemediationAlertProcesses", NewTopologyRecovery(analysisEntry), false)
+	}
+	return fmt.Errorf("Unsupported remediation action: %s", remediation.Action)
+}
+
+// remediateInstance re-reads given instance, and if still broken, applies the first matching remediation rule,
+// subject to per-instance rate limiting. All actions are audited.
+func remediateInstance(instanceKey *inst.InstanceKey, clusterInfo *inst.ClusterInfo) (*ReplicationRemediation, error) {
+	instance, err := inst.ReadTopologyInstanceUnbuffered(instanceKey)
+	if err != nil {
+		return nil, log.Errore(err)
+	}
+	remediation := getReplicationRemediation(instance)
+	if remediation == nil {
+		return nil, nil
+	}
+	countRecent, err := countRecentReplicationRemediations(instanceKey)
+	if err != nil {
+		return nil, log.Errore(err)
+	}
+	if isRemediationRateLimited(countRecent) {
+		log.Debugf("remediateInstance: %+v has been remediated %d times in past %d minutes; will not %s", *instanceKey, countRecent, config.Config.ReplicationRemediationRateLimitMinutes, remediation.Action)
+		return nil, nil
+	}
+
+	log.Infof("remediateInstance: will %s on %+v due to %s thread error %d: %s", remediation.Action, *instanceKey, remediation.ReplicationThread, remediation.ErrorNumber, remediation.ErrorMessage)
+	replicationRemediationCounter.Inc(1)
+	err = executeReplicationRemediation(remediation, clusterInfo)
+	remediation.IsSuccessful = (err == nil)
+	if err == nil {
+		remediation.ResultMessage = "OK"
+	} else {
+		replicationRemediationFailureCounter.Inc(1)
+		remediation.ResultMessage = err.Error()
+	}
+	if writeErr := writeReplicationRemediation(remediation); writeErr != nil {
+		log.Errore(writeErr)
+	}
+	inst.AuditOperation("replication-remediation", instanceKey, fmt.Sprintf("%s on %s thread error %d: %s; result: %s", remediation.Action, remediation.ReplicationThread, remediation.ErrorNumber, remediation.ErrorMessage, remediation.ResultMessage))
+	return remediation, err
+}
+
+// RemediateBrokenReplication looks for replicas with broken replication threads on clusters opted in via
+// ReplicationRemediationClusterFilters, and applies configured remediation rules.
+func RemediateBrokenReplication() error {
+	if len(config.Config.ReplicationRemediationClusterFilters) == 0 || len(config.Config.ReplicationRemediationRules) == 0 {
+		return nil
+	}
+	if !atomic.CompareAndSwapInt64(&replicationRemediationRunning, 0, 1) {
+		return nil
+	}
+	defer atomic.StoreInt64(&replicationRemediationRunning, 0)
+
+	instances, err := inst.ReadBrokenReplicationInstances()
+	if err != nil {
+		return log.Errore(err)
+	}
+	clustersInfo := make(map[string]*inst.ClusterInfo)
+	for _, instance := range instances {
+		clusterInfo, found := clustersInfo[instance.ClusterName]
+		if !found {
+			clusterInfo, err = inst.ReadClusterInfo(instance.ClusterName)
+			if err != nil {
+				log.Errore(err)
+				continue
+			}
+			clustersInfo[instance.ClusterName] = clusterInfo
+		}
+		if !clusterInfo.HasAutomatedReplicationRemediation {
+			continue
+		}
+		if getReplicationRemediation(instance) == nil {
+			continue
+		}
+		remediateInstance(&instance.Key, clusterInfo)
+	}
+	return nil
+}
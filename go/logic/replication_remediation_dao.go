@@ -0,0 +1,131 @@
+/*
+   Copyright 2014 Outbrain Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package logic
+
+import (
+	"github.com/outbrain/golib/log"
+	"github.com/outbrain/golib/sqlutils"
+	"github.com/outbrain/orchestrator/go/config"
+	"github.com/outbrain/orchestrator/go/db"
+	"github.com/outbrain/orchestrator/go/inst"
+)
+
+// writeReplicationRemediation records a remediation action taken
+func writeReplicationRemediation(remediation *ReplicationRemediation) error {
+	sqlResult, err := db.ExecOrchestrator(`
+			insert
+				into replication_remediation (
+					hostname,
+					port,
+					cluster_name,
+					remediation_timestamp,
+					replication_thread,
+					error_number,
+					error_message,
+					action,
+					is_successful,
+					result_message
+				) values (
+					?, ?, ?, NOW(), ?, ?, ?, ?, ?, ?
+				)
+			`, remediation.Key.Hostname, remediation.Key.Port, remediation.ClusterName,
+		remediation.ReplicationThread, remediation.ErrorNumber, remediation.ErrorMessage,
+		remediation.Action, remediation.IsSuccessful, remediation.ResultMessage,
+	)
+	if err != nil {
+		return log.Errore(err)
+	}
+	remediation.Id, _ = sqlResult.LastInsertId()
+	return nil
+}
+
+// countRecentReplicationRemediations returns the number of remediation actions taken on given instance
+// within ReplicationRemediationRateLimitMinutes
+func countRecentReplicationRemediations(instanceKey *inst.InstanceKey) (count uint, err error) {
+	query := `
+		select
+			count(*) as count_remediations
+		from
+			replication_remediation
+		where
+			hostname = ?
+			and port = ?
+			and remediation_timestamp >= now() - interval ? minute
+		`
+	err = db.QueryOrchestrator(query, sqlutils.Args(instanceKey.Hostname, instanceKey.Port, config.Config.ReplicationRemediationRateLimitMinutes), func(m sqlutils.RowMap) error {
+		count = m.GetUint("count_remediations")
+		return nil
+	})
+	return count, log.Errore(err)
+}
+
+// ReadReplicationRemediations returns recorded remediation actions, most recent first, optionally filtered by cluster
+func ReadReplicationRemediations(clusterName string, page int) ([]ReplicationRemediation, error) {
+	res := []ReplicationRemediation{}
+	query := `
+		select
+			remediation_id,
+			hostname,
+			port,
+			cluster_name,
+			remediation_timestamp,
+			replication_thread,
+			error_number,
+			error_message,
+			action,
+			is_successful,
+			result_message
+		from
+			replication_remediation
+		where
+			cluster_name like if(? = '', '%', ?)
+		order by
+			remediation_id desc
+		limit ?
+		offset ?
+		`
+	args := sqlutils.Args(clusterName, clusterName, config.Config.AuditPageSize, page*config.Config.AuditPageSize)
+	err := db.QueryOrchestrator(query, args, func(m sqlutils.RowMap) error {
+		remediation := ReplicationRemediation{}
+		remediation.Id = m.GetInt64("remediation_id")
+		remediation.Key.Hostname = m.GetString("hostname")
+		remediation.Key.Port = m.GetInt("port")
+		remediation.ClusterName = m.GetString("cluster_name")
+		remediation.RemediationTimestamp = m.GetString("remediation_timestamp")
+		remediation.ReplicationThread = m.GetString("replication_thread")
+		remediation.ErrorNumber = m.GetUint("error_number")
+		remediation.ErrorMessage = m.GetString("error_message")
+		remediation.Action = m.GetString("action")
+		remediation.IsSuccessful = m.GetBool("is_successful")
+		remediation.ResultMessage = m.GetString("result_message")
+		res = append(res, remediation)
+		return nil
+	})
+	return res, log.Errore(err)
+}
+
+// ExpireReplicationRemediations removes old remediation history
+func ExpireReplicationRemediations() error {
+	_, err := db.ExecOrchestrator(`
+			delete
+				from replication_remediation
+			where
+				remediation_timestamp < NOW() - INTERVAL ? DAY
+			`, config.Config.AuditPurgeDays,
+	)
+	return log.Errore(err)
+}
@@ -0,0 +1,86 @@
+/*
+   Copyright 2014 Outbrain Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package logic
+
+import (
+	"testing"
+
+	test "github.com/outbrain/golib/tests"
+	"github.com/outbrain/orchestrator/go/config"
+	"github.com/outbrain/orchestrator/go/inst"
+)
+
+func TestIsValidRemediationRule(t *testing.T) {
+	test.S(t).ExpectNil(isValidRemediationRule(&config.RemediationRule{Thread: "io", Action: RemediationRestartIOThread}))
+	test.S(t).ExpectNil(isValidRemediationRule(&config.RemediationRule{Thread: "sql", Action: RemediationSkipTransaction, ErrorCodes: []uint{1062}}))
+	test.S(t).ExpectNil(isValidRemediationRule(&config.RemediationRule{Thread: "sql", Action: RemediationDowntime, ErrorPattern: "^Duplicate entry"}))
+	test.S(t).ExpectNotNil(isValidRemediationRule(&config.RemediationRule{Thread: "both", Action: RemediationRestartReplication}))
+	test.S(t).ExpectNotNil(isValidRemediationRule(&config.RemediationRule{Thread: "io", Action: RemediationSkipTransaction}))
+	test.S(t).ExpectNotNil(isValidRemediationRule(&config.RemediationRule{Thread: "sql", Action: "reboot"}))
+	test.S(t).ExpectNotNil(isValidRemediationRule(&config.RemediationRule{Thread: "sql", Action: RemediationDowntime, ErrorPattern: "(unclosed"}))
+}
+
+func TestRemediationRuleMatches(t *testing.T) {
+	rule := &config.RemediationRule{Thread: "sql", ErrorCodes: []uint{1032, 1062}, ErrorPattern: "Duplicate"}
+	test.S(t).ExpectTrue(remediationRuleMatches(rule, "sql", 1062, "Duplicate entry '3' for key 'PRIMARY'"))
+	test.S(t).ExpectFalse(remediationRuleMatches(rule, "io", 1062, "Duplicate entry '3' for key 'PRIMARY'"))
+	test.S(t).ExpectFalse(remediationRuleMatches(rule, "sql", 1146, "Duplicate entry '3' for key 'PRIMARY'"))
+	test.S(t).ExpectFalse(remediationRuleMatches(rule, "sql", 1032, "Can't find record"))
+
+	anyError := &config.RemediationRule{Thread: "io"}
+	test.S(t).ExpectTrue(remediationRuleMatches(anyError, "io", 2003, "error connecting to master"))
+}
+
+func TestGetReplicationRemediation(t *testing.T) {
+	defer func(rules []config.RemediationRule) { config.Config.ReplicationRemediationRules = rules }(config.Config.ReplicationRemediationRules)
+	config.Config.ReplicationRemediationRules = []config.RemediationRule{
+		{Thread: "sql", Action: "no-such-action"},
+		{Thread: "sql", ErrorCodes: []uint{1062}, Action: RemediationSkipTransaction},
+		{Thread: "io", Action: RemediationRestartIOThread},
+	}
+	instance := &inst.Instance{Key: inst.InstanceKey{Hostname: "replica", Port: 3306}, ClusterName: "c1", Slave_SQL_Running: true, Slave_IO_Running: true}
+	test.S(t).ExpectTrue(getReplicationRemediation(instance) == nil)
+
+	instance.Slave_IO_Running = false
+	instance.LastIOErrno = 2003
+	remediation := getReplicationRemediation(instance)
+	test.S(t).ExpectFalse(remediation == nil)
+	test.S(t).ExpectEquals(remediation.Action, RemediationRestartIOThread)
+	test.S(t).ExpectEquals(remediation.ReplicationThread, "io")
+
+	// SQL thread takes precedence
+	instance.Slave_SQL_Running = false
+	instance.LastSQLErrno = 1062
+	remediation = getReplicationRemediation(instance)
+	test.S(t).ExpectEquals(remediation.Action, RemediationSkipTransaction)
+	test.S(t).ExpectEquals(remediation.ErrorNumber, uint(1062))
+	test.S(t).ExpectEquals(remediation.ClusterName, "c1")
+
+	// No matching SQL rule: falls through to IO thread
+	instance.LastSQLErrno = 1146
+	remediation = getReplicationRemediation(instance)
+	test.S(t).ExpectEquals(remediation.Action, RemediationRestartIOThread)
+}
+
+func TestIsRemediationRateLimited(t *testing.T) {
+	defer func(count uint) { config.Config.ReplicationRemediationRateLimitCount = count }(config.Config.ReplicationRemediationRateLimitCount)
+	config.Config.ReplicationRemediationRateLimitCount = 3
+	test.S(t).ExpectFalse(isRemediationRateLimited(2))
+	test.S(t).ExpectTrue(isRemediationRateLimited(3))
+	config.Config.ReplicationRemediationRateLimitCount = 0
+	test.S(t).ExpectFalse(isRemediationRateLimited(100))
+}
@@ -0,0 +1,155 @@
+/*
+   Copyright 2014 Outbrain Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package logic
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/outbrain/orchestrator/go/inst"
+)
+
+const (
+	RollingRestartPending   = "pending"
+	RollingRestartRunning   = "running"
+	RollingRestartCompleted = "completed"
+	RollingRestartFailed    = "failed"
+)
+
+// RollingRestart represents an entry in the rolling_restart table: a restart of all instances of a
+// cluster, one at a time
+type RollingRestart struct {
+	Id                     int64
+	ClusterName            string
+	Owner                  string
+	TakeoverMaster         bool
+	Status                 string
+	Message                string
+	ProcessingNodeHostname string
+	StartTimestamp         string
+	EndTimestamp           string
+	Steps                  []RollingRestartStep
+}
+
+// RollingRestartStep represents an entry in the rolling_restart_step table: the restart of a single instance
+type RollingRestartStep struct {
+	RollingRestartId int64
+	StepOrder        int
+	Key              inst.InstanceKey
+	IsMaster         bool
+	Status           string
+	Message          string
+	StartTimestamp   string
+	EndTimestamp     string
+}
+
+// restartWaitAction is the decision taken on each poll of an instance being waited for after restart
+type restartWaitAction string
+
+const (
+	restartWaitKeepWaiting        restartWaitAction = "keep-waiting"
+	restartWaitStartReplication   restartWaitAction = "start-replication"
+	restartWaitCaughtUp           restartWaitAction = "caught-up"
+	restartWaitReplicationStopped restartWaitAction = "replication-stopped"
+)
+
+// rollingRestartInstancesByDepth sorts instances deepest first
+type rollingRestartInstancesByDepth [](*inst.Instance)
+
+func (this rollingRestartInstancesByDepth) Len() int      { return len(this) }
+func (this rollingRestartInstancesByDepth) Swap(i, j int) { this[i], this[j] = this[j], this[i] }
+func (this rollingRestartInstancesByDepth) Less(i, j int) bool {
+	if this[i].ReplicationDepth != this[j].ReplicationDepth {
+		return this[i].ReplicationDepth > this[j].ReplicationDepth
+	}
+	return this[i].Key.StringCode() < this[j].Key.StringCode()
+}
+
+// newRollingRestartSteps returns the restart order of a cluster's instances: replicas leaf-first, and,
+// when takeoverMaster is requested, the master last.
+func newRollingRestartSteps(instances [](*inst.Instance), masterKey *inst.InstanceKey, takeoverMaster bool) []RollingRestartStep {
+	replicas := [](*inst.Instance){}
+	for _, instance := range instances {
+		if !instance.Key.Equals(masterKey) {
+			replicas = append(replicas, instance)
+		}
+	}
+	sort.Sort(rollingRestartInstancesByDepth(replicas))
+
+	steps := []RollingRestartStep{}
+	for _, replica := range replicas {
+		steps = append(steps, RollingRestartStep{StepOrder: len(steps), Key: replica.Key, Status: RollingRestartPending})
+	}
+	if takeoverMaster {
+		steps = append(steps, RollingRestartStep{StepOrder: len(steps), Key: *masterKey, IsMaster: true, Status: RollingRestartPending})
+	}
+	return steps
+}
+
+// validateRollingRestartTakeover checks that a master can be taken over by a rolling restart. A graceful master
+// takeover requires the master to have exactly one direct replica.
+func validateRollingRestartTakeover(master *inst.Instance) error {
+	if len(master.SlaveHosts) != 1 {
+		return fmt.Errorf("Master %+v must have exactly one direct replica for a rolling restart with takeover; found %d", master.Key, len(master.SlaveHosts))
+	}
+	return nil
+}
+
+// rollingRestartStepFailureMessage describes the failure of a step, noting whether the instance was left downtimed
+func rollingRestartStepFailureMessage(err error, downtimed bool, downtimeMinutes uint) string {
+	if !downtimed {
+		return err.Error()
+	}
+	return fmt.Sprintf("%s; instance is left downtimed for up to %d minutes (end-downtime once it is healthy)", err.Error(), downtimeMinutes)
+}
+
+// getRestartWaitAction decides how waiting goes on for a restarted instance. A replica is caught up only when
+// replicating with known, reasonable lag. Replication is started once; should it stop again, or fail to start,
+// waiting is futile.
+func getRestartWaitAction(instance *inst.Instance, replicationStarted bool) restartWaitAction {
+	if !instance.IsSlave() {
+		return restartWaitCaughtUp
+	}
+	if !instance.SlaveRunning() {
+		if !replicationStarted {
+			return restartWaitStartReplication
+		}
+		if !instance.Slave_SQL_Running || instance.LastIOError != "" || instance.LastSQLError != "" {
+			return restartWaitReplicationStopped
+		}
+		// IO thread still connecting
+		return restartWaitKeepWaiting
+	}
+	if !instance.SecondsBehindMaster.Valid {
+		return restartWaitKeepWaiting
+	}
+	if instance.HasReasonableMaintenanceReplicationLag() {
+		return restartWaitCaughtUp
+	}
+	return restartWaitKeepWaiting
+}
+
+// nextRollingRestartStep returns the first step not yet completed, or nil when all are. A resumed rolling
+// restart thus skips instances already restarted.
+func nextRollingRestartStep(steps []RollingRestartStep) *RollingRestartStep {
+	for i := range steps {
+		if steps[i].Status != RollingRestartCompleted {
+			return &steps[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,478 @@
+/*
+   Copyright 2014 Outbrain Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package logic
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/outbrain/golib/log"
+	"github.com/outbrain/golib/sqlutils"
+	"github.com/outbrain/orchestrator/go/agent"
+	"github.com/outbrain/orchestrator/go/config"
+	"github.com/outbrain/orchestrator/go/db"
+	"github.com/outbrain/orchestrator/go/inst"
+	"github.com/outbrain/orchestrator/go/os"
+	"github.com/outbrain/orchestrator/go/process"
+)
+
+// activeRollingRestarts lists rolling restarts being executed by this process
+var activeRollingRestarts = make(map[int64]bool)
+var activeRollingRestartsMutex = &sync.Mutex{}
+
+// writeRollingRestart persists a new rolling restart along with its steps
+func writeRollingRestart(rollingRestart *RollingRestart) error {
+	sqlResult, err := db.ExecOrchestrator(`
+			insert
+				into rolling_restart (
+					cluster_name, owner, takeover_master, status, message, processing_node_hostname, start_timestamp
+				) values (
+					?, ?, ?, ?, '', ?, NOW()
+				)
+			`, rollingRestart.ClusterName, rollingRestart.Owner, rollingRestart.TakeoverMaster, rollingRestart.Status, process.ThisHostname,
+	)
+	if err != nil {
+		return log.Errore(err)
+	}
+	rollingRestart.Id, err = sqlResult.LastInsertId()
+	if err != nil {
+		return log.Errore(err)
+	}
+	for i := range rollingRestart.Steps {
+		step := &rollingRestart.Steps[i]
+		step.RollingRestartId = rollingRestart.Id
+		_, err := db.ExecOrchestrator(`
+				insert
+					into rolling_restart_step (
+						rolling_restart_id, step_order, hostname, port, is_master, status, message
+					) values (
+						?, ?, ?, ?, ?, ?, ''
+					)
+				`, step.RollingRestartId, step.StepOrder, step.Key.Hostname, step.Key.Port, step.IsMaster, step.Status,
+		)
+		if err != nil {
+			return log.Errore(err)
+		}
+	}
+	return nil
+}
+
+// updateRollingRestartStatus updates status & message of a rolling restart. Final statuses also set its end timestamp.
+func updateRollingRestartStatus(rollingRestart *RollingRestart, status string, message string) error {
+	rollingRestart.Status = status
+	rollingRestart.Message = message
+	_, err := db.ExecOrchestrator(`
+			update
+				rolling_restart
+			set
+				status = ?,
+				message = ?,
+				processing_node_hostname = ?,
+				end_timestamp = if(? in (?, ?), NOW(), NULL)
+			where
+				rolling_restart_id = ?
+			`, status, message, process.ThisHostname, status, RollingRestartCompleted, RollingRestartFailed, rollingRestart.Id,
+	)
+	return log.Errore(err)
+}
+
+// updateRollingRestartStep updates status & message of a rolling restart step
+func updateRollingRestartStep(step *RollingRestartStep, status string, message string) error {
+	step.Status = status
+	step.Message = message
+	_, err := db.ExecOrchestrator(`
+			update
+				rolling_restart_step
+			set
+				status = ?,
+				message = ?,
+				start_timestamp = if(? = ?, IFNULL(start_timestamp, NOW()), start_timestamp),
+				end_timestamp = if(? in (?, ?), NOW(), NULL)
+			where
+				rolling_restart_id = ?
+				and step_order = ?
+			`, status, message, status, RollingRestartRunning, status, RollingRestartCompleted, RollingRestartFailed,
+		step.RollingRestartId, step.StepOrder,
+	)
+	return log.Errore(err)
+}
+
+// readRollingRestartSteps reads the steps of given rolling restart, in execution order
+func readRollingRestartSteps(rollingRestartId int64) ([]RollingRestartStep, error) {
+	res := []RollingRestartStep{}
+	query := `
+		select
+			rolling_restart_id,
+			step_order,
+			hostname,
+			port,
+			is_master,
+			status,
+			message,
+			IFNULL(start_timestamp, '') as start_timestamp,
+			IFNULL(end_timestamp, '') as end_timestamp
+		from
+			rolling_restart_step
+		where
+			rolling_restart_id = ?
+		order by
+			step_order asc
+		`
+	err := db.QueryOrchestrator(query, sqlutils.Args(rollingRestartId), func(m sqlutils.RowMap) error {
+		step := RollingRestartStep{}
+		step.RollingRestartId = m.GetInt64("rolling_restart_id")
+		step.StepOrder = m.GetInt("step_order")
+		step.Key.Hostname = m.GetString("hostname")
+		step.Key.Port = m.GetInt("port")
+		step.IsMaster = m.GetBool("is_master")
+		step.Status = m.GetString("status")
+		step.Message = m.GetString("message")
+		step.StartTimestamp = m.GetString("start_timestamp")
+		step.EndTimestamp = m.GetString("end_timestamp")
+
+		res = append(res, step)
+		return nil
+	})
+	return res, log.Errore(err)
+}
+
+// readRollingRestarts reads rolling restarts by given condition, most recent first
+func readRollingRestarts(whereCondition string, args []interface{}, limit string) ([]RollingRestart, error) {
+	res := []RollingRestart{}
+	query := fmt.Sprintf(`
+		select
+			rolling_restart_id,
+			cluster_name,
+			owner,
+			takeover_master,
+			status,
+			message,
+			processing_node_hostname,
+			start_timestamp,
+			IFNULL(end_timestamp, '') as end_timestamp
+		from
+			rolling_restart
+		%s
+		order by
+			rolling_restart_id desc
+		%s
+		`, whereCondition, limit)
+	err := db.QueryOrchestrator(query, args, func(m sqlutils.RowMap) error {
+		rollingRestart := RollingRestart{}
+		rollingRestart.Id = m.GetInt64("rolling_restart_id")
+		rollingRestart.ClusterName = m.GetString("cluster_name")
+		rollingRestart.Owner = m.GetString("owner")
+		rollingRestart.TakeoverMaster = m.GetBool("takeover_master")
+		rollingRestart.Status = m.GetString("status")
+		rollingRestart.Message = m.GetString("message")
+		rollingRestart.ProcessingNodeHostname = m.GetString("processing_node_hostname")
+		rollingRestart.StartTimestamp = m.GetString("start_timestamp")
+		rollingRestart.EndTimestamp = m.GetString("end_timestamp")
+
+		res = append(res, rollingRestart)
+		return nil
+	})
+	return res, log.Errore(err)
+}
+
+// ReadRollingRestart reads a rolling restart along with its steps
+func ReadRollingRestart(rollingRestartId int64) (*RollingRestart, error) {
+	rollingRestarts, err := readRollingRestarts(`where rolling_restart_id = ?`, sqlutils.Args(rollingRestartId), "")
+	if err != nil {
+		return nil, err
+	}
+	if len(rollingRestarts) == 0 {
+		return nil, fmt.Errorf("Rolling restart %d not found", rollingRestartId)
+	}
+	rollingRestart := &rollingRestarts[0]
+	if rollingRestart.Steps, err = readRollingRestartSteps(rollingRestartId); err != nil {
+		return nil, err
+	}
+	return rollingRestart, nil
+}
+
+// ReadRecentRollingRestarts reads recent rolling restarts, optionally filtered by cluster name
+func ReadRecentRollingRestarts(clusterName string, page int) ([]RollingRestart, error) {
+	whereCondition := ``
+	args := sqlutils.Args()
+	if clusterName != "" {
+		whereCondition = `where cluster_name = ?`
+		args = append(args, clusterName)
+	}
+	limit := fmt.Sprintf(`limit %d offset %d`, config.Config.AuditPageSize, page*config.Config.AuditPageSize)
+	return readRollingRestarts(whereCondition, args, limit)
+}
+
+// replaceRollingRestartPlaceholders replaces the placeholders of RollingRestartCommand
+func replaceRollingRestartPlaceholders(command string, rollingRestart *RollingRestart, instanceKey *inst.InstanceKey) string {
+	command = strings.Replace(command, "{host}", instanceKey.Hostname, -1)
+	command = strings.Replace(command, "{port}", fmt.Sprintf("%d", instanceKey.Port), -1)
+	command = strings.Replace(command, "{clusterName}", rollingRestart.ClusterName, -1)
+	return command
+}
+
+// restartMySQL restarts MySQL on given instance via RollingRestartCommand, or else via orchestrator-agent
+func restartMySQL(rollingRestart *RollingRestart, instanceKey *inst.InstanceKey) error {
+	if config.Config.RollingRestartCommand != "" {
+		command := replaceRollingRestartPlaceholders(config.Config.RollingRestartCommand, rollingRestart, instanceKey)
+		if err := os.CommandRun(command); err != nil {
+			return fmt.Errorf("Failed to execute restart command: %s: %+v", command, err)
+		}
+		log.Infof("Executed restart command: %s", command)
+		return nil
+	}
+	if _, err := agent.MySQLStop(instanceKey.Hostname); err != nil {
+		return err
+	}
+	_, err := agent.MySQLStart(instanceKey.Hostname)
+	return err
+}
+
+// waitForRestartedInstance waits, up to RollingRestartInstanceTimeoutSeconds, for a restarted instance to be
+// reachable, to replicate if it is a replica, and to have reasonable maintenance replication lag
+func waitForRestartedInstance(instanceKey *inst.InstanceKey) error {
+	timeout := time.Duration(config.Config.RollingRestartInstanceTimeoutSeconds) * time.Second
+	startTime := time.Now()
+	slaveStarted := false
+	for {
+		instance, err := inst.ReadTopologyInstanceUnbuffered(instanceKey)
+		if err == nil {
+			switch getRestartWaitAction(instance, slaveStarted) {
+			case restartWaitStartReplication:
+				if _, err := inst.StartSlave(instanceKey); err != nil {
+					return err
+				}
+				slaveStarted = true
+			case restartWaitReplicationStopped:
+				return fmt.Errorf("Replication on %+v stopped after restart: %s %s", *instanceKey, instance.LastIOError, instance.LastSQLError)
+			case restartWaitCaughtUp:
+				return nil
+			}
+		}
+		if time.Since(startTime) >= timeout {
+			if err != nil {
+				return fmt.Errorf("Instance %+v did not return after restart: %+v", *instanceKey, err)
+			}
+			return fmt.Errorf("Instance %+v did not catch up after restart", *instanceKey)
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+// takeoverRollingRestartMaster gracefully hands mastership over to the master's single replica, and
+// points the former master below it at the position the takeover took place. When the instance is no
+// longer the master (e.g. takeover completed on a previous run of a resumed rolling restart) this is a no-op.
+func takeoverRollingRestartMaster(clusterLock *inst.ClusterLockToken, rollingRestart *RollingRestart, instanceKey *inst.InstanceKey) error {
+	masters, err := inst.ReadClusterWriteableMaster(rollingRestart.ClusterName)
+	if err != nil {
+		return err
+	}
+	if len(masters) != 1 || !masters[0].Key.Equals(instanceKey) {
+		return nil
+	}
+	topologyRecovery, promotedMasterCoordinates, err := gracefulMasterTakeover(rollingRestart.ClusterName, clusterLock)
+	if err != nil {
+		return err
+	}
+	if _, err := inst.ChangeMasterTo(instanceKey, topologyRecovery.SuccessorKey, promotedMasterCoordinates, false, inst.GTIDHintNeutral); err != nil {
+		return err
+	}
+	inst.AuditOperation("rolling-restart", instanceKey, fmt.Sprintf("Master taken over by %+v", *topologyRecovery.SuccessorKey))
+	return nil
+}
+
+// executeRollingRestartStep downtimes, restarts and waits for a single instance. It returns whether the
+// instance is left downtimed.
+func executeRollingRestartStep(clusterLock *inst.ClusterLockToken, rollingRestart *RollingRestart, step *RollingRestartStep) (downtimed bool, err error) {
+	if err := clusterLock.Err(); err != nil {
+		return false, err
+	}
+	instanceKey := &step.Key
+	if step.IsMaster {
+		updateRollingRestartStep(step, RollingRestartRunning, "Taking over master")
+		if err := takeoverRollingRestartMaster(clusterLock, rollingRestart, instanceKey); err != nil {
+			return false, err
+		}
+	}
+	instance, err := inst.ReadTopologyInstanceUnbuffered(instanceKey)
+	if err != nil {
+		return false, err
+	}
+	reason := fmt.Sprintf("rolling-restart %d", rollingRestart.Id)
+	if err := inst.BeginDowntime(instanceKey, rollingRestart.Owner, reason, config.Config.RollingRestartDowntimeMinutes*60); err != nil {
+		return false, err
+	}
+	if instance.IsSlave() {
+		updateRollingRestartStep(step, RollingRestartRunning, "Stopping replication")
+		if _, err := inst.StopSlave(instanceKey); err != nil {
+			return true, err
+		}
+	}
+	updateRollingRestartStep(step, RollingRestartRunning, "Restarting MySQL")
+	if err := restartMySQL(rollingRestart, instanceKey); err != nil {
+		return true, err
+	}
+	updateRollingRestartStep(step, RollingRestartRunning, "Waiting for instance to return and catch up")
+	if err := waitForRestartedInstance(instanceKey); err != nil {
+		return true, err
+	}
+	if err := inst.EndDowntime(instanceKey); err != nil {
+		return true, err
+	}
+	inst.AuditOperation("rolling-restart", instanceKey, fmt.Sprintf("Restarted by %s", reason))
+	return false, nil
+}
+
+// executeRollingRestart runs the pending steps of a rolling restart, stopping on first failure.
+// The cluster lock is expected to be held, and is released by this function.
+func executeRollingRestart(clusterLock *inst.ClusterLockToken, rollingRestart *RollingRestart) {
+	defer clusterLock.Release()
+	defer func() {
+		activeRollingRestartsMutex.Lock()
+		defer activeRollingRestartsMutex.Unlock()
+		delete(activeRollingRestarts, rollingRestart.Id)
+	}()
+
+	updateRollingRestartStatus(rollingRestart, RollingRestartRunning, "")
+	for step := nextRollingRestartStep(rollingRestart.Steps); step != nil; step = nextRollingRestartStep(rollingRestart.Steps) {
+		updateRollingRestartStep(step, RollingRestartRunning, "")
+		if downtimed, err := executeRollingRestartStep(clusterLock, rollingRestart, step); err != nil {
+			message := rollingRestartStepFailureMessage(err, downtimed, config.Config.RollingRestartDowntimeMinutes)
+			log.Errorf("Rolling restart %d failed on %+v: %s", rollingRestart.Id, step.Key, message)
+			updateRollingRestartStep(step, RollingRestartFailed, message)
+			updateRollingRestartStatus(rollingRestart, RollingRestartFailed, fmt.Sprintf("%+v: %s", step.Key, message))
+			inst.AuditOperation("rolling-restart", &step.Key, fmt.Sprintf("Rolling restart %d failed: %s", rollingRestart.Id, message))
+			return
+		}
+		updateRollingRestartStep(step, RollingRestartCompleted, "")
+	}
+	updateRollingRestartStatus(rollingRestart, RollingRestartCompleted, "")
+	inst.AuditOperation("rolling-restart", nil, fmt.Sprintf("Completed rolling restart %d of %s", rollingRestart.Id, rollingRestart.ClusterName))
+}
+
+// acquireRollingRestart marks the rolling restart as running in this process and takes the cluster lock
+func acquireRollingRestart(rollingRestart *RollingRestart) (*inst.ClusterLockToken, error) {
+	activeRollingRestartsMutex.Lock()
+	defer activeRollingRestartsMutex.Unlock()
+
+	if activeRollingRestarts[rollingRestart.Id] {
+		return nil, fmt.Errorf("Rolling restart %d is already running", rollingRestart.Id)
+	}
+	clusterLock, err := inst.AcquireClusterLock(rollingRestart.ClusterName, rollingRestart.Owner, fmt.Sprintf("rolling-restart %d", rollingRestart.Id), nil)
+	if err != nil {
+		return nil, err
+	}
+	activeRollingRestarts[rollingRestart.Id] = true
+	return clusterLock, nil
+}
+
+// launchRollingRestart takes the cluster lock and runs the rolling restart in the background
+func launchRollingRestart(rollingRestart *RollingRestart) error {
+	clusterLock, err := acquireRollingRestart(rollingRestart)
+	if err != nil {
+		return err
+	}
+	go executeRollingRestart(clusterLock, rollingRestart)
+	return nil
+}
+
+// newRollingRestart plans and persists a rolling restart of given cluster
+func newRollingRestart(clusterName string, owner string, takeoverMaster bool) (*RollingRestart, error) {
+	masters, err := inst.ReadClusterWriteableMaster(clusterName)
+	if err != nil {
+		return nil, err
+	}
+	if len(masters) != 1 {
+		return nil, fmt.Errorf("Cannot deduce cluster master for %+v. Found %+v potential masters", clusterName, len(masters))
+	}
+	if takeoverMaster {
+		if err := validateRollingRestartTakeover(masters[0]); err != nil {
+			return nil, err
+		}
+	}
+	instances, err := inst.ReadClusterInstances(clusterName)
+	if err != nil {
+		return nil, err
+	}
+	rollingRestart := &RollingRestart{
+		ClusterName:    clusterName,
+		Owner:          owner,
+		TakeoverMaster: takeoverMaster,
+		Status:         RollingRestartPending,
+		Steps:          newRollingRestartSteps(instances, &masters[0].Key, takeoverMaster),
+	}
+	if len(rollingRestart.Steps) == 0 {
+		return nil, fmt.Errorf("No instances to restart in cluster %s", clusterName)
+	}
+	if err := writeRollingRestart(rollingRestart); err != nil {
+		return nil, err
+	}
+	inst.AuditOperation("rolling-restart", &masters[0].Key, fmt.Sprintf("Began rolling restart %d of %s; %d instances, takeover master: %t", rollingRestart.Id, clusterName, len(rollingRestart.Steps), takeoverMaster))
+	return rollingRestart, nil
+}
+
+// BeginRollingRestart begins a restart of all instances of given cluster, one at a time and leaf-first. With
+// takeoverMaster, it ends with a graceful master takeover, after which the former master is restarted.
+// The restart runs in the background; its progress is readable via ReadRollingRestart.
+func BeginRollingRestart(clusterName string, owner string, takeoverMaster bool) (*RollingRestart, error) {
+	rollingRestart, err := newRollingRestart(clusterName, owner, takeoverMaster)
+	if err != nil {
+		return nil, err
+	}
+	if err := launchRollingRestart(rollingRestart); err != nil {
+		updateRollingRestartStatus(rollingRestart, RollingRestartFailed, err.Error())
+		return rollingRestart, err
+	}
+	return rollingRestart, nil
+}
+
+// ResumeRollingRestart runs a rolling restart anew, skipping instances already restarted
+func ResumeRollingRestart(rollingRestartId int64) (*RollingRestart, error) {
+	rollingRestart, err := ReadRollingRestart(rollingRestartId)
+	if err != nil {
+		return nil, err
+	}
+	if rollingRestart.Status == RollingRestartCompleted {
+		return rollingRestart, fmt.Errorf("Rolling restart %d is already completed", rollingRestartId)
+	}
+	if err := launchRollingRestart(rollingRestart); err != nil {
+		return rollingRestart, err
+	}
+	inst.AuditOperation("rolling-restart", nil, fmt.Sprintf("Resumed rolling restart %d of %s", rollingRestart.Id, rollingRestart.ClusterName))
+	return rollingRestart, nil
+}
+
+// RollingRestartAndWait runs a rolling restart of given cluster, as BeginRollingRestart, and waits for it to
+// complete or fail
+func RollingRestartAndWait(clusterName string, owner string, takeoverMaster bool) (*RollingRestart, error) {
+	rollingRestart, err := newRollingRestart(clusterName, owner, takeoverMaster)
+	if err != nil {
+		return nil, err
+	}
+	clusterLock, err := acquireRollingRestart(rollingRestart)
+	if err != nil {
+		updateRollingRestartStatus(rollingRestart, RollingRestartFailed, err.Error())
+		return rollingRestart, err
+	}
+	executeRollingRestart(clusterLock, rollingRestart)
+	if rollingRestart.Status != RollingRestartCompleted {
+		return rollingRestart, fmt.Errorf("Rolling restart %d failed: %s", rollingRestart.Id, rollingRestart.Message)
+	}
+	return rollingRestart, nil
+}
@@ -0,0 +1,135 @@
+/*
+   Copyright 2014 Outbrain Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package logic
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	test "github.com/outbrain/golib/tests"
+	"github.com/outbrain/orchestrator/go/config"
+	"github.com/outbrain/orchestrator/go/inst"
+)
+
+func newRollingRestartTestInstance(hostname string, depth uint) *inst.Instance {
+	instance := inst.NewInstance()
+	instance.Key = inst.InstanceKey{Hostname: hostname, Port: 3306}
+	instance.ReplicationDepth = depth
+	return instance
+}
+
+func TestNewRollingRestartSteps(t *testing.T) {
+	master := newRollingRestartTestInstance("master", 0)
+	instances := [](*inst.Instance){
+		newRollingRestartTestInstance("replica-b", 1),
+		master,
+		newRollingRestartTestInstance("leaf-b", 2),
+		newRollingRestartTestInstance("replica-a", 1),
+		newRollingRestartTestInstance("leaf-a", 2),
+	}
+	expectOrder := func(steps []RollingRestartStep, hostnames ...string) {
+		test.S(t).ExpectEquals(len(steps), len(hostnames))
+		for i, step := range steps {
+			test.S(t).ExpectEquals(step.StepOrder, i)
+			test.S(t).ExpectEquals(step.Key.Hostname, hostnames[i])
+			test.S(t).ExpectEquals(step.IsMaster, hostnames[i] == "master")
+			test.S(t).ExpectEquals(step.Status, RollingRestartPending)
+		}
+	}
+
+	expectOrder(newRollingRestartSteps(instances, &master.Key, false), "leaf-a", "leaf-b", "replica-a", "replica-b")
+	expectOrder(newRollingRestartSteps(instances, &master.Key, true), "leaf-a", "leaf-b", "replica-a", "replica-b", "master")
+	expectOrder(newRollingRestartSteps([](*inst.Instance){master}, &master.Key, false))
+}
+
+func TestValidateRollingRestartTakeover(t *testing.T) {
+	master := newRollingRestartTestInstance("master", 0)
+	test.S(t).ExpectNotNil(validateRollingRestartTakeover(master))
+
+	master.SlaveHosts.AddKey(inst.InstanceKey{Hostname: "replica-a", Port: 3306})
+	test.S(t).ExpectNil(validateRollingRestartTakeover(master))
+
+	master.SlaveHosts.AddKey(inst.InstanceKey{Hostname: "replica-b", Port: 3306})
+	test.S(t).ExpectNotNil(validateRollingRestartTakeover(master))
+}
+
+func TestRollingRestartStepFailureMessage(t *testing.T) {
+	err := errors.New("restart failed")
+	test.S(t).ExpectEquals(rollingRestartStepFailureMessage(err, false, 30), "restart failed")
+
+	message := rollingRestartStepFailureMessage(err, true, 30)
+	test.S(t).ExpectTrue(strings.HasPrefix(message, "restart failed; "))
+	test.S(t).ExpectTrue(strings.Contains(message, "downtimed for up to 30 minutes"))
+}
+
+func TestNextRollingRestartStep(t *testing.T) {
+	steps := []RollingRestartStep{
+		{StepOrder: 0, Status: RollingRestartCompleted},
+		{StepOrder: 1, Status: RollingRestartCompleted},
+		{StepOrder: 2, Status: RollingRestartFailed},
+		{StepOrder: 3, Status: RollingRestartPending},
+	}
+	// A resumed rolling restart picks up at the failed step
+	step := nextRollingRestartStep(steps)
+	test.S(t).ExpectEquals(step.StepOrder, 2)
+
+	step.Status = RollingRestartCompleted
+	step = nextRollingRestartStep(steps)
+	test.S(t).ExpectEquals(step.StepOrder, 3)
+
+	step.Status = RollingRestartCompleted
+	test.S(t).ExpectTrue(nextRollingRestartStep(steps) == nil)
+	test.S(t).ExpectTrue(nextRollingRestartStep([]RollingRestartStep{}) == nil)
+}
+
+func TestGetRestartWaitAction(t *testing.T) {
+	defer func(lag int) {
+		config.Config.ReasonableMaintenanceReplicationLagSeconds = lag
+	}(config.Config.ReasonableMaintenanceReplicationLagSeconds)
+	config.Config.ReasonableMaintenanceReplicationLagSeconds = 20
+
+	newReplica := func(ioRunning bool, sqlRunning bool, lag int64, lagKnown bool) *inst.Instance {
+		replica := newRollingRestartTestInstance("replica", 1)
+		replica.MasterKey = inst.InstanceKey{Hostname: "master", Port: 3306}
+		replica.ReadBinlogCoordinates = inst.BinlogCoordinates{LogFile: "mysql-bin.000001", LogPos: 4}
+		replica.Slave_IO_Running = ioRunning
+		replica.Slave_SQL_Running = sqlRunning
+		replica.SecondsBehindMaster.Int64 = lag
+		replica.SecondsBehindMaster.Valid = lagKnown
+		return replica
+	}
+	expectAction := func(instance *inst.Instance, replicationStarted bool, expected restartWaitAction) {
+		test.S(t).ExpectEquals(string(getRestartWaitAction(instance, replicationStarted)), string(expected))
+	}
+
+	expectAction(newRollingRestartTestInstance("master", 0), false, restartWaitCaughtUp)
+
+	stopped := newReplica(false, false, 0, false)
+	expectAction(stopped, false, restartWaitStartReplication)
+	expectAction(stopped, true, restartWaitReplicationStopped)
+
+	connecting := newReplica(false, true, 0, false)
+	expectAction(connecting, true, restartWaitKeepWaiting)
+	connecting.LastIOError = "error connecting to master"
+	expectAction(connecting, true, restartWaitReplicationStopped)
+
+	expectAction(newReplica(true, true, 0, false), true, restartWaitKeepWaiting)
+	expectAction(newReplica(true, true, 300, true), true, restartWaitKeepWaiting)
+	expectAction(newReplica(true, true, 5, true), true, restartWaitCaughtUp)
+	expectAction(newReplica(true, true, 5, true), false, restartWaitCaughtUp)
+}
@@ -65,6 +65,7 @@ type TopologyRecovery struct {
 	AcknowledgedComment       string
 	LastDetectionId           int64
 	RelatedRecoveryId         int64
+	clusterLock               *inst.ClusterLockToken
 }
 
 func NewTopologyRecovery(replicationAnalysis inst.ReplicationAnalysis) *TopologyRecovery {
@@ -202,7 +203,7 @@ func recoverDeadMasterInBinlogServerTopology(topologyRecovery *TopologyRecovery)
 
 	var promotedBinlogServer *inst.Instance
 
-	_, promotedBinlogServer, err = inst.RegroupSlavesBinlogServers(failedMasterKey, true)
+	_, promotedBinlogServer, err = inst.RegroupSlavesBinlogServersUnderLock(topologyRecovery.clusterLock, failedMasterKey, true)
 	if err != nil {
 		return nil, log.Errore(err)
 	}
@@ -250,7 +251,7 @@ func recoverDeadMasterInBinlogServerTopology(topologyRecovery *TopologyRecovery)
 	if err != nil {
 		return promotedSlave, log.Errore(err)
 	}
-	promotedBinlogServer, err = inst.Repoint(&promotedBinlogServer.Key, &promotedSlave.Key, inst.GTIDHintDeny)
+	promotedBinlogServer, err = inst.RepointUnderLock(topologyRecovery.clusterLock, &promotedBinlogServer.Key, &promotedSlave.Key, inst.GTIDHintDeny)
 	if err != nil {
 		return nil, log.Errore(err)
 	}
@@ -292,6 +293,20 @@ func recoverDeadMasterInBinlogServerTopology(topologyRecovery *TopologyRecovery)
 	return promotedSlave, err
 }
 
+// acquireRecoveryClusterLock takes the operation lock on the cluster under recovery, such that no
+// concurrent refactoring takes place on the cluster while it is being recovered. The lock is taken before
+// the recovery is registered: a recovery which cannot lock the cluster is not attempted, and does not
+// block a later attempt.
+func acquireRecoveryClusterLock(analysisEntry *inst.ReplicationAnalysis, recoveryName string, heldLock *inst.ClusterLockToken) (*inst.ClusterLockToken, error) {
+	reason := fmt.Sprintf("%s: %s on %+v", recoveryName, analysisEntry.Analysis, analysisEntry.AnalyzedInstanceKey)
+	clusterLock, err := inst.AcquireClusterLock(analysisEntry.ClusterDetails.ClusterName, "recovery", reason, heldLock)
+	if err != nil {
+		inst.AuditOperation(recoveryName, &analysisEntry.AnalyzedInstanceKey, fmt.Sprintf("cannot recover: %+v", err))
+		return nil, log.Errore(err)
+	}
+	return clusterLock, nil
+}
+
 // RecoverDeadMaster recovers a dead master, complete logic inside
 func RecoverDeadMaster(topologyRecovery *TopologyRecovery, skipProcesses bool) (promotedSlave *inst.Instance, lostSlaves [](*inst.Instance), err error) {
 	analysisEntry := &topologyRecovery.AnalysisEntry
@@ -318,11 +333,11 @@ func RecoverDeadMaster(topologyRecovery *TopologyRecovery, skipProcesses bool) (
 	switch masterRecoveryType {
 	case MasterRecoveryGTID:
 		{
-			lostSlaves, _, cannotReplicateSlaves, promotedSlave, err = inst.RegroupSlavesGTID(failedInstanceKey, true, nil)
+			lostSlaves, _, cannotReplicateSlaves, promotedSlave, err = inst.RegroupSlavesGTIDUnderLock(topologyRecovery.clusterLock, failedInstanceKey, true, nil)
 		}
 	case MasterRecoveryPseudoGTID:
 		{
-			lostSlaves, _, _, cannotReplicateSlaves, promotedSlave, err = inst.RegroupSlavesPseudoGTIDIncludingSubSlavesOfBinlogServers(failedInstanceKey, true, nil, &topologyRecovery.PostponedFunctionsContainer)
+			lostSlaves, _, _, cannotReplicateSlaves, promotedSlave, err = inst.RegroupSlavesPseudoGTIDIncludingSubSlavesOfBinlogServersUnderLock(topologyRecovery.clusterLock, failedInstanceKey, true, nil, &topologyRecovery.PostponedFunctionsContainer)
 		}
 	case MasterRecoveryBinlogServer:
 		{
@@ -367,7 +382,7 @@ func RecoverDeadMaster(topologyRecovery *TopologyRecovery, skipProcesses bool) (
 // But, is there an even better slave to promote?
 // if candidateInstanceKey is given, then it is forced to be promoted over the promotedSlave
 // Otherwise, search for the best to promote!
-func replacePromotedSlaveWithCandidate(deadInstanceKey *inst.InstanceKey, promotedSlave *inst.Instance, candidateInstanceKey *inst.InstanceKey) (*inst.Instance, error) {
+func replacePromotedSlaveWithCandidate(clusterLock *inst.ClusterLockToken, deadInstanceKey *inst.InstanceKey, promotedSlave *inst.Instance, candidateInstanceKey *inst.InstanceKey) (*inst.Instance, error) {
 	candidateSlaves, _ := inst.ReadClusterCandidateInstances(promotedSlave.ClusterName)
 	// So we've already promoted a slave.
 	// However, can we improve on our choice? Are there any slaves marked with "is_candidate"?
@@ -450,7 +465,7 @@ func replacePromotedSlaveWithCandidate(deadInstanceKey *inst.InstanceKey, promot
 
 	if candidateInstance.MasterKey.Equals(&promotedSlave.Key) {
 		log.Debugf("topology_recovery: suggested candidate %+v is slave of promoted instance %+v. Will try and enslave its master", *candidateInstanceKey, promotedSlave.Key)
-		candidateInstance, err = inst.EnslaveMaster(&candidateInstance.Key)
+		candidateInstance, err = inst.EnslaveMasterUnderLock(clusterLock, &candidateInstance.Key)
 		if err != nil {
 			return promotedSlave, log.Errore(err)
 		}
@@ -464,15 +479,32 @@ func replacePromotedSlaveWithCandidate(deadInstanceKey *inst.InstanceKey, promot
 
 // checkAndRecoverDeadMaster checks a given analysis, decides whether to take action, and possibly takes action
 // Returns true when action was taken.
-func checkAndRecoverDeadMaster(analysisEntry inst.ReplicationAnalysis, candidateInstanceKey *inst.InstanceKey, forceInstanceRecovery bool, skipProcesses bool) (bool, *TopologyRecovery, error) {
+func checkAndRecoverDeadMaster(analysisEntry inst.ReplicationAnalysis, candidateInstanceKey *inst.InstanceKey, forceInstanceRecovery bool, skipProcesses bool, heldLock *inst.ClusterLockToken) (bool, *TopologyRecovery, error) {
 	if !(forceInstanceRecovery || analysisEntry.ClusterDetails.HasAutomatedMasterRecovery) {
 		return false, nil, nil
 	}
+	if !forceInstanceRecovery {
+		if confirmed, err := ConfirmFailureByQuorum(&analysisEntry); !confirmed {
+			log.Infof("topology_recovery: failure of %+v not confirmed by quorum. Will not issue RecoverDeadMaster.", analysisEntry.AnalyzedInstanceKey)
+			return false, nil, err
+		}
+		if !VerifyFailureWithAgent(&analysisEntry) {
+			log.Infof("topology_recovery: agent on %+v indicates failure is not to be recovered. Will not issue RecoverDeadMaster.", analysisEntry.AnalyzedInstanceKey)
+			return false, nil, nil
+		}
+	}
+	clusterLock, err := acquireRecoveryClusterLock(&analysisEntry, "recover-dead-master", heldLock)
+	if err != nil {
+		return false, nil, err
+	}
+	defer clusterLock.Release()
+
 	topologyRecovery, err := AttemptRecoveryRegistration(&analysisEntry, !forceInstanceRecovery, !forceInstanceRecovery)
 	if topologyRecovery == nil {
 		log.Debugf("topology_recovery: found an active or recent recovery on %+v. Will not issue another RecoverDeadMaster.", analysisEntry.AnalyzedInstanceKey)
 		return false, nil, err
 	}
+	topologyRecovery.clusterLock = clusterLock
 
 	// That's it! We must do recovery!
 	log.Debugf("topology_recovery: will handle DeadMaster event on %+v", analysisEntry.ClusterDetails.ClusterName)
@@ -481,7 +513,7 @@ func checkAndRecoverDeadMaster(analysisEntry inst.ReplicationAnalysis, candidate
 	topologyRecovery.LostSlaves.AddInstances(lostSlaves)
 
 	if promotedSlave != nil {
-		promotedSlave, err = replacePromotedSlaveWithCandidate(&analysisEntry.AnalyzedInstanceKey, promotedSlave, candidateInstanceKey)
+		promotedSlave, err = replacePromotedSlaveWithCandidate(clusterLock, &analysisEntry.AnalyzedInstanceKey, promotedSlave, candidateInstanceKey)
 		topologyRecovery.AddError(err)
 	}
 	// And this is the end; whether successful or not, we're done.
@@ -492,7 +524,7 @@ func checkAndRecoverDeadMaster(analysisEntry inst.ReplicationAnalysis, candidate
 
 		if config.Config.ApplyMySQLPromotionAfterMasterFailover {
 			log.Debugf("topology_recovery: - RecoverDeadMaster: will apply MySQL changes to promoted master")
-			inst.ResetSlaveOperation(&promotedSlave.Key)
+			inst.ResetSlaveOperationUnderLock(clusterLock, &promotedSlave.Key)
 			inst.SetReadOnly(&promotedSlave.Key, false)
 		}
 		if !skipProcesses {
@@ -647,7 +679,7 @@ func RecoverDeadIntermediateMaster(topologyRecovery *TopologyRecovery, skipProce
 		}
 		// We have a candidate
 		log.Debugf("topology_recovery: - RecoverDeadIntermediateMaster: will attempt a candidate intermediate master: %+v", candidateSiblingOfIntermediateMaster.Key)
-		relocatedSlaves, candidateSibling, err, errs := inst.RelocateSlaves(failedInstanceKey, &candidateSiblingOfIntermediateMaster.Key, "")
+		relocatedSlaves, candidateSibling, err, errs := inst.RelocateSlavesUnderLock(topologyRecovery.clusterLock, failedInstanceKey, &candidateSiblingOfIntermediateMaster.Key, "")
 		topologyRecovery.AddErrors(errs)
 		topologyRecovery.ParticipatingInstanceKeys.AddKey(candidateSiblingOfIntermediateMaster.Key)
 
@@ -673,7 +705,7 @@ func RecoverDeadIntermediateMaster(topologyRecovery *TopologyRecovery, skipProce
 	if !recoveryResolved {
 		log.Debugf("topology_recovery: - RecoverDeadIntermediateMaster: will next attempt regrouping of slaves")
 		// Plan B: regroup (we wish to reduce cross-DC replication streams)
-		_, _, _, _, regroupPromotedSlave, err := inst.RegroupSlaves(failedInstanceKey, true, nil, nil)
+		_, _, _, _, regroupPromotedSlave, err := inst.RegroupSlavesUnderLock(topologyRecovery.clusterLock, failedInstanceKey, true, nil, nil)
 		if err != nil {
 			topologyRecovery.AddError(err)
 			log.Debugf("topology_recovery: - RecoverDeadIntermediateMaster: regroup failed on: %+v", err)
@@ -698,7 +730,7 @@ func RecoverDeadIntermediateMaster(topologyRecovery *TopologyRecovery, skipProce
 
 		var errs []error
 		var relocatedSlaves [](*inst.Instance)
-		relocatedSlaves, successorInstance, err, errs = inst.RelocateSlaves(failedInstanceKey, &analysisEntry.AnalyzedInstanceMasterKey, "")
+		relocatedSlaves, successorInstance, err, errs = inst.RelocateSlavesUnderLock(topologyRecovery.clusterLock, failedInstanceKey, &analysisEntry.AnalyzedInstanceMasterKey, "")
 		topologyRecovery.AddErrors(errs)
 		topologyRecovery.ParticipatingInstanceKeys.AddKey(analysisEntry.AnalyzedInstanceMasterKey)
 
@@ -719,15 +751,22 @@ func RecoverDeadIntermediateMaster(topologyRecovery *TopologyRecovery, skipProce
 
 // checkAndRecoverDeadIntermediateMaster checks a given analysis, decides whether to take action, and possibly takes action
 // Returns true when action was taken.
-func checkAndRecoverDeadIntermediateMaster(analysisEntry inst.ReplicationAnalysis, candidateInstanceKey *inst.InstanceKey, forceInstanceRecovery bool, skipProcesses bool) (bool, *TopologyRecovery, error) {
+func checkAndRecoverDeadIntermediateMaster(analysisEntry inst.ReplicationAnalysis, candidateInstanceKey *inst.InstanceKey, forceInstanceRecovery bool, skipProcesses bool, heldLock *inst.ClusterLockToken) (bool, *TopologyRecovery, error) {
 	if !(forceInstanceRecovery || analysisEntry.ClusterDetails.HasAutomatedIntermediateMasterRecovery) {
 		return false, nil, nil
 	}
+	clusterLock, err := acquireRecoveryClusterLock(&analysisEntry, "recover-dead-intermediate-master", heldLock)
+	if err != nil {
+		return false, nil, err
+	}
+	defer clusterLock.Release()
+
 	topologyRecovery, err := AttemptRecoveryRegistration(&analysisEntry, !forceInstanceRecovery, !forceInstanceRecovery)
 	if topologyRecovery == nil {
 		log.Debugf("topology_recovery: found an active or recent recovery on %+v. Will not issue another RecoverDeadIntermediateMaster.", analysisEntry.AnalyzedInstanceKey)
 		return false, nil, err
 	}
+	topologyRecovery.clusterLock = clusterLock
 
 	// That's it! We must do recovery!
 	recoverDeadIntermediateMasterCounter.Inc(1)
@@ -757,6 +796,7 @@ func RecoverDeadCoMaster(topologyRecovery *TopologyRecovery, skipProcesses bool)
 	if otherCoMaster == nil || !found {
 		return nil, lostSlaves, topologyRecovery.AddError(log.Errorf("RecoverDeadCoMaster: could not read info for co-master %+v of %+v", *otherCoMasterKey, *failedInstanceKey))
 	}
+
 	inst.AuditOperation("recover-dead-co-master", failedInstanceKey, "problem found; will recover")
 	if !skipProcesses {
 		if err := executeProcesses(config.Config.PreFailoverProcesses, "PreFailoverProcesses", topologyRecovery, true); err != nil {
@@ -777,11 +817,11 @@ func RecoverDeadCoMaster(topologyRecovery *TopologyRecovery, skipProcesses bool)
 	switch coMasterRecoveryType {
 	case MasterRecoveryGTID:
 		{
-			lostSlaves, _, cannotReplicateSlaves, promotedSlave, err = inst.RegroupSlavesGTID(failedInstanceKey, true, nil)
+			lostSlaves, _, cannotReplicateSlaves, promotedSlave, err = inst.RegroupSlavesGTIDUnderLock(topologyRecovery.clusterLock, failedInstanceKey, true, nil)
 		}
 	case MasterRecoveryPseudoGTID:
 		{
-			lostSlaves, _, _, cannotReplicateSlaves, promotedSlave, err = inst.RegroupSlavesPseudoGTIDIncludingSubSlavesOfBinlogServers(failedInstanceKey, true, nil, &topologyRecovery.PostponedFunctionsContainer)
+			lostSlaves, _, _, cannotReplicateSlaves, promotedSlave, err = inst.RegroupSlavesPseudoGTIDIncludingSubSlavesOfBinlogServersUnderLock(topologyRecovery.clusterLock, failedInstanceKey, true, nil, &topologyRecovery.PostponedFunctionsContainer)
 		}
 	}
 	topologyRecovery.AddError(err)
@@ -798,15 +838,15 @@ func RecoverDeadCoMaster(topologyRecovery *TopologyRecovery, skipProcesses bool)
 		topologyRecovery.ParticipatingInstanceKeys.AddKey(promotedSlave.Key)
 		if mustPromoteOtherCoMaster {
 			log.Debugf("topology_recovery: mustPromoteOtherCoMaster. Verifying that %+v is/can be promoted", *otherCoMasterKey)
-			promotedSlave, err = replacePromotedSlaveWithCandidate(failedInstanceKey, promotedSlave, otherCoMasterKey)
+			promotedSlave, err = replacePromotedSlaveWithCandidate(topologyRecovery.clusterLock, failedInstanceKey, promotedSlave, otherCoMasterKey)
 		} else {
 			// We are allowed to promote any server
-			promotedSlave, err = replacePromotedSlaveWithCandidate(failedInstanceKey, promotedSlave, nil)
+			promotedSlave, err = replacePromotedSlaveWithCandidate(topologyRecovery.clusterLock, failedInstanceKey, promotedSlave, nil)
 
 			if promotedSlave.DataCenter == otherCoMaster.DataCenter &&
 				promotedSlave.PhysicalEnvironment == otherCoMaster.PhysicalEnvironment && false {
 				// and _still_ we prefer to promote the co-master! They're in same env & DC so no worries about geo issues!
-				promotedSlave, err = replacePromotedSlaveWithCandidate(failedInstanceKey, promotedSlave, otherCoMasterKey)
+				promotedSlave, err = replacePromotedSlaveWithCandidate(topologyRecovery.clusterLock, failedInstanceKey, promotedSlave, otherCoMasterKey)
 			}
 		}
 		topologyRecovery.AddError(err)
@@ -836,7 +876,7 @@ func RecoverDeadCoMaster(topologyRecovery *TopologyRecovery, skipProcesses bool)
 	// but we want to make sure the circle is broken no matter what.
 	// So in the case we promoted not-the-other-co-master, we issue a detach-slave-master-host, which is a reversible operation
 	if promotedSlave != nil && !promotedSlave.Key.Equals(otherCoMasterKey) {
-		_, err = inst.DetachSlaveMasterHost(&promotedSlave.Key)
+		_, err = inst.DetachSlaveMasterHostUnderLock(topologyRecovery.clusterLock, &promotedSlave.Key)
 		topologyRecovery.AddError(log.Errore(err))
 	}
 
@@ -868,16 +908,33 @@ func RecoverDeadCoMaster(topologyRecovery *TopologyRecovery, skipProcesses bool)
 
 // checkAndRecoverDeadCoMaster checks a given analysis, decides whether to take action, and possibly takes action
 // Returns true when action was taken.
-func checkAndRecoverDeadCoMaster(analysisEntry inst.ReplicationAnalysis, candidateInstanceKey *inst.InstanceKey, forceInstanceRecovery bool, skipProcesses bool) (bool, *TopologyRecovery, error) {
+func checkAndRecoverDeadCoMaster(analysisEntry inst.ReplicationAnalysis, candidateInstanceKey *inst.InstanceKey, forceInstanceRecovery bool, skipProcesses bool, heldLock *inst.ClusterLockToken) (bool, *TopologyRecovery, error) {
 	failedInstanceKey := &analysisEntry.AnalyzedInstanceKey
 	if !(forceInstanceRecovery || analysisEntry.ClusterDetails.HasAutomatedMasterRecovery) {
 		return false, nil, nil
 	}
+	if !forceInstanceRecovery {
+		if confirmed, err := ConfirmFailureByQuorum(&analysisEntry); !confirmed {
+			log.Infof("topology_recovery: failure of %+v not confirmed by quorum. Will not issue RecoverDeadCoMaster.", analysisEntry.AnalyzedInstanceKey)
+			return false, nil, err
+		}
+		if !VerifyFailureWithAgent(&analysisEntry) {
+			log.Infof("topology_recovery: agent on %+v indicates failure is not to be recovered. Will not issue RecoverDeadCoMaster.", analysisEntry.AnalyzedInstanceKey)
+			return false, nil, nil
+		}
+	}
+	clusterLock, err := acquireRecoveryClusterLock(&analysisEntry, "recover-dead-co-master", heldLock)
+	if err != nil {
+		return false, nil, err
+	}
+	defer clusterLock.Release()
+
 	topologyRecovery, err := AttemptRecoveryRegistration(&analysisEntry, !forceInstanceRecovery, !forceInstanceRecovery)
 	if topologyRecovery == nil {
 		log.Debugf("topology_recovery: found an active or recent recovery on %+v. Will not issue another RecoverDeadCoMaster.", analysisEntry.AnalyzedInstanceKey)
 		return false, nil, err
 	}
+	topologyRecovery.clusterLock = clusterLock
 
 	// That's it! We must do recovery!
 	recoverDeadCoMasterCounter.Inc(1)
@@ -911,7 +968,7 @@ func checkAndRecoverDeadCoMaster(analysisEntry inst.ReplicationAnalysis, candida
 
 // checkAndRecoverUnreachableMasterWithStaleSlaves executes an external process. No other action is taken.
 // Returns false.
-func checkAndRecoverUnreachableMasterWithStaleSlaves(analysisEntry inst.ReplicationAnalysis, candidateInstanceKey *inst.InstanceKey, forceInstanceRecovery bool, skipProcesses bool) (bool, *TopologyRecovery, error) {
+func checkAndRecoverUnreachableMasterWithStaleSlaves(analysisEntry inst.ReplicationAnalysis, candidateInstanceKey *inst.InstanceKey, forceInstanceRecovery bool, skipProcesses bool, heldLock *inst.ClusterLockToken) (bool, *TopologyRecovery, error) {
 	topologyRecovery, err := AttemptRecoveryRegistration(&analysisEntry, !forceInstanceRecovery, !forceInstanceRecovery)
 	if topologyRecovery == nil {
 		log.Debugf("topology_recovery: found an active or recent recovery on %+v. Will not issue another UnreachableMasterWithStaleSlaves.", analysisEntry.AnalyzedInstanceKey)
@@ -930,7 +987,7 @@ func checkAndRecoverUnreachableMasterWithStaleSlaves(analysisEntry inst.Replicat
 }
 
 // checkAndRecoverGenericProblem is a general-purpose recovery function
-func checkAndRecoverGenericProblem(analysisEntry inst.ReplicationAnalysis, candidateInstanceKey *inst.InstanceKey, forceInstanceRecovery bool, skipProcesses bool) (bool, *TopologyRecovery, error) {
+func checkAndRecoverGenericProblem(analysisEntry inst.ReplicationAnalysis, candidateInstanceKey *inst.InstanceKey, forceInstanceRecovery bool, skipProcesses bool, heldLock *inst.ClusterLockToken) (bool, *TopologyRecovery, error) {
 	return false, nil, nil
 }
 
@@ -976,8 +1033,8 @@ func checkAndExecuteFailureDetectionProcesses(analysisEntry inst.ReplicationAnal
 
 // executeCheckAndRecoverFunction will choose the correct check & recovery function based on analysis.
 // It executes the function synchronuously
-func executeCheckAndRecoverFunction(analysisEntry inst.ReplicationAnalysis, candidateInstanceKey *inst.InstanceKey, forceInstanceRecovery bool, skipProcesses bool) (recoveryAttempted bool, topologyRecovery *TopologyRecovery, err error) {
-	var checkAndRecoverFunction func(analysisEntry inst.ReplicationAnalysis, candidateInstanceKey *inst.InstanceKey, forceInstanceRecovery bool, skipProcesses bool) (recoveryAttempted bool, topologyRecovery *TopologyRecovery, err error) = nil
+func executeCheckAndRecoverFunction(analysisEntry inst.ReplicationAnalysis, candidateInstanceKey *inst.InstanceKey, forceInstanceRecovery bool, skipProcesses bool, heldLock *inst.ClusterLockToken) (recoveryAttempted bool, topologyRecovery *TopologyRecovery, err error) {
+	var checkAndRecoverFunction func(analysisEntry inst.ReplicationAnalysis, candidateInstanceKey *inst.InstanceKey, forceInstanceRecovery bool, skipProcesses bool, heldLock *inst.ClusterLockToken) (recoveryAttempted bool, topologyRecovery *TopologyRecovery, err error) = nil
 
 	switch analysisEntry.Analysis {
 	case inst.DeadMaster:
@@ -1023,7 +1080,7 @@ func executeCheckAndRecoverFunction(analysisEntry inst.ReplicationAnalysis, cand
 		return false, nil, err
 	}
 
-	recoveryAttempted, topologyRecovery, err = checkAndRecoverFunction(analysisEntry, candidateInstanceKey, forceInstanceRecovery, skipProcesses)
+	recoveryAttempted, topologyRecovery, err = checkAndRecoverFunction(analysisEntry, candidateInstanceKey, forceInstanceRecovery, skipProcesses, heldLock)
 	if !recoveryAttempted {
 		return recoveryAttempted, topologyRecovery, err
 	}
@@ -1075,7 +1132,7 @@ func CheckAndRecover(specificInstance *inst.InstanceKey, candidateInstanceKey *i
 		if specificInstance != nil {
 			// force mode. Keep it synchronuous
 			var topologyRecovery *TopologyRecovery
-			recoveryAttempted, topologyRecovery, err = executeCheckAndRecoverFunction(analysisEntry, candidateInstanceKey, true, skipProcesses)
+			recoveryAttempted, topologyRecovery, err = executeCheckAndRecoverFunction(analysisEntry, candidateInstanceKey, true, skipProcesses, nil)
 			if topologyRecovery != nil {
 				promotedSlaveKey = topologyRecovery.SuccessorKey
 			}
@@ -1084,7 +1141,7 @@ func CheckAndRecover(specificInstance *inst.InstanceKey, candidateInstanceKey *i
 				"skipProcesses: %v: NOT Recovering host (disabled globally)",
 				analysisEntry.AnalyzedInstanceKey, candidateInstanceKey, skipProcesses)
 		} else {
-			go executeCheckAndRecoverFunction(analysisEntry, candidateInstanceKey, false, skipProcesses)
+			go executeCheckAndRecoverFunction(analysisEntry, candidateInstanceKey, false, skipProcesses, nil)
 		}
 	}
 	return recoveryAttempted, promotedSlaveKey, err
@@ -1094,6 +1151,11 @@ func CheckAndRecover(specificInstance *inst.InstanceKey, candidateInstanceKey *i
 // The caller of this function injects the type of analysis it wishes the function to assume.
 // By calling this function one takes responsibility for one's actions.
 func ForceExecuteRecovery(clusterName string, analysisCode inst.AnalysisCode, failedInstanceKey *inst.InstanceKey, candidateInstanceKey *inst.InstanceKey, skipProcesses bool) (recoveryAttempted bool, topologyRecovery *TopologyRecovery, err error) {
+	return forceExecuteRecovery(clusterName, analysisCode, failedInstanceKey, candidateInstanceKey, skipProcesses, nil)
+}
+
+// forceExecuteRecovery is ForceExecuteRecovery, on behalf of an operation holding given cluster lock
+func forceExecuteRecovery(clusterName string, analysisCode inst.AnalysisCode, failedInstanceKey *inst.InstanceKey, candidateInstanceKey *inst.InstanceKey, skipProcesses bool, heldLock *inst.ClusterLockToken) (recoveryAttempted bool, topologyRecovery *TopologyRecovery, err error) {
 	clusterInfo, err := inst.ReadClusterInfo(clusterName)
 	if err != nil {
 		return recoveryAttempted, topologyRecovery, err
@@ -1104,7 +1166,7 @@ func ForceExecuteRecovery(clusterName string, analysisCode inst.AnalysisCode, fa
 		ClusterDetails:      *clusterInfo,
 		AnalyzedInstanceKey: *failedInstanceKey,
 	}
-	return executeCheckAndRecoverFunction(analysisEntry, candidateInstanceKey, true, skipProcesses)
+	return executeCheckAndRecoverFunction(analysisEntry, candidateInstanceKey, true, skipProcesses, heldLock)
 }
 
 // ForceMasterTakeover *trusts* master of given cluster is dead and fails over to designated instance,
@@ -1146,6 +1208,18 @@ func ForceMasterTakeover(clusterName string, destination *inst.Instance) (topolo
 // This function is graceful in that it will first lock down the master, then wait
 // for the designated replica to catch up with last position.
 func GracefulMasterTakeover(clusterName string) (topologyRecovery *TopologyRecovery, promotedMasterCoordinates *inst.BinlogCoordinates, err error) {
+	return gracefulMasterTakeover(clusterName, nil)
+}
+
+// gracefulMasterTakeover is GracefulMasterTakeover, on behalf of an operation holding given cluster lock.
+// The cluster lock is held from demoting the master through to completing the recovery.
+func gracefulMasterTakeover(clusterName string, heldLock *inst.ClusterLockToken) (topologyRecovery *TopologyRecovery, promotedMasterCoordinates *inst.BinlogCoordinates, err error) {
+	clusterLock, err := inst.AcquireClusterLock(clusterName, inst.GetMaintenanceOwner(), "graceful-master-takeover", heldLock)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer clusterLock.Release()
+
 	clusterMasters, err := inst.ReadClusterWriteableMaster(clusterName)
 	if err != nil {
 		return nil, nil, fmt.Errorf("Cannot deduce cluster master for %+v", clusterName)
@@ -1192,7 +1266,7 @@ func GracefulMasterTakeover(clusterName string) (topologyRecovery *TopologyRecov
 	}
 	promotedMasterCoordinates = &designatedInstance.SelfBinlogCoordinates
 
-	recoveryAttempted, topologyRecovery, err := ForceExecuteRecovery(clusterName, inst.DeadMaster, &clusterMaster.Key, &designatedInstance.Key, false)
+	recoveryAttempted, topologyRecovery, err := forceExecuteRecovery(clusterName, inst.DeadMaster, &clusterMaster.Key, &designatedInstance.Key, false, clusterLock)
 	if err != nil {
 		return nil, nil, err
 	}
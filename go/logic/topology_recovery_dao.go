@@ -17,6 +17,7 @@
 package logic
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
 
@@ -102,6 +103,11 @@ func clearAcknowledgedFailureDetections(whereClause string, args []interface{})
 
 // AttemptRecoveryRegistration tries to add a recovery entry; if this fails that means recovery is already in place.
 func AttemptRecoveryRegistration(analysisEntry *inst.ReplicationAnalysis, failIfFailedInstanceInActiveRecovery bool, failIfClusterInActiveRecovery bool) (*TopologyRecovery, error) {
+	if failIfClusterInActiveRecovery && isRateLimitedRecoveryAnalysis(analysisEntry.Analysis) && isRecoveryRateLimitConfigured() {
+		// Automated master recovery: check & register atomically with regard to other such recoveries.
+		recoveryRateLimitMutex.Lock()
+		defer recoveryRateLimitMutex.Unlock()
+	}
 	if failIfFailedInstanceInActiveRecovery {
 		// Let's check if this instance has just been promoted recently and is still in active period.
 		// If so, we reject recovery registration to avoid flapping.
@@ -126,6 +132,12 @@ func AttemptRecoveryRegistration(analysisEntry *inst.ReplicationAnalysis, failIf
 			return nil, log.Errorf("AttemptRecoveryRegistration: cluster %+v has recently experienced a failover (of %+v) and is in active period. It will not be failed over again. You may acknowledge the failure on this cluster (-c ack-cluster-recoveries) or on %+v (-c ack-instance-recoveries) to remove this blockage", analysisEntry.ClusterDetails.ClusterName, recoveries[0].AnalysisEntry.AnalyzedInstanceKey, recoveries[0].AnalysisEntry.AnalyzedInstanceKey)
 		}
 	}
+	if failIfClusterInActiveRecovery {
+		// Manual recoveries are not rate limited
+		if err := checkRecoveryRateLimits(analysisEntry); err != nil {
+			return nil, err
+		}
+	}
 	if !failIfFailedInstanceInActiveRecovery {
 		// Implicitly acknowledge this instance's possibly existing active recovery, provided they are completed.
 		AcknowledgeInstanceCompletedRecoveries(&analysisEntry.AnalyzedInstanceKey, "orchestrator", fmt.Sprintf("implicit acknowledge due to user invocation of recovery on same instance: %+v", analysisEntry.AnalyzedInstanceKey))
@@ -133,6 +145,12 @@ func AttemptRecoveryRegistration(analysisEntry *inst.ReplicationAnalysis, failIf
 		// trying to recover the same instance at the same time
 	}
 
+	agentHostCheck := ""
+	if analysisEntry.AgentHostCheck != nil {
+		if agentHostCheckJSON, err := json.Marshal(analysisEntry.AgentHostCheck); err == nil {
+			agentHostCheck = string(agentHostCheckJSON)
+		}
+	}
 	sqlResult, err := db.ExecOrchestrator(`
 			insert ignore
 				into topology_recovery (
@@ -148,6 +166,7 @@ func AttemptRecoveryRegistration(analysisEntry *inst.ReplicationAnalysis, failIf
 					cluster_alias,
 					count_affected_slaves,
 					slave_hosts,
+					agent_host_check,
 					last_detection_id
 				) values (
 					?,
@@ -162,10 +181,12 @@ func AttemptRecoveryRegistration(analysisEntry *inst.ReplicationAnalysis, failIf
 					?,
 					?,
 					?,
+					?,
 					(select ifnull(max(detection_id), 0) from topology_failure_detection where hostname=? and port=?)
 				)
 			`, analysisEntry.AnalyzedInstanceKey.Hostname, analysisEntry.AnalyzedInstanceKey.Port, process.ThisHostname, process.ProcessToken.Hash,
 		string(analysisEntry.Analysis), analysisEntry.ClusterDetails.ClusterName, analysisEntry.ClusterDetails.ClusterAlias, analysisEntry.CountSlaves, analysisEntry.SlaveHosts.ToCommaDelimitedList(),
+		agentHostCheck,
 		analysisEntry.AnalyzedInstanceKey.Hostname, analysisEntry.AnalyzedInstanceKey.Port,
 	)
 	if err != nil {
@@ -422,7 +443,8 @@ func readRecoveries(whereCondition string, limit string, args []interface{}) ([]
             acknowledged_at,
             acknowledged_by,
             acknowledge_comment,
-            last_detection_id
+            last_detection_id,
+            agent_host_check
 		from
 			topology_recovery
 		%s
@@ -466,6 +488,12 @@ func readRecoveries(whereCondition string, limit string, args []interface{}) ([]
 		topologyRecovery.AcknowledgedComment = m.GetString("acknowledge_comment")
 
 		topologyRecovery.LastDetectionId = m.GetInt64("last_detection_id")
+		if agentHostCheck := m.GetString("agent_host_check"); agentHostCheck != "" {
+			topologyRecovery.AnalysisEntry.AgentHostCheck = &inst.AgentHostCheck{}
+			if err := json.Unmarshal([]byte(agentHostCheck), topologyRecovery.AnalysisEntry.AgentHostCheck); err != nil {
+				log.Errore(err)
+			}
+		}
 
 		res = append(res, topologyRecovery)
 		return nil
@@ -0,0 +1,184 @@
+/*
+   Copyright 2014 Outbrain Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package process
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	APIKeyScopeReadOnly  = "read-only"
+	APIKeyScopeCluster   = "cluster"
+	APIKeyScopeOperation = "operation"
+
+	apiKeySecretPrefix = "orc_"
+	apiKeyPrefixLength = 12
+)
+
+// APIKey is a named, long lived credential used by automation. Only a hash of the secret is kept.
+type APIKey struct {
+	Id              int64
+	Name            string
+	KeyPrefix       string
+	ReadOnly        bool
+	ClusterPatterns []string
+	Operations      []string
+	CreatedAt       string
+	CreatedBy       string
+	ExpiresAt       string
+	LastUsedAt      string
+	IsRevoked       bool
+	RevokedAt       string
+}
+
+// UserId is the identity by which an API key shows in audit and authorization
+func (this *APIKey) UserId() string {
+	return fmt.Sprintf("api-key:%s", this.Name)
+}
+
+// Scopes returns the key's scopes in the textual form accepted by ParseAPIKeyScopes
+func (this *APIKey) Scopes() string {
+	scopes := []string{}
+	if this.ReadOnly {
+		scopes = append(scopes, APIKeyScopeReadOnly)
+	}
+	for _, pattern := range this.ClusterPatterns {
+		scopes = append(scopes, fmt.Sprintf("%s=%s", APIKeyScopeCluster, pattern))
+	}
+	for _, operation := range this.Operations {
+		scopes = append(scopes, fmt.Sprintf("%s=%s", APIKeyScopeOperation, operation))
+	}
+	return strings.Join(scopes, ",")
+}
+
+// AllowsOperation checks whether the key may invoke given API operation, e.g. "relocate"
+func (this *APIKey) AllowsOperation(operation string) bool {
+	if len(this.Operations) == 0 {
+		return true
+	}
+	for _, allowed := range this.Operations {
+		if allowed == operation {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsCluster checks whether the key may access given cluster, by name or alias.
+// An empty clusterName indicates a request not referring to any specific cluster, which is only
+// allowed to keys not limited to clusters.
+func (this *APIKey) AllowsCluster(clusterName string, clusterAlias string) bool {
+	if len(this.ClusterPatterns) == 0 {
+		return true
+	}
+	if clusterName == "" {
+		return false
+	}
+	for _, pattern := range this.ClusterPatterns {
+		if matched, _ := regexp.MatchString(pattern, clusterName); matched {
+			return true
+		}
+		if clusterAlias != "" {
+			if matched, _ := regexp.MatchString(pattern, clusterAlias); matched {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// CoversScopesOf checks that another key's scopes are a subset of this key's, such that a key issued
+// by this key cannot do more than this key itself. Cluster patterns are compared literally.
+func (this *APIKey) CoversScopesOf(other *APIKey) error {
+	if this.ReadOnly && !other.ReadOnly {
+		return fmt.Errorf("API key %s is read-only; so must be keys it issues", this.Name)
+	}
+	if len(this.ClusterPatterns) > 0 {
+		if len(other.ClusterPatterns) == 0 {
+			return fmt.Errorf("API key %s is limited to clusters; so must be keys it issues", this.Name)
+		}
+		for _, pattern := range other.ClusterPatterns {
+			if !stringsContain(this.ClusterPatterns, pattern) {
+				return fmt.Errorf("API key %s is not scoped for %s=%s", this.Name, APIKeyScopeCluster, pattern)
+			}
+		}
+	}
+	if len(this.Operations) > 0 {
+		if len(other.Operations) == 0 {
+			return fmt.Errorf("API key %s is limited to operations; so must be keys it issues", this.Name)
+		}
+		for _, operation := range other.Operations {
+			if !this.AllowsOperation(operation) {
+				return fmt.Errorf("API key %s is not scoped for %s=%s", this.Name, APIKeyScopeOperation, operation)
+			}
+		}
+	}
+	return nil
+}
+
+func stringsContain(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseAPIKeyScopes parses a comma delimited list of scopes, each of:
+// "read-only", "cluster=<regexp>" (name or alias pattern), "operation=<api operation>"
+func ParseAPIKeyScopes(scopes string) (key *APIKey, err error) {
+	key = &APIKey{ClusterPatterns: []string{}, Operations: []string{}}
+	for _, scope := range strings.Split(scopes, ",") {
+		scope = strings.TrimSpace(scope)
+		if scope == "" {
+			continue
+		}
+		if scope == APIKeyScopeReadOnly {
+			key.ReadOnly = true
+			continue
+		}
+		tokens := strings.SplitN(scope, "=", 2)
+		if len(tokens) != 2 || tokens[1] == "" {
+			return key, fmt.Errorf("Invalid API key scope: %s", scope)
+		}
+		switch tokens[0] {
+		case APIKeyScopeCluster:
+			if _, err := regexp.Compile(tokens[1]); err != nil {
+				return key, fmt.Errorf("Invalid cluster pattern in API key scope %s: %+v", scope, err)
+			}
+			key.ClusterPatterns = append(key.ClusterPatterns, tokens[1])
+		case APIKeyScopeOperation:
+			key.Operations = append(key.Operations, tokens[1])
+		default:
+			return key, fmt.Errorf("Unknown API key scope: %s", scope)
+		}
+	}
+	return key, nil
+}
+
+// newAPIKeySecret generates a secret, of which only the prefix is ever stored in clear text
+func newAPIKeySecret() string {
+	return apiKeySecretPrefix + NewToken().Hash
+}
+
+// hashAPIKeySecret is the stored form of a secret
+func hashAPIKeySecret(secret string) string {
+	return GetHash([]byte(secret))
+}
@@ -0,0 +1,189 @@
+/*
+   Copyright 2014 Outbrain Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package process
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/outbrain/golib/log"
+	"github.com/outbrain/golib/sqlutils"
+	"github.com/outbrain/orchestrator/go/db"
+	"github.com/patrickmn/go-cache"
+)
+
+// apiKeyLastUsedWriteInterval throttles writes of api_key.last_used_at, which is otherwise updated on each request
+const apiKeyLastUsedWriteInterval = time.Minute
+
+var recentAPIKeyUsage = cache.New(apiKeyLastUsedWriteInterval, time.Minute)
+
+// CreateAPIKey generates a new API key with given scopes. The secret is returned to the caller and is not
+// retrievable afterwards. A zero expirySeconds indicates the key does not expire.
+// When the new key is issued by way of another API key (issuer), its scopes must be within the issuer's scopes.
+func CreateAPIKey(name string, scopes string, expirySeconds uint, createdBy string, issuer *APIKey) (key *APIKey, secret string, err error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, "", log.Errorf("CreateAPIKey: API key name must not be empty")
+	}
+	key, err = ParseAPIKeyScopes(scopes)
+	if err != nil {
+		return nil, "", log.Errore(err)
+	}
+	if issuer != nil {
+		if err := issuer.CoversScopesOf(key); err != nil {
+			return nil, "", log.Errore(err)
+		}
+	}
+	key.Name = name
+	key.CreatedBy = createdBy
+	secret = newAPIKeySecret()
+	key.KeyPrefix = secret[0:apiKeyPrefixLength]
+
+	clusterPatterns, _ := json.Marshal(key.ClusterPatterns)
+	operations, _ := json.Marshal(key.Operations)
+
+	// A revoked key's name may be reused
+	if _, err := db.ExecOrchestrator(`delete from api_key where key_name=? and is_revoked=1`, name); err != nil {
+		return nil, "", log.Errore(err)
+	}
+	_, err = db.ExecOrchestrator(`
+			insert into api_key (
+					key_name, key_prefix, key_hash, is_read_only, cluster_patterns, operations, created_at, created_by, expires_at
+				) values (
+					?, ?, ?, ?, ?, ?, now(), ?, if(? = 0, '1971-01-01 00:00:00', now() + interval ? second)
+				)
+			`,
+		name, key.KeyPrefix, hashAPIKeySecret(secret), key.ReadOnly, string(clusterPatterns), string(operations), createdBy, expirySeconds, expirySeconds,
+	)
+	if err != nil {
+		return nil, "", log.Errore(err)
+	}
+	return key, secret, nil
+}
+
+func readAPIKeys(condition string, args []interface{}) ([]APIKey, error) {
+	keys := []APIKey{}
+	query := fmt.Sprintf(`
+		select
+			api_key_id,
+			key_name,
+			key_prefix,
+			is_read_only,
+			cluster_patterns,
+			operations,
+			created_at,
+			created_by,
+			if(expires_at = '1971-01-01 00:00:00', '', expires_at) as expires_at,
+			if(last_used_at = '1971-01-01 00:00:00', '', last_used_at) as last_used_at,
+			is_revoked,
+			if(revoked_at = '1971-01-01 00:00:00', '', revoked_at) as revoked_at
+		from
+			api_key
+		where
+			%s
+		order by
+			key_name
+		`, condition)
+	err := db.QueryOrchestrator(query, args, func(m sqlutils.RowMap) error {
+		key := APIKey{
+			Id:              m.GetInt64("api_key_id"),
+			Name:            m.GetString("key_name"),
+			KeyPrefix:       m.GetString("key_prefix"),
+			ReadOnly:        m.GetBool("is_read_only"),
+			ClusterPatterns: []string{},
+			Operations:      []string{},
+			CreatedAt:       m.GetString("created_at"),
+			CreatedBy:       m.GetString("created_by"),
+			ExpiresAt:       m.GetString("expires_at"),
+			LastUsedAt:      m.GetString("last_used_at"),
+			IsRevoked:       m.GetBool("is_revoked"),
+			RevokedAt:       m.GetString("revoked_at"),
+		}
+		if err := json.Unmarshal([]byte(m.GetString("cluster_patterns")), &key.ClusterPatterns); err != nil {
+			return log.Errore(err)
+		}
+		if err := json.Unmarshal([]byte(m.GetString("operations")), &key.Operations); err != nil {
+			return log.Errore(err)
+		}
+		keys = append(keys, key)
+		return nil
+	})
+	return keys, log.Errore(err)
+}
+
+// ReadAPIKeys returns all API keys, including revoked and expired ones
+func ReadAPIKeys() ([]APIKey, error) {
+	return readAPIKeys("1=1", sqlutils.Args())
+}
+
+// RevokeAPIKey disables an API key by name
+func RevokeAPIKey(name string) error {
+	sqlResult, err := db.ExecOrchestrator(`
+			update api_key
+				set
+					is_revoked=1,
+					revoked_at=now()
+				where
+					key_name=?
+					and is_revoked=0
+			`,
+		name,
+	)
+	if err != nil {
+		return log.Errore(err)
+	}
+	rows, err := sqlResult.RowsAffected()
+	if err != nil {
+		return log.Errore(err)
+	}
+	if rows == 0 {
+		return log.Errorf("RevokeAPIKey: no active API key named %s", name)
+	}
+	return nil
+}
+
+// AuthenticateAPIKey looks up a non-revoked, non-expired key by its secret, and marks it as used.
+// last_used_at is written at most once per apiKeyLastUsedWriteInterval per key.
+// It returns nil when no such key exists.
+func AuthenticateAPIKey(secret string) (*APIKey, error) {
+	if !strings.HasPrefix(secret, apiKeySecretPrefix) {
+		return nil, nil
+	}
+	condition := `
+			key_hash=?
+			and is_revoked=0
+			and (expires_at = '1971-01-01 00:00:00' or expires_at > now())
+		`
+	keys, err := readAPIKeys(condition, sqlutils.Args(hashAPIKeySecret(secret)))
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	key := &keys[0]
+	if err := recentAPIKeyUsage.Add(fmt.Sprintf("%d", key.Id), true, cache.DefaultExpiration); err != nil {
+		// Recently written
+		return key, nil
+	}
+	if _, err := db.ExecOrchestrator(`update api_key set last_used_at=now() where api_key_id=?`, key.Id); err != nil {
+		return key, log.Errore(err)
+	}
+	return key, nil
+}
@@ -39,6 +39,13 @@ type HealthStatus struct {
 	AvailableNodes []string
 }
 
+// NodeHTTPEndpoint is a healthy orchestrator HTTP service node, reachable via its advertised URL
+type NodeHTTPEndpoint struct {
+	Hostname      string
+	Token         string
+	HTTPAdvertise string
+}
+
 type OrchestratorExecutionMode string
 
 const (
@@ -50,6 +57,10 @@ var continuousRegistrationInitiated bool = false
 
 // RegisterNode writes down this node in the node_health table
 func RegisterNode(extraInfo string, command string, firstTime bool) (sql.Result, error) {
+	httpAdvertise := ""
+	if extraInfo == string(OrchestratorExecutionHttpMode) {
+		httpAdvertise = HTTPAdvertise()
+	}
 	if firstTime {
 		db.ExecOrchestrator(`
 			insert ignore into node_health_history
@@ -63,17 +74,18 @@ func RegisterNode(extraInfo string, command string, firstTime bool) (sql.Result,
 	}
 	return db.ExecOrchestrator(`
 			insert into node_health
-				(hostname, token, last_seen_active, extra_info, command, app_version)
+				(hostname, token, last_seen_active, extra_info, command, app_version, http_advertise)
 			values
-				(?, ?, NOW(), ?, ?, ?)
+				(?, ?, NOW(), ?, ?, ?, ?)
 			on duplicate key update
 				token=values(token),
 				last_seen_active=values(last_seen_active),
 				extra_info=if(values(extra_info) != '', values(extra_info), extra_info),
-				app_version=values(app_version)
+				app_version=values(app_version),
+				http_advertise=if(values(http_advertise) != '', values(http_advertise), http_advertise)
 			`,
 		ThisHostname, ProcessToken.Hash, extraInfo, command,
-		config.RuntimeCLIFlags.ConfiguredVersion,
+		config.RuntimeCLIFlags.ConfiguredVersion, httpAdvertise,
 	)
 }
 
@@ -195,11 +207,12 @@ func TokenBelongsToHealthyHttpService(token string) (result bool, err error) {
 		from
 			node_health
 		where
-			and token = ?
+			token = ?
 			and extra_info = ?
+			and last_seen_active > now() - interval ? second
 		`
 
-	err = db.QueryOrchestrator(query, sqlutils.Args(token, extraInfo), func(m sqlutils.RowMap) error {
+	err = db.QueryOrchestrator(query, sqlutils.Args(token, extraInfo, registrationPollSeconds*2), func(m sqlutils.RowMap) error {
 		// Row exists? We're happy
 		result = true
 		return nil
@@ -207,6 +220,36 @@ func TokenBelongsToHealthyHttpService(token string) (result bool, err error) {
 	return result, log.Errore(err)
 }
 
+// ReadAvailableHttpNodeEndpoints returns healthy HTTP service nodes other than this one, which advertise their HTTP API
+func ReadAvailableHttpNodeEndpoints() ([]NodeHTTPEndpoint, error) {
+	res := []NodeHTTPEndpoint{}
+	query := `
+		select
+			hostname,
+			token,
+			http_advertise
+		from
+			node_health
+		where
+			last_seen_active > now() - interval ? second
+			and extra_info = ?
+			and http_advertise != ''
+			and token != ?
+		order by
+			hostname
+		`
+	args := sqlutils.Args(registrationPollSeconds*2, string(OrchestratorExecutionHttpMode), ProcessToken.Hash)
+	err := db.QueryOrchestrator(query, args, func(m sqlutils.RowMap) error {
+		res = append(res, NodeHTTPEndpoint{
+			Hostname:      m.GetString("hostname"),
+			Token:         m.GetString("token"),
+			HTTPAdvertise: m.GetString("http_advertise"),
+		})
+		return nil
+	})
+	return res, log.Errore(err)
+}
+
 // Just check to make sure we can connect to the database
 func SimpleHealthTest() (*HealthStatus, error) {
 	health := HealthStatus{Healthy: false, Hostname: ThisHostname, Token: ProcessToken.Hash}
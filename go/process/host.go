@@ -17,8 +17,12 @@
 package process
 
 import (
-	"github.com/outbrain/golib/log"
+	"fmt"
+	"net"
 	"os"
+
+	"github.com/outbrain/golib/log"
+	"github.com/outbrain/orchestrator/go/config"
 )
 
 var ThisHostname string
@@ -30,3 +34,25 @@ func init() {
 		log.Fatalf("Cannot resolve self hostname; required. Aborting. %+v", err)
 	}
 }
+
+// HTTPAdvertise returns the URL by which other orchestrator nodes can reach this node's HTTP API.
+// Unless explicitly configured, it is derived from this host's name and the HTTP listen address.
+func HTTPAdvertise() string {
+	if config.Config.HTTPAdvertise != "" {
+		return config.Config.HTTPAdvertise
+	}
+	if config.Config.ListenSocket != "" {
+		// Not reachable over the network
+		return ""
+	}
+	_, port, err := net.SplitHostPort(config.Config.ListenAddress)
+	if err != nil {
+		log.Errore(err)
+		return ""
+	}
+	scheme := "http"
+	if config.Config.UseSSL {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s", scheme, net.JoinHostPort(ThisHostname, port))
+}
@@ -579,7 +579,7 @@ function normalizeInstance(instance) {
 
   instance.replicationRunning = instance.Slave_SQL_Running && instance.Slave_IO_Running;
   instance.replicationAttemptingToRun = instance.Slave_SQL_Running || instance.Slave_IO_Running;
-  instance.replicationLagReasonable = Math.abs(instance.SlaveLagSeconds.Int64 - instance.SQLDelay) <= 10;
+  instance.replicationLagReasonable = (instance.SlaveLagSeconds.Int64 - instance.SQLDelay) <= 10;
   instance.isSeenRecently = instance.SecondsSinceLastSeen.Valid && instance.SecondsSinceLastSeen.Int64 <= 3600;
   instance.usingGTID = instance.UsingOracleGTID || instance.UsingMariaDBGTID;
   instance.isMaxScale = (instance.Version.indexOf("maxscale") >= 0);
@@ -820,6 +820,9 @@ function renderInstanceElement(popoverElement, instance, renderType) {
     if (instance.inMaintenanceProblem()) {
       popoverElement.find("h3 div.pull-right").prepend('<span class="glyphicon glyphicon-wrench" title="In maintenance"></span> ');
     }
+    if (instance.IsDelayedSlave) {
+      popoverElement.find("h3 div.pull-right").prepend('<span class="glyphicon glyphicon-time" title="Delayed slave: ' + instance.SQLDelay + 's"></span> ');
+    }
     if (instance.IsDetached) {
       popoverElement.find("h3 div.pull-right").prepend('<span class="glyphicon glyphicon-remove-sign" title="Replication forcibly detached"></span> ');
     }
@@ -12,17 +12,42 @@ function appendSeedDetails(seed, selector) {
 	row += '<td><a href="' + appUrl('/web/agent/'+seed.TargetHostname) + '">'+seed.TargetHostname+'</a></td>';
 	row += '<td><a href="' + appUrl('/web/agent/'+seed.SourceHostname) + '">'+seed.SourceHostname+'</a></td>';
 	row += '<td>' + seed.StartTimestamp + '</td>';
-	row += '<td>' + (seed.IsComplete ? seed.EndTimestamp : '<button class="btn btn-xs btn-danger" data-command="abort-seed" data-seed-source-host="'+seed.SourceHostname+'" data-seed-target-host="'+seed.TargetHostname+'" data-seed-id="' + seed.SeedId + '">Abort</button>') + '</td>';
+	var endMessage;
+	if (seed.IsComplete) {
+		endMessage = seed.EndTimestamp;
+		if (!seed.IsSuccessful) {
+			endMessage += ' <button class="btn btn-xs btn-warning" data-command="resume-seed" data-seed-source-host="'+seed.SourceHostname+'" data-seed-target-host="'+seed.TargetHostname+'" data-seed-id="' + seed.SeedId + '">Resume</button>';
+		}
+	} else {
+		endMessage = '<button class="btn btn-xs btn-danger" data-command="abort-seed" data-seed-source-host="'+seed.SourceHostname+'" data-seed-target-host="'+seed.TargetHostname+'" data-seed-id="' + seed.SeedId + '">Abort</button>';
+		if (seed.Progress && seed.Progress.BytesPerSecond > 0) {
+			endMessage += ' ' + seedProgressDescription(seed.Progress);
+		}
+	}
+	row += '<td>' + endMessage + '</td>';
 	row += '</tr>';
 	$(selector).append(row);
     hideLoader();
 }
 
+function seedProgressDescription(progress) {
+	var description = toHumanFormat(progress.BytesPerSecond) + "/sec";
+	if (progress.ETASeconds >= 0) {
+		var hours = Math.floor(progress.ETASeconds / 3600);
+		var minutes = Math.floor((progress.ETASeconds % 3600) / 60);
+		description += ", ETA " + (hours > 0 ? hours + "h" : "") + minutes + "m";
+	}
+	return description;
+}
+
 function appendSeedState(seedState) {    	
 	var action = seedState.Action;
 	action = action.replace(/Copied ([0-9]+).([0-9]+) bytes (.*$)/, function(match, match1, match2, match3) { 
 		return "Copied " + toHumanFormat(match1) + " / " + toHumanFormat(match2) + " " + match3;
 	});
+	if (seedState.Progress && seedState.Progress.BytesPerSecond > 0) {
+		action += ", " + seedProgressDescription(seedState.Progress);
+	}
 	var row = '<tr>';
 	row += '<td>' + seedState.StateTimestamp + '</td>';
 	row += '<td>' + action + '</td>';
@@ -54,3 +79,26 @@ $("body").on("click", "button[data-command=abort-seed]", function(event) {
 		}
 	});
 });
+
+$("body").on("click", "button[data-command=resume-seed]", function(event) {
+	var seedId = $(event.target).attr("data-seed-id");
+	var sourceHost = $(event.target).attr("data-seed-source-host");
+	var targetHost = $(event.target).attr("data-seed-target-host");
+
+	var message = "Are you sure you wish to resume seed " + seedId + " from <code><strong>" + 
+		sourceHost + "</strong></code> to <code><strong>" + 
+		targetHost + "</strong></code> ?";
+	bootbox.confirm(message, function(confirm) {
+		if (confirm) {
+	    	showLoader();
+	        $.get(appUrl("/api/agent-resume-seed/"+seedId), function (operationResult) {
+				hideLoader();
+				if (operationResult.Code == "ERROR") {
+					addAlert(operationResult.Message)
+				} else {
+					location.reload();
+				}	
+	        }, "json");
+		}
+	});
+});